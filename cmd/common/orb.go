@@ -0,0 +1,82 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+
+	"github.com/trustbloc/ace/pkg/did"
+)
+
+const (
+	// OrbResolveTimeoutFlagName is how long a did:orb resolution attempt may take.
+	OrbResolveTimeoutFlagName = "did-orb-resolve-timeout"
+	// OrbResolveTimeoutFlagUsage describes the usage.
+	OrbResolveTimeoutFlagUsage = "How long an attempt to resolve a did:orb DID may take, eg. 5s." +
+		" Defaults to 0: attempts do not time out." +
+		" Alternatively, this can be set with the following environment variable: " + OrbResolveTimeoutEnvKey
+	// OrbResolveTimeoutEnvKey is how long a did:orb resolution attempt may take.
+	OrbResolveTimeoutEnvKey = "DID_ORB_RESOLVE_TIMEOUT"
+
+	// OrbResolveRetriesFlagName is the number of times a failed did:orb resolution is retried.
+	OrbResolveRetriesFlagName = "did-orb-resolve-retries"
+	// OrbResolveRetriesFlagUsage describes the usage.
+	OrbResolveRetriesFlagUsage = "Number of times a did:orb resolution which failed or timed out is retried." +
+		" Defaults to 0: resolutions are not retried." +
+		" Alternatively, this can be set with the following environment variable: " + OrbResolveRetriesEnvKey
+	// OrbResolveRetriesEnvKey is the number of times a failed did:orb resolution is retried.
+	OrbResolveRetriesEnvKey = "DID_ORB_RESOLVE_RETRIES"
+)
+
+// OrbResolveParameters configure the resolution of did:orb DIDs.
+type OrbResolveParameters struct {
+	Timeout time.Duration
+	Retries int
+}
+
+// Opts returns the options of the VDR resolving did:orb DIDs with these parameters.
+func (p *OrbResolveParameters) Opts() []did.ResolveOpt {
+	return []did.ResolveOpt{did.WithResolveTimeout(p.Timeout), did.WithResolveRetries(p.Retries)}
+}
+
+// OrbResolveFlags registers the did:orb resolution flags.
+func OrbResolveFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP(OrbResolveTimeoutFlagName, "", "", OrbResolveTimeoutFlagUsage)
+	cmd.Flags().StringP(OrbResolveRetriesFlagName, "", "", OrbResolveRetriesFlagUsage)
+}
+
+// OrbResolveParams fetches the did:orb resolution parameters configured for this command.
+func OrbResolveParams(cmd *cobra.Command) (*OrbResolveParameters, error) {
+	params := &OrbResolveParameters{}
+
+	timeout := cmdutils.GetUserSetOptionalVarFromString(cmd, OrbResolveTimeoutFlagName, OrbResolveTimeoutEnvKey)
+	if timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid %s %s: must be a non-negative duration", OrbResolveTimeoutFlagName, timeout)
+		}
+
+		params.Timeout = d
+	}
+
+	retries := cmdutils.GetUserSetOptionalVarFromString(cmd, OrbResolveRetriesFlagName, OrbResolveRetriesEnvKey)
+	if retries != "" {
+		n, err := strconv.Atoi(retries)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid %s %s: must be a non-negative number", OrbResolveRetriesFlagName, retries)
+		}
+
+		params.Retries = n
+	}
+
+	return params, nil
+}
@@ -0,0 +1,60 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/ace/cmd/common"
+)
+
+func TestOrbResolveParams(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		cmd := &cobra.Command{}
+		common.OrbResolveFlags(cmd)
+		result, err := common.OrbResolveParams(cmd)
+		require.NoError(t, err)
+		require.Equal(t, &common.OrbResolveParameters{}, result)
+		require.Len(t, result.Opts(), 2)
+	})
+
+	t.Run("configured", func(t *testing.T) {
+		t.Setenv(common.OrbResolveTimeoutEnvKey, "5s")
+		t.Setenv(common.OrbResolveRetriesEnvKey, "3")
+		cmd := &cobra.Command{}
+		common.OrbResolveFlags(cmd)
+		result, err := common.OrbResolveParams(cmd)
+		require.NoError(t, err)
+		require.Equal(t, &common.OrbResolveParameters{Timeout: 5 * time.Second, Retries: 3}, result)
+	})
+
+	t.Run("error if invalid", func(t *testing.T) {
+		for _, tc := range []struct {
+			env   string
+			name  string
+			value string
+		}{
+			{common.OrbResolveTimeoutEnvKey, common.OrbResolveTimeoutFlagName, "soon"},
+			{common.OrbResolveTimeoutEnvKey, common.OrbResolveTimeoutFlagName, "-1s"},
+			{common.OrbResolveRetriesEnvKey, common.OrbResolveRetriesFlagName, "many"},
+			{common.OrbResolveRetriesEnvKey, common.OrbResolveRetriesFlagName, "-1"},
+		} {
+			t.Run(tc.value, func(t *testing.T) {
+				t.Setenv(tc.env, tc.value)
+				cmd := &cobra.Command{}
+				common.OrbResolveFlags(cmd)
+				_, err := common.OrbResolveParams(cmd)
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "invalid "+tc.name+" "+tc.value)
+			})
+		}
+	})
+}
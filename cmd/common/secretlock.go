@@ -0,0 +1,276 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock/local"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock/local/masterlock/hkdf"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock/noop"
+	"github.com/spf13/cobra"
+	"github.com/trustbloc/edge-core/pkg/log"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+)
+
+const (
+	// SecretLockTypeFlagName is the type of secret lock protecting the local KMS keys.
+	SecretLockTypeFlagName = "secret-lock-type"
+	// SecretLockTypeFlagUsage describes the usage.
+	SecretLockTypeFlagUsage = "Type of secret lock used to protect the keys of the local KMS." +
+		" Supported types are [noop, local], along with the HSM or KMS-backed types registered with" +
+		" RegisterSecretLock. Defaults to noop, which stores the keys unencrypted and" +
+		" must only be used for development." +
+		" Alternatively, this can be set with the following environment variable: " + SecretLockTypeEnvKey
+	// SecretLockTypeEnvKey is the type of secret lock.
+	SecretLockTypeEnvKey = "SECRET_LOCK_TYPE"
+
+	// SecretLockKeyPathFlagName is the path to the master key of the local secret lock.
+	SecretLockKeyPathFlagName = "secret-lock-key-path"
+	// SecretLockKeyPathFlagUsage describes the usage.
+	SecretLockKeyPathFlagUsage = "Path to the file with the base64URL-encoded 32-byte master key of the local" +
+		" secret lock. Required if the secret lock type is local." +
+		" Alternatively, this can be set with the following environment variable: " + SecretLockKeyPathEnvKey
+	// SecretLockKeyPathEnvKey is the path to the master key.
+	SecretLockKeyPathEnvKey = "SECRET_LOCK_KEY_PATH"
+
+	// SecretLockPassphraseFlagName is the passphrase protecting the master key.
+	SecretLockPassphraseFlagName = "secret-lock-passphrase"
+	// SecretLockPassphraseFlagUsage describes the usage.
+	SecretLockPassphraseFlagUsage = "Optional. Passphrase used to derive (HKDF-SHA256) the key that decrypts the" +
+		" master key of the local secret lock. If set, the master key file must hold the encrypted master key." +
+		" Alternatively, this can be set with the following environment variable: " + SecretLockPassphraseEnvKey
+	// SecretLockPassphraseEnvKey is the passphrase protecting the master key.
+	SecretLockPassphraseEnvKey = "SECRET_LOCK_PASSPHRASE" // nolint:gosec
+
+	// SecretLockKeyURIFlagName is the URI of the key backing an HSM or KMS-backed secret lock.
+	SecretLockKeyURIFlagName = "secret-lock-key-uri"
+	// SecretLockKeyURIFlagUsage describes the usage.
+	SecretLockKeyURIFlagUsage = "URI of the key protecting the keys of the local KMS in the HSM or KMS backing" +
+		" the secret lock. Passed to the secret lock types registered with RegisterSecretLock." +
+		" Alternatively, this can be set with the following environment variable: " + SecretLockKeyURIEnvKey
+	// SecretLockKeyURIEnvKey is the URI of the key backing the secret lock.
+	SecretLockKeyURIEnvKey = "SECRET_LOCK_KEY_URI"
+
+	// SecretLockNoopFallbackFlagName allows reading the local KMS keys stored before a secret lock was configured.
+	SecretLockNoopFallbackFlagName = "secret-lock-noop-fallback"
+	// SecretLockNoopFallbackFlagUsage describes the usage.
+	SecretLockNoopFallbackFlagUsage = "Set to true to migrate a local KMS whose keys were stored with the noop" +
+		" secret lock: the keys the configured secret lock fails to decrypt are read as unencrypted keys," +
+		" while new keys are encrypted. Possible values [true] [false]. Defaults to false." +
+		" Alternatively, this can be set with the following environment variable: " + SecretLockNoopFallbackEnvKey
+	// SecretLockNoopFallbackEnvKey allows reading the local KMS keys stored before a secret lock was configured.
+	SecretLockNoopFallbackEnvKey = "SECRET_LOCK_NOOP_FALLBACK"
+
+	// SecretLockTypeNoop stores the KMS keys unencrypted.
+	SecretLockTypeNoop = "noop"
+	// SecretLockTypeLocal encrypts the KMS keys with a master key read from a file.
+	SecretLockTypeLocal = "local"
+
+	// masterKeyLen is the length of the AES-256 master key of the local secret lock.
+	masterKeyLen = 32
+)
+
+// SecretLockFactory creates a secret lock backed by an HSM or a KMS, eg. protecting the local KMS keys with the
+// key at params.KeyURI.
+type SecretLockFactory func(params *SecretLockParameters) (secretlock.Service, error)
+
+// nolint:gochecknoglobals
+var (
+	logger = log.New("secret-lock")
+
+	secretLockFactoriesMutex sync.RWMutex
+	secretLockFactories      = map[string]SecretLockFactory{}
+)
+
+// RegisterSecretLock makes the secret lock type available to the secret-lock-type flag. It is meant to be called
+// by the init functions of the packages providing HSM or KMS-backed secret locks, and panics if the type is
+// already registered.
+func RegisterSecretLock(lockType string, factory SecretLockFactory) {
+	secretLockFactoriesMutex.Lock()
+	defer secretLockFactoriesMutex.Unlock()
+
+	if _, ok := secretLockFactories[lockType]; ok || lockType == SecretLockTypeNoop || lockType == SecretLockTypeLocal {
+		panic(fmt.Sprintf("secret lock type %s is already registered", lockType))
+	}
+
+	secretLockFactories[lockType] = factory
+}
+
+func secretLockFactory(lockType string) (SecretLockFactory, bool) {
+	secretLockFactoriesMutex.RLock()
+	defer secretLockFactoriesMutex.RUnlock()
+
+	factory, ok := secretLockFactories[lockType]
+
+	return factory, ok
+}
+
+// SecretLockParameters holds the secret lock configuration.
+type SecretLockParameters struct {
+	Type       string
+	KeyPath    string
+	Passphrase string
+	KeyURI     string
+	// NoopFallback reads the keys the secret lock fails to decrypt as keys stored with the noop secret lock.
+	NoopFallback bool
+}
+
+// SecretLockFlags registers the secret lock flags.
+func SecretLockFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP(SecretLockTypeFlagName, "", "", SecretLockTypeFlagUsage)
+	cmd.Flags().StringP(SecretLockKeyPathFlagName, "", "", SecretLockKeyPathFlagUsage)
+	cmd.Flags().StringP(SecretLockPassphraseFlagName, "", "", SecretLockPassphraseFlagUsage)
+	cmd.Flags().StringP(SecretLockKeyURIFlagName, "", "", SecretLockKeyURIFlagUsage)
+	cmd.Flags().StringP(SecretLockNoopFallbackFlagName, "", "", SecretLockNoopFallbackFlagUsage)
+}
+
+// SecretLockParams fetches the secret lock parameters configured for this command.
+func SecretLockParams(cmd *cobra.Command) (*SecretLockParameters, error) {
+	params := &SecretLockParameters{
+		Type:       cmdutils.GetUserSetOptionalVarFromString(cmd, SecretLockTypeFlagName, SecretLockTypeEnvKey),
+		KeyPath:    cmdutils.GetUserSetOptionalVarFromString(cmd, SecretLockKeyPathFlagName, SecretLockKeyPathEnvKey),
+		Passphrase: cmdutils.GetUserSetOptionalVarFromString(cmd, SecretLockPassphraseFlagName, SecretLockPassphraseEnvKey),
+		KeyURI:     cmdutils.GetUserSetOptionalVarFromString(cmd, SecretLockKeyURIFlagName, SecretLockKeyURIEnvKey),
+	}
+
+	if params.Type == "" {
+		params.Type = SecretLockTypeNoop
+	}
+
+	switch params.Type {
+	case SecretLockTypeNoop:
+	case SecretLockTypeLocal:
+		if params.KeyPath == "" {
+			return nil, fmt.Errorf("%s is required for the %s secret lock", SecretLockKeyPathFlagName, params.Type)
+		}
+	default:
+		if _, ok := secretLockFactory(params.Type); !ok {
+			return nil, fmt.Errorf("unsupported secret lock type: %s", params.Type)
+		}
+	}
+
+	noopFallback := cmdutils.GetUserSetOptionalVarFromString(cmd, SecretLockNoopFallbackFlagName,
+		SecretLockNoopFallbackEnvKey)
+	if noopFallback != "" {
+		var err error
+
+		params.NoopFallback, err = strconv.ParseBool(noopFallback)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %s: must be true or false", SecretLockNoopFallbackFlagName, noopFallback)
+		}
+	}
+
+	return params, nil
+}
+
+// CreateSecretLock creates the secret lock service protecting the local KMS keys. If params.NoopFallback is set,
+// the keys the secret lock fails to decrypt are read as keys stored with the noop secret lock: a local KMS whose
+// keys were stored unencrypted keeps reading them once a secret lock is configured, and encrypts the keys it creates
+// from then on.
+func CreateSecretLock(params *SecretLockParameters) (secretlock.Service, error) { //nolint:ireturn
+	lock, err := createSecretLock(params)
+	if err != nil {
+		return nil, err
+	}
+
+	if params.NoopFallback && params.Type != "" && params.Type != SecretLockTypeNoop {
+		return &noopFallbackLock{Service: lock}, nil
+	}
+
+	return lock, nil
+}
+
+func createSecretLock(params *SecretLockParameters) (secretlock.Service, error) { //nolint:ireturn
+	switch params.Type {
+	case "", SecretLockTypeNoop:
+		return &noop.NoLock{}, nil
+	case SecretLockTypeLocal:
+		return createLocalSecretLock(params)
+	}
+
+	factory, ok := secretLockFactory(params.Type)
+	if !ok {
+		return nil, fmt.Errorf("unsupported secret lock type: %s", params.Type)
+	}
+
+	lock, err := factory(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s secret lock: %w", params.Type, err)
+	}
+
+	return lock, nil
+}
+
+// createLocalSecretLock creates the local secret lock, failing unless its master key is an AES-256 key: unlike
+// local.NewService, which falls back to the raw content of the file and accepts AES-128 and AES-192 keys.
+func createLocalSecretLock(params *SecretLockParameters) (secretlock.Service, error) { //nolint:ireturn
+	content, err := os.ReadFile(filepath.Clean(params.KeyPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read master key from %s: %w", params.KeyPath, err)
+	}
+
+	content = bytes.TrimSpace(content)
+
+	var masterKey []byte
+
+	if params.Passphrase != "" {
+		masterLock, e := hkdf.NewMasterLock(params.Passphrase, sha256.New, nil)
+		if e != nil {
+			return nil, fmt.Errorf("failed to create master lock: %w", e)
+		}
+
+		decrypted, e := masterLock.Decrypt("", &secretlock.DecryptRequest{Ciphertext: string(content)})
+		if e != nil {
+			return nil, fmt.Errorf("failed to create local secret lock: decrypt master key: %w", e)
+		}
+
+		masterKey = []byte(decrypted.Plaintext)
+	} else {
+		masterKey, err = base64.URLEncoding.DecodeString(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("invalid master key in %s: must be base64URL-encoded: %w", params.KeyPath, err)
+		}
+	}
+
+	if len(masterKey) != masterKeyLen {
+		return nil, fmt.Errorf("invalid master key in %s: must be %d bytes, got %d",
+			params.KeyPath, masterKeyLen, len(masterKey))
+	}
+
+	lock, err := local.NewService(strings.NewReader(base64.URLEncoding.EncodeToString(masterKey)), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create local secret lock: %w", err)
+	}
+
+	return lock, nil
+}
+
+// noopFallbackLock decrypts with the noop secret lock what its secret lock fails to decrypt.
+type noopFallbackLock struct {
+	secretlock.Service
+}
+
+func (l *noopFallbackLock) Decrypt(keyURI string, req *secretlock.DecryptRequest) (*secretlock.DecryptResponse, error) {
+	resp, err := l.Service.Decrypt(keyURI, req)
+	if err == nil {
+		return resp, nil
+	}
+
+	logger.Warnf("failed to decrypt key %s, reading it as unencrypted: %s", keyURI, err)
+
+	return (&noop.NoLock{}).Decrypt(keyURI, req)
+}
@@ -0,0 +1,271 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common_test
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock/local/masterlock/hkdf"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock/noop"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/ace/cmd/common"
+)
+
+func TestSecretLockParams(t *testing.T) {
+	t.Run("defaults to noop", func(t *testing.T) {
+		cmd := &cobra.Command{}
+		common.SecretLockFlags(cmd)
+		result, err := common.SecretLockParams(cmd)
+		require.NoError(t, err)
+		require.Equal(t, common.SecretLockTypeNoop, result.Type)
+	})
+
+	t.Run("local", func(t *testing.T) {
+		t.Setenv(common.SecretLockTypeEnvKey, common.SecretLockTypeLocal)
+		t.Setenv(common.SecretLockKeyPathEnvKey, "/path/to/key")
+		t.Setenv(common.SecretLockPassphraseEnvKey, "passphrase")
+		cmd := &cobra.Command{}
+		common.SecretLockFlags(cmd)
+		result, err := common.SecretLockParams(cmd)
+		require.NoError(t, err)
+		require.Equal(t, &common.SecretLockParameters{
+			Type:       common.SecretLockTypeLocal,
+			KeyPath:    "/path/to/key",
+			Passphrase: "passphrase",
+		}, result)
+	})
+
+	t.Run("error if local key path is missing", func(t *testing.T) {
+		t.Setenv(common.SecretLockTypeEnvKey, common.SecretLockTypeLocal)
+		cmd := &cobra.Command{}
+		common.SecretLockFlags(cmd)
+		_, err := common.SecretLockParams(cmd)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), common.SecretLockKeyPathFlagName)
+	})
+
+	t.Run("registered type", func(t *testing.T) {
+		t.Setenv(common.SecretLockTypeEnvKey, "test-params-hsm")
+		t.Setenv(common.SecretLockKeyURIEnvKey, "hsm://keys/master")
+		t.Setenv(common.SecretLockNoopFallbackEnvKey, "true")
+
+		common.RegisterSecretLock("test-params-hsm", func(*common.SecretLockParameters) (secretlock.Service, error) {
+			return &noop.NoLock{}, nil
+		})
+
+		cmd := &cobra.Command{}
+		common.SecretLockFlags(cmd)
+		result, err := common.SecretLockParams(cmd)
+		require.NoError(t, err)
+		require.Equal(t, &common.SecretLockParameters{
+			Type:         "test-params-hsm",
+			KeyURI:       "hsm://keys/master",
+			NoopFallback: true,
+		}, result)
+	})
+
+	t.Run("error if noop fallback is invalid", func(t *testing.T) {
+		t.Setenv(common.SecretLockNoopFallbackEnvKey, "maybe")
+		cmd := &cobra.Command{}
+		common.SecretLockFlags(cmd)
+		_, err := common.SecretLockParams(cmd)
+		require.EqualError(t, err, "invalid secret-lock-noop-fallback maybe: must be true or false")
+	})
+
+	t.Run("error if type is unsupported", func(t *testing.T) {
+		t.Setenv(common.SecretLockTypeEnvKey, "unsupported")
+		cmd := &cobra.Command{}
+		common.SecretLockFlags(cmd)
+		_, err := common.SecretLockParams(cmd)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unsupported secret lock type")
+	})
+}
+
+func TestCreateSecretLock(t *testing.T) {
+	t.Run("noop", func(t *testing.T) {
+		lock, err := common.CreateSecretLock(&common.SecretLockParameters{Type: common.SecretLockTypeNoop})
+		require.NoError(t, err)
+		require.IsType(t, &noop.NoLock{}, lock)
+	})
+
+	t.Run("local", func(t *testing.T) {
+		path := writeMasterKey(t, base64.URLEncoding.EncodeToString(masterKey(t)))
+
+		lock, err := common.CreateSecretLock(&common.SecretLockParameters{
+			Type:    common.SecretLockTypeLocal,
+			KeyPath: path,
+		})
+		require.NoError(t, err)
+		requireRoundTrip(t, lock)
+	})
+
+	t.Run("local with passphrase", func(t *testing.T) {
+		masterLock, err := hkdf.NewMasterLock("passphrase", sha256.New, nil)
+		require.NoError(t, err)
+
+		encrypted, err := masterLock.Encrypt("", &secretlock.EncryptRequest{Plaintext: string(masterKey(t))})
+		require.NoError(t, err)
+
+		params := &common.SecretLockParameters{
+			Type:       common.SecretLockTypeLocal,
+			KeyPath:    writeMasterKey(t, encrypted.Ciphertext),
+			Passphrase: "passphrase",
+		}
+
+		lock, err := common.CreateSecretLock(params)
+		require.NoError(t, err)
+		requireRoundTrip(t, lock)
+
+		params.Passphrase = "wrong"
+
+		_, err = common.CreateSecretLock(params)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to create local secret lock")
+	})
+
+	t.Run("error if master key file is missing", func(t *testing.T) {
+		_, err := common.CreateSecretLock(&common.SecretLockParameters{
+			Type:    common.SecretLockTypeLocal,
+			KeyPath: filepath.Join(t.TempDir(), "missing"),
+		})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to read master key")
+	})
+
+	t.Run("error if master key has the wrong length", func(t *testing.T) {
+		for _, key := range []string{
+			base64.URLEncoding.EncodeToString(masterKey(t)[:16]),
+			base64.URLEncoding.EncodeToString(append(masterKey(t), 0)),
+		} {
+			_, err := common.CreateSecretLock(&common.SecretLockParameters{
+				Type:    common.SecretLockTypeLocal,
+				KeyPath: writeMasterKey(t, key),
+			})
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "must be 32 bytes")
+		}
+	})
+
+	t.Run("error if master key is not base64URL-encoded", func(t *testing.T) {
+		_, err := common.CreateSecretLock(&common.SecretLockParameters{
+			Type:    common.SecretLockTypeLocal,
+			KeyPath: writeMasterKey(t, string(masterKey(t))),
+		})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "must be base64URL-encoded")
+	})
+
+	t.Run("registered type", func(t *testing.T) {
+		var received *common.SecretLockParameters
+
+		common.RegisterSecretLock("test-hsm", func(params *common.SecretLockParameters) (secretlock.Service, error) {
+			received = params
+
+			return &noop.NoLock{}, nil
+		})
+
+		params := &common.SecretLockParameters{Type: "test-hsm", KeyURI: "hsm://keys/master"}
+
+		lock, err := common.CreateSecretLock(params)
+		require.NoError(t, err)
+		require.IsType(t, &noop.NoLock{}, lock)
+		require.Same(t, params, received)
+
+		require.Panics(t, func() {
+			common.RegisterSecretLock("test-hsm", nil)
+		})
+		require.Panics(t, func() {
+			common.RegisterSecretLock(common.SecretLockTypeLocal, nil)
+		})
+	})
+
+	t.Run("error if registered type fails", func(t *testing.T) {
+		common.RegisterSecretLock("test-failing-hsm", func(*common.SecretLockParameters) (secretlock.Service, error) {
+			return nil, errors.New("hsm unavailable")
+		})
+
+		_, err := common.CreateSecretLock(&common.SecretLockParameters{Type: "test-failing-hsm"})
+		require.EqualError(t, err, "failed to create test-failing-hsm secret lock: hsm unavailable")
+	})
+
+	t.Run("noop fallback", func(t *testing.T) {
+		path := writeMasterKey(t, base64.URLEncoding.EncodeToString(masterKey(t)))
+
+		lock, err := common.CreateSecretLock(&common.SecretLockParameters{
+			Type:         common.SecretLockTypeLocal,
+			KeyPath:      path,
+			NoopFallback: true,
+		})
+		require.NoError(t, err)
+		requireRoundTrip(t, lock)
+
+		stored, err := (&noop.NoLock{}).Encrypt("", &secretlock.EncryptRequest{Plaintext: "c2VjcmV0"})
+		require.NoError(t, err)
+
+		decrypted, err := lock.Decrypt("", &secretlock.DecryptRequest{Ciphertext: stored.Ciphertext})
+		require.NoError(t, err)
+		require.Equal(t, "c2VjcmV0", decrypted.Plaintext)
+
+		withoutFallback, err := common.CreateSecretLock(&common.SecretLockParameters{
+			Type:    common.SecretLockTypeLocal,
+			KeyPath: path,
+		})
+		require.NoError(t, err)
+
+		_, err = withoutFallback.Decrypt("", &secretlock.DecryptRequest{Ciphertext: stored.Ciphertext})
+		require.Error(t, err)
+	})
+
+	t.Run("error if type is unsupported", func(t *testing.T) {
+		_, err := common.CreateSecretLock(&common.SecretLockParameters{Type: "unsupported"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unsupported secret lock type")
+	})
+}
+
+func masterKey(t *testing.T) []byte {
+	t.Helper()
+
+	key := make([]byte, sha256.Size)
+
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+
+	return key
+}
+
+func writeMasterKey(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "master.key")
+
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
+
+func requireRoundTrip(t *testing.T, lock secretlock.Service) {
+	t.Helper()
+
+	encrypted, err := lock.Encrypt("", &secretlock.EncryptRequest{Plaintext: "secret"})
+	require.NoError(t, err)
+	require.NotEqual(t, "secret", encrypted.Ciphertext)
+
+	decrypted, err := lock.Decrypt("", &secretlock.DecryptRequest{Ciphertext: encrypted.Ciphertext})
+	require.NoError(t, err)
+	require.Equal(t, "secret", decrypted.Plaintext)
+}
@@ -0,0 +1,46 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+)
+
+const (
+	// SlowRequestThresholdFlagName is the duration above which requests are logged as slow.
+	SlowRequestThresholdFlagName = "slow-request-threshold"
+	// SlowRequestThresholdFlagUsage describes the usage.
+	SlowRequestThresholdFlagUsage = "Duration, eg. 2s, above which a warning is logged for a request with its route," +
+		" status and elapsed time. Defaults to 0, which logs no slow requests." +
+		" Alternatively, this can be set with the following environment variable: " + SlowRequestThresholdEnvKey
+	// SlowRequestThresholdEnvKey is the duration above which requests are logged as slow.
+	SlowRequestThresholdEnvKey = "SLOW_REQUEST_THRESHOLD"
+)
+
+// SlowRequestThresholdFlags registers the slow request threshold flag.
+func SlowRequestThresholdFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP(SlowRequestThresholdFlagName, "", "", SlowRequestThresholdFlagUsage)
+}
+
+// SlowRequestThreshold fetches the slow request threshold configured for this command, 0 if not set.
+func SlowRequestThreshold(cmd *cobra.Command) (time.Duration, error) {
+	value := cmdutils.GetUserSetOptionalVarFromString(cmd, SlowRequestThresholdFlagName, SlowRequestThresholdEnvKey)
+	if value == "" {
+		return 0, nil
+	}
+
+	threshold, err := time.ParseDuration(value)
+	if err != nil || threshold < 0 {
+		return 0, fmt.Errorf("invalid %s %s: must be a non-negative duration", SlowRequestThresholdFlagName, value)
+	}
+
+	return threshold, nil
+}
@@ -0,0 +1,47 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/ace/cmd/common"
+)
+
+func TestSlowRequestThreshold(t *testing.T) {
+	t.Run("defaults to 0", func(t *testing.T) {
+		cmd := &cobra.Command{}
+		common.SlowRequestThresholdFlags(cmd)
+		result, err := common.SlowRequestThreshold(cmd)
+		require.NoError(t, err)
+		require.Zero(t, result)
+	})
+
+	t.Run("duration", func(t *testing.T) {
+		t.Setenv(common.SlowRequestThresholdEnvKey, "1.5s")
+		cmd := &cobra.Command{}
+		common.SlowRequestThresholdFlags(cmd)
+		result, err := common.SlowRequestThreshold(cmd)
+		require.NoError(t, err)
+		require.Equal(t, 1500*time.Millisecond, result)
+	})
+
+	t.Run("error if invalid", func(t *testing.T) {
+		for _, value := range []string{"soon", "-1s"} {
+			t.Setenv(common.SlowRequestThresholdEnvKey, value)
+			cmd := &cobra.Command{}
+			common.SlowRequestThresholdFlags(cmd)
+			_, err := common.SlowRequestThreshold(cmd)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "invalid slow-request-threshold "+value)
+		}
+	})
+}
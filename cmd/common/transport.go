@@ -0,0 +1,123 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+)
+
+// Defaults of the pool of idle connections of the outbound HTTP transport. They allow more idle connections per
+// host than the Go default of 2, for high fan-out workloads on a few EDV and KMS servers.
+const (
+	DefaultHTTPMaxIdleConns        = 100
+	DefaultHTTPMaxIdleConnsPerHost = 32
+	DefaultHTTPIdleConnTimeout     = 90 * time.Second
+)
+
+const (
+	// HTTPMaxIdleConnsFlagName is the maximum number of idle outbound connections across all hosts.
+	HTTPMaxIdleConnsFlagName = "http-max-idle-conns"
+	// HTTPMaxIdleConnsFlagUsage describes the usage.
+	HTTPMaxIdleConnsFlagUsage = "Maximum number of idle connections kept open to upstream servers, across all" +
+		" hosts. Default: 100." +
+		" Alternatively, this can be set with the following environment variable: " + HTTPMaxIdleConnsEnvKey
+	// HTTPMaxIdleConnsEnvKey is the maximum number of idle outbound connections.
+	HTTPMaxIdleConnsEnvKey = "HTTP_MAX_IDLE_CONNS"
+
+	// HTTPMaxIdleConnsPerHostFlagName is the maximum number of idle outbound connections per host.
+	HTTPMaxIdleConnsPerHostFlagName = "http-max-idle-conns-per-host"
+	// HTTPMaxIdleConnsPerHostFlagUsage describes the usage.
+	HTTPMaxIdleConnsPerHostFlagUsage = "Maximum number of idle connections kept open to each upstream server." +
+		" Default: 32." +
+		" Alternatively, this can be set with the following environment variable: " + HTTPMaxIdleConnsPerHostEnvKey
+	// HTTPMaxIdleConnsPerHostEnvKey is the maximum number of idle outbound connections per host.
+	HTTPMaxIdleConnsPerHostEnvKey = "HTTP_MAX_IDLE_CONNS_PER_HOST"
+
+	// HTTPIdleConnTimeoutFlagName is how long idle outbound connections are kept open.
+	HTTPIdleConnTimeoutFlagName = "http-idle-conn-timeout"
+	// HTTPIdleConnTimeoutFlagUsage describes the usage.
+	HTTPIdleConnTimeoutFlagUsage = "How long idle connections to upstream servers are kept open, eg. 90s or 5m." +
+		" Default: 90s." +
+		" Alternatively, this can be set with the following environment variable: " + HTTPIdleConnTimeoutEnvKey
+	// HTTPIdleConnTimeoutEnvKey is how long idle outbound connections are kept open.
+	HTTPIdleConnTimeoutEnvKey = "HTTP_IDLE_CONN_TIMEOUT"
+)
+
+// HTTPTransportParameters size the pool of idle connections of the outbound HTTP transport.
+type HTTPTransportParameters struct {
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+}
+
+// HTTPTransportFlags registers the outbound HTTP transport flags.
+func HTTPTransportFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP(HTTPMaxIdleConnsFlagName, "", "", HTTPMaxIdleConnsFlagUsage)
+	cmd.Flags().StringP(HTTPMaxIdleConnsPerHostFlagName, "", "", HTTPMaxIdleConnsPerHostFlagUsage)
+	cmd.Flags().StringP(HTTPIdleConnTimeoutFlagName, "", "", HTTPIdleConnTimeoutFlagUsage)
+}
+
+// HTTPTransportParams fetches the outbound HTTP transport parameters configured for this command.
+func HTTPTransportParams(cmd *cobra.Command) (*HTTPTransportParameters, error) {
+	params := &HTTPTransportParameters{
+		MaxIdleConns:        DefaultHTTPMaxIdleConns,
+		MaxIdleConnsPerHost: DefaultHTTPMaxIdleConnsPerHost,
+		IdleConnTimeout:     DefaultHTTPIdleConnTimeout,
+	}
+
+	for _, p := range []struct {
+		name, env string
+		value     *int
+	}{
+		{HTTPMaxIdleConnsFlagName, HTTPMaxIdleConnsEnvKey, &params.MaxIdleConns},
+		{HTTPMaxIdleConnsPerHostFlagName, HTTPMaxIdleConnsPerHostEnvKey, &params.MaxIdleConnsPerHost},
+	} {
+		s := cmdutils.GetUserSetOptionalVarFromString(cmd, p.name, p.env)
+		if s == "" {
+			continue
+		}
+
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid %s %s: must be a positive number", p.name, s)
+		}
+
+		*p.value = n
+	}
+
+	timeout := cmdutils.GetUserSetOptionalVarFromString(cmd, HTTPIdleConnTimeoutFlagName, HTTPIdleConnTimeoutEnvKey)
+	if timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid %s %s: must be a positive duration", HTTPIdleConnTimeoutFlagName, timeout)
+		}
+
+		params.IdleConnTimeout = d
+	}
+
+	return params, nil
+}
+
+// NewHTTPTransport returns an outbound HTTP transport with the TLS config and the connection pool sized by the
+// parameters. The other settings are the ones of http.DefaultTransport.
+func NewHTTPTransport(tlsConfig *tls.Config, params *HTTPTransportParameters) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone() //nolint:forcetypeassert
+
+	t.TLSClientConfig = tlsConfig
+	t.MaxIdleConns = params.MaxIdleConns
+	t.MaxIdleConnsPerHost = params.MaxIdleConnsPerHost
+	t.IdleConnTimeout = params.IdleConnTimeout
+
+	return t
+}
@@ -0,0 +1,81 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common_test
+
+import (
+	"crypto/tls"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/ace/cmd/common"
+)
+
+func TestHTTPTransportParams(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		cmd := &cobra.Command{}
+		common.HTTPTransportFlags(cmd)
+		result, err := common.HTTPTransportParams(cmd)
+		require.NoError(t, err)
+		require.Equal(t, &common.HTTPTransportParameters{
+			MaxIdleConns:        common.DefaultHTTPMaxIdleConns,
+			MaxIdleConnsPerHost: common.DefaultHTTPMaxIdleConnsPerHost,
+			IdleConnTimeout:     common.DefaultHTTPIdleConnTimeout,
+		}, result)
+	})
+
+	t.Run("user set", func(t *testing.T) {
+		t.Setenv(common.HTTPMaxIdleConnsEnvKey, "500")
+		t.Setenv(common.HTTPMaxIdleConnsPerHostEnvKey, "200")
+		t.Setenv(common.HTTPIdleConnTimeoutEnvKey, "5m")
+		cmd := &cobra.Command{}
+		common.HTTPTransportFlags(cmd)
+		result, err := common.HTTPTransportParams(cmd)
+		require.NoError(t, err)
+		require.Equal(t, &common.HTTPTransportParameters{
+			MaxIdleConns:        500,
+			MaxIdleConnsPerHost: 200,
+			IdleConnTimeout:     5 * time.Minute,
+		}, result)
+	})
+
+	t.Run("error if invalid", func(t *testing.T) {
+		for env, flag := range map[string]string{
+			common.HTTPMaxIdleConnsEnvKey:        common.HTTPMaxIdleConnsFlagName,
+			common.HTTPMaxIdleConnsPerHostEnvKey: common.HTTPMaxIdleConnsPerHostFlagName,
+			common.HTTPIdleConnTimeoutEnvKey:     common.HTTPIdleConnTimeoutFlagName,
+		} {
+			for _, value := range []string{"0", "-1", "abc"} {
+				t.Setenv(env, value)
+				cmd := &cobra.Command{}
+				common.HTTPTransportFlags(cmd)
+				_, err := common.HTTPTransportParams(cmd)
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "invalid "+flag)
+			}
+
+			t.Setenv(env, "")
+		}
+	})
+}
+
+func TestNewHTTPTransport(t *testing.T) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	transport := common.NewHTTPTransport(tlsConfig, &common.HTTPTransportParameters{
+		MaxIdleConns:        500,
+		MaxIdleConnsPerHost: 200,
+		IdleConnTimeout:     5 * time.Minute,
+	})
+	require.Equal(t, tlsConfig, transport.TLSClientConfig)
+	require.Equal(t, 500, transport.MaxIdleConns)
+	require.Equal(t, 200, transport.MaxIdleConnsPerHost)
+	require.Equal(t, 5*time.Minute, transport.IdleConnTimeout)
+	require.NotNil(t, transport.Proxy)
+}
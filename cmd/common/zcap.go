@@ -0,0 +1,115 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"compress/gzip"
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+
+	"github.com/trustbloc/ace/pkg/restapi/csh/operation/zcapld"
+)
+
+const (
+	// ZCAPCompressionLevelFlagName is the gzip level of the compression of zcaps.
+	ZCAPCompressionLevelFlagName = "zcap-compression-level"
+	// ZCAPCompressionLevelFlagUsage describes the usage.
+	ZCAPCompressionLevelFlagUsage = "The gzip level of the compression of the zcaps issued, from -2 (huffman only)" +
+		" to 9 (best compression). Defaults to -1: gzip's default compression." +
+		" Alternatively, this can be set with the following environment variable: " + ZCAPCompressionLevelEnvKey
+	// ZCAPCompressionLevelEnvKey is the gzip level of the compression of zcaps.
+	ZCAPCompressionLevelEnvKey = "ZCAP_COMPRESSION_LEVEL"
+
+	// ZCAPCompressionThresholdFlagName is the size in bytes below which zcaps are not compressed.
+	ZCAPCompressionThresholdFlagName = "zcap-compression-threshold"
+	// ZCAPCompressionThresholdFlagUsage describes the usage.
+	ZCAPCompressionThresholdFlagUsage = "The size in bytes of the JSON encoding of the zcaps issued below which" +
+		" they are not compressed. Defaults to 0: all zcaps are compressed." +
+		" Alternatively, this can be set with the following environment variable: " + ZCAPCompressionThresholdEnvKey
+	// ZCAPCompressionThresholdEnvKey is the size in bytes below which zcaps are not compressed.
+	ZCAPCompressionThresholdEnvKey = "ZCAP_COMPRESSION_THRESHOLD"
+
+	// ZCAPMaxChainDepthFlagName is the maximum number of delegations of the zcaps accepted from their root zcaps.
+	ZCAPMaxChainDepthFlagName = "zcap-max-chain-depth"
+	// ZCAPMaxChainDepthFlagUsage describes the usage.
+	ZCAPMaxChainDepthFlagUsage = "The maximum number of delegations from their root zcaps of the upstream zcaps" +
+		" accepted. Deeper zcaps are rejected with 403 Forbidden. Defaults to 10." +
+		" Alternatively, this can be set with the following environment variable: " + ZCAPMaxChainDepthEnvKey
+	// ZCAPMaxChainDepthEnvKey is the maximum number of delegations of the zcaps accepted from their root zcaps.
+	ZCAPMaxChainDepthEnvKey = "ZCAP_MAX_CHAIN_DEPTH"
+)
+
+// ZCAPCompressionParameters configure the compression of the zcaps issued.
+type ZCAPCompressionParameters struct {
+	Level     int
+	Threshold int
+}
+
+// Opts returns the options compressing zcaps with these parameters.
+func (p *ZCAPCompressionParameters) Opts() []zcapld.CompressOpt {
+	return []zcapld.CompressOpt{zcapld.WithCompressionLevel(p.Level), zcapld.WithCompressionThreshold(p.Threshold)}
+}
+
+// ZCAPCompressionFlags registers the zcap compression flags.
+func ZCAPCompressionFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP(ZCAPCompressionLevelFlagName, "", "", ZCAPCompressionLevelFlagUsage)
+	cmd.Flags().StringP(ZCAPCompressionThresholdFlagName, "", "", ZCAPCompressionThresholdFlagUsage)
+}
+
+// ZCAPCompressionParams fetches the zcap compression parameters configured for this command.
+func ZCAPCompressionParams(cmd *cobra.Command) (*ZCAPCompressionParameters, error) {
+	params := &ZCAPCompressionParameters{Level: gzip.DefaultCompression}
+
+	level := cmdutils.GetUserSetOptionalVarFromString(cmd, ZCAPCompressionLevelFlagName, ZCAPCompressionLevelEnvKey)
+	if level != "" {
+		n, err := strconv.Atoi(level)
+		if err != nil || n < gzip.HuffmanOnly || n > gzip.BestCompression {
+			return nil, fmt.Errorf("invalid %s %s: must be a number from %d to %d",
+				ZCAPCompressionLevelFlagName, level, gzip.HuffmanOnly, gzip.BestCompression)
+		}
+
+		params.Level = n
+	}
+
+	threshold := cmdutils.GetUserSetOptionalVarFromString(cmd,
+		ZCAPCompressionThresholdFlagName, ZCAPCompressionThresholdEnvKey)
+	if threshold != "" {
+		n, err := strconv.Atoi(threshold)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid %s %s: must be a non-negative number",
+				ZCAPCompressionThresholdFlagName, threshold)
+		}
+
+		params.Threshold = n
+	}
+
+	return params, nil
+}
+
+// ZCAPMaxChainDepthFlags registers the zcap max chain depth flag.
+func ZCAPMaxChainDepthFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP(ZCAPMaxChainDepthFlagName, "", "", ZCAPMaxChainDepthFlagUsage)
+}
+
+// ZCAPMaxChainDepth fetches the zcap max chain depth configured for this command, zcapld.DefaultMaxChainDepth if
+// not set.
+func ZCAPMaxChainDepth(cmd *cobra.Command) (int, error) {
+	value := cmdutils.GetUserSetOptionalVarFromString(cmd, ZCAPMaxChainDepthFlagName, ZCAPMaxChainDepthEnvKey)
+	if value == "" {
+		return zcapld.DefaultMaxChainDepth, nil
+	}
+
+	depth, err := strconv.Atoi(value)
+	if err != nil || depth < 1 {
+		return 0, fmt.Errorf("invalid %s %s: must be a positive number", ZCAPMaxChainDepthFlagName, value)
+	}
+
+	return depth, nil
+}
@@ -0,0 +1,94 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common_test
+
+import (
+	"compress/gzip"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/ace/cmd/common"
+	"github.com/trustbloc/ace/pkg/restapi/csh/operation/zcapld"
+)
+
+func TestZCAPCompressionParams(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		cmd := &cobra.Command{}
+		common.ZCAPCompressionFlags(cmd)
+		result, err := common.ZCAPCompressionParams(cmd)
+		require.NoError(t, err)
+		require.Equal(t, &common.ZCAPCompressionParameters{Level: gzip.DefaultCompression}, result)
+		require.Len(t, result.Opts(), 2)
+	})
+
+	t.Run("configured", func(t *testing.T) {
+		t.Setenv(common.ZCAPCompressionLevelEnvKey, "9")
+		t.Setenv(common.ZCAPCompressionThresholdEnvKey, "512")
+		cmd := &cobra.Command{}
+		common.ZCAPCompressionFlags(cmd)
+		result, err := common.ZCAPCompressionParams(cmd)
+		require.NoError(t, err)
+		require.Equal(t, &common.ZCAPCompressionParameters{Level: gzip.BestCompression, Threshold: 512}, result)
+	})
+
+	t.Run("error if invalid", func(t *testing.T) {
+		for _, tc := range []struct {
+			env   string
+			name  string
+			value string
+		}{
+			{common.ZCAPCompressionLevelEnvKey, common.ZCAPCompressionLevelFlagName, "best"},
+			{common.ZCAPCompressionLevelEnvKey, common.ZCAPCompressionLevelFlagName, "10"},
+			{common.ZCAPCompressionLevelEnvKey, common.ZCAPCompressionLevelFlagName, "-3"},
+			{common.ZCAPCompressionThresholdEnvKey, common.ZCAPCompressionThresholdFlagName, "small"},
+			{common.ZCAPCompressionThresholdEnvKey, common.ZCAPCompressionThresholdFlagName, "-1"},
+		} {
+			t.Run(tc.value, func(t *testing.T) {
+				t.Setenv(tc.env, tc.value)
+				cmd := &cobra.Command{}
+				common.ZCAPCompressionFlags(cmd)
+				_, err := common.ZCAPCompressionParams(cmd)
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "invalid "+tc.name+" "+tc.value)
+			})
+		}
+	})
+}
+
+func TestZCAPMaxChainDepth(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		cmd := &cobra.Command{}
+		common.ZCAPMaxChainDepthFlags(cmd)
+		depth, err := common.ZCAPMaxChainDepth(cmd)
+		require.NoError(t, err)
+		require.Equal(t, zcapld.DefaultMaxChainDepth, depth)
+	})
+
+	t.Run("configured", func(t *testing.T) {
+		t.Setenv(common.ZCAPMaxChainDepthEnvKey, "3")
+		cmd := &cobra.Command{}
+		common.ZCAPMaxChainDepthFlags(cmd)
+		depth, err := common.ZCAPMaxChainDepth(cmd)
+		require.NoError(t, err)
+		require.Equal(t, 3, depth)
+	})
+
+	t.Run("error if invalid", func(t *testing.T) {
+		for _, value := range []string{"deep", "0", "-1"} {
+			t.Run(value, func(t *testing.T) {
+				t.Setenv(common.ZCAPMaxChainDepthEnvKey, value)
+				cmd := &cobra.Command{}
+				common.ZCAPMaxChainDepthFlags(cmd)
+				_, err := common.ZCAPMaxChainDepth(cmd)
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "invalid "+common.ZCAPMaxChainDepthFlagName+" "+value)
+			})
+		}
+	})
+}
@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package startcmd
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"net/http"
@@ -34,10 +35,14 @@ import (
 	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
 	tlsutils "github.com/trustbloc/edge-core/pkg/utils/tls"
 
+	"github.com/trustbloc/ace/cmd/common"
 	"github.com/trustbloc/ace/pkg/ld"
 	"github.com/trustbloc/ace/pkg/restapi/comparator"
 	"github.com/trustbloc/ace/pkg/restapi/comparator/operation"
 	"github.com/trustbloc/ace/pkg/restapi/healthcheck"
+	healthcheckop "github.com/trustbloc/ace/pkg/restapi/healthcheck/operation"
+	"github.com/trustbloc/ace/pkg/restapi/mw/slowmw"
+	"github.com/trustbloc/ace/pkg/restapi/version"
 )
 
 const (
@@ -113,12 +118,49 @@ const (
 	requestTokensFlagUsage = "Tokens used for http request " +
 		" Alternatively, this can be set with the following environment variable: " + requestTokensEnvKey
 
+	authzDefaultExpiryFlagName  = "authorization-default-expiry"
+	authzDefaultExpiryEnvKey    = "COMPARATOR_AUTHORIZATION_DEFAULT_EXPIRY"
+	authzDefaultExpiryFlagUsage = "Expiry applied to authorizations requested without an expiry caveat, eg. 24h." +
+		" Must be at least 1s. Authorizations do not expire by default." +
+		" Alternatively, this can be set with the following environment variable: " + authzDefaultExpiryEnvKey
+
+	authzMaxExpiryFlagName  = "authorization-max-expiry"
+	authzMaxExpiryEnvKey    = "COMPARATOR_AUTHORIZATION_MAX_EXPIRY"
+	authzMaxExpiryFlagUsage = "Maximum expiry an authorization can be requested with, eg. 720h. No limit by default." +
+		" Alternatively, this can be set with the following environment variable: " + authzMaxExpiryEnvKey
+
+	didCheckIntervalFlagName  = "did-check-interval"
+	didCheckIntervalEnvKey    = "COMPARATOR_DID_CHECK_INTERVAL"
+	didCheckIntervalFlagUsage = "Interval at which the comparator checks that its DID is resolvable, eg. 1m." +
+		" Default: 1m." +
+		" Alternatively, this can be set with the following environment variable: " + didCheckIntervalEnvKey
+
+	edvDocumentTargetsFlagName  = "edv-document-targets"
+	edvDocumentTargetsEnvKey    = "COMPARATOR_EDV_DOCUMENT_TARGETS"
+	edvDocumentTargetsFlagUsage = "Set if the EDV servers accept zcaps targeting a single document: the EDV zcaps" +
+		" the comparator delegates to the CSH then grant read on the authorized document rather than its vault." +
+		" Possible values [true] [false]. Defaults to false." +
+		" Alternatively, this can be set with the following environment variable: " + edvDocumentTargetsEnvKey
+
+	docMetaCacheTTLFlagName  = "doc-metadata-cache-ttl"
+	docMetaCacheTTLEnvKey    = "COMPARATOR_DOC_METADATA_CACHE_TTL"
+	docMetaCacheTTLFlagUsage = "How long the metadata of the documents fetched from the vault server is cached," +
+		" eg. 30s. Defaults to 0: the metadata is fetched for every request." +
+		" Alternatively, this can be set with the following environment variable: " + docMetaCacheTTLEnvKey
+
+	cshQueryTargetTypeFlagName  = "csh-query-target-type"
+	cshQueryTargetTypeEnvKey    = "COMPARATOR_CSH_QUERY_TARGET_TYPE"
+	cshQueryTargetTypeFlagUsage = "Invocation target type of the zcaps the comparator issues for CSH queries, a URN" +
+		" expected by the CSH. Default: " + operation.DefaultCSHQueryTargetType + "." +
+		" Alternatively, this can be set with the following environment variable: " + cshQueryTargetTypeEnvKey
+
 	splitRequestTokenLength = 2
 )
 
 const (
-	keystorePrimaryKeyURI = "local-lock://keystorekms"
-	sleep                 = 1 * time.Second
+	keystorePrimaryKeyURI   = "local-lock://keystorekms"
+	sleep                   = 1 * time.Second
+	didCheckIntervalDefault = time.Minute
 )
 
 var logger = log.New("comparator-rest")
@@ -166,14 +208,22 @@ type dsnParams struct {
 }
 
 type serviceParameters struct {
-	host            string
-	tlsParams       *tlsParameters
-	dsnParams       *dsnParams
-	didDomain       string
-	cshURL          string
-	vaultURL        string
-	didAnchorOrigin string
-	requestTokens   map[string]string
+	host                 string
+	tlsParams            *tlsParameters
+	dsnParams            *dsnParams
+	didDomain            string
+	cshURL               string
+	vaultURL             string
+	didAnchorOrigin      string
+	requestTokens        map[string]string
+	authzExpiry          *operation.AuthzExpiry
+	didCheckInterval     time.Duration
+	edvDocTargets        bool
+	docMetaCacheTTL      time.Duration
+	cshQueryTargetType   string
+	slowRequestThreshold time.Duration
+	zcapCompression      *common.ZCAPCompressionParameters
+	zcapMaxChainDepth    int
 }
 
 type server interface {
@@ -280,18 +330,144 @@ func getParameters(cmd *cobra.Command) (*serviceParameters, error) {
 
 	requestTokens := getRequestTokens(cmd)
 
+	authzExpiry, err := getAuthzExpiry(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	didCheckInterval, err := getDuration(cmd, didCheckIntervalFlagName, didCheckIntervalEnvKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if didCheckInterval == 0 {
+		didCheckInterval = didCheckIntervalDefault
+	}
+
+	edvDocTargets, err := getEDVDocumentTargets(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	docMetaCacheTTL, err := getDuration(cmd, docMetaCacheTTLFlagName, docMetaCacheTTLEnvKey)
+	if err != nil {
+		return nil, err
+	}
+
+	cshQueryTargetType, err := getCSHQueryTargetType(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	slowRequestThreshold, err := common.SlowRequestThreshold(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	zcapCompression, err := common.ZCAPCompressionParams(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	zcapMaxChainDepth, err := common.ZCAPMaxChainDepth(cmd)
+	if err != nil {
+		return nil, err
+	}
+
 	return &serviceParameters{
-		host:            host,
-		tlsParams:       tlsParams,
-		dsnParams:       dsnParams,
-		didDomain:       didDomain,
-		cshURL:          cshURL,
-		vaultURL:        vaultURL,
-		didAnchorOrigin: didAnchorOrigin,
-		requestTokens:   requestTokens,
+		host:                 host,
+		tlsParams:            tlsParams,
+		dsnParams:            dsnParams,
+		didDomain:            didDomain,
+		cshURL:               cshURL,
+		vaultURL:             vaultURL,
+		didAnchorOrigin:      didAnchorOrigin,
+		requestTokens:        requestTokens,
+		authzExpiry:          authzExpiry,
+		didCheckInterval:     didCheckInterval,
+		edvDocTargets:        edvDocTargets,
+		docMetaCacheTTL:      docMetaCacheTTL,
+		cshQueryTargetType:   cshQueryTargetType,
+		slowRequestThreshold: slowRequestThreshold,
+		zcapCompression:      zcapCompression,
+		zcapMaxChainDepth:    zcapMaxChainDepth,
 	}, err
 }
 
+func getEDVDocumentTargets(cmd *cobra.Command) (bool, error) {
+	value := cmdutils.GetUserSetOptionalVarFromString(cmd, edvDocumentTargetsFlagName, edvDocumentTargetsEnvKey)
+	if value == "" {
+		return false, nil
+	}
+
+	supported, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s %s: %w", edvDocumentTargetsFlagName, value, err)
+	}
+
+	return supported, nil
+}
+
+func getAuthzExpiry(cmd *cobra.Command) (*operation.AuthzExpiry, error) {
+	expiry := &operation.AuthzExpiry{}
+
+	var err error
+
+	expiry.Default, err = getDuration(cmd, authzDefaultExpiryFlagName, authzDefaultExpiryEnvKey)
+	if err != nil {
+		return nil, err
+	}
+
+	expiry.Max, err = getDuration(cmd, authzMaxExpiryFlagName, authzMaxExpiryEnvKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// expiry caveats are in seconds: a shorter default would be truncated to zero
+	if expiry.Default > 0 && expiry.Default < time.Second {
+		return nil, fmt.Errorf("%s %s is less than 1s", authzDefaultExpiryFlagName, expiry.Default)
+	}
+
+	if expiry.Max > 0 && expiry.Default > expiry.Max {
+		return nil, fmt.Errorf("%s %s exceeds %s %s",
+			authzDefaultExpiryFlagName, expiry.Default, authzMaxExpiryFlagName, expiry.Max)
+	}
+
+	return expiry, nil
+}
+
+func getCSHQueryTargetType(cmd *cobra.Command) (string, error) {
+	targetType := cmdutils.GetUserSetOptionalVarFromString(cmd, cshQueryTargetTypeFlagName, cshQueryTargetTypeEnvKey)
+	if targetType == "" {
+		return operation.DefaultCSHQueryTargetType, nil
+	}
+
+	err := operation.CheckURN(targetType)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s: %w", cshQueryTargetTypeFlagName, err)
+	}
+
+	return targetType, nil
+}
+
+func getDuration(cmd *cobra.Command, flagName, envKey string) (time.Duration, error) {
+	value := cmdutils.GetUserSetOptionalVarFromString(cmd, flagName, envKey)
+	if value == "" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %s %s: %w", flagName, value, err)
+	}
+
+	if d < 0 {
+		return 0, fmt.Errorf("%s must not be negative", flagName)
+	}
+
+	return d, nil
+}
+
 func getDsnParams(cmd *cobra.Command) (*dsnParams, error) {
 	params := &dsnParams{}
 
@@ -422,6 +598,15 @@ func createFlags(cmd *cobra.Command) {
 	cmd.Flags().StringP(vaultURLFlagName, "", "", vaultURLFlagUsage)
 	cmd.Flags().StringP(didAnchorOriginFlagName, "", "", didAnchorOriginFlagUsage)
 	cmd.Flags().StringArrayP(requestTokensFlagName, "", []string{}, requestTokensFlagUsage)
+	cmd.Flags().StringP(authzDefaultExpiryFlagName, "", "", authzDefaultExpiryFlagUsage)
+	cmd.Flags().StringP(authzMaxExpiryFlagName, "", "", authzMaxExpiryFlagUsage)
+	cmd.Flags().StringP(didCheckIntervalFlagName, "", "", didCheckIntervalFlagUsage)
+	cmd.Flags().StringP(docMetaCacheTTLFlagName, "", "", docMetaCacheTTLFlagUsage)
+	cmd.Flags().StringP(cshQueryTargetTypeFlagName, "", "", cshQueryTargetTypeFlagUsage)
+	cmd.Flags().StringP(edvDocumentTargetsFlagName, "", "", edvDocumentTargetsFlagUsage)
+	common.SlowRequestThresholdFlags(cmd)
+	common.ZCAPCompressionFlags(cmd)
+	common.ZCAPMaxChainDepthFlags(cmd)
 }
 
 //nolint:funlen,gocyclo
@@ -454,14 +639,6 @@ func startService(params *serviceParameters, srv server) error {
 
 	router := mux.NewRouter()
 
-	// add health check endpoint
-	healthCheckService := healthcheck.New()
-
-	healthCheckHandlers := healthCheckService.GetOperations()
-	for _, handler := range healthCheckHandlers {
-		router.HandleFunc(handler.Path(), handler.Handle()).Methods(handler.Method())
-	}
-
 	ldStore, err := ld.NewStoreProvider(storeProvider)
 	if err != nil {
 		return err
@@ -473,21 +650,42 @@ func startService(params *serviceParameters, srv server) error {
 	}
 
 	service, err := comparator.New(&operation.Config{
-		VDR:             vdr.New(vdr.WithVDR(trustblocVDR)),
-		KeyManager:      keyManager,
-		TLSConfig:       tlsConfig,
-		DIDMethod:       orb.DIDMethod,
-		StoreProvider:   storeProvider,
-		CSHBaseURL:      params.cshURL,
-		VaultBaseURL:    params.vaultURL,
-		DIDDomain:       params.didDomain,
-		DIDAnchorOrigin: params.didAnchorOrigin,
-		DocumentLoader:  loader,
+		VDR:                vdr.New(vdr.WithVDR(trustblocVDR)),
+		KeyManager:         keyManager,
+		TLSConfig:          tlsConfig,
+		DIDMethod:          orb.DIDMethod,
+		StoreProvider:      storeProvider,
+		CSHBaseURL:         params.cshURL,
+		VaultBaseURL:       params.vaultURL,
+		DIDDomain:          params.didDomain,
+		DIDAnchorOrigin:    params.didAnchorOrigin,
+		DocumentLoader:     loader,
+		AuthzExpiry:        params.authzExpiry,
+		EDVDocumentTargets: params.edvDocTargets,
+		DocMetaCacheTTL:    params.docMetaCacheTTL,
+		CSHQueryTargetType: params.cshQueryTargetType,
+		ZCAPCompression:    params.zcapCompression.Opts(),
+		MaxZCAPChainDepth:  params.zcapMaxChainDepth,
 	})
 	if err != nil {
 		return err
 	}
 
+	go service.MonitorDID(context.Background(), params.didCheckInterval)
+
+	// add health check endpoint
+	healthCheckService := healthcheck.New(healthcheckop.WithReadinessCheck("did", service.DIDReady))
+
+	healthCheckHandlers := healthCheckService.GetOperations()
+	for _, handler := range healthCheckHandlers {
+		router.HandleFunc(handler.Path(), handler.Handle()).Methods(handler.Method())
+	}
+
+	// add version endpoint
+	for _, handler := range version.New().GetOperations() {
+		router.HandleFunc(handler.Path(), handler.Handle()).Methods(handler.Method())
+	}
+
 	for _, handler := range service.GetOperations() {
 		router.HandleFunc(handler.Path(), handler.Handle()).Methods(handler.Method())
 	}
@@ -496,6 +694,8 @@ func startService(params *serviceParameters, srv server) error {
 		router.HandleFunc(handler.Path(), handler.Handle()).Methods(handler.Method())
 	}
 
+	router.Use(slowmw.New(params.slowRequestThreshold, logger))
+
 	// start server on given port and serve using given handlers
 	return srv.ListenAndServe(params.host, params.tlsParams.serveCertPath, params.tlsParams.serveKeyPath,
 		cors.New(cors.Options{
@@ -11,6 +11,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/ace/cmd/common"
 )
 
 type mockServer struct{}
@@ -241,3 +243,158 @@ func TestTLSInvalidArgs(t *testing.T) {
 		require.Contains(t, err.Error(), "invalid syntax")
 	})
 }
+
+func TestAuthzExpiryInvalidArgs(t *testing.T) {
+	baseArgs := []string{
+		"--" + hostURLFlagName, "localhost:8080",
+		"--" + datasourceNameFlagName, "mem://test",
+		"--" + didDomainFlagName, "did",
+		"--" + cshURLFlagName, "https://localhost:8081",
+		"--" + vaultURLFlagName, "https://localhost:8081",
+	}
+
+	t.Run("test invalid default expiry", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		startCmd.SetArgs(append(baseArgs, "--"+authzDefaultExpiryFlagName, "wrong"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to parse authorization-default-expiry")
+	})
+
+	t.Run("test default expiry less than 1s", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		startCmd.SetArgs(append(baseArgs, "--"+authzDefaultExpiryFlagName, "500ms"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "authorization-default-expiry 500ms is less than 1s")
+	})
+
+	t.Run("test negative max expiry", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		startCmd.SetArgs(append(baseArgs, "--"+authzMaxExpiryFlagName, "-1h"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "authorization-max-expiry must not be negative")
+	})
+
+	t.Run("test default expiry exceeds max expiry", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		startCmd.SetArgs(append(baseArgs,
+			"--"+authzDefaultExpiryFlagName, "48h",
+			"--"+authzMaxExpiryFlagName, "24h",
+		))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "authorization-default-expiry 48h0m0s exceeds authorization-max-expiry 24h0m0s")
+	})
+}
+
+func TestDIDCheckIntervalInvalidArgs(t *testing.T) {
+	startCmd := GetStartCmd(&mockServer{})
+
+	startCmd.SetArgs([]string{
+		"--" + hostURLFlagName, "localhost:8080",
+		"--" + datasourceNameFlagName, "mem://test",
+		"--" + didDomainFlagName, "did",
+		"--" + cshURLFlagName, "https://localhost:8081",
+		"--" + vaultURLFlagName, "https://localhost:8081",
+		"--" + didCheckIntervalFlagName, "wrong",
+	})
+
+	err := startCmd.Execute()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to parse did-check-interval")
+}
+
+func TestDocMetaCacheTTLInvalidArgs(t *testing.T) {
+	startCmd := GetStartCmd(&mockServer{})
+
+	startCmd.SetArgs([]string{
+		"--" + hostURLFlagName, "localhost:8080",
+		"--" + datasourceNameFlagName, "mem://test",
+		"--" + didDomainFlagName, "did",
+		"--" + cshURLFlagName, "https://localhost:8081",
+		"--" + vaultURLFlagName, "https://localhost:8081",
+		"--" + docMetaCacheTTLFlagName, "-1s",
+	})
+
+	err := startCmd.Execute()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "doc-metadata-cache-ttl must not be negative")
+}
+
+func TestCSHQueryTargetTypeInvalidArgs(t *testing.T) {
+	startCmd := GetStartCmd(&mockServer{})
+
+	startCmd.SetArgs([]string{
+		"--" + hostURLFlagName, "localhost:8080",
+		"--" + datasourceNameFlagName, "mem://test",
+		"--" + didDomainFlagName, "did",
+		"--" + cshURLFlagName, "https://localhost:8081",
+		"--" + vaultURLFlagName, "https://localhost:8081",
+		"--" + cshQueryTargetTypeFlagName, "query",
+	})
+
+	err := startCmd.Execute()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `invalid csh-query-target-type: "query" is not a URN`)
+}
+
+func TestEDVDocumentTargetsInvalidArgs(t *testing.T) {
+	startCmd := GetStartCmd(&mockServer{})
+
+	startCmd.SetArgs([]string{
+		"--" + hostURLFlagName, "localhost:8080",
+		"--" + datasourceNameFlagName, "mem://test",
+		"--" + didDomainFlagName, "did",
+		"--" + cshURLFlagName, "https://localhost:8081",
+		"--" + vaultURLFlagName, "https://localhost:8081",
+		"--" + edvDocumentTargetsFlagName, "maybe",
+	})
+
+	err := startCmd.Execute()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid edv-document-targets maybe")
+}
+
+func TestZCAPCompressionInvalidArgs(t *testing.T) {
+	startCmd := GetStartCmd(&mockServer{})
+
+	startCmd.SetArgs([]string{
+		"--" + hostURLFlagName, "localhost:8080",
+		"--" + datasourceNameFlagName, "mem://test",
+		"--" + didDomainFlagName, "did",
+		"--" + cshURLFlagName, "https://localhost:8081",
+		"--" + vaultURLFlagName, "https://localhost:8081",
+		"--" + common.ZCAPCompressionThresholdFlagName, "-1",
+	})
+
+	err := startCmd.Execute()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid zcap-compression-threshold -1")
+}
+
+func TestZCAPMaxChainDepthInvalidArgs(t *testing.T) {
+	startCmd := GetStartCmd(&mockServer{})
+
+	startCmd.SetArgs([]string{
+		"--" + hostURLFlagName, "localhost:8080",
+		"--" + datasourceNameFlagName, "mem://test",
+		"--" + didDomainFlagName, "did",
+		"--" + cshURLFlagName, "https://localhost:8081",
+		"--" + vaultURLFlagName, "https://localhost:8081",
+		"--" + common.ZCAPMaxChainDepthFlagName, "deep",
+	})
+
+	err := startCmd.Execute()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid zcap-max-chain-depth deep")
+}
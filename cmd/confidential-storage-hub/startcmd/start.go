@@ -7,11 +7,13 @@ SPDX-License-Identifier: Apache-2.0
 package startcmd
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/hyperledger/aries-framework-go-ext/component/vdr/orb"
@@ -24,7 +26,6 @@ import (
 	"github.com/hyperledger/aries-framework-go/pkg/kms/webkms"
 	ldsvc "github.com/hyperledger/aries-framework-go/pkg/ld"
 	"github.com/hyperledger/aries-framework-go/pkg/secretlock"
-	"github.com/hyperledger/aries-framework-go/pkg/secretlock/noop"
 	"github.com/hyperledger/aries-framework-go/pkg/vdr"
 	"github.com/hyperledger/aries-framework-go/pkg/vdr/key"
 	ariesstorage "github.com/hyperledger/aries-framework-go/spi/storage"
@@ -45,6 +46,9 @@ import (
 	"github.com/trustbloc/ace/pkg/restapi/csh/operation"
 	zcapld2 "github.com/trustbloc/ace/pkg/restapi/csh/operation/zcapld"
 	"github.com/trustbloc/ace/pkg/restapi/healthcheck"
+	"github.com/trustbloc/ace/pkg/restapi/mw/gzipmw"
+	"github.com/trustbloc/ace/pkg/restapi/mw/slowmw"
+	"github.com/trustbloc/ace/pkg/restapi/version"
 )
 
 const (
@@ -94,25 +98,108 @@ const (
 	didAnchorOriginFlagUsage = "DID anchor origin." +
 		" Alternatively, this can be set with the following environment variable: " + didAnchorOriginEnvKey
 
+	vaultURLFlagName  = "vault-url"
+	vaultURLEnvKey    = "CSH_VAULT_URL"
+	vaultURLFlagUsage = "URL of the vault server issuing the upstream zcaps of queries. Zcaps it revoked, or delegated" +
+		" from zcaps it revoked, are rejected with 403. Revocation is not checked if not set." +
+		" Alternatively, this can be set with the following environment variable: " + vaultURLEnvKey
+
 	requestTokensFlagName  = "request-tokens"
 	requestTokensEnvKey    = "CSH_REQUEST_TOKENS" //nolint: gosec
 	requestTokensFlagUsage = "Tokens used for http request " +
 		" Alternatively, this can be set with the following environment variable: " + requestTokensEnvKey
 
+	upstreamAuthTokensFlagName  = "upstream-auth-tokens"
+	upstreamAuthTokensEnvKey    = "CSH_UPSTREAM_AUTH_TOKENS" //nolint: gosec
+	upstreamAuthTokensFlagUsage = "Bearer tokens sent to upstream EDV and KMS servers in addition to zcaps," +
+		" in the format host=token, eg. edv.example.com:8081=secret." +
+		" Alternatively, this can be set with the following environment variable: " + upstreamAuthTokensEnvKey
+
+	maxDocSizeFlagName  = "max-doc-size"
+	maxDocSizeEnvKey    = "CSH_MAX_DOC_SIZE"
+	maxDocSizeFlagUsage = "Maximum size, in bytes, of the decrypted documents of queries. Queries resolving to" +
+		" larger documents fail with 413. Default: 10485760 (10 MiB)." +
+		" Alternatively, this can be set with the following environment variable: " + maxDocSizeEnvKey
+
+	didCacheTTLFlagName  = "did-cache-ttl"
+	didCacheTTLEnvKey    = "CSH_DID_CACHE_TTL"
+	didCacheTTLFlagUsage = "How long resolved DID documents are cached, eg. 30s or 5m. Expired documents are swept" +
+		" every TTL. Default: 5m. Alternatively, this can be set with the following environment variable: " +
+		didCacheTTLEnvKey
+
+	didCacheSizeFlagName  = "did-cache-size"
+	didCacheSizeEnvKey    = "CSH_DID_CACHE_SIZE"
+	didCacheSizeFlagUsage = "Maximum number of cached DID documents. The documents resolved first are evicted" +
+		" once full. Default: 1000. Alternatively, this can be set with the following environment variable: " +
+		didCacheSizeEnvKey
+
+	upstreamRetriesFlagName  = "upstream-retries"
+	upstreamRetriesEnvKey    = "CSH_UPSTREAM_RETRIES"
+	upstreamRetriesFlagUsage = "Number of times requests to upstream EDV and KMS servers are retried when they" +
+		" respond with 429 or 503, honoring their Retry-After header. Queries still failing respond with 503." +
+		" Default: 3. Alternatively, this can be set with the following environment variable: " +
+		upstreamRetriesEnvKey
+
+	allowedDIDMethodsFlagName  = "allowed-did-methods"
+	allowedDIDMethodsEnvKey    = "CSH_ALLOWED_DID_METHODS"
+	allowedDIDMethodsFlagUsage = "DID methods, eg. key or orb, allowed to invoke zcaps. All methods are allowed" +
+		" if none is set. Alternatively, this can be set with the following environment variable: " +
+		allowedDIDMethodsEnvKey
+
+	deniedDIDMethodsFlagName  = "denied-did-methods"
+	deniedDIDMethodsEnvKey    = "CSH_DENIED_DID_METHODS"
+	deniedDIDMethodsFlagUsage = "DID methods not allowed to invoke zcaps, taking precedence over the allowed ones." +
+		" Alternatively, this can be set with the following environment variable: " + deniedDIDMethodsEnvKey
+
+	exactNumbersFlagName  = "exact-numbers"
+	exactNumbersEnvKey    = "CSH_EXACT_NUMBERS"
+	exactNumbersFlagUsage = "Decode the numbers of documents exactly rather than as 64-bit floats, so that integers" +
+		" beyond 2^53, eg. 64-bit IDs, are extracted and compared exactly. Possible values [true] [false]." +
+		" Defaults to false." +
+		" Alternatively, this can be set with the following environment variable: " + exactNumbersEnvKey
+
+	deniedPathsFlagName  = "denied-paths"
+	deniedPathsEnvKey    = "CSH_DENIED_PATHS"
+	deniedPathsFlagUsage = "JSONPaths of the values never extracted, whatever the authorization of the queries," +
+		" eg. $.ssn or $.cards[*].number. Only child members and array elements can be selected, by name, index" +
+		" or wildcard. Alternatively, this can be set with the following environment variable: " + deniedPathsEnvKey
+
+	denyModeFlagName  = "deny-mode"
+	denyModeEnvKey    = "CSH_DENY_MODE"
+	denyModeFlagUsage = "How extractions of denied paths are handled: strip removes the denied values from the" +
+		" documents, refuse responds with 403 to the extractions that would return denied values." +
+		" Possible values [strip] [refuse]. Defaults to strip." +
+		" Alternatively, this can be set with the following environment variable: " + denyModeEnvKey
+
 	splitRequestTokenLength = 2
 )
 
 var logger = log.New("confidential-storage-hub/start")
 
 type serviceParameters struct {
-	host              string
-	baseURL           string
-	tlsParams         *tlsParameters
-	dbParams          *common.DBParameters
-	trustblocDomain   string
-	identityDIDMethod string
-	didAnchorOrigin   string
-	requestTokens     map[string]string
+	host                 string
+	baseURL              string
+	tlsParams            *tlsParameters
+	dbParams             *common.DBParameters
+	trustblocDomain      string
+	identityDIDMethod    string
+	didAnchorOrigin      string
+	vaultURL             string
+	requestTokens        map[string]string
+	upstreamTokens       map[string]string
+	secretLock           *common.SecretLockParameters
+	maxDocSize           int64
+	didCacheTTL          time.Duration
+	didCacheSize         int
+	httpTransport        *common.HTTPTransportParameters
+	orbResolve           *common.OrbResolveParameters
+	upstreamRetries      int
+	didMethods           *zcapld2.DIDMethodPolicy
+	slowRequestThreshold time.Duration
+	exactNumbers         bool
+	denyList             *operation.DenyList
+	zcapCompression      *common.ZCAPCompressionParameters
+	zcapMaxChainDepth    int
 }
 
 type tlsParameters struct {
@@ -196,24 +283,195 @@ func getParameters(cmd *cobra.Command) (*serviceParameters, error) {
 
 	didAnchorOrigin := cmdutils.GetUserSetOptionalVarFromString(cmd, didAnchorOriginFlagName, didAnchorOriginEnvKey)
 
+	vaultURL := cmdutils.GetUserSetOptionalVarFromString(cmd, vaultURLFlagName, vaultURLEnvKey)
+
 	if identityDIDMethod == "" {
 		identityDIDMethod = "key"
 	}
 
-	requestTokens := getRequestTokens(cmd)
+	requestTokens := getTokens(cmd, requestTokensFlagName, requestTokensEnvKey)
+
+	upstreamTokens := getTokens(cmd, upstreamAuthTokensFlagName, upstreamAuthTokensEnvKey)
+
+	secretLock, err := common.SecretLockParams(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	maxDocSize, err := getMaxDocSize(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	didCacheTTL, didCacheSize, err := getDIDCache(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	httpTransport, err := common.HTTPTransportParams(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	orbResolve, err := common.OrbResolveParams(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	upstreamRetries, err := getUpstreamRetries(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	didMethods, err := getDIDMethods(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	slowRequestThreshold, err := common.SlowRequestThreshold(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	exactNumbers, err := getExactNumbers(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	denyList, err := getDenyList(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	zcapCompression, err := common.ZCAPCompressionParams(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	zcapMaxChainDepth, err := common.ZCAPMaxChainDepth(cmd)
+	if err != nil {
+		return nil, err
+	}
 
 	return &serviceParameters{
-		host:              host,
-		tlsParams:         tlsParams,
-		dbParams:          dbParams,
-		baseURL:           baseURL,
-		trustblocDomain:   trustblocDomain,
-		identityDIDMethod: identityDIDMethod,
-		didAnchorOrigin:   didAnchorOrigin,
-		requestTokens:     requestTokens,
+		host:                 host,
+		tlsParams:            tlsParams,
+		dbParams:             dbParams,
+		baseURL:              baseURL,
+		trustblocDomain:      trustblocDomain,
+		identityDIDMethod:    identityDIDMethod,
+		didAnchorOrigin:      didAnchorOrigin,
+		vaultURL:             vaultURL,
+		requestTokens:        requestTokens,
+		upstreamTokens:       upstreamTokens,
+		secretLock:           secretLock,
+		maxDocSize:           maxDocSize,
+		didCacheTTL:          didCacheTTL,
+		didCacheSize:         didCacheSize,
+		httpTransport:        httpTransport,
+		orbResolve:           orbResolve,
+		upstreamRetries:      upstreamRetries,
+		didMethods:           didMethods,
+		slowRequestThreshold: slowRequestThreshold,
+		exactNumbers:         exactNumbers,
+		denyList:             denyList,
+		zcapCompression:      zcapCompression,
+		zcapMaxChainDepth:    zcapMaxChainDepth,
 	}, err
 }
 
+func getExactNumbers(cmd *cobra.Command) (bool, error) {
+	exactNumbers := cmdutils.GetUserSetOptionalVarFromString(cmd, exactNumbersFlagName, exactNumbersEnvKey)
+	if exactNumbers == "" {
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(exactNumbers)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s %s: %w", exactNumbersFlagName, exactNumbers, err)
+	}
+
+	return b, nil
+}
+
+func getMaxDocSize(cmd *cobra.Command) (int64, error) {
+	maxDocSize := cmdutils.GetUserSetOptionalVarFromString(cmd, maxDocSizeFlagName, maxDocSizeEnvKey)
+	if maxDocSize == "" {
+		return operation.DefaultMaxDocSize, nil
+	}
+
+	size, err := strconv.ParseInt(maxDocSize, 10, 64)
+	if err != nil || size <= 0 {
+		return 0, fmt.Errorf("invalid %s %s: must be a positive number of bytes", maxDocSizeFlagName, maxDocSize)
+	}
+
+	return size, nil
+}
+
+func getDIDCache(cmd *cobra.Command) (time.Duration, int, error) {
+	ttl := operation.DefaultDIDCacheTTL
+	size := operation.DefaultDIDCacheSize
+
+	if v := cmdutils.GetUserSetOptionalVarFromString(cmd, didCacheTTLFlagName, didCacheTTLEnvKey); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return 0, 0, fmt.Errorf("invalid %s %s: must be a positive duration", didCacheTTLFlagName, v)
+		}
+
+		ttl = d
+	}
+
+	if v := cmdutils.GetUserSetOptionalVarFromString(cmd, didCacheSizeFlagName, didCacheSizeEnvKey); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return 0, 0, fmt.Errorf("invalid %s %s: must be a positive number", didCacheSizeFlagName, v)
+		}
+
+		size = n
+	}
+
+	return ttl, size, nil
+}
+
+func getUpstreamRetries(cmd *cobra.Command) (int, error) {
+	upstreamRetries := cmdutils.GetUserSetOptionalVarFromString(cmd, upstreamRetriesFlagName, upstreamRetriesEnvKey)
+	if upstreamRetries == "" {
+		return operation.DefaultUpstreamRetries, nil
+	}
+
+	retries, err := strconv.Atoi(upstreamRetries)
+	if err != nil || retries < 0 {
+		return 0, fmt.Errorf("invalid %s %s: must be zero or a positive number", upstreamRetriesFlagName,
+			upstreamRetries)
+	}
+
+	return retries, nil
+}
+
+func getDIDMethods(cmd *cobra.Command) (*zcapld2.DIDMethodPolicy, error) {
+	allowed := cmdutils.GetUserSetOptionalVarFromArrayString(cmd, allowedDIDMethodsFlagName, allowedDIDMethodsEnvKey)
+	denied := cmdutils.GetUserSetOptionalVarFromArrayString(cmd, deniedDIDMethodsFlagName, deniedDIDMethodsEnvKey)
+
+	policy, err := zcapld2.NewDIDMethodPolicy(allowed, denied)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s or %s: %w", allowedDIDMethodsFlagName, deniedDIDMethodsFlagName, err)
+	}
+
+	return policy, nil
+}
+
+func getDenyList(cmd *cobra.Command) (*operation.DenyList, error) {
+	paths := cmdutils.GetUserSetOptionalVarFromArrayString(cmd, deniedPathsFlagName, deniedPathsEnvKey)
+	mode := cmdutils.GetUserSetOptionalVarFromString(cmd, denyModeFlagName, denyModeEnvKey)
+
+	denyList, err := operation.NewDenyList(paths, operation.DenyMode(mode))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s or %s: %w", deniedPathsFlagName, denyModeFlagName, err)
+	}
+
+	return denyList, nil
+}
+
 func createFlags(cmd *cobra.Command) {
 	common.Flags(cmd)
 	cmd.Flags().StringP(hostURLFlagName, hostURLFlagShorthand, "", hostURLFlagUsage)
@@ -225,7 +483,24 @@ func createFlags(cmd *cobra.Command) {
 	cmd.Flags().StringP(didDomainFlagName, "", "", didDomainFlagUsage)
 	cmd.Flags().StringP(identityDIDMethodFlagName, "", "", identityDIDMethodFlagUsage)
 	cmd.Flags().StringP(didAnchorOriginFlagName, "", "", didAnchorOriginFlagUsage)
+	cmd.Flags().StringP(vaultURLFlagName, "", "", vaultURLFlagUsage)
 	cmd.Flags().StringArrayP(requestTokensFlagName, "", []string{}, requestTokensFlagUsage)
+	cmd.Flags().StringArrayP(upstreamAuthTokensFlagName, "", []string{}, upstreamAuthTokensFlagUsage)
+	cmd.Flags().StringP(maxDocSizeFlagName, "", "", maxDocSizeFlagUsage)
+	cmd.Flags().StringP(didCacheTTLFlagName, "", "", didCacheTTLFlagUsage)
+	cmd.Flags().StringP(didCacheSizeFlagName, "", "", didCacheSizeFlagUsage)
+	cmd.Flags().StringArrayP(allowedDIDMethodsFlagName, "", []string{}, allowedDIDMethodsFlagUsage)
+	cmd.Flags().StringArrayP(deniedDIDMethodsFlagName, "", []string{}, deniedDIDMethodsFlagUsage)
+	cmd.Flags().StringP(upstreamRetriesFlagName, "", "", upstreamRetriesFlagUsage)
+	cmd.Flags().StringP(exactNumbersFlagName, "", "", exactNumbersFlagUsage)
+	cmd.Flags().StringArrayP(deniedPathsFlagName, "", []string{}, deniedPathsFlagUsage)
+	cmd.Flags().StringP(denyModeFlagName, "", "", denyModeFlagUsage)
+	common.SecretLockFlags(cmd)
+	common.HTTPTransportFlags(cmd)
+	common.OrbResolveFlags(cmd)
+	common.SlowRequestThresholdFlags(cmd)
+	common.ZCAPCompressionFlags(cmd)
+	common.ZCAPMaxChainDepthFlags(cmd)
 }
 
 func getTLS(cmd *cobra.Command) (*tlsParameters, error) {
@@ -265,9 +540,9 @@ func getTLS(cmd *cobra.Command) (*tlsParameters, error) {
 	}, nil
 }
 
-func getRequestTokens(cmd *cobra.Command) map[string]string {
-	requestTokens := cmdutils.GetUserSetOptionalVarFromArrayString(cmd, requestTokensFlagName,
-		requestTokensEnvKey)
+// getTokens parses tokens given in the format name=token. Malformed tokens are ignored.
+func getTokens(cmd *cobra.Command, flagName, envKey string) map[string]string {
+	requestTokens := cmdutils.GetUserSetOptionalVarFromArrayString(cmd, flagName, envKey)
 
 	tokens := make(map[string]string)
 
@@ -305,6 +580,11 @@ func startService(params *serviceParameters, srv server) error { // nolint:funle
 		router.HandleFunc(handler.Path(), handler.Handle()).Methods(handler.Method())
 	}
 
+	// add version endpoint
+	for _, handler := range version.New().GetOperations() {
+		router.HandleFunc(handler.Path(), handler.Handle()).Methods(handler.Method())
+	}
+
 	baseURL := params.baseURL
 	if baseURL == "" {
 		baseURL = params.host
@@ -320,21 +600,44 @@ func startService(params *serviceParameters, srv server) error { // nolint:funle
 		return err
 	}
 
-	service, err := csh.New(&operation.Config{
-		StoreProvider: provider,
-		Aries:         ariesConfig,
-		EDVClient:     adaptedEDVClientConstructor(),
-		HTTPClient: &http.Client{Transport: &http.Transport{
-			TLSClientConfig: params.tlsParams.tlsConfig,
-		}},
-		BaseURL:        baseURL,
-		DIDDomain:      params.trustblocDomain,
-		DocumentLoader: loader,
-	})
+	upstreamClient := &http.Client{Transport: &operation.RetryTransport{
+		Base: &upstreamAuthTransport{
+			base:   common.NewHTTPTransport(params.tlsParams.tlsConfig, params.httpTransport),
+			tokens: params.upstreamTokens,
+		},
+		Retries: params.upstreamRetries,
+	}}
+
+	config := &operation.Config{
+		StoreProvider:     provider,
+		Aries:             ariesConfig,
+		EDVClient:         adaptedEDVClientConstructor(upstreamClient),
+		HTTPClient:        upstreamClient,
+		BaseURL:           baseURL,
+		DIDDomain:         params.trustblocDomain,
+		DocumentLoader:    loader,
+		MaxDocSize:        params.maxDocSize,
+		DIDCacheTTL:       params.didCacheTTL,
+		DIDCacheSize:      params.didCacheSize,
+		DIDMethods:        params.didMethods,
+		ExactNumbers:      params.exactNumbers,
+		DenyList:          params.denyList,
+		ZCAPCompression:   params.zcapCompression.Opts(),
+		MaxZCAPChainDepth: params.zcapMaxChainDepth,
+	}
+
+	// the vault server records the zcaps of the authorizations it deleted as revoked
+	if params.vaultURL != "" {
+		config.Revocations = vault.New(params.vaultURL, vault.WithHTTPClient(upstreamClient))
+	}
+
+	service, err := csh.New(config)
 	if err != nil {
 		return fmt.Errorf("failed to initialize confidential storage hub operations: %w", err)
 	}
 
+	go service.SweepDIDCache(context.Background())
+
 	for _, handler := range service.GetOperations() {
 		router.HandleFunc(handler.Path(), handler.Handle()).Methods(handler.Method())
 	}
@@ -343,6 +646,12 @@ func startService(params *serviceParameters, srv server) error { // nolint:funle
 		router.HandleFunc(handler.Path(), handler.Handle()).Methods(handler.Method())
 	}
 
+	// the elapsed time of slow requests includes the compression of their responses
+	router.Use(slowmw.New(params.slowRequestThreshold, logger))
+
+	// gzip request bodies are decompressed and responses compressed for clients accepting gzip
+	router.Use(gzipmw.New(gzipmw.DefaultMaxDecompressedSize))
+
 	logger.Infof("starting server on host: %s", params.host)
 
 	// start server on given port and serve using given handlers
@@ -360,6 +669,7 @@ func startService(params *serviceParameters, srv server) error { // nolint:funle
 				"Origin",
 				"Accept",
 				"Content-Type",
+				"Content-Encoding",
 				"X-Requested-With",
 				"Authorization",
 			},
@@ -374,11 +684,16 @@ func newAriesConfig(params *serviceParameters) (*operation.AriesConfig, error) {
 		return nil, fmt.Errorf("failed to init aries store: %w", err)
 	}
 
+	secretLock, err := common.CreateSecretLock(params.secretLock)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init secret lock: %w", err)
+	}
+
 	k, err := localkms.New(
 		"local-lock://custom/primary/key/",
 		&kmsProvider{
 			sp: store,
-			sl: &noop.NoLock{},
+			sl: secretLock,
 		},
 	)
 	if err != nil {
@@ -390,20 +705,28 @@ func newAriesConfig(params *serviceParameters) (*operation.AriesConfig, error) {
 		return nil, fmt.Errorf("failed to init tink crypto: %w", err)
 	}
 
-	didVDR, err := orb.New(
+	orbVDR, err := orb.New(
 		nil,
 		orb.WithDomain(params.trustblocDomain),
-		orb.WithTLSConfig(params.tlsParams.tlsConfig),
+		orb.WithHTTPClient(&http.Client{
+			Transport: common.NewHTTPTransport(params.tlsParams.tlsConfig, params.httpTransport),
+		}),
 		orb.WithAuthToken(params.requestTokens["sidetreeToken"]),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to init trustbloc VDR: %w", err)
 	}
 
+	// orb resolutions time out and are retried, so that a transient orb outage does not hang identity creation
+	// or zcap verification
+	didVDR := did.NewResolvingVDR(orbVDR, params.orbResolve.Opts()...)
+
 	// TODO make these configurable:
 	//  - DID resolvers
 	//  - Key types
 	//  - Verification method type
+	// the operations sign the upstream requests with the options of the zcap client builder: the clients are built
+	// here, rather than with zcap.NewSignedKMSClient, so that the tests of the operations can replace them
 	return &operation.AriesConfig{
 		KMS:    k,
 		Crypto: c,
@@ -413,7 +736,7 @@ func newAriesConfig(params *serviceParameters) (*operation.AriesConfig, error) {
 		WebCrypto: func(url string, client webcrypto.HTTPClient, opts ...webkms.Opt) crypto.Crypto {
 			return webcrypto.New(url, client, opts...)
 		},
-		DIDResolvers: []zcapld2.DIDResolver{key.New(), didVDR},
+		DIDResolvers: []zcapld2.DIDResolver{key.New(), zcapld2.NewJWKResolver(), didVDR},
 		PublicDIDCreator: did.PublicDID(&did.Config{
 			Method:                 params.identityDIDMethod,
 			VerificationMethodType: "JsonWebKey2020",
@@ -438,8 +761,16 @@ func (k *kmsProvider) SecretLock() secretlock.Service {
 	return k.sl
 }
 
-func adaptedEDVClientConstructor() func(string, ...edv.Option) vault.ConfidentialStorageDocReader {
+// adaptedEDVClientConstructor returns EDV clients sending their requests with the given HTTP client, so that
+// they carry the upstream auth tokens and are retried when the servers are overloaded. The operations sign the
+// requests with the option zcap.EDVSigner gives. An HTTP client given in
+// the options, which must wrap it, takes precedence: the operations bind the requests to their deadline with it.
+func adaptedEDVClientConstructor(
+	httpClient edv.HTTPClient,
+) func(string, ...edv.Option) vault.ConfidentialStorageDocReader {
 	return func(url string, opts ...edv.Option) vault.ConfidentialStorageDocReader {
+		opts = append([]edv.Option{edv.WithHTTPClient(httpClient)}, opts...)
+
 		return &adaptedEDVClient{wrapped: edv.New(url, opts...)}
 	}
 }
@@ -452,3 +783,22 @@ func (a *adaptedEDVClient) ReadDocument(
 	vaultID, docID string, opts ...edv.ReqOption) (*models.EncryptedDocument, error) {
 	return a.wrapped.ReadDocument(vaultID, docID, opts...)
 }
+
+// upstreamAuthTransport attaches bearer tokens, keyed by host, to requests sent to upstream EDV and KMS servers.
+type upstreamAuthTransport struct {
+	base   http.RoundTripper
+	tokens map[string]string
+}
+
+func (t *upstreamAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, ok := t.tokens[req.URL.Host]
+	if !ok || req.Header.Get("Authorization") != "" {
+		return t.base.RoundTrip(req)
+	}
+
+	// round trippers must not modify the request
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	return t.base.RoundTrip(req)
+}
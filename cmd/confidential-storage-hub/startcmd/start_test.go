@@ -8,10 +8,14 @@ package startcmd
 
 import (
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	edv "github.com/trustbloc/edv/pkg/client"
 
 	"github.com/trustbloc/ace/cmd/common"
 )
@@ -77,6 +81,197 @@ func TestStartCmdWithInvalidArgs(t *testing.T) {
 		require.Error(t, err)
 		require.EqualError(t, err, "failed to init provider: failed to parse invalid: invalid dbURL invalid")
 	})
+
+	t.Run("invalid max doc size", func(t *testing.T) {
+		for _, size := range []string{"10MB", "0"} {
+			args := []string{
+				"--" + hostURLFlagName, "localhost:8080",
+				"--" + common.DatabaseURLFlagName, "mem://test",
+				"--" + common.DatabasePrefixFlagName, "test",
+				"--" + didDomainFlagName, "testnet.orb.local",
+				"--" + maxDocSizeFlagName, size,
+			}
+			startCmd := GetStartCmd(&mockServer{})
+
+			startCmd.SetArgs(args)
+			err := startCmd.Execute()
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "invalid max-doc-size "+size)
+		}
+	})
+
+	t.Run("invalid DID cache", func(t *testing.T) {
+		for flag, value := range map[string]string{
+			didCacheTTLFlagName:  "0s",
+			didCacheSizeFlagName: "-1",
+		} {
+			args := []string{
+				"--" + hostURLFlagName, "localhost:8080",
+				"--" + common.DatabaseURLFlagName, "mem://test",
+				"--" + common.DatabasePrefixFlagName, "test",
+				"--" + didDomainFlagName, "testnet.orb.local",
+				"--" + flag, value,
+			}
+			startCmd := GetStartCmd(&mockServer{})
+
+			startCmd.SetArgs(args)
+			err := startCmd.Execute()
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "invalid "+flag+" "+value)
+		}
+	})
+
+	t.Run("invalid http transport", func(t *testing.T) {
+		args := []string{
+			"--" + hostURLFlagName, "localhost:8080",
+			"--" + common.DatabaseURLFlagName, "mem://test",
+			"--" + common.DatabasePrefixFlagName, "test",
+			"--" + didDomainFlagName, "testnet.orb.local",
+			"--" + common.HTTPIdleConnTimeoutFlagName, "forever",
+		}
+		startCmd := GetStartCmd(&mockServer{})
+
+		startCmd.SetArgs(args)
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid http-idle-conn-timeout forever")
+	})
+
+	t.Run("invalid upstream retries", func(t *testing.T) {
+		for _, retries := range []string{"many", "-1"} {
+			args := []string{
+				"--" + hostURLFlagName, "localhost:8080",
+				"--" + common.DatabaseURLFlagName, "mem://test",
+				"--" + common.DatabasePrefixFlagName, "test",
+				"--" + didDomainFlagName, "testnet.orb.local",
+				"--" + upstreamRetriesFlagName, retries,
+			}
+			startCmd := GetStartCmd(&mockServer{})
+
+			startCmd.SetArgs(args)
+			err := startCmd.Execute()
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "invalid upstream-retries "+retries)
+		}
+	})
+
+	t.Run("invalid exact numbers", func(t *testing.T) {
+		args := []string{
+			"--" + hostURLFlagName, "localhost:8080",
+			"--" + common.DatabaseURLFlagName, "mem://test",
+			"--" + common.DatabasePrefixFlagName, "test",
+			"--" + didDomainFlagName, "testnet.orb.local",
+			"--" + exactNumbersFlagName, "maybe",
+		}
+		startCmd := GetStartCmd(&mockServer{})
+
+		startCmd.SetArgs(args)
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid exact-numbers maybe")
+	})
+
+	t.Run("invalid deny-list", func(t *testing.T) {
+		for _, flags := range [][]string{
+			{"--" + deniedPathsFlagName, "$..ssn"},
+			{"--" + deniedPathsFlagName, "$.ssn", "--" + denyModeFlagName, "hide"},
+		} {
+			args := []string{
+				"--" + hostURLFlagName, "localhost:8080",
+				"--" + common.DatabaseURLFlagName, "mem://test",
+				"--" + common.DatabasePrefixFlagName, "test",
+				"--" + didDomainFlagName, "testnet.orb.local",
+			}
+			startCmd := GetStartCmd(&mockServer{})
+
+			startCmd.SetArgs(append(args, flags...))
+			err := startCmd.Execute()
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "invalid denied-paths or deny-mode")
+		}
+	})
+
+	t.Run("invalid DID method", func(t *testing.T) {
+		for _, flag := range []string{allowedDIDMethodsFlagName, deniedDIDMethodsFlagName} {
+			args := []string{
+				"--" + hostURLFlagName, "localhost:8080",
+				"--" + common.DatabaseURLFlagName, "mem://test",
+				"--" + common.DatabasePrefixFlagName, "test",
+				"--" + didDomainFlagName, "testnet.orb.local",
+				"--" + flag, "did:key",
+			}
+			startCmd := GetStartCmd(&mockServer{})
+
+			startCmd.SetArgs(args)
+			err := startCmd.Execute()
+			require.Error(t, err)
+			require.Contains(t, err.Error(), `invalid DID method name: "did:key"`)
+		}
+	})
+
+	t.Run("invalid slow request threshold", func(t *testing.T) {
+		args := []string{
+			"--" + hostURLFlagName, "localhost:8080",
+			"--" + common.DatabaseURLFlagName, "mem://test",
+			"--" + common.DatabasePrefixFlagName, "test",
+			"--" + didDomainFlagName, "testnet.orb.local",
+			"--" + common.SlowRequestThresholdFlagName, "-1s",
+		}
+		startCmd := GetStartCmd(&mockServer{})
+
+		startCmd.SetArgs(args)
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid slow-request-threshold -1s")
+	})
+
+	t.Run("invalid did:orb resolution", func(t *testing.T) {
+		args := []string{
+			"--" + hostURLFlagName, "localhost:8080",
+			"--" + common.DatabaseURLFlagName, "mem://test",
+			"--" + common.DatabasePrefixFlagName, "test",
+			"--" + didDomainFlagName, "testnet.orb.local",
+			"--" + common.OrbResolveRetriesFlagName, "-1",
+		}
+		startCmd := GetStartCmd(&mockServer{})
+
+		startCmd.SetArgs(args)
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid did-orb-resolve-retries -1")
+	})
+
+	t.Run("invalid zcap compression", func(t *testing.T) {
+		args := []string{
+			"--" + hostURLFlagName, "localhost:8080",
+			"--" + common.DatabaseURLFlagName, "mem://test",
+			"--" + common.DatabasePrefixFlagName, "test",
+			"--" + didDomainFlagName, "testnet.orb.local",
+			"--" + common.ZCAPCompressionLevelFlagName, "10",
+		}
+		startCmd := GetStartCmd(&mockServer{})
+
+		startCmd.SetArgs(args)
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid zcap-compression-level 10")
+	})
+
+	t.Run("invalid zcap max chain depth", func(t *testing.T) {
+		args := []string{
+			"--" + hostURLFlagName, "localhost:8080",
+			"--" + common.DatabaseURLFlagName, "mem://test",
+			"--" + common.DatabasePrefixFlagName, "test",
+			"--" + didDomainFlagName, "testnet.orb.local",
+			"--" + common.ZCAPMaxChainDepthFlagName, "0",
+		}
+		startCmd := GetStartCmd(&mockServer{})
+
+		startCmd.SetArgs(args)
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid zcap-max-chain-depth 0")
+	})
 }
 
 func TestStartCmdWithBlankEnvVar(t *testing.T) {
@@ -101,6 +296,9 @@ func TestStartCmdValidArgs(t *testing.T) {
 		"--" + common.DatabasePrefixFlagName, "test",
 		"--" + didDomainFlagName, "testnet.orb.local",
 		"--" + requestTokensFlagName, "token2=tk2=1",
+		"--" + exactNumbersFlagName, "true",
+		"--" + deniedPathsFlagName, "$.ssn",
+		"--" + denyModeFlagName, "refuse",
 	}
 	startCmd.SetArgs(args)
 
@@ -108,6 +306,91 @@ func TestStartCmdValidArgs(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestUpstreamAuthTokens(t *testing.T) {
+	t.Run("parses host=token pairs", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		require.NoError(t, startCmd.ParseFlags([]string{
+			"--" + upstreamAuthTokensFlagName, "edv.example.com=edvToken",
+			"--" + upstreamAuthTokensFlagName, "kms.example.com:8443=kmsToken",
+			"--" + upstreamAuthTokensFlagName, "invalid",
+			"--" + upstreamAuthTokensFlagName, "invalid=to=ken",
+		}))
+
+		require.Equal(t, map[string]string{
+			"edv.example.com":      "edvToken",
+			"kms.example.com:8443": "kmsToken",
+		}, getTokens(startCmd, upstreamAuthTokensFlagName, upstreamAuthTokensEnvKey))
+	})
+
+	t.Run("EDV requests carry the token of their host", func(t *testing.T) {
+		var authHeader string
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authHeader = r.Header.Get("Authorization")
+
+			_, err := w.Write([]byte(`{"id":"docID"}`))
+			require.NoError(t, err)
+		}))
+		defer srv.Close()
+
+		u, err := url.Parse(srv.URL)
+		require.NoError(t, err)
+
+		transport := &upstreamAuthTransport{
+			base:   http.DefaultTransport,
+			tokens: map[string]string{u.Host: "edvToken"},
+		}
+		client := &http.Client{Transport: transport}
+
+		// the operations bind the requests to their deadline with a client wrapping the one given to the constructor
+		_, err = adaptedEDVClientConstructor(client)(srv.URL+"/encrypted-data-vaults",
+			edv.WithHTTPClient(&http.Client{Transport: transport})).ReadDocument("vaultID", "docID")
+		require.NoError(t, err)
+		require.Equal(t, "Bearer edvToken", authHeader)
+
+		transport.tokens = map[string]string{"other.example.com": "otherToken"}
+
+		_, err = adaptedEDVClientConstructor(client)(srv.URL+"/encrypted-data-vaults").ReadDocument("vaultID", "docID")
+		require.NoError(t, err)
+		require.Empty(t, authHeader)
+	})
+}
+
+func TestSecretLock(t *testing.T) {
+	args := []string{
+		"--" + hostURLFlagName, "localhost:8080",
+		"--" + common.DatabaseURLFlagName, "mem://test",
+		"--" + common.DatabasePrefixFlagName, "test",
+		"--" + didDomainFlagName, "testnet.orb.local",
+	}
+
+	t.Run("local", func(t *testing.T) {
+		keyPath := filepath.Join(t.TempDir(), "master.key")
+		require.NoError(t, os.WriteFile(keyPath, []byte("4lm2P8Bv7Q5xGNVXFVBwFRGmD5bQmAvYK5yRnKnKTwk="), 0o600))
+
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args,
+			"--"+common.SecretLockTypeFlagName, common.SecretLockTypeLocal,
+			"--"+common.SecretLockKeyPathFlagName, keyPath,
+		))
+
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("missing master key", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args,
+			"--"+common.SecretLockTypeFlagName, common.SecretLockTypeLocal,
+			"--"+common.SecretLockKeyPathFlagName, filepath.Join(t.TempDir(), "missing"),
+		))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to init secret lock")
+	})
+}
+
 func TestTLSInvalidArgs(t *testing.T) {
 	t.Run("test wrong tls cert pool flag", func(t *testing.T) {
 		startCmd := GetStartCmd(&mockServer{})
@@ -11,8 +11,12 @@ import (
 	"crypto/tls"
 	"fmt"
 	"net/http"
+	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	httptransport "github.com/go-openapi/runtime/client"
 	"github.com/go-openapi/strfmt"
@@ -34,12 +38,16 @@ import (
 	"github.com/trustbloc/ace/cmd/common"
 	"github.com/trustbloc/ace/pkg/client/csh/client"
 	vaultclient "github.com/trustbloc/ace/pkg/client/vault"
+	"github.com/trustbloc/ace/pkg/did"
 	"github.com/trustbloc/ace/pkg/gatekeeper/config"
+	"github.com/trustbloc/ace/pkg/gatekeeper/release"
 	"github.com/trustbloc/ace/pkg/restapi/gatekeeper"
 	"github.com/trustbloc/ace/pkg/restapi/handler"
 	"github.com/trustbloc/ace/pkg/restapi/healthcheck"
 	"github.com/trustbloc/ace/pkg/restapi/mw/httpsigmw"
+	"github.com/trustbloc/ace/pkg/restapi/mw/slowmw"
 	"github.com/trustbloc/ace/pkg/restapi/mw/tokenauth"
+	"github.com/trustbloc/ace/pkg/restapi/version"
 	"github.com/trustbloc/ace/pkg/vcissuer"
 )
 
@@ -124,6 +132,22 @@ const (
 	authTokenFlagUsage = "Bearer token used for a token protected api calls. " +
 		" Alternatively, this can be set with the following environment variable: " + authTokenEnvKey
 
+	releaseWorkersFlagName  = "release-workers"
+	releaseWorkersEnvKey    = "GK_RELEASE_WORKERS"
+	releaseWorkersFlagUsage = "Number of release ticket authorizations processed concurrently. Default: 4." +
+		" Alternatively, this can be set with the following environment variable: " + releaseWorkersEnvKey
+
+	releaseQueueDepthFlagName  = "release-queue-depth"
+	releaseQueueDepthEnvKey    = "GK_RELEASE_QUEUE_DEPTH"
+	releaseQueueDepthFlagUsage = "Number of release ticket authorizations waiting to be processed at most, beyond" +
+		" which authorizations are rejected with 503 Service Unavailable. Default: 100." +
+		" Alternatively, this can be set with the following environment variable: " + releaseQueueDepthEnvKey
+
+	// shutdownTimeout is how long the requests in flight are handled for once the server is asked to stop.
+	shutdownTimeout = 30 * time.Second
+	// releaseDrainTimeout is how long the queued ticket authorizations are processed for once the server stopped.
+	releaseDrainTimeout = 30 * time.Second
+
 	tokenLength2              = 2
 	vcsIssuerRequestTokenName = "vcs_issuer"
 	sidetreeRequestTokenName  = "sidetreeToken"
@@ -140,19 +164,24 @@ type tlsParameters struct {
 }
 
 type serviceParameters struct {
-	host                string
-	tlsParams           *tlsParameters
-	dbParams            *common.DBParameters
-	blocDomain          string
-	didResolverURL      string
-	contextProviderURLs []string
-	vcIssuerURL         string
-	vcIssuerProfile     string
-	vaultServerURL      string
-	didAnchorOrigin     string
-	cshURL              string
-	authToken           string
-	requestTokens       map[string]string
+	host                 string
+	tlsParams            *tlsParameters
+	dbParams             *common.DBParameters
+	blocDomain           string
+	didResolverURL       string
+	contextProviderURLs  []string
+	vcIssuerURL          string
+	vcIssuerProfile      string
+	vaultServerURL       string
+	didAnchorOrigin      string
+	cshURL               string
+	authToken            string
+	requestTokens        map[string]string
+	httpTransport        *common.HTTPTransportParameters
+	orbResolve           *common.OrbResolveParameters
+	slowRequestThreshold time.Duration
+	releaseWorkers       int
+	releaseQueueDepth    int
 }
 
 type server interface {
@@ -162,13 +191,45 @@ type server interface {
 // HTTPServer represents an actual HTTP server implementation.
 type HTTPServer struct{}
 
-// ListenAndServe starts the server using the standard Go HTTP server implementation.
+// ListenAndServe starts the server using the standard Go HTTP server implementation. On SIGINT or SIGTERM, the
+// server stops accepting connections and returns once the requests in flight are handled.
 func (s *HTTPServer) ListenAndServe(host, certFile, keyFile string, router http.Handler) error {
-	if certFile == "" || keyFile == "" {
-		return http.ListenAndServe(host, router)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return serve(ctx, &http.Server{Addr: host, Handler: router}, certFile, keyFile)
+}
+
+// serve serves until the server fails or the context is done, in which case the server is shut down gracefully.
+func serve(ctx context.Context, srv *http.Server, certFile, keyFile string) error {
+	served := make(chan error, 1)
+
+	go func() {
+		if certFile == "" || keyFile == "" {
+			served <- srv.ListenAndServe()
+
+			return
+		}
+
+		served <- srv.ListenAndServeTLS(certFile, keyFile)
+	}()
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
 	}
 
-	return http.ListenAndServeTLS(host, certFile, keyFile, router)
+	logger.Infof("shutting down the server")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shut down server: %w", err)
+	}
+
+	return nil
 }
 
 // GetStartCmd returns the Cobra start command.
@@ -290,26 +351,78 @@ func getParameters(cmd *cobra.Command) (*serviceParameters, error) { //nolint: f
 		return nil, err
 	}
 
+	httpTransport, err := common.HTTPTransportParams(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	orbResolve, err := common.OrbResolveParams(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	slowRequestThreshold, err := common.SlowRequestThreshold(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	releaseWorkers, releaseQueueDepth, err := getReleasePool(cmd)
+	if err != nil {
+		return nil, err
+	}
+
 	authToken, err := cmdutils.GetUserSetVarFromString(cmd, authTokenFlagName,
 		authTokenEnvKey, true)
 
 	return &serviceParameters{
-		host:                host,
-		tlsParams:           tlsParams,
-		dbParams:            dbParams,
-		blocDomain:          blocDomain,
-		didResolverURL:      didResolverURL,
-		contextProviderURLs: contextProviderURLs,
-		vcIssuerURL:         vcIssuerURL,
-		vcIssuerProfile:     vcIssuerProfile,
-		vaultServerURL:      vaultServerURL,
-		didAnchorOrigin:     didAnchorOrigin,
-		cshURL:              cshURL,
-		authToken:           authToken,
-		requestTokens:       requestTokens,
+		host:                 host,
+		tlsParams:            tlsParams,
+		dbParams:             dbParams,
+		blocDomain:           blocDomain,
+		didResolverURL:       didResolverURL,
+		contextProviderURLs:  contextProviderURLs,
+		vcIssuerURL:          vcIssuerURL,
+		vcIssuerProfile:      vcIssuerProfile,
+		vaultServerURL:       vaultServerURL,
+		didAnchorOrigin:      didAnchorOrigin,
+		cshURL:               cshURL,
+		authToken:            authToken,
+		requestTokens:        requestTokens,
+		httpTransport:        httpTransport,
+		orbResolve:           orbResolve,
+		slowRequestThreshold: slowRequestThreshold,
+		releaseWorkers:       releaseWorkers,
+		releaseQueueDepth:    releaseQueueDepth,
 	}, err
 }
 
+func getReleasePool(cmd *cobra.Command) (int, int, error) {
+	workers, depth := release.DefaultWorkers, release.DefaultQueueDepth
+
+	for _, p := range []struct {
+		flagName string
+		envKey   string
+		value    *int
+	}{
+		{releaseWorkersFlagName, releaseWorkersEnvKey, &workers},
+		{releaseQueueDepthFlagName, releaseQueueDepthEnvKey, &depth},
+	} {
+		value := cmdutils.GetUserSetOptionalVarFromString(cmd, p.flagName, p.envKey)
+		if value == "" {
+			continue
+		}
+
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return 0, 0, fmt.Errorf("invalid %s %s: must be a positive number", p.flagName, value)
+		}
+
+		*p.value = n
+	}
+
+	return workers, depth, nil
+}
+
 func createFlags(cmd *cobra.Command) {
 	cmd.Flags().StringP(hostURLFlagName, hostURLFlagShorthand, "", hostURLFlagUsage)
 	cmd.Flags().StringP(tlsSystemCertPoolFlagName, "", "", tlsSystemCertPoolFlagUsage)
@@ -326,8 +439,13 @@ func createFlags(cmd *cobra.Command) {
 	cmd.Flags().StringP(vcIssuerProfileFlagName, "", "", vcIssuerProfileFlagUsage)
 	cmd.Flags().StringArrayP(requestTokensFlagName, "", []string{}, requestTokensFlagUsage)
 	cmd.Flags().StringP(authTokenFlagName, "", "", authTokenFlagUsage)
+	cmd.Flags().StringP(releaseWorkersFlagName, "", "", releaseWorkersFlagUsage)
+	cmd.Flags().StringP(releaseQueueDepthFlagName, "", "", releaseQueueDepthFlagUsage)
 
 	common.Flags(cmd)
+	common.HTTPTransportFlags(cmd)
+	common.OrbResolveFlags(cmd)
+	common.SlowRequestThresholdFlags(cmd)
 }
 
 func startService(params *serviceParameters, srv server) error { // nolint: funlen,gocyclo
@@ -353,12 +471,15 @@ func startService(params *serviceParameters, srv server) error { // nolint: funl
 		router.HandleFunc(handler.Path(), handler.Handle()).Methods(handler.Method())
 	}
 
-	httpClient := &http.Client{Transport: &http.Transport{
-		TLSClientConfig: tlsConfig,
-	}}
+	// add version endpoint
+	for _, handler := range version.New().GetOperations() {
+		router.HandleFunc(handler.Path(), handler.Handle()).Methods(handler.Method())
+	}
+
+	httpClient := &http.Client{Transport: common.NewHTTPTransport(tlsConfig, params.httpTransport)}
 
 	vdr, err := createVDR(params.didResolverURL, params.blocDomain, params.requestTokens[sidetreeRequestTokenName],
-		httpClient)
+		httpClient, params.orbResolve)
 	if err != nil {
 		return err
 	}
@@ -412,11 +533,24 @@ func startService(params *serviceParameters, srv server) error { // nolint: funl
 		VDR:                    vdr,
 		VCIssuer:               vcIssuer,
 		ConfidentialStorageHub: cshClient,
+		ReleaseWorkers:         params.releaseWorkers,
+		ReleaseQueueDepth:      params.releaseQueueDepth,
 	})
 	if err != nil {
 		return err
 	}
 
+	// the authorizations queued when the server stops, including by the requests handled during its shutdown, are
+	// still processed
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), releaseDrainTimeout)
+		defer cancel()
+
+		if closeErr := service.Close(ctx); closeErr != nil {
+			logger.Warnf("release queue not drained: %s", closeErr)
+		}
+	}()
+
 	httpSigMW := httpsigmw.New(&httpsigmw.Config{
 		VDR: vdr,
 	})
@@ -468,6 +602,8 @@ func startService(params *serviceParameters, srv server) error { // nolint: funl
 		}
 	}
 
+	router.Use(slowmw.New(params.slowRequestThreshold, logger))
+
 	// start server on given port and serve using given handlers
 	return srv.ListenAndServe(params.host, params.tlsParams.serveCertPath, params.tlsParams.serveKeyPath,
 		cors.New(cors.Options{
@@ -522,7 +658,8 @@ func getRequestTokens(cmd *cobra.Command) (map[string]string, error) {
 	return tokens, nil
 }
 
-func createVDR(didResolverURL, blocDomain, sidetreeToken string, httpClient *http.Client) (vdrapi.Registry, error) {
+func createVDR(didResolverURL, blocDomain, sidetreeToken string, httpClient *http.Client,
+	orbResolve *common.OrbResolveParameters) (vdrapi.Registry, error) {
 	var opts []vdrpkg.Option
 
 	if didResolverURL != "" {
@@ -546,7 +683,8 @@ func createVDR(didResolverURL, blocDomain, sidetreeToken string, httpClient *htt
 			return nil, err
 		}
 
-		opts = append(opts, vdrpkg.WithVDR(vdr))
+		// orb resolutions time out and are retried, so that a transient orb outage does not hang the requests
+		opts = append(opts, vdrpkg.WithVDR(did.NewResolvingVDR(vdr, orbResolve.Opts()...)))
 	}
 
 	return vdrpkg.New(opts...), nil
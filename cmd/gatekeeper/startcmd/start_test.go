@@ -6,9 +6,11 @@ SPDX-License-Identifier: Apache-2.0
 package startcmd //nolint:testpackage
 
 import (
+	"context"
 	"net/http"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -28,6 +30,27 @@ func TestListenAndServe(t *testing.T) {
 	require.Contains(t, err.Error(), "address wronghost: missing port in address")
 }
 
+func TestServe(t *testing.T) {
+	t.Run("shuts down once the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		served := make(chan error)
+
+		go func() {
+			served <- serve(ctx, &http.Server{Addr: "127.0.0.1:0", Handler: http.NotFoundHandler()}, "", "")
+		}()
+
+		cancel()
+
+		select {
+		case err := <-served:
+			require.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("the server did not shut down")
+		}
+	})
+}
+
 func TestStartCmdWithBlankArg(t *testing.T) {
 	t.Run("test blank host url arg", func(t *testing.T) {
 		startCmd := GetStartCmd(&mockServer{})
@@ -136,6 +159,10 @@ func TestStartCmdValidArgs(t *testing.T) {
 		"--" + didAnchorOriginFlagName, "https://did-anchor-orign",
 		"--" + cshURLFlagName, "https://csh-url",
 		"--" + vcIssuerProfileFlagName, "test-profile",
+		"--" + common.OrbResolveTimeoutFlagName, "5s",
+		"--" + common.OrbResolveRetriesFlagName, "2",
+		"--" + releaseWorkersFlagName, "8",
+		"--" + releaseQueueDepthFlagName, "500",
 	}
 	startCmd.SetArgs(args)
 
@@ -158,3 +185,49 @@ func TestTLSInvalidArgs(t *testing.T) {
 		require.Contains(t, err.Error(), "invalid syntax")
 	})
 }
+
+func TestOrbResolveInvalidArgs(t *testing.T) {
+	startCmd := GetStartCmd(&mockServer{})
+
+	args := []string{
+		"--" + hostURLFlagName, "localhost:8080",
+		"--" + common.DatabaseURLFlagName, "mem://test",
+		"--" + common.DatabasePrefixFlagName, "test_",
+		"--" + vaultServerURLFlagName, "https://vault-server-url",
+		"--" + vcIssuerURLFlagName, "https://vc-isssuer-url",
+		"--" + didAnchorOriginFlagName, "https://did-anchor-orign",
+		"--" + cshURLFlagName, "https://csh-url",
+		"--" + vcIssuerProfileFlagName, "test-profile",
+		"--" + common.OrbResolveTimeoutFlagName, "soon",
+	}
+	startCmd.SetArgs(args)
+
+	err := startCmd.Execute()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid did-orb-resolve-timeout soon")
+}
+
+func TestReleasePoolInvalidArgs(t *testing.T) {
+	for _, flagName := range []string{releaseWorkersFlagName, releaseQueueDepthFlagName} {
+		t.Run(flagName, func(t *testing.T) {
+			startCmd := GetStartCmd(&mockServer{})
+
+			args := []string{
+				"--" + hostURLFlagName, "localhost:8080",
+				"--" + common.DatabaseURLFlagName, "mem://test",
+				"--" + common.DatabasePrefixFlagName, "test_",
+				"--" + vaultServerURLFlagName, "https://vault-server-url",
+				"--" + vcIssuerURLFlagName, "https://vc-isssuer-url",
+				"--" + didAnchorOriginFlagName, "https://did-anchor-orign",
+				"--" + cshURLFlagName, "https://csh-url",
+				"--" + vcIssuerProfileFlagName, "test-profile",
+				"--" + flagName, "0",
+			}
+			startCmd.SetArgs(args)
+
+			err := startCmd.Execute()
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "invalid "+flagName+" 0: must be a positive number")
+		})
+	}
+}
@@ -7,9 +7,12 @@ SPDX-License-Identifier: Apache-2.0
 package startcmd
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -22,10 +25,10 @@ import (
 	"github.com/hyperledger/aries-framework-go-ext/component/vdr/orb"
 	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
 	ldrest "github.com/hyperledger/aries-framework-go/pkg/controller/rest/ld"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
 	"github.com/hyperledger/aries-framework-go/pkg/kms/localkms"
 	ldsvc "github.com/hyperledger/aries-framework-go/pkg/ld"
 	"github.com/hyperledger/aries-framework-go/pkg/secretlock"
-	"github.com/hyperledger/aries-framework-go/pkg/secretlock/noop"
 	ariesvdr "github.com/hyperledger/aries-framework-go/pkg/vdr"
 	vdrkey "github.com/hyperledger/aries-framework-go/pkg/vdr/key"
 	"github.com/hyperledger/aries-framework-go/spi/storage"
@@ -35,10 +38,14 @@ import (
 	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
 	tlsutils "github.com/trustbloc/edge-core/pkg/utils/tls"
 
+	"github.com/trustbloc/ace/cmd/common"
 	"github.com/trustbloc/ace/pkg/ld"
 	"github.com/trustbloc/ace/pkg/restapi/healthcheck"
+	"github.com/trustbloc/ace/pkg/restapi/mw/gzipmw"
+	"github.com/trustbloc/ace/pkg/restapi/mw/slowmw"
 	"github.com/trustbloc/ace/pkg/restapi/vault"
 	"github.com/trustbloc/ace/pkg/restapi/vault/operation"
+	"github.com/trustbloc/ace/pkg/restapi/version"
 )
 
 const (
@@ -117,21 +124,159 @@ const (
 	requestTokensFlagUsage = "Tokens used for http request " +
 		" Alternatively, this can be set with the following environment variable: " + requestTokensEnvKey
 
+	maxDocSizeFlagName  = "max-doc-size"
+	maxDocSizeEnvKey    = "VAULT_MAX_DOC_SIZE"
+	maxDocSizeFlagUsage = "Maximum size, in bytes, of the documents saved in vaults. Larger requests are rejected" +
+		" with 413. Default: " + maxDocSizeDefault + " (10 MiB)." +
+		" Alternatively, this can be set with the following environment variable: " + maxDocSizeEnvKey
+	maxDocSizeDefault = "10485760"
+
+	docChunkSizeFlagName  = "doc-chunk-size"
+	docChunkSizeEnvKey    = "VAULT_DOC_CHUNK_SIZE"
+	docChunkSizeFlagUsage = "Size, in bytes, of the chunks binary documents are split into when saved. Larger" +
+		" documents are encrypted and stored one chunk at a time, bounding the memory used to save them." +
+		" Default: 1048576 (1 MiB)." +
+		" Alternatively, this can be set with the following environment variable: " + docChunkSizeEnvKey
+
+	docMetadataCacheTTLFlagName  = "doc-metadata-cache-ttl"
+	docMetadataCacheTTLEnvKey    = "VAULT_DOC_METADATA_CACHE_TTL"
+	docMetadataCacheTTLFlagUsage = "How long the metadata of the documents read by the comparator and gatekeeper" +
+		" is cached, eg. 30s. Saving or deleting a document drops its cached metadata." +
+		" Defaults to 0: metadata is not cached." +
+		" Alternatively, this can be set with the following environment variable: " + docMetadataCacheTTLEnvKey
+
+	docMetadataCacheSizeFlagName  = "doc-metadata-cache-size"
+	docMetadataCacheSizeEnvKey    = "VAULT_DOC_METADATA_CACHE_SIZE"
+	docMetadataCacheSizeFlagUsage = "Number of documents whose metadata is cached at most. Default: 1000." +
+		" Alternatively, this can be set with the following environment variable: " + docMetadataCacheSizeEnvKey
+
+	authorizationGCIntervalFlagName  = "authorization-gc-interval"
+	authorizationGCIntervalEnvKey    = "VAULT_AUTHORIZATION_GC_INTERVAL"
+	authorizationGCIntervalFlagUsage = "Interval at which the authorizations expired beyond their retention are" +
+		" deleted, eg. 30m. Default: 1h. Set to 0 to keep expired authorizations, eg. for auditing." +
+		" Alternatively, this can be set with the following environment variable: " + authorizationGCIntervalEnvKey
+
+	authorizationRetentionFlagName  = "authorization-retention"
+	authorizationRetentionEnvKey    = "VAULT_AUTHORIZATION_RETENTION"
+	authorizationRetentionFlagUsage = "How long expired authorizations are kept before being deleted, eg. 72h." +
+		" Default: 24h." +
+		" Alternatively, this can be set with the following environment variable: " + authorizationRetentionEnvKey
+
+	edvBackendsFlagName  = "edv-backends"
+	edvBackendsEnvKey    = "VAULT_EDV_BACKENDS"
+	edvBackendsFlagUsage = "Additional EDV backends vaults can be created in, in the format name=url," +
+		" eg. new=https://edv2.example.com. The EDV URL is the backend named default." +
+		" Alternatively, this can be set with the following environment variable: " + edvBackendsEnvKey
+
+	defaultEDVBackendFlagName  = "default-edv-backend"
+	defaultEDVBackendEnvKey    = "VAULT_DEFAULT_EDV_BACKEND"
+	defaultEDVBackendFlagUsage = "Name of the EDV backend new vaults are created in. Existing vaults keep using" +
+		" the backend they were created in. Default: default, the EDV URL." +
+		" Alternatively, this can be set with the following environment variable: " + defaultEDVBackendEnvKey
+
+	adminTokenFlagName  = "admin-token"
+	adminTokenEnvKey    = "VAULT_ADMIN_TOKEN" //nolint: gosec
+	adminTokenFlagUsage = "Bearer token of the administrators, required to pin the EDV backend of new vaults." +
+		" Alternatively, this can be set with the following environment variable: " + adminTokenEnvKey
+
+	docIDStrategyFlagName  = "doc-id-strategy"
+	docIDStrategyEnvKey    = "VAULT_DOC_ID_STRATEGY"
+	docIDStrategyFlagUsage = "How the IDs of saved documents are generated: edv-compatible, uuid or" +
+		" caller-supplied-required. IDs supplied by callers must match the strategy. Default: EDV-compatible IDs" +
+		" are generated and IDs of any format are accepted." +
+		" Alternatively, this can be set with the following environment variable: " + docIDStrategyEnvKey
+
+	requireInvocationAuthFlagName  = "require-invocation-auth"
+	requireInvocationAuthEnvKey    = "VAULT_REQUIRE_INVOCATION_AUTH"
+	requireInvocationAuthFlagUsage = "Require the requests acting on a vault, its documents or its authorizations to" +
+		" invoke the capability of the vault signed by its invoker or to be signed by the vault's controller." +
+		" Importing vaults then requires the admin token, and creating vaults a signature by their owner." +
+		" Possible values [true] [false]. Defaults to false: these endpoints are open to anyone." +
+		" Alternatively, this can be set with the following environment variable: " + requireInvocationAuthEnvKey
+
+	disableContentDigestsFlagName  = "disable-content-digests"
+	disableContentDigestsEnvKey    = "VAULT_DISABLE_CONTENT_DIGESTS"
+	disableContentDigestsFlagUsage = "Do not record the SHA-256 digests of the saved documents, returned in their" +
+		" metadata, for deployments considering that revealing whether two documents are equal is sensitive." +
+		" Possible values [true] [false]. Defaults to false." +
+		" Alternatively, this can be set with the following environment variable: " + disableContentDigestsEnvKey
+
+	verifyCredentialsFlagName  = "verify-credentials"
+	verifyCredentialsEnvKey    = "VAULT_VERIFY_CREDENTIALS"
+	verifyCredentialsFlagUsage = "Verify the proofs of the verifiable credentials saved in vaults against the DIDs" +
+		" of their issuers, rejecting the credentials whose proofs are missing or invalid." +
+		" Possible values [true] [false]. Defaults to false." +
+		" Alternatively, this can be set with the following environment variable: " + verifyCredentialsEnvKey
+
+	requireKeyProofFlagName  = "require-key-proof"
+	requireKeyProofEnvKey    = "VAULT_REQUIRE_KEY_PROOF"
+	requireKeyProofFlagUsage = "Require the requesting parties of new authorizations to prove that they control a" +
+		" key of their DID, by signing a challenge issued by the server. Otherwise, proofs are only verified if given." +
+		" Possible values [true] [false]. Defaults to false." +
+		" Alternatively, this can be set with the following environment variable: " + requireKeyProofEnvKey
+
+	challengeTTLFlagName  = "key-proof-challenge-ttl"
+	challengeTTLEnvKey    = "VAULT_KEY_PROOF_CHALLENGE_TTL"
+	challengeTTLFlagUsage = "How long the challenges issued to prove key control can be answered, eg. 2m." +
+		" Default: 5m." +
+		" Alternatively, this can be set with the following environment variable: " + challengeTTLEnvKey
+
+	keyTypeFlagName  = "key-type"
+	keyTypeEnvKey    = "VAULT_KEY_TYPE"
+	keyTypeFlagUsage = "Type of the key-wrapping keys the documents of new vaults are encrypted to, unless a vault" +
+		" is created with its own. Possible values [NISTP256ECDHKW] [NISTP384ECDHKW] [X25519ECDHKW]." +
+		" Defaults to NISTP256ECDHKW. Existing vaults keep the type they were created with." +
+		" Alternatively, this can be set with the following environment variable: " + keyTypeEnvKey
+
+	kmsURLsFlagName  = "kms-urls"
+	kmsURLsEnvKey    = "VAULT_KMS_URLS"
+	kmsURLsFlagUsage = "Base URLs of the WebKMS instances vaults can be created in instead of the remote KMS," +
+		" eg. https://kms2.example.com. Existing vaults keep using the KMS they were created in." +
+		" Alternatively, this can be set with the following environment variable: " + kmsURLsEnvKey
+
+	legacyRecordsFlagName  = "legacy-records"
+	legacyRecordsEnvKey    = "VAULT_LEGACY_RECORDS"
+	legacyRecordsFlagUsage = "Path to a file listing the keys of the records saved by the first versions, which" +
+		" were not tagged by vault, one per line: meta_doc_info_<vault ID>_<doc ID> for documents and" +
+		" authorization_<vault ID>_<authorization ID> for authorizations. The records are tagged at startup so" +
+		" that they are listed, counted and deleted with their vaults. The file must list all of them:" +
+		" the vaults created by the first versions cannot be deleted until then." +
+		" Alternatively, this can be set with the following environment variable: " + legacyRecordsEnvKey
+
 	splitRequestTokenLength = 2
 )
 
 var logger = log.New("vault-server")
 
 type serviceParameters struct {
-	host            string
-	remoteKMSURL    string
-	edvURL          string
-	didDomain       string
-	didMethod       string
-	tlsParams       *tlsParameters
-	dsnParams       *dsnParams
-	didAnchorOrigin string
-	requestTokens   map[string]string
+	host                  string
+	remoteKMSURL          string
+	edvURL                string
+	didDomain             string
+	didMethod             string
+	tlsParams             *tlsParameters
+	dsnParams             *dsnParams
+	didAnchorOrigin       string
+	requestTokens         map[string]string
+	secretLock            *common.SecretLockParameters
+	maxDocSize            int64
+	docChunkSize          int
+	docMetadataCacheTTL   time.Duration
+	authorizationGC       *authorizationGCParameters
+	docMetadataCacheSize  int
+	edvBackends           map[string]string
+	defaultBackend        string
+	adminToken            string
+	docIDStrategy         operation.DocIDStrategy
+	requireInvocationAuth bool
+	disableContentDigests bool
+	verifyCredentials     bool
+	requireKeyProof       bool
+	challengeTTL          time.Duration
+	keyType               kms.KeyType
+	kmsURLs               []string
+	slowRequestThreshold  time.Duration
+	legacyRecords         string
 }
 
 type dsnParams struct {
@@ -147,6 +292,12 @@ type tlsParameters struct {
 	serveKeyPath   string
 }
 
+// authorizationGCParameters configure the deletion of expired authorizations: an interval of 0 disables it.
+type authorizationGCParameters struct {
+	interval  time.Duration
+	retention time.Duration
+}
+
 // nolint:gochecknoglobals
 var supportedStorageProviders = map[string]func(string, string) (storage.Provider, error){
 	"couchdb": func(dsn, prefix string) (storage.Provider, error) {
@@ -243,19 +394,282 @@ func getParameters(cmd *cobra.Command) (*serviceParameters, error) {
 
 	requestTokens := getRequestTokens(cmd)
 
+	secretLock, err := common.SecretLockParams(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	maxDocSize, err := getMaxDocSize(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	docChunkSize, err := getDocChunkSize(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	docMetadataCacheTTL, docMetadataCacheSize, err := getDocMetadataCache(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	edvBackends, defaultBackend, err := getEDVBackends(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	adminToken := cmdutils.GetUserSetOptionalVarFromString(cmd, adminTokenFlagName, adminTokenEnvKey)
+
+	docIDStrategy, err := getDocIDStrategy(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	requireInvocationAuth, err := getBool(cmd, requireInvocationAuthFlagName, requireInvocationAuthEnvKey)
+	if err != nil {
+		return nil, err
+	}
+
+	disableContentDigests, err := getBool(cmd, disableContentDigestsFlagName, disableContentDigestsEnvKey)
+	if err != nil {
+		return nil, err
+	}
+
+	verifyCredentials, err := getBool(cmd, verifyCredentialsFlagName, verifyCredentialsEnvKey)
+	if err != nil {
+		return nil, err
+	}
+
+	requireKeyProof, err := getBool(cmd, requireKeyProofFlagName, requireKeyProofEnvKey)
+	if err != nil {
+		return nil, err
+	}
+
+	challengeTTL, err := getChallengeTTL(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	authorizationGC, err := getAuthorizationGC(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	keyType, err := getKeyType(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	slowRequestThreshold, err := common.SlowRequestThreshold(cmd)
+	if err != nil {
+		return nil, err
+	}
+
 	return &serviceParameters{
-		host:            host,
-		remoteKMSURL:    remoteKMSURL,
-		didDomain:       didDomain,
-		didMethod:       didMethod,
-		edvURL:          edvURL,
-		dsnParams:       dsn,
-		tlsParams:       tlsParams,
-		didAnchorOrigin: didAnchorOrigin,
-		requestTokens:   requestTokens,
+		host:                  host,
+		remoteKMSURL:          remoteKMSURL,
+		didDomain:             didDomain,
+		didMethod:             didMethod,
+		edvURL:                edvURL,
+		dsnParams:             dsn,
+		tlsParams:             tlsParams,
+		didAnchorOrigin:       didAnchorOrigin,
+		requestTokens:         requestTokens,
+		secretLock:            secretLock,
+		maxDocSize:            maxDocSize,
+		docChunkSize:          docChunkSize,
+		docMetadataCacheTTL:   docMetadataCacheTTL,
+		authorizationGC:       authorizationGC,
+		docMetadataCacheSize:  docMetadataCacheSize,
+		edvBackends:           edvBackends,
+		defaultBackend:        defaultBackend,
+		adminToken:            adminToken,
+		docIDStrategy:         docIDStrategy,
+		requireInvocationAuth: requireInvocationAuth,
+		disableContentDigests: disableContentDigests,
+		verifyCredentials:     verifyCredentials,
+		requireKeyProof:       requireKeyProof,
+		challengeTTL:          challengeTTL,
+		keyType:               keyType,
+		kmsURLs:               cmdutils.GetUserSetOptionalVarFromArrayString(cmd, kmsURLsFlagName, kmsURLsEnvKey),
+		legacyRecords:         cmdutils.GetUserSetOptionalVarFromString(cmd, legacyRecordsFlagName, legacyRecordsEnvKey),
+		slowRequestThreshold:  slowRequestThreshold,
 	}, err
 }
 
+func getEDVBackends(cmd *cobra.Command) (map[string]string, string, error) {
+	backends := make(map[string]string)
+
+	for _, backend := range cmdutils.GetUserSetOptionalVarFromArrayString(cmd, edvBackendsFlagName,
+		edvBackendsEnvKey) {
+		split := strings.SplitN(backend, "=", splitRequestTokenLength)
+		if len(split) != splitRequestTokenLength || split[0] == "" || split[1] == "" {
+			return nil, "", fmt.Errorf("invalid %s %s: must be name=url", edvBackendsFlagName, backend)
+		}
+
+		if _, ok := backends[split[0]]; ok || split[0] == vault.DefaultEDVBackend {
+			return nil, "", fmt.Errorf("invalid %s %s: duplicate name %s", edvBackendsFlagName, backend, split[0])
+		}
+
+		backends[split[0]] = split[1]
+	}
+
+	defaultBackend := cmdutils.GetUserSetOptionalVarFromString(cmd, defaultEDVBackendFlagName, defaultEDVBackendEnvKey)
+	if defaultBackend == "" {
+		defaultBackend = vault.DefaultEDVBackend
+	}
+
+	if _, ok := backends[defaultBackend]; !ok && defaultBackend != vault.DefaultEDVBackend {
+		return nil, "", fmt.Errorf("invalid %s %s: unknown EDV backend", defaultEDVBackendFlagName, defaultBackend)
+	}
+
+	return backends, defaultBackend, nil
+}
+
+func getDocIDStrategy(cmd *cobra.Command) (operation.DocIDStrategy, error) {
+	name := cmdutils.GetUserSetOptionalVarFromString(cmd, docIDStrategyFlagName, docIDStrategyEnvKey)
+	if name == "" {
+		return "", nil
+	}
+
+	strategy, err := operation.ParseDocIDStrategy(name)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s %s: %w", docIDStrategyFlagName, name, err)
+	}
+
+	return strategy, nil
+}
+
+func getKeyType(cmd *cobra.Command) (kms.KeyType, error) {
+	keyType := kms.KeyType(cmdutils.GetUserSetOptionalVarFromString(cmd, keyTypeFlagName, keyTypeEnvKey))
+	if keyType == "" {
+		return vault.DefaultKeyType, nil
+	}
+
+	err := vault.CheckKeyType(keyType)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s %s: %w", keyTypeFlagName, keyType, err)
+	}
+
+	return keyType, nil
+}
+
+// getBool returns the boolean value of the flag, false if it is not set.
+func getBool(cmd *cobra.Command, flagName, envKey string) (bool, error) {
+	value := cmdutils.GetUserSetOptionalVarFromString(cmd, flagName, envKey)
+	if value == "" {
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s %s: %w", flagName, value, err)
+	}
+
+	return b, nil
+}
+
+func getMaxDocSize(cmd *cobra.Command) (int64, error) {
+	maxDocSize := cmdutils.GetUserSetOptionalVarFromString(cmd, maxDocSizeFlagName, maxDocSizeEnvKey)
+
+	if maxDocSize == "" {
+		maxDocSize = maxDocSizeDefault
+	}
+
+	size, err := strconv.ParseInt(maxDocSize, 10, 64)
+	if err != nil || size <= 0 {
+		return 0, fmt.Errorf("invalid %s %s: must be a positive number of bytes", maxDocSizeFlagName, maxDocSize)
+	}
+
+	return size, nil
+}
+
+func getDocChunkSize(cmd *cobra.Command) (int, error) {
+	docChunkSize := cmdutils.GetUserSetOptionalVarFromString(cmd, docChunkSizeFlagName, docChunkSizeEnvKey)
+
+	if docChunkSize == "" {
+		return vault.DefaultChunkSize, nil
+	}
+
+	size, err := strconv.Atoi(docChunkSize)
+	if err != nil || size <= 0 {
+		return 0, fmt.Errorf("invalid %s %s: must be a positive number of bytes", docChunkSizeFlagName, docChunkSize)
+	}
+
+	return size, nil
+}
+
+func getChallengeTTL(cmd *cobra.Command) (time.Duration, error) {
+	value := cmdutils.GetUserSetOptionalVarFromString(cmd, challengeTTLFlagName, challengeTTLEnvKey)
+	if value == "" {
+		return vault.DefaultChallengeTTL, nil
+	}
+
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl <= 0 {
+		return 0, fmt.Errorf("invalid %s %s: must be a positive duration", challengeTTLFlagName, value)
+	}
+
+	return ttl, nil
+}
+
+func getAuthorizationGC(cmd *cobra.Command) (*authorizationGCParameters, error) {
+	params := &authorizationGCParameters{
+		interval:  vault.DefaultAuthorizationGCInterval,
+		retention: vault.DefaultAuthorizationRetention,
+	}
+
+	for _, p := range []struct {
+		flagName string
+		envKey   string
+		value    *time.Duration
+	}{
+		{authorizationGCIntervalFlagName, authorizationGCIntervalEnvKey, &params.interval},
+		{authorizationRetentionFlagName, authorizationRetentionEnvKey, &params.retention},
+	} {
+		value := cmdutils.GetUserSetOptionalVarFromString(cmd, p.flagName, p.envKey)
+		if value == "" {
+			continue
+		}
+
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid %s %s: must be a non-negative duration", p.flagName, value)
+		}
+
+		*p.value = d
+	}
+
+	return params, nil
+}
+
+func getDocMetadataCache(cmd *cobra.Command) (time.Duration, int, error) {
+	var (
+		ttl  time.Duration
+		size int
+		err  error
+	)
+
+	if value := cmdutils.GetUserSetOptionalVarFromString(cmd, docMetadataCacheTTLFlagName,
+		docMetadataCacheTTLEnvKey); value != "" {
+		ttl, err = time.ParseDuration(value)
+		if err != nil || ttl < 0 {
+			return 0, 0, fmt.Errorf("invalid %s %s: must be a non-negative duration", docMetadataCacheTTLFlagName, value)
+		}
+	}
+
+	if value := cmdutils.GetUserSetOptionalVarFromString(cmd, docMetadataCacheSizeFlagName,
+		docMetadataCacheSizeEnvKey); value != "" {
+		size, err = strconv.Atoi(value)
+		if err != nil || size <= 0 {
+			return 0, 0, fmt.Errorf("invalid %s %s: must be a positive number", docMetadataCacheSizeFlagName, value)
+		}
+	}
+
+	return ttl, size, nil
+}
+
 func getTLS(cmd *cobra.Command) (*tlsParameters, error) {
 	tlsSystemCertPoolString := cmdutils.GetUserSetOptionalVarFromString(cmd, tlsSystemCertPoolFlagName,
 		tlsSystemCertPoolEnvKey)
@@ -319,6 +733,26 @@ func createFlags(cmd *cobra.Command) {
 	cmd.Flags().StringP(didMethodFlagName, "", "key", didMethodFlagUsage)
 	cmd.Flags().StringP(didAnchorOriginFlagName, "", "", didAnchorOriginFlagUsage)
 	cmd.Flags().StringArrayP(requestTokensFlagName, "", []string{}, requestTokensFlagUsage)
+	cmd.Flags().StringP(maxDocSizeFlagName, "", "", maxDocSizeFlagUsage)
+	cmd.Flags().StringP(docChunkSizeFlagName, "", "", docChunkSizeFlagUsage)
+	cmd.Flags().StringP(docMetadataCacheTTLFlagName, "", "", docMetadataCacheTTLFlagUsage)
+	cmd.Flags().StringP(authorizationGCIntervalFlagName, "", "", authorizationGCIntervalFlagUsage)
+	cmd.Flags().StringP(authorizationRetentionFlagName, "", "", authorizationRetentionFlagUsage)
+	cmd.Flags().StringP(docMetadataCacheSizeFlagName, "", "", docMetadataCacheSizeFlagUsage)
+	cmd.Flags().StringArrayP(edvBackendsFlagName, "", []string{}, edvBackendsFlagUsage)
+	cmd.Flags().StringP(defaultEDVBackendFlagName, "", "", defaultEDVBackendFlagUsage)
+	cmd.Flags().StringP(adminTokenFlagName, "", "", adminTokenFlagUsage)
+	cmd.Flags().StringP(docIDStrategyFlagName, "", "", docIDStrategyFlagUsage)
+	cmd.Flags().StringP(requireInvocationAuthFlagName, "", "", requireInvocationAuthFlagUsage)
+	cmd.Flags().StringP(disableContentDigestsFlagName, "", "", disableContentDigestsFlagUsage)
+	cmd.Flags().StringP(verifyCredentialsFlagName, "", "", verifyCredentialsFlagUsage)
+	cmd.Flags().StringP(requireKeyProofFlagName, "", "", requireKeyProofFlagUsage)
+	cmd.Flags().StringP(challengeTTLFlagName, "", "", challengeTTLFlagUsage)
+	cmd.Flags().StringP(keyTypeFlagName, "", "", keyTypeFlagUsage)
+	cmd.Flags().StringArrayP(kmsURLsFlagName, "", []string{}, kmsURLsFlagUsage)
+	cmd.Flags().StringP(legacyRecordsFlagName, "", "", legacyRecordsFlagUsage)
+	common.SecretLockFlags(cmd)
+	common.SlowRequestThresholdFlags(cmd)
 }
 
 const (
@@ -339,6 +773,31 @@ func (k kmsProvider) SecretLock() secretlock.Service {
 	return k.secretLock
 }
 
+// tagLegacyRecords tags the records whose keys are listed in the file, one per line.
+func tagLegacyRecords(vaultClient *vault.Client, path string) error {
+	src, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return fmt.Errorf("read legacy records: %w", err)
+	}
+
+	var keys []string
+
+	for _, line := range strings.Split(string(src), "\n") {
+		if key := strings.TrimSpace(line); key != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	tagged, err := vaultClient.TagLegacyRecords(keys)
+	if err != nil {
+		return fmt.Errorf("tag legacy records: %w", err)
+	}
+
+	logger.Infof("tagged %d of %d legacy records", tagged, len(keys))
+
+	return nil
+}
+
 func startService(params *serviceParameters, srv server) error { // nolint: funlen
 	rootCAs, err := tlsutils.GetCertPool(params.tlsParams.systemCertPool, params.tlsParams.caCerts)
 	if err != nil {
@@ -350,9 +809,14 @@ func startService(params *serviceParameters, srv server) error { // nolint: funl
 		return err
 	}
 
+	secretLock, err := common.CreateSecretLock(params.secretLock)
+	if err != nil {
+		return err
+	}
+
 	keyManager, err := localkms.New(keystorePrimaryKeyURI, &kmsProvider{
 		storageProvider: storeProvider,
-		secretLock:      &noop.NoLock{},
+		secretLock:      secretLock,
 	})
 	if err != nil {
 		return fmt.Errorf("localkms new: %w", err)
@@ -383,12 +847,7 @@ func startService(params *serviceParameters, srv server) error { // nolint: funl
 		return err
 	}
 
-	vaultClient, err := vault.NewClient(
-		params.remoteKMSURL,
-		params.edvURL,
-		keyManager,
-		storeProvider,
-		loader,
+	vaultOpts := []vault.Opt{
 		vault.WithRegistry(ariesvdr.New(
 			ariesvdr.WithVDR(vdrkey.New()),
 			ariesvdr.WithVDR(vdrBloc),
@@ -396,24 +855,92 @@ func startService(params *serviceParameters, srv server) error { // nolint: funl
 		vault.WithDidAnchorOrigin(params.didAnchorOrigin),
 		vault.WithDidDomain(params.didDomain),
 		vault.WithDidMethod(params.didMethod),
+		vault.WithEDVBackends(params.edvBackends, params.defaultBackend),
+		vault.WithKeyType(params.keyType),
+		vault.WithKMSURLs(params.kmsURLs),
+		vault.WithChunkSize(params.docChunkSize),
+		vault.WithDocMetadataCache(params.docMetadataCacheTTL, params.docMetadataCacheSize),
+		vault.WithChallengeTTL(params.challengeTTL),
 		vault.WithHTTPClient(&http.Client{
 			Timeout: time.Minute,
 			Transport: &http.Transport{
 				TLSClientConfig: tCfg,
 			},
 		}),
+		vault.WithWebhookHTTPClient(vault.NewWebhookHTTPClient(tCfg)),
+	}
+
+	if params.disableContentDigests {
+		vaultOpts = append(vaultOpts, vault.WithoutContentDigests())
+	}
+
+	if params.verifyCredentials {
+		vaultOpts = append(vaultOpts, vault.WithCredentialVerification())
+	}
+
+	if params.requireKeyProof {
+		vaultOpts = append(vaultOpts, vault.WithRequiredKeyProof())
+	}
+
+	vaultClient, err := vault.NewClient(
+		params.remoteKMSURL,
+		params.edvURL,
+		keyManager,
+		storeProvider,
+		loader,
+		vaultOpts...,
 	)
 	if err != nil {
 		return fmt.Errorf("vault new client: %w", err)
 	}
 
-	service := operation.New(vaultClient)
+	// pending webhook deliveries are recorded as dead letters when the server stops
+	defer vaultClient.Close()
+
+	if params.legacyRecords != "" {
+		// before the migration, which then finds the vaults of the records tagged
+		err = tagLegacyRecords(vaultClient, params.legacyRecords)
+		if err != nil {
+			return err
+		}
+	}
+
+	// indexes the vaults saved by older versions by controller
+	migrated, err := vaultClient.MigrateVaults()
+	if err != nil {
+		return fmt.Errorf("migrate vaults: %w", err)
+	}
+
+	logger.Infof("checked the records of %d vaults", migrated)
+
+	// the sweeper stops when the server does
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if params.authorizationGC.interval > 0 {
+		go vaultClient.SweepAuthorizations(ctx, params.authorizationGC.interval, params.authorizationGC.retention)
+	}
+
+	opts := []operation.Option{
+		operation.WithMaxDocSize(params.maxDocSize),
+		operation.WithAdminToken(params.adminToken),
+		operation.WithDocIDStrategy(params.docIDStrategy),
+	}
+
+	if params.requireInvocationAuth {
+		opts = append(opts, operation.WithInvocationVerifier(vaultClient))
+	}
+
+	service := operation.New(vaultClient, opts...)
 	handlers := service.GetRESTHandlers()
 
 	// add health check endpoint
 	healthCheckService := healthcheck.New()
 	handlers = append(handlers, healthCheckService.GetOperations()...)
 
+	// add version endpoint
+	handlers = append(handlers, version.New().GetOperations()...)
+
 	router := mux.NewRouter()
 
 	for _, handler := range handlers {
@@ -424,6 +951,12 @@ func startService(params *serviceParameters, srv server) error { // nolint: funl
 		router.HandleFunc(handler.Path(), handler.Handle()).Methods(handler.Method())
 	}
 
+	// the elapsed time of slow requests includes the compression of their responses
+	router.Use(slowmw.New(params.slowRequestThreshold, logger))
+
+	// gzip request bodies are decompressed and responses compressed for clients accepting gzip
+	router.Use(gzipmw.New(gzipmw.DefaultMaxDecompressedSize))
+
 	// start server on given port and serve using given handlers
 	return srv.ListenAndServe(params.host,
 		params.tlsParams.serveCertPath,
@@ -433,14 +966,20 @@ func startService(params *serviceParameters, srv server) error { // nolint: funl
 				http.MethodHead,
 				http.MethodGet,
 				http.MethodPost,
+				http.MethodPatch,
 				http.MethodDelete,
 			},
 			AllowedHeaders: []string{
 				"Origin",
 				"Accept",
 				"Content-Type",
+				"Content-Encoding",
 				"X-Requested-With",
 				"Authorization",
+				"Capability-Invocation",
+				"Signature",
+				"Digest",
+				"If-Match",
 			},
 		}).Handler(router))
 }
@@ -9,9 +9,12 @@ package startcmd
 import (
 	"net/http"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/ace/cmd/common"
 )
 
 func TestListenAndServe(t *testing.T) {
@@ -69,6 +72,23 @@ func TestStartCmdValidArgs(t *testing.T) {
 		"--" + edvURLFlagName, "localhost:8082",
 		"--" + datasourceNameFlagName, "mem://test",
 		"--" + requestTokensFlagName, "token2=tk2=1",
+		"--" + edvBackendsFlagName, "new=localhost:8083",
+		"--" + defaultEDVBackendFlagName, "new",
+		"--" + adminTokenFlagName, "admin",
+		"--" + docIDStrategyFlagName, "uuid",
+		"--" + requireInvocationAuthFlagName, "true",
+		"--" + disableContentDigestsFlagName, "true",
+		"--" + verifyCredentialsFlagName, "true",
+		"--" + requireKeyProofFlagName, "true",
+		"--" + challengeTTLFlagName, "2m",
+		"--" + authorizationGCIntervalFlagName, "30m",
+		"--" + authorizationRetentionFlagName, "72h",
+		"--" + docChunkSizeFlagName, "65536",
+		"--" + docMetadataCacheTTLFlagName, "30s",
+		"--" + docMetadataCacheSizeFlagName, "500",
+		"--" + keyTypeFlagName, "X25519ECDHKW",
+		"--" + kmsURLsFlagName, "localhost:8084",
+		"--" + legacyRecordsFlagName, legacyRecordsFile(t, "meta_doc_info_did:example:vault_doc1\n\n"),
 	}
 	startCmd.SetArgs(args)
 
@@ -76,6 +96,20 @@ func TestStartCmdValidArgs(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestStartCmdAuthorizationGCDisabled(t *testing.T) {
+	startCmd := GetStartCmd(&mockServer{})
+
+	startCmd.SetArgs([]string{
+		"--" + hostURLFlagName, "localhost:8080",
+		"--" + remoteKMSURLFlagName, "localhost:8081",
+		"--" + edvURLFlagName, "localhost:8082",
+		"--" + datasourceNameFlagName, "mem://test",
+		"--" + authorizationGCIntervalFlagName, "0",
+	})
+
+	require.NoError(t, startCmd.Execute())
+}
+
 func TestStartCmdEmptyDomain(t *testing.T) {
 	startCmd := GetStartCmd(&mockServer{})
 
@@ -155,6 +189,381 @@ func TestDSN(t *testing.T) {
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "failed to parse dsn timeout")
 	})
+
+	t.Run("Bad max doc size", func(t *testing.T) {
+		for _, size := range []string{"10MB", "0", "-1"} {
+			startCmd := GetStartCmd(&mockServer{})
+
+			args := []string{
+				"--" + hostURLFlagName, "localhost:8080",
+				"--" + remoteKMSURLFlagName, "localhost:8081",
+				"--" + edvURLFlagName, "localhost:8082",
+				"--" + datasourceNameFlagName, "mem://test",
+				"--" + maxDocSizeFlagName, size,
+			}
+			startCmd.SetArgs(args)
+
+			err := startCmd.Execute()
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "invalid max-doc-size "+size)
+		}
+	})
+
+	t.Run("Bad doc chunk size", func(t *testing.T) {
+		for _, size := range []string{"1MB", "0", "-1"} {
+			startCmd := GetStartCmd(&mockServer{})
+
+			args := []string{
+				"--" + hostURLFlagName, "localhost:8080",
+				"--" + remoteKMSURLFlagName, "localhost:8081",
+				"--" + edvURLFlagName, "localhost:8082",
+				"--" + datasourceNameFlagName, "mem://test",
+				"--" + docChunkSizeFlagName, size,
+			}
+			startCmd.SetArgs(args)
+
+			err := startCmd.Execute()
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "invalid doc-chunk-size "+size)
+		}
+	})
+
+	t.Run("Bad doc metadata cache", func(t *testing.T) {
+		for _, tc := range []struct {
+			flag  string
+			value string
+		}{
+			{docMetadataCacheTTLFlagName, "30"},
+			{docMetadataCacheTTLFlagName, "-1s"},
+			{docMetadataCacheSizeFlagName, "many"},
+			{docMetadataCacheSizeFlagName, "0"},
+		} {
+			startCmd := GetStartCmd(&mockServer{})
+
+			args := []string{
+				"--" + hostURLFlagName, "localhost:8080",
+				"--" + remoteKMSURLFlagName, "localhost:8081",
+				"--" + edvURLFlagName, "localhost:8082",
+				"--" + datasourceNameFlagName, "mem://test",
+				"--" + tc.flag, tc.value,
+			}
+			startCmd.SetArgs(args)
+
+			err := startCmd.Execute()
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "invalid "+tc.flag+" "+tc.value)
+		}
+	})
+
+	t.Run("Bad EDV backends", func(t *testing.T) {
+		for _, tc := range []struct {
+			backends       []string
+			defaultBackend string
+			msg            string
+		}{
+			{backends: []string{"new"}, msg: "invalid edv-backends new: must be name=url"},
+			{backends: []string{"=localhost:8083"}, msg: "invalid edv-backends =localhost:8083: must be name=url"},
+			{
+				backends: []string{"new=localhost:8083", "new=localhost:8084"},
+				msg:      "invalid edv-backends new=localhost:8084: duplicate name new",
+			},
+			{
+				backends: []string{"default=localhost:8083"},
+				msg:      "invalid edv-backends default=localhost:8083: duplicate name default",
+			},
+			{
+				backends:       []string{"new=localhost:8083"},
+				defaultBackend: "other",
+				msg:            "invalid default-edv-backend other: unknown EDV backend",
+			},
+		} {
+			startCmd := GetStartCmd(&mockServer{})
+
+			args := []string{
+				"--" + hostURLFlagName, "localhost:8080",
+				"--" + remoteKMSURLFlagName, "localhost:8081",
+				"--" + edvURLFlagName, "localhost:8082",
+				"--" + datasourceNameFlagName, "mem://test",
+			}
+
+			for _, backend := range tc.backends {
+				args = append(args, "--"+edvBackendsFlagName, backend)
+			}
+
+			if tc.defaultBackend != "" {
+				args = append(args, "--"+defaultEDVBackendFlagName, tc.defaultBackend)
+			}
+
+			startCmd.SetArgs(args)
+
+			err := startCmd.Execute()
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.msg)
+		}
+	})
+
+	t.Run("Bad doc ID strategy", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		args := []string{
+			"--" + hostURLFlagName, "localhost:8080",
+			"--" + remoteKMSURLFlagName, "localhost:8081",
+			"--" + edvURLFlagName, "localhost:8082",
+			"--" + datasourceNameFlagName, "mem://test",
+			"--" + docIDStrategyFlagName, "ulid",
+		}
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid doc-id-strategy ulid")
+	})
+
+	t.Run("Bad require invocation auth", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		args := []string{
+			"--" + hostURLFlagName, "localhost:8080",
+			"--" + remoteKMSURLFlagName, "localhost:8081",
+			"--" + edvURLFlagName, "localhost:8082",
+			"--" + datasourceNameFlagName, "mem://test",
+			"--" + requireInvocationAuthFlagName, "maybe",
+		}
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid require-invocation-auth maybe")
+	})
+
+	t.Run("Bad disable content digests", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		args := []string{
+			"--" + hostURLFlagName, "localhost:8080",
+			"--" + remoteKMSURLFlagName, "localhost:8081",
+			"--" + edvURLFlagName, "localhost:8082",
+			"--" + datasourceNameFlagName, "mem://test",
+			"--" + disableContentDigestsFlagName, "maybe",
+		}
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid disable-content-digests maybe")
+	})
+
+	t.Run("Bad verify credentials", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		args := []string{
+			"--" + hostURLFlagName, "localhost:8080",
+			"--" + remoteKMSURLFlagName, "localhost:8081",
+			"--" + edvURLFlagName, "localhost:8082",
+			"--" + datasourceNameFlagName, "mem://test",
+			"--" + verifyCredentialsFlagName, "maybe",
+		}
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid verify-credentials maybe")
+	})
+
+	t.Run("Bad authorization GC", func(t *testing.T) {
+		for _, tc := range []struct {
+			flag  string
+			value string
+		}{
+			{authorizationGCIntervalFlagName, "hourly"},
+			{authorizationGCIntervalFlagName, "-1h"},
+			{authorizationRetentionFlagName, "-1h"},
+		} {
+			startCmd := GetStartCmd(&mockServer{})
+
+			args := []string{
+				"--" + hostURLFlagName, "localhost:8080",
+				"--" + remoteKMSURLFlagName, "localhost:8081",
+				"--" + edvURLFlagName, "localhost:8082",
+				"--" + datasourceNameFlagName, "mem://test",
+				"--" + tc.flag, tc.value,
+			}
+			startCmd.SetArgs(args)
+
+			err := startCmd.Execute()
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "invalid "+tc.flag+" "+tc.value)
+		}
+	})
+
+	t.Run("Bad key proof", func(t *testing.T) {
+		for _, tc := range []struct {
+			flag  string
+			value string
+		}{
+			{requireKeyProofFlagName, "maybe"},
+			{challengeTTLFlagName, "soon"},
+			{challengeTTLFlagName, "0s"},
+		} {
+			startCmd := GetStartCmd(&mockServer{})
+
+			args := []string{
+				"--" + hostURLFlagName, "localhost:8080",
+				"--" + remoteKMSURLFlagName, "localhost:8081",
+				"--" + edvURLFlagName, "localhost:8082",
+				"--" + datasourceNameFlagName, "mem://test",
+				"--" + tc.flag, tc.value,
+			}
+			startCmd.SetArgs(args)
+
+			err := startCmd.Execute()
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "invalid "+tc.flag+" "+tc.value)
+		}
+	})
+
+	t.Run("Unsupported key type", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		args := []string{
+			"--" + hostURLFlagName, "localhost:8080",
+			"--" + remoteKMSURLFlagName, "localhost:8081",
+			"--" + edvURLFlagName, "localhost:8082",
+			"--" + datasourceNameFlagName, "mem://test",
+			"--" + keyTypeFlagName, "ED25519",
+		}
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid key-type ED25519: unsupported key type")
+	})
+
+	t.Run("Invalid slow request threshold", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		args := []string{
+			"--" + hostURLFlagName, "localhost:8080",
+			"--" + remoteKMSURLFlagName, "localhost:8081",
+			"--" + edvURLFlagName, "localhost:8082",
+			"--" + datasourceNameFlagName, "mem://test",
+			"--" + common.SlowRequestThresholdFlagName, "soon",
+		}
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid slow-request-threshold soon")
+	})
+}
+
+func TestStartCmdLegacyRecords(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		path string
+		err  string
+	}{
+		{"Missing file", filepath.Join(t.TempDir(), "missing"), "read legacy records"},
+		{"Unsupported key", legacyRecordsFile(t, "info_did:example:vault\n"), "unsupported record key"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			startCmd := GetStartCmd(&mockServer{})
+
+			startCmd.SetArgs([]string{
+				"--" + hostURLFlagName, "localhost:8080",
+				"--" + remoteKMSURLFlagName, "localhost:8081",
+				"--" + edvURLFlagName, "localhost:8082",
+				"--" + datasourceNameFlagName, "mem://test",
+				"--" + legacyRecordsFlagName, tc.path,
+			})
+
+			err := startCmd.Execute()
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.err)
+		})
+	}
+}
+
+func legacyRecordsFile(t *testing.T, keys string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "legacy-records")
+
+	require.NoError(t, os.WriteFile(path, []byte(keys), 0o600))
+
+	return path
+}
+
+func TestSecretLock(t *testing.T) {
+	args := []string{
+		"--" + hostURLFlagName, "localhost:8080",
+		"--" + remoteKMSURLFlagName, "localhost:8081",
+		"--" + edvURLFlagName, "localhost:8082",
+		"--" + datasourceNameFlagName, "mem://test",
+	}
+
+	t.Run("local", func(t *testing.T) {
+		keyPath := filepath.Join(t.TempDir(), "master.key")
+		require.NoError(t, os.WriteFile(keyPath, []byte("4lm2P8Bv7Q5xGNVXFVBwFRGmD5bQmAvYK5yRnKnKTwk="), 0o600))
+
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args,
+			"--"+common.SecretLockTypeFlagName, common.SecretLockTypeLocal,
+			"--"+common.SecretLockKeyPathFlagName, keyPath,
+		))
+
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("unsupported type", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+common.SecretLockTypeFlagName, "unsupported"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unsupported secret lock type")
+	})
+
+	t.Run("missing master key", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args,
+			"--"+common.SecretLockTypeFlagName, common.SecretLockTypeLocal,
+			"--"+common.SecretLockKeyPathFlagName, filepath.Join(t.TempDir(), "missing"),
+		))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to read master key")
+	})
+
+	t.Run("master key of the wrong length", func(t *testing.T) {
+		keyPath := filepath.Join(t.TempDir(), "master.key")
+		require.NoError(t, os.WriteFile(keyPath, []byte("4lm2P8Bv7Q5xGNVXFVBwFQ=="), 0o600))
+
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args,
+			"--"+common.SecretLockTypeFlagName, common.SecretLockTypeLocal,
+			"--"+common.SecretLockKeyPathFlagName, keyPath,
+		))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "must be 32 bytes, got 16")
+	})
+
+	t.Run("noop fallback", func(t *testing.T) {
+		keyPath := filepath.Join(t.TempDir(), "master.key")
+		require.NoError(t, os.WriteFile(keyPath, []byte("4lm2P8Bv7Q5xGNVXFVBwFRGmD5bQmAvYK5yRnKnKTwk="), 0o600))
+
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args,
+			"--"+common.SecretLockTypeFlagName, common.SecretLockTypeLocal,
+			"--"+common.SecretLockKeyPathFlagName, keyPath,
+			"--"+common.SecretLockNoopFallbackFlagName, "true",
+		))
+
+		require.NoError(t, startCmd.Execute())
+	})
 }
 
 func TestTLSInvalidArgs(t *testing.T) {
@@ -0,0 +1,38 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package buildinfo holds the build metadata of the services. The values are set at build time, e.g.:
+//
+//	go build -ldflags "-X github.com/trustbloc/ace/pkg/buildinfo.Version=v1.0.0 \
+//	  -X github.com/trustbloc/ace/pkg/buildinfo.Commit=$(git rev-parse HEAD) \
+//	  -X github.com/trustbloc/ace/pkg/buildinfo.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+package buildinfo
+
+// nolint:gochecknoglobals
+var (
+	// Version of the build.
+	Version = "dev"
+	// Commit is the git commit the build is based on.
+	Commit = "unknown"
+	// BuildDate is the time of the build.
+	BuildDate = "unknown"
+)
+
+// Info is the build metadata.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"buildDate"`
+}
+
+// Get returns the build metadata.
+func Get() *Info {
+	return &Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+	}
+}
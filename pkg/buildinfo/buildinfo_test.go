@@ -0,0 +1,19 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package buildinfo_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/ace/pkg/buildinfo"
+)
+
+func TestGet(t *testing.T) {
+	require.Equal(t, &buildinfo.Info{Version: "dev", Commit: "unknown", BuildDate: "unknown"}, buildinfo.Get())
+}
@@ -39,6 +39,8 @@ type ClientService interface {
 
 	PostAuthorizations(params *PostAuthorizationsParams, opts ...ClientOption) (*PostAuthorizationsOK, error)
 
+	PostAuthorizationsVerify(params *PostAuthorizationsVerifyParams, opts ...ClientOption) (*PostAuthorizationsVerifyOK, error)
+
 	PostCompare(params *PostCompareParams, opts ...ClientOption) (*PostCompareOK, error)
 
 	PostExtract(params *PostExtractParams, opts ...ClientOption) (*PostExtractOK, error)
@@ -130,6 +132,48 @@ func (a *Client) PostAuthorizations(params *PostAuthorizationsParams, opts ...Cl
 	panic(msg)
 }
 
+/*
+  PostAuthorizationsVerify Verify an authorization token previously issued by this Comparator without executing a comparison.
+
+The token's signature, issuer, parent capability, allowed actions, invocation target and caveats are checked
+against the Comparator's configuration. The remote Confidential Storage Hub and Vault Server are not contacted.
+
+*/
+func (a *Client) PostAuthorizationsVerify(params *PostAuthorizationsVerifyParams, opts ...ClientOption) (*PostAuthorizationsVerifyOK, error) {
+	// TODO: Validate the params before sending
+	if params == nil {
+		params = NewPostAuthorizationsVerifyParams()
+	}
+	op := &runtime.ClientOperation{
+		ID:                 "PostAuthorizationsVerify",
+		Method:             "POST",
+		PathPattern:        "/authorizations/verify",
+		ProducesMediaTypes: []string{"application/json"},
+		ConsumesMediaTypes: []string{"application/json"},
+		Schemes:            []string{"http"},
+		Params:             params,
+		Reader:             &PostAuthorizationsVerifyReader{formats: a.formats},
+		Context:            params.Context,
+		Client:             params.HTTPClient,
+	}
+	for _, opt := range opts {
+		opt(op)
+	}
+
+	result, err := a.transport.Submit(op)
+	if err != nil {
+		return nil, err
+	}
+	success, ok := result.(*PostAuthorizationsVerifyOK)
+	if ok {
+		return success, nil
+	}
+	// unexpected success response
+	// safeguard: normally, absent a default response, unknown success responses return an error above: so this is a codegen issue
+	msg := fmt.Sprintf("unexpected success response for PostAuthorizationsVerify: API contract not enforced by server. Client expected to get an error, but got: %T", result)
+	panic(msg)
+}
+
 /*
   PostCompare Execute a _remote_ comparison of the Confidential Storage documents fetched with the credentials provided.
 This comparison is performed remotely by the Confidential Storage hub using the credentials.
@@ -31,6 +31,9 @@ type Config struct {
 	// Required: true
 	Did *string `json:"did"`
 
+	// Whether the comparator's DID was resolvable with its signing key as a capabilityDelegation method at the last check.
+	DidResolvable bool `json:"didResolvable"`
+
 	// A JWK Set containing the primary public/private key pair.
 	// Required: true
 	Key interface{} `json:"key"`
@@ -28,6 +28,10 @@ import (
 //
 // swagger:model Extract
 type Extract struct {
+
+	// Include the source metadata of each extracted document in the response.
+	IncludeMetadata bool `json:"includeMetadata,omitempty"`
+
 	queriesField []Query
 }
 
@@ -44,6 +48,10 @@ func (m *Extract) SetQueries(val []Query) {
 // UnmarshalJSON unmarshals this object with a polymorphic type from a JSON structure
 func (m *Extract) UnmarshalJSON(raw []byte) error {
 	var data struct {
+
+		// Include the source metadata of each extracted document in the response.
+		IncludeMetadata bool `json:"includeMetadata,omitempty"`
+
 		Queries json.RawMessage `json:"queries"`
 	}
 	buf := bytes.NewBuffer(raw)
@@ -61,6 +69,9 @@ func (m *Extract) UnmarshalJSON(raw []byte) error {
 
 	var result Extract
 
+	// includeMetadata
+	result.IncludeMetadata = data.IncludeMetadata
+
 	// queries
 	result.queriesField = propQueries
 
@@ -74,7 +85,13 @@ func (m Extract) MarshalJSON() ([]byte, error) {
 	var b1, b2, b3 []byte
 	var err error
 	b1, err = json.Marshal(struct {
-	}{})
+
+		// Include the source metadata of each extracted document in the response.
+		IncludeMetadata bool `json:"includeMetadata,omitempty"`
+	}{
+
+		IncludeMetadata: m.IncludeMetadata,
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -133,15 +133,71 @@ type ExtractRespDocumentsItems0 struct {
 
 	// id
 	ID string `json:"id,omitempty"`
+
+	// source
+	Source *ExtractionSource `json:"source,omitempty"`
 }
 
 // Validate validates this extract resp documents items0
 func (m *ExtractRespDocumentsItems0) Validate(formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.validateSource(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *ExtractRespDocumentsItems0) validateSource(formats strfmt.Registry) error {
+	if swag.IsZero(m.Source) { // not required
+		return nil
+	}
+
+	if m.Source != nil {
+		if err := m.Source.Validate(formats); err != nil {
+			if ve, ok := err.(*errors.Validation); ok {
+				return ve.ValidateName("source")
+			} else if ce, ok := err.(*errors.CompositeError); ok {
+				return ce.ValidateName("source")
+			}
+			return err
+		}
+	}
+
 	return nil
 }
 
-// ContextValidate validates this extract resp documents items0 based on context it is used
+// ContextValidate validate this extract resp documents items0 based on the context it is used
 func (m *ExtractRespDocumentsItems0) ContextValidate(ctx context.Context, formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.contextValidateSource(ctx, formats); err != nil {
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *ExtractRespDocumentsItems0) contextValidateSource(ctx context.Context, formats strfmt.Registry) error {
+
+	if m.Source != nil {
+		if err := m.Source.ContextValidate(ctx, formats); err != nil {
+			if ve, ok := err.(*errors.Validation); ok {
+				return ve.ValidateName("source")
+			} else if ce, ok := err.(*errors.CompositeError); ok {
+				return ce.ValidateName("source")
+			}
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -19,6 +19,7 @@ import (
 	"github.com/go-openapi/runtime"
 	cr "github.com/go-openapi/runtime/client"
 	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
 
 	"github.com/trustbloc/ace/pkg/client/csh/models"
 )
@@ -66,6 +67,12 @@ func NewPostExtractParamsWithHTTPClient(client *http.Client) *PostExtractParams
 */
 type PostExtractParams struct {
 
+	/* IncludeMetadata.
+
+	   Include the non-secret metadata of the source documents in the extractions.
+	*/
+	IncludeMetadata *bool
+
 	// Request.
 	Request []models.Query
 
@@ -122,6 +129,17 @@ func (o *PostExtractParams) SetHTTPClient(client *http.Client) {
 	o.HTTPClient = client
 }
 
+// WithIncludeMetadata adds the includeMetadata to the post extract params
+func (o *PostExtractParams) WithIncludeMetadata(includeMetadata *bool) *PostExtractParams {
+	o.SetIncludeMetadata(includeMetadata)
+	return o
+}
+
+// SetIncludeMetadata adds the includeMetadata to the post extract params
+func (o *PostExtractParams) SetIncludeMetadata(includeMetadata *bool) {
+	o.IncludeMetadata = includeMetadata
+}
+
 // WithRequest adds the request to the post extract params
 func (o *PostExtractParams) WithRequest(request []models.Query) *PostExtractParams {
 	o.SetRequest(request)
@@ -140,6 +158,23 @@ func (o *PostExtractParams) WriteToRequest(r runtime.ClientRequest, reg strfmt.R
 		return err
 	}
 	var res []error
+
+	if o.IncludeMetadata != nil {
+
+		// query param include_metadata
+		var qrIncludeMetadata bool
+
+		if o.IncludeMetadata != nil {
+			qrIncludeMetadata = *o.IncludeMetadata
+		}
+		qIncludeMetadata := swag.FormatBool(qrIncludeMetadata)
+		if qIncludeMetadata != "" {
+
+			if err := r.SetQueryParam("include_metadata", qIncludeMetadata); err != nil {
+				return err
+			}
+		}
+	}
 	if o.Request != nil {
 		if err := r.SetBodyParam(o.Request); err != nil {
 			return err
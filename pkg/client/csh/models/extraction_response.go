@@ -82,20 +82,85 @@ func (m ExtractionResponse) ContextValidate(ctx context.Context, formats strfmt.
 // swagger:model ExtractionResponseItems0
 type ExtractionResponseItems0 struct {
 
+	// doc ID
+	DocID string `json:"docID,omitempty"`
+
 	// document
 	Document interface{} `json:"document,omitempty"`
 
 	// id
 	ID string `json:"id,omitempty"`
+
+	// metadata
+	Metadata *ExtractionMetadata `json:"metadata,omitempty"`
+
+	// path
+	Path string `json:"path,omitempty"`
+
+	// vault ID
+	VaultID string `json:"vaultID,omitempty"`
 }
 
 // Validate validates this extraction response items0
 func (m *ExtractionResponseItems0) Validate(formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.validateMetadata(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
 	return nil
 }
 
-// ContextValidate validates this extraction response items0 based on context it is used
+func (m *ExtractionResponseItems0) validateMetadata(formats strfmt.Registry) error {
+	if swag.IsZero(m.Metadata) { // not required
+		return nil
+	}
+
+	if m.Metadata != nil {
+		if err := m.Metadata.Validate(formats); err != nil {
+			if ve, ok := err.(*errors.Validation); ok {
+				return ve.ValidateName("metadata")
+			} else if ce, ok := err.(*errors.CompositeError); ok {
+				return ce.ValidateName("metadata")
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ContextValidate validate this extraction response items0 based on the context it is used
 func (m *ExtractionResponseItems0) ContextValidate(ctx context.Context, formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.contextValidateMetadata(ctx, formats); err != nil {
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *ExtractionResponseItems0) contextValidateMetadata(ctx context.Context, formats strfmt.Registry) error {
+
+	if m.Metadata != nil {
+		if err := m.Metadata.ContextValidate(ctx, formats); err != nil {
+			if ve, ok := err.(*errors.Validation); ok {
+				return ve.ValidateName("metadata")
+			} else if ce, ok := err.(*errors.CompositeError); ok {
+				return ce.ValidateName("metadata")
+			}
+			return err
+		}
+	}
+
 	return nil
 }
 
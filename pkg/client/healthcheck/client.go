@@ -0,0 +1,205 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package healthcheck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/trustbloc/edge-core/pkg/log"
+)
+
+const (
+	// HealthCheckPath is the path of the healthcheck endpoint exposed by the ACE services.
+	HealthCheckPath = "/healthcheck"
+	// LivenessPath is the path of the liveness endpoint, which checks no dependency of the service.
+	LivenessPath = "/livez"
+	// ReadinessPath is the path of the readiness endpoint.
+	ReadinessPath = "/readiness"
+
+	defaultTimeout         = time.Minute
+	defaultInitialInterval = 500 * time.Millisecond
+	defaultMaxInterval     = 5 * time.Second
+)
+
+var logger = log.New("healthcheck-client")
+
+// ErrNotReady is returned when the service did not become healthy before the deadline.
+var ErrNotReady = errors.New("service not ready")
+
+// HTTPClient interface for the http client.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Status is the status reported by the service.
+type Status struct {
+	StatusCode  int       `json:"-"`
+	Status      string    `json:"status"`
+	CurrentTime time.Time `json:"currentTime"`
+}
+
+// Healthy returns true if the service reported a healthy status.
+func (s *Status) Healthy() bool {
+	return s != nil && s.StatusCode == http.StatusOK
+}
+
+// Client polls the healthcheck endpoint of an ACE service.
+type Client struct {
+	httpClient      HTTPClient
+	baseURL         string
+	path            string
+	timeout         time.Duration
+	initialInterval time.Duration
+	maxInterval     time.Duration
+}
+
+// New returns new instance of healthcheck client.
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		httpClient: &http.Client{
+			Timeout: time.Minute,
+		},
+		baseURL:         baseURL,
+		path:            HealthCheckPath,
+		timeout:         defaultTimeout,
+		initialInterval: defaultInitialInterval,
+		maxInterval:     defaultMaxInterval,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Check performs a single request to the healthcheck endpoint and returns the reported status.
+func (c *Client) Check(ctx context.Context) (*Status, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+c.path, http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http do: %w", err)
+	}
+
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			logger.Warnf("failed to close response body")
+		}
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response body: %w", err)
+	}
+
+	status := &Status{StatusCode: resp.StatusCode}
+
+	if resp.StatusCode == http.StatusOK {
+		if err = json.Unmarshal(body, status); err != nil {
+			return nil, fmt.Errorf("unmarshal to Status: %w", err)
+		}
+	}
+
+	return status, nil
+}
+
+// WaitUntilReady polls the healthcheck endpoint with backoff until the service reports healthy or the deadline
+// is reached. The last observed status is returned in both cases.
+func (c *Client) WaitUntilReady(ctx context.Context) (*Status, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = c.initialInterval
+	b.MaxInterval = c.maxInterval
+	b.MaxElapsedTime = 0
+
+	var (
+		status  *Status
+		lastErr error
+	)
+
+	err := backoff.RetryNotify(
+		func() error {
+			s, err := c.Check(ctx)
+			if err != nil {
+				lastErr = err
+
+				return err
+			}
+
+			status = s
+
+			if !s.Healthy() {
+				lastErr = fmt.Errorf("%w: status code %d", ErrNotReady, s.StatusCode)
+
+				return lastErr
+			}
+
+			return nil
+		},
+		backoff.WithContext(b, ctx),
+		func(err error, d time.Duration) {
+			logger.Debugf("%s%s not ready, retrying in %s: %s", c.baseURL, c.path, d, err)
+		},
+	)
+	if err != nil {
+		if lastErr == nil {
+			lastErr = err
+		}
+
+		if errors.Is(lastErr, ErrNotReady) {
+			return status, lastErr
+		}
+
+		return status, fmt.Errorf("%w: %s", ErrNotReady, lastErr)
+	}
+
+	return status, nil
+}
+
+// Option is a healthcheck client instance option.
+type Option func(opts *Client)
+
+// WithHTTPClient allows providing HTTP client.
+func WithHTTPClient(c HTTPClient) Option {
+	return func(opts *Client) {
+		opts.httpClient = c
+	}
+}
+
+// WithPath sets the path of the endpoint to poll. Defaults to HealthCheckPath.
+func WithPath(path string) Option {
+	return func(opts *Client) {
+		opts.path = path
+	}
+}
+
+// WithTimeout sets the deadline for WaitUntilReady. Defaults to one minute.
+func WithTimeout(timeout time.Duration) Option {
+	return func(opts *Client) {
+		opts.timeout = timeout
+	}
+}
+
+// WithRetryInterval sets the initial and maximum intervals between polls.
+func WithRetryInterval(initial, max time.Duration) Option {
+	return func(opts *Client) {
+		opts.initialInterval = initial
+		opts.maxInterval = max
+	}
+}
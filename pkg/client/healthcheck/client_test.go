@@ -0,0 +1,108 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package healthcheck_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/ace/pkg/client/healthcheck"
+)
+
+func TestClient_WaitUntilReady(t *testing.T) {
+	t.Run("success after service unavailable", func(t *testing.T) {
+		var calls int32
+
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, healthcheck.HealthCheckPath, r.URL.Path)
+
+			if atomic.AddInt32(&calls, 1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+
+				return
+			}
+
+			w.WriteHeader(http.StatusOK)
+			_, err := fmt.Fprint(w, `{"status":"success","currentTime":"2022-04-01T10:00:00Z"}`)
+			require.NoError(t, err)
+		}))
+		defer serv.Close()
+
+		c := healthcheck.New(serv.URL, healthcheck.WithRetryInterval(time.Millisecond, 5*time.Millisecond))
+
+		status, err := c.WaitUntilReady(context.Background())
+		require.NoError(t, err)
+		require.True(t, status.Healthy())
+		require.Equal(t, "success", status.Status)
+		require.EqualValues(t, 3, atomic.LoadInt32(&calls))
+	})
+
+	t.Run("custom path", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, healthcheck.ReadinessPath, r.URL.Path)
+			w.WriteHeader(http.StatusOK)
+			_, err := fmt.Fprint(w, `{"status":"success"}`)
+			require.NoError(t, err)
+		}))
+		defer serv.Close()
+
+		c := healthcheck.New(serv.URL, healthcheck.WithPath(healthcheck.ReadinessPath),
+			healthcheck.WithHTTPClient(&http.Client{}))
+
+		status, err := c.WaitUntilReady(context.Background())
+		require.NoError(t, err)
+		require.True(t, status.Healthy())
+	})
+
+	t.Run("error if deadline is reached", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer serv.Close()
+
+		c := healthcheck.New(serv.URL,
+			healthcheck.WithTimeout(50*time.Millisecond),
+			healthcheck.WithRetryInterval(time.Millisecond, 5*time.Millisecond),
+		)
+
+		status, err := c.WaitUntilReady(context.Background())
+		require.ErrorIs(t, err, healthcheck.ErrNotReady)
+		require.NotNil(t, status)
+		require.False(t, status.Healthy())
+		require.Equal(t, http.StatusServiceUnavailable, status.StatusCode)
+	})
+
+	t.Run("error if service is unreachable", func(t *testing.T) {
+		c := healthcheck.New("", healthcheck.WithTimeout(20*time.Millisecond))
+
+		status, err := c.WaitUntilReady(context.Background())
+		require.ErrorIs(t, err, healthcheck.ErrNotReady)
+		require.Contains(t, err.Error(), "unsupported protocol scheme")
+		require.Nil(t, status)
+	})
+}
+
+func TestClient_Check(t *testing.T) {
+	t.Run("error from unmarshal response", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			_, err := fmt.Fprint(w, "wrongValue")
+			require.NoError(t, err)
+		}))
+		defer serv.Close()
+
+		_, err := healthcheck.New(serv.URL).Check(context.Background())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unmarshal to Status")
+	})
+}
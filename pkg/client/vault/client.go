@@ -9,12 +9,14 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"time"
 
+	"github.com/cenkalti/backoff/v4"
 	"github.com/trustbloc/edge-core/pkg/log"
 
 	"github.com/trustbloc/ace/pkg/restapi/vault"
@@ -24,31 +26,78 @@ import (
 const (
 	saveDocPath              = "/vaults/%s/docs"
 	getDocMetadataPath       = "/vaults/%s/docs/%s/metadata"
+	getDocMetadataByURIPath  = "/vaults/docs/metadata?uri=%s"
 	getAuthorizationsPath    = "/vaults/%s/authorizations/%s"
 	createAuthorizationsPath = "/vaults/%s/authorizations"
+	createChallengePath      = "/vaults/%s/authorizations/challenges"
+	useAuthorizationPath     = "/vaults/%s/authorizations/%s/uses"
+	getRevocationPath        = "/revocations/%s"
 )
 
+// IdempotencyKeyHeader is the header carrying the idempotency key of a request.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// CapabilityInvocationHeader is the header presenting the capability of a vault.
+const CapabilityInvocationHeader = "Capability-Invocation"
+
 var logger = log.New("vault-client")
 
+var errSign = errors.New("failed to sign request")
+
 // HTTPClient interface for the http client.
 type HTTPClient interface {
 	Do(req *http.Request) (*http.Response, error)
 }
 
-// Vault defines vault client interface.
+// Vault defines vault client interface. Requests are aborted once their context is done.
 type Vault interface {
-	CreateVault() (*vault.CreatedVault, error)
-	SaveDoc(vaultID, id string, content interface{}) (*vault.DocumentMetadata, error)
-	GetDocMetaData(vaultID, docID string) (*vault.DocumentMetadata, error)
-	CreateAuthorization(vaultID, requestingParty string,
+	CreateVault(ctx context.Context) (*vault.CreatedVault, error)
+	SaveDoc(ctx context.Context, vaultID, id string, content interface{}) (*vault.DocumentMetadata, error)
+	GetDocMetaData(ctx context.Context, vaultID, docID string) (*vault.DocumentMetadata, error)
+	GetDocMetaDataByURI(ctx context.Context, uri string) (*vault.DocumentMetadata, error)
+	CreateAuthorization(ctx context.Context, vaultID, requestingParty string,
 		scope *vault.AuthorizationsScope) (*vault.CreatedAuthorization, error)
-	GetAuthorization(vaultID, id string) (*vault.CreatedAuthorization, error)
+	GetAuthorization(ctx context.Context, vaultID, id string) (*vault.CreatedAuthorization, error)
+	UseAuthorization(ctx context.Context, vaultID, id string) (*vault.CreatedAuthorization, error)
+	DeleteAuthorization(ctx context.Context, vaultID, id string) error
+	IsRevoked(ctx context.Context, zcapID string) (bool, error)
 }
 
 // Client for vault.
 type Client struct {
-	httpClient HTTPClient
-	baseURL    string
+	httpClient  HTTPClient
+	baseURL     string
+	maxAttempts int
+	backoff     time.Duration
+	signer      func(*http.Request) (*http.Header, error)
+	headers     http.Header
+}
+
+type idempotencyKeyCtxKey struct{}
+
+type capabilityCtxKey struct{}
+
+type keyProofCtxKey struct{}
+
+// ContextWithIdempotencyKey returns a context making the vault client send the key in the Idempotency-Key header of
+// the requests made with the context. Calls that are not idempotent, eg. CreateVault or SaveDoc, are only retried
+// when they carry an idempotency key.
+func ContextWithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyCtxKey{}, key)
+}
+
+// ContextWithCapability returns a context making the vault client present the capability, the EDV authorization
+// token returned when the vault was created, in the Capability-Invocation header of the requests made with the
+// context. Vault servers requiring invocation authentication reject the requests acting on a vault without it, or
+// not signed by its invoker with a signer covering the header, see WithHTTPSigner.
+func ContextWithCapability(ctx context.Context, capability string) context.Context {
+	return context.WithValue(ctx, capabilityCtxKey{}, capability)
+}
+
+// ContextWithKeyProof returns a context making the vault client send the proof that the requesting party controls
+// a key of its DID with the authorizations created with the context, see CreateAuthorizationChallenge.
+func ContextWithKeyProof(ctx context.Context, proof *vault.KeyProof) context.Context {
+	return context.WithValue(ctx, keyProofCtxKey{}, proof)
 }
 
 // New return new instance of vault client.
@@ -68,8 +117,8 @@ func New(baseURL string, opts ...Option) *Client {
 }
 
 // CreateVault creates a new vault.
-func (c *Client) CreateVault() (*vault.CreatedVault, error) {
-	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, c.baseURL+operation.CreateVaultPath,
+func (c *Client) CreateVault(ctx context.Context) (*vault.CreatedVault, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+operation.CreateVaultPath,
 		http.NoBody)
 	if err != nil {
 		return nil, fmt.Errorf("new request: %w", err)
@@ -91,7 +140,7 @@ func (c *Client) CreateVault() (*vault.CreatedVault, error) {
 }
 
 // SaveDoc saves a document.
-func (c *Client) SaveDoc(vaultID, id string, content interface{}) (*vault.DocumentMetadata, error) {
+func (c *Client) SaveDoc(ctx context.Context, vaultID, id string, content interface{}) (*vault.DocumentMetadata, error) {
 	target := c.baseURL + fmt.Sprintf(saveDocPath, url.QueryEscape(vaultID))
 
 	raw, err := json.Marshal(content)
@@ -107,9 +156,7 @@ func (c *Client) SaveDoc(vaultID, id string, content interface{}) (*vault.Docume
 		return nil, fmt.Errorf("marshal: %w", err)
 	}
 
-	fmt.Printf("saved doc body %s", string(src))
-
-	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, target, bytes.NewReader(src))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(src))
 	if err != nil {
 		return nil, fmt.Errorf("new request: %w", err)
 	}
@@ -130,10 +177,34 @@ func (c *Client) SaveDoc(vaultID, id string, content interface{}) (*vault.Docume
 }
 
 // GetDocMetaData get doc metadata.
-func (c *Client) GetDocMetaData(vaultID, docID string) (*vault.DocumentMetadata, error) { // nolint: dupl
+func (c *Client) GetDocMetaData(ctx context.Context, vaultID, docID string, // nolint: dupl
+) (*vault.DocumentMetadata, error) {
 	target := c.baseURL + fmt.Sprintf(getDocMetadataPath, url.QueryEscape(vaultID), url.QueryEscape(docID))
 
-	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, target, http.NoBody)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+
+	resp, err := c.sendHTTPRequest(req, http.StatusOK)
+	if err != nil {
+		return nil, fmt.Errorf("http request: %w", err)
+	}
+
+	var docMeta vault.DocumentMetadata
+	if err := json.Unmarshal(resp, &docMeta); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal resp to vault doc meta: %w", err)
+	}
+
+	return &docMeta, nil
+}
+
+// GetDocMetaDataByURI gets the metadata of the document stored at the EDV document URI, the edvDocURI of its
+// metadata.
+func (c *Client) GetDocMetaDataByURI(ctx context.Context, uri string) (*vault.DocumentMetadata, error) { // nolint: dupl
+	target := c.baseURL + fmt.Sprintf(getDocMetadataByURIPath, url.QueryEscape(uri))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, http.NoBody)
 	if err != nil {
 		return nil, fmt.Errorf("new request: %w", err)
 	}
@@ -152,19 +223,22 @@ func (c *Client) GetDocMetaData(vaultID, docID string) (*vault.DocumentMetadata,
 }
 
 // CreateAuthorization creates an authorization.
-func (c *Client) CreateAuthorization(vaultID, requestingParty string, scope *vault.AuthorizationsScope,
-) (*vault.CreatedAuthorization, error) {
+func (c *Client) CreateAuthorization(ctx context.Context, vaultID, requestingParty string,
+	scope *vault.AuthorizationsScope) (*vault.CreatedAuthorization, error) {
 	target := c.baseURL + fmt.Sprintf(createAuthorizationsPath, url.QueryEscape(vaultID))
 
+	proof, _ := ctx.Value(keyProofCtxKey{}).(*vault.KeyProof) // nolint:errcheck
+
 	src, err := json.Marshal(operation.CreateAuthorizationsBody{
 		RequestingParty: requestingParty,
 		Scope:           *scope,
+		Proof:           proof,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("marshal: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, target, bytes.NewReader(src))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(src))
 	if err != nil {
 		return nil, fmt.Errorf("new request: %w", err)
 	}
@@ -182,11 +256,60 @@ func (c *Client) CreateAuthorization(vaultID, requestingParty string, scope *vau
 	return &result, nil
 }
 
+// CreateAuthorizationChallenge returns a challenge for the requesting party of an authorization of the vault to
+// sign, see ContextWithKeyProof.
+func (c *Client) CreateAuthorizationChallenge(ctx context.Context, vaultID string, // nolint: dupl
+) (*vault.AuthorizationChallenge, error) {
+	target := c.baseURL + fmt.Sprintf(createChallengePath, url.QueryEscape(vaultID))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+
+	resp, err := c.sendHTTPRequest(req, http.StatusCreated)
+	if err != nil {
+		return nil, fmt.Errorf("http request: %w", err)
+	}
+
+	var result vault.AuthorizationChallenge
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("unmarshal to AuthorizationChallenge: %w", err)
+	}
+
+	return &result, nil
+}
+
 // GetAuthorization returns an authorization.
-func (c *Client) GetAuthorization(vaultID, id string) (*vault.CreatedAuthorization, error) { // nolint: dupl
+func (c *Client) GetAuthorization(ctx context.Context, vaultID, id string, // nolint: dupl
+) (*vault.CreatedAuthorization, error) {
 	target := c.baseURL + fmt.Sprintf(getAuthorizationsPath, url.QueryEscape(vaultID), url.QueryEscape(id))
 
-	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, target, http.NoBody)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+
+	resp, err := c.sendHTTPRequest(req, http.StatusOK)
+	if err != nil {
+		return nil, fmt.Errorf("http request: %w", err)
+	}
+
+	var result vault.CreatedAuthorization
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("unmarshal to CreatedAuthorization: %w", err)
+	}
+
+	return &result, nil
+}
+
+// UseAuthorization records a use of an authorization and returns it with its remaining uses. The request is not
+// retried, which could count the use twice.
+func (c *Client) UseAuthorization(ctx context.Context, vaultID, id string, // nolint: dupl
+) (*vault.CreatedAuthorization, error) {
+	target := c.baseURL + fmt.Sprintf(useAuthorizationPath, url.QueryEscape(vaultID), url.QueryEscape(id))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, http.NoBody)
 	if err != nil {
 		return nil, fmt.Errorf("new request: %w", err)
 	}
@@ -204,8 +327,57 @@ func (c *Client) GetAuthorization(vaultID, id string) (*vault.CreatedAuthorizati
 	return &result, nil
 }
 
+// DeleteAuthorization deletes an authorization and revokes its zcaps.
+func (c *Client) DeleteAuthorization(ctx context.Context, vaultID, id string) error {
+	target := c.baseURL + fmt.Sprintf(getAuthorizationsPath, url.QueryEscape(vaultID), url.QueryEscape(id))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, target, http.NoBody)
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+
+	_, err = c.sendHTTPRequest(req, http.StatusOK)
+	if err != nil {
+		return fmt.Errorf("http request: %w", err)
+	}
+
+	return nil
+}
+
+// IsRevoked returns whether the vault server revoked the zcap with the given ID.
+func (c *Client) IsRevoked(ctx context.Context, zcapID string) (bool, error) {
+	target := c.baseURL + fmt.Sprintf(getRevocationPath, url.PathEscape(zcapID))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, http.NoBody)
+	if err != nil {
+		return false, fmt.Errorf("new request: %w", err)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return false, fmt.Errorf("http request: %w", err)
+	}
+
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil {
+			logger.Warnf("failed to close response body")
+		}
+	}()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		body, _ := io.ReadAll(resp.Body) // nolint: errcheck
+
+		return false, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+	}
+}
+
 func (c *Client) sendHTTPRequest(req *http.Request, status int) ([]byte, error) {
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -229,6 +401,115 @@ func (c *Client) sendHTTPRequest(req *http.Request, status int) ([]byte, error)
 	return body, nil
 }
 
+// do sends the request. If retries are enabled, GET requests and requests carrying an idempotency key are retried
+// with exponential backoff on network errors and 5xx responses, until the attempts are exhausted or the context of
+// the request is done. The last response is returned once the attempts are exhausted.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	for name, values := range c.headers {
+		req.Header[name] = values
+	}
+
+	if key, ok := req.Context().Value(idempotencyKeyCtxKey{}).(string); ok && key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+
+	if capability, ok := req.Context().Value(capabilityCtxKey{}).(string); ok && capability != "" {
+		action := "write"
+		if req.Method == http.MethodGet {
+			action = "read"
+		}
+
+		req.Header.Set(CapabilityInvocationHeader, fmt.Sprintf(`zcap capability=%q,action=%q`, capability, action))
+	}
+
+	if c.maxAttempts < 2 || (req.Method != http.MethodGet && req.Header.Get(IdempotencyKeyHeader) == "") {
+		return c.send(req)
+	}
+
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = c.backoff
+	b.MaxElapsedTime = 0
+
+	var resp *http.Response
+
+	err := backoff.RetryNotify(
+		func() error {
+			if resp != nil {
+				closeResponseBody(resp)
+
+				resp = nil
+			}
+
+			attempt := req.Clone(req.Context())
+
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return backoff.Permanent(err)
+				}
+
+				attempt.Body = body
+			}
+
+			var err error
+
+			resp, err = c.send(attempt)
+			if err != nil {
+				if errors.Is(err, errSign) || req.Context().Err() != nil {
+					return backoff.Permanent(err)
+				}
+
+				return err
+			}
+
+			if resp.StatusCode >= http.StatusInternalServerError {
+				return fmt.Errorf("status %d", resp.StatusCode)
+			}
+
+			return nil
+		},
+		backoff.WithContext(backoff.WithMaxRetries(b, uint64(c.maxAttempts-1)), req.Context()),
+		func(err error, d time.Duration) {
+			logger.Debugf("%s %s failed, retrying in %s: %s", req.Method, req.URL, d, err)
+		},
+	)
+	if err != nil {
+		if resp != nil && req.Context().Err() == nil {
+			return resp, nil
+		}
+
+		if resp != nil {
+			closeResponseBody(resp)
+		}
+
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// send signs the request, each attempt anew so that its signature is fresh, and sends it.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	if c.signer != nil {
+		header, err := c.signer(req)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errSign, err) // nolint:errorlint
+		}
+
+		for name, values := range *header {
+			req.Header[name] = values
+		}
+	}
+
+	return c.httpClient.Do(req)
+}
+
+func closeResponseBody(resp *http.Response) {
+	if err := resp.Body.Close(); err != nil {
+		logger.Warnf("failed to close response body")
+	}
+}
+
 // Option is a vault client instance option.
 type Option func(opts *Client)
 
@@ -238,3 +519,31 @@ func WithHTTPClient(c HTTPClient) Option {
 		opts.httpClient = c
 	}
 }
+
+// WithRetry retries GET requests and requests carrying an idempotency key up to maxAttempts times in total on network
+// errors and 5xx responses, waiting an exponentially growing delay starting at initialBackoff between attempts.
+func WithRetry(maxAttempts int, initialBackoff time.Duration) Option {
+	return func(opts *Client) {
+		opts.maxAttempts = maxAttempts
+		opts.backoff = initialBackoff
+	}
+}
+
+// WithHTTPSigner signs every request with the signer, eg. one returned by zcapld.NewHTTPSigner, before it is sent.
+// The headers returned by the signer are set on the request, replacing the ones of the same name.
+func WithHTTPSigner(signer func(*http.Request) (*http.Header, error)) Option {
+	return func(opts *Client) {
+		opts.signer = signer
+	}
+}
+
+// WithRequestHeader sets the header on every request. Headers set by the signer take precedence.
+func WithRequestHeader(name, value string) Option {
+	return func(opts *Client) {
+		if opts.headers == nil {
+			opts.headers = make(http.Header)
+		}
+
+		opts.headers.Add(name, value)
+	}
+}
@@ -6,23 +6,29 @@ SPDX-License-Identifier: Apache-2.0
 package vault //nolint: testpackage
 
 import (
+	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
 	"github.com/trustbloc/ace/pkg/restapi/vault"
+	"github.com/trustbloc/ace/pkg/restapi/vault/operation"
 )
 
 func TestClient_GetDocMetaData(t *testing.T) {
 	t.Run("test error from http post", func(t *testing.T) {
 		v := New("")
 
-		_, err := v.GetDocMetaData("v1", "doc1")
+		_, err := v.GetDocMetaData(context.Background(), "v1", "doc1")
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "unsupported protocol scheme")
 	})
@@ -35,7 +41,7 @@ func TestClient_GetDocMetaData(t *testing.T) {
 
 		v := New(serv.URL)
 
-		_, err := v.GetDocMetaData("v1", "doc1")
+		_, err := v.GetDocMetaData(context.Background(), "v1", "doc1")
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "failed to read response body for status 500")
 	})
@@ -50,7 +56,7 @@ func TestClient_GetDocMetaData(t *testing.T) {
 
 		v := New(serv.URL)
 
-		_, err := v.GetDocMetaData("v1", "doc1")
+		_, err := v.GetDocMetaData(context.Background(), "v1", "doc1")
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "failed to unmarshal resp to vault doc meta")
 	})
@@ -73,21 +79,85 @@ func TestClient_GetDocMetaData(t *testing.T) {
 			},
 		}))
 
-		p, err := v.GetDocMetaData("v1", "doc1")
+		p, err := v.GetDocMetaData(context.Background(), "v1", "doc1")
 		require.NoError(t, err)
 		require.Equal(t, "test", p.ID)
 	})
+
+	t.Run("test context cancelled while the request is in flight", func(t *testing.T) {
+		received := make(chan struct{})
+
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(received)
+			<-r.Context().Done()
+		}))
+		defer serv.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		go func() {
+			<-received
+			cancel()
+		}()
+
+		_, err := New(serv.URL).GetDocMetaData(ctx, "v1", "doc1")
+		require.Error(t, err)
+		require.True(t, errors.Is(err, context.Canceled))
+	})
+}
+
+func TestClient_GetDocMetaDataByURI(t *testing.T) {
+	const uri = "https://edv.example.com/encrypted-data-vaults/HwtZ1bUn4SzXoQRoX9br6m/documents/M3aS9xwj8ybCwHkEiCJJR1"
+
+	t.Run("test http get return 404 status", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer serv.Close()
+
+		_, err := New(serv.URL).GetDocMetaDataByURI(context.Background(), uri)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to read response body for status 404")
+	})
+
+	t.Run("test error from unmarshal resp", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, err := fmt.Fprint(w, "wrongValue")
+			require.NoError(t, err)
+		}))
+		defer serv.Close()
+
+		_, err := New(serv.URL).GetDocMetaDataByURI(context.Background(), uri)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to unmarshal resp to vault doc meta")
+	})
+
+	t.Run("test success", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, http.MethodGet, r.Method)
+			require.Equal(t, "/vaults/docs/metadata", r.URL.Path)
+			require.Equal(t, uri, r.URL.Query().Get("uri"))
+
+			require.NoError(t, json.NewEncoder(w).Encode(vault.DocumentMetadata{ID: "test", URI: uri}))
+		}))
+		defer serv.Close()
+
+		p, err := New(serv.URL).GetDocMetaDataByURI(context.Background(), uri)
+		require.NoError(t, err)
+		require.Equal(t, "test", p.ID)
+		require.Equal(t, uri, p.URI)
+	})
 }
 
 func TestClient_CreateVault(t *testing.T) {
 	t.Run("Send request (error)", func(t *testing.T) {
-		_, err := New("").CreateVault()
+		_, err := New("").CreateVault(context.Background())
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "unsupported protocol scheme")
 	})
 
 	t.Run("Invalid URL", func(t *testing.T) {
-		_, err := New("http://user^foo.com").CreateVault()
+		_, err := New("http://user^foo.com").CreateVault(context.Background())
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "invalid character \"^\" in host name")
 	})
@@ -100,7 +170,7 @@ func TestClient_CreateVault(t *testing.T) {
 		}))
 		defer serv.Close()
 
-		_, err := New(serv.URL).CreateVault()
+		_, err := New(serv.URL).CreateVault(context.Background())
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "unmarshal to CreatedVault")
 	})
@@ -119,7 +189,7 @@ func TestClient_CreateVault(t *testing.T) {
 		}))
 		defer serv.Close()
 
-		p, err := New(serv.URL).CreateVault()
+		p, err := New(serv.URL).CreateVault(context.Background())
 		require.NoError(t, err)
 		require.Equal(t, ID, p.ID)
 	})
@@ -132,7 +202,7 @@ func TestClient_CreateAuthorization(t *testing.T) {
 	)
 
 	t.Run("Send request (error)", func(t *testing.T) {
-		_, err := New("").CreateAuthorization(vID, rp, &vault.AuthorizationsScope{})
+		_, err := New("").CreateAuthorization(context.Background(), vID, rp, &vault.AuthorizationsScope{})
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "unsupported protocol scheme")
 	})
@@ -145,7 +215,7 @@ func TestClient_CreateAuthorization(t *testing.T) {
 		}))
 		defer serv.Close()
 
-		_, err := New(serv.URL).CreateAuthorization(vID, rp, &vault.AuthorizationsScope{})
+		_, err := New(serv.URL).CreateAuthorization(context.Background(), vID, rp, &vault.AuthorizationsScope{})
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "unmarshal to CreatedAuthorization")
 	})
@@ -164,10 +234,57 @@ func TestClient_CreateAuthorization(t *testing.T) {
 		}))
 		defer serv.Close()
 
-		p, err := New(serv.URL).CreateAuthorization(vID, rp, &vault.AuthorizationsScope{})
+		p, err := New(serv.URL).CreateAuthorization(context.Background(), vID, rp, &vault.AuthorizationsScope{})
 		require.NoError(t, err)
 		require.Equal(t, ID, p.ID)
 	})
+
+	t.Run("Sends the proof of key control", func(t *testing.T) {
+		proof := &vault.KeyProof{Challenge: "challenge", VerificationMethod: "did:example:rp#key", Signature: "sig"}
+
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var body operation.CreateAuthorizationsBody
+
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			require.Equal(t, proof, body.Proof)
+
+			w.WriteHeader(http.StatusCreated)
+			require.NoError(t, json.NewEncoder(w).Encode(vault.CreatedAuthorization{ID: "ID"}))
+		}))
+		defer serv.Close()
+
+		_, err := New(serv.URL).CreateAuthorization(ContextWithKeyProof(context.Background(), proof), vID, rp,
+			&vault.AuthorizationsScope{})
+		require.NoError(t, err)
+	})
+}
+
+func TestClient_CreateAuthorizationChallenge(t *testing.T) {
+	t.Run("Vault not found", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer serv.Close()
+
+		_, err := New(serv.URL).CreateAuthorizationChallenge(context.Background(), "vid")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "status 404")
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, http.MethodPost, r.Method)
+			require.Equal(t, "/vaults/vid/authorizations/challenges", r.URL.Path)
+
+			w.WriteHeader(http.StatusCreated)
+			require.NoError(t, json.NewEncoder(w).Encode(vault.AuthorizationChallenge{Challenge: "challenge"}))
+		}))
+		defer serv.Close()
+
+		challenge, err := New(serv.URL).CreateAuthorizationChallenge(context.Background(), "vid")
+		require.NoError(t, err)
+		require.Equal(t, "challenge", challenge.Challenge)
+	})
 }
 
 func TestClient_SaveDoc(t *testing.T) {
@@ -177,7 +294,7 @@ func TestClient_SaveDoc(t *testing.T) {
 	)
 
 	t.Run("Send request (error)", func(t *testing.T) {
-		_, err := New("").SaveDoc(vID, ID, nil)
+		_, err := New("").SaveDoc(context.Background(), vID, ID, nil)
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "unsupported protocol scheme")
 	})
@@ -190,7 +307,7 @@ func TestClient_SaveDoc(t *testing.T) {
 		}))
 		defer serv.Close()
 
-		_, err := New(serv.URL).SaveDoc(vID, ID, nil)
+		_, err := New(serv.URL).SaveDoc(context.Background(), vID, ID, nil)
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "unmarshal to DocumentMetadata")
 	})
@@ -209,7 +326,7 @@ func TestClient_SaveDoc(t *testing.T) {
 		}))
 		defer serv.Close()
 
-		p, err := New(serv.URL).SaveDoc(vID, ID, nil)
+		p, err := New(serv.URL).SaveDoc(context.Background(), vID, ID, nil)
 		require.NoError(t, err)
 		require.Equal(t, ID, p.ID)
 	})
@@ -217,7 +334,7 @@ func TestClient_SaveDoc(t *testing.T) {
 
 func TestClient_GetAuthorization(t *testing.T) {
 	t.Run("Send request (error)", func(t *testing.T) {
-		_, err := New("").GetAuthorization("vid", "id")
+		_, err := New("").GetAuthorization(context.Background(), "vid", "id")
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "unsupported protocol scheme")
 	})
@@ -230,7 +347,7 @@ func TestClient_GetAuthorization(t *testing.T) {
 		}))
 		defer serv.Close()
 
-		_, err := New(serv.URL).GetAuthorization("vid", "id")
+		_, err := New(serv.URL).GetAuthorization(context.Background(), "vid", "id")
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "unmarshal to CreatedAuthorization")
 	})
@@ -249,8 +366,303 @@ func TestClient_GetAuthorization(t *testing.T) {
 		}))
 		defer serv.Close()
 
-		p, err := New(serv.URL).GetAuthorization("vid", "id")
+		p, err := New(serv.URL).GetAuthorization(context.Background(), "vid", "id")
 		require.NoError(t, err)
 		require.Equal(t, ID, p.ID)
 	})
 }
+
+func TestClient_UseAuthorization(t *testing.T) {
+	t.Run("Exhausted", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		}))
+		defer serv.Close()
+
+		_, err := New(serv.URL, WithRetry(3, time.Millisecond)).UseAuthorization(context.Background(), "vid", "id")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "status 403")
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, http.MethodPost, r.Method)
+			require.Equal(t, "/vaults/vid/authorizations/id/uses", r.URL.Path)
+
+			remaining := uint64(4)
+
+			w.WriteHeader(http.StatusOK)
+			require.NoError(t, json.NewEncoder(w).Encode(vault.CreatedAuthorization{
+				ID: "id", Uses: 1, RemainingUses: &remaining,
+			}))
+		}))
+		defer serv.Close()
+
+		a, err := New(serv.URL).UseAuthorization(context.Background(), "vid", "id")
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), a.Uses)
+		require.Equal(t, uint64(4), *a.RemainingUses)
+	})
+}
+
+func TestClient_DeleteAuthorization(t *testing.T) {
+	t.Run("Send request (error)", func(t *testing.T) {
+		err := New("").DeleteAuthorization(context.Background(), "vid", "id")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unsupported protocol scheme")
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer serv.Close()
+
+		err := New(serv.URL).DeleteAuthorization(context.Background(), "vid", "id")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "status 404")
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, http.MethodDelete, r.Method)
+			require.Equal(t, "/vaults/vid/authorizations/id", r.URL.Path)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer serv.Close()
+
+		require.NoError(t, New(serv.URL).DeleteAuthorization(context.Background(), "vid", "id"))
+	})
+}
+
+func TestClient_IsRevoked(t *testing.T) {
+	const zcapID = "urn:uuid:123"
+
+	t.Run("Send request (error)", func(t *testing.T) {
+		_, err := New("").IsRevoked(context.Background(), zcapID)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unsupported protocol scheme")
+	})
+
+	t.Run("Revoked", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "/revocations/"+zcapID, r.URL.Path)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer serv.Close()
+
+		revoked, err := New(serv.URL).IsRevoked(context.Background(), zcapID)
+		require.NoError(t, err)
+		require.True(t, revoked)
+	})
+
+	t.Run("Not revoked", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer serv.Close()
+
+		revoked, err := New(serv.URL).IsRevoked(context.Background(), zcapID)
+		require.NoError(t, err)
+		require.False(t, revoked)
+	})
+
+	t.Run("Unexpected status", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer serv.Close()
+
+		_, err := New(serv.URL).IsRevoked(context.Background(), zcapID)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unexpected status 500")
+	})
+}
+
+func TestContextWithCapability(t *testing.T) {
+	var header string
+
+	serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get(CapabilityInvocationHeader)
+
+		w.WriteHeader(http.StatusCreated)
+		require.NoError(t, json.NewEncoder(w).Encode(vault.DocumentMetadata{ID: "test"}))
+	}))
+	t.Cleanup(serv.Close)
+
+	_, err := New(serv.URL).SaveDoc(context.Background(), "vid", "id", nil)
+	require.NoError(t, err)
+	require.Empty(t, header)
+
+	ctx := ContextWithCapability(context.Background(), "token")
+
+	_, err = New(serv.URL).SaveDoc(ctx, "vid", "id", nil)
+	require.NoError(t, err)
+	require.Equal(t, `zcap capability="token",action="write"`, header)
+}
+
+func TestClient_WithRetry(t *testing.T) {
+	// flakyServer fails the first requests with 503 and records the requests it receives.
+	flakyServer := func(t *testing.T, failures, status int, body interface{}) (*httptest.Server, *[]*http.Request) {
+		t.Helper()
+
+		var received []*http.Request
+
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			src, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+
+			r.Body = io.NopCloser(bytes.NewReader(src))
+			received = append(received, r)
+
+			if len(received) <= failures {
+				w.WriteHeader(http.StatusServiceUnavailable)
+
+				return
+			}
+
+			w.WriteHeader(status)
+			require.NoError(t, json.NewEncoder(w).Encode(body))
+		}))
+		t.Cleanup(serv.Close)
+
+		return serv, &received
+	}
+
+	t.Run("retries idempotent calls", func(t *testing.T) {
+		serv, received := flakyServer(t, 2, http.StatusOK, vault.DocumentMetadata{ID: "test"})
+
+		p, err := New(serv.URL, WithRetry(3, time.Millisecond)).GetDocMetaData(context.Background(), "v1", "doc1")
+		require.NoError(t, err)
+		require.Equal(t, "test", p.ID)
+		require.Len(t, *received, 3)
+	})
+
+	t.Run("returns the last response once the attempts are exhausted", func(t *testing.T) {
+		serv, received := flakyServer(t, 3, http.StatusOK, vault.CreatedAuthorization{ID: "test"})
+
+		_, err := New(serv.URL, WithRetry(2, time.Millisecond)).GetAuthorization(context.Background(), "vid", "id")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "status 503")
+		require.Len(t, *received, 2)
+	})
+
+	t.Run("does not retry calls that are not idempotent", func(t *testing.T) {
+		serv, received := flakyServer(t, 1, http.StatusCreated, vault.DocumentMetadata{ID: "test"})
+
+		_, err := New(serv.URL, WithRetry(3, time.Millisecond)).SaveDoc(context.Background(), "vid", "id", nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "status 503")
+		require.Len(t, *received, 1)
+		require.Empty(t, (*received)[0].Header.Get(IdempotencyKeyHeader))
+	})
+
+	t.Run("retries calls carrying an idempotency key", func(t *testing.T) {
+		serv, received := flakyServer(t, 1, http.StatusCreated, vault.DocumentMetadata{ID: "test"})
+
+		ctx := ContextWithIdempotencyKey(context.Background(), "key")
+
+		p, err := New(serv.URL, WithRetry(3, time.Millisecond)).SaveDoc(ctx, "vid", "id", map[string]string{"a": "b"})
+		require.NoError(t, err)
+		require.Equal(t, "test", p.ID)
+		require.Len(t, *received, 2)
+
+		for _, r := range *received {
+			require.Equal(t, "key", r.Header.Get(IdempotencyKeyHeader))
+
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			require.Contains(t, string(body), `"content":{"a":"b"}`)
+		}
+	})
+
+	t.Run("stops retrying once the context deadline is exceeded", func(t *testing.T) {
+		serv, received := flakyServer(t, 3, http.StatusOK, vault.DocumentMetadata{ID: "test"})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		_, err := New(serv.URL, WithRetry(3, time.Minute)).GetDocMetaData(ctx, "v1", "doc1")
+		require.Error(t, err)
+		require.True(t, errors.Is(err, context.DeadlineExceeded))
+		require.Len(t, *received, 1)
+	})
+}
+
+func TestClient_WithHTTPSigner(t *testing.T) {
+	var received []http.Header
+
+	serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, r.Header.Clone())
+
+		// the first request of the retried client fails
+		if len(received) == 1 && r.Header.Get("X-Tenant") != "" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		require.NoError(t, json.NewEncoder(w).Encode(vault.DocumentMetadata{ID: "test"}))
+	}))
+	t.Cleanup(serv.Close)
+
+	t.Run("signs every request and sends the static headers", func(t *testing.T) {
+		received = nil
+
+		var signed int
+
+		client := New(serv.URL,
+			WithRequestHeader("X-Tenant", "tenant1"),
+			WithRequestHeader("Signature", "overwritten"),
+			WithRetry(2, time.Millisecond),
+			WithHTTPSigner(func(r *http.Request) (*http.Header, error) {
+				signed++
+
+				r.Header.Set(CapabilityInvocationHeader, `zcap capability="zcap",action="read"`)
+				r.Header.Set("Signature", fmt.Sprintf(`keyId="did:key:z6Mk#key",signature="%d"`, signed))
+
+				return &r.Header, nil
+			}),
+		)
+
+		_, err := client.GetDocMetaData(context.Background(), "vid", "docID")
+		require.NoError(t, err)
+
+		// the retry is signed anew
+		require.Equal(t, 2, signed)
+		require.Len(t, received, 2)
+
+		for i, header := range received {
+			require.Equal(t, "tenant1", header.Get("X-Tenant"))
+			require.Equal(t, `zcap capability="zcap",action="read"`, header.Get(CapabilityInvocationHeader))
+			require.Equal(t, fmt.Sprintf(`keyId="did:key:z6Mk#key",signature="%d"`, i+1), header.Get("Signature"))
+		}
+	})
+
+	t.Run("headers returned by the signer are set", func(t *testing.T) {
+		received = nil
+
+		client := New(serv.URL, WithHTTPSigner(func(*http.Request) (*http.Header, error) {
+			return &http.Header{"Signature": []string{"sig"}}, nil
+		}))
+
+		_, err := client.GetDocMetaData(context.Background(), "vid", "docID")
+		require.NoError(t, err)
+		require.Len(t, received, 1)
+		require.Equal(t, "sig", received[0].Get("Signature"))
+	})
+
+	t.Run("error if the signer fails", func(t *testing.T) {
+		received = nil
+
+		client := New(serv.URL, WithRetry(3, time.Millisecond), WithHTTPSigner(func(*http.Request) (*http.Header, error) {
+			return nil, errors.New("no key")
+		}))
+
+		_, err := client.GetDocMetaData(context.Background(), "vid", "docID")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to sign request: no key")
+		require.Empty(t, received)
+	})
+}
@@ -0,0 +1,97 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/hyperledger/aries-framework-go/pkg/crypto"
+	vdrapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	edv "github.com/trustbloc/edv/pkg/client"
+
+	"github.com/trustbloc/ace/pkg/client/zcap"
+	cshzcapld "github.com/trustbloc/ace/pkg/restapi/csh/operation/zcapld"
+	"github.com/trustbloc/ace/pkg/restapi/vault"
+)
+
+// EDVClient is an EDV client whose requests invoke the EDV authorization token of a vault.
+type EDVClient struct {
+	*edv.Client
+	// VaultID is the ID of the vault in the EDV server, to be passed to the methods of the client.
+	VaultID string
+}
+
+// NewEDVClientFromVault returns an EDV client invoking the EDV authorization token of the created vault, as
+// zcap.NewSignedEDVClient does. The requests are signed on behalf of the invoker of the token, whose key must be
+// held by km.
+func NewEDVClientFromVault(cv *vault.CreatedVault, km kms.KeyManager, cr crypto.Crypto, vdr vdrapi.Registry,
+	httpClient *http.Client) (*EDVClient, error) {
+	if cv == nil || cv.Authorization == nil || cv.EDV == nil {
+		return nil, errors.New("vault has no EDV authorization")
+	}
+
+	i := strings.LastIndex(cv.EDV.URI, "/")
+	if i <= 0 || i == len(cv.EDV.URI)-1 {
+		return nil, fmt.Errorf("invalid EDV URI: %s", cv.EDV.URI)
+	}
+
+	invoker, err := newInvoker(cv.EDV.AuthToken, km, cr, vdr)
+	if err != nil {
+		return nil, fmt.Errorf("edv auth token: %w", err)
+	}
+
+	return &EDVClient{
+		Client:  zcap.NewSignedEDVClient(cv.EDV.URI[:i], invoker, edv.WithHTTPClient(httpClient)),
+		VaultID: cv.EDV.URI[i+1:],
+	}, nil
+}
+
+// NewKMSClientFromVault returns the WebKMS clients invoking the KMS authorization token of the created vault on its
+// keystore, as zcap.NewSignedKMSClient does. The requests are signed on behalf of the invoker of the token, whose
+// key must be held by km.
+func NewKMSClientFromVault(cv *vault.CreatedVault, km kms.KeyManager, cr crypto.Crypto, vdr vdrapi.Registry,
+	httpClient *http.Client) (*zcap.KMSClient, error) {
+	if cv == nil || cv.Authorization == nil || cv.KMS == nil {
+		return nil, errors.New("vault has no KMS authorization")
+	}
+
+	invoker, err := newInvoker(cv.KMS.AuthToken, km, cr, vdr)
+	if err != nil {
+		return nil, fmt.Errorf("kms auth token: %w", err)
+	}
+
+	return zcap.NewSignedKMSClient(cv.KMS.URI, httpClient, invoker), nil
+}
+
+// newInvoker returns the invoker of the compressed zcap, signing with its key held by km.
+func newInvoker(token string, km kms.KeyManager, cr crypto.Crypto, vdr vdrapi.Registry) (*zcap.Invoker, error) {
+	capability, err := cshzcapld.DecompressZCAP(token)
+	if err != nil {
+		return nil, fmt.Errorf("decompress zcap: %w", err)
+	}
+
+	controller := capability.Invoker
+	if controller == "" {
+		controller = capability.Controller
+	}
+
+	if controller == "" {
+		return nil, errors.New("zcap does not specify a controller nor an invoker")
+	}
+
+	return &zcap.Invoker{
+		Controller: controller,
+		ZCAP:       token,
+		KMS:        km,
+		Crypto:     cr,
+		Resolver:   vdr,
+	}, nil
+}
@@ -0,0 +1,296 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault //nolint: testpackage
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	"github.com/hyperledger/aries-framework-go/pkg/crypto/tinkcrypto"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/suite"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/suite/ed25519signature2018"
+	vdrapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/hyperledger/aries-framework-go/pkg/kms/localkms"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock/noop"
+	vdrpkg "github.com/hyperledger/aries-framework-go/pkg/vdr"
+	vdrkey "github.com/hyperledger/aries-framework-go/pkg/vdr/key"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/zcapld"
+	"github.com/trustbloc/edv/pkg/restapi/models"
+
+	"github.com/trustbloc/ace/pkg/internal/testutil"
+	cshzcapld "github.com/trustbloc/ace/pkg/restapi/csh/operation/zcapld"
+	"github.com/trustbloc/ace/pkg/restapi/vault"
+)
+
+const (
+	// recordedVault is the response of the vault server to a request creating a vault. Its tokens are the root zcaps
+	// of the EDV vault and WebKMS keystore, invoked by recordedInvoker.
+	recordedVault = `{
+  "id": "did:key:z6MkvaultFixture",
+  "edv": {
+    "uri": "https://edv.example.com/encrypted-data-vaults/Fq3cN2vZDmT5sRBxpA6Kjb",
+    "authToken": "` + recordedEDVToken + `"
+  },
+  "kms": {
+    "uri": "https://kms.example.com/v1/keystores/c43bl2u0d3ilo6h1ddhg",
+    "authToken": "` + recordedKMSToken + `"
+  }
+}`
+	recordedInvoker = "did:key:z6Mkw9u7PKrc9c4VP6oWrW3GAzi7YHFzeC5MmwENCiLooh2z" +
+		"#z6Mkw9u7PKrc9c4VP6oWrW3GAzi7YHFzeC5MmwENCiLooh2z"
+	// recordedInvokerSeed is the seed of the ed25519 key of recordedInvoker.
+	recordedInvokerSeed = "ed3311601aef73b91b639968c0d4afde848810c8aa67378f2536158e890103da"
+
+	recordedEDVToken = "" +
+		"H4sIAAAAAAAA_7ySTXOqSBiF_8t7t6CAfCir8foRiUJAUKOpLLBpsZWmsWlASOW_T5nMYmp2qZq66z6nT53nvB_wF2K5wHcB" +
+		"NpyFKEq7328GJOkxnvZLjCpORNuvNZCAJP_S4KTu4XtMiwz3EKN9nCPeFgInchKLWK7jKhNlf34bIE-rD1MaGeX6970Ym8vL" +
+		"8fFXXrMr5mBDQhL7ilu7M91rM6osf8nRCOlb32Q7vhs8jTti7RfzDk8MlzYzb0JWjJ217tdPDSDBoypnWfbHg-MsYw1OxkgQ" +
+		"loP9BhzHCUjQcCIwvH_zQPHjNYp5igXYH-BM_z_eUVtgsKHiuY2T2v6Sw6cEKK5xLEqw8yrLJCg4Yyew3z4AcRwL_FhcUzRT" +
+		"VhVZtSJFtzXT1pTe0DL1gaEoxgEkuDQl2IDb5_PxCZEX8jw_zNZREDqlQx3Nmzjmgc5LpG1Kh3pt_BqQl6wk-8tecTJ11Ost" +
+		"eMk9N6Xp-EgnnaJZi0XlJc7pddPuo0beL7tb68n50KKKd6jJwVc7h-jNYCnTkTU48nB3qv3jwAjdas8DVVt31_M4AAlylqNH" +
+		"a6ve6q7iT2ib_pb13SFw5psX5daU5GioRbNXl6m32K4vt6nWDFfDJlqtilOmJtdR4dG1OasyWV-xlLb3cNfkWiqH1muRwj-4" +
+		"_IoXrHzkoLiIjyQjop3iDKdfc4IE4pv9LNEMQx2FJM1jUXGsKeoQJKgxJyfyvb2LxZkl_7lNnO6eW6punngd-MU1KOuhek7T" +
+		"7WbnrsWS-qeQ6UG4YDd3FP36qQE-3z__HgDGnYXYBAQAAA=="
+	recordedKMSToken = "" +
+		"H4sIAAAAAAAA_7ySXY-qSBiE_8t7blH5UBSudiLqgB-jDCpycnKCTYMNDc10NyhO5r9vnNlNNns32WTvq7q6nnrf4Q_EKolv" +
+		"Emy4SFkLezC4GiTpM54NBEYNJ7IbtDooQJJ_aIpS9PEtLmuK-4iVg1YbFLgTknEsBmhonKneqIlBKDMvWpJcsscDVcsKzMGG" +
+		"hCR2gTv7bq6Lq9WMt0uOLDQ8bE125Edj8XQn49Pz_I6no3V5nW2mZMXYRb__-K4BFHj044zS_z04ppRdcfKEJGEV2D8BcRxL" +
+		"vMQdKCBIVoEC-FYzLuHXFxwUP6RBzDMswX4H1_mPxIOuxmBDwyu7KIX9tx4-FEBxi2MpwK4aShWoOWMp2D_f__rlY2td1c2e" +
+		"pva0caAObd20dbU_mYxM1dJMIwIF8qsAG3DnXc4LRF6IN49mfrB7dYVbuvpm6ppRORdI3wu33HRxuCMvVJBTflJdqln9fhhK" +
+		"P7E6kUjtba45pZ8Ga2TR47h8HmZv_mrxNvTX-WbkYsfprclTqqHTPtry9HlyWHhDK6wblnvBTvudrdw5YtPDMXB2oEDFKvQo" +
+		"Xq_C0XyMOW9mh23mJWPDMDK0LPM4LByrOfcmjVVZ9x31b6rfxmuWB6nHw-uJbk4v6e-9Y1q5uN38XddycYiMsl3M_UfAJ65t" +
+		"w2smHjkoruMzoUR2DqY4-5wRFJBf-GeJPhpp1ivJqlg2HOuqNgEFWsxJSr42X2N5Ycm_DjQfxfiwNScimgVeOj3HDE-u2Ny8" +
+		"BkYROWm1KJZk763CMGqCH981wMevjz8HAJJsg5T-AwAA"
+)
+
+func TestNewEDVClientFromVault(t *testing.T) {
+	km, cr, vdr := newInvokerKeys(t)
+
+	for _, tc := range []struct {
+		name   string
+		action string
+		call   func(*EDVClient) error
+	}{
+		{
+			name:   "reads documents",
+			action: "read",
+			call: func(c *EDVClient) error {
+				doc, err := c.ReadDocument(c.VaultID, "doc1")
+				if err == nil {
+					require.Equal(t, "doc1", doc.ID)
+				}
+
+				return err
+			},
+		},
+		{
+			name:   "writes documents",
+			action: "write",
+			call: func(c *EDVClient) error {
+				_, err := c.CreateDocument(c.VaultID, &models.EncryptedDocument{ID: "doc1"})
+
+				return err
+			},
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			cv, zcap := recordedCreatedVault(t, func(cv *vault.CreatedVault) *vault.Location { return cv.EDV })
+
+			invoked := false
+
+			serv := httptest.NewServer(zcapAuthHandler(t, zcap, tc.action, km, cr, vdr,
+				func(w http.ResponseWriter, r *http.Request) {
+					invoked = true
+
+					require.True(t, strings.HasPrefix(r.URL.Path, "/encrypted-data-vaults/Fq3cN2vZDmT5sRBxpA6Kjb/documents"))
+
+					if r.Method == http.MethodPost {
+						w.Header().Set("Location", r.URL.String()+"/doc1")
+						w.WriteHeader(http.StatusCreated)
+
+						return
+					}
+
+					require.NoError(t, json.NewEncoder(w).Encode(&models.EncryptedDocument{ID: "doc1"}))
+				}))
+			t.Cleanup(serv.Close)
+
+			cv.EDV.URI = serv.URL + "/encrypted-data-vaults/Fq3cN2vZDmT5sRBxpA6Kjb"
+
+			client, err := NewEDVClientFromVault(cv, km, cr, vdr, serv.Client())
+			require.NoError(t, err)
+			require.Equal(t, "Fq3cN2vZDmT5sRBxpA6Kjb", client.VaultID)
+
+			require.NoError(t, tc.call(client))
+			require.True(t, invoked)
+		})
+	}
+
+	t.Run("error if the vault has no EDV authorization", func(t *testing.T) {
+		_, err := NewEDVClientFromVault(&vault.CreatedVault{ID: "did:example:vault"}, km, cr, vdr, nil)
+		require.EqualError(t, err, "vault has no EDV authorization")
+	})
+
+	t.Run("error if the EDV URI is invalid", func(t *testing.T) {
+		cv, _ := recordedCreatedVault(t, func(cv *vault.CreatedVault) *vault.Location { return cv.EDV })
+		cv.EDV.URI = "https://edv.example.com/encrypted-data-vaults/"
+
+		_, err := NewEDVClientFromVault(cv, km, cr, vdr, nil)
+		require.EqualError(t, err, "invalid EDV URI: https://edv.example.com/encrypted-data-vaults/")
+	})
+
+	t.Run("error if the EDV token is malformed", func(t *testing.T) {
+		cv, _ := recordedCreatedVault(t, func(cv *vault.CreatedVault) *vault.Location { return cv.EDV })
+		cv.EDV.AuthToken = "invalid"
+
+		_, err := NewEDVClientFromVault(cv, km, cr, vdr, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "edv auth token: decompress zcap")
+	})
+}
+
+func TestNewKMSClientFromVault(t *testing.T) {
+	km, cr, vdr := newInvokerKeys(t)
+
+	t.Run("operates the keys of the keystore", func(t *testing.T) {
+		cv, zcap := recordedCreatedVault(t, func(cv *vault.CreatedVault) *vault.Location { return cv.KMS })
+
+		invoked := false
+
+		serv := httptest.NewServer(zcapAuthHandler(t, zcap, "sign", km, cr, vdr,
+			func(w http.ResponseWriter, r *http.Request) {
+				invoked = true
+
+				require.Equal(t, "/v1/keystores/c43bl2u0d3ilo6h1ddhg/keys/key1/sign", r.URL.Path)
+				require.NoError(t, json.NewEncoder(w).Encode(map[string][]byte{"signature": []byte("signature")}))
+			}))
+		t.Cleanup(serv.Close)
+
+		cv.KMS.URI = serv.URL + "/v1/keystores/c43bl2u0d3ilo6h1ddhg"
+
+		client, err := NewKMSClientFromVault(cv, km, cr, vdr, serv.Client())
+		require.NoError(t, err)
+		require.NotNil(t, client.KMS)
+
+		signature, err := client.Crypto.Sign([]byte("message"), cv.KMS.URI+"/keys/key1")
+		require.NoError(t, err)
+		require.Equal(t, []byte("signature"), signature)
+		require.True(t, invoked)
+	})
+
+	t.Run("error if the vault has no KMS authorization", func(t *testing.T) {
+		_, err := NewKMSClientFromVault(nil, km, cr, vdr, nil)
+		require.EqualError(t, err, "vault has no KMS authorization")
+	})
+
+	t.Run("error if the KMS token is malformed", func(t *testing.T) {
+		cv, _ := recordedCreatedVault(t, func(cv *vault.CreatedVault) *vault.Location { return cv.KMS })
+		cv.KMS.AuthToken = "invalid"
+
+		_, err := NewKMSClientFromVault(cv, km, cr, vdr, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "kms auth token: decompress zcap")
+	})
+}
+
+// recordedCreatedVault returns the recorded vault along with the zcap of the location.
+func recordedCreatedVault(t *testing.T,
+	location func(*vault.CreatedVault) *vault.Location) (*vault.CreatedVault, *zcapld.Capability) {
+	t.Helper()
+
+	cv := &vault.CreatedVault{}
+	require.NoError(t, json.Unmarshal([]byte(recordedVault), cv))
+
+	zcap, err := cshzcapld.DecompressZCAP(location(cv).AuthToken)
+	require.NoError(t, err)
+	require.Equal(t, recordedInvoker, zcap.Invoker)
+
+	return cv, zcap.Capability
+}
+
+// newInvokerKeys returns the KMS holding the key of recordedInvoker, along with a crypto and a registry resolving it.
+func newInvokerKeys(t *testing.T) (kms.KeyManager, *tinkcrypto.Crypto, vdrapi.Registry) { //nolint:ireturn
+	t.Helper()
+
+	km, err := localkms.New("local-lock://test/key-uri/", &kmsProvider{
+		storageProvider: mem.NewProvider(),
+		secretLock:      &noop.NoLock{},
+	})
+	require.NoError(t, err)
+
+	seed, err := hex.DecodeString(recordedInvokerSeed)
+	require.NoError(t, err)
+
+	key := ed25519.NewKeyFromSeed(seed)
+
+	// the invoker's requests are signed with the key of the KID derived from its did:key
+	kid, err := localkms.CreateKID(key.Public().(ed25519.PublicKey), kms.ED25519Type) //nolint:forcetypeassert
+	require.NoError(t, err)
+
+	_, _, err = km.ImportPrivateKey(key, kms.ED25519Type, kms.WithKeyID(kid))
+	require.NoError(t, err)
+
+	cr, err := tinkcrypto.New()
+	require.NoError(t, err)
+
+	return km, cr, vdrpkg.New(vdrpkg.WithVDR(vdrkey.New()))
+}
+
+// zcapAuthHandler serves the requests invoking the root zcap with the action, as the EDV and WebKMS servers do.
+func zcapAuthHandler(t *testing.T, zcap *zcapld.Capability, action string, km kms.KeyManager,
+	cr *tinkcrypto.Crypto, vdr vdrapi.Registry, next http.HandlerFunc) http.HandlerFunc {
+	t.Helper()
+
+	return zcapld.NewHTTPSigAuthHandler(
+		&zcapld.HTTPSigAuthConfig{
+			CapabilityResolver: zcapld.SimpleCapabilityResolver{zcap.ID: zcap},
+			KeyResolver:        zcapld.NewDIDKeyResolver(vdr),
+			VDRResolver:        vdr,
+			VerifierOptions: []zcapld.VerificationOption{
+				zcapld.WithLDDocumentLoaders(testutil.DocumentLoader(t)),
+				zcapld.WithSignatureSuites(
+					ed25519signature2018.New(suite.WithVerifier(ed25519signature2018.NewPublicKeyVerifier())),
+				),
+			},
+			Secrets:     &zcapld.AriesDIDKeySecrets{},
+			ErrConsumer: func(err error) { require.NoError(t, err) },
+			KMS:         km,
+			Crypto:      cr,
+		},
+		&zcapld.InvocationExpectations{
+			Target:         zcap.InvocationTarget.ID,
+			RootCapability: zcap.ID,
+			Action:         action,
+		},
+		next,
+	)
+}
+
+type kmsProvider struct {
+	storageProvider storage.Provider
+	secretLock      secretlock.Service
+}
+
+func (k *kmsProvider) StorageProvider() storage.Provider { //nolint:ireturn
+	return k.storageProvider
+}
+
+func (k *kmsProvider) SecretLock() secretlock.Service { //nolint:ireturn
+	return k.secretLock
+}
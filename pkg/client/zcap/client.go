@@ -0,0 +1,93 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package zcap
+
+import (
+	"net/http"
+
+	"github.com/hyperledger/aries-framework-go/pkg/crypto"
+	webcrypto "github.com/hyperledger/aries-framework-go/pkg/crypto/webkms"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/hyperledger/aries-framework-go/pkg/kms/webkms"
+	"github.com/igor-pavlenko/httpsignatures-go"
+	"github.com/trustbloc/edge-core/pkg/zcapld"
+	edv "github.com/trustbloc/edv/pkg/client"
+
+	zcapld2 "github.com/trustbloc/ace/pkg/restapi/csh/operation/zcapld"
+)
+
+// Invoker holds what is needed to sign requests that invoke a zcap.
+type Invoker struct {
+	// Controller is the DID URL of the key used to sign requests.
+	Controller string
+	// ZCAP is the compressed zcap invoked by requests.
+	ZCAP string
+	// KMS holds the controller's key.
+	KMS kms.KeyManager
+	// Crypto signs with the controller's key.
+	Crypto crypto.Crypto
+	// Resolver resolves the controller's DID.
+	Resolver zcapld.VDRResolver
+	// Algorithm, if set, signs requests in place of KMS, Crypto and Resolver, eg. to resolve the controller's DID
+	// with several resolvers.
+	Algorithm httpsignatures.SignatureHashAlgorithm
+}
+
+// KMSClient is a WebKMS client with zcap-signed requests.
+type KMSClient struct {
+	KMS    *webkms.RemoteKMS
+	Crypto *webcrypto.RemoteCrypto
+}
+
+// NewSignedEDVClient returns an EDV client that signs requests invoking the zcap with the "read" action for GET
+// requests and the "write" action for all others.
+func NewSignedEDVClient(baseURL string, invoker *Invoker, opts ...edv.Option) *edv.Client {
+	return edv.New(baseURL, append(opts, EDVSigner(invoker))...)
+}
+
+// NewSignedKMSClient returns a WebKMS client for the keystore that signs requests invoking the zcap with the
+// action of the keystore operation.
+func NewSignedKMSClient(keystoreURL string, httpClient webkms.HTTPClient, invoker *Invoker,
+	opts ...webkms.Opt) *KMSClient {
+	opts = append(opts, KMSSigner(invoker))
+
+	return &KMSClient{
+		KMS:    webkms.New(keystoreURL, httpClient, opts...),
+		Crypto: webcrypto.New(keystoreURL, httpClient, opts...),
+	}
+}
+
+// EDVSigner returns the option signing the requests of an EDV client as NewSignedEDVClient does, for clients built
+// otherwise.
+func EDVSigner(invoker *Invoker) edv.Option {
+	return edv.WithHeaders(invoker.signer(zcapld2.EDVActions()))
+}
+
+// KMSSigner returns the option signing the requests of WebKMS clients as NewSignedKMSClient does, for clients built
+// otherwise.
+func KMSSigner(invoker *Invoker) webkms.Opt {
+	return webkms.WithHeaders(invoker.signer(zcapld2.KMSActions()))
+}
+
+func (i *Invoker) signer(actions *zcapld2.ActionMapper) func(*http.Request) (*http.Header, error) {
+	algorithm := i.Algorithm
+	if algorithm == nil {
+		algorithm = &zcapld.AriesDIDKeySignatureHashAlgorithm{
+			KMS:      i.KMS,
+			Crypto:   i.Crypto,
+			Resolver: i.Resolver,
+		}
+	}
+
+	return zcapld2.NewHTTPSigner(
+		i.Controller,
+		i.ZCAP,
+		actions.Action,
+		&zcapld.AriesDIDKeySecrets{},
+		algorithm,
+	)
+}
@@ -0,0 +1,199 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package zcap_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	"github.com/hyperledger/aries-framework-go/pkg/crypto"
+	"github.com/hyperledger/aries-framework-go/pkg/crypto/tinkcrypto"
+	webcrypto "github.com/hyperledger/aries-framework-go/pkg/crypto/webkms"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/util/signature"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/hyperledger/aries-framework-go/pkg/kms/localkms"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock/noop"
+	"github.com/hyperledger/aries-framework-go/pkg/vdr"
+	"github.com/hyperledger/aries-framework-go/pkg/vdr/fingerprint"
+	"github.com/hyperledger/aries-framework-go/pkg/vdr/key"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/igor-pavlenko/httpsignatures-go"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/zcapld"
+	edv "github.com/trustbloc/edv/pkg/client"
+
+	"github.com/trustbloc/ace/pkg/client/zcap"
+)
+
+func TestNewSignedEDVClient(t *testing.T) {
+	invoker := newInvoker(t)
+	actions := make(chan string, 1)
+
+	serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actions <- invocationAction(t, invoker, r)
+
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer serv.Close()
+
+	client := zcap.NewSignedEDVClient(serv.URL, invoker, edv.WithHTTPClient(&http.Client{}))
+
+	_, err := client.ReadDocument("vault1", "doc1")
+	require.Error(t, err)
+	require.Equal(t, "read", <-actions)
+
+	err = client.DeleteDocument("vault1", "doc1")
+	require.Error(t, err)
+	require.Equal(t, "write", <-actions)
+}
+
+func TestNewSignedKMSClient(t *testing.T) {
+	invoker := newInvoker(t)
+	actions := make(chan string, 1)
+
+	serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actions <- invocationAction(t, invoker, r)
+
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer serv.Close()
+
+	client := zcap.NewSignedKMSClient(serv.URL+"/v1/keystores/ks1", &http.Client{}, invoker)
+
+	_, _, err := client.KMS.Create(kms.ED25519Type)
+	require.Error(t, err)
+	require.Equal(t, "createKey", <-actions)
+
+	_, err = client.Crypto.Sign([]byte("data"), serv.URL+"/v1/keystores/ks1/keys/key1")
+	require.Error(t, err)
+	require.Equal(t, "sign", <-actions)
+
+	_, err = client.Crypto.UnwrapKey(&crypto.RecipientWrappedKey{}, serv.URL+"/v1/keystores/ks1/keys/key1")
+	require.Error(t, err)
+	require.Equal(t, "unwrap", <-actions)
+}
+
+func TestSigners(t *testing.T) {
+	t.Run("Sign the requests of clients built otherwise", func(t *testing.T) {
+		invoker := newInvoker(t)
+		actions := make(chan string, 1)
+
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			actions <- invocationAction(t, invoker, r)
+
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer serv.Close()
+
+		_, err := edv.New(serv.URL, zcap.EDVSigner(invoker)).ReadDocument("vault1", "doc1")
+		require.Error(t, err)
+		require.Equal(t, "read", <-actions)
+
+		_, err = webcrypto.New(serv.URL+"/v1/keystores/ks1", &http.Client{}, zcap.KMSSigner(invoker)).
+			UnwrapKey(&crypto.RecipientWrappedKey{}, serv.URL+"/v1/keystores/ks1/keys/key1")
+		require.Error(t, err)
+		require.Equal(t, "unwrap", <-actions)
+	})
+
+	t.Run("Sign with the algorithm of the invoker", func(t *testing.T) {
+		invoker := newInvoker(t)
+		algorithm := &recordingAlgorithm{}
+
+		// the DID of the controller cannot be resolved without the algorithm
+		invoker.Resolver = vdr.New()
+		invoker.Algorithm = algorithm
+
+		actions := make(chan string, 1)
+
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			actions <- invocationAction(t, invoker, r)
+
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer serv.Close()
+
+		err := zcap.NewSignedEDVClient(serv.URL, invoker).DeleteDocument("vault1", "doc1")
+		require.Error(t, err)
+		require.Equal(t, "write", <-actions)
+		require.Equal(t, []string{invoker.Controller}, algorithm.keyIDs)
+	})
+}
+
+type recordingAlgorithm struct {
+	keyIDs []string
+}
+
+func (a *recordingAlgorithm) Algorithm() string {
+	return "https://github.com/hyperledger/aries-framework-go/zcaps"
+}
+
+func (a *recordingAlgorithm) Create(secret httpsignatures.Secret, _ []byte) ([]byte, error) {
+	a.keyIDs = append(a.keyIDs, secret.KeyID)
+
+	return []byte("signature"), nil
+}
+
+func (a *recordingAlgorithm) Verify(httpsignatures.Secret, []byte, []byte) error {
+	return nil
+}
+
+func invocationAction(t *testing.T, invoker *zcap.Invoker, r *http.Request) string {
+	t.Helper()
+
+	require.Contains(t, r.Header.Get("Signature"), invoker.Controller)
+
+	header := r.Header.Get(zcapld.CapabilityInvocationHTTPHeader)
+	require.Contains(t, header, `capability="`+invoker.ZCAP+`"`)
+
+	i := strings.Index(header, `action="`)
+	require.NotEqual(t, -1, i)
+
+	return strings.TrimSuffix(header[i+len(`action="`):], `"`)
+}
+
+func newInvoker(t *testing.T) *zcap.Invoker {
+	t.Helper()
+
+	km, err := localkms.New("local-lock://test/key-uri/", &kmsProvider{
+		storageProvider: mem.NewProvider(),
+		secretLock:      &noop.NoLock{},
+	})
+	require.NoError(t, err)
+
+	c, err := tinkcrypto.New()
+	require.NoError(t, err)
+
+	signer, err := signature.NewCryptoSigner(c, km, kms.ED25519Type)
+	require.NoError(t, err)
+
+	_, didURL := fingerprint.CreateDIDKey(signer.PublicKeyBytes())
+
+	return &zcap.Invoker{
+		Controller: didURL,
+		ZCAP:       "H4sIAAAAAAAA_zcap",
+		KMS:        km,
+		Crypto:     c,
+		Resolver:   vdr.New(vdr.WithVDR(key.New())),
+	}
+}
+
+type kmsProvider struct {
+	storageProvider storage.Provider
+	secretLock      secretlock.Service
+}
+
+func (k *kmsProvider) StorageProvider() storage.Provider { //nolint:ireturn
+	return k.storageProvider
+}
+
+func (k *kmsProvider) SecretLock() secretlock.Service { //nolint:ireturn
+	return k.secretLock
+}
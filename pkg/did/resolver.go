@@ -0,0 +1,122 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package did
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr"
+)
+
+// DefaultResolveRetryDelay is the delay before retrying a failed resolution unless configured otherwise.
+const DefaultResolveRetryDelay = 500 * time.Millisecond
+
+// ErrResolutionFailed is returned when a DID could not be resolved within the configured attempts.
+var ErrResolutionFailed = errors.New("DID resolution failed")
+
+// ResolvingVDR is a VDR whose resolutions time out and are retried. Its other operations are the ones of the VDR
+// it wraps.
+type ResolvingVDR struct {
+	vdr.VDR
+	timeout time.Duration
+	retries int
+	delay   time.Duration
+}
+
+// ResolveOpt configures a ResolvingVDR.
+type ResolveOpt func(*ResolvingVDR)
+
+// WithResolveTimeout bounds each resolution attempt. Attempts do not time out by default.
+func WithResolveTimeout(timeout time.Duration) ResolveOpt {
+	return func(v *ResolvingVDR) {
+		v.timeout = timeout
+	}
+}
+
+// WithResolveRetries retries failed resolutions the given number of times. They are not retried by default.
+func WithResolveRetries(retries int) ResolveOpt {
+	return func(v *ResolvingVDR) {
+		v.retries = retries
+	}
+}
+
+// WithResolveRetryDelay sets the delay before retrying a failed resolution, DefaultResolveRetryDelay by default.
+func WithResolveRetryDelay(delay time.Duration) ResolveOpt {
+	return func(v *ResolvingVDR) {
+		v.delay = delay
+	}
+}
+
+// NewResolvingVDR wraps the VDR so that its resolutions time out and are retried.
+func NewResolvingVDR(v vdr.VDR, opts ...ResolveOpt) *ResolvingVDR {
+	r := &ResolvingVDR{VDR: v, delay: DefaultResolveRetryDelay}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Read resolves the DID, retrying the attempts which fail or time out. A DID which is not found is not retried.
+// It fails with ErrResolutionFailed, wrapping the error of the last attempt, once the retries are exhausted.
+func (v *ResolvingVDR) Read(id string, opts ...vdr.DIDMethodOption) (*did.DocResolution, error) {
+	var err error
+
+	for attempt := 0; attempt <= v.retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(v.delay)
+		}
+
+		var res *did.DocResolution
+
+		res, err = v.read(id, opts...)
+		if err == nil {
+			return res, nil
+		}
+
+		if errors.Is(err, vdr.ErrNotFound) {
+			return nil, err
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s after %d attempts: %v", ErrResolutionFailed, id, v.retries+1, err) // nolint:errorlint
+}
+
+type readResult struct {
+	res *did.DocResolution
+	err error
+}
+
+// read makes a resolution attempt, abandoning it once it times out.
+func (v *ResolvingVDR) read(id string, opts ...vdr.DIDMethodOption) (*did.DocResolution, error) {
+	if v.timeout <= 0 {
+		return v.VDR.Read(id, opts...)
+	}
+
+	// buffered so that an abandoned attempt does not block once it completes
+	result := make(chan readResult, 1)
+
+	go func() {
+		res, err := v.VDR.Read(id, opts...)
+
+		result <- readResult{res: res, err: err}
+	}()
+
+	timer := time.NewTimer(v.timeout)
+	defer timer.Stop()
+
+	select {
+	case r := <-result:
+		return r.res, r.err
+	case <-timer.C:
+		return nil, fmt.Errorf("timed out after %s", v.timeout)
+	}
+}
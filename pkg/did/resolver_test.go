@@ -0,0 +1,117 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package did_test
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	vdrapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr"
+	vdr2 "github.com/hyperledger/aries-framework-go/pkg/mock/vdr"
+	"github.com/stretchr/testify/require"
+
+	did2 "github.com/trustbloc/ace/pkg/did"
+)
+
+func TestResolvingVDR(t *testing.T) {
+	const id = "did:orb:test"
+
+	// flakyVDR fails the given number of resolutions before succeeding
+	flakyVDR := func(failures int32, err error) (*vdr2.MockVDR, *int32) {
+		var calls int32
+
+		return &vdr2.MockVDR{
+			AcceptValue: true,
+			ReadFunc: func(didID string, _ ...vdrapi.DIDMethodOption) (*did.DocResolution, error) {
+				if atomic.AddInt32(&calls, 1) <= failures {
+					return nil, err
+				}
+
+				return &did.DocResolution{DIDDocument: &did.Doc{ID: didID}}, nil
+			},
+		}, &calls
+	}
+
+	t.Run("Retries failed resolutions", func(t *testing.T) {
+		mock, calls := flakyVDR(2, errors.New("orb unavailable"))
+
+		v := did2.NewResolvingVDR(mock, did2.WithResolveRetries(2), did2.WithResolveRetryDelay(time.Millisecond))
+
+		res, err := v.Read(id)
+		require.NoError(t, err)
+		require.Equal(t, id, res.DIDDocument.ID)
+		require.Equal(t, int32(3), atomic.LoadInt32(calls))
+		require.True(t, v.Accept("orb"))
+	})
+
+	t.Run("Error if the retries are exhausted", func(t *testing.T) {
+		mock, calls := flakyVDR(3, errors.New("orb unavailable"))
+
+		v := did2.NewResolvingVDR(mock, did2.WithResolveRetries(2), did2.WithResolveRetryDelay(time.Millisecond))
+
+		_, err := v.Read(id)
+		require.True(t, errors.Is(err, did2.ErrResolutionFailed))
+		require.Contains(t, err.Error(), "after 3 attempts: orb unavailable")
+		require.Equal(t, int32(3), atomic.LoadInt32(calls))
+	})
+
+	t.Run("Retries resolutions timing out", func(t *testing.T) {
+		var calls int32
+
+		mock := &vdr2.MockVDR{
+			ReadFunc: func(didID string, _ ...vdrapi.DIDMethodOption) (*did.DocResolution, error) {
+				if atomic.AddInt32(&calls, 1) == 1 {
+					time.Sleep(time.Second)
+				}
+
+				return &did.DocResolution{DIDDocument: &did.Doc{ID: didID}}, nil
+			},
+		}
+
+		v := did2.NewResolvingVDR(mock,
+			did2.WithResolveTimeout(50*time.Millisecond),
+			did2.WithResolveRetries(1),
+			did2.WithResolveRetryDelay(time.Millisecond),
+		)
+
+		start := time.Now()
+
+		res, err := v.Read(id)
+		require.NoError(t, err)
+		require.Equal(t, id, res.DIDDocument.ID)
+		require.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("Error if the resolution times out", func(t *testing.T) {
+		mock := &vdr2.MockVDR{
+			ReadFunc: func(string, ...vdrapi.DIDMethodOption) (*did.DocResolution, error) {
+				time.Sleep(time.Second)
+
+				return nil, nil
+			},
+		}
+
+		v := did2.NewResolvingVDR(mock, did2.WithResolveTimeout(50*time.Millisecond))
+
+		_, err := v.Read(id)
+		require.True(t, errors.Is(err, did2.ErrResolutionFailed))
+		require.Contains(t, err.Error(), "timed out after 50ms")
+	})
+
+	t.Run("DIDs not found are not retried", func(t *testing.T) {
+		mock, calls := flakyVDR(1, vdrapi.ErrNotFound)
+
+		v := did2.NewResolvingVDR(mock, did2.WithResolveRetries(2), did2.WithResolveRetryDelay(time.Millisecond))
+
+		_, err := v.Read(id)
+		require.True(t, errors.Is(err, vdrapi.ErrNotFound))
+		require.Equal(t, int32(1), atomic.LoadInt32(calls))
+	})
+}
@@ -40,9 +40,9 @@ type cshClient interface {
 }
 
 type vaultClient interface {
-	CreateAuthorization(vaultID, requestingParty string,
+	CreateAuthorization(ctx context.Context, vaultID, requestingParty string,
 		scope *vault.AuthorizationsScope) (*vault.CreatedAuthorization, error)
-	GetDocMetaData(vaultID, docID string) (*vault.DocumentMetadata, error)
+	GetDocMetaData(ctx context.Context, vaultID, docID string) (*vault.DocumentMetadata, error)
 }
 
 // Service is a service for collecting protected resources.
@@ -63,8 +63,9 @@ func NewService(configService configService, vClient vaultClient, cshClient cshC
 
 // Collect collects protected resource and returns access handle for it.
 func (s *Service) Collect(
-	_ context.Context, protectedData *protect.ProtectedData, requestingPartyDID string) (string, error) {
+	ctx context.Context, protectedData *protect.ProtectedData, requestingPartyDID string) (string, error) {
 	auth, err := s.createQueryOnCSH(
+		ctx,
 		protectedData.DID,
 		protectedData.VCDocID,
 		requestingPartyDID,
@@ -76,13 +77,14 @@ func (s *Service) Collect(
 	return auth, nil
 }
 
-func (s *Service) createQueryOnCSH(vaultID, docID, _ string) (string, error) { // nolint:funlen
+func (s *Service) createQueryOnCSH(ctx context.Context, vaultID, docID, _ string) (string, error) { // nolint:funlen
 	cfg, err := s.configService.Get()
 	if err != nil {
 		return "", fmt.Errorf("failed get config: %w", err)
 	}
 
 	docAuth, err := s.vClient.CreateAuthorization(
+		ctx,
 		vaultID,
 		cfg.CSHPubKeyURL,
 		&vault.AuthorizationsScope{
@@ -99,7 +101,7 @@ func (s *Service) createQueryOnCSH(vaultID, docID, _ string) (string, error) { /
 		return "", errors.New("missing auth token from vault-server")
 	}
 
-	docMeta, err := s.vClient.GetDocMetaData(vaultID, docID)
+	docMeta, err := s.vClient.GetDocMetaData(ctx, vaultID, docID)
 	if err != nil {
 		return "", fmt.Errorf("failed to get doc meta: %w", err)
 	}
@@ -40,7 +40,7 @@ func TestCollect_Success(t *testing.T) {
 		}, nil)
 
 	vaultClient.EXPECT().CreateAuthorization(
-		"did:orb:vault12345", "did:orb:csh123456#122344", gomock.Any()).Return(
+		gomock.Any(), "did:orb:vault12345", "did:orb:csh123456#122344", gomock.Any()).Return(
 		&vault.CreatedAuthorization{
 			Tokens: &vault.Tokens{
 				EDV: "edv-token",
@@ -50,7 +50,7 @@ func TestCollect_Success(t *testing.T) {
 		nil,
 	)
 
-	vaultClient.EXPECT().GetDocMetaData("did:orb:vault12345", "did:orb:vc12345").Return(
+	vaultClient.EXPECT().GetDocMetaData(gomock.Any(), "did:orb:vault12345", "did:orb:vc12345").Return(
 		&vault.DocumentMetadata{
 			ID:        "did:orb:vault12345",
 			URI:       "https://edv/vaultId/doc/docID",
@@ -102,7 +102,7 @@ func TestCollect_BadAuthorization(t *testing.T) {
 			CSHPubKeyURL: "did:orb:csh123456#122344",
 		}, nil)
 
-	vaultClient.EXPECT().CreateAuthorization(gomock.Any(), gomock.Any(), gomock.Any()).
+	vaultClient.EXPECT().CreateAuthorization(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
 		Return(nil, errors.New("create authorization failed"))
 
 	srv := collect.NewService(cfgService, vaultClient, cshService)
@@ -131,7 +131,7 @@ func TestCollect_PostAuthorizationFailed(t *testing.T) {
 		Return(nil, errors.New("post authorization failed"))
 
 	vaultClient.EXPECT().CreateAuthorization(
-		"did:orb:vault12345", "did:orb:csh123456#122344", gomock.Any()).Return(
+		gomock.Any(), "did:orb:vault12345", "did:orb:csh123456#122344", gomock.Any()).Return(
 		&vault.CreatedAuthorization{
 			Tokens: &vault.Tokens{
 				EDV: "edv-token",
@@ -141,7 +141,7 @@ func TestCollect_PostAuthorizationFailed(t *testing.T) {
 		nil,
 	)
 
-	vaultClient.EXPECT().GetDocMetaData("did:orb:vault12345", "did:orb:vc12345").Return(
+	vaultClient.EXPECT().GetDocMetaData(gomock.Any(), "did:orb:vault12345", "did:orb:vc12345").Return(
 		&vault.DocumentMetadata{
 			ID:        "did:orb:vault12345",
 			URI:       "https://edv/vaultId/doc/docID",
@@ -25,11 +25,11 @@ import (
 	"github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr"
 	"github.com/hyperledger/aries-framework-go/pkg/kms"
 	"github.com/hyperledger/aries-framework-go/spi/storage"
-	"github.com/trustbloc/edge-core/pkg/zcapld"
 
 	"github.com/trustbloc/ace/pkg/client/csh/client/operations"
 	cshclientmodels "github.com/trustbloc/ace/pkg/client/csh/models"
 	vccrypto "github.com/trustbloc/ace/pkg/doc/vc/crypto"
+	"github.com/trustbloc/ace/pkg/restapi/csh/operation/zcapld"
 )
 
 const (
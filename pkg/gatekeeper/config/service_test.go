@@ -43,6 +43,7 @@ func TestService_CreateConfig(t *testing.T) {
 		vdr.EXPECT().Resolve("did:orb:test123456").Return(nil, nil)
 
 		zcap := &zcapld.Capability{
+			InvocationTarget: zcapld.InvocationTarget{ID: "profile", Type: "urn:confidentialstoragehub:profile"},
 			Proof: []verifiable.Proof{
 				map[string]interface{}{
 					"verificationMethod": "did:orb:test12345#key1234",
@@ -99,6 +100,7 @@ func TestService_CreateConfig(t *testing.T) {
 		vdr.EXPECT().Resolve("did:orb:test123456").Return(nil, nil)
 
 		zcap := &zcapld.Capability{
+			InvocationTarget: zcapld.InvocationTarget{ID: "profile", Type: "urn:confidentialstoragehub:profile"},
 			Proof: []verifiable.Proof{
 				map[string]interface{}{
 					"verificationMethod": 10,
@@ -19,8 +19,13 @@ type Policy struct {
 	// A list of DIDs identifying entities required to provide authorization for the release of the protected object.
 	Approvers []string `json:"approvers"`
 	// The minimum number of (unique) approvers required before an object may be released back to the handler.
-	// This allows for an "m of N" approval scenario. Constraints: 0 < min_approvers < approvers.length.
+	// This allows for an "m of N" approval scenario. Constraints: 0 <= min_approvers <= approvers.length.
 	MinApprovers int `json:"min_approvers"`
+	// The number of seconds after which the release transactions (tickets) on the objects protected with this policy
+	// expire if they are not collected. Tickets do not expire if 0.
+	TicketExpiry int `json:"ticket_expiry,omitempty"`
+	// Version of the policy, incremented each time the policy is saved.
+	Version int `json:"version"`
 }
 
 // Role is a role of entity represented by DID.
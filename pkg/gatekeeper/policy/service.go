@@ -11,6 +11,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/hyperledger/aries-framework-go/spi/storage"
 )
@@ -19,12 +20,35 @@ const (
 	storeName = "policy"
 )
 
-// ErrNotAllowed is returned when a subject DID is not allowed to proceed under the given policy.
-var ErrNotAllowed = errors.New("not allowed")
+var (
+	// ErrNotAllowed is returned when a subject DID is not allowed to proceed under the given policy.
+	ErrNotAllowed = errors.New("not allowed")
+	// ErrInvalidPolicy is returned when saving a policy configuration that is not valid.
+	ErrInvalidPolicy = errors.New("invalid policy")
+	// ErrVersionMismatch is returned when saving or deleting a policy whose version is not the expected one.
+	ErrVersionMismatch = errors.New("version mismatch")
+)
 
 // Service works with policy configurations.
 type Service struct {
 	store storage.Store
+	// mu serializes the read-modify-write cycles of the policies, so that each one is checked against the version
+	// it replaces.
+	mu sync.Mutex
+}
+
+// Opt is an option of Save and Delete.
+type Opt func(opts *options)
+
+type options struct {
+	expectedVersion *int
+}
+
+// WithExpectedVersion makes Save and Delete fail with ErrVersionMismatch unless the policy exists with this version.
+func WithExpectedVersion(version int) Opt {
+	return func(opts *options) {
+		opts.expectedVersion = &version
+	}
 }
 
 // NewService returns a new instance of Service.
@@ -37,8 +61,26 @@ func NewService(storeProvider storage.Provider) (*Service, error) {
 	return &Service{store: store}, nil
 }
 
-// Save stores policy configuration.
-func (s *Service) Save(_ context.Context, doc *Policy) error {
+// Save stores policy configuration and sets its new version.
+func (s *Service) Save(ctx context.Context, doc *Policy, opts ...Opt) error {
+	if err := validate(doc); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, err := s.current(ctx, doc.ID, opts)
+	if err != nil && !errors.Is(err, storage.ErrDataNotFound) {
+		return err
+	}
+
+	doc.Version = 1
+
+	if current != nil {
+		doc.Version = current.Version + 1
+	}
+
 	b, err := json.Marshal(doc)
 	if err != nil {
 		return fmt.Errorf("marshal policy: %w", err)
@@ -51,6 +93,59 @@ func (s *Service) Save(_ context.Context, doc *Policy) error {
 	return nil
 }
 
+// Delete deletes policy configuration. The objects protected with the policy keep their snapshot of it.
+func (s *Service) Delete(ctx context.Context, policyID string, opts ...Opt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.current(ctx, policyID, opts); err != nil {
+		return err
+	}
+
+	if err := s.store.Delete(policyID); err != nil {
+		return fmt.Errorf("delete policy: %w", err)
+	}
+
+	return nil
+}
+
+// current returns the stored policy, after checking it has the expected version of the options if any.
+func (s *Service) current(ctx context.Context, policyID string, opts []Opt) (*Policy, error) {
+	options := &options{}
+
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	p, err := s.Get(ctx, policyID)
+	if err != nil {
+		if errors.Is(err, storage.ErrDataNotFound) && options.expectedVersion != nil {
+			return nil, fmt.Errorf("%w: expected %d, policy %s does not exist", ErrVersionMismatch,
+				*options.expectedVersion, policyID)
+		}
+
+		return nil, err
+	}
+
+	if options.expectedVersion != nil && *options.expectedVersion != p.Version {
+		return nil, fmt.Errorf("%w: expected %d, current %d", ErrVersionMismatch, *options.expectedVersion, p.Version)
+	}
+
+	return p, nil
+}
+
+func validate(doc *Policy) error {
+	if doc.MinApprovers < 0 || doc.MinApprovers > len(doc.Approvers) {
+		return fmt.Errorf("%w: min_approvers must be between 0 and the number of approvers", ErrInvalidPolicy)
+	}
+
+	if doc.TicketExpiry < 0 {
+		return fmt.Errorf("%w: ticket_expiry must not be negative", ErrInvalidPolicy)
+	}
+
+	return nil
+}
+
 // Check checks if DID is allowed to proceed under the given policy.
 func (s *Service) Check(_ context.Context, policyID, did string, role Role) error {
 	b, err := s.store.Get(policyID)
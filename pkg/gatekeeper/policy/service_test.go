@@ -14,6 +14,7 @@ import (
 	"testing"
 
 	"github.com/hyperledger/aries-framework-go/pkg/mock/storage"
+	storageapi "github.com/hyperledger/aries-framework-go/spi/storage"
 	"github.com/stretchr/testify/require"
 
 	"github.com/trustbloc/ace/pkg/gatekeeper/policy"
@@ -83,6 +84,102 @@ func TestService_Save(t *testing.T) {
 
 		require.EqualError(t, err, "save policy: put error")
 	})
+
+	t.Run("Increments the version", func(t *testing.T) {
+		svc, err := policy.NewService(storage.NewMockStoreProvider())
+		require.NoError(t, err)
+
+		doc := p
+
+		require.NoError(t, svc.Save(context.Background(), &doc))
+		require.Equal(t, 1, doc.Version)
+
+		require.NoError(t, svc.Save(context.Background(), &doc, policy.WithExpectedVersion(1)))
+		require.Equal(t, 2, doc.Version)
+
+		// without an expected version, policies are overwritten
+		require.NoError(t, svc.Save(context.Background(), &doc))
+		require.Equal(t, 3, doc.Version)
+
+		stored, err := svc.Get(context.Background(), testPolicyID)
+		require.NoError(t, err)
+		require.Equal(t, 3, stored.Version)
+	})
+
+	t.Run("Fail if the version is not the expected one", func(t *testing.T) {
+		svc, err := policy.NewService(storage.NewMockStoreProvider())
+		require.NoError(t, err)
+
+		doc := p
+
+		err = svc.Save(context.Background(), &doc, policy.WithExpectedVersion(1))
+		require.True(t, errors.Is(err, policy.ErrVersionMismatch))
+		require.EqualError(t, err, "version mismatch: expected 1, policy test-policy does not exist")
+
+		require.NoError(t, svc.Save(context.Background(), &doc))
+
+		err = svc.Save(context.Background(), &doc, policy.WithExpectedVersion(0))
+		require.EqualError(t, err, "version mismatch: expected 0, current 1")
+	})
+
+	t.Run("Fail if the policy is invalid", func(t *testing.T) {
+		svc, err := policy.NewService(storage.NewMockStoreProvider())
+		require.NoError(t, err)
+
+		for _, doc := range []*policy.Policy{
+			{ID: testPolicyID, Approvers: []string{testDID}, MinApprovers: 2},
+			{ID: testPolicyID, MinApprovers: -1},
+			{ID: testPolicyID, TicketExpiry: -1},
+		} {
+			require.True(t, errors.Is(svc.Save(context.Background(), doc), policy.ErrInvalidPolicy))
+		}
+	})
+}
+
+func TestService_Delete(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		store := storage.NewMockStoreProvider()
+		store.Store.Store[testPolicyID] = storage.DBEntry{Value: []byte(testPolicy)}
+
+		svc, err := policy.NewService(store)
+		require.NoError(t, err)
+
+		require.NoError(t, svc.Delete(context.Background(), testPolicyID))
+
+		_, err = svc.Get(context.Background(), testPolicyID)
+		require.True(t, errors.Is(err, storageapi.ErrDataNotFound))
+	})
+
+	t.Run("Success with expected version", func(t *testing.T) {
+		svc, err := policy.NewService(storage.NewMockStoreProvider())
+		require.NoError(t, err)
+
+		require.NoError(t, svc.Save(context.Background(), &policy.Policy{ID: testPolicyID}))
+
+		err = svc.Delete(context.Background(), testPolicyID, policy.WithExpectedVersion(2))
+		require.EqualError(t, err, "version mismatch: expected 2, current 1")
+
+		require.NoError(t, svc.Delete(context.Background(), testPolicyID, policy.WithExpectedVersion(1)))
+	})
+
+	t.Run("Policy not found", func(t *testing.T) {
+		svc, err := policy.NewService(storage.NewMockStoreProvider())
+		require.NoError(t, err)
+
+		err = svc.Delete(context.Background(), testPolicyID)
+		require.True(t, errors.Is(err, storageapi.ErrDataNotFound))
+	})
+
+	t.Run("Fail to delete policy", func(t *testing.T) {
+		store := storage.NewMockStoreProvider()
+		store.Store.Store[testPolicyID] = storage.DBEntry{Value: []byte(testPolicy)}
+		store.Store.ErrDelete = errors.New("delete error")
+
+		svc, err := policy.NewService(store)
+		require.NoError(t, err)
+
+		require.EqualError(t, svc.Delete(context.Background(), testPolicyID), "delete policy: delete error")
+	})
 }
 
 func TestService_Check(t *testing.T) {
@@ -7,7 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package protect
 
 //nolint: lll
-//go:generate mockgen -destination gomocks_test.go -package protect_test -source=service.go -mock_names vaultClient=MockVault,vdrRegistry=MockVDR,vcIssuer=MockVCIssuer
+//go:generate mockgen -destination gomocks_test.go -package protect_test -source=service.go -mock_names vaultClient=MockVault,vdrRegistry=MockVDR,vcIssuer=MockVCIssuer,policyService=MockPolicyService
 
 import (
 	"context"
@@ -27,6 +27,7 @@ import (
 	"github.com/hyperledger/aries-framework-go/spi/storage"
 	"github.com/trustbloc/edv/pkg/edvutils"
 
+	"github.com/trustbloc/ace/pkg/gatekeeper/policy"
 	"github.com/trustbloc/ace/pkg/restapi/vault"
 )
 
@@ -40,8 +41,8 @@ const (
 var logger = log.New("protect-svc")
 
 type vaultClient interface {
-	CreateVault() (*vault.CreatedVault, error)
-	SaveDoc(vaultID, id string, content interface{}) (*vault.DocumentMetadata, error)
+	CreateVault(ctx context.Context) (*vault.CreatedVault, error)
+	SaveDoc(ctx context.Context, vaultID, id string, content interface{}) (*vault.DocumentMetadata, error)
 }
 
 type vdrRegistry interface {
@@ -52,12 +53,17 @@ type vcIssuer interface {
 	IssueCredential(ctx context.Context, cred []byte) (*verifiable.Credential, error)
 }
 
+type policyService interface {
+	Get(ctx context.Context, policyID string) (*policy.Policy, error)
+}
+
 // Config defines dependencies for Service.
 type Config struct {
 	StoreProvider storage.Provider
 	VaultClient   vaultClient
 	VDR           vdrRegistry
 	VCIssuer      vcIssuer
+	PolicyService policyService
 }
 
 // Service is a service for converting sensitive data into DID.
@@ -66,6 +72,7 @@ type Service struct {
 	vaultClient vaultClient
 	vdr         vdrRegistry
 	issuer      vcIssuer
+	policies    policyService
 }
 
 // NewService returns a new instance of Service.
@@ -85,6 +92,7 @@ func NewService(config *Config) (*Service, error) {
 		vaultClient: config.VaultClient,
 		vdr:         config.VDR,
 		issuer:      config.VCIssuer,
+		policies:    config.PolicyService,
 	}, nil
 }
 
@@ -93,6 +101,8 @@ type ProtectedData struct {
 	DID      string `json:"did"`
 	VCDocID  string `json:"vc_doc_id,omitempty"`
 	PolicyID string `json:"policy_id,omitempty"`
+	// Policy is the snapshot of the policy the data was protected with.
+	Policy *policy.Policy `json:"policy,omitempty"`
 }
 
 // Get gets protected data for target DID.
@@ -137,29 +147,28 @@ func (s *Service) Get(_ context.Context, targetDID string) (*ProtectedData, erro
 	return nil, fmt.Errorf("get protected data: %w", storage.ErrDataNotFound)
 }
 
-// Protect converts sensitive data into DID.
+// Protect converts sensitive data into DID. The policy must exist: the protected data keeps a snapshot of it.
 func (s *Service) Protect(ctx context.Context, target, policyID string) (*ProtectedData, error) {
 	hash, err := calculateHash(target, policyID)
 	if err != nil {
 		return nil, fmt.Errorf("calculate hash: %w", err)
 	}
 
-	b, err := s.store.Get(hash)
-	if err != nil && !errors.Is(err, storage.ErrDataNotFound) {
-		return nil, fmt.Errorf("get protected data by hash: %w", err)
+	existing, err := s.getByHash(hash)
+	if err == nil {
+		return existing, nil
 	}
 
-	if b != nil {
-		var data ProtectedData
-
-		if err = json.Unmarshal(b, &data); err != nil {
-			return nil, fmt.Errorf("unmarshal protected data: %w", err)
-		}
+	if !errors.Is(err, storage.ErrDataNotFound) {
+		return nil, err
+	}
 
-		return &data, nil
+	p, err := s.policies.Get(ctx, policyID)
+	if err != nil {
+		return nil, fmt.Errorf("get policy: %w", err)
 	}
 
-	vaultData, err := s.vaultClient.CreateVault()
+	vaultData, err := s.vaultClient.CreateVault(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("create vault: %w", err)
 	}
@@ -177,7 +186,7 @@ func (s *Service) Protect(ctx context.Context, target, policyID string) (*Protec
 		return nil, fmt.Errorf("resolve did %s : %w", vaultID, err)
 	}
 
-	vcDocID, err := s.saveVCDoc(vaultID, vc)
+	vcDocID, err := s.saveVCDoc(ctx, vaultID, vc)
 	if err != nil {
 		return nil, fmt.Errorf("save vc doc: %w", err)
 	}
@@ -186,9 +195,10 @@ func (s *Service) Protect(ctx context.Context, target, policyID string) (*Protec
 		DID:      vaultID,
 		VCDocID:  vcDocID,
 		PolicyID: policyID,
+		Policy:   p,
 	}
 
-	b, err = json.Marshal(&data)
+	b, err := json.Marshal(&data)
 	if err != nil {
 		return nil, fmt.Errorf("marshal protected data: %w", err)
 	}
@@ -232,13 +242,13 @@ func (s *Service) wrapDataIntoVC(ctx context.Context, sub, data string) (*verifi
 	return vc, nil
 }
 
-func (s *Service) saveVCDoc(vaultID string, vc *verifiable.Credential) (string, error) {
+func (s *Service) saveVCDoc(ctx context.Context, vaultID string, vc *verifiable.Credential) (string, error) {
 	docID, err := edvutils.GenerateEDVCompatibleID()
 	if err != nil {
 		return "", fmt.Errorf("create edv doc id : %w", err)
 	}
 
-	_, err = s.vaultClient.SaveDoc(vaultID, docID, vc)
+	_, err = s.vaultClient.SaveDoc(ctx, vaultID, docID, vc)
 	if err != nil {
 		return "", fmt.Errorf("failed to save doc : %w", err)
 	}
@@ -246,6 +256,25 @@ func (s *Service) saveVCDoc(vaultID string, vc *verifiable.Credential) (string,
 	return docID, nil
 }
 
+func (s *Service) getByHash(hash string) (*ProtectedData, error) {
+	b, err := s.store.Get(hash)
+	if err != nil {
+		if errors.Is(err, storage.ErrDataNotFound) {
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("get protected data by hash: %w", err)
+	}
+
+	var data ProtectedData
+
+	if err = json.Unmarshal(b, &data); err != nil {
+		return nil, fmt.Errorf("unmarshal protected data: %w", err)
+	}
+
+	return &data, nil
+}
+
 func calculateHash(target, policyID string) (string, error) {
 	h := fnv.New128()
 
@@ -21,6 +21,7 @@ import (
 	storageapi "github.com/hyperledger/aries-framework-go/spi/storage"
 	"github.com/stretchr/testify/require"
 
+	"github.com/trustbloc/ace/pkg/gatekeeper/policy"
 	"github.com/trustbloc/ace/pkg/gatekeeper/protect"
 	"github.com/trustbloc/ace/pkg/restapi/vault"
 )
@@ -47,6 +48,7 @@ func TestProtect_StoreGetFailed(t *testing.T) {
 		VaultClient:   vaultClient,
 		VDR:           vdr,
 		VCIssuer:      vcIssuer,
+		PolicyService: newPolicyService(ctrl),
 	})
 	require.NoError(t, err)
 
@@ -77,6 +79,7 @@ func TestProtect_StoreGetExist(t *testing.T) {
 		VaultClient:   vaultClient,
 		VDR:           vdr,
 		VCIssuer:      vcIssuer,
+		PolicyService: newPolicyService(ctrl),
 	})
 	require.NoError(t, err)
 
@@ -86,6 +89,16 @@ func TestProtect_StoreGetExist(t *testing.T) {
 	require.Equal(t, protectedData.DID, "test did")
 }
 
+func newPolicyService(ctrl *gomock.Controller) *MockPolicyService {
+	policyService := NewMockPolicyService(ctrl)
+	policyService.EXPECT().Get(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, policyID string) (*policy.Policy, error) {
+			return &policy.Policy{ID: policyID, Version: 1}, nil
+		}).AnyTimes()
+
+	return policyService
+}
+
 func calculateHash(target, policyID string) (string, error) {
 	h := fnv.New128()
 
@@ -110,10 +123,11 @@ func TestProtect_CreateVaultFailed(t *testing.T) {
 		VaultClient:   vaultClient,
 		VDR:           vdr,
 		VCIssuer:      vcIssuer,
+		PolicyService: newPolicyService(ctrl),
 	})
 	require.NoError(t, err)
 
-	vaultClient.EXPECT().CreateVault().Return(nil, errors.New("create vaultClient failed"))
+	vaultClient.EXPECT().CreateVault(gomock.Any()).Return(nil, errors.New("create vaultClient failed"))
 
 	_, err = svc.Protect(context.Background(), "test data", "policyID")
 
@@ -134,10 +148,11 @@ func TestProtect_WrapVcFailed(t *testing.T) {
 		VaultClient:   vaultClient,
 		VDR:           vdr,
 		VCIssuer:      vcIssuer,
+		PolicyService: newPolicyService(ctrl),
 	})
 	require.NoError(t, err)
 
-	vaultClient.EXPECT().CreateVault().Return(&vault.CreatedVault{
+	vaultClient.EXPECT().CreateVault(gomock.Any()).Return(&vault.CreatedVault{
 		ID: "did:orb:test",
 	}, nil)
 
@@ -162,10 +177,11 @@ func TestProtect_DidDoesNotExists(t *testing.T) {
 		VaultClient:   vaultClient,
 		VDR:           vdr,
 		VCIssuer:      vcIssuer,
+		PolicyService: newPolicyService(ctrl),
 	})
 	require.NoError(t, err)
 
-	vaultClient.EXPECT().CreateVault().Return(&vault.CreatedVault{
+	vaultClient.EXPECT().CreateVault(gomock.Any()).Return(&vault.CreatedVault{
 		ID: "did:orb:test",
 	}, nil)
 
@@ -192,10 +208,11 @@ func TestProtect_SaveDocFailed(t *testing.T) {
 		VaultClient:   vaultClient,
 		VDR:           vdr,
 		VCIssuer:      vcIssuer,
+		PolicyService: newPolicyService(ctrl),
 	})
 	require.NoError(t, err)
 
-	vaultClient.EXPECT().CreateVault().Return(&vault.CreatedVault{
+	vaultClient.EXPECT().CreateVault(gomock.Any()).Return(&vault.CreatedVault{
 		ID: "did:orb:vault",
 	}, nil)
 
@@ -205,7 +222,7 @@ func TestProtect_SaveDocFailed(t *testing.T) {
 
 	vdr.EXPECT().Resolve("did:orb:vault").Return(nil, nil)
 
-	vaultClient.EXPECT().SaveDoc("did:orb:vault", gomock.Any(), vc).Return(nil, errors.New("save doc failed"))
+	vaultClient.EXPECT().SaveDoc(gomock.Any(), "did:orb:vault", gomock.Any(), vc).Return(nil, errors.New("save doc failed"))
 
 	_, err = svc.Protect(context.Background(), "test data", "policyID")
 
@@ -228,10 +245,11 @@ func TestProtect_StorePutFailed(t *testing.T) {
 		VaultClient:   vaultClient,
 		VDR:           vdr,
 		VCIssuer:      vcIssuer,
+		PolicyService: newPolicyService(ctrl),
 	})
 	require.NoError(t, err)
 
-	vaultClient.EXPECT().CreateVault().Return(&vault.CreatedVault{
+	vaultClient.EXPECT().CreateVault(gomock.Any()).Return(&vault.CreatedVault{
 		ID: "did:orb:vault",
 	}, nil)
 
@@ -241,7 +259,7 @@ func TestProtect_StorePutFailed(t *testing.T) {
 
 	vdr.EXPECT().Resolve("did:orb:vault").Return(nil, nil)
 
-	vaultClient.EXPECT().SaveDoc("did:orb:vault", gomock.Any(), vc).Return(nil, nil)
+	vaultClient.EXPECT().SaveDoc(gomock.Any(), "did:orb:vault", gomock.Any(), vc).Return(nil, nil)
 
 	_, err = svc.Protect(context.Background(), "test data", "policyID")
 
@@ -262,10 +280,11 @@ func TestProtect_Success(t *testing.T) {
 		VaultClient:   vaultClient,
 		VDR:           vdr,
 		VCIssuer:      vcIssuer,
+		PolicyService: newPolicyService(ctrl),
 	})
 	require.NoError(t, err)
 
-	vaultClient.EXPECT().CreateVault().Return(&vault.CreatedVault{
+	vaultClient.EXPECT().CreateVault(gomock.Any()).Return(&vault.CreatedVault{
 		ID: "did:orb:vault",
 	}, nil)
 
@@ -275,12 +294,47 @@ func TestProtect_Success(t *testing.T) {
 
 	vdr.EXPECT().Resolve("did:orb:vault").Return(nil, nil)
 
-	vaultClient.EXPECT().SaveDoc("did:orb:vault", gomock.Any(), vc).Return(nil, nil)
+	vaultClient.EXPECT().SaveDoc(gomock.Any(), "did:orb:vault", gomock.Any(), vc).Return(nil, nil)
 
 	protectedData, err := svc.Protect(context.Background(), "test data", "policyID")
 
 	require.Nil(t, err)
 	require.Equal(t, protectedData.DID, "did:orb:vault")
+	require.Equal(t, &policy.Policy{ID: "policyID", Version: 1}, protectedData.Policy)
+
+	hash, err := calculateHash("test data", "policyID")
+	require.NoError(t, err)
+
+	var stored protect.ProtectedData
+
+	require.NoError(t, json.Unmarshal(store.Store.Store[hash].Value, &stored))
+	require.Equal(t, protectedData.Policy, stored.Policy)
+}
+
+func TestProtect_PolicyNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	policyService := NewMockPolicyService(ctrl)
+	policyService.EXPECT().Get(gomock.Any(), "policyID").
+		Return(nil, fmt.Errorf("get policy: %w", storageapi.ErrDataNotFound))
+
+	vaultClient := NewMockVault(ctrl)
+	vaultClient.EXPECT().CreateVault(gomock.Any()).Times(0)
+
+	svc, err := protect.NewService(&protect.Config{
+		StoreProvider: storage.NewMockStoreProvider(),
+		VaultClient:   vaultClient,
+		VDR:           NewMockVDR(ctrl),
+		VCIssuer:      NewMockVCIssuer(ctrl),
+		PolicyService: policyService,
+	})
+	require.NoError(t, err)
+
+	_, err = svc.Protect(context.Background(), "test data", "policyID")
+
+	require.True(t, errors.Is(err, storageapi.ErrDataNotFound))
+	require.EqualError(t, err, "get policy: get policy: data not found")
 }
 
 func TestProtect_GetSuccess(t *testing.T) {
@@ -0,0 +1,183 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package release
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/log"
+
+	"github.com/trustbloc/ace/pkg/gatekeeper/release/ticket"
+)
+
+const (
+	// DefaultWorkers is the default number of workers of a Pool.
+	DefaultWorkers = 4
+	// DefaultQueueDepth is the default number of transitions a Pool queues at most.
+	DefaultQueueDepth = 100
+)
+
+var (
+	// ErrQueueFull is returned when a transition is enqueued while the queue of the pool is full.
+	ErrQueueFull = errors.New("release queue is full")
+	// ErrPoolClosed is returned when a transition is enqueued after the pool was closed.
+	ErrPoolClosed = errors.New("release pool is closed")
+)
+
+var logger = log.New("gatekeeper-release")
+
+type notifier interface {
+	Notify(ctx context.Context, t *ticket.Ticket) error
+}
+
+// PoolConfig defines the configuration of a Pool.
+type PoolConfig struct {
+	// Workers is the number of transitions processed concurrently. Default: DefaultWorkers.
+	Workers int
+	// QueueDepth is the number of transitions waiting to be processed at most. Default: DefaultQueueDepth.
+	QueueDepth int
+	// Notifier is notified of the ticket once a transition is processed, optional.
+	Notifier notifier
+}
+
+type transition struct {
+	ticketID string
+	approver string
+}
+
+// Pool processes the state transitions of tickets in the background: the transitions are queued and processed by
+// a fixed number of workers. The transitions of a ticket are processed by the same worker, in the order they were
+// enqueued in, so that concurrent approvals of a ticket are not lost.
+type Pool struct {
+	service  *Service
+	notifier notifier
+	queues   []chan *transition
+	wg       sync.WaitGroup
+	mu       sync.RWMutex
+	closed   bool
+}
+
+// NewPool returns a new Pool processing the transitions with the service. Its workers run until it is closed.
+func NewPool(service *Service, config *PoolConfig) *Pool {
+	workers := config.Workers
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+
+	depth := config.QueueDepth
+	if depth <= 0 {
+		depth = DefaultQueueDepth
+	}
+
+	p := &Pool{
+		service:  service,
+		notifier: config.Notifier,
+		queues:   make([]chan *transition, workers),
+	}
+
+	for i := range p.queues {
+		// the queue is shared out between the workers
+		p.queues[i] = make(chan *transition, (depth+workers-1)/workers)
+
+		p.wg.Add(1)
+
+		go p.work(p.queues[i])
+	}
+
+	return p
+}
+
+// Authorize enqueues the authorization of the ticket by the approver. The authorization is checked before it is
+// enqueued, see Service.CheckAuthorization, so that the requests that would fail are rejected right away. Fails with
+// ErrQueueFull if the queue is full, in which case the authorization is not processed, and with ErrPoolClosed if the
+// pool is closed.
+func (p *Pool) Authorize(ctx context.Context, ticketID, approver string) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return ErrPoolClosed
+	}
+
+	if err := p.service.CheckAuthorization(ctx, ticketID, approver); err != nil {
+		return err
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(ticketID))
+
+	select {
+	case p.queues[h.Sum32()%uint32(len(p.queues))] <- &transition{ticketID: ticketID, approver: approver}:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Close stops accepting transitions and waits until the queued ones are processed, or until the context is done.
+func (p *Pool) Close(ctx context.Context) error {
+	p.mu.Lock()
+
+	if !p.closed {
+		p.closed = true
+
+		for _, q := range p.queues {
+			close(q)
+		}
+	}
+
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("drain release queue: %w", ctx.Err())
+	}
+}
+
+func (p *Pool) work(queue <-chan *transition) {
+	defer p.wg.Done()
+
+	for t := range queue {
+		if err := p.process(context.Background(), t); err != nil {
+			logger.Errorf("failed to process ticket %s: %s", t.ticketID, err)
+		}
+	}
+}
+
+func (p *Pool) process(ctx context.Context, t *transition) error {
+	if err := p.service.Authorize(ctx, t.ticketID, t.approver); err != nil {
+		return err
+	}
+
+	if p.notifier == nil {
+		return nil
+	}
+
+	updated, err := p.service.Get(ctx, t.ticketID)
+	if err != nil {
+		return fmt.Errorf("get authorized ticket: %w", err)
+	}
+
+	if err = p.notifier.Notify(ctx, updated); err != nil {
+		return fmt.Errorf("notify: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,203 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package release_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/hyperledger/aries-framework-go/pkg/mock/storage"
+	spi "github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/ace/pkg/gatekeeper/policy"
+	"github.com/trustbloc/ace/pkg/gatekeeper/protect"
+	"github.com/trustbloc/ace/pkg/gatekeeper/release"
+	"github.com/trustbloc/ace/pkg/gatekeeper/release/ticket"
+)
+
+func TestPool(t *testing.T) {
+	t.Run("Processes the enqueued transitions", func(t *testing.T) {
+		svc := newPoolService(t, 2)
+		n := &testNotifier{}
+
+		pool := release.NewPool(svc, &release.PoolConfig{Workers: 2, Notifier: n})
+
+		require.NoError(t, pool.Authorize(context.Background(), testTicketID, testApprover))
+		require.NoError(t, pool.Authorize(context.Background(), testTicketID, "did:example:another-approver"))
+		require.NoError(t, pool.Close(context.Background()))
+
+		t1, err := svc.Get(context.Background(), testTicketID)
+		require.NoError(t, err)
+		require.Equal(t, ticket.ReadyToCollect, t1.Status)
+		require.Equal(t, []string{testApprover, "did:example:another-approver"}, t1.ApprovedBy)
+
+		// the notifications follow the transitions of the ticket
+		require.Len(t, n.tickets, 2)
+		require.Equal(t, ticket.Collecting, n.tickets[0].Status)
+		require.Equal(t, ticket.ReadyToCollect, n.tickets[1].Status)
+	})
+
+	t.Run("Fails with ErrQueueFull when the queue is full", func(t *testing.T) {
+		svc := newPoolService(t, 1)
+		n := &testNotifier{started: make(chan struct{}, 1), release: make(chan struct{})}
+
+		pool := release.NewPool(svc, &release.PoolConfig{Workers: 1, QueueDepth: 1, Notifier: n})
+
+		// the first transition keeps the worker busy, the second fills the queue
+		require.NoError(t, pool.Authorize(context.Background(), testTicketID, testApprover))
+		<-n.started
+
+		require.NoError(t, pool.Authorize(context.Background(), testTicketID, testApprover))
+		require.True(t, errors.Is(pool.Authorize(context.Background(), testTicketID, testApprover), release.ErrQueueFull))
+
+		close(n.release)
+
+		require.NoError(t, pool.Close(context.Background()))
+		require.Len(t, n.tickets, 2)
+	})
+
+	t.Run("Close drains the queue", func(t *testing.T) {
+		svc := newPoolService(t, 1)
+		n := &testNotifier{started: make(chan struct{}, 3), release: make(chan struct{})}
+
+		pool := release.NewPool(svc, &release.PoolConfig{Workers: 1, Notifier: n})
+
+		for i := 0; i < 3; i++ {
+			require.NoError(t, pool.Authorize(context.Background(), testTicketID, testApprover))
+		}
+
+		<-n.started
+
+		closed := make(chan error)
+
+		go func() {
+			closed <- pool.Close(context.Background())
+		}()
+
+		require.Eventually(t, func() bool {
+			return errors.Is(pool.Authorize(context.Background(), testTicketID, testApprover), release.ErrPoolClosed)
+		}, time.Second, 10*time.Millisecond)
+
+		close(n.release)
+
+		require.NoError(t, <-closed)
+		require.Len(t, n.tickets, 3)
+	})
+
+	t.Run("Close stops waiting when the context is done", func(t *testing.T) {
+		svc := newPoolService(t, 1)
+		n := &testNotifier{started: make(chan struct{}, 1), release: make(chan struct{})}
+
+		pool := release.NewPool(svc, &release.PoolConfig{Notifier: n})
+
+		require.NoError(t, pool.Authorize(context.Background(), testTicketID, testApprover))
+		<-n.started
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		require.EqualError(t, pool.Close(ctx), "drain release queue: context deadline exceeded")
+
+		close(n.release)
+
+		require.NoError(t, pool.Close(context.Background()))
+	})
+
+	t.Run("Rejects the transitions that would fail before enqueueing them", func(t *testing.T) {
+		svc := newPoolService(t, 1)
+		n := &testNotifier{}
+
+		pool := release.NewPool(svc, &release.PoolConfig{Notifier: n})
+
+		err := pool.Authorize(context.Background(), "unknown-ticket", testApprover)
+		require.ErrorIs(t, err, spi.ErrDataNotFound)
+
+		err = pool.Authorize(context.Background(), testTicketID, "did:example:someone")
+		require.ErrorIs(t, err, release.ErrNotApprover)
+
+		require.NoError(t, pool.Close(context.Background()))
+		require.Empty(t, n.tickets)
+	})
+
+	t.Run("Failed transitions are not notified", func(t *testing.T) {
+		svc := newPoolService(t, 2)
+		n := &testNotifier{started: make(chan struct{}, 1), release: make(chan struct{})}
+
+		pool := release.NewPool(svc, &release.PoolConfig{Workers: 1, Notifier: n})
+
+		require.NoError(t, pool.Authorize(context.Background(), testTicketID, testApprover))
+		<-n.started
+
+		require.NoError(t, pool.Authorize(context.Background(), testTicketID, testApprover))
+
+		// the ticket is closed while the second transition is queued
+		require.NoError(t, svc.Reject(context.Background(), testTicketID))
+
+		close(n.release)
+
+		require.NoError(t, pool.Close(context.Background()))
+		require.Len(t, n.tickets, 1)
+	})
+}
+
+// newPoolService returns a service authorizing the test ticket, which requires the given number of approvals.
+func newPoolService(t *testing.T, minApprovers int) *release.Service {
+	t.Helper()
+
+	ctrl := gomock.NewController(t)
+
+	store := storage.NewMockStoreProvider()
+	store.Store.Store[testTicketID] = storage.DBEntry{Value: []byte(testTicketWithoutApprovements)}
+
+	protectService := NewMockProtectService(ctrl)
+	protectService.EXPECT().Get(gomock.Any(), testDID).
+		Return(&protect.ProtectedData{PolicyID: testPolicyID}, nil).AnyTimes()
+
+	policyService := NewMockPolicyService(ctrl)
+	policyService.EXPECT().Get(gomock.Any(), testPolicyID).Return(&policy.Policy{
+		ID:           testPolicyID,
+		Approvers:    []string{testApprover, "did:example:another-approver"},
+		MinApprovers: minApprovers,
+	}, nil).AnyTimes()
+
+	svc, err := release.NewService(&release.Config{
+		StoreProvider:  store,
+		ProtectService: protectService,
+		PolicyService:  policyService,
+	})
+	require.NoError(t, err)
+
+	return svc
+}
+
+// testNotifier records the notified tickets. If release is set, it blocks until release is closed, signaling on
+// started that a notification is in progress.
+type testNotifier struct {
+	mu      sync.Mutex
+	tickets []*ticket.Ticket
+	started chan struct{}
+	release chan struct{}
+}
+
+func (n *testNotifier) Notify(_ context.Context, t *ticket.Ticket) error {
+	if n.release != nil {
+		n.started <- struct{}{}
+		<-n.release
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.tickets = append(n.tickets, t)
+
+	return nil
+}
@@ -11,8 +11,13 @@ package release
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/hyperledger/aries-framework-go/spi/storage"
@@ -22,7 +27,18 @@ import (
 	"github.com/trustbloc/ace/pkg/gatekeeper/release/ticket"
 )
 
-const storeName = "ticket"
+const (
+	storeName = "ticket"
+	// openTicketIndex tags the open tickets with the hash of their DID and requesting party.
+	openTicketIndex = "openTicket"
+)
+
+var (
+	// ErrInvalidTransition is returned when a ticket cannot move from its status to the requested one.
+	ErrInvalidTransition = errors.New("invalid ticket transition")
+	// ErrNotApprover is returned when a ticket is authorized by a DID that is not an approver of its policy.
+	ErrNotApprover = errors.New("not an approver of the ticket")
+)
 
 type policyService interface {
 	Get(ctx context.Context, policyID string) (*policy.Policy, error)
@@ -44,6 +60,9 @@ type Service struct {
 	store          storage.Store
 	policyService  policyService
 	protectService protectService
+	// mu serializes the read-modify-write cycles of the tickets, so that each transition is checked against the
+	// status it replaces.
+	mu sync.Mutex
 }
 
 // NewService returns a new instance of Service.
@@ -53,6 +72,11 @@ func NewService(config *Config) (*Service, error) {
 		return nil, fmt.Errorf("open ticket store: %w", err)
 	}
 
+	err = config.StoreProvider.SetStoreConfig(storeName, storage.StoreConfiguration{TagNames: []string{openTicketIndex}})
+	if err != nil {
+		return nil, fmt.Errorf("set ticket store configuration: %w", err)
+	}
+
 	return &Service{
 		store:          store,
 		policyService:  config.PolicyService,
@@ -60,28 +84,62 @@ func NewService(config *Config) (*Service, error) {
 	}, nil
 }
 
-// Release creates release transaction (ticket) on the protected resource (DID).
-func (s *Service) Release(_ context.Context, did string) (*ticket.Ticket, error) {
-	t := &ticket.Ticket{
-		ID:     uuid.New().String(),
-		DID:    did,
-		Status: ticket.New,
+// Release creates release transaction (ticket) on the protected resource (DID) for the requesting party, with a
+// snapshot of the policy of the resource. If the party already has an open ticket on the DID, that ticket is
+// returned instead.
+func (s *Service) Release(ctx context.Context, did, requestingParty string) (*ticket.Ticket, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.openTicket(did, requestingParty)
+	if err == nil {
+		return t, nil
 	}
 
-	b, err := json.Marshal(t)
+	if !errors.Is(err, storage.ErrDataNotFound) {
+		return nil, fmt.Errorf("find open ticket: %w", err)
+	}
+
+	data, err := s.protectService.Get(ctx, did)
+	if err != nil {
+		return nil, fmt.Errorf("get protected data: %w", err)
+	}
+
+	p, err := s.policyService.Get(ctx, data.PolicyID)
 	if err != nil {
-		return nil, fmt.Errorf("marshal ticket: %w", err)
+		return nil, fmt.Errorf("get policy: %w", err)
 	}
 
-	if err = s.store.Put(t.ID, b); err != nil {
+	now := time.Now().UTC()
+
+	t = &ticket.Ticket{
+		ID:              uuid.New().String(),
+		DID:             did,
+		RequestingParty: requestingParty,
+		Policy:          p,
+		Status:          ticket.New,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+
+	if err = s.put(t); err != nil {
 		return nil, fmt.Errorf("store ticket: %w", err)
 	}
 
 	return t, nil
 }
 
-// Get retrieves ticket from the underlying storage by ID.
+// Get retrieves ticket from the underlying storage by ID. Open tickets past the ticket expiry of their policy are
+// marked as expired first.
 func (s *Service) Get(_ context.Context, ticketID string) (*ticket.Ticket, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.get(ticketID)
+}
+
+// get retrieves the ticket and expires it if needed. The caller must hold the lock.
+func (s *Service) get(ticketID string) (*ticket.Ticket, error) {
 	b, err := s.store.Get(ticketID)
 	if err != nil {
 		return nil, fmt.Errorf("get ticket: %w", err)
@@ -93,24 +151,39 @@ func (s *Service) Get(_ context.Context, ticketID string) (*ticket.Ticket, error
 		return nil, fmt.Errorf("unmarshal ticket: %w", err)
 	}
 
+	if err = s.expire(&t); err != nil {
+		return nil, err
+	}
+
 	return &t, nil
 }
 
-// Authorize authorizes ticket by approver.
-func (s *Service) Authorize(ctx context.Context, ticketID, approver string) error {
-	t, err := s.Get(ctx, ticketID)
-	if err != nil {
-		return fmt.Errorf("get ticket to authorize: %w", err)
+// expire marks the ticket as expired if it is past the ticket expiry of its policy. The caller must hold the lock.
+func (s *Service) expire(t *ticket.Ticket) error {
+	if !t.Expired(time.Now()) {
+		return nil
 	}
 
-	data, err := s.protectService.Get(ctx, t.DID)
+	if err := s.transition(t, ticket.Expired); err != nil {
+		return fmt.Errorf("expire ticket: %w", err)
+	}
+
+	return nil
+}
+
+// Authorize authorizes ticket by approver, under the policy the ticket was created with.
+func (s *Service) Authorize(ctx context.Context, ticketID, approver string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.get(ticketID)
 	if err != nil {
-		return fmt.Errorf("get protected data: %w", err)
+		return fmt.Errorf("get ticket to authorize: %w", err)
 	}
 
-	p, err := s.policyService.Get(ctx, data.PolicyID)
+	p, err := s.ticketPolicy(ctx, t)
 	if err != nil {
-		return fmt.Errorf("get policy: %w", err)
+		return err
 	}
 
 	for _, a := range p.Approvers {
@@ -131,20 +204,183 @@ func (s *Service) Authorize(ctx context.Context, ticketID, approver string) erro
 		}
 	}
 
+	next := ticket.ReadyToCollect
+
 	if len(t.ApprovedBy) < p.MinApprovers {
-		t.Status = ticket.Collecting
-	} else {
-		t.Status = ticket.ReadyToCollect
+		next = ticket.Collecting
 	}
 
-	b, err := json.Marshal(t)
+	if err = s.transition(t, next); err != nil {
+		return fmt.Errorf("update ticket: %w", err)
+	}
+
+	return nil
+}
+
+// CheckAuthorization checks that the approver may authorize the ticket: the ticket exists, is still open and the
+// approver is one of the policy the ticket was created with. Fails with an error wrapping storage.ErrDataNotFound,
+// ErrInvalidTransition or ErrNotApprover otherwise.
+func (s *Service) CheckAuthorization(ctx context.Context, ticketID, approver string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.get(ticketID)
 	if err != nil {
-		return fmt.Errorf("marshal ticket: %w", err)
+		return fmt.Errorf("get ticket to authorize: %w", err)
 	}
 
-	if err = s.store.Put(t.ID, b); err != nil {
+	if !t.Status.CanTransitionTo(ticket.ReadyToCollect) {
+		return fmt.Errorf("%w: %s ticket cannot be authorized", ErrInvalidTransition, t.Status)
+	}
+
+	p, err := s.ticketPolicy(ctx, t)
+	if err != nil {
+		return err
+	}
+
+	for _, a := range p.Approvers {
+		if a == approver {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s", ErrNotApprover, approver)
+}
+
+// Collect marks the ticket as collected. Only the tickets ready to collect may be collected, once.
+func (s *Service) Collect(_ context.Context, ticketID string) error {
+	return s.close(ticketID, ticket.Collected)
+}
+
+// Reject marks the open ticket as rejected.
+func (s *Service) Reject(_ context.Context, ticketID string) error {
+	return s.close(ticketID, ticket.Rejected)
+}
+
+// Expire marks the open ticket as expired.
+func (s *Service) Expire(_ context.Context, ticketID string) error {
+	return s.close(ticketID, ticket.Expired)
+}
+
+func (s *Service) close(ticketID string, status ticket.Status) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.get(ticketID)
+	if err != nil {
+		return err
+	}
+
+	if err = s.transition(t, status); err != nil {
 		return fmt.Errorf("update ticket: %w", err)
 	}
 
 	return nil
 }
+
+// ticketPolicy returns the policy snapshot of the ticket, or the current policy of its DID for the tickets created
+// without a snapshot.
+func (s *Service) ticketPolicy(ctx context.Context, t *ticket.Ticket) (*policy.Policy, error) {
+	if t.Policy != nil {
+		return t.Policy, nil
+	}
+
+	data, err := s.protectService.Get(ctx, t.DID)
+	if err != nil {
+		return nil, fmt.Errorf("get protected data: %w", err)
+	}
+
+	p, err := s.policyService.Get(ctx, data.PolicyID)
+	if err != nil {
+		return nil, fmt.Errorf("get policy: %w", err)
+	}
+
+	return p, nil
+}
+
+// transition moves the ticket to the status and stores it. The caller must hold the lock.
+func (s *Service) transition(t *ticket.Ticket, status ticket.Status) error {
+	if !t.Status.CanTransitionTo(status) {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidTransition, t.Status, status)
+	}
+
+	t.Status = status
+	t.UpdatedAt = time.Now().UTC()
+
+	return s.put(t)
+}
+
+// put stores the ticket along with its index in a single write, so that a ticket is never stored without the index
+// of its status. The open tickets only are indexed.
+func (s *Service) put(t *ticket.Ticket) error {
+	b, err := json.Marshal(t)
+	if err != nil {
+		return fmt.Errorf("marshal ticket: %w", err)
+	}
+
+	var tags []storage.Tag
+
+	if t.Status.Open() {
+		tags = append(tags, storage.Tag{Name: openTicketIndex, Value: openTicketKey(t.DID, t.RequestingParty)})
+	}
+
+	return s.store.Put(t.ID, b, tags...)
+}
+
+func (s *Service) openTicket(did, requestingParty string) (*ticket.Ticket, error) {
+	iter, err := s.store.Query(openTicketIndex + ":" + openTicketKey(did, requestingParty))
+	if err != nil {
+		return nil, fmt.Errorf("query tickets: %w", err)
+	}
+
+	defer func() {
+		if closeErr := iter.Close(); closeErr != nil {
+			logger.Errorf("Failed to close iterator: %s", closeErr.Error())
+		}
+	}()
+
+	for {
+		var ok bool
+
+		ok, err = iter.Next()
+		if err != nil {
+			return nil, fmt.Errorf("next entry: %w", err)
+		}
+
+		if !ok {
+			break
+		}
+
+		var v []byte
+
+		v, err = iter.Value()
+		if err != nil {
+			return nil, fmt.Errorf("get value: %w", err)
+		}
+
+		var t ticket.Ticket
+
+		if err = json.Unmarshal(v, &t); err != nil {
+			return nil, fmt.Errorf("unmarshal ticket: %w", err)
+		}
+
+		if err = s.expire(&t); err != nil {
+			return nil, err
+		}
+
+		// the index is updated along with the ticket, the status is checked in case the store lags behind
+		if t.Status.Open() && t.DID == did && t.RequestingParty == requestingParty {
+			return &t, nil
+		}
+	}
+
+	return nil, storage.ErrDataNotFound
+}
+
+// openTicketKey returns the value of the index of the open tickets, hashed since DIDs hold colons, which separate
+// the name and the value of the tags in queries.
+func openTicketKey(did, requestingParty string) string {
+	h := sha256.Sum256([]byte(did + "\n" + requestingParty))
+
+	return hex.EncodeToString(h[:])
+}
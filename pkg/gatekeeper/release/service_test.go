@@ -8,21 +8,26 @@ package release_test
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/hyperledger/aries-framework-go/pkg/mock/storage"
+	spi "github.com/hyperledger/aries-framework-go/spi/storage"
 	"github.com/stretchr/testify/require"
 
 	"github.com/trustbloc/ace/pkg/gatekeeper/policy"
 	"github.com/trustbloc/ace/pkg/gatekeeper/protect"
 	"github.com/trustbloc/ace/pkg/gatekeeper/release"
+	"github.com/trustbloc/ace/pkg/gatekeeper/release/ticket"
 )
 
 const (
 	testDID      = "did:example:test"
 	testApprover = "did:example:approver"
+	testHandler  = "did:example:handler"
 	testPolicyID = "test-policy"
 	testTicketID = "test-ticket"
 	testTicket   = `{
@@ -33,6 +38,18 @@ const (
 		"did:example:approver"
 	  ]
 	}`
+	testTicketWithPolicy = `{
+	  "id": "test-ticket",
+	  "did": "did:example:test",
+	  "requesting_party": "did:example:handler",
+	  "policy": {
+		"id": "test-policy",
+		"approvers": ["did:example:approver"],
+		"min_approvers": 1
+	  },
+	  "status": 0,
+	  "approved_by": []
+	}`
 	testTicketWithoutApprovements = `{
 	  "id": "test-ticket",
 	  "did": "did:example:test",
@@ -54,6 +71,18 @@ func TestNewService(t *testing.T) {
 		require.Nil(t, svc)
 	})
 
+	t.Run("Fail to set store configuration", func(t *testing.T) {
+		store := storage.NewMockStoreProvider()
+		store.ErrSetStoreConfig = errors.New("config error")
+
+		svc, err := release.NewService(&release.Config{
+			StoreProvider: store,
+		})
+
+		require.EqualError(t, err, "set ticket store configuration: config error")
+		require.Nil(t, svc)
+	})
+
 	t.Run("Success", func(t *testing.T) {
 		svc, err := release.NewService(&release.Config{
 			StoreProvider: storage.NewMockStoreProvider(),
@@ -65,31 +94,144 @@ func TestNewService(t *testing.T) {
 }
 
 func TestService_Release(t *testing.T) {
+	testPolicy := &policy.Policy{
+		ID:           testPolicyID,
+		Handlers:     []string{testHandler},
+		Approvers:    []string{testApprover},
+		MinApprovers: 1,
+	}
+
+	newService := func(t *testing.T, store *storage.MockStoreProvider) *release.Service {
+		t.Helper()
+
+		ctrl := gomock.NewController(t)
+
+		protectService := NewMockProtectService(ctrl)
+		protectService.EXPECT().Get(gomock.Any(), testDID).
+			Return(&protect.ProtectedData{DID: testDID, PolicyID: testPolicyID}, nil).AnyTimes()
+
+		policyService := NewMockPolicyService(ctrl)
+		policyService.EXPECT().Get(gomock.Any(), testPolicyID).Return(testPolicy, nil).AnyTimes()
+
+		svc, err := release.NewService(&release.Config{
+			StoreProvider:  store,
+			ProtectService: protectService,
+			PolicyService:  policyService,
+		})
+		require.NoError(t, err)
+
+		return svc
+	}
+
 	t.Run("Fail to store ticket", func(t *testing.T) {
 		store := storage.NewMockStoreProvider()
 		store.Store.ErrPut = errors.New("put error")
 
+		ticket, err := newService(t, store).Release(context.Background(), testDID, testHandler)
+
+		require.EqualError(t, err, "store ticket: put error")
+		require.Nil(t, ticket)
+	})
+
+	t.Run("Fail to find open ticket", func(t *testing.T) {
+		store := storage.NewMockStoreProvider()
+		store.Store.ErrQuery = errors.New("query error")
+
+		ticket, err := newService(t, store).Release(context.Background(), testDID, testHandler)
+
+		require.EqualError(t, err, "find open ticket: query tickets: query error")
+		require.Nil(t, ticket)
+	})
+
+	t.Run("Fail to get protected data", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		protectService := NewMockProtectService(ctrl)
+		protectService.EXPECT().Get(gomock.Any(), testDID).Return(nil, errors.New("get error"))
+
 		svc, err := release.NewService(&release.Config{
-			StoreProvider: store,
+			StoreProvider:  storage.NewMockStoreProvider(),
+			ProtectService: protectService,
 		})
 		require.NoError(t, err)
 
-		ticket, err := svc.Release(context.Background(), testDID)
+		ticket, err := svc.Release(context.Background(), testDID, testHandler)
 
-		require.EqualError(t, err, "store ticket: put error")
+		require.EqualError(t, err, "get protected data: get error")
 		require.Nil(t, ticket)
 	})
 
-	t.Run("Success", func(t *testing.T) {
+	t.Run("Fail to get policy", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		protectService := NewMockProtectService(ctrl)
+		protectService.EXPECT().Get(gomock.Any(), testDID).Return(&protect.ProtectedData{PolicyID: testPolicyID}, nil)
+
+		policyService := NewMockPolicyService(ctrl)
+		policyService.EXPECT().Get(gomock.Any(), testPolicyID).Return(nil, errors.New("get error"))
+
 		svc, err := release.NewService(&release.Config{
-			StoreProvider: storage.NewMockStoreProvider(),
+			StoreProvider:  storage.NewMockStoreProvider(),
+			ProtectService: protectService,
+			PolicyService:  policyService,
 		})
 		require.NoError(t, err)
 
-		ticket, err := svc.Release(context.Background(), testDID)
+		ticket, err := svc.Release(context.Background(), testDID, testHandler)
+
+		require.EqualError(t, err, "get policy: get error")
+		require.Nil(t, ticket)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		store := storage.NewMockStoreProvider()
+
+		t1, err := newService(t, store).Release(context.Background(), testDID, testHandler)
 
 		require.NoError(t, err)
-		require.NotNil(t, ticket)
+		require.NotEmpty(t, t1.ID)
+		require.Equal(t, testDID, t1.DID)
+		require.Equal(t, testHandler, t1.RequestingParty)
+		require.Equal(t, testPolicy, t1.Policy)
+		require.Equal(t, ticket.New, t1.Status)
+		require.False(t, t1.CreatedAt.IsZero())
+		require.Equal(t, t1.CreatedAt, t1.UpdatedAt)
+
+		var stored ticket.Ticket
+
+		require.NoError(t, json.Unmarshal(store.Store.Store[t1.ID].Value, &stored))
+		require.Equal(t, t1.Policy, stored.Policy)
+		require.Len(t, store.Store.Store[t1.ID].Tags, 1)
+	})
+
+	t.Run("Returns the open ticket of the party", func(t *testing.T) {
+		svc := newService(t, storage.NewMockStoreProvider())
+
+		t1, err := svc.Release(context.Background(), testDID, testHandler)
+		require.NoError(t, err)
+
+		t2, err := svc.Release(context.Background(), testDID, testHandler)
+		require.NoError(t, err)
+		require.Equal(t, t1.ID, t2.ID)
+
+		t3, err := svc.Release(context.Background(), testDID, "did:example:another-handler")
+		require.NoError(t, err)
+		require.NotEqual(t, t1.ID, t3.ID)
+	})
+
+	t.Run("Creates a new ticket once the open ticket is closed", func(t *testing.T) {
+		store := storage.NewMockStoreProvider()
+		svc := newService(t, store)
+
+		t1, err := svc.Release(context.Background(), testDID, testHandler)
+		require.NoError(t, err)
+
+		require.NoError(t, svc.Reject(context.Background(), t1.ID))
+		require.Empty(t, store.Store.Store[t1.ID].Tags)
+
+		t2, err := svc.Release(context.Background(), testDID, testHandler)
+		require.NoError(t, err)
+		require.NotEqual(t, t1.ID, t2.ID)
 	})
 }
 
@@ -269,3 +411,264 @@ func TestService_Authorize(t *testing.T) {
 		require.NoError(t, err)
 	})
 }
+
+func TestService_Authorize_PolicySnapshot(t *testing.T) {
+	t.Run("Authorizes under the policy of the ticket", func(t *testing.T) {
+		store := storage.NewMockStoreProvider()
+		store.Store.Store[testTicketID] = storage.DBEntry{Value: []byte(testTicketWithPolicy)}
+
+		// the protected data and the policy are not read
+		svc, err := release.NewService(&release.Config{
+			StoreProvider: store,
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, svc.Authorize(context.Background(), testTicketID, testApprover))
+
+		updated, err := svc.Get(context.Background(), testTicketID)
+		require.NoError(t, err)
+		require.Equal(t, ticket.ReadyToCollect, updated.Status)
+		require.Equal(t, []string{testApprover}, updated.ApprovedBy)
+		require.False(t, updated.UpdatedAt.IsZero())
+	})
+
+	t.Run("Fail to authorize a closed ticket", func(t *testing.T) {
+		store := storage.NewMockStoreProvider()
+		store.Store.Store[testTicketID] = storage.DBEntry{Value: []byte(testTicketWithPolicy)}
+
+		svc, err := release.NewService(&release.Config{
+			StoreProvider: store,
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, svc.Expire(context.Background(), testTicketID))
+
+		err = svc.Authorize(context.Background(), testTicketID, testApprover)
+		require.True(t, errors.Is(err, release.ErrInvalidTransition))
+		require.EqualError(t, err, "update ticket: invalid ticket transition: EXPIRED to READY_TO_COLLECT")
+	})
+}
+
+func TestService_CheckAuthorization(t *testing.T) {
+	newService := func(t *testing.T) *release.Service {
+		t.Helper()
+
+		store := storage.NewMockStoreProvider()
+		store.Store.Store[testTicketID] = storage.DBEntry{Value: []byte(testTicketWithPolicy)}
+
+		svc, err := release.NewService(&release.Config{
+			StoreProvider: store,
+		})
+		require.NoError(t, err)
+
+		return svc
+	}
+
+	t.Run("Approver of an open ticket", func(t *testing.T) {
+		svc := newService(t)
+
+		require.NoError(t, svc.CheckAuthorization(context.Background(), testTicketID, testApprover))
+
+		// the ticket is left as it is
+		unchanged, err := svc.Get(context.Background(), testTicketID)
+		require.NoError(t, err)
+		require.Equal(t, ticket.New, unchanged.Status)
+		require.Empty(t, unchanged.ApprovedBy)
+	})
+
+	t.Run("Unknown ticket", func(t *testing.T) {
+		err := newService(t).CheckAuthorization(context.Background(), "unknown-ticket", testApprover)
+		require.ErrorIs(t, err, spi.ErrDataNotFound)
+	})
+
+	t.Run("Not an approver", func(t *testing.T) {
+		err := newService(t).CheckAuthorization(context.Background(), testTicketID, "did:example:someone")
+		require.ErrorIs(t, err, release.ErrNotApprover)
+	})
+
+	t.Run("Closed ticket", func(t *testing.T) {
+		svc := newService(t)
+
+		require.NoError(t, svc.Reject(context.Background(), testTicketID))
+
+		err := svc.CheckAuthorization(context.Background(), testTicketID, testApprover)
+		require.ErrorIs(t, err, release.ErrInvalidTransition)
+	})
+}
+
+func TestService_Transitions(t *testing.T) {
+	transitions := map[string]func(svc *release.Service, ticketID string) error{
+		"COLLECTED": func(svc *release.Service, ticketID string) error {
+			return svc.Collect(context.Background(), ticketID)
+		},
+		"REJECTED": func(svc *release.Service, ticketID string) error {
+			return svc.Reject(context.Background(), ticketID)
+		},
+		"EXPIRED": func(svc *release.Service, ticketID string) error {
+			return svc.Expire(context.Background(), ticketID)
+		},
+	}
+
+	allowed := map[ticket.Status]map[string]bool{
+		ticket.New:            {"REJECTED": true, "EXPIRED": true},
+		ticket.Collecting:     {"REJECTED": true, "EXPIRED": true},
+		ticket.ReadyToCollect: {"COLLECTED": true, "REJECTED": true, "EXPIRED": true},
+		ticket.Collected:      {},
+		ticket.Rejected:       {},
+		ticket.Expired:        {},
+	}
+
+	for from, next := range allowed {
+		for to, transition := range transitions {
+			from, to, transition := from, to, transition
+
+			t.Run(from.String()+" to "+to, func(t *testing.T) {
+				b, err := json.Marshal(&ticket.Ticket{ID: testTicketID, DID: testDID, Status: from})
+				require.NoError(t, err)
+
+				store := storage.NewMockStoreProvider()
+				store.Store.Store[testTicketID] = storage.DBEntry{Value: b}
+
+				svc, err := release.NewService(&release.Config{
+					StoreProvider: store,
+				})
+				require.NoError(t, err)
+
+				err = transition(svc, testTicketID)
+
+				updated, getErr := svc.Get(context.Background(), testTicketID)
+				require.NoError(t, getErr)
+
+				if next[to] {
+					require.NoError(t, err)
+					require.Equal(t, to, updated.Status.String())
+
+					return
+				}
+
+				require.True(t, errors.Is(err, release.ErrInvalidTransition))
+				require.Equal(t, from, updated.Status)
+			})
+		}
+	}
+
+	t.Run("Fail to get ticket", func(t *testing.T) {
+		store := storage.NewMockStoreProvider()
+		store.Store.ErrGet = errors.New("get error")
+
+		svc, err := release.NewService(&release.Config{
+			StoreProvider: store,
+		})
+		require.NoError(t, err)
+
+		require.EqualError(t, svc.Collect(context.Background(), testTicketID), "get ticket: get error")
+	})
+
+	t.Run("Fail to store ticket", func(t *testing.T) {
+		store := storage.NewMockStoreProvider()
+		store.Store.Store[testTicketID] = storage.DBEntry{Value: []byte(testTicketWithPolicy)}
+		store.Store.ErrPut = errors.New("put error")
+
+		svc, err := release.NewService(&release.Config{
+			StoreProvider: store,
+		})
+		require.NoError(t, err)
+
+		require.EqualError(t, svc.Reject(context.Background(), testTicketID), "update ticket: put error")
+	})
+}
+
+func TestService_TicketExpiry(t *testing.T) {
+	newTicket := func(t *testing.T, createdAt time.Time) []byte {
+		t.Helper()
+
+		b, err := json.Marshal(&ticket.Ticket{
+			ID:        testTicketID,
+			DID:       testDID,
+			Policy:    &policy.Policy{ID: testPolicyID, TicketExpiry: 60},
+			Status:    ticket.Collecting,
+			CreatedAt: createdAt,
+			UpdatedAt: createdAt,
+		})
+		require.NoError(t, err)
+
+		return b
+	}
+
+	t.Run("Expires the open tickets past the ticket expiry of their policy", func(t *testing.T) {
+		store := storage.NewMockStoreProvider()
+		store.Store.Store[testTicketID] = storage.DBEntry{Value: newTicket(t, time.Now().Add(-time.Hour))}
+
+		svc, err := release.NewService(&release.Config{
+			StoreProvider: store,
+		})
+		require.NoError(t, err)
+
+		expired, err := svc.Get(context.Background(), testTicketID)
+		require.NoError(t, err)
+		require.Equal(t, ticket.Expired, expired.Status)
+
+		var stored ticket.Ticket
+
+		require.NoError(t, json.Unmarshal(store.Store.Store[testTicketID].Value, &stored))
+		require.Equal(t, ticket.Expired, stored.Status)
+
+		err = svc.Collect(context.Background(), testTicketID)
+		require.True(t, errors.Is(err, release.ErrInvalidTransition))
+	})
+
+	t.Run("Keeps the tickets open until the ticket expiry of their policy", func(t *testing.T) {
+		store := storage.NewMockStoreProvider()
+		store.Store.Store[testTicketID] = storage.DBEntry{Value: newTicket(t, time.Now())}
+
+		svc, err := release.NewService(&release.Config{
+			StoreProvider: store,
+		})
+		require.NoError(t, err)
+
+		open, err := svc.Get(context.Background(), testTicketID)
+		require.NoError(t, err)
+		require.Equal(t, ticket.Collecting, open.Status)
+	})
+
+	t.Run("Creates a new ticket once the open ticket expired", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		protectService := NewMockProtectService(ctrl)
+		protectService.EXPECT().Get(gomock.Any(), testDID).
+			Return(&protect.ProtectedData{DID: testDID, PolicyID: testPolicyID}, nil).Times(2)
+
+		policyService := NewMockPolicyService(ctrl)
+		policyService.EXPECT().Get(gomock.Any(), testPolicyID).
+			Return(&policy.Policy{ID: testPolicyID, TicketExpiry: 60}, nil).Times(2)
+
+		store := storage.NewMockStoreProvider()
+
+		svc, err := release.NewService(&release.Config{
+			StoreProvider:  store,
+			ProtectService: protectService,
+			PolicyService:  policyService,
+		})
+		require.NoError(t, err)
+
+		t1, err := svc.Release(context.Background(), testDID, testHandler)
+		require.NoError(t, err)
+
+		// the ticket was created an hour ago
+		t1.CreatedAt = t1.CreatedAt.Add(-time.Hour)
+
+		b, err := json.Marshal(t1)
+		require.NoError(t, err)
+
+		store.Store.Store[t1.ID] = storage.DBEntry{Value: b, Tags: store.Store.Store[t1.ID].Tags}
+
+		t2, err := svc.Release(context.Background(), testDID, testHandler)
+		require.NoError(t, err)
+		require.NotEqual(t, t1.ID, t2.ID)
+
+		expired, err := svc.Get(context.Background(), t1.ID)
+		require.NoError(t, err)
+		require.Equal(t, ticket.Expired, expired.Status)
+		require.Empty(t, store.Store.Store[t1.ID].Tags)
+	})
+}
@@ -6,6 +6,12 @@ SPDX-License-Identifier: Apache-2.0
 
 package ticket
 
+import (
+	"time"
+
+	"github.com/trustbloc/ace/pkg/gatekeeper/policy"
+)
+
 // Status is a ticket release status.
 type Status int
 
@@ -16,6 +22,12 @@ const (
 	Collecting
 	// ReadyToCollect represents a ticket ready to collect.
 	ReadyToCollect
+	// Collected represents a ticket whose protected data was collected.
+	Collected
+	// Rejected represents a ticket whose release was rejected.
+	Rejected
+	// Expired represents a ticket that expired before it was collected.
+	Expired
 )
 
 // String returns string representation of Status.
@@ -27,15 +39,56 @@ func (s Status) String() string {
 		return "COLLECTING"
 	case ReadyToCollect:
 		return "READY_TO_COLLECT"
+	case Collected:
+		return "COLLECTED"
+	case Rejected:
+		return "REJECTED"
+	case Expired:
+		return "EXPIRED"
 	default:
 		return ""
 	}
 }
 
+// Open returns true if the release transaction of a ticket in this status is not over.
+func (s Status) Open() bool {
+	return s == New || s == Collecting || s == ReadyToCollect
+}
+
+// CanTransitionTo returns true if a ticket in this status may move to the next status:
+// NEW → COLLECTING → READY_TO_COLLECT → COLLECTED, and any open status to REJECTED or EXPIRED.
+func (s Status) CanTransitionTo(next Status) bool {
+	switch next {
+	case Collecting:
+		return s == New || s == Collecting
+	case ReadyToCollect:
+		return s.Open()
+	case Collected:
+		return s == ReadyToCollect
+	case Rejected, Expired:
+		return s.Open()
+	default:
+		return false
+	}
+}
+
 // Ticket represents a ticket to release protected resource (DID).
 type Ticket struct {
-	ID         string   `json:"id"`
-	DID        string   `json:"did"`
-	Status     Status   `json:"status"`
-	ApprovedBy []string `json:"approved_by"`
+	ID              string         `json:"id"`
+	DID             string         `json:"did"`
+	RequestingParty string         `json:"requesting_party,omitempty"`
+	Policy          *policy.Policy `json:"policy,omitempty"`
+	Status          Status         `json:"status"`
+	ApprovedBy      []string       `json:"approved_by"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+}
+
+// Expired returns true if the ticket is still open past the ticket expiry of its policy.
+func (t *Ticket) Expired(now time.Time) bool {
+	if !t.Status.Open() || t.Policy == nil || t.Policy.TicketExpiry <= 0 {
+		return false
+	}
+
+	return now.After(t.CreatedAt.Add(time.Duration(t.Policy.TicketExpiry) * time.Second))
 }
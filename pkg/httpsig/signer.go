@@ -25,14 +25,14 @@ const (
 // DefaultGetSignerConfig returns the default configuration for signing HTTP GET requests.
 func DefaultGetSignerConfig() SignerConfig {
 	return SignerConfig{
-		Headers: []string{"(request-target)", "Date"},
+		Headers: []string{"(request-target)", "(created)", "Date"},
 	}
 }
 
 // DefaultPostSignerConfig returns the default configuration for signing HTTP POST requests.
 func DefaultPostSignerConfig() SignerConfig {
 	return SignerConfig{
-		Headers: []string{"(request-target)", "Date", "Digest"},
+		Headers: []string{"(request-target)", "(created)", "Date", "Digest"},
 	}
 }
 
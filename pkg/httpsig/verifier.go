@@ -55,7 +55,7 @@ func (v *Verifier) VerifyRequest(req *http.Request) (bool, string) {
 		return false, ""
 	}
 
-	keyID := getKeyIDFromSignatureHeader(req)
+	keyID := KeyID(req)
 	keyIDParts := strings.Split(keyID, "#")
 
 	if len(keyIDParts) != 2 { //nolint:gomnd
@@ -69,7 +69,8 @@ func (v *Verifier) VerifyRequest(req *http.Request) (bool, string) {
 	return true, keyIDParts[0]
 }
 
-func getKeyIDFromSignatureHeader(req *http.Request) string {
+// KeyID returns the key ID of the HTTP signature of the request, or an empty string if the request is not signed.
+func KeyID(req *http.Request) string {
 	signatureHeader, ok := req.Header["Signature"]
 	if !ok || len(signatureHeader) == 0 {
 		logger.Debugf("'Signature' not found in request header for request %s", req.URL)
@@ -12,9 +12,10 @@ import (
 
 // MockProvider is a mock edge storage provider that can hold several stores, not just one.
 type MockProvider struct {
-	Stores   map[string]storage.Store
-	OpenErr  error
-	CloseErr error
+	Stores       map[string]storage.Store
+	OpenErr      error
+	SetConfigErr error
+	CloseErr     error
 }
 
 // OpenStore opens the store.
@@ -31,9 +32,9 @@ func (m *MockProvider) OpenStore(name string) (storage.Store, error) {
 	return s, nil
 }
 
-// SetStoreConfig is not implemented.
+// SetStoreConfig returns SetConfigErr.
 func (m *MockProvider) SetStoreConfig(name string, config storage.StoreConfiguration) error {
-	panic("implement me")
+	return m.SetConfigErr
 }
 
 // GetStoreConfig is not implemented.
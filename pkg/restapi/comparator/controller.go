@@ -7,6 +7,9 @@ SPDX-License-Identifier: Apache-2.0
 package comparator
 
 import (
+	"context"
+	"time"
+
 	"github.com/trustbloc/ace/pkg/restapi/comparator/operation"
 	"github.com/trustbloc/ace/pkg/restapi/handler"
 )
@@ -19,13 +22,25 @@ func New(config *operation.Config) (*Controller, error) {
 	}
 
 	return &Controller{
-		handlers: comparatorService.GetRESTHandlers(),
+		handlers:  comparatorService.GetRESTHandlers(),
+		operation: comparatorService,
 	}, nil
 }
 
 // Controller contains handlers for controller.
 type Controller struct {
-	handlers []handler.Handler
+	handlers  []handler.Handler
+	operation *operation.Operation
+}
+
+// MonitorDID periodically checks that the comparator's DID is resolvable until the context is done.
+func (c *Controller) MonitorDID(ctx context.Context, interval time.Duration) {
+	c.operation.MonitorDID(ctx, interval)
+}
+
+// DIDReady returns the result of the last check of the comparator's DID.
+func (c *Controller) DIDReady() error {
+	return c.operation.DIDReady()
 }
 
 // GetOperations returns all controller endpoints.
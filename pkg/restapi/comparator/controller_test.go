@@ -7,8 +7,10 @@ SPDX-License-Identifier: Apache-2.0
 package comparator_test
 
 import (
+	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	mockstorage "github.com/hyperledger/aries-framework-go/pkg/mock/storage"
 	"github.com/stretchr/testify/require"
@@ -55,5 +57,27 @@ func TestController_GetOperations(t *testing.T) {
 
 	ops := controller.GetOperations()
 
-	require.Equal(t, 4, len(ops))
+	require.Equal(t, 5, len(ops))
+}
+
+func TestController_DIDReady(t *testing.T) {
+	s := &mockstorage.MockStore{Store: make(map[string]mockstorage.DBEntry)}
+	s.Store["config"] = mockstorage.DBEntry{Value: []byte(`{}`)}
+	s.Store["csh_config"] = mockstorage.DBEntry{Value: []byte(`{}`)}
+	controller, err := comparator.New(&operation.Config{
+		CSHBaseURL:    "https://localhost",
+		StoreProvider: &mockstorage.MockStoreProvider{Store: s},
+	})
+	require.NoError(t, err)
+
+	require.Error(t, controller.DIDReady())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	controller.MonitorDID(ctx, time.Minute)
+
+	err = controller.DIDReady()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "comparator DID is not configured")
 }
@@ -7,13 +7,19 @@ SPDX-License-Identifier: Apache-2.0
 package operation
 
 import (
+	"context"
 	"crypto/ed25519"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"regexp"
 	"strings"
+	"time"
 
+	"github.com/go-openapi/strfmt"
+	"github.com/google/uuid"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/jsonld"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/suite"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/suite/ed25519signature2018"
@@ -23,11 +29,51 @@ import (
 	"github.com/trustbloc/ace/pkg/client/csh/client/operations"
 	cshclientmodels "github.com/trustbloc/ace/pkg/client/csh/models"
 	"github.com/trustbloc/ace/pkg/restapi/comparator/operation/models"
+	cshzcapld "github.com/trustbloc/ace/pkg/restapi/csh/operation/zcapld"
 )
 
+// errRevoked is returned when the vault server revoked an upstream zcap.
+var errRevoked = errors.New("zcap has been revoked")
+
+const (
+	referenceAction  = "reference"
+	readAction       = "read"
+	edvDocTargetType = "urn:edv:document"
+)
+
+// DefaultCSHQueryTargetType is the invocation target type of the zcaps the comparator issues for CSH queries,
+// unless configured otherwise.
+const DefaultCSHQueryTargetType = "urn:confidentialstoragehub:query"
+
+// urnPattern matches URNs as defined by RFC 8141: a namespace identifier and a non-empty namespace-specific string.
+var urnPattern = regexp.MustCompile(`^(?i:urn):[a-zA-Z0-9][a-zA-Z0-9-]{0,30}[a-zA-Z0-9]:\S+$`)
+
+// CheckURN fails unless the value is a URN, eg. urn:confidentialstoragehub:query.
+func CheckURN(value string) error {
+	if !urnPattern.MatchString(value) {
+		return fmt.Errorf("%q is not a URN", value)
+	}
+
+	return nil
+}
+
 // HandleAuthz handles a CreateAuthzReq.
-func (o *Operation) HandleAuthz(w http.ResponseWriter, authz *models.Authorization) { //nolint: funlen
-	docMeta, err := o.vaultClient.GetDocMetaData(authz.Scope.VaultID, *authz.Scope.DocID)
+func (o *Operation) HandleAuthz(ctx context.Context, w http.ResponseWriter, authz *models.Authorization) { //nolint: funlen,gocyclo,cyclop
+	expiry, err := o.applyExpiry(authz.Scope)
+	if err != nil {
+		respondErrorf(w, http.StatusBadRequest, "invalid expiry caveat: %s", err.Error())
+
+		return
+	}
+
+	err = o.checkRevoked(ctx, authz.Scope.AuthTokens.Edv, authz.Scope.AuthTokens.Kms)
+	if err != nil {
+		respondErrorf(w, revocationErrorStatus(err), "invalid auth tokens: %s", err.Error())
+
+		return
+	}
+
+	docMeta, err := o.getDocMetaData(ctx, authz.Scope.VaultID, *authz.Scope.DocID)
 	if err != nil {
 		respondErrorf(w, http.StatusInternalServerError, "failed to get doc meta: %s", err.Error())
 
@@ -36,35 +82,39 @@ func (o *Operation) HandleAuthz(w http.ResponseWriter, authz *models.Authorizati
 
 	kmsURL, err := url.Parse(docMeta.EncKeyURI)
 	if err != nil {
+		o.invalidateDocMetaData(authz.Scope.VaultID, *authz.Scope.DocID)
 		respondErrorf(w, http.StatusInternalServerError, "failed to parse enc key uri: %s", err.Error())
 
 		return
 	}
 
-	edvURL, err := url.Parse(docMeta.URI)
+	edvDoc, err := locateEDVDocument(docMeta)
 	if err != nil {
+		o.invalidateDocMetaData(authz.Scope.VaultID, *authz.Scope.DocID)
 		respondErrorf(w, http.StatusInternalServerError, "failed to parse doc uri: %s", err.Error())
 
 		return
 	}
 
-	parts := strings.Split(docMeta.URI, "/")
+	edvToken, err := o.driveEDVZCAPForCSH(authz.Scope.AuthTokens.Edv, docMeta.URI, authz.Scope.Caveats())
+	if err != nil {
+		respondErrorf(w, chainErrorStatus(err), "failed to drive EDV zcap for csh: %s", err.Error())
 
-	vaultID := parts[len(parts)-3]
-	docID := parts[len(parts)-1]
+		return
+	}
 
 	response, err := o.cshClient.PostHubstoreProfilesProfileIDQueries(
 		operations.NewPostHubstoreProfilesProfileIDQueriesParams().
 			WithTimeout(requestTimeout).
 			WithProfileID(o.cshProfile.ID).
 			WithRequest(&cshclientmodels.DocQuery{
-				VaultID: &vaultID,
-				DocID:   &docID,
+				VaultID: &edvDoc.vaultID,
+				DocID:   &edvDoc.docID,
 				Path:    authz.Scope.DocAttrPath,
 				UpstreamAuth: &cshclientmodels.DocQueryAO1UpstreamAuth{
 					Edv: &cshclientmodels.UpstreamAuthorization{
-						BaseURL: fmt.Sprintf("%s://%s/%s", edvURL.Scheme, edvURL.Host, parts[3]),
-						Zcap:    authz.Scope.AuthTokens.Edv,
+						BaseURL: edvDoc.baseURL,
+						Zcap:    edvToken,
 					},
 					Kms: &cshclientmodels.UpstreamAuthorization{
 						BaseURL: fmt.Sprintf("%s://%s", kmsURL.Scheme, kmsURL.Host),
@@ -73,11 +123,19 @@ func (o *Operation) HandleAuthz(w http.ResponseWriter, authz *models.Authorizati
 				},
 			}))
 	if err != nil {
+		o.invalidateDocMetaData(authz.Scope.VaultID, *authz.Scope.DocID)
 		respondErrorf(w, http.StatusInternalServerError, "failed to create query: %s", err.Error())
 
 		return
 	}
 
+	expiry, err = o.capToProfileExpiry(authz.Scope, expiry)
+	if err != nil {
+		respondErrorf(w, http.StatusInternalServerError, "failed to drive child zcap from csh zcap: %s", err.Error())
+
+		return
+	}
+
 	// TODO - encode docPathAttr in zcap token
 	// deriving a child zcap for csh
 	zcap, err := o.driveZCAPForCSH(*authz.RequestingParty, response.Location,
@@ -88,46 +146,264 @@ func (o *Operation) HandleAuthz(w http.ResponseWriter, authz *models.Authorizati
 		return
 	}
 
-	authToken, err := zcapld.CompressZCAP(zcap)
+	authToken, err := cshzcapld.CompressZCAP(&cshzcapld.Capability{Capability: zcap}, o.zcapCompression...)
 	if err != nil {
 		respondErrorf(w, http.StatusInternalServerError, "failed to compress zcap: %s", err.Error())
 
 		return
 	}
 
+	result := &models.Authorization{
+		ID:               uuid.New().String(),
+		RequestingParty:  authz.RequestingParty,
+		AuthToken:        authToken,
+		InvocationTarget: zcap.InvocationTarget.ID,
+		AllowedActions:   zcap.AllowedAction,
+	}
+
+	if expiry > 0 {
+		expiresAt := strfmt.DateTime(time.Now().UTC().Add(expiry))
+		result.ExpiresAt = &expiresAt
+	}
+
+	if err = o.saveAuthz(result, authz.Scope); err != nil {
+		respondErrorf(w, http.StatusInternalServerError, "failed to store authorization: %s", err.Error())
+
+		return
+	}
+
 	headers := map[string]string{
 		"Content-Type": "application/json",
 	}
 
-	respond(w, http.StatusOK, headers, models.Authorization{
-		RequestingParty: authz.RequestingParty,
-		AuthToken:       authToken,
-	})
+	respond(w, http.StatusOK, headers, result)
+}
+
+// applyExpiry injects the configured default expiry caveat into the scope if it has none, and returns the
+// effective expiry of the authorization. Zero means the authorization does not expire.
+func (o *Operation) applyExpiry(scope *models.Scope) (time.Duration, error) {
+	var expiry *models.ExpiryCaveat
+
+	for _, caveat := range scope.Caveats() {
+		if c, ok := caveat.(*models.ExpiryCaveat); ok {
+			expiry = c
+
+			break
+		}
+	}
+
+	if expiry == nil {
+		if o.authzExpiry.Default == 0 {
+			return 0, nil
+		}
+
+		expiry = &models.ExpiryCaveat{Duration: int64(o.authzExpiry.Default / time.Second)}
+
+		scope.SetCaveats(append(scope.Caveats(), expiry))
+	}
+
+	duration := time.Duration(expiry.Duration) * time.Second
+
+	if o.authzExpiry.Max > 0 && duration > o.authzExpiry.Max {
+		return 0, fmt.Errorf("duration %s exceeds the maximum of %s", duration, o.authzExpiry.Max)
+	}
+
+	return duration, nil
+}
+
+// capToProfileExpiry ensures the authorization does not outlive the CSH profile zcap its own zcap is derived
+// from. The scope's expiry caveat is replaced if it would, and the effective expiry is returned.
+func (o *Operation) capToProfileExpiry(scope *models.Scope, expiry time.Duration) (time.Duration, error) {
+	expires, err := cshzcapld.Expires(o.cshProfile.Zcap)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse CSH profile zcap: %w", err)
+	}
+
+	if expires == nil {
+		return expiry, nil
+	}
+
+	remaining := time.Until(*expires).Truncate(time.Second)
+	if remaining <= 0 {
+		return 0, fmt.Errorf("CSH profile zcap expired at %s", expires.Format(time.RFC3339))
+	}
+
+	if expiry > 0 && expiry <= remaining {
+		return expiry, nil
+	}
+
+	caveats := []models.Caveat{&models.ExpiryCaveat{Duration: int64(remaining / time.Second)}}
+
+	for _, caveat := range scope.Caveats() {
+		if _, ok := caveat.(*models.ExpiryCaveat); !ok {
+			caveats = append(caveats, caveat)
+		}
+	}
+
+	scope.SetCaveats(caveats)
+
+	return remaining, nil
+}
+
+// checkRevoked fails with errRevoked if the vault server revoked any of the upstream zcaps. Tokens that are not
+// zcaps cannot be honored by the EDV and KMS either and are left for them to reject.
+func (o *Operation) checkRevoked(ctx context.Context, tokens ...string) error {
+	for _, token := range tokens {
+		zcap, err := decompressZCAP(token)
+		if err != nil {
+			continue
+		}
+
+		revoked, err := o.vaultClient.IsRevoked(ctx, zcap.ID)
+		if err != nil {
+			return fmt.Errorf("failed to check the revocation of zcap %s: %w", zcap.ID, err)
+		}
+
+		if revoked {
+			return fmt.Errorf("%w: %s", errRevoked, zcap.ID)
+		}
+	}
+
+	return nil
+}
+
+// revocationErrorStatus maps revoked upstream zcaps to 403.
+func revocationErrorStatus(err error) int {
+	if errors.Is(err, errRevoked) {
+		return http.StatusForbidden
+	}
+
+	return http.StatusInternalServerError
+}
+
+// chainErrorStatus maps upstream zcaps delegated more times than allowed to 403.
+func chainErrorStatus(err error) int {
+	if errors.Is(err, cshzcapld.ErrChainTooDeep) {
+		return http.StatusForbidden
+	}
+
+	return http.StatusInternalServerError
+}
+
+// saveAuthz stores the authorization record. The upstream auth tokens are not persisted.
+func (o *Operation) saveAuthz(authz *models.Authorization, scope *models.Scope) error {
+	storedScope := *scope
+	storedScope.AuthTokens = nil
+
+	record := *authz
+	record.Scope = &storedScope
+
+	raw, err := record.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("failed to marshal authorization: %w", err)
+	}
+
+	return o.store.Put(authzKeyPrefix+authz.ID, raw)
 }
 
 func (o *Operation) driveZCAPForCSH(invokerDID, queryIDPath string,
 	caveats []models.Caveat) (*zcapld.Capability, error) {
-	cshZCAP, err := zcapld.DecompressZCAP(o.cshProfile.Zcap)
+	cshZCAP, err := decompressZCAP(o.cshProfile.Zcap)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse CSH profile zcap: %w", err)
 	}
 
+	signer, err := o.zcapSigner()
+	if err != nil {
+		return nil, err
+	}
+
+	return zcapld.NewCapability(signer, zcapld.WithParent(cshZCAP.ID), zcapld.WithInvoker(invokerDID),
+		zcapld.WithAllowedActions(referenceAction),
+		zcapld.WithCaveats(toZCaveats(caveats)...),
+		zcapld.WithInvocationTarget(queryIDPath, o.cshQueryTargetType),
+		zcapld.WithCapabilityChain(cshZCAP.ID),
+	)
+}
+
+// driveEDVZCAPForCSH delegates the upstream EDV zcap to the CSH, only allowing it to read the document. The
+// delegated zcap targets the document if the EDV servers support it, the whole vault otherwise. Zcaps not invoked
+// by the comparator cannot be delegated and are passed on to the CSH as is. Zcaps whose delegation to the CSH would
+// exceed the maximum chain depth are rejected.
+func (o *Operation) driveEDVZCAPForCSH(edvToken, docURI string, caveats []models.Caveat) (string, error) {
+	edvZCAP, err := decompressZCAP(edvToken)
+	if err != nil || strings.Split(edvZCAP.Invoker, "#")[0] != *o.comparatorConfig.Did {
+		return edvToken, nil // nolint:nilerr
+	}
+
+	cshZCAP, err := decompressZCAP(o.cshProfile.Zcap)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse CSH profile zcap: %w", err)
+	}
+
+	// the CSH invokes upstream zcaps with the key it signed its profile zcap with
+	if len(cshZCAP.Proof) == 0 {
+		return "", errors.New("CSH profile zcap has no proof")
+	}
+
+	cshInvoker, ok := cshZCAP.Proof[0]["verificationMethod"].(string)
+	if !ok {
+		return "", errors.New("CSH profile zcap proof has no verification method")
+	}
+
+	target := edvZCAP.InvocationTarget
+	if o.edvDocTargets {
+		target = zcapld.InvocationTarget{ID: docURI, Type: edvDocTargetType}
+	}
+
+	var chain []interface{}
+
+	if len(edvZCAP.Proof) > 0 {
+		chain, _ = edvZCAP.Proof[0]["capabilityChain"].([]interface{}) // nolint:errcheck
+	}
+
+	signer, err := o.zcapSigner()
+	if err != nil {
+		return "", err
+	}
+
+	zcap, err := zcapld.NewCapability(signer, zcapld.WithParent(edvZCAP.ID), zcapld.WithInvoker(cshInvoker),
+		zcapld.WithAllowedActions(readAction),
+		zcapld.WithCaveats(toZCaveats(caveats)...),
+		zcapld.WithInvocationTarget(target.ID, target.Type),
+		zcapld.WithCapabilityChain(append(chain, edvZCAP.ID)...),
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to create EDV zcap: %w", err)
+	}
+
+	err = cshzcapld.CheckChainDepth(zcap, o.maxChainDepth)
+	if err != nil {
+		return "", err
+	}
+
+	return cshzcapld.CompressZCAP(&cshzcapld.Capability{Capability: zcap}, o.edvZCAPCompression...)
+}
+
+// decompressZCAP parses zcaps compressed by the comparator, the CSH or the vault server. Those below the
+// compression threshold of the comparator or the CSH are not compressed.
+func decompressZCAP(compressed string) (*zcapld.Capability, error) {
+	zcap, err := cshzcapld.DecompressZCAP(compressed)
+	if err != nil {
+		return nil, err
+	}
+
+	return zcap.Capability, nil
+}
+
+// zcapSigner signs the zcaps delegated by the comparator with its key.
+func (o *Operation) zcapSigner() (*zcapld.Signer, error) {
 	keyID, key, err := getKey(o.comparatorConfig)
 	if err != nil {
 		return nil, err
 	}
 
-	return zcapld.NewCapability(&zcapld.Signer{
+	return &zcapld.Signer{
 		SignatureSuite:     ed25519signature2018.New(suite.WithSigner(&ed25519Signer{key: key})),
 		SuiteType:          ed25519signature2018.SignatureType,
 		VerificationMethod: fmt.Sprintf("%s#%s", *o.comparatorConfig.Did, keyID),
 		ProcessorOpts:      []jsonld.ProcessorOpts{jsonld.WithDocumentLoader(o.documentLoader)},
-	}, zcapld.WithParent(cshZCAP.ID), zcapld.WithInvoker(invokerDID),
-		zcapld.WithAllowedActions("reference"),
-		zcapld.WithCaveats(toZCaveats(caveats)...),
-		zcapld.WithInvocationTarget(queryIDPath, "urn:confidentialstoragehub:query"),
-		zcapld.WithCapabilityChain(cshZCAP.ID),
-	)
+	}, nil
 }
 
 func getKey(comparatorConfig *models.Config) (string, ed25519.PrivateKey, error) {
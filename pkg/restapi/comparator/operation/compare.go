@@ -7,48 +7,54 @@ SPDX-License-Identifier: Apache-2.0
 package operation
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/url"
 	"strings"
 
-	"github.com/trustbloc/edge-core/pkg/zcapld"
-
 	"github.com/trustbloc/ace/pkg/client/csh/client/operations"
 	cshclientmodels "github.com/trustbloc/ace/pkg/client/csh/models"
 	"github.com/trustbloc/ace/pkg/restapi/comparator/operation/models"
 )
 
 // HandleEqOp handles a ComparisonRequest using the EqOp operator.
-func (o *Operation) HandleEqOp(w http.ResponseWriter, op *models.EqOp) { //nolint: funlen
+func (o *Operation) HandleEqOp(ctx context.Context, w http.ResponseWriter, op *models.EqOp) { //nolint: funlen
 	queries := make([]cshclientmodels.Query, 0)
+	docs := make([]docMetaKey, 0)
 
 	for i := range op.Args() {
 		query := op.Args()[i]
 
 		switch q := query.(type) {
 		case *models.DocQuery:
-			docMeta, err := o.vaultClient.GetDocMetaData(*q.VaultID, *q.DocID)
+			err := o.checkRevoked(ctx, q.AuthTokens.Edv, q.AuthTokens.Kms)
 			if err != nil {
-				respondErrorf(w, http.StatusInternalServerError, "failed to get doc meta: %s", err.Error())
+				respondErrorf(w, revocationErrorStatus(err), "invalid auth tokens: %s", err.Error())
 
 				return
 			}
 
-			parts := strings.Split(docMeta.URI, "/")
+			docMeta, err := o.getDocMetaData(ctx, *q.VaultID, *q.DocID)
+			if err != nil {
+				respondErrorf(w, http.StatusInternalServerError, "failed to get doc meta: %s", err.Error())
 
-			vaultID := parts[len(parts)-3]
-			docID := parts[len(parts)-1]
+				return
+			}
+
+			docs = append(docs, docMetaKey{vaultID: *q.VaultID, docID: *q.DocID})
 
 			kmsURL, err := url.Parse(docMeta.EncKeyURI)
 			if err != nil {
+				o.invalidateDocMetaData(*q.VaultID, *q.DocID)
 				respondErrorf(w, http.StatusInternalServerError, "failed to parse url: %s", err.Error())
 
 				return
 			}
 
-			edvURL, err := url.Parse(docMeta.URI)
+			edvDoc, err := locateEDVDocument(docMeta)
 			if err != nil {
+				o.invalidateDocMetaData(*q.VaultID, *q.DocID)
 				respondErrorf(w, http.StatusInternalServerError, "failed to parse url: %s", err.Error())
 
 				return
@@ -57,12 +63,12 @@ func (o *Operation) HandleEqOp(w http.ResponseWriter, op *models.EqOp) { //nolin
 			queries = append(
 				queries,
 				&cshclientmodels.DocQuery{
-					VaultID: &vaultID,
-					DocID:   &docID,
+					VaultID: &edvDoc.vaultID,
+					DocID:   &edvDoc.docID,
 					Path:    q.DocAttrPath,
 					UpstreamAuth: &cshclientmodels.DocQueryAO1UpstreamAuth{
 						Edv: &cshclientmodels.UpstreamAuthorization{
-							BaseURL: fmt.Sprintf("%s://%s/%s", edvURL.Scheme, edvURL.Host, parts[3]),
+							BaseURL: edvDoc.baseURL,
 							Zcap:    q.AuthTokens.Edv,
 						},
 						Kms: &cshclientmodels.UpstreamAuthorization{
@@ -73,7 +79,7 @@ func (o *Operation) HandleEqOp(w http.ResponseWriter, op *models.EqOp) { //nolin
 				},
 			)
 		case *models.AuthorizedQuery:
-			orgZCAP, err := zcapld.DecompressZCAP(*q.AuthToken)
+			orgZCAP, err := decompressZCAP(*q.AuthToken)
 			if err != nil {
 				respondErrorf(w, http.StatusInternalServerError, "failed to parse org zcap: %s", err.Error())
 
@@ -98,6 +104,11 @@ func (o *Operation) HandleEqOp(w http.ResponseWriter, op *models.EqOp) { //nolin
 			WithRequest(request),
 	)
 	if err != nil {
+		// the comparison may have failed because of stale metadata
+		for _, doc := range docs {
+			o.docMetaCache.invalidate(doc)
+		}
+
 		respondErrorf(w, http.StatusInternalServerError, "failed to execute comparison: %s", err)
 
 		return
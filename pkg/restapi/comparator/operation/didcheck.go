@@ -0,0 +1,111 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+)
+
+// errDIDNotChecked is reported until the comparator's DID has been checked at least once.
+var errDIDNotChecked = errors.New("comparator DID has not been checked yet")
+
+// didStatus holds the result of the last check of the comparator's DID.
+type didStatus struct {
+	mu  sync.RWMutex
+	err error
+}
+
+func (s *didStatus) get() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.err
+}
+
+func (s *didStatus) set(err error) (prev error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev, s.err = s.err, err
+
+	return prev
+}
+
+// CheckDID resolves the comparator's DID and verifies that the key the comparator signs zcaps with is one of its
+// capabilityDelegation methods. Zcaps issued by the comparator fail verification until this check succeeds.
+func (o *Operation) CheckDID() error {
+	err := o.checkDID()
+
+	prev := o.didStatus.set(err)
+
+	switch {
+	case err != nil:
+		logger.Errorf("comparator DID is not usable, the zcaps it issues will fail verification: %s", err.Error())
+	case prev != nil:
+		logger.Infof("comparator DID [%s] is resolvable", *o.comparatorConfig.Did)
+	}
+
+	return err
+}
+
+// DIDReady returns the result of the last CheckDID.
+func (o *Operation) DIDReady() error {
+	return o.didStatus.get()
+}
+
+// MonitorDID runs CheckDID immediately and then every interval until the context is done.
+func (o *Operation) MonitorDID(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		_ = o.CheckDID() // nolint: errcheck // the result is logged and reported by DIDReady
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (o *Operation) checkDID() error {
+	if o.comparatorConfig == nil || o.comparatorConfig.Did == nil {
+		return errors.New("comparator DID is not configured")
+	}
+
+	didID := *o.comparatorConfig.Did
+
+	keyID, _, err := getKey(o.comparatorConfig)
+	if err != nil {
+		return fmt.Errorf("failed to get the comparator's signing key: %w", err)
+	}
+
+	resolution, err := o.vdr.Resolve(didID)
+	if err != nil {
+		return fmt.Errorf("failed to resolve DID [%s]: %w", didID, err)
+	}
+
+	if resolution.DIDDocument == nil {
+		return fmt.Errorf("DID [%s] resolved without a DID document", didID)
+	}
+
+	for _, vm := range resolution.DIDDocument.VerificationMethods(did.CapabilityDelegation)[did.CapabilityDelegation] {
+		if vm.VerificationMethod.ID == keyID || strings.HasSuffix(vm.VerificationMethod.ID, "#"+keyID) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("key [%s] is not a capabilityDelegation method of DID [%s]", keyID, didID)
+}
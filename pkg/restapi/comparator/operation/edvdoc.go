@@ -0,0 +1,47 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/trustbloc/ace/pkg/restapi/vault"
+)
+
+// edvDocument locates a document in its EDV server, which the CSH reads it from.
+type edvDocument struct {
+	baseURL string
+	vaultID string
+	docID   string
+}
+
+// locateEDVDocument returns the location of the EDV document of the vault document. Vault servers that do not return
+// it along with the metadata of documents store them at URIs of the form
+// <scheme>://<host>/<base path>/<EDV vault ID>/documents/<EDV document ID>, which the location is taken from.
+func locateEDVDocument(docMeta *vault.DocumentMetadata) (*edvDocument, error) {
+	if docMeta.EDVURL != "" && docMeta.EDVVaultID != "" && docMeta.EDVDocID != "" {
+		return &edvDocument{baseURL: docMeta.EDVURL, vaultID: docMeta.EDVVaultID, docID: docMeta.EDVDocID}, nil
+	}
+
+	edvURL, err := url.Parse(docMeta.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.Split(docMeta.URI, "/")
+	if len(parts) < 5 { //nolint:gomnd
+		return nil, fmt.Errorf("unexpected EDV document URI %s", docMeta.URI)
+	}
+
+	return &edvDocument{
+		baseURL: fmt.Sprintf("%s://%s/%s", edvURL.Scheme, edvURL.Host, parts[3]),
+		vaultID: parts[len(parts)-3],
+		docID:   parts[len(parts)-1],
+	}, nil
+}
@@ -10,7 +10,7 @@ import (
 	"net/http"
 	"strings"
 
-	"github.com/trustbloc/edge-core/pkg/zcapld"
+	"github.com/go-openapi/swag"
 
 	"github.com/trustbloc/ace/pkg/client/csh/client/operations"
 	cshclientmodels "github.com/trustbloc/ace/pkg/client/csh/models"
@@ -20,6 +20,7 @@ import (
 // HandleExtract handles extract req.
 func (o *Operation) HandleExtract(w http.ResponseWriter, extract *models.Extract) {
 	queries := make([]cshclientmodels.Query, 0)
+	authzIDs := make([]string, 0)
 
 	for _, query := range extract.Queries() {
 		q, ok := query.(*models.AuthorizedQuery)
@@ -29,7 +30,7 @@ func (o *Operation) HandleExtract(w http.ResponseWriter, extract *models.Extract
 			return
 		}
 
-		orgZCAP, err := zcapld.DecompressZCAP(*q.AuthToken)
+		orgZCAP, err := decompressZCAP(*q.AuthToken)
 		if err != nil {
 			respondErrorf(w, http.StatusInternalServerError, "failed to parse org zcap: %s", err.Error())
 
@@ -42,13 +43,18 @@ func (o *Operation) HandleExtract(w http.ResponseWriter, extract *models.Extract
 		refQuery.SetID(query.ID())
 
 		queries = append(queries, refQuery)
+		authzIDs = append(authzIDs, orgZCAP.ID)
 	}
 
-	extractions, err := o.cshClient.PostExtract(
-		operations.NewPostExtractParams().
-			WithTimeout(requestTimeout).
-			WithRequest(queries),
-	)
+	params := operations.NewPostExtractParams().
+		WithTimeout(requestTimeout).
+		WithRequest(queries)
+
+	if extract.IncludeMetadata {
+		params.SetIncludeMetadata(swag.Bool(true))
+	}
+
+	extractions, err := o.cshClient.PostExtract(params)
 	if err != nil {
 		respondErrorf(w, http.StatusInternalServerError, "failed to execute extract: %s", err)
 
@@ -60,10 +66,16 @@ func (o *Operation) HandleExtract(w http.ResponseWriter, extract *models.Extract
 	for i := range extractions.Payload {
 		extraction := extractions.Payload[i]
 
-		response.Documents = append(response.Documents, &models.ExtractRespDocumentsItems0{
+		document := &models.ExtractRespDocumentsItems0{
 			ID:       extraction.ID,
 			Contents: extraction.Document,
-		})
+		}
+
+		if extract.IncludeMetadata {
+			document.Source = extractionSource(i, authzIDs, extraction)
+		}
+
+		response.Documents = append(response.Documents, document)
 	}
 
 	headers := map[string]string{
@@ -72,3 +84,25 @@ func (o *Operation) HandleExtract(w http.ResponseWriter, extract *models.Extract
 
 	respond(w, http.StatusOK, headers, response)
 }
+
+// extractionSource relies on the CSH returning the extractions in the same order as the queries.
+func extractionSource(index int, authzIDs []string,
+	extraction *cshclientmodels.ExtractionResponseItems0) *models.ExtractionSource {
+	source := &models.ExtractionSource{
+		Index:       swag.Int64(int64(index)),
+		VaultID:     extraction.VaultID,
+		DocID:       extraction.DocID,
+		DocAttrPath: extraction.Path,
+	}
+
+	if index < len(authzIDs) {
+		source.AuthorizationID = authzIDs[index]
+	}
+
+	if extraction.Metadata != nil {
+		source.EdvDocURI = extraction.Metadata.EdvDocURI
+		source.Sequence = extraction.Metadata.Sequence
+	}
+
+	return source
+}
@@ -0,0 +1,152 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/trustbloc/ace/pkg/restapi/vault"
+)
+
+// defaultDocMetaCacheSize is the number of documents whose metadata is cached unless configured otherwise.
+const defaultDocMetaCacheSize = 1000
+
+type docMetaKey struct {
+	vaultID string
+	docID   string
+}
+
+type docMetaEntry struct {
+	meta    *vault.DocumentMetadata
+	expires time.Time
+}
+
+// docMetaCache caches the metadata of documents fetched from the vault server for a short time. A nil cache
+// caches nothing.
+type docMetaCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	size    int
+	entries map[docMetaKey]*docMetaEntry
+	now     func() time.Time
+}
+
+// newDocMetaCache returns a cache of at most size entries kept for ttl, nil if ttl is zero.
+func newDocMetaCache(ttl time.Duration, size int) *docMetaCache {
+	if ttl <= 0 {
+		return nil
+	}
+
+	if size <= 0 {
+		size = defaultDocMetaCacheSize
+	}
+
+	return &docMetaCache{ttl: ttl, size: size, entries: make(map[docMetaKey]*docMetaEntry), now: time.Now}
+}
+
+func (c *docMetaCache) get(key docMetaKey) *vault.DocumentMetadata {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+
+	if !c.now().Before(entry.expires) {
+		delete(c.entries, key)
+
+		return nil
+	}
+
+	return entry.meta
+}
+
+func (c *docMetaCache) put(key docMetaKey, meta *vault.DocumentMetadata) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.size {
+		c.evict(now)
+	}
+
+	c.entries[key] = &docMetaEntry{meta: meta, expires: now.Add(c.ttl)}
+}
+
+// evict drops the expired entries, or the one expiring first if none is.
+func (c *docMetaCache) evict(now time.Time) {
+	var (
+		first *docMetaKey
+		at    time.Time
+	)
+
+	for key, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, key)
+
+			continue
+		}
+
+		if first == nil || entry.expires.Before(at) {
+			k := key
+			first, at = &k, entry.expires
+		}
+	}
+
+	if len(c.entries) >= c.size && first != nil {
+		delete(c.entries, *first)
+	}
+}
+
+func (c *docMetaCache) invalidate(key docMetaKey) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, key)
+}
+
+// getDocMetaData returns the metadata of the document, from the cache if it was fetched within its TTL. A failed
+// lookup invalidates the cached metadata.
+func (o *Operation) getDocMetaData(ctx context.Context, vaultID, docID string) (*vault.DocumentMetadata, error) {
+	key := docMetaKey{vaultID: vaultID, docID: docID}
+
+	if meta := o.docMetaCache.get(key); meta != nil {
+		return meta, nil
+	}
+
+	meta, err := o.vaultClient.GetDocMetaData(ctx, vaultID, docID)
+	if err != nil {
+		o.docMetaCache.invalidate(key)
+
+		return nil, err
+	}
+
+	o.docMetaCache.put(key, meta)
+
+	return meta, nil
+}
+
+// invalidateDocMetaData drops the cached metadata of the document, when a request relying on it failed.
+func (o *Operation) invalidateDocMetaData(vaultID, docID string) {
+	o.docMetaCache.invalidate(docMetaKey{vaultID: vaultID, docID: docID})
+}
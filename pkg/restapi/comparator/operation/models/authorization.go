@@ -31,14 +31,26 @@ import (
 // swagger:model Authorization
 type Authorization struct {
 
+	// The actions the `authToken` allows on the `invocationTarget`, as read from its zcap.
+	AllowedActions []string `json:"allowedActions"`
+
 	// An opaque authorization token authorizing the requesting party to perform a comparison
 	// referencing the document in the `scope`.
 	//
 	AuthToken string `json:"authToken,omitempty"`
 
+	// The time at which the authorization expires.
+	// Format: date-time
+	ExpiresAt *strfmt.DateTime `json:"expiresAt,omitempty"`
+
 	// The authorization's unique ID.
 	ID string `json:"id,omitempty"`
 
+	// The Confidential Storage Hub query the `authToken` authorizes, as read from its zcap. Informative only:
+	// the `authToken` is what authorizes the requesting party.
+	//
+	InvocationTarget string `json:"invocationTarget,omitempty"`
+
 	// KeyID in the format of a DID URL that identifies the party granted authorization.
 	// Required: true
 	RequestingParty *string `json:"requestingParty"`
@@ -52,6 +64,10 @@ type Authorization struct {
 func (m *Authorization) Validate(formats strfmt.Registry) error {
 	var res []error
 
+	if err := m.validateExpiresAt(formats); err != nil {
+		res = append(res, err)
+	}
+
 	if err := m.validateRequestingParty(formats); err != nil {
 		res = append(res, err)
 	}
@@ -66,6 +82,18 @@ func (m *Authorization) Validate(formats strfmt.Registry) error {
 	return nil
 }
 
+func (m *Authorization) validateExpiresAt(formats strfmt.Registry) error {
+	if swag.IsZero(m.ExpiresAt) { // not required
+		return nil
+	}
+
+	if err := validate.FormatOf("expiresAt", "body", "date-time", m.ExpiresAt.String(), formats); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (m *Authorization) validateRequestingParty(formats strfmt.Registry) error {
 
 	if err := validate.Required("requestingParty", "body", m.RequestingParty); err != nil {
@@ -22,6 +22,9 @@ import (
 // swagger:model Error
 type Error struct {
 
+	// code
+	Code string `json:"code,omitempty"`
+
 	// err message
 	ErrMessage string `json:"errMessage,omitempty"`
 }
@@ -347,7 +347,9 @@ func (m *Scope) UnmarshalBinary(b []byte) error {
 // swagger:model ScopeAuthTokens
 type ScopeAuthTokens struct {
 
-	// edv
+	// The EDV zcap. If the comparator is its invoker, the comparator delegates to the CSH only the read of
+	// the document, targeting the document itself if the EDV servers support it. Other zcaps are passed on
+	// to the CSH as is.
 	Edv string `json:"edv,omitempty"`
 
 	// kms
@@ -36,6 +36,22 @@ type createAuthorizationResp struct { // nolint:deadcode,unused // swagger model
 	Body models.Authorization
 }
 
+// verifyAuthzReq model.
+//
+// swagger:parameters verifyAuthzReq
+type verifyAuthzReq struct { // nolint:deadcode,unused // swagger model
+	// in: body
+	Body models.AuthorizationVerification
+}
+
+// verificationResp model.
+//
+// swagger:response verificationResp
+type verificationResp struct { // nolint:deadcode,unused // swagger model
+	// in: body
+	Body models.VerificationReport
+}
+
 // compareReq model.
 //
 // swagger:parameters compareReq
@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package operation
 
 import (
+	"context"
 	"crypto"
 	"crypto/ed25519"
 	"crypto/rand"
@@ -18,6 +19,7 @@ import (
 	"strings"
 	"time"
 
+	openapierrors "github.com/go-openapi/errors"
 	httptransport "github.com/go-openapi/runtime/client"
 	"github.com/go-openapi/strfmt"
 	"github.com/google/uuid"
@@ -30,7 +32,6 @@ import (
 	"github.com/piprate/json-gold/ld"
 	"github.com/square/go-jose/v3"
 	"github.com/trustbloc/edge-core/pkg/log"
-	"github.com/trustbloc/edge-core/pkg/zcapld"
 
 	"github.com/trustbloc/ace/pkg/client/csh/client"
 	"github.com/trustbloc/ace/pkg/client/csh/client/operations"
@@ -38,6 +39,7 @@ import (
 	vaultclient "github.com/trustbloc/ace/pkg/client/vault"
 	vccrypto "github.com/trustbloc/ace/pkg/doc/vc/crypto"
 	"github.com/trustbloc/ace/pkg/restapi/comparator/operation/models"
+	cshzcapld "github.com/trustbloc/ace/pkg/restapi/csh/operation/zcapld"
 	"github.com/trustbloc/ace/pkg/restapi/handler"
 	"github.com/trustbloc/ace/pkg/restapi/model"
 	"github.com/trustbloc/ace/pkg/restapi/vault"
@@ -48,13 +50,18 @@ const (
 	comparePath     = "/compare"
 	extractPath     = "/extract"
 	getConfigPath   = "/config"
+	verifyAuthzPath = "/authorizations/verify"
 )
 
 const (
 	configKeyDB    = "config"
 	cshConfigKeyDB = "csh_config"
+	authzKeyPrefix = "authz_"
 	storeName      = "comparator"
 	requestTimeout = 5 * time.Second
+	// calls to the vault server are retried to ride out transient failures.
+	vaultMaxAttempts  = 3
+	vaultRetryBackoff = 200 * time.Millisecond
 )
 
 type cshClient interface {
@@ -69,7 +76,8 @@ type cshClient interface {
 }
 
 type vaultClient interface {
-	GetDocMetaData(vaultID, docID string) (*vault.DocumentMetadata, error)
+	GetDocMetaData(ctx context.Context, vaultID, docID string) (*vault.DocumentMetadata, error)
+	IsRevoked(ctx context.Context, zcapID string) (bool, error)
 }
 
 var logger = log.New("comparator-ops")
@@ -88,6 +96,17 @@ type Operation struct {
 	didDomain        string
 	didAnchorOrigin  string
 	documentLoader   ld.DocumentLoader
+	authzExpiry      *AuthzExpiry
+	didStatus        *didStatus
+	edvDocTargets    bool
+	docMetaCache     *docMetaCache
+	// cshQueryTargetType is the invocation target type of the zcaps issued for CSH queries.
+	cshQueryTargetType string
+	// zcapCompression compresses the auth tokens, edvZCAPCompression the EDV zcaps delegated to the CSH.
+	zcapCompression    []cshzcapld.CompressOpt
+	edvZCAPCompression []cshzcapld.CompressOpt
+	// maxChainDepth is the maximum number of delegations of the EDV zcaps delegated to the CSH.
+	maxChainDepth int
 }
 
 // Config defines configuration for comparator operations.
@@ -102,10 +121,45 @@ type Config struct {
 	DIDDomain       string
 	DIDAnchorOrigin string
 	DocumentLoader  ld.DocumentLoader
+	AuthzExpiry     *AuthzExpiry
+	// EDVDocumentTargets is set if the EDV servers accept zcaps whose invocation target is a single document.
+	// Otherwise the EDV zcaps the comparator delegates to the CSH target the whole vault.
+	EDVDocumentTargets bool
+	// DocMetaCacheTTL is how long the metadata of the documents fetched from the vault server is cached. Zero
+	// disables the cache.
+	DocMetaCacheTTL time.Duration
+	// DocMetaCacheSize is the maximum number of documents whose metadata is cached. Defaults to 1000.
+	DocMetaCacheSize int
+	// CSHQueryTargetType is the invocation target type of the zcaps issued for CSH queries, a URN expected by the
+	// CSH. Defaults to DefaultCSHQueryTargetType.
+	CSHQueryTargetType string
+	// ZCAPCompression configures the compression of the zcaps issued. Defaults to gzip's default compression of
+	// all zcaps. The EDV zcaps delegated to the CSH are always compressed: EDV servers do not accept others.
+	ZCAPCompression []cshzcapld.CompressOpt
+	// MaxZCAPChainDepth is the maximum number of delegations from their root zcaps of the EDV zcaps the comparator
+	// delegates to the CSH. Deeper upstream zcaps are rejected. Defaults to zcapld.DefaultMaxChainDepth.
+	MaxZCAPChainDepth int
+}
+
+// AuthzExpiry configures the validity of the authorizations issued by the comparator.
+type AuthzExpiry struct {
+	// Default is the expiry applied to authorizations requested without an expiry caveat. Zero disables it.
+	Default time.Duration
+	// Max is the maximum expiry an authorization may be requested with. Zero means no limit.
+	Max time.Duration
 }
 
 // New returns operation instance.
 func New(cfg *Config) (*Operation, error) {
+	cshQueryTargetType := cfg.CSHQueryTargetType
+	if cshQueryTargetType == "" {
+		cshQueryTargetType = DefaultCSHQueryTargetType
+	}
+
+	if err := CheckURN(cshQueryTargetType); err != nil {
+		return nil, fmt.Errorf("invalid CSH query target type: %w", err)
+	}
+
 	store, err := cfg.StoreProvider.OpenStore(storeName)
 	if err != nil {
 		return nil, err
@@ -134,8 +188,26 @@ func New(cfg *Config) (*Operation, error) {
 			Transport: &http.Transport{
 				TLSClientConfig: cfg.TLSConfig,
 			},
-		})),
+		}), vaultclient.WithRetry(vaultMaxAttempts, vaultRetryBackoff)),
 		documentLoader: cfg.DocumentLoader,
+		authzExpiry:    cfg.AuthzExpiry,
+		didStatus:      &didStatus{err: errDIDNotChecked},
+		edvDocTargets:  cfg.EDVDocumentTargets,
+		docMetaCache:   newDocMetaCache(cfg.DocMetaCacheTTL, cfg.DocMetaCacheSize),
+
+		cshQueryTargetType: cshQueryTargetType,
+		zcapCompression:    cfg.ZCAPCompression,
+		edvZCAPCompression: append(append([]cshzcapld.CompressOpt{}, cfg.ZCAPCompression...),
+			cshzcapld.WithCompressionThreshold(0)),
+	}
+
+	if op.authzExpiry == nil {
+		op.authzExpiry = &AuthzExpiry{}
+	}
+
+	op.maxChainDepth = cfg.MaxZCAPChainDepth
+	if op.maxChainDepth == 0 {
+		op.maxChainDepth = cshzcapld.DefaultMaxChainDepth
 	}
 
 	if _, err := op.getConfig(); err != nil { //nolint: nestif
@@ -172,6 +244,7 @@ func (o *Operation) GetRESTHandlers() []handler.Handler {
 		handler.NewHTTPHandler(comparePath, http.MethodPost, o.Compare),
 		handler.NewHTTPHandler(extractPath, http.MethodPost, o.Extract),
 		handler.NewHTTPHandler(getConfigPath, http.MethodGet, o.GetConfig),
+		handler.NewHTTPHandler(verifyAuthzPath, http.MethodPost, o.VerifyAuthorization),
 	}
 }
 
@@ -185,6 +258,7 @@ func (o *Operation) GetRESTHandlers() []handler.Handler {
 //   - application/json
 // Responses:
 //   201: createAuthorizationResp
+//   400: Error
 //   403: Error
 //   500: Error
 func (o *Operation) CreateAuthorization(w http.ResponseWriter, r *http.Request) {
@@ -197,7 +271,44 @@ func (o *Operation) CreateAuthorization(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	o.HandleAuthz(w, request)
+	if err = validateRequest(r.Context(), request); err != nil {
+		respondValidationError(w, err)
+
+		return
+	}
+
+	o.HandleAuthz(r.Context(), w, request)
+}
+
+// VerifyAuthorization swagger:route POST /authorizations/verify verifyAuthzReq
+//
+// Verifies an authorization token previously issued by this comparator.
+//
+// Consumes:
+//   - application/json
+// Produces:
+//   - application/json
+// Responses:
+//   200: verificationResp
+//   400: Error
+//   500: Error
+func (o *Operation) VerifyAuthorization(w http.ResponseWriter, r *http.Request) {
+	request := &models.AuthorizationVerification{}
+
+	err := json.NewDecoder(r.Body).Decode(request)
+	if err != nil {
+		respondErrorf(w, http.StatusBadRequest, "bad request: %s", err.Error())
+
+		return
+	}
+
+	if err = validateRequest(r.Context(), request); err != nil {
+		respondValidationError(w, err)
+
+		return
+	}
+
+	o.HandleVerifyAuthz(w, request)
 }
 
 // Compare swagger:route POST /compare compareReq
@@ -210,6 +321,8 @@ func (o *Operation) CreateAuthorization(w http.ResponseWriter, r *http.Request)
 //   - application/json
 // Responses:
 //   200: comparisonResp
+//   400: Error
+//   403: Error
 //   500: Error
 func (o *Operation) Compare(w http.ResponseWriter, r *http.Request) {
 	request := &models.Comparison{}
@@ -221,9 +334,21 @@ func (o *Operation) Compare(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if request.Op() == nil {
+		respondErrorCodef(w, http.StatusBadRequest, model.ErrCodeInvalidRequest, "invalid request: op in body is required")
+
+		return
+	}
+
+	if err = validateRequest(r.Context(), request); err != nil {
+		respondValidationError(w, err)
+
+		return
+	}
+
 	switch t := request.Op().(type) {
 	case *models.EqOp:
-		o.HandleEqOp(w, t)
+		o.HandleEqOp(r.Context(), w, t)
 	default:
 		respondErrorf(w, http.StatusNotImplemented, "operator not yet implemented: %s", request.Op().Type())
 	}
@@ -237,6 +362,7 @@ func (o *Operation) Compare(w http.ResponseWriter, r *http.Request) {
 //   - application/json
 // Responses:
 //   200: extractionResp
+//   400: Error
 //   500: Error
 func (o *Operation) Extract(w http.ResponseWriter, r *http.Request) {
 	request := &models.Extract{}
@@ -248,6 +374,12 @@ func (o *Operation) Extract(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err = validateRequest(r.Context(), request); err != nil {
+		respondValidationError(w, err)
+
+		return
+	}
+
 	o.HandleExtract(w, request)
 }
 
@@ -276,6 +408,8 @@ func (o *Operation) GetConfig(w http.ResponseWriter, _ *http.Request) {
 
 	fmt.Printf("getConfig did: %s", *cc.Did)
 
+	cc.DidResolvable = o.DIDReady() == nil
+
 	headers := map[string]string{
 		"Content-Type": "application/json",
 	}
@@ -334,7 +468,7 @@ func (o *Operation) createConfig() error { //nolint: funlen
 	}
 
 	// TODO need to find better way to get csh DID
-	cshZCAP, err := zcapld.DecompressZCAP(cshProfile.Payload.Zcap)
+	cshZCAP, err := decompressZCAP(cshProfile.Payload.Zcap)
 	if err != nil {
 		return fmt.Errorf("failed to parse CSH profile zcap: %w", err)
 	}
@@ -401,6 +535,8 @@ func (o *Operation) newPublicKeys() (*did.Doc, []json.RawMessage, error) {
 
 	didDoc.Authentication = append(didDoc.Authentication, *did.NewReferencedVerification(vm, did.Authentication))
 	didDoc.AssertionMethod = append(didDoc.AssertionMethod, *did.NewReferencedVerification(vm, did.AssertionMethod))
+	didDoc.CapabilityDelegation = append(didDoc.CapabilityDelegation,
+		*did.NewReferencedVerification(vm, did.CapabilityDelegation))
 
 	return didDoc, m, nil
 }
@@ -428,6 +564,11 @@ func respond(w http.ResponseWriter, statusCode int, headers map[string]string, p
 }
 
 func respondErrorf(w http.ResponseWriter, statusCode int, format string, args ...interface{}) {
+	respondErrorCodef(w, statusCode, model.StatusErrorCode(statusCode), format, args...)
+}
+
+// respondErrorCodef responds with an error code more specific than the one of the status code.
+func respondErrorCodef(w http.ResponseWriter, statusCode int, code model.ErrorCode, format string, args ...interface{}) {
 	msg := fmt.Sprintf(format, args...)
 
 	w.Header().Add("Content-Type", "application/json")
@@ -437,12 +578,49 @@ func respondErrorf(w http.ResponseWriter, statusCode int, format string, args ..
 
 	err := json.NewEncoder(w).Encode(&model.ErrorResponse{
 		Message: msg,
+		Code:    code,
 	})
 	if err != nil {
 		logger.Errorf("failed to write error response: %s", err.Error())
 	}
 }
 
+type validatable interface {
+	Validate(formats strfmt.Registry) error
+	ContextValidate(ctx context.Context, formats strfmt.Registry) error
+}
+
+// validateRequest runs the swagger model's validations against the decoded request.
+func validateRequest(ctx context.Context, request validatable) error {
+	if err := request.Validate(strfmt.Default); err != nil {
+		return err
+	}
+
+	return request.ContextValidate(ctx, strfmt.Default)
+}
+
+func respondValidationError(w http.ResponseWriter, err error) {
+	respondErrorCodef(w, http.StatusBadRequest, model.ErrCodeInvalidRequest,
+		"invalid request: %s", strings.Join(validationErrors(err), "; "))
+}
+
+// validationErrors flattens (possibly nested) composite validation errors into a list of violated constraints.
+func validationErrors(err error) []string {
+	var ce *openapierrors.CompositeError
+
+	if !errors.As(err, &ce) {
+		return []string{err.Error()}
+	}
+
+	var msgs []string
+
+	for _, e := range ce.Errors {
+		msgs = append(msgs, validationErrors(e)...)
+	}
+
+	return msgs
+}
+
 func (o *Operation) setConfigs() error {
 	configBytes, err := o.store.Get(configKeyDB)
 	if err != nil {
@@ -9,16 +9,22 @@ package operation_test
 import (
 	"bytes"
 	"compress/gzip"
+	gocontext "context"
 	"crypto/ed25519"
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/go-openapi/swag"
 	"github.com/google/uuid"
 	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
@@ -42,6 +48,7 @@ import (
 	"github.com/trustbloc/ace/pkg/internal/testutil"
 	"github.com/trustbloc/ace/pkg/restapi/comparator/operation"
 	"github.com/trustbloc/ace/pkg/restapi/comparator/operation/models"
+	cshzcapld "github.com/trustbloc/ace/pkg/restapi/csh/operation/zcapld"
 	"github.com/trustbloc/ace/pkg/restapi/vault"
 )
 
@@ -72,7 +79,7 @@ func Test_New(t *testing.T) {
 		require.NoError(t, err)
 		require.NotNil(t, op)
 
-		require.Equal(t, 4, len(op.GetRESTHandlers()))
+		require.Equal(t, 5, len(op.GetRESTHandlers()))
 	})
 
 	t.Run("test failed to create profile from csh", func(t *testing.T) {
@@ -122,6 +129,14 @@ func Test_New(t *testing.T) {
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "failed to get config")
 	})
+
+	t.Run("test invalid CSH query target type", func(t *testing.T) {
+		for _, targetType := range []string{"query", "urn:", "urn:csh", "urn:-csh:query", "urn:csh: query"} {
+			_, err := operation.New(&operation.Config{CSHQueryTargetType: targetType})
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "invalid CSH query target type")
+		}
+	})
 }
 
 func TestOperation_CreateAuthorization(t *testing.T) {
@@ -144,6 +159,35 @@ func TestOperation_CreateAuthorization(t *testing.T) {
 
 		require.Equal(t, http.StatusBadRequest, result.Code)
 		require.Contains(t, result.Body.String(), "bad request")
+		require.Contains(t, result.Body.String(), `"code":"MALFORMED_REQUEST"`)
+	})
+
+	t.Run("test invalid request", func(t *testing.T) {
+		s := &mockstorage.MockStore{Store: make(map[string]mockstorage.DBEntry)}
+		s.Store["config"] = mockstorage.DBEntry{Value: []byte(`{}`)}
+		s.Store["csh_config"] = mockstorage.DBEntry{Value: []byte(`{}`)}
+		op, err := operation.New(&operation.Config{
+			CSHBaseURL:    "https://localhost",
+			StoreProvider: &mockstorage.MockStoreProvider{Store: s},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, op)
+
+		docID := "docID"
+		auth := &models.Authorization{Scope: &models.Scope{DocID: &docID}}
+
+		result := httptest.NewRecorder()
+		op.CreateAuthorization(result, newReq(t, http.MethodPost, "/authorizations", auth))
+		require.Equal(t, http.StatusBadRequest, result.Code)
+		require.Contains(t, result.Body.String(), "requestingParty in body is required")
+		require.Contains(t, result.Body.String(), "scope.actions in body is required")
+		require.Contains(t, result.Body.String(), "scope.authTokens in body is required")
+		require.Contains(t, result.Body.String(), `"code":"INVALID_REQUEST"`)
+
+		result = httptest.NewRecorder()
+		op.CreateAuthorization(result, newReq(t, http.MethodPost, "/authorizations", &models.Authorization{}))
+		require.Equal(t, http.StatusBadRequest, result.Code)
+		require.Contains(t, result.Body.String(), "scope in body is required")
 	})
 
 	t.Run("test failed to get doc meta from vault server", func(t *testing.T) {
@@ -162,10 +206,14 @@ func TestOperation_CreateAuthorization(t *testing.T) {
 		require.NoError(t, err)
 		require.NotNil(t, op)
 		result := httptest.NewRecorder()
-		auth := &models.Authorization{}
+		rpDID := "did1"
+		auth := &models.Authorization{RequestingParty: &rpDID}
 		docID := "docID11"
 		vaultID := "vaultID11"
-		auth.Scope = &models.Scope{DocID: &docID, VaultID: vaultID}
+		auth.Scope = &models.Scope{
+			DocID: &docID, VaultID: vaultID, Actions: []string{"compare"},
+			AuthTokens: &models.ScopeAuthTokens{Kms: "kms", Edv: "edv"},
+		}
 		op.CreateAuthorization(result, newReq(t,
 			http.MethodPost,
 			"/authorizations",
@@ -198,10 +246,14 @@ func TestOperation_CreateAuthorization(t *testing.T) {
 		require.NoError(t, err)
 		require.NotNil(t, op)
 		result := httptest.NewRecorder()
-		auth := &models.Authorization{}
+		rpDID := "did1"
+		auth := &models.Authorization{RequestingParty: &rpDID}
 		docID := "docID12"
 		vaultID := "vaultID12"
-		auth.Scope = &models.Scope{DocID: &docID, VaultID: vaultID}
+		auth.Scope = &models.Scope{
+			DocID: &docID, VaultID: vaultID, Actions: []string{"compare"},
+			AuthTokens: &models.ScopeAuthTokens{Kms: "kms", Edv: "edv"},
+		}
 		op.CreateAuthorization(result, newReq(t,
 			http.MethodPost,
 			"/authorizations",
@@ -234,10 +286,14 @@ func TestOperation_CreateAuthorization(t *testing.T) {
 		require.NoError(t, err)
 		require.NotNil(t, op)
 		result := httptest.NewRecorder()
-		auth := &models.Authorization{}
+		rpDID := "did1"
+		auth := &models.Authorization{RequestingParty: &rpDID}
 		docID := "docID13"
 		vaultID := "vaultID13"
-		auth.Scope = &models.Scope{DocID: &docID, VaultID: vaultID}
+		auth.Scope = &models.Scope{
+			DocID: &docID, VaultID: vaultID, Actions: []string{"compare"},
+			AuthTokens: &models.ScopeAuthTokens{Kms: "kms", Edv: "edv"},
+		}
 		op.CreateAuthorization(result, newReq(t,
 			http.MethodPost,
 			"/authorizations",
@@ -275,11 +331,12 @@ func TestOperation_CreateAuthorization(t *testing.T) {
 		require.NoError(t, err)
 		require.NotNil(t, op)
 		result := httptest.NewRecorder()
-		auth := &models.Authorization{}
+		rpDID := "did1"
+		auth := &models.Authorization{RequestingParty: &rpDID}
 		docID := "docID14"
 		vaultID := "vaultID14"
 		auth.Scope = &models.Scope{
-			DocID: &docID, VaultID: vaultID,
+			DocID: &docID, VaultID: vaultID, Actions: []string{"compare"},
 			AuthTokens: &models.ScopeAuthTokens{Kms: "kms", Edv: "edv"},
 		}
 		op.CreateAuthorization(result, newReq(t,
@@ -326,7 +383,7 @@ func TestOperation_CreateAuthorization(t *testing.T) {
 		docID := "docID15"
 		vaultID := "vaultID15"
 		auth.Scope = &models.Scope{
-			DocID: &docID, VaultID: vaultID,
+			DocID: &docID, VaultID: vaultID, Actions: []string{"compare"},
 			AuthTokens: &models.ScopeAuthTokens{Kms: "kms", Edv: "edv"},
 		}
 		op.CreateAuthorization(result, newReq(t,
@@ -379,7 +436,7 @@ func TestOperation_CreateAuthorization(t *testing.T) {
 		docID := "docID16"
 		vaultID := "vaultID16"
 		auth.Scope = &models.Scope{
-			DocID: &docID, VaultID: vaultID,
+			DocID: &docID, VaultID: vaultID, Actions: []string{"compare"},
 			AuthTokens: &models.ScopeAuthTokens{Kms: "kms", Edv: "edv"},
 		}
 		op.CreateAuthorization(result, newReq(t,
@@ -443,7 +500,7 @@ func TestOperation_CreateAuthorization(t *testing.T) {
 		docID := "docID17"
 		vaultID := "vaultID17"
 		auth.Scope = &models.Scope{
-			DocID: &docID, VaultID: vaultID,
+			DocID: &docID, VaultID: vaultID, Actions: []string{"compare"},
 			AuthTokens: &models.ScopeAuthTokens{Kms: "kms", Edv: "edv"},
 		}
 		auth.Scope.SetCaveats([]models.Caveat{&models.ExpiryCaveat{Duration: int64(200)}})
@@ -458,6 +515,719 @@ func TestOperation_CreateAuthorization(t *testing.T) {
 	})
 }
 
+func TestOperation_CreateAuthorization_Expiry(t *testing.T) {
+	newRequest := func(caveats ...models.Caveat) *models.Authorization {
+		rpDID := "did3"
+		docID := "docID"
+		auth := &models.Authorization{RequestingParty: &rpDID}
+		auth.Scope = &models.Scope{
+			DocID: &docID, VaultID: "vaultID", Actions: []string{"compare"},
+			AuthTokens: &models.ScopeAuthTokens{Kms: "kms", Edv: "edv"},
+		}
+		auth.Scope.SetCaveats(caveats)
+
+		return auth
+	}
+
+	t.Run("default expiry is applied when request has no expiry caveat", func(t *testing.T) {
+		op, s := newAuthzOperation(t, &operation.AuthzExpiry{Default: time.Hour, Max: 2 * time.Hour})
+
+		result := httptest.NewRecorder()
+		op.CreateAuthorization(result, newReq(t, http.MethodPost, "/authorizations", newRequest()))
+		require.Equal(t, http.StatusOK, result.Code)
+
+		resp := &models.Authorization{}
+		require.NoError(t, json.Unmarshal(result.Body.Bytes(), resp))
+		require.NotEmpty(t, resp.ID)
+		require.WithinDuration(t, time.Now().Add(time.Hour), time.Time(*resp.ExpiresAt), time.Minute)
+
+		zcap, err := zcapld.DecompressZCAP(resp.AuthToken)
+		require.NoError(t, err)
+		require.Len(t, zcap.Caveats, 1)
+		require.Equal(t, uint64(3600), zcap.Caveats[0].Duration)
+
+		entry, ok := s.Store["authz_"+resp.ID]
+		require.True(t, ok)
+
+		stored := &models.Authorization{}
+		require.NoError(t, stored.UnmarshalBinary(entry.Value))
+		require.Equal(t, resp.ExpiresAt.String(), stored.ExpiresAt.String())
+		require.Nil(t, stored.Scope.AuthTokens)
+		require.Len(t, stored.Scope.Caveats(), 1)
+		require.Equal(t, int64(3600), stored.Scope.Caveats()[0].(*models.ExpiryCaveat).Duration)
+	})
+
+	t.Run("explicit expiry caveat takes precedence over the default", func(t *testing.T) {
+		op, _ := newAuthzOperation(t, &operation.AuthzExpiry{Default: time.Hour})
+
+		result := httptest.NewRecorder()
+		op.CreateAuthorization(result, newReq(t, http.MethodPost, "/authorizations",
+			newRequest(&models.ExpiryCaveat{Duration: 60})))
+		require.Equal(t, http.StatusOK, result.Code)
+
+		resp := &models.Authorization{}
+		require.NoError(t, json.Unmarshal(result.Body.Bytes(), resp))
+		require.WithinDuration(t, time.Now().Add(time.Minute), time.Time(*resp.ExpiresAt), 10*time.Second)
+	})
+
+	t.Run("no expiry without default and caveat", func(t *testing.T) {
+		op, _ := newAuthzOperation(t, nil)
+
+		result := httptest.NewRecorder()
+		op.CreateAuthorization(result, newReq(t, http.MethodPost, "/authorizations", newRequest()))
+		require.Equal(t, http.StatusOK, result.Code)
+		require.NotContains(t, result.Body.String(), "expiresAt")
+	})
+
+	t.Run("error if explicit expiry caveat exceeds the maximum", func(t *testing.T) {
+		op, _ := newAuthzOperation(t, &operation.AuthzExpiry{Default: time.Hour, Max: 2 * time.Hour})
+
+		result := httptest.NewRecorder()
+		op.CreateAuthorization(result, newReq(t, http.MethodPost, "/authorizations",
+			newRequest(&models.ExpiryCaveat{Duration: int64((3 * time.Hour) / time.Second)})))
+		require.Equal(t, http.StatusBadRequest, result.Code)
+		require.Contains(t, result.Body.String(), "exceeds the maximum of 2h0m0s")
+	})
+
+	t.Run("error if default expiry exceeds the maximum", func(t *testing.T) {
+		op, _ := newAuthzOperation(t, &operation.AuthzExpiry{Default: 3 * time.Hour, Max: 2 * time.Hour})
+
+		result := httptest.NewRecorder()
+		op.CreateAuthorization(result, newReq(t, http.MethodPost, "/authorizations", newRequest()))
+		require.Equal(t, http.StatusBadRequest, result.Code)
+	})
+
+	t.Run("expiry is capped to the CSH profile zcap's expiry", func(t *testing.T) {
+		profileExpires := time.Now().Add(time.Hour)
+
+		for _, request := range []*models.Authorization{
+			newRequest(),
+			newRequest(&models.ExpiryCaveat{Duration: int64((2 * time.Hour) / time.Second)}),
+		} {
+			op, _ := newAuthzOperationWithOptions(t, &authzOperationOptions{profileExpires: &profileExpires})
+
+			result := httptest.NewRecorder()
+			op.CreateAuthorization(result, newReq(t, http.MethodPost, "/authorizations", request))
+			require.Equal(t, http.StatusOK, result.Code)
+
+			resp := &models.Authorization{}
+			require.NoError(t, json.Unmarshal(result.Body.Bytes(), resp))
+			require.NotNil(t, resp.ExpiresAt)
+			require.False(t, time.Time(*resp.ExpiresAt).After(profileExpires))
+
+			zcap, err := zcapld.DecompressZCAP(resp.AuthToken)
+			require.NoError(t, err)
+			require.Len(t, zcap.Caveats, 1)
+			require.LessOrEqual(t, zcap.Caveats[0].Duration, uint64(3600))
+			require.Greater(t, zcap.Caveats[0].Duration, uint64(3500))
+		}
+	})
+
+	t.Run("shorter expiry is kept if the CSH profile zcap expires", func(t *testing.T) {
+		profileExpires := time.Now().Add(time.Hour)
+		op, _ := newAuthzOperationWithOptions(t, &authzOperationOptions{profileExpires: &profileExpires})
+
+		result := httptest.NewRecorder()
+		op.CreateAuthorization(result, newReq(t, http.MethodPost, "/authorizations",
+			newRequest(&models.ExpiryCaveat{Duration: 60})))
+		require.Equal(t, http.StatusOK, result.Code)
+
+		resp := &models.Authorization{}
+		require.NoError(t, json.Unmarshal(result.Body.Bytes(), resp))
+		require.WithinDuration(t, time.Now().Add(time.Minute), time.Time(*resp.ExpiresAt), 10*time.Second)
+	})
+
+	t.Run("error if the CSH profile zcap has expired", func(t *testing.T) {
+		profileExpires := time.Now().Add(-time.Minute)
+		op, _ := newAuthzOperationWithOptions(t, &authzOperationOptions{profileExpires: &profileExpires})
+
+		result := httptest.NewRecorder()
+		op.CreateAuthorization(result, newReq(t, http.MethodPost, "/authorizations", newRequest()))
+		require.Equal(t, http.StatusInternalServerError, result.Code)
+		require.Contains(t, result.Body.String(), "CSH profile zcap expired")
+	})
+}
+
+func TestOperation_CreateAuthorization_Revocation(t *testing.T) {
+	agent := newAgent(t)
+	edvZCAP := newZCAP(t, agent, agent)
+	kmsZCAP := newZCAP(t, agent, agent)
+
+	newRequest := func() *models.Authorization {
+		rpDID := "did3"
+		docID := "docID"
+		auth := &models.Authorization{RequestingParty: &rpDID}
+		auth.Scope = &models.Scope{
+			DocID: &docID, VaultID: "vaultID", Actions: []string{"compare"},
+			AuthTokens: &models.ScopeAuthTokens{
+				Edv: compress(t, marshal(t, edvZCAP)),
+				Kms: compress(t, marshal(t, kmsZCAP)),
+			},
+		}
+
+		return auth
+	}
+
+	t.Run("authorizes zcaps that were not revoked", func(t *testing.T) {
+		op, _ := newAuthzOperation(t, nil)
+
+		result := httptest.NewRecorder()
+		op.CreateAuthorization(result, newReq(t, http.MethodPost, "/authorizations", newRequest()))
+		require.Equal(t, http.StatusOK, result.Code)
+	})
+
+	t.Run("error Forbidden if a zcap was revoked", func(t *testing.T) {
+		op, s := newAuthzOperationWithOptions(t, &authzOperationOptions{revoked: []string{kmsZCAP.ID}})
+
+		result := httptest.NewRecorder()
+		op.CreateAuthorization(result, newReq(t, http.MethodPost, "/authorizations", newRequest()))
+		require.Equal(t, http.StatusForbidden, result.Code)
+		require.Contains(t, result.Body.String(), kmsZCAP.ID)
+
+		for k := range s.Store {
+			require.False(t, strings.HasPrefix(k, "authz_"))
+		}
+	})
+}
+
+func TestOperation_CreateAuthorization_EDVZCAP(t *testing.T) {
+	const (
+		edvVaultURL = "https://edv.example.com/encrypted-data-vaults/zMbxmSDn2Xzz"
+		docURI      = edvVaultURL + "/documents/VJYHHJx4C8J9Fsgz"
+	)
+
+	// the EDV zcap the vault server delegated to the comparator
+	edvZCAP := newEDVZCAP(t, newAgent(t), "did:ex:123#key1", edvVaultURL)
+
+	newRequest := func(edvToken string) *models.Authorization {
+		rpDID := "did3"
+		docID := "docID"
+		auth := &models.Authorization{RequestingParty: &rpDID}
+		auth.Scope = &models.Scope{
+			DocID: &docID, VaultID: "vaultID", Actions: []string{"compare"},
+			AuthTokens: &models.ScopeAuthTokens{Edv: edvToken, Kms: "kms"},
+		}
+
+		return auth
+	}
+
+	for _, docTargets := range []bool{true, false} {
+		t.Run(fmt.Sprintf("delegates read on the document to the csh (doc targets: %t)", docTargets),
+			func(t *testing.T) {
+				opts := &authzOperationOptions{docURI: docURI, edvDocTargets: docTargets}
+				op, _ := newAuthzOperationWithOptions(t, opts)
+
+				result := httptest.NewRecorder()
+				op.CreateAuthorization(result, newReq(t, http.MethodPost, "/authorizations",
+					newRequest(compress(t, marshal(t, edvZCAP)))))
+				require.Equal(t, http.StatusOK, result.Code, result.Body.String())
+				require.Len(t, opts.cshQueries, 1)
+
+				delegated, err := zcapld.DecompressZCAP(opts.cshQueries[0].UpstreamAuth.Edv.Zcap)
+				require.NoError(t, err)
+				require.Equal(t, edvZCAP.ID, delegated.Parent)
+				require.Equal(t, []string{"read"}, delegated.AllowedAction)
+				require.True(t, strings.HasPrefix(delegated.Invoker, "did:key:"))
+				require.Equal(t, []interface{}{edvZCAP.ID}, delegated.Proof[0]["capabilityChain"])
+
+				if docTargets {
+					require.Equal(t, docURI, delegated.InvocationTarget.ID)
+					require.Equal(t, "urn:edv:document", delegated.InvocationTarget.Type)
+				} else {
+					require.Equal(t, edvZCAP.InvocationTarget, delegated.InvocationTarget)
+				}
+			})
+	}
+
+	t.Run("passes on the zcaps of other invokers", func(t *testing.T) {
+		opts := &authzOperationOptions{docURI: docURI, edvDocTargets: true}
+		op, _ := newAuthzOperationWithOptions(t, opts)
+
+		edvToken := compress(t, marshal(t, newEDVZCAP(t, newAgent(t), "did:ex:other#key1", edvVaultURL)))
+
+		result := httptest.NewRecorder()
+		op.CreateAuthorization(result, newReq(t, http.MethodPost, "/authorizations", newRequest(edvToken)))
+		require.Equal(t, http.StatusOK, result.Code, result.Body.String())
+		require.Len(t, opts.cshQueries, 1)
+		require.Equal(t, edvToken, opts.cshQueries[0].UpstreamAuth.Edv.Zcap)
+	})
+
+	// the EDV zcap delegated depth times before it was delegated to the comparator
+	delegatedEDVZCAP := func(t *testing.T, depth int) string {
+		t.Helper()
+
+		zcap := newEDVZCAP(t, newAgent(t), "did:ex:123#key1", edvVaultURL)
+		chain := make([]interface{}, depth)
+
+		for i := range chain {
+			chain[i] = uuid.New().URN()
+		}
+
+		zcap.Proof[0]["capabilityChain"] = chain
+
+		return compress(t, marshal(t, zcap))
+	}
+
+	t.Run("delegates zcaps up to the max chain depth", func(t *testing.T) {
+		opts := &authzOperationOptions{docURI: docURI, maxChainDepth: 3}
+		op, _ := newAuthzOperationWithOptions(t, opts)
+
+		result := httptest.NewRecorder()
+		op.CreateAuthorization(result, newReq(t, http.MethodPost, "/authorizations",
+			newRequest(delegatedEDVZCAP(t, 2))))
+		require.Equal(t, http.StatusOK, result.Code, result.Body.String())
+		require.Len(t, opts.cshQueries, 1)
+
+		delegated, err := zcapld.DecompressZCAP(opts.cshQueries[0].UpstreamAuth.Edv.Zcap)
+		require.NoError(t, err)
+		require.Equal(t, 3, cshzcapld.ChainDepth(delegated))
+	})
+
+	t.Run("error Forbidden if the zcap would exceed the max chain depth", func(t *testing.T) {
+		for maxDepth, depth := range map[int]int{0: cshzcapld.DefaultMaxChainDepth, 3: 3} {
+			opts := &authzOperationOptions{docURI: docURI, maxChainDepth: maxDepth}
+			op, s := newAuthzOperationWithOptions(t, opts)
+
+			result := httptest.NewRecorder()
+			op.CreateAuthorization(result, newReq(t, http.MethodPost, "/authorizations",
+				newRequest(delegatedEDVZCAP(t, depth))))
+			require.Equal(t, http.StatusForbidden, result.Code, result.Body.String())
+			require.Contains(t, result.Body.String(), cshzcapld.ErrChainTooDeep.Error())
+			require.Empty(t, opts.cshQueries)
+
+			for k := range s.Store {
+				require.False(t, strings.HasPrefix(k, "authz_"))
+			}
+		}
+	})
+}
+
+func TestOperation_CreateAuthorization_EDVLocation(t *testing.T) {
+	newRequest := func() *models.Authorization {
+		rpDID := "did3"
+		docID := "docID"
+		auth := &models.Authorization{RequestingParty: &rpDID}
+		auth.Scope = &models.Scope{
+			DocID: &docID, VaultID: "vaultID", Actions: []string{"compare"},
+			AuthTokens: &models.ScopeAuthTokens{Edv: "edv", Kms: "kms"},
+		}
+
+		return auth
+	}
+
+	t.Run("the EDV document is located by the metadata", func(t *testing.T) {
+		// an EDV server mounted under a path prefix
+		opts := &authzOperationOptions{docMeta: &vault.DocumentMetadata{
+			ID:         "id",
+			URI:        "https://example.com/edv/encrypted-data-vaults/zMbxmSDn2Xzz/documents/VJYHHJx4C8J9Fsgz",
+			EDVURL:     "https://example.com/edv/encrypted-data-vaults",
+			EDVVaultID: "zMbxmSDn2Xzz",
+			EDVDocID:   "VJYHHJx4C8J9Fsgz",
+		}}
+		op, _ := newAuthzOperationWithOptions(t, opts)
+
+		result := httptest.NewRecorder()
+		op.CreateAuthorization(result, newReq(t, http.MethodPost, "/authorizations", newRequest()))
+		require.Equal(t, http.StatusOK, result.Code, result.Body.String())
+		require.Len(t, opts.cshQueries, 1)
+
+		query := opts.cshQueries[0]
+		require.Equal(t, "zMbxmSDn2Xzz", *query.VaultID)
+		require.Equal(t, "VJYHHJx4C8J9Fsgz", *query.DocID)
+		require.Equal(t, "https://example.com/edv/encrypted-data-vaults", query.UpstreamAuth.Edv.BaseURL)
+	})
+
+	t.Run("the EDV document is located by the URI of older metadata", func(t *testing.T) {
+		opts := &authzOperationOptions{
+			docURI: "https://example.com/encrypted-data-vaults/zMbxmSDn2Xzz/documents/VJYHHJx4C8J9Fsgz",
+		}
+		op, _ := newAuthzOperationWithOptions(t, opts)
+
+		result := httptest.NewRecorder()
+		op.CreateAuthorization(result, newReq(t, http.MethodPost, "/authorizations", newRequest()))
+		require.Equal(t, http.StatusOK, result.Code, result.Body.String())
+		require.Len(t, opts.cshQueries, 1)
+
+		query := opts.cshQueries[0]
+		require.Equal(t, "zMbxmSDn2Xzz", *query.VaultID)
+		require.Equal(t, "VJYHHJx4C8J9Fsgz", *query.DocID)
+		require.Equal(t, "https://example.com/encrypted-data-vaults", query.UpstreamAuth.Edv.BaseURL)
+	})
+
+	t.Run("unexpected URI", func(t *testing.T) {
+		opts := &authzOperationOptions{docURI: "https://example.com/documents"}
+		op, _ := newAuthzOperationWithOptions(t, opts)
+
+		result := httptest.NewRecorder()
+		op.CreateAuthorization(result, newReq(t, http.MethodPost, "/authorizations", newRequest()))
+		require.Equal(t, http.StatusInternalServerError, result.Code)
+		require.Contains(t, result.Body.String(), "unexpected EDV document URI")
+	})
+}
+
+func TestOperation_CreateAuthorization_DocMetaCache(t *testing.T) {
+	newRequest := func() *models.Authorization {
+		rpDID := "did3"
+		docID := "docID"
+		auth := &models.Authorization{RequestingParty: &rpDID}
+		auth.Scope = &models.Scope{
+			DocID: &docID, VaultID: "vaultID", Actions: []string{"compare"},
+			AuthTokens: &models.ScopeAuthTokens{Edv: "edv", Kms: "kms"},
+		}
+
+		return auth
+	}
+
+	authorize := func(t *testing.T, op *operation.Operation) int {
+		t.Helper()
+
+		result := httptest.NewRecorder()
+		op.CreateAuthorization(result, newReq(t, http.MethodPost, "/authorizations", newRequest()))
+
+		return result.Code
+	}
+
+	t.Run("metadata is fetched once within the TTL", func(t *testing.T) {
+		opts := &authzOperationOptions{docMetaTTL: time.Hour}
+		op, _ := newAuthzOperationWithOptions(t, opts)
+
+		require.Equal(t, http.StatusOK, authorize(t, op))
+		require.Equal(t, http.StatusOK, authorize(t, op))
+		require.Equal(t, int32(1), atomic.LoadInt32(&opts.docMetaLookups))
+		require.Len(t, opts.cshQueries, 2)
+	})
+
+	t.Run("metadata is not cached by default", func(t *testing.T) {
+		opts := &authzOperationOptions{}
+		op, _ := newAuthzOperationWithOptions(t, opts)
+
+		require.Equal(t, http.StatusOK, authorize(t, op))
+		require.Equal(t, http.StatusOK, authorize(t, op))
+		require.Equal(t, int32(2), atomic.LoadInt32(&opts.docMetaLookups))
+	})
+
+	t.Run("metadata is fetched again after a failure", func(t *testing.T) {
+		opts := &authzOperationOptions{docMetaTTL: time.Hour, cshFailures: 1}
+		op, _ := newAuthzOperationWithOptions(t, opts)
+
+		require.Equal(t, http.StatusInternalServerError, authorize(t, op))
+		require.Equal(t, http.StatusOK, authorize(t, op))
+		require.Equal(t, http.StatusOK, authorize(t, op))
+		require.Equal(t, int32(2), atomic.LoadInt32(&opts.docMetaLookups))
+	})
+}
+
+func TestOperation_CreateAuthorization_CSHQueryTargetType(t *testing.T) {
+	authorize := func(t *testing.T, op *operation.Operation) string {
+		t.Helper()
+
+		rpDID := "did3"
+		docID := "docID"
+		auth := &models.Authorization{RequestingParty: &rpDID}
+		auth.Scope = &models.Scope{
+			DocID: &docID, VaultID: "vaultID", Actions: []string{"compare"},
+			AuthTokens: &models.ScopeAuthTokens{Edv: "edv", Kms: "kms"},
+		}
+
+		result := httptest.NewRecorder()
+		op.CreateAuthorization(result, newReq(t, http.MethodPost, "/authorizations", auth))
+		require.Equal(t, http.StatusOK, result.Code, result.Body.String())
+
+		resp := &models.Authorization{}
+		require.NoError(t, json.Unmarshal(result.Body.Bytes(), resp))
+
+		return resp.AuthToken
+	}
+
+	t.Run("zcaps target the default type", func(t *testing.T) {
+		op, _ := newAuthzOperation(t, nil)
+
+		zcap, err := zcapld.DecompressZCAP(authorize(t, op))
+		require.NoError(t, err)
+		require.Equal(t, operation.DefaultCSHQueryTargetType, zcap.InvocationTarget.Type)
+	})
+
+	t.Run("zcaps target the configured type", func(t *testing.T) {
+		op, _ := newAuthzOperationWithOptions(t, &authzOperationOptions{cshTargetType: "urn:example:csh-query"})
+
+		authToken := authorize(t, op)
+
+		zcap, err := zcapld.DecompressZCAP(authToken)
+		require.NoError(t, err)
+		require.Equal(t, "urn:example:csh-query", zcap.InvocationTarget.Type)
+
+		result := httptest.NewRecorder()
+		op.VerifyAuthorization(result, newReq(t, http.MethodPost, "/authorizations/verify",
+			&models.AuthorizationVerification{AuthToken: &authToken}))
+		require.Equal(t, http.StatusOK, result.Code)
+
+		report := &models.VerificationReport{}
+		require.NoError(t, json.Unmarshal(result.Body.Bytes(), report))
+
+		for _, check := range report.Checks {
+			if *check.Name == "invocationTarget" {
+				require.True(t, *check.Valid, check.Error)
+			}
+		}
+	})
+}
+
+func TestOperation_CreateAuthorization_Summary(t *testing.T) {
+	op, _ := newAuthzOperation(t, nil)
+
+	rpDID := "did3"
+	docID := "docID"
+	auth := &models.Authorization{RequestingParty: &rpDID}
+	auth.Scope = &models.Scope{
+		DocID: &docID, VaultID: "vaultID", Actions: []string{"compare"},
+		AuthTokens: &models.ScopeAuthTokens{Edv: "edv", Kms: "kms"},
+	}
+
+	result := httptest.NewRecorder()
+	op.CreateAuthorization(result, newReq(t, http.MethodPost, "/authorizations", auth))
+	require.Equal(t, http.StatusOK, result.Code, result.Body.String())
+
+	resp := &models.Authorization{}
+	require.NoError(t, json.Unmarshal(result.Body.Bytes(), resp))
+
+	zcap, err := zcapld.DecompressZCAP(resp.AuthToken)
+	require.NoError(t, err)
+	require.NotEmpty(t, resp.InvocationTarget)
+	require.Equal(t, zcap.InvocationTarget.ID, resp.InvocationTarget)
+	require.Equal(t, zcap.AllowedAction, resp.AllowedActions)
+	require.Equal(t, []string{"reference"}, resp.AllowedActions)
+}
+
+func TestOperation_CreateAuthorization_ZCAPCompression(t *testing.T) {
+	const (
+		edvVaultURL = "https://edv.example.com/encrypted-data-vaults/zMbxmSDn2Xzz"
+		docURI      = edvVaultURL + "/documents/VJYHHJx4C8J9Fsgz"
+	)
+
+	edvZCAP := newEDVZCAP(t, newAgent(t), "did:ex:123#key1", edvVaultURL)
+
+	authorize := func(t *testing.T, op *operation.Operation) string {
+		t.Helper()
+
+		rpDID := "did3"
+		docID := "docID"
+		auth := &models.Authorization{RequestingParty: &rpDID}
+		auth.Scope = &models.Scope{
+			DocID: &docID, VaultID: "vaultID", Actions: []string{"compare"},
+			AuthTokens: &models.ScopeAuthTokens{Edv: compress(t, marshal(t, edvZCAP)), Kms: "kms"},
+		}
+
+		result := httptest.NewRecorder()
+		op.CreateAuthorization(result, newReq(t, http.MethodPost, "/authorizations", auth))
+		require.Equal(t, http.StatusOK, result.Code, result.Body.String())
+
+		resp := &models.Authorization{}
+		require.NoError(t, json.Unmarshal(result.Body.Bytes(), resp))
+
+		return resp.AuthToken
+	}
+
+	verify := func(t *testing.T, op *operation.Operation, authToken string) {
+		t.Helper()
+
+		result := httptest.NewRecorder()
+		op.VerifyAuthorization(result, newReq(t, http.MethodPost, "/authorizations/verify",
+			&models.AuthorizationVerification{AuthToken: &authToken}))
+		require.Equal(t, http.StatusOK, result.Code, result.Body.String())
+
+		report := &models.VerificationReport{}
+		require.NoError(t, json.Unmarshal(result.Body.Bytes(), report))
+		require.True(t, *report.Valid)
+	}
+
+	t.Run("auth tokens below the threshold are not compressed", func(t *testing.T) {
+		opts := &authzOperationOptions{
+			docURI:          docURI,
+			zcapCompression: []cshzcapld.CompressOpt{cshzcapld.WithCompressionThreshold(1 << 20)},
+		}
+		op, _ := newAuthzOperationWithOptions(t, opts)
+
+		authToken := authorize(t, op)
+
+		raw, err := base64.URLEncoding.DecodeString(authToken)
+		require.NoError(t, err)
+		require.True(t, json.Valid(raw))
+
+		verify(t, op, authToken)
+
+		// EDV servers only accept compressed zcaps
+		require.Len(t, opts.cshQueries, 1)
+
+		delegated, err := zcapld.DecompressZCAP(opts.cshQueries[0].UpstreamAuth.Edv.Zcap)
+		require.NoError(t, err)
+		require.Equal(t, edvZCAP.ID, delegated.Parent)
+	})
+
+	t.Run("auth tokens are compressed with the level", func(t *testing.T) {
+		op, _ := newAuthzOperationWithOptions(t, &authzOperationOptions{
+			docURI:          docURI,
+			zcapCompression: []cshzcapld.CompressOpt{cshzcapld.WithCompressionLevel(gzip.BestCompression)},
+		})
+
+		authToken := authorize(t, op)
+
+		_, err := zcapld.DecompressZCAP(authToken)
+		require.NoError(t, err)
+
+		verify(t, op, authToken)
+	})
+
+	t.Run("the CSH profile zcap may not be compressed", func(t *testing.T) {
+		op, _ := newAuthzOperationWithOptions(t, &authzOperationOptions{docURI: docURI, profileUncompressed: true})
+
+		verify(t, op, authorize(t, op))
+	})
+}
+
+func TestOperation_VerifyAuthorization(t *testing.T) {
+	newAuthToken := func(t *testing.T, op *operation.Operation, caveats ...models.Caveat) string {
+		t.Helper()
+
+		rpDID := "did3"
+		docID := "docID"
+		auth := &models.Authorization{RequestingParty: &rpDID}
+		auth.Scope = &models.Scope{
+			DocID: &docID, VaultID: "vaultID", Actions: []string{"compare"},
+			AuthTokens: &models.ScopeAuthTokens{Kms: "kms", Edv: "edv"},
+		}
+		auth.Scope.SetCaveats(caveats)
+
+		result := httptest.NewRecorder()
+		op.CreateAuthorization(result, newReq(t, http.MethodPost, "/authorizations", auth))
+		require.Equal(t, http.StatusOK, result.Code)
+
+		resp := &models.Authorization{}
+		require.NoError(t, json.Unmarshal(result.Body.Bytes(), resp))
+
+		return resp.AuthToken
+	}
+
+	verify := func(t *testing.T, op *operation.Operation, authToken, rp string) *models.VerificationReport {
+		t.Helper()
+
+		result := httptest.NewRecorder()
+		op.VerifyAuthorization(result, newReq(t, http.MethodPost, "/authorizations/verify",
+			&models.AuthorizationVerification{AuthToken: &authToken, RequestingParty: rp}))
+		require.Equal(t, http.StatusOK, result.Code)
+
+		report := &models.VerificationReport{}
+		require.NoError(t, json.Unmarshal(result.Body.Bytes(), report))
+
+		return report
+	}
+
+	failedChecks := func(report *models.VerificationReport) []string {
+		var failed []string
+
+		for _, check := range report.Checks {
+			if !*check.Valid {
+				failed = append(failed, *check.Name)
+			}
+		}
+
+		return failed
+	}
+
+	t.Run("success", func(t *testing.T) {
+		op, _ := newAuthzOperation(t, &operation.AuthzExpiry{Default: time.Hour})
+		authToken := newAuthToken(t, op)
+
+		report := verify(t, op, authToken, "did3")
+		require.True(t, *report.Valid)
+		require.Empty(t, failedChecks(report))
+		require.Len(t, report.Checks, 6)
+		require.Equal(t, "did3", report.Invoker)
+		require.NotEmpty(t, report.ID)
+		require.NotEmpty(t, report.InvocationTarget)
+		require.NotNil(t, report.ExpiresAt)
+		require.WithinDuration(t, time.Now().Add(time.Hour), time.Time(*report.ExpiresAt), time.Minute)
+	})
+
+	t.Run("success without expiry and requesting party", func(t *testing.T) {
+		op, _ := newAuthzOperation(t, nil)
+
+		report := verify(t, op, newAuthToken(t, op), "")
+		require.True(t, *report.Valid)
+		require.Nil(t, report.ExpiresAt)
+	})
+
+	t.Run("invalid if requesting party is not the invoker", func(t *testing.T) {
+		op, _ := newAuthzOperation(t, nil)
+
+		report := verify(t, op, newAuthToken(t, op), "did4")
+		require.False(t, *report.Valid)
+		require.Equal(t, []string{"invoker"}, failedChecks(report))
+	})
+
+	t.Run("invalid if token was tampered with", func(t *testing.T) {
+		op, _ := newAuthzOperation(t, nil)
+
+		zcap, err := zcapld.DecompressZCAP(newAuthToken(t, op))
+		require.NoError(t, err)
+
+		zcap.AllowedAction = []string{"read"}
+
+		report := verify(t, op, compress(t, marshal(t, zcap)), "did3")
+		require.False(t, *report.Valid)
+		require.Equal(t, []string{"signature", "allowedAction"}, failedChecks(report))
+	})
+
+	t.Run("invalid if token was not issued by the comparator", func(t *testing.T) {
+		op, _ := newAuthzOperation(t, nil)
+		agent := newAgent(t)
+
+		report := verify(t, op, compress(t, marshal(t, newZCAP(t, agent, agent))), "")
+		require.False(t, *report.Valid)
+		require.ElementsMatch(t,
+			[]string{"signature", "parentCapability", "allowedAction", "invocationTarget"}, failedChecks(report))
+	})
+
+	t.Run("invalid if token has expired", func(t *testing.T) {
+		op, _ := newAuthzOperation(t, nil)
+		authToken := newAuthToken(t, op, &models.ExpiryCaveat{Duration: 1})
+
+		time.Sleep(2 * time.Second)
+
+		report := verify(t, op, authToken, "did3")
+		require.False(t, *report.Valid)
+		require.Equal(t, []string{"caveats"}, failedChecks(report))
+		require.NotNil(t, report.ExpiresAt)
+	})
+
+	t.Run("error if token is malformed", func(t *testing.T) {
+		op, _ := newAuthzOperation(t, nil)
+
+		result := httptest.NewRecorder()
+		op.VerifyAuthorization(result, newReq(t, http.MethodPost, "/authorizations/verify",
+			&models.AuthorizationVerification{AuthToken: swag.String("invalid")}))
+		require.Equal(t, http.StatusBadRequest, result.Code)
+		require.Contains(t, result.Body.String(), "failed to parse auth token")
+	})
+
+	t.Run("error if token is missing", func(t *testing.T) {
+		op, _ := newAuthzOperation(t, nil)
+
+		result := httptest.NewRecorder()
+		op.VerifyAuthorization(result, newReq(t, http.MethodPost, "/authorizations/verify",
+			&models.AuthorizationVerification{}))
+		require.Equal(t, http.StatusBadRequest, result.Code)
+	})
+
+	t.Run("error if request is malformed", func(t *testing.T) {
+		op, _ := newAuthzOperation(t, nil)
+
+		result := httptest.NewRecorder()
+		op.VerifyAuthorization(result, httptest.NewRequest(http.MethodPost, "/authorizations/verify",
+			bytes.NewReader([]byte("{"))))
+		require.Equal(t, http.StatusBadRequest, result.Code)
+	})
+}
+
 func TestOperation_Compare(t *testing.T) {
 	t.Run("test bad request", func(t *testing.T) {
 		s := &mockstorage.MockStore{Store: make(map[string]mockstorage.DBEntry)}
@@ -477,7 +1247,74 @@ func TestOperation_Compare(t *testing.T) {
 		))
 
 		require.Equal(t, http.StatusBadRequest, result.Code)
-		require.Contains(t, result.Body.String(), "bad request")
+		require.Contains(t, result.Body.String(), "bad request")
+	})
+
+	t.Run("test missing op", func(t *testing.T) {
+		s := &mockstorage.MockStore{Store: make(map[string]mockstorage.DBEntry)}
+		s.Store["config"] = mockstorage.DBEntry{Value: []byte(`{}`)}
+		s.Store["csh_config"] = mockstorage.DBEntry{Value: []byte(`{}`)}
+		op, err := operation.New(&operation.Config{
+			CSHBaseURL:    "https://localhost",
+			StoreProvider: &mockstorage.MockStoreProvider{Store: s},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, op)
+		result := httptest.NewRecorder()
+		op.Compare(result, newReq(t, http.MethodPost, "/compare", &models.Comparison{}))
+
+		require.Equal(t, http.StatusBadRequest, result.Code)
+		require.Contains(t, result.Body.String(), "op in body is required")
+	})
+
+	t.Run("test invalid DocQuery", func(t *testing.T) {
+		s := &mockstorage.MockStore{Store: make(map[string]mockstorage.DBEntry)}
+		s.Store["config"] = mockstorage.DBEntry{Value: []byte(`{}`)}
+		s.Store["csh_config"] = mockstorage.DBEntry{Value: []byte(`{}`)}
+		op, err := operation.New(&operation.Config{
+			CSHBaseURL:    "https://localhost",
+			StoreProvider: &mockstorage.MockStoreProvider{Store: s},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, op)
+		docID := "docID"
+		eq := &models.EqOp{}
+		eq.SetArgs([]models.Query{
+			&models.DocQuery{DocID: &docID},
+			&models.AuthorizedQuery{},
+		})
+		cr := &models.Comparison{}
+		cr.SetOp(eq)
+
+		result := httptest.NewRecorder()
+		op.Compare(result, newReq(t, http.MethodPost, "/compare", cr))
+
+		require.Equal(t, http.StatusBadRequest, result.Code)
+		require.Contains(t, result.Body.String(), "op.args.0.authTokens in body is required")
+		require.Contains(t, result.Body.String(), "op.args.0.vaultID in body is required")
+	})
+
+	t.Run("test too few args", func(t *testing.T) {
+		s := &mockstorage.MockStore{Store: make(map[string]mockstorage.DBEntry)}
+		s.Store["config"] = mockstorage.DBEntry{Value: []byte(`{}`)}
+		s.Store["csh_config"] = mockstorage.DBEntry{Value: []byte(`{}`)}
+		op, err := operation.New(&operation.Config{
+			CSHBaseURL:    "https://localhost",
+			StoreProvider: &mockstorage.MockStoreProvider{Store: s},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, op)
+		authToken := "token"
+		eq := &models.EqOp{}
+		eq.SetArgs([]models.Query{&models.AuthorizedQuery{AuthToken: &authToken}})
+		cr := &models.Comparison{}
+		cr.SetOp(eq)
+
+		result := httptest.NewRecorder()
+		op.Compare(result, newReq(t, http.MethodPost, "/compare", cr))
+
+		require.Equal(t, http.StatusBadRequest, result.Code)
+		require.Contains(t, result.Body.String(), "op.args in body should have at least 2 items")
 	})
 
 	t.Run("test failed to get doc meta from vault server", func(t *testing.T) {
@@ -501,7 +1338,13 @@ func TestOperation_Compare(t *testing.T) {
 		query := make([]models.Query, 0)
 		docID := "docID18"
 		vaultID := "vaultID18"
-		query = append(query, &models.DocQuery{DocID: &docID, VaultID: &vaultID})
+		query = append(query, &models.DocQuery{
+			DocID: &docID, VaultID: &vaultID,
+			AuthTokens: &models.DocQueryAO1AuthTokens{Edv: "edvToken", Kms: "kmsToken"},
+		}, &models.DocQuery{
+			DocID: &docID, VaultID: &vaultID,
+			AuthTokens: &models.DocQueryAO1AuthTokens{Edv: "edvToken", Kms: "kmsToken"},
+		})
 		eq.SetArgs(query)
 		cr.SetOp(eq)
 		op.Compare(result, newReq(t,
@@ -514,6 +1357,48 @@ func TestOperation_Compare(t *testing.T) {
 		require.Contains(t, result.Body.String(), "failed to get doc meta")
 	})
 
+	t.Run("test revoked auth tokens", func(t *testing.T) {
+		agent := newAgent(t)
+		edvZCAP := newZCAP(t, agent, agent)
+
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "/revocations/"+edvZCAP.ID, r.URL.Path)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer serv.Close()
+
+		s := &mockstorage.MockStore{Store: make(map[string]mockstorage.DBEntry)}
+		s.Store["config"] = mockstorage.DBEntry{Value: []byte(`{}`)}
+		s.Store["csh_config"] = mockstorage.DBEntry{Value: []byte(`{}`)}
+		op, err := operation.New(&operation.Config{
+			CSHBaseURL: "https://localhost", VaultBaseURL: serv.URL,
+			StoreProvider: &mockstorage.MockStoreProvider{Store: s},
+		})
+		require.NoError(t, err)
+
+		docID := "docID18"
+		vaultID := "vaultID18"
+		eq := &models.EqOp{}
+		eq.SetArgs([]models.Query{
+			&models.DocQuery{
+				DocID: &docID, VaultID: &vaultID,
+				AuthTokens: &models.DocQueryAO1AuthTokens{Edv: compress(t, marshal(t, edvZCAP)), Kms: "kmsToken"},
+			},
+			&models.DocQuery{
+				DocID: &docID, VaultID: &vaultID,
+				AuthTokens: &models.DocQueryAO1AuthTokens{Edv: "edvToken", Kms: "kmsToken"},
+			},
+		})
+		cr := &models.Comparison{}
+		cr.SetOp(eq)
+
+		result := httptest.NewRecorder()
+		op.Compare(result, newReq(t, http.MethodPost, "/compare", cr))
+
+		require.Equal(t, http.StatusForbidden, result.Code)
+		require.Contains(t, result.Body.String(), "zcap has been revoked")
+	})
+
 	t.Run("test error from compare csh", func(t *testing.T) {
 		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusOK)
@@ -549,6 +1434,9 @@ func TestOperation_Compare(t *testing.T) {
 		query = append(query, &models.DocQuery{
 			DocID: &docID, VaultID: &vaultID,
 			AuthTokens: &models.DocQueryAO1AuthTokens{Edv: "edvToken", Kms: "kmsToken"},
+		}, &models.DocQuery{
+			DocID: &docID, VaultID: &vaultID,
+			AuthTokens: &models.DocQueryAO1AuthTokens{Edv: "edvToken", Kms: "kmsToken"},
 		})
 		eq.SetArgs(query)
 		cr.SetOp(eq)
@@ -700,6 +1588,43 @@ func TestOperation_Extract(t *testing.T) {
 		require.Contains(t, result.Body.String(), "bad request")
 	})
 
+	t.Run("test invalid AuthorizedQuery", func(t *testing.T) {
+		s := &mockstorage.MockStore{Store: make(map[string]mockstorage.DBEntry)}
+		s.Store["config"] = mockstorage.DBEntry{Value: []byte(`{}`)}
+		s.Store["csh_config"] = mockstorage.DBEntry{Value: []byte(`{}`)}
+		op, err := operation.New(&operation.Config{
+			CSHBaseURL:    "https://localhost",
+			StoreProvider: &mockstorage.MockStoreProvider{Store: s},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, op)
+		request := &models.Extract{}
+		request.SetQueries([]models.Query{&models.AuthorizedQuery{}})
+
+		result := httptest.NewRecorder()
+		op.Extract(result, newReq(t, http.MethodPost, "/extract", request))
+
+		require.Equal(t, http.StatusBadRequest, result.Code)
+		require.Contains(t, result.Body.String(), "queries.0.authToken in body is required")
+	})
+
+	t.Run("test missing queries", func(t *testing.T) {
+		s := &mockstorage.MockStore{Store: make(map[string]mockstorage.DBEntry)}
+		s.Store["config"] = mockstorage.DBEntry{Value: []byte(`{}`)}
+		s.Store["csh_config"] = mockstorage.DBEntry{Value: []byte(`{}`)}
+		op, err := operation.New(&operation.Config{
+			CSHBaseURL:    "https://localhost",
+			StoreProvider: &mockstorage.MockStoreProvider{Store: s},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, op)
+		result := httptest.NewRecorder()
+		op.Extract(result, newReq(t, http.MethodPost, "/extract", &models.Extract{}))
+
+		require.Equal(t, http.StatusBadRequest, result.Code)
+		require.Contains(t, result.Body.String(), "queries in body is required")
+	})
+
 	t.Run("test failed to decompress ZCAP", func(t *testing.T) {
 		s := &mockstorage.MockStore{Store: make(map[string]mockstorage.DBEntry)}
 		s.Store["config"] = mockstorage.DBEntry{Value: []byte(`{}`)}
@@ -795,6 +1720,99 @@ func TestOperation_Extract(t *testing.T) {
 		require.Contains(t, result.Body.String(), "dataValue")
 	})
 
+	t.Run("test success with metadata", func(t *testing.T) {
+		cshServ := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "true", r.URL.Query().Get("include_metadata"))
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			m := []*cshclientmodels.ExtractionResponseItems0{{
+				Document: "dataValue",
+				VaultID:  "vaultID",
+				DocID:    "docID",
+				Path:     "$.email",
+				Metadata: &cshclientmodels.ExtractionMetadata{
+					EdvDocURI: "https://edv.example.com/vaultID/documents/docID",
+					Sequence:  3,
+				},
+			}}
+
+			res, err := json.Marshal(m)
+			require.NoError(t, err)
+
+			_, err = fmt.Fprint(w, string(res))
+			require.NoError(t, err)
+		}))
+		defer cshServ.Close()
+
+		s := &mockstorage.MockStore{Store: make(map[string]mockstorage.DBEntry)}
+		s.Store["config"] = mockstorage.DBEntry{Value: []byte(`{}`)}
+		s.Store["csh_config"] = mockstorage.DBEntry{Value: []byte(`{}`)}
+		op, err := operation.New(&operation.Config{
+			CSHBaseURL: cshServ.URL, VaultBaseURL: "",
+			StoreProvider: &mockstorage.MockStoreProvider{Store: s},
+		})
+		require.NoError(t, err)
+		result := httptest.NewRecorder()
+		chs := newAgent(t)
+		zcap := newZCAP(t, chs, chs)
+		chsZCAP := compress(t, marshal(t, zcap))
+		request := &models.Extract{IncludeMetadata: true}
+		request.SetQueries([]models.Query{&models.AuthorizedQuery{AuthToken: &chsZCAP}})
+		op.Extract(result, newReq(t,
+			http.MethodPost,
+			"/extract",
+			request,
+		))
+
+		require.Equal(t, http.StatusOK, result.Code)
+
+		response := &models.ExtractResp{}
+		require.NoError(t, json.NewDecoder(result.Body).Decode(response))
+		require.Len(t, response.Documents, 1)
+		require.Equal(t, "dataValue", response.Documents[0].Contents)
+		require.Equal(t, &models.ExtractionSource{
+			Index:           swag.Int64(0),
+			AuthorizationID: zcap.ID,
+			VaultID:         "vaultID",
+			DocID:           "docID",
+			DocAttrPath:     "$.email",
+			EdvDocURI:       "https://edv.example.com/vaultID/documents/docID",
+			Sequence:        3,
+		}, response.Documents[0].Source)
+	})
+
+	t.Run("test metadata omitted by default", func(t *testing.T) {
+		cshServ := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Empty(t, r.URL.Query().Get("include_metadata"))
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			_, err := fmt.Fprint(w, `[{"document":"dataValue","vaultID":"vaultID","docID":"docID"}]`)
+			require.NoError(t, err)
+		}))
+		defer cshServ.Close()
+
+		s := &mockstorage.MockStore{Store: make(map[string]mockstorage.DBEntry)}
+		s.Store["config"] = mockstorage.DBEntry{Value: []byte(`{}`)}
+		s.Store["csh_config"] = mockstorage.DBEntry{Value: []byte(`{}`)}
+		op, err := operation.New(&operation.Config{
+			CSHBaseURL: cshServ.URL, VaultBaseURL: "",
+			StoreProvider: &mockstorage.MockStoreProvider{Store: s},
+		})
+		require.NoError(t, err)
+		result := httptest.NewRecorder()
+		chs := newAgent(t)
+		chsZCAP := compress(t, marshal(t, newZCAP(t, chs, chs)))
+		request := &models.Extract{}
+		request.SetQueries([]models.Query{&models.AuthorizedQuery{AuthToken: &chsZCAP}})
+		op.Extract(result, newReq(t, http.MethodPost, "/extract", request))
+
+		require.Equal(t, http.StatusOK, result.Code)
+		require.NotContains(t, result.Body.String(), "source")
+		require.NotContains(t, result.Body.String(), "vaultID")
+	})
+
 	t.Run("error StatusNotImplemented for DocQuery", func(t *testing.T) {
 		s := &mockstorage.MockStore{Store: make(map[string]mockstorage.DBEntry)}
 		s.Store["config"] = mockstorage.DBEntry{Value: []byte(`{}`)}
@@ -805,14 +1823,20 @@ func TestOperation_Extract(t *testing.T) {
 		})
 		require.NoError(t, err)
 
+		docID := "docID"
+		vaultID := "vaultID"
 		request := &models.Extract{}
-		request.SetQueries([]models.Query{&models.DocQuery{}})
+		request.SetQueries([]models.Query{&models.DocQuery{
+			DocID: &docID, VaultID: &vaultID,
+			AuthTokens: &models.DocQueryAO1AuthTokens{Edv: "edvToken", Kms: "kmsToken"},
+		}})
 
 		result := httptest.NewRecorder()
 
 		op.Extract(result, newReq(t, http.MethodPost, "/extract", request))
 		require.Equal(t, http.StatusNotImplemented, result.Code)
 		require.Contains(t, result.Body.String(), "unsupported query type")
+		require.Contains(t, result.Body.String(), `"code":"NOT_IMPLEMENTED"`)
 	})
 }
 
@@ -873,6 +1897,269 @@ func TestOperation_GetConfig(t *testing.T) {
 	})
 }
 
+func TestOperation_CheckDID(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		var keyID string
+
+		op := newDIDCheckOperation(t, func(didID string, keyIDs ...string) (*did.DocResolution, error) {
+			keyID = keyIDs[0]
+
+			return comparatorDIDResolution(didID, keyID), nil
+		})
+
+		require.Error(t, op.DIDReady())
+		require.Contains(t, op.DIDReady().Error(), "has not been checked yet")
+		require.NoError(t, op.CheckDID())
+		require.NoError(t, op.DIDReady())
+
+		result := httptest.NewRecorder()
+		op.GetConfig(result, nil)
+		require.Equal(t, http.StatusOK, result.Code)
+		require.Contains(t, result.Body.String(), `"didResolvable":true`)
+	})
+
+	t.Run("error if DID is not resolvable", func(t *testing.T) {
+		op := newDIDCheckOperation(t, func(string, ...string) (*did.DocResolution, error) {
+			return nil, errors.New("DID not found")
+		})
+
+		err := op.CheckDID()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to resolve DID [did:ex:123]: DID not found")
+		require.Equal(t, err, op.DIDReady())
+
+		result := httptest.NewRecorder()
+		op.GetConfig(result, nil)
+		require.Equal(t, http.StatusOK, result.Code)
+		require.Contains(t, result.Body.String(), `"didResolvable":false`)
+	})
+
+	t.Run("error if signing key is not a capabilityDelegation method", func(t *testing.T) {
+		op := newDIDCheckOperation(t, func(didID string, _ ...string) (*did.DocResolution, error) {
+			return comparatorDIDResolution(didID, "other"), nil
+		})
+
+		err := op.CheckDID()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "is not a capabilityDelegation method of DID [did:ex:123]")
+	})
+
+	t.Run("recovers once the DID resolves", func(t *testing.T) {
+		resolvable := false
+
+		op := newDIDCheckOperation(t, func(didID string, keyIDs ...string) (*did.DocResolution, error) {
+			if !resolvable {
+				return nil, errors.New("DID not found")
+			}
+
+			return comparatorDIDResolution(didID, keyIDs[0]), nil
+		})
+
+		require.Error(t, op.CheckDID())
+
+		resolvable = true
+
+		require.NoError(t, op.CheckDID())
+		require.NoError(t, op.DIDReady())
+	})
+
+	t.Run("monitor stops when the context is done", func(t *testing.T) {
+		checked := make(chan struct{}, 1)
+
+		op := newDIDCheckOperation(t, func(didID string, keyIDs ...string) (*did.DocResolution, error) {
+			select {
+			case checked <- struct{}{}:
+			default:
+			}
+
+			return comparatorDIDResolution(didID, keyIDs[0]), nil
+		})
+
+		ctx, cancel := gocontext.WithCancel(gocontext.Background())
+		done := make(chan struct{})
+
+		go func() {
+			op.MonitorDID(ctx, time.Millisecond)
+			close(done)
+		}()
+
+		<-checked
+		cancel()
+		<-done
+
+		require.NoError(t, op.DIDReady())
+	})
+}
+
+func newDIDCheckOperation(t *testing.T,
+	resolve func(didID string, keyIDs ...string) (*did.DocResolution, error)) *operation.Operation {
+	t.Helper()
+
+	s := &mockstorage.MockStore{Store: make(map[string]mockstorage.DBEntry)}
+	didID := "did:ex:123"
+	keyID := uuid.New().String()
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	jwkBytes, err := jose.JSONWebKey{KeyID: keyID, Key: privateKey}.MarshalJSON()
+	require.NoError(t, err)
+	conf := models.Config{Did: &didID, Key: []json.RawMessage{jwkBytes}}
+	confBytes, err := conf.MarshalBinary()
+	require.NoError(t, err)
+	s.Store["config"] = mockstorage.DBEntry{Value: confBytes}
+	s.Store["csh_config"] = mockstorage.DBEntry{Value: []byte(`{}`)}
+
+	op, err := operation.New(&operation.Config{
+		CSHBaseURL:    "https://localhost",
+		StoreProvider: &mockstorage.MockStoreProvider{Store: s},
+		VDR: &vdr.MockVDRegistry{
+			ResolveFunc: func(didID string, _ ...vdrapi.DIDMethodOption) (*did.DocResolution, error) {
+				return resolve(didID, keyID)
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	return op
+}
+
+func comparatorDIDResolution(didID, keyID string) *did.DocResolution {
+	vm := did.VerificationMethod{ID: didID + "#" + keyID, Type: "JsonWebKey2020", Controller: didID}
+
+	return &did.DocResolution{DIDDocument: &did.Doc{
+		ID:                   didID,
+		VerificationMethod:   []did.VerificationMethod{vm},
+		CapabilityDelegation: []did.Verification{*did.NewReferencedVerification(&vm, did.CapabilityDelegation)},
+	}}
+}
+
+func newAuthzOperation(t *testing.T, expiry *operation.AuthzExpiry) (*operation.Operation, *mockstorage.MockStore) {
+	t.Helper()
+
+	return newAuthzOperationWithOptions(t, &authzOperationOptions{expiry: expiry})
+}
+
+type authzOperationOptions struct {
+	expiry         *operation.AuthzExpiry
+	profileExpires *time.Time // expiry of the CSH profile zcap
+	revoked        []string   // IDs of the zcaps revoked by the vault server
+	docURI         string     // EDV URI of the authorized document
+	edvDocTargets  bool
+	docMeta        *vault.DocumentMetadata     // metadata of the authorized document, instead of the one at docURI
+	cshQueries     []*cshclientmodels.DocQuery // queries created in the CSH
+	cshFailures    int                         // number of query creations the CSH fails first
+	docMetaTTL     time.Duration
+	docMetaLookups int32 // number of doc metadata requests served by the vault server
+	cshTargetType  string
+	// zcapCompression compresses the zcaps of the comparator, profileUncompressed leaves the CSH profile zcap
+	// uncompressed as the CSH does with small zcaps.
+	zcapCompression     []cshzcapld.CompressOpt
+	profileUncompressed bool
+	maxChainDepth       int
+}
+
+func newAuthzOperationWithOptions(t *testing.T,
+	opts *authzOperationOptions) (*operation.Operation, *mockstorage.MockStore) {
+	t.Helper()
+
+	serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if zcapID := strings.TrimPrefix(r.URL.Path, "/revocations/"); zcapID != r.URL.Path {
+			status := http.StatusNotFound
+
+			for _, id := range opts.revoked {
+				if id == zcapID {
+					status = http.StatusOK
+				}
+			}
+
+			w.WriteHeader(status)
+
+			return
+		}
+
+		atomic.AddInt32(&opts.docMetaLookups, 1)
+
+		docURI := opts.docURI
+		if docURI == "" {
+			docURI = "/test/test/test/test"
+		}
+
+		p := &vault.DocumentMetadata{ID: "id", URI: docURI}
+		if opts.docMeta != nil {
+			p = opts.docMeta
+		}
+
+		w.WriteHeader(http.StatusOK)
+		b, err := json.Marshal(p)
+		require.NoError(t, err)
+
+		_, err = fmt.Fprint(w, string(b))
+		require.NoError(t, err)
+	}))
+	t.Cleanup(serv.Close)
+
+	cshServ := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := &cshclientmodels.DocQuery{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(query))
+
+		opts.cshQueries = append(opts.cshQueries, query)
+
+		if opts.cshFailures > 0 {
+			opts.cshFailures--
+
+			w.WriteHeader(http.StatusInternalServerError)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", "https://localhost:8080/queries")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	t.Cleanup(cshServ.Close)
+
+	s := &mockstorage.MockStore{Store: make(map[string]mockstorage.DBEntry)}
+	didID := "did:ex:123"
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	jwkBytes, err := jose.JSONWebKey{KeyID: uuid.New().String(), Key: privateKey}.MarshalJSON()
+	require.NoError(t, err)
+	conf := models.Config{Did: &didID, Key: []json.RawMessage{jwkBytes}}
+	confBytes, err := conf.MarshalBinary()
+	require.NoError(t, err)
+	s.Store["config"] = mockstorage.DBEntry{Value: confBytes}
+	chs := newAgent(t)
+	profileZCAP := make(map[string]interface{})
+	require.NoError(t, json.Unmarshal(marshal(t, newZCAP(t, chs, chs)), &profileZCAP))
+
+	if opts.profileExpires != nil {
+		profileZCAP["expires"] = opts.profileExpires.UTC().Format(time.RFC3339)
+	}
+
+	p := cshclientmodels.Profile{Zcap: compress(t, marshal(t, profileZCAP))}
+	if opts.profileUncompressed {
+		p.Zcap = base64.URLEncoding.EncodeToString(marshal(t, profileZCAP))
+	}
+
+	chsProfileBytes, err := p.MarshalBinary()
+	require.NoError(t, err)
+	s.Store["csh_config"] = mockstorage.DBEntry{Value: chsProfileBytes}
+
+	op, err := operation.New(&operation.Config{
+		CSHBaseURL: cshServ.URL, VaultBaseURL: serv.URL,
+		StoreProvider:      &mockstorage.MockStoreProvider{Store: s},
+		DocumentLoader:     testutil.DocumentLoader(t),
+		AuthzExpiry:        opts.expiry,
+		EDVDocumentTargets: opts.edvDocTargets,
+		DocMetaCacheTTL:    opts.docMetaTTL,
+		CSHQueryTargetType: opts.cshTargetType,
+		ZCAPCompression:    opts.zcapCompression,
+		MaxZCAPChainDepth:  opts.maxChainDepth,
+	})
+	require.NoError(t, err)
+
+	return op, s
+}
+
 func newReq(t *testing.T, method, path string, payload interface{}) *http.Request { //nolint: unparam
 	t.Helper()
 
@@ -921,6 +2208,30 @@ func newZCAP(t *testing.T, server, rp *context.Provider) *zcapld.Capability {
 	return zcap
 }
 
+// newEDVZCAP returns a zcap for the EDV vault signed by the server.
+func newEDVZCAP(t *testing.T, server *context.Provider, invoker, vaultURL string) *zcapld.Capability {
+	t.Helper()
+
+	signer, err := signature.NewCryptoSigner(server.Crypto(), server.KMS(), kms.ED25519Type)
+	require.NoError(t, err)
+
+	zcap, err := zcapld.NewCapability(
+		&zcapld.Signer{
+			SignatureSuite:     ed25519signature2018.New(suite.WithSigner(signer)),
+			SuiteType:          ed25519signature2018.SignatureType,
+			VerificationMethod: didKeyURL(signer.PublicKeyBytes()),
+			ProcessorOpts:      []jsonld.ProcessorOpts{jsonld.WithDocumentLoader(testutil.DocumentLoader(t))},
+		},
+		zcapld.WithID(uuid.New().URN()),
+		zcapld.WithInvoker(invoker),
+		zcapld.WithAllowedActions("read"),
+		zcapld.WithInvocationTarget(vaultURL, "urn:edv:vault"),
+	)
+	require.NoError(t, err)
+
+	return zcap
+}
+
 func newAgent(t *testing.T) *context.Provider {
 	t.Helper()
 
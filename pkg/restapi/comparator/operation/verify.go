@@ -0,0 +1,227 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/jsonld"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/suite"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/suite/ed25519signature2018"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/verifier"
+	"github.com/trustbloc/edge-core/pkg/zcapld"
+
+	"github.com/trustbloc/ace/pkg/restapi/comparator/operation/models"
+)
+
+// HandleVerifyAuthz handles a verifyAuthzReq.
+func (o *Operation) HandleVerifyAuthz(w http.ResponseWriter, request *models.AuthorizationVerification) {
+	zcap, err := decompressZCAP(*request.AuthToken)
+	if err != nil {
+		respondErrorf(w, http.StatusBadRequest, "failed to parse auth token: %s", err.Error())
+
+		return
+	}
+
+	report, err := o.verifyAuthz(zcap, request.RequestingParty, time.Now())
+	if err != nil {
+		respondErrorf(w, http.StatusInternalServerError, "failed to verify auth token: %s", err.Error())
+
+		return
+	}
+
+	headers := map[string]string{
+		"Content-Type": "application/json",
+	}
+
+	respond(w, http.StatusOK, headers, report)
+}
+
+// verifyAuthz checks that the zcap was minted by driveZCAPForCSH with the comparator's current configuration.
+// It is read-only: neither the CSH nor the vault server are contacted.
+func (o *Operation) verifyAuthz(zcap *zcapld.Capability, requestingParty string,
+	now time.Time) (*models.VerificationReport, error) {
+	keyID, key, err := getKey(o.comparatorConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	cshZCAP, err := decompressZCAP(o.cshProfile.Zcap)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CSH profile zcap: %w", err)
+	}
+
+	report := &models.VerificationReport{
+		ID:               zcap.ID,
+		Invoker:          zcap.Invoker,
+		InvocationTarget: zcap.InvocationTarget.ID,
+		Checks:           []*models.VerificationCheck{},
+		Valid:            swag.Bool(true),
+	}
+
+	check := func(name string, err error) {
+		c := &models.VerificationCheck{Name: swag.String(name), Valid: swag.Bool(err == nil)}
+
+		if err != nil {
+			c.Error = err.Error()
+			report.Valid = swag.Bool(false)
+		}
+
+		report.Checks = append(report.Checks, c)
+	}
+
+	verificationMethod := fmt.Sprintf("%s#%s", *o.comparatorConfig.Did, keyID)
+
+	check("signature", o.verifyDelegationProof(zcap, verificationMethod, key))
+	check("parentCapability", verifyParentCapability(zcap, cshZCAP.ID))
+	check("invoker", verifyInvoker(zcap, requestingParty))
+	check("allowedAction", verifyAllowedAction(zcap))
+	check("invocationTarget", verifyInvocationTarget(zcap, o.cshQueryTargetType))
+
+	expiresAt, err := verifyCaveats(zcap, now)
+	check("caveats", err)
+
+	if expiresAt != nil {
+		dt := strfmt.DateTime(*expiresAt)
+		report.ExpiresAt = &dt
+	}
+
+	return report, nil
+}
+
+func (o *Operation) verifyDelegationProof(zcap *zcapld.Capability, verificationMethod string,
+	key ed25519.PrivateKey) error {
+	if len(zcap.Proof) == 0 {
+		return errors.New("zcap has no proof")
+	}
+
+	for _, proof := range zcap.Proof {
+		if proof["verificationMethod"] != verificationMethod {
+			return fmt.Errorf("proof was not created by the comparator: %v", proof["verificationMethod"])
+		}
+
+		if proof["proofPurpose"] != "capabilityDelegation" {
+			return fmt.Errorf("unexpected proof purpose: %v", proof["proofPurpose"])
+		}
+	}
+
+	publicKey, ok := key.Public().(ed25519.PublicKey)
+	if !ok {
+		return errors.New("comparator key is not ed25519")
+	}
+
+	v, err := verifier.New(
+		zcapld.SimpleKeyResolver{
+			verificationMethod: &verifier.PublicKey{Type: "Ed25519VerificationKey2018", Value: publicKey},
+		},
+		ed25519signature2018.New(suite.WithVerifier(ed25519signature2018.NewPublicKeyVerifier())),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to init verifier: %w", err)
+	}
+
+	raw, err := json.Marshal(zcap)
+	if err != nil {
+		return fmt.Errorf("failed to marshal zcap: %w", err)
+	}
+
+	err = v.Verify(raw, jsonld.WithDocumentLoader(o.documentLoader))
+	if err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+
+	return nil
+}
+
+func verifyParentCapability(zcap *zcapld.Capability, cshZCAPID string) error {
+	if zcap.Parent != cshZCAPID {
+		return fmt.Errorf("parent capability %s is not the CSH profile zcap %s", zcap.Parent, cshZCAPID)
+	}
+
+	for _, proof := range zcap.Proof {
+		chain, ok := proof["capabilityChain"].([]interface{})
+		if !ok || len(chain) != 1 || chain[0] != cshZCAPID {
+			return fmt.Errorf("invalid capability chain: %v", proof["capabilityChain"])
+		}
+	}
+
+	return nil
+}
+
+func verifyInvoker(zcap *zcapld.Capability, requestingParty string) error {
+	if zcap.Invoker == "" {
+		return errors.New("zcap has no invoker")
+	}
+
+	if requestingParty != "" && zcap.Invoker != requestingParty {
+		return fmt.Errorf("zcap was granted to %s", zcap.Invoker)
+	}
+
+	return nil
+}
+
+func verifyAllowedAction(zcap *zcapld.Capability) error {
+	if len(zcap.AllowedAction) != 1 || zcap.AllowedAction[0] != referenceAction {
+		return fmt.Errorf("unexpected allowed actions: %v", zcap.AllowedAction)
+	}
+
+	return nil
+}
+
+func verifyInvocationTarget(zcap *zcapld.Capability, targetType string) error {
+	if zcap.InvocationTarget.Type != targetType {
+		return fmt.Errorf("unexpected invocation target type: %s", zcap.InvocationTarget.Type)
+	}
+
+	if zcap.InvocationTarget.ID == "" {
+		return errors.New("zcap has no invocation target")
+	}
+
+	return nil
+}
+
+// verifyCaveats returns the expiry of the zcap, or nil if it has no expiry caveat.
+func verifyCaveats(zcap *zcapld.Capability, now time.Time) (*time.Time, error) {
+	var expiresAt *time.Time
+
+	for _, caveat := range zcap.Caveats {
+		// toZCaveats uses the comparator's caveat type rather than zcapld.CaveatTypeExpiry.
+		if caveat.Type != (&models.ExpiryCaveat{}).Type() && caveat.Type != zcapld.CaveatTypeExpiry {
+			return nil, fmt.Errorf("unsupported caveat: %s", caveat.Type)
+		}
+
+		if len(zcap.Proof) == 0 {
+			return nil, errors.New("zcap has no proof")
+		}
+
+		created, ok := zcap.Proof[0]["created"].(string)
+		if !ok {
+			return nil, errors.New("proof has no creation time")
+		}
+
+		createdAt, err := time.Parse(time.RFC3339, created)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proof creation time: %w", err)
+		}
+
+		t := createdAt.Add(time.Duration(caveat.Duration) * time.Second).UTC()
+		expiresAt = &t
+
+		if !now.Before(t) {
+			return expiresAt, fmt.Errorf("zcap expired at %s", t.Format(time.RFC3339))
+		}
+	}
+
+	return expiresAt, nil
+}
@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package csh
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/trustbloc/ace/pkg/restapi/csh/operation"
@@ -20,14 +21,21 @@ func New(cfg *operation.Config) (*Controller, error) {
 		return nil, fmt.Errorf("failed to initialize operation: %w", err)
 	}
 
-	return &Controller{handlers: ops.GetRESTHandlers()}, nil
+	return &Controller{ops: ops, handlers: ops.GetRESTHandlers()}, nil
 }
 
 // Controller contains handlers for controller.
 type Controller struct {
+	ops      *operation.Operation
 	handlers []handler.Handler
 }
 
+// SweepDIDCache drops the expired documents from the cache of resolved DID documents periodically until the context
+// is done.
+func (c *Controller) SweepDIDCache(ctx context.Context) {
+	c.ops.SweepDIDCache(ctx)
+}
+
 // GetOperations returns all controller endpoints.
 func (c *Controller) GetOperations() []handler.Handler {
 	return c.handlers
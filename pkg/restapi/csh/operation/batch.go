@@ -0,0 +1,197 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-openapi/runtime"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+
+	openapi "github.com/trustbloc/ace/pkg/restapi/csh/operation/models"
+	"github.com/trustbloc/ace/pkg/restapi/model"
+)
+
+// ErrQueryNotInProfile is returned when a RefQuery of a batch extraction group references a query saved under
+// another profile.
+var ErrQueryNotInProfile = errors.New("query does not belong to the profile")
+
+// ExtractionGroup is a group of queries of a batch extraction, resolved against the stored queries and the
+// upstream auth of its profile.
+type ExtractionGroup struct {
+	ProfileID string          `json:"profileID"`
+	Queries   json.RawMessage `json:"queries"`
+}
+
+// ExtractionGroupResult holds the extractions of a group of a batch extraction, or the error that failed the group.
+type ExtractionGroupResult struct {
+	ProfileID   string                     `json:"profileID"`
+	Extractions openapi.ExtractionResponse `json:"extractions,omitempty"`
+	Error       *openapi.Error             `json:"error,omitempty"`
+}
+
+// BatchExtract swagger:route POST /hubstore/extract/batch batchExtractionReq
+//
+// Extracts the contents of documents for queries grouped by profile. The results requested with recipients are
+// encrypted to all of them.
+//
+// Consumes:
+//   - application/json
+// Produces:
+//   - application/json
+// Responses:
+//   200: batchExtractionResp
+//   400: Error
+//   500: Error
+func (o *Operation) BatchExtract(w http.ResponseWriter, r *http.Request) {
+	logger.Debugf("handling request")
+
+	params, proceed := parseExtractionParams(w, r)
+	if !proceed {
+		return
+	}
+
+	ctx, cancel, err := requestContext(r)
+	if err != nil {
+		respondErrorf(w, http.StatusBadRequest, "bad request: %s", err.Error())
+
+		return
+	}
+
+	defer cancel()
+
+	var groups []*ExtractionGroup
+
+	err = json.NewDecoder(r.Body).Decode(&groups)
+	if err != nil {
+		respondErrorf(w, http.StatusBadRequest, "bad request: %s", err.Error())
+
+		return
+	}
+
+	for i, group := range groups {
+		if group == nil || group.ProfileID == "" {
+			respondErrorf(w, http.StatusBadRequest, "bad request: group %d has no profileID", i)
+
+			return
+		}
+	}
+
+	results := make([]*ExtractionGroupResult, 0, len(groups))
+
+	// a group failing does not fail the others: each is authorized against its own profile
+	for _, group := range groups {
+		results = append(results, o.extractGroup(ctx, group, params))
+	}
+
+	o.respondExtractions(w, params, results)
+}
+
+func (o *Operation) extractGroup(ctx context.Context, group *ExtractionGroup,
+	params *extractionParams) *ExtractionGroupResult {
+	result := &ExtractionGroupResult{ProfileID: group.ProfileID}
+
+	_, err := o.storage.profiles.Get(group.ProfileID)
+	if errors.Is(err, storage.ErrDataNotFound) {
+		result.Error = groupError(model.ErrCodeNotFound, "no such profile: %s", group.ProfileID)
+
+		return result
+	}
+
+	if err != nil {
+		result.Error = groupError(model.ErrCodeInternal, "failed to fetch profile %s: %s", group.ProfileID, err)
+
+		return result
+	}
+
+	queries, err := openapi.UnmarshalQuerySlice(bytes.NewReader(group.Queries), runtime.JSONConsumer())
+	if err != nil {
+		result.Error = groupError(model.ErrCodeMalformedRequest, "bad request: %s", err)
+
+		return result
+	}
+
+	extractions := make(openapi.ExtractionResponse, 0, len(queries))
+
+	for _, query := range queries {
+		extraction, err := o.extractGroupQuery(ctx, group.ProfileID, query, params)
+		if err != nil {
+			result.Error = groupError(groupErrorCode(err), "failed to extract query %s: %s", query.ID(), err)
+
+			return result
+		}
+
+		extractions = append(extractions, extraction)
+	}
+
+	result.Extractions = extractions
+
+	return result
+}
+
+// extractGroupQuery resolves the query against the profile of its group. RefQueries must reference queries saved
+// under the profile, and DocQueries without their own upstream auth get the profile's.
+func (o *Operation) extractGroupQuery(ctx context.Context, profileID string, query openapi.Query,
+	params *extractionParams) (*openapi.ExtractionResponseItems0, error) {
+	spec := query
+
+	if refQuery, ok := query.(*openapi.RefQuery); ok {
+		savedQuery, querySpec, err := o.readRefQuery(refQuery)
+		if err != nil {
+			return nil, err
+		}
+
+		if savedQuery.ProfileID != profileID {
+			return nil, fmt.Errorf("%w: %s", ErrQueryNotInProfile, *refQuery.Ref)
+		}
+
+		spec = querySpec
+	}
+
+	err := o.applyProfileUpstreamAuth(spec, profileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve upstream auth of profile %s: %w", profileID, err)
+	}
+
+	doc, metadata, err := o.extractDocument(ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	docQuery, _ := spec.(*openapi.DocQuery)
+
+	return params.newExtraction(query.ID(), doc, docQuery, metadata), nil
+}
+
+// groupErrorCode maps the failure of a query of a batch extraction group to an error code.
+func groupErrorCode(err error) model.ErrorCode {
+	if errors.Is(err, ErrQueryNotFound) {
+		return model.ErrCodeQueryNotFound
+	}
+
+	if errors.Is(err, ErrQueryNotInProfile) {
+		return model.ErrCodeForbidden
+	}
+
+	return fetchErrorCode(err)
+}
+
+func groupError(code model.ErrorCode, format string, args ...interface{}) *openapi.Error {
+	msg := fmt.Sprintf(format, args...)
+
+	logger.Errorf(msg)
+
+	return &openapi.Error{
+		Code:       string(code),
+		ErrMessage: msg,
+	}
+}
@@ -0,0 +1,244 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/stretchr/testify/require"
+	edv "github.com/trustbloc/edv/pkg/client"
+	"github.com/trustbloc/edv/pkg/restapi/models"
+
+	"github.com/trustbloc/ace/pkg/client/vault"
+	"github.com/trustbloc/ace/pkg/restapi/csh/operation"
+	openapi "github.com/trustbloc/ace/pkg/restapi/csh/operation/models"
+)
+
+func TestOperation_BatchExtract(t *testing.T) {
+	t.Run("extracts the groups against their profiles", func(t *testing.T) {
+		doc1 := randomDoc(t)
+		doc2 := randomDoc(t)
+		agent := newAgent(t)
+
+		edvClient := newMockEDVClient(t, nil, encryptedJWE(t, agent, doc1), encryptedJWE(t, agent, doc2))
+
+		var edvURLs []string
+
+		config := agentConfig(agent)
+		config.EDVClient = func(url string, _ ...edv.Option) vault.ConfidentialStorageDocReader {
+			edvURLs = append(edvURLs, url)
+
+			return edvClient
+		}
+
+		profile1 := saveProfile(t, config, &openapi.ProfileUpstreamAuth{
+			Edv: &openapi.UpstreamAuthorization{BaseURL: "https://edv.example.com/profile1"},
+		})
+		profile2 := saveProfile(t, config, nil)
+
+		o := newOperation(t, config)
+
+		savedQuery := docQuery(nil, nil)
+		savedQuery.UpstreamAuth = nil
+
+		ref := createProfileQuery(t, o, profile1, savedQuery)
+
+		inlineQuery := docQuery(&openapi.UpstreamAuthorization{BaseURL: "https://edv.example.com/inline"}, nil)
+		unknownProfile := uuid.New().URN()
+
+		result := batchExtract(t, o, []interface{}{
+			map[string]interface{}{"profileID": profile1, "queries": []interface{}{refQuery(ref)}},
+			map[string]interface{}{"profileID": unknownProfile, "queries": []interface{}{refQuery(ref)}},
+			map[string]interface{}{"profileID": profile2, "queries": []interface{}{inlineQuery}},
+		})
+		require.Equal(t, http.StatusOK, result.Code)
+
+		var groups []*operation.ExtractionGroupResult
+
+		unmarshal(t, &groups, result.Body.Bytes())
+		require.Len(t, groups, 3)
+		require.Equal(t, []string{"https://edv.example.com/profile1", "https://edv.example.com/inline"}, edvURLs)
+
+		require.Equal(t, profile1, groups[0].ProfileID)
+		require.Nil(t, groups[0].Error)
+		require.Len(t, groups[0].Extractions, 1)
+		require.Empty(t, groups[0].Extractions[0].DocID)
+		require.Equal(t, content(t, doc1), groups[0].Extractions[0].Document)
+
+		require.Equal(t, unknownProfile, groups[1].ProfileID)
+		require.Empty(t, groups[1].Extractions)
+		require.Equal(t, "NOT_FOUND", groups[1].Error.Code)
+		require.Contains(t, groups[1].Error.ErrMessage, "no such profile")
+
+		require.Equal(t, profile2, groups[2].ProfileID)
+		require.Nil(t, groups[2].Error)
+		require.Len(t, groups[2].Extractions, 1)
+		require.Empty(t, groups[2].Extractions[0].DocID)
+		require.Equal(t, content(t, doc2), groups[2].Extractions[0].Document)
+	})
+
+	t.Run("error Forbidden in the group if a RefQuery references a query of another profile", func(t *testing.T) {
+		config := agentConfig(newAgent(t))
+
+		profile1 := saveProfile(t, config, nil)
+		profile2 := saveProfile(t, config, nil)
+
+		o := newOperation(t, config)
+
+		ref := createProfileQuery(t, o, profile1,
+			docQuery(&openapi.UpstreamAuthorization{BaseURL: "https://edv.example.com"}, nil))
+
+		result := batchExtract(t, o, []interface{}{
+			map[string]interface{}{"profileID": profile2, "queries": []interface{}{refQuery(ref)}},
+		})
+		require.Equal(t, http.StatusOK, result.Code)
+
+		var groups []*operation.ExtractionGroupResult
+
+		unmarshal(t, &groups, result.Body.Bytes())
+		require.Len(t, groups, 1)
+		require.Empty(t, groups[0].Extractions)
+		require.Equal(t, "FORBIDDEN", groups[0].Error.Code)
+		require.Contains(t, groups[0].Error.ErrMessage, operation.ErrQueryNotInProfile.Error())
+	})
+
+	t.Run("error in the group if a query fails", func(t *testing.T) {
+		config := agentConfig(newAgent(t))
+
+		profileID := saveProfile(t, config, nil)
+
+		query := docQuery(nil, nil)
+		query.UpstreamAuth = nil
+
+		result := batchExtract(t, newOperation(t, config), []interface{}{
+			map[string]interface{}{"profileID": profileID, "queries": []interface{}{query}},
+			map[string]interface{}{"profileID": profileID, "queries": []interface{}{refQuery(uuid.New().String())}},
+		})
+		require.Equal(t, http.StatusOK, result.Code)
+
+		var groups []*operation.ExtractionGroupResult
+
+		unmarshal(t, &groups, result.Body.Bytes())
+		require.Len(t, groups, 2)
+		require.Equal(t, "INVALID_REQUEST", groups[0].Error.Code)
+		require.Equal(t, "QUERY_NOT_FOUND", groups[1].Error.Code)
+	})
+
+	t.Run("encrypts the results to the recipients", func(t *testing.T) {
+		agent := newAgent(t)
+
+		config := agentConfig(agent)
+		config.EDVClient = func(string, ...edv.Option) vault.ConfidentialStorageDocReader {
+			return newMockEDVClient(t, nil, encryptedJWE(t, agent, randomDoc(t)))
+		}
+
+		profileID := saveProfile(t, config, nil)
+		recipient, recipientDID := newRecipient(t, kms.X25519ECDHKWType)
+
+		result := httptest.NewRecorder()
+
+		newOperation(t, config).BatchExtract(result, httptest.NewRequest(http.MethodPost,
+			"/test?recipient="+url.QueryEscape(recipientDID),
+			bytes.NewReader(marshal(t, []interface{}{
+				map[string]interface{}{
+					"profileID": profileID,
+					"queries": []interface{}{
+						docQuery(&openapi.UpstreamAuthorization{BaseURL: "https://edv.example.com"}, nil),
+					},
+				},
+			}))))
+		require.Equal(t, http.StatusOK, result.Code, result.Body.String())
+
+		var encrypted operation.EncryptedExtraction
+
+		unmarshal(t, &encrypted, result.Body.Bytes())
+
+		jwe, err := jose.Deserialize(encrypted.JWE)
+		require.NoError(t, err)
+
+		plaintext, err := jose.NewJWEDecrypt(nil, recipient.Crypto(), recipient.KMS()).Decrypt(jwe)
+		require.NoError(t, err)
+
+		var groups []*operation.ExtractionGroupResult
+
+		unmarshal(t, &groups, plaintext)
+		require.Len(t, groups, 1)
+		require.Nil(t, groups[0].Error)
+		require.Len(t, groups[0].Extractions, 1)
+	})
+
+	t.Run("error BadRequest if a group has no profile", func(t *testing.T) {
+		result := batchExtract(t, newOperation(t, agentConfig(newAgent(t))), []interface{}{
+			map[string]interface{}{"queries": []interface{}{refQuery(uuid.New().String())}},
+		})
+		require.Equal(t, http.StatusBadRequest, result.Code)
+		require.Contains(t, result.Body.String(), "group 0 has no profileID")
+	})
+
+	t.Run("error BadRequest on malformed request", func(t *testing.T) {
+		result := httptest.NewRecorder()
+
+		newOperation(t, agentConfig(newAgent(t))).BatchExtract(result,
+			httptest.NewRequest(http.MethodPost, "/test", bytes.NewReader([]byte("{"))))
+		require.Equal(t, http.StatusBadRequest, result.Code)
+	})
+
+	t.Run("error BadRequest on invalid redact path", func(t *testing.T) {
+		result := httptest.NewRecorder()
+
+		newOperation(t, agentConfig(newAgent(t))).BatchExtract(result,
+			httptest.NewRequest(http.MethodPost, "/test?redact=$..", bytes.NewReader([]byte("[]"))))
+		require.Equal(t, http.StatusBadRequest, result.Code)
+		require.Contains(t, result.Body.String(), `"code":"INVALID_JSON_PATH"`)
+	})
+}
+
+// createProfileQuery saves the query under the profile and returns its ID.
+func createProfileQuery(t *testing.T, o *operation.Operation, profileID string, query openapi.Query) string {
+	t.Helper()
+
+	result := httptest.NewRecorder()
+
+	o.CreateQuery(result, mux.SetURLVars(
+		httptest.NewRequest(http.MethodPost, "/test", bytes.NewReader(marshal(t, query))),
+		map[string]string{"profileID": profileID},
+	))
+	require.Equal(t, http.StatusCreated, result.Code)
+
+	location := result.Header().Get("Location")
+	require.NotEmpty(t, location)
+
+	return location[strings.LastIndex(location, "/")+1:]
+}
+
+func batchExtract(t *testing.T, o *operation.Operation, groups []interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+
+	result := httptest.NewRecorder()
+
+	o.BatchExtract(result, httptest.NewRequest(http.MethodPost, "/test", bytes.NewReader(marshal(t, groups))))
+
+	return result
+}
+
+func content(t *testing.T, doc []byte) interface{} {
+	t.Helper()
+
+	structured := &models.StructuredDocument{}
+	unmarshal(t, structured, doc)
+
+	return structured.Content
+}
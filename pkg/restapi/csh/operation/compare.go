@@ -13,19 +13,20 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"reflect"
+	"net/url"
+	"strings"
 
-	"github.com/PaesslerAG/gval"
-	"github.com/PaesslerAG/jsonpath"
 	"github.com/go-openapi/runtime"
 	"github.com/hyperledger/aries-framework-go/spi/storage"
 	"github.com/trustbloc/edv/pkg/restapi/models"
 
 	openapi "github.com/trustbloc/ace/pkg/restapi/csh/operation/models"
+	"github.com/trustbloc/ace/pkg/restapi/csh/operation/zcapld"
+	"github.com/trustbloc/ace/pkg/restapi/model"
 )
 
-// HandleEqOp handles a ComparisonRequest using the EqOp operator.
-func (o *Operation) HandleEqOp(w http.ResponseWriter, op *openapi.EqOp) {
+// HandleEqOp handles a ComparisonRequest using the EqOp operator. The documents are fetched with the context.
+func (o *Operation) HandleEqOp(ctx context.Context, w http.ResponseWriter, op *openapi.EqOp) {
 	const minArgs = 2
 
 	if len(op.Args()) < minArgs {
@@ -47,9 +48,9 @@ func (o *Operation) HandleEqOp(w http.ResponseWriter, op *openapi.EqOp) {
 		case *openapi.DocQuery:
 			var err error
 
-			document, err = o.fetchDocument(q)
+			document, err = o.fetchDocument(ctx, q)
 			if err != nil {
-				respondErrorf(w, http.StatusInternalServerError,
+				respondFetchErrorf(w, err,
 					"failed to fetch Confidential Storage document for docquery: %s", err.Error())
 
 				return
@@ -57,10 +58,14 @@ func (o *Operation) HandleEqOp(w http.ResponseWriter, op *openapi.EqOp) {
 		case *openapi.RefQuery:
 			var proceed bool
 
-			document, proceed = o.resolveRefQuery(w, q)
+			document, proceed = o.resolveRefQuery(ctx, w, q)
 			if !proceed {
 				return
 			}
+		default:
+			respondErrorf(w, http.StatusNotImplemented, "unsupported query type: %s", query.Type())
+
+			return
 		}
 
 		if i == 0 {
@@ -69,7 +74,7 @@ func (o *Operation) HandleEqOp(w http.ResponseWriter, op *openapi.EqOp) {
 			continue
 		}
 
-		comparison.Result = reflect.DeepEqual(prevDoc, document)
+		comparison.Result = jsonEqual(prevDoc, document)
 		if !comparison.Result {
 			break
 		}
@@ -84,81 +89,204 @@ func (o *Operation) HandleEqOp(w http.ResponseWriter, op *openapi.EqOp) {
 	respond(w, http.StatusOK, headers, comparison)
 }
 
-func (o *Operation) fetchDocument(query openapi.Query) (interface{}, error) {
+func (o *Operation) fetchDocument(ctx context.Context, query openapi.Query) (interface{}, error) {
+	result, _, err := o.fetchDocumentWithMetadata(ctx, query)
+
+	return result, err
+}
+
+// fetchDocumentWithMetadata also returns the non-secret metadata of the Confidential Storage document.
+func (o *Operation) fetchDocumentWithMetadata(ctx context.Context,
+	query openapi.Query) (interface{}, *openapi.ExtractionMetadata, error) {
+	return o.readDocument(ctx, query, nil)
+}
+
+// extractDocument fetches the document of the query as fetchDocumentWithMetadata does, for an extraction: the
+// values of the deny-list are never returned.
+func (o *Operation) extractDocument(ctx context.Context,
+	query openapi.Query) (interface{}, *openapi.ExtractionMetadata, error) {
+	return o.readDocument(ctx, query, o.denyList)
+}
+
+// readDocument evaluates the path of the query in its document without the values denied by the deny-list.
+func (o *Operation) readDocument(ctx context.Context, query openapi.Query,
+	denyList *DenyList) (interface{}, *openapi.ExtractionMetadata, error) {
+	if ctx.Err() != nil {
+		return nil, nil, deadlineError(ctx, ctx.Err())
+	}
+
 	docQuery, ok := query.(*openapi.DocQuery)
 	if !ok {
-		return nil, fmt.Errorf("cannot fetch structured documents for query type: %s", query.Type())
+		return nil, nil, fmt.Errorf("cannot fetch structured documents for query type: %s", query.Type())
 	}
 
-	contents, err := o.ReadDocQuery(docQuery)
+	var path *JSONPath
+
+	if docQuery.Path != "" {
+		var err error
+
+		path, err = CompileJSONPath(docQuery.Path)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	contents, encDoc, err := o.readDocQuery(ctx, docQuery)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read Confidential Storage document: %w", err)
+		return nil, nil, deadlineError(ctx, fmt.Errorf("failed to read Confidential Storage document: %w", err))
 	}
 
 	document := &models.StructuredDocument{}
 
-	err = json.Unmarshal(contents, document)
+	err = decodeJSON(contents, document, o.exactNumbers)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse Confidential Storage structured document: %w", err)
+		return nil, nil, fmt.Errorf("failed to parse Confidential Storage structured document: %w", err)
 	}
 
-	var result interface{} = document.Content
+	result, err := denyList.evaluate(document.Content, path)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	if docQuery.Path != "" {
-		builder := gval.Full(jsonpath.PlaceholderExtension())
+	return result, newExtractionMetadata(docQuery, encDoc), nil
+}
 
-		path, err := builder.NewEvaluable(docQuery.Path)
-		if err != nil {
-			return nil, fmt.Errorf("failed to build new json path evaluator: %w", err)
-		}
+// newExtractionMetadata never includes the encrypted contents of the document.
+func newExtractionMetadata(query *openapi.DocQuery, doc *models.EncryptedDocument) *openapi.ExtractionMetadata {
+	metadata := &openapi.ExtractionMetadata{
+		EdvDocURI: fmt.Sprintf("%s/%s/documents/%s",
+			strings.TrimSuffix(query.UpstreamAuth.Edv.BaseURL, "/"),
+			url.PathEscape(*query.VaultID), url.PathEscape(*query.DocID),
+		),
+	}
 
-		result, err = path(context.TODO(), result)
-		if err != nil {
-			return nil, fmt.Errorf("failed to evaluate json path [%s]: %w", docQuery.Path, err)
-		}
+	if doc != nil {
+		metadata.Sequence = int64(doc.Sequence)
 	}
 
-	return result, nil
+	return metadata
 }
 
-func (o *Operation) resolveRefQuery(w http.ResponseWriter, query *openapi.RefQuery) (interface{}, bool) {
-	raw, err := o.storage.queries.Get(*query.Ref)
-	if errors.Is(err, storage.ErrDataNotFound) {
-		respondErrorf(w, http.StatusBadRequest, "no such query: %s", *query.Ref)
+// respondFetchErrorf responds with the status and code of the failure to fetch a document.
+func respondFetchErrorf(w http.ResponseWriter, err error, format string, args ...interface{}) {
+	respondErrorCodef(w, fetchErrorStatus(err), fetchErrorCode(err), format, args...)
+}
+
+// fetchErrorCode maps a failure to fetch a document to an error code, more specific than the one of its status
+// code where possible.
+func fetchErrorCode(err error) model.ErrorCode {
+	if errors.Is(err, ErrInvalidJSONPath) || errors.Is(err, ErrJSONPathNotFound) {
+		return model.ErrCodeInvalidJSONPath
+	}
+
+	if errors.Is(err, ErrMissingUpstreamAuth) {
+		return model.ErrCodeInvalidRequest
+	}
+
+	if errors.Is(err, ErrDeadlineExceeded) {
+		return model.ErrCodeDeadlineExceeded
+	}
+
+	return model.StatusErrorCode(fetchErrorStatus(err))
+}
+
+// fetchErrorStatus maps a failure to fetch a document to a status code. Invoking an expired zcap, one whose
+// invoker uses a DID method that is not allowed, one delegated more times than allowed, or one that was revoked, is
+// forbidden, as is extracting denied values.
+// Paths that are malformed or select nothing in the document, and queries without upstream auth, are bad requests.
+// Running out of the deadline of the request is a gateway timeout.
+func fetchErrorStatus(err error) int {
+	if errors.Is(err, ErrDeadlineExceeded) {
+		return http.StatusGatewayTimeout
+	}
 
+	if errors.Is(err, zcapld.ErrExpired) || errors.Is(err, zcapld.ErrDIDMethodNotAllowed) ||
+		errors.Is(err, zcapld.ErrChainTooDeep) || errors.Is(err, zcapld.ErrRevoked) ||
+		errors.Is(err, ErrPathDenied) {
+		return http.StatusForbidden
+	}
+
+	if errors.Is(err, ErrDocumentTooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+
+	if errors.Is(err, ErrInvalidJSONPath) || errors.Is(err, ErrJSONPathNotFound) ||
+		errors.Is(err, ErrMissingUpstreamAuth) {
+		return http.StatusBadRequest
+	}
+
+	if upstreamUnavailable(err) {
+		return http.StatusServiceUnavailable
+	}
+
+	return http.StatusInternalServerError
+}
+
+func (o *Operation) resolveRefQuery(ctx context.Context, w http.ResponseWriter,
+	query *openapi.RefQuery) (interface{}, bool) {
+	querySpec, proceed := o.loadRefQuery(w, query)
+	if !proceed {
 		return nil, false
 	}
 
+	document, err := o.fetchDocument(ctx, querySpec)
 	if err != nil {
-		respondErrorf(w, http.StatusInternalServerError,
-			"failed to fetch query object for ref %s: %s", *query.Ref, err.Error())
+		respondFetchErrorf(w, err,
+			"failed to fetch Confidential Storage document for refquery: %s", err.Error())
 
 		return nil, false
 	}
 
-	savedQuery := &Query{}
+	return document, true
+}
 
-	err = json.NewDecoder(bytes.NewReader(raw)).Decode(savedQuery)
-	if err != nil {
-		respondErrorf(w, http.StatusInternalServerError, "failed to parse doc query: %s", err)
+func (o *Operation) loadRefQuery(w http.ResponseWriter, query *openapi.RefQuery) (openapi.Query, bool) {
+	savedQuery, querySpec, err := o.readRefQuery(query)
+	if errors.Is(err, ErrQueryNotFound) {
+		respondErrorCodef(w, http.StatusBadRequest, model.ErrCodeQueryNotFound, "%s", err.Error())
 
 		return nil, false
 	}
 
-	querySpec, err := openapi.UnmarshalQuery(bytes.NewReader(savedQuery.Spec), runtime.JSONConsumer())
 	if err != nil {
-		respondErrorf(w, http.StatusInternalServerError, "failed to parse query spec: %s", err.Error())
+		respondErrorf(w, http.StatusInternalServerError, "%s", err.Error())
 
 		return nil, false
 	}
 
-	document, err := o.fetchDocument(querySpec)
+	err = o.applyProfileUpstreamAuth(querySpec, savedQuery.ProfileID)
 	if err != nil {
 		respondErrorf(w, http.StatusInternalServerError,
-			"failed to fetch Confidential Storage document for refquery: %s", err.Error())
+			"failed to resolve upstream auth for ref %s: %s", *query.Ref, err.Error())
 
 		return nil, false
 	}
 
-	return document, true
+	return querySpec, true
+}
+
+// readRefQuery returns the saved query the RefQuery references along with its parsed spec.
+func (o *Operation) readRefQuery(query *openapi.RefQuery) (*Query, openapi.Query, error) {
+	raw, err := o.storage.queries.Get(*query.Ref)
+	if errors.Is(err, storage.ErrDataNotFound) {
+		return nil, nil, fmt.Errorf("%w: %s", ErrQueryNotFound, *query.Ref)
+	}
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch query object for ref %s: %w", *query.Ref, err)
+	}
+
+	savedQuery := &Query{}
+
+	err = json.NewDecoder(bytes.NewReader(raw)).Decode(savedQuery)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse doc query: %w", err)
+	}
+
+	querySpec, err := openapi.UnmarshalQuery(bytes.NewReader(savedQuery.Spec), runtime.JSONConsumer())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse query spec: %w", err)
+	}
+
+	return savedQuery, querySpec, nil
 }
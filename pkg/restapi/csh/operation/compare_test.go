@@ -8,6 +8,7 @@ package operation_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
@@ -54,7 +55,7 @@ func TestOperation_HandleEqOp(t *testing.T) {
 			}, nil),
 		)
 
-		o.HandleEqOp(result, op)
+		o.HandleEqOp(context.Background(), result, op)
 		require.Equal(t, http.StatusOK, result.Code)
 		requireCompareResult(t, true, result.Body)
 	})
@@ -103,7 +104,7 @@ func TestOperation_HandleEqOp(t *testing.T) {
 
 		result = httptest.NewRecorder()
 
-		o.HandleEqOp(result, op)
+		o.HandleEqOp(context.Background(), result, op)
 		require.Equal(t, http.StatusOK, result.Code)
 		requireCompareResult(t, true, result.Body)
 	})
@@ -133,16 +134,74 @@ func TestOperation_HandleEqOp(t *testing.T) {
 			}, nil),
 		)
 
-		o.HandleEqOp(result, op)
+		o.HandleEqOp(context.Background(), result, op)
 		require.Equal(t, http.StatusOK, result.Code)
 		requireCompareResult(t, false, result.Body)
 	})
 
+	t.Run("large integers", func(t *testing.T) {
+		// 9007199254740993 is not representable as a float64, which rounds it to 9007199254740992
+		for name, tc := range map[string]struct {
+			exactNumbers bool
+			doc1, doc2   string
+			expected     bool
+		}{
+			"falsely equal as floats": {
+				doc1:     `{"id":"doc","content":{"serial":9007199254740993}}`,
+				doc2:     `{"id":"doc","content":{"serial":9007199254740992}}`,
+				expected: true,
+			},
+			"unequal with exact numbers": {
+				exactNumbers: true,
+				doc1:         `{"id":"doc","content":{"serial":9007199254740993}}`,
+				doc2:         `{"id":"doc","content":{"serial":9007199254740992}}`,
+				expected:     false,
+			},
+			"equal with exact numbers": {
+				exactNumbers: true,
+				doc1:         `{"id":"doc","content":{"serial":9007199254740993,"ratio":[1.5,2]}}`,
+				doc2:         `{"id":"doc","content":{"ratio":[1.50,2.0],"serial":9007199254740993}}`,
+				expected:     true,
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				agent := newAgent(t)
+
+				edvClient := newMockEDVClient(t, nil,
+					encryptedJWE(t, agent, []byte(tc.doc1)),
+					encryptedJWE(t, agent, []byte(tc.doc2)),
+				)
+
+				config := agentConfig(agent)
+				config.ExactNumbers = tc.exactNumbers
+				config.EDVClient = func(string, ...edv.Option) vault.ConfidentialStorageDocReader {
+					return edvClient
+				}
+
+				o := newOperation(t, config)
+				result := httptest.NewRecorder()
+
+				op := newEqOp(t,
+					docQuery(&openapi.UpstreamAuthorization{
+						BaseURL: "https://edv.example.com",
+					}, nil),
+					docQuery(&openapi.UpstreamAuthorization{
+						BaseURL: "https://edv.example.com",
+					}, nil),
+				)
+
+				o.HandleEqOp(context.Background(), result, op)
+				require.Equal(t, http.StatusOK, result.Code)
+				requireCompareResult(t, tc.expected, result.Body)
+			})
+		}
+	})
+
 	t.Run("error BadRequest if there are less than 2 args", func(t *testing.T) {
 		o := newOperation(t, agentConfig(newAgent(t)))
 		result := httptest.NewRecorder()
 
-		o.HandleEqOp(result, newEqOp(t))
+		o.HandleEqOp(context.Background(), result, newEqOp(t))
 		require.Equal(t, http.StatusBadRequest, result.Code)
 		require.Contains(t, result.Body.String(), "requires at least two arguments")
 	})
@@ -157,7 +216,7 @@ func TestOperation_HandleEqOp(t *testing.T) {
 		result := httptest.NewRecorder()
 		op := newEqOp(t, newDocQuery(t), newDocQuery(t))
 
-		o.HandleEqOp(result, op)
+		o.HandleEqOp(context.Background(), result, op)
 		require.Equal(t, http.StatusInternalServerError, result.Code)
 		require.Contains(t, result.Body.String(), "failed to read Confidential Storage document")
 	})
@@ -187,7 +246,7 @@ func TestOperation_HandleEqOp(t *testing.T) {
 			}, nil),
 		)
 
-		o.HandleEqOp(result, op)
+		o.HandleEqOp(context.Background(), result, op)
 		require.Equal(t, http.StatusInternalServerError, result.Code)
 		require.Contains(t, result.Body.String(), "failed to parse Confidential Storage structured document")
 	})
@@ -196,13 +255,14 @@ func TestOperation_HandleEqOp(t *testing.T) {
 		o := newOperation(t, config(t))
 		result := httptest.NewRecorder()
 
-		o.HandleEqOp(result, newEqOp(t,
+		o.HandleEqOp(context.Background(), result, newEqOp(t,
 			refQuery("INVALID"),
 			refQuery("INVALID"),
 		))
 
 		require.Equal(t, http.StatusBadRequest, result.Code)
 		require.Contains(t, result.Body.String(), "no such query")
+		require.Contains(t, result.Body.String(), `"code":"QUERY_NOT_FOUND"`)
 	})
 
 	t.Run("error InternalServerError if cannot fetch query object from store", func(t *testing.T) {
@@ -220,7 +280,7 @@ func TestOperation_HandleEqOp(t *testing.T) {
 		o := newOperation(t, config)
 		result := httptest.NewRecorder()
 
-		o.HandleEqOp(result, newEqOp(t,
+		o.HandleEqOp(context.Background(), result, newEqOp(t,
 			refQuery("test"),
 			refQuery("test"),
 		))
@@ -259,7 +319,7 @@ func TestOperation_HandleEqOp(t *testing.T) {
 		o := newOperation(t, config)
 		result := httptest.NewRecorder()
 
-		o.HandleEqOp(result, newEqOp(t,
+		o.HandleEqOp(context.Background(), result, newEqOp(t,
 			refQuery(queryID),
 			refQuery(queryID),
 		))
@@ -299,7 +359,7 @@ func TestOperation_HandleEqOp(t *testing.T) {
 		o := newOperation(t, config)
 		result := httptest.NewRecorder()
 
-		o.HandleEqOp(result, newEqOp(t,
+		o.HandleEqOp(context.Background(), result, newEqOp(t,
 			refQuery(queryID),
 			refQuery(queryID),
 		))
@@ -336,9 +396,9 @@ func TestOperation_HandleEqOp(t *testing.T) {
 			}, nil),
 		)
 
-		o.HandleEqOp(result, op)
-		require.Equal(t, http.StatusInternalServerError, result.Code)
-		require.Contains(t, result.Body.String(), "failed to build new json path evaluator")
+		o.HandleEqOp(context.Background(), result, op)
+		require.Equal(t, http.StatusBadRequest, result.Code)
+		require.Contains(t, result.Body.String(), "invalid json path [}]")
 	})
 
 	t.Run("error on invalid jsonpath", func(t *testing.T) {
@@ -369,9 +429,10 @@ func TestOperation_HandleEqOp(t *testing.T) {
 			}, nil),
 		)
 
-		o.HandleEqOp(result, op)
-		require.Equal(t, http.StatusInternalServerError, result.Code)
-		require.Contains(t, result.Body.String(), "failed to evaluate json path")
+		o.HandleEqOp(context.Background(), result, op)
+		require.Equal(t, http.StatusBadRequest, result.Code)
+		require.Contains(t, result.Body.String(), "json path not found [$.invalid.path]")
+		require.Contains(t, result.Body.String(), `"code":"INVALID_JSON_PATH"`)
 	})
 }
 
@@ -404,3 +465,16 @@ func newEqOp(t *testing.T, queries ...interface{}) *openapi.EqOp {
 
 	return op
 }
+
+// revocationList holds the IDs of the revoked zcaps.
+type revocationList map[string]bool
+
+func (l revocationList) IsRevoked(_ context.Context, zcapID string) (bool, error) {
+	return l[zcapID], nil
+}
+
+type failingRevocationList struct{}
+
+func (failingRevocationList) IsRevoked(context.Context, string) (bool, error) {
+	return false, errors.New("test")
+}
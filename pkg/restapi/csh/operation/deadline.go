@@ -0,0 +1,82 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ErrDeadlineExceeded is returned when the deadline of a Compare or Extract request expires before the documents
+// are fetched.
+var ErrDeadlineExceeded = errors.New("request deadline exceeded")
+
+// requestContext returns the context of the request, bounded by its `deadline` query parameter, in seconds, if any.
+// It governs all the upstream requests made to serve the request, and caches the zcaps they are invoked with: the
+// queries of a request often reuse the same upstream authorization.
+func requestContext(r *http.Request) (context.Context, context.CancelFunc, error) {
+	parent := withZCAPCache(r.Context())
+
+	v := r.URL.Query().Get("deadline")
+	if v == "" {
+		ctx, cancel := context.WithCancel(parent)
+
+		return ctx, cancel, nil
+	}
+
+	seconds, err := strconv.ParseFloat(v, 64)
+	if err != nil || seconds <= 0 || math.IsInf(seconds, 0) {
+		return nil, nil, fmt.Errorf("invalid deadline: %s: must be a positive number of seconds", v)
+	}
+
+	ctx, cancel := context.WithTimeout(parent, time.Duration(seconds*float64(time.Second)))
+
+	return ctx, cancel, nil
+}
+
+// deadlineError reports the failure to fetch a document as ErrDeadlineExceeded if the deadline of the request
+// expired meanwhile: the EDV and KMS clients do not wrap the errors of their requests.
+func deadlineError(ctx context.Context, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %v", ErrDeadlineExceeded, err) // nolint:errorlint
+	}
+
+	return err
+}
+
+// upstreamClient returns an HTTP client sending the requests to upstream EDV and KMS servers with the context,
+// since their clients do not take one.
+func (o *Operation) upstreamClient(ctx context.Context) *http.Client {
+	client := http.Client{}
+	if o.httpClient != nil {
+		client = *o.httpClient
+	}
+
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	client.Transport = &contextTransport{base: base, ctx: ctx}
+
+	return &client
+}
+
+// contextTransport sends requests with its context, which cancels them once done.
+type contextTransport struct {
+	base http.RoundTripper
+	ctx  context.Context // nolint:containedctx
+}
+
+func (t *contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.base.RoundTrip(req.WithContext(t.ctx))
+}
@@ -0,0 +1,126 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DenyMode is how extractions of the values of a DenyList are handled.
+type DenyMode string
+
+const (
+	// DenyModeStrip removes the denied values from the documents before the paths of the queries are evaluated.
+	DenyModeStrip DenyMode = "strip"
+	// DenyModeRefuse refuses the extractions that would return denied values.
+	DenyModeRefuse DenyMode = "refuse"
+)
+
+// ErrPathDenied is returned when an extraction would return values of the deny-list.
+var ErrPathDenied = errors.New("extraction of a denied path")
+
+// DenyList is a server-level list of JSONPaths whose values are never extracted, whatever the authorization of
+// the queries. It is a defense-in-depth control, independent of the caveats of the zcaps. Comparisons are not
+// affected since they only return whether the documents are equal.
+//
+// A nil DenyList denies nothing.
+type DenyList struct {
+	paths []*RedactionPath
+	mode  DenyMode
+}
+
+// NewDenyList compiles the JSONPaths of the deny-list, which select values as redactions do, eg. `$.ssn` or
+// `$.cards[*].number`. The mode defaults to DenyModeStrip.
+func NewDenyList(exprs []string, mode DenyMode) (*DenyList, error) {
+	switch mode {
+	case "":
+		mode = DenyModeStrip
+	case DenyModeStrip, DenyModeRefuse:
+	default:
+		return nil, fmt.Errorf("unsupported deny mode: %s", mode)
+	}
+
+	list := &DenyList{mode: mode}
+
+	for _, expr := range exprs {
+		path, err := CompileRedactionPath(expr)
+		if err != nil {
+			return nil, err
+		}
+
+		list.paths = append(list.paths, path)
+	}
+
+	return list, nil
+}
+
+// evaluate returns the value the path selects in the document, or the document if the path is nil, without the
+// denied values. In refuse mode, it fails with ErrPathDenied if the value would differ had the denied values been
+// stripped, which also refuses the paths whose filters read denied values. The document is modified in place.
+func (l *DenyList) evaluate(document interface{}, path *JSONPath) (interface{}, error) {
+	if l == nil || len(l.paths) == 0 {
+		return evaluate(document, path)
+	}
+
+	if l.mode == DenyModeStrip {
+		return evaluate(l.strip(document), path)
+	}
+
+	result, err := evaluate(document, path)
+	if err != nil {
+		return nil, err
+	}
+
+	stripped, err := evaluate(l.strip(deepCopy(document)), path)
+	if err != nil || !jsonEqual(result, stripped) {
+		return nil, fmt.Errorf("%w: the extraction would return values the hub never returns", ErrPathDenied)
+	}
+
+	return result, nil
+}
+
+func (l *DenyList) strip(document interface{}) interface{} {
+	for _, path := range l.paths {
+		document = path.Redact(document)
+	}
+
+	return document
+}
+
+func evaluate(document interface{}, path *JSONPath) (interface{}, error) {
+	if path == nil {
+		return document, nil
+	}
+
+	return path.Evaluate(document)
+}
+
+// deepCopy copies the maps and slices of the document, which must be made of the types produced by json.Unmarshal
+// into an interface{}.
+func deepCopy(node interface{}) interface{} {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(n))
+
+		for k, v := range n {
+			c[k] = deepCopy(v)
+		}
+
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(n))
+
+		for i, v := range n {
+			c[i] = deepCopy(v)
+		}
+
+		return c
+	default:
+		return node
+	}
+}
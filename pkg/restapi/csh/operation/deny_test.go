@@ -0,0 +1,36 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/ace/pkg/restapi/csh/operation"
+)
+
+func TestNewDenyList(t *testing.T) {
+	t.Run("compiles the paths", func(t *testing.T) {
+		for _, mode := range []operation.DenyMode{"", operation.DenyModeStrip, operation.DenyModeRefuse} {
+			list, err := operation.NewDenyList([]string{"$.ssn", "$.cards[*].number"}, mode)
+			require.NoError(t, err)
+			require.NotNil(t, list)
+		}
+	})
+
+	t.Run("error if a path is invalid", func(t *testing.T) {
+		_, err := operation.NewDenyList([]string{"$.ssn", "$..ssn"}, operation.DenyModeStrip)
+		require.True(t, errors.Is(err, operation.ErrInvalidJSONPath))
+	})
+
+	t.Run("error if the mode is unsupported", func(t *testing.T) {
+		_, err := operation.NewDenyList([]string{"$.ssn"}, "hide")
+		require.EqualError(t, err, "unsupported deny mode: hide")
+	})
+}
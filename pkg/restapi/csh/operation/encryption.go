@@ -0,0 +1,95 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hyperledger/aries-framework-go/pkg/crypto"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/util/kmsdidkey"
+)
+
+const (
+	// encryptedExtractionType is the typ header of the JWEs of encrypted extractions.
+	encryptedExtractionType = "JWE"
+	// encryptedExtractionContentType is the cty header of the JWEs of encrypted extractions.
+	encryptedExtractionContentType = "application/json"
+)
+
+// keyAgreementCurves are the curves of the recipient keys extractions can be encrypted to.
+var keyAgreementCurves = map[string]bool{ //nolint:gochecknoglobals
+	"X25519":    true,
+	"NIST_P256": true,
+	"NIST_P384": true,
+	"NIST_P521": true,
+}
+
+// EncryptedExtraction is the response of the extractions requested with recipients.
+type EncryptedExtraction struct {
+	// JWE is the JSON serialization of a JWE encrypted to all the recipients, which can each decrypt it with their
+	// own key. Its plaintext is the response of the request without recipients: the extractions, or their
+	// SignedExtraction if the request had a nonce.
+	JWE string `json:"jwe"`
+}
+
+// parseRecipients returns the public keys of the recipients, did:key DIDs of X25519 or NIST P-curve keys.
+func parseRecipients(recipients []string) ([]*crypto.PublicKey, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("the recipient list is empty")
+	}
+
+	keys := make([]*crypto.PublicKey, 0, len(recipients))
+
+	for _, recipient := range recipients {
+		if recipient == "" {
+			return nil, errors.New("empty recipient")
+		}
+
+		key, err := kmsdidkey.EncryptionPubKeyFromDIDKey(recipient)
+		if err != nil {
+			return nil, fmt.Errorf("recipient %s: %w", recipient, err)
+		}
+
+		if !keyAgreementCurves[key.Curve] {
+			return nil, fmt.Errorf("recipient %s: %s keys cannot be encrypted to", recipient, key.Curve)
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// encryptExtractions encrypts the response of an extraction request to all the recipients at once.
+func (o *Operation) encryptExtractions(recipients []*crypto.PublicKey,
+	response interface{}) (*EncryptedExtraction, error) {
+	plaintext, err := json.Marshal(response)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal extractions: %w", err)
+	}
+
+	encrypter, err := jose.NewJWEEncrypt(jose.A256GCM, encryptedExtractionType, encryptedExtractionContentType, "",
+		nil, recipients, o.aries.Crypto)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init encrypter: %w", err)
+	}
+
+	jwe, err := encrypter.Encrypt(plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt extractions: %w", err)
+	}
+
+	serialized, err := jwe.FullSerialize(json.Marshal)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize encrypted extractions: %w", err)
+	}
+
+	return &EncryptedExtraction{JWE: serialized}, nil
+}
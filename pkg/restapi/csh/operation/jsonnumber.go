@@ -0,0 +1,122 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/PaesslerAG/gval"
+)
+
+// decodeJSON decodes the JSON value into v. With exact numbers, the numbers of untyped values are decoded as
+// json.Number rather than float64, which cannot represent integers beyond 2^53.
+func decodeJSON(data []byte, v interface{}, exactNumbers bool) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	if exactNumbers {
+		dec.UseNumber()
+	}
+
+	return dec.Decode(v)
+}
+
+// jsonEqual reports whether the decoded JSON values are equal. Numbers are compared by value: json.Number values,
+// eg. 1 and 1.0, are equal if they denote the same number.
+func jsonEqual(a, b interface{}) bool {
+	switch x := a.(type) {
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+
+		for k, v := range x {
+			w, ok := y[k]
+			if !ok || !jsonEqual(v, w) {
+				return false
+			}
+		}
+
+		return true
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+
+		for i := range x {
+			if !jsonEqual(x[i], y[i]) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	if x, ok := toRat(a); ok {
+		if y, ok := toRat(b); ok {
+			return x.Cmp(y) == 0
+		}
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+// toRat returns the exact value of the number.
+func toRat(v interface{}) (*big.Rat, bool) {
+	switch n := v.(type) {
+	case json.Number:
+		return new(big.Rat).SetString(string(n))
+	case float64:
+		r := new(big.Rat).SetFloat64(n)
+
+		return r, r != nil
+	default:
+		return nil, false
+	}
+}
+
+// jsonNumberComparisons makes the comparison operators of JSONPath filters compare json.Number values by value,
+// which gval would compare as strings. Other values are compared as gval does. The number literals of the filters
+// are float64 values, so they are only exact up to 2^53.
+func jsonNumberComparisons() gval.Language {
+	equal := func(a, b interface{}) (interface{}, error) {
+		return jsonEqual(a, b), nil
+	}
+
+	order := func(name string, test func(cmp int) bool) gval.Language {
+		return gval.InfixOperator(name, func(a, b interface{}) (interface{}, error) {
+			if x, ok := toRat(a); ok {
+				if y, ok := toRat(b); ok {
+					return test(x.Cmp(y)), nil
+				}
+			}
+
+			if a == nil || b == nil {
+				return nil, fmt.Errorf("invalid operation (%T) %s (%T)", a, name, b)
+			}
+
+			return test(strings.Compare(fmt.Sprintf("%v", a), fmt.Sprintf("%v", b))), nil
+		})
+	}
+
+	return gval.NewLanguage(
+		gval.InfixOperator("==", equal),
+		gval.InfixOperator("!=", func(a, b interface{}) (interface{}, error) {
+			return !jsonEqual(a, b), nil
+		}),
+		order(">", func(cmp int) bool { return cmp > 0 }),
+		order(">=", func(cmp int) bool { return cmp >= 0 }),
+		order("<", func(cmp int) bool { return cmp < 0 }),
+		order("<=", func(cmp int) bool { return cmp <= 0 }),
+	)
+}
@@ -0,0 +1,58 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/PaesslerAG/gval"
+	"github.com/PaesslerAG/jsonpath"
+)
+
+var (
+	// ErrInvalidJSONPath is returned when a JSONPath expression cannot be compiled.
+	ErrInvalidJSONPath = errors.New("invalid json path")
+	// ErrJSONPathNotFound is returned when a JSONPath expression selects nothing in a document.
+	ErrJSONPathNotFound = errors.New("json path not found")
+)
+
+// JSONPath is a compiled JSONPath expression, eg. `$.credentialSubject.name`.
+type JSONPath struct {
+	expr string
+	eval gval.Evaluable
+}
+
+// CompileJSONPath compiles the JSONPath expression. The result can be evaluated against any number of documents.
+func CompileJSONPath(expr string) (*JSONPath, error) {
+	// the operators of the first language take precedence over the ones of gval
+	language := gval.NewLanguage(jsonNumberComparisons(), gval.Full(jsonpath.PlaceholderExtension()))
+
+	eval, err := language.NewEvaluable(expr)
+	if err != nil {
+		return nil, fmt.Errorf("%w [%s]: %s", ErrInvalidJSONPath, expr, err)
+	}
+
+	return &JSONPath{expr: expr, eval: eval}, nil
+}
+
+// String returns the JSONPath expression.
+func (p *JSONPath) String() string {
+	return p.expr
+}
+
+// Evaluate returns the value the path selects in the document, which must be made of the types produced by
+// json.Unmarshal into an interface{}, with numbers decoded as float64 or json.Number.
+func (p *JSONPath) Evaluate(document interface{}) (interface{}, error) {
+	result, err := p.eval(context.Background(), document)
+	if err != nil {
+		return nil, fmt.Errorf("%w [%s]: %s", ErrJSONPathNotFound, p.expr, err)
+	}
+
+	return result, nil
+}
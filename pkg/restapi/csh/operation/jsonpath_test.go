@@ -0,0 +1,106 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/ace/pkg/restapi/csh/operation"
+)
+
+func TestJSONPath(t *testing.T) {
+	var doc interface{}
+
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"credentialSubject": {
+			"testMessage": "hello",
+			"scores": [1, 2, 3]
+		}
+	}`), &doc))
+
+	t.Run("evaluates a compiled path against documents", func(t *testing.T) {
+		path, err := operation.CompileJSONPath("$.credentialSubject.testMessage")
+		require.NoError(t, err)
+		require.Equal(t, "$.credentialSubject.testMessage", path.String())
+
+		result, err := path.Evaluate(doc)
+		require.NoError(t, err)
+		require.Equal(t, "hello", result)
+
+		result, err = path.Evaluate(map[string]interface{}{
+			"credentialSubject": map[string]interface{}{"testMessage": "world"},
+		})
+		require.NoError(t, err)
+		require.Equal(t, "world", result)
+	})
+
+	t.Run("selects array elements", func(t *testing.T) {
+		path, err := operation.CompileJSONPath("$.credentialSubject.scores[1]")
+		require.NoError(t, err)
+
+		result, err := path.Evaluate(doc)
+		require.NoError(t, err)
+		require.Equal(t, float64(2), result)
+	})
+
+	t.Run("filters exact numbers by value", func(t *testing.T) {
+		dec := json.NewDecoder(strings.NewReader(`{"items": [
+			{"id": 9007199254740993, "n": 5},
+			{"id": 9007199254740992, "n": 30},
+			{"id": 1, "n": 1.0}
+		]}`))
+		dec.UseNumber()
+
+		var exact interface{}
+
+		require.NoError(t, dec.Decode(&exact))
+
+		for expr, expected := range map[string][]interface{}{
+			"$.items[?(@.n > 10)].id":               {json.Number("9007199254740992")},
+			"$.items[?(@.n <= 5)].id":               {json.Number("9007199254740993"), json.Number("1")},
+			"$.items[?(@.n == 1)].id":               {json.Number("1")},
+			"$.items[?(@.id > 9007199254740991)].n": {json.Number("5"), json.Number("30")},
+			"$.items[?(@.n != 30)].n":               {json.Number("5"), json.Number("1.0")},
+			"$.items[?(@.n >= 30)].n":               {json.Number("30")},
+			"$.items[?(@.n < 2)].n":                 {json.Number("1.0")},
+		} {
+			path, err := operation.CompileJSONPath(expr)
+			require.NoError(t, err)
+
+			result, err := path.Evaluate(exact)
+			require.NoError(t, err)
+			require.Equal(t, expected, result, expr)
+		}
+	})
+
+	t.Run("error if the path is malformed", func(t *testing.T) {
+		_, err := operation.CompileJSONPath("}")
+		require.True(t, errors.Is(err, operation.ErrInvalidJSONPath))
+		require.Contains(t, err.Error(), "[}]")
+	})
+
+	t.Run("error if the path selects nothing", func(t *testing.T) {
+		for _, expr := range []string{
+			"$.credentialSubject.missing",
+			"$.credentialSubject.scores[5]",
+			"$.credentialSubject.testMessage.length",
+		} {
+			path, err := operation.CompileJSONPath(expr)
+			require.NoError(t, err)
+
+			_, err = path.Evaluate(doc)
+			require.True(t, errors.Is(err, operation.ErrJSONPathNotFound), expr)
+			require.False(t, errors.Is(err, operation.ErrInvalidJSONPath), expr)
+			require.Contains(t, err.Error(), expr)
+		}
+	})
+}
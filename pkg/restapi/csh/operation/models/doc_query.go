@@ -34,9 +34,8 @@ type DocQuery struct {
 	// path
 	Path string `json:"path,omitempty"`
 
-	// upstream auth
-	// Required: true
-	UpstreamAuth *DocQueryAO1UpstreamAuth `json:"upstreamAuth"`
+	// The EDV and KMS authorizations. Stored queries default to the upstream auth of their profile.
+	UpstreamAuth *DocQueryAO1UpstreamAuth `json:"upstreamAuth,omitempty"`
 
 	// vault ID
 	// Required: true
@@ -73,9 +72,8 @@ func (m *DocQuery) UnmarshalJSON(raw []byte) error {
 		// path
 		Path string `json:"path,omitempty"`
 
-		// upstream auth
-		// Required: true
-		UpstreamAuth *DocQueryAO1UpstreamAuth `json:"upstreamAuth"`
+		// The EDV and KMS authorizations. Stored queries default to the upstream auth of their profile.
+		UpstreamAuth *DocQueryAO1UpstreamAuth `json:"upstreamAuth,omitempty"`
 
 		// vault ID
 		// Required: true
@@ -136,9 +134,8 @@ func (m DocQuery) MarshalJSON() ([]byte, error) {
 		// path
 		Path string `json:"path,omitempty"`
 
-		// upstream auth
-		// Required: true
-		UpstreamAuth *DocQueryAO1UpstreamAuth `json:"upstreamAuth"`
+		// The EDV and KMS authorizations. Stored queries default to the upstream auth of their profile.
+		UpstreamAuth *DocQueryAO1UpstreamAuth `json:"upstreamAuth,omitempty"`
 
 		// vault ID
 		// Required: true
@@ -206,8 +203,8 @@ func (m *DocQuery) validateDocID(formats strfmt.Registry) error {
 
 func (m *DocQuery) validateUpstreamAuth(formats strfmt.Registry) error {
 
-	if err := validate.Required("upstreamAuth", "body", m.UpstreamAuth); err != nil {
-		return err
+	if swag.IsZero(m.UpstreamAuth) { // not required
+		return nil
 	}
 
 	if m.UpstreamAuth != nil {
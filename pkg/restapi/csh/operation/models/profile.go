@@ -28,10 +28,20 @@ type Profile struct {
 	// Required: true
 	Controller *string `json:"controller"`
 
+	// The time at which the profile's zcap expires. The profile's zcap does not expire if absent.
+	// Format: date-time
+	Expiry *strfmt.DateTime `json:"expiry,omitempty"`
+
 	// id
 	ID string `json:"id,omitempty"`
 
-	// zcap
+	// upstream auth
+	UpstreamAuth *ProfileUpstreamAuth `json:"upstreamAuth,omitempty"`
+
+	// The root zcap of the profile. Setting it when creating a profile imports the root zcap of a profile created
+	// by another instance instead of minting a new one: the profile keeps its ID and expiry, so the capabilities
+	// delegated from it remain valid. It must be signed with a capability delegation key and invoked by the
+	// controller.
 	Zcap string `json:"zcap,omitempty"`
 }
 
@@ -43,6 +53,14 @@ func (m *Profile) Validate(formats strfmt.Registry) error {
 		res = append(res, err)
 	}
 
+	if err := m.validateExpiry(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.validateUpstreamAuth(formats); err != nil {
+		res = append(res, err)
+	}
+
 	if len(res) > 0 {
 		return errors.CompositeValidationError(res...)
 	}
@@ -58,8 +76,64 @@ func (m *Profile) validateController(formats strfmt.Registry) error {
 	return nil
 }
 
-// ContextValidate validates this profile based on context it is used
+func (m *Profile) validateExpiry(formats strfmt.Registry) error {
+	if swag.IsZero(m.Expiry) { // not required
+		return nil
+	}
+
+	if err := validate.FormatOf("expiry", "body", "date-time", m.Expiry.String(), formats); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *Profile) validateUpstreamAuth(formats strfmt.Registry) error {
+	if swag.IsZero(m.UpstreamAuth) { // not required
+		return nil
+	}
+
+	if m.UpstreamAuth != nil {
+		if err := m.UpstreamAuth.Validate(formats); err != nil {
+			if ve, ok := err.(*errors.Validation); ok {
+				return ve.ValidateName("upstreamAuth")
+			} else if ce, ok := err.(*errors.CompositeError); ok {
+				return ce.ValidateName("upstreamAuth")
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ContextValidate validate this profile based on the context it is used
 func (m *Profile) ContextValidate(ctx context.Context, formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.contextValidateUpstreamAuth(ctx, formats); err != nil {
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *Profile) contextValidateUpstreamAuth(ctx context.Context, formats strfmt.Registry) error {
+
+	if m.UpstreamAuth != nil {
+		if err := m.UpstreamAuth.ContextValidate(ctx, formats); err != nil {
+			if ve, ok := err.(*errors.Validation); ok {
+				return ve.ValidateName("upstreamAuth")
+			} else if ce, ok := err.(*errors.CompositeError); ok {
+				return ce.ValidateName("upstreamAuth")
+			}
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -80,3 +154,141 @@ func (m *Profile) UnmarshalBinary(b []byte) error {
 	*m = res
 	return nil
 }
+
+// ProfileUpstreamAuth The EDV and KMS authorizations of the queries stored under the profile without their own.
+//
+// swagger:model ProfileUpstreamAuth
+type ProfileUpstreamAuth struct {
+
+	// edv
+	// Required: true
+	Edv *UpstreamAuthorization `json:"edv"`
+
+	// kms
+	Kms *UpstreamAuthorization `json:"kms,omitempty"`
+}
+
+// Validate validates this profile upstream auth
+func (m *ProfileUpstreamAuth) Validate(formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.validateEdv(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.validateKms(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *ProfileUpstreamAuth) validateEdv(formats strfmt.Registry) error {
+
+	if err := validate.Required("upstreamAuth"+"."+"edv", "body", m.Edv); err != nil {
+		return err
+	}
+
+	if m.Edv != nil {
+		if err := m.Edv.Validate(formats); err != nil {
+			if ve, ok := err.(*errors.Validation); ok {
+				return ve.ValidateName("upstreamAuth" + "." + "edv")
+			} else if ce, ok := err.(*errors.CompositeError); ok {
+				return ce.ValidateName("upstreamAuth" + "." + "edv")
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *ProfileUpstreamAuth) validateKms(formats strfmt.Registry) error {
+	if swag.IsZero(m.Kms) { // not required
+		return nil
+	}
+
+	if m.Kms != nil {
+		if err := m.Kms.Validate(formats); err != nil {
+			if ve, ok := err.(*errors.Validation); ok {
+				return ve.ValidateName("upstreamAuth" + "." + "kms")
+			} else if ce, ok := err.(*errors.CompositeError); ok {
+				return ce.ValidateName("upstreamAuth" + "." + "kms")
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ContextValidate validate this profile upstream auth based on the context it is used
+func (m *ProfileUpstreamAuth) ContextValidate(ctx context.Context, formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.contextValidateEdv(ctx, formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.contextValidateKms(ctx, formats); err != nil {
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *ProfileUpstreamAuth) contextValidateEdv(ctx context.Context, formats strfmt.Registry) error {
+
+	if m.Edv != nil {
+		if err := m.Edv.ContextValidate(ctx, formats); err != nil {
+			if ve, ok := err.(*errors.Validation); ok {
+				return ve.ValidateName("upstreamAuth" + "." + "edv")
+			} else if ce, ok := err.(*errors.CompositeError); ok {
+				return ce.ValidateName("upstreamAuth" + "." + "edv")
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *ProfileUpstreamAuth) contextValidateKms(ctx context.Context, formats strfmt.Registry) error {
+
+	if m.Kms != nil {
+		if err := m.Kms.ContextValidate(ctx, formats); err != nil {
+			if ve, ok := err.(*errors.Validation); ok {
+				return ve.ValidateName("upstreamAuth" + "." + "kms")
+			} else if ce, ok := err.(*errors.CompositeError); ok {
+				return ce.ValidateName("upstreamAuth" + "." + "kms")
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MarshalBinary interface implementation
+func (m *ProfileUpstreamAuth) MarshalBinary() ([]byte, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return swag.WriteJSON(m)
+}
+
+// UnmarshalBinary interface implementation
+func (m *ProfileUpstreamAuth) UnmarshalBinary(b []byte) error {
+	var res ProfileUpstreamAuth
+	if err := swag.ReadJSON(b, &res); err != nil {
+		return err
+	}
+	*m = res
+	return nil
+}
@@ -55,6 +55,55 @@ type createQueryResp struct { // nolint:deadcode,unused // swagger model
 	Body openapi.Query
 }
 
+// listQueriesReq model
+//
+// swagger:parameters listQueriesReq
+type listQueriesReq struct { // nolint:deadcode,unused // swagger model
+	// in: path
+	// required: true
+	ProfileID string `json:"profileID"`
+
+	// The maximum number of queries to return. Defaults to 100, at most 1000.
+	// in: query
+	Limit int `json:"limit"`
+
+	// The continuation token returned with the previous page.
+	// in: query
+	Next string `json:"next"`
+
+	// Include the specs of the queries.
+	// in: query
+	IncludeSpec bool `json:"includeSpec"`
+}
+
+// QueryList.
+//
+// swagger:response listQueriesResp
+type listQueriesResp struct { // nolint:deadcode,unused // swagger model
+	// in: body
+	Body openapi.QueryList
+}
+
+// validateQueryReq model
+//
+// swagger:parameters validateQueryReq
+type validateQueryReq struct { // nolint:deadcode,unused // swagger model
+	// in: path
+	// required: true
+	ProfileID string `json:"profileID"`
+
+	// in: body
+	Body openapi.Query
+}
+
+// QueryValidation.
+//
+// swagger:response validateQueryResp
+type validateQueryResp struct { // nolint:deadcode,unused // swagger model
+	// in: body
+	Body openapi.QueryValidation
+}
+
 // createAuthorizationReq model
 //
 // swagger:parameters createAuthorizationReq
@@ -81,6 +130,9 @@ type createAuthorizationResp struct { // nolint:deadcode,unused // swagger model
 //
 // swagger:parameters comparisonReq
 type comparisonReq struct { // nolint:deadcode,unused // swagger model
+	// Seconds the hub may spend fetching the documents.
+	// in: query
+	Deadline float64 `json:"deadline"`
 	// in: body
 	Body openapi.ComparisonRequest
 }
@@ -97,6 +149,18 @@ type comparisonResp struct { // nolint:deadcode,unused // swagger model
 //
 // swagger:parameters extractionReq
 type extractionReq struct { // nolint:deadcode,unused // swagger model
+	// Include the non-secret metadata of the source documents in the extractions.
+	// in: query
+	IncludeMetadata bool `json:"include_metadata"`
+	// JSONPaths of the values removed from every extracted document, eg. `$.credentialSubject.ssn`.
+	// in: query
+	Redact []string `json:"redact"`
+	// Seconds the hub may spend fetching the documents.
+	// in: query
+	Deadline float64 `json:"deadline"`
+	// Nonce of the request: the response is then a SignedExtraction, whose signed payload includes it.
+	// in: query
+	Nonce string `json:"nonce"`
 	// in: body
 	Body []openapi.Query
 }
@@ -108,3 +172,28 @@ type extractionResp struct { // nolint:deadcode,unused // swagger model
 	// in: body
 	Body openapi.ExtractionResponse
 }
+
+// batchExtractionReq model
+//
+// swagger:parameters batchExtractionReq
+type batchExtractionReq struct { // nolint:deadcode,unused // swagger model
+	// Include the non-secret metadata of the source documents in the extractions.
+	// in: query
+	IncludeMetadata bool `json:"include_metadata"`
+	// JSONPaths of the values removed from every extracted document, eg. `$.credentialSubject.ssn`.
+	// in: query
+	Redact []string `json:"redact"`
+	// Seconds the hub may spend fetching the documents.
+	// in: query
+	Deadline float64 `json:"deadline"`
+	// in: body
+	Body []ExtractionGroup
+}
+
+// batchExtractionResp model
+//
+// swagger:response batchExtractionResp
+type batchExtractionResp struct { // nolint:deadcode,unused // swagger model
+	// in: body
+	Body []ExtractionGroupResult
+}
@@ -7,12 +7,21 @@ SPDX-License-Identifier: Apache-2.0
 package operation
 
 import (
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/cenkalti/backoff/v4"
 	"github.com/go-openapi/runtime"
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/hyperledger/aries-framework-go/pkg/crypto"
@@ -34,16 +43,20 @@ import (
 	openapi "github.com/trustbloc/ace/pkg/restapi/csh/operation/models"
 	zcapld2 "github.com/trustbloc/ace/pkg/restapi/csh/operation/zcapld"
 	"github.com/trustbloc/ace/pkg/restapi/handler"
+	"github.com/trustbloc/ace/pkg/restapi/model"
 )
 
 const (
 	operationID       = "/hubstore/profiles"
 	createProfilePath = operationID
 	createQueryPath   = operationID + "/{profileID}/queries"
+	listQueriesPath   = createQueryPath
+	validateQueryPath = createQueryPath + "/validate"
 	createAuthzPath   = operationID + "/{profileID}/authorizations"
 
 	comparePath = "/compare"
 	extractPath = "/extract"
+	batchPath   = extractPath + "/batch"
 )
 
 const (
@@ -55,6 +68,33 @@ const (
 	identityKey = "config"
 )
 
+// profileZCAPType is the invocation target type of the root zcaps of profiles.
+const profileZCAPType = "urn:confidentialstoragehub:profile"
+
+// DefaultDIDCacheTTL is the default time resolved DID documents are cached for.
+const DefaultDIDCacheTTL = 5 * time.Minute
+
+// DefaultDIDCacheSize is the default maximum number of cached DID documents.
+const DefaultDIDCacheSize = 1000
+
+// DefaultMaxDocSize is the default maximum size, in bytes, of the decrypted documents of queries.
+const DefaultMaxDocSize = 10 << 20
+
+const (
+	// DefaultIdentityRetries is the default number of times the creation of the identity DID is retried at startup.
+	DefaultIdentityRetries = 5
+	// DefaultIdentityRetryDelay is the default delay before the first retry of the creation of the identity DID.
+	DefaultIdentityRetryDelay = time.Second
+)
+
+const (
+	defaultQueryListLimit = 100
+	maxQueryListLimit     = 1000
+)
+
+// ndjsonMediaType is requested by clients that want extractions streamed one per line.
+const ndjsonMediaType = "application/x-ndjson"
+
 var logger = log.New("confidential-storage-hub")
 
 // Operation defines handlers for vault service.
@@ -71,6 +111,19 @@ type Operation struct {
 	baseURL        string
 	didDomain      string
 	documentLoader ld.DocumentLoader
+	didCache       *zcapld2.DIDCache
+	didCacheTTL    time.Duration
+	didMethods     *zcapld2.DIDMethodPolicy
+	maxDocSize     int64
+	exactNumbers   bool
+	// maxChainDepth is the maximum number of delegations of the upstream zcaps from their root zcaps.
+	maxChainDepth int
+	// zcapCompression compresses the zcaps of the profiles.
+	zcapCompression []zcapld2.CompressOpt
+	// denyList holds the paths whose values are never extracted.
+	denyList *DenyList
+	// revocations tells whether the upstream zcaps were revoked.
+	revocations zcapld2.RevocationList
 }
 
 // Config defines configuration for vault operations.
@@ -82,6 +135,36 @@ type Config struct {
 	BaseURL        string
 	DIDDomain      string
 	DocumentLoader ld.DocumentLoader
+	// DIDCacheTTL is how long resolved DID documents are cached. Defaults to DefaultDIDCacheTTL.
+	DIDCacheTTL time.Duration
+	// DIDCacheSize is the maximum number of cached DID documents. Defaults to DefaultDIDCacheSize.
+	DIDCacheSize int
+	// MaxDocSize is the maximum size, in bytes, of the decrypted documents of queries. Defaults to DefaultMaxDocSize.
+	MaxDocSize int64
+	// DIDMethods restricts the DID methods of the invokers of zcaps. All methods are allowed if nil.
+	DIDMethods *zcapld2.DIDMethodPolicy
+	// MaxZCAPChainDepth is the maximum number of delegations of the upstream zcaps of queries from their root zcaps.
+	// Deeper zcaps are rejected before the EDV or KMS are invoked. Defaults to zcapld.DefaultMaxChainDepth.
+	MaxZCAPChainDepth int
+	// ExactNumbers decodes the numbers of documents as json.Number rather than float64, so that integers beyond
+	// 2^53, eg. 64-bit IDs, are extracted and compared exactly.
+	ExactNumbers bool
+	// IdentityRetries is the number of times the creation of the identity DID is retried at startup when it fails,
+	// eg. because the network of the DID method is briefly unavailable. Defaults to DefaultIdentityRetries, negative
+	// values disable retries.
+	IdentityRetries int
+	// IdentityRetryDelay is the delay before the first retry of the creation of the identity DID, doubled on every
+	// retry. Defaults to DefaultIdentityRetryDelay.
+	IdentityRetryDelay time.Duration
+	// ZCAPCompression configures the compression of the zcaps of the profiles. Defaults to gzip's default
+	// compression of all zcaps.
+	ZCAPCompression []zcapld2.CompressOpt
+	// DenyList holds the paths whose values are never extracted, whatever the authorization of the queries. Nothing
+	// is denied if nil.
+	DenyList *DenyList
+	// Revocations tells whether the upstream zcaps of queries were revoked, eg. the client of the vault server that
+	// issued them. Revoked zcaps are rejected before the EDV or KMS are invoked. Revocation is not checked if nil.
+	Revocations zcapld2.RevocationList
 }
 
 // AriesConfig holds all configurations for aries-framework-go dependencies.
@@ -97,12 +180,39 @@ type AriesConfig struct {
 // New returns operation instance.
 func New(cfg *Config) (*Operation, error) {
 	ops := &Operation{
-		aries:          cfg.Aries,
-		httpClient:     cfg.HTTPClient,
-		edvClient:      cfg.EDVClient,
-		baseURL:        cfg.BaseURL,
-		didDomain:      cfg.DIDDomain,
-		documentLoader: cfg.DocumentLoader,
+		aries:           cfg.Aries,
+		httpClient:      cfg.HTTPClient,
+		edvClient:       cfg.EDVClient,
+		baseURL:         cfg.BaseURL,
+		didDomain:       cfg.DIDDomain,
+		documentLoader:  cfg.DocumentLoader,
+		didMethods:      cfg.DIDMethods,
+		exactNumbers:    cfg.ExactNumbers,
+		zcapCompression: cfg.ZCAPCompression,
+		denyList:        cfg.DenyList,
+		revocations:     cfg.Revocations,
+	}
+
+	ops.didCacheTTL = cfg.DIDCacheTTL
+	if ops.didCacheTTL == 0 {
+		ops.didCacheTTL = DefaultDIDCacheTTL
+	}
+
+	size := cfg.DIDCacheSize
+	if size == 0 {
+		size = DefaultDIDCacheSize
+	}
+
+	ops.didCache = zcapld2.NewDIDCache(ops.didCacheTTL, size)
+
+	ops.maxDocSize = cfg.MaxDocSize
+	if ops.maxDocSize == 0 {
+		ops.maxDocSize = DefaultMaxDocSize
+	}
+
+	ops.maxChainDepth = cfg.MaxZCAPChainDepth
+	if ops.maxChainDepth == 0 {
+		ops.maxChainDepth = zcapld2.DefaultMaxChainDepth
 	}
 
 	err := ops.configure(cfg)
@@ -118,22 +228,28 @@ func (o *Operation) GetRESTHandlers() []handler.Handler {
 	return []handler.Handler{
 		handler.NewHTTPHandler(createProfilePath, http.MethodPost, o.CreateProfile),
 		handler.NewHTTPHandler(createQueryPath, http.MethodPost, o.CreateQuery),
+		handler.NewHTTPHandler(listQueriesPath, http.MethodGet, o.ListQueries),
+		handler.NewHTTPHandler(validateQueryPath, http.MethodPost, o.ValidateQuery),
 		handler.NewHTTPHandler(createAuthzPath, http.MethodPost, o.CreateAuthorization),
 		handler.NewHTTPHandler(comparePath, http.MethodPost, o.Compare),
 		handler.NewHTTPHandler(extractPath, http.MethodPost, o.Extract),
+		handler.NewHTTPHandler(batchPath, http.MethodPost, o.BatchExtract),
 	}
 }
 
 // CreateProfile swagger:route POST /hubstore/profiles createProfileReq
 //
-// Creates a Profile.
+// Creates a Profile. The root zcap of a profile created by another instance may be imported by setting `zcap`.
 //
 // Produces:
 //   - application/json
 // Responses:
 //   201: createProfileResp
+//   400: Error
+//   403: Error
+//   409: Error
 //   500: Error
-func (o *Operation) CreateProfile(w http.ResponseWriter, r *http.Request) {
+func (o *Operation) CreateProfile(w http.ResponseWriter, r *http.Request) { // nolint:funlen
 	logger.Infof("handling request")
 
 	profile := &openapi.Profile{}
@@ -151,15 +267,77 @@ func (o *Operation) CreateProfile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	profile.ID = uuid.New().URN()
-
-	zcap, err := o.newProfileZCAP(profile.ID, *profile.Controller)
+	// the controller invokes the root zcap of the profile
+	err = o.didMethods.CheckInvoker(*profile.Controller)
 	if err != nil {
-		respondErrorf(w, http.StatusInternalServerError, "failed to create zcap: %s", err.Error())
+		respondErrorf(w, http.StatusForbidden, "invalid controller: %s", err.Error())
+
+		return
+	}
+
+	if profile.UpstreamAuth != nil && profile.UpstreamAuth.Edv == nil {
+		respondErrorf(w, http.StatusBadRequest, "invalid upstreamAuth: edv is required")
 
 		return
 	}
 
+	var expires *time.Time
+
+	if profile.Expiry != nil {
+		t := time.Time(*profile.Expiry)
+
+		if !t.After(time.Now()) {
+			respondErrorf(w, http.StatusBadRequest, "expiry must be in the future")
+
+			return
+		}
+
+		expires = &t
+	}
+
+	var zcap *zcapld2.Capability
+
+	if profile.Zcap != "" {
+		if expires != nil {
+			respondErrorf(w, http.StatusBadRequest, "expiry cannot be set when importing a zcap")
+
+			return
+		}
+
+		zcap, err = o.importProfileZCAP(profile.Zcap, *profile.Controller)
+		if err != nil {
+			respondErrorf(w, http.StatusBadRequest, "invalid zcap: %s", err.Error())
+
+			return
+		}
+
+		_, err = o.storage.profiles.Get(zcap.ID)
+		if err == nil {
+			respondErrorf(w, http.StatusConflict, "profile %s already exists", zcap.ID)
+
+			return
+		}
+
+		if !errors.Is(err, storage.ErrDataNotFound) {
+			respondErrorf(w, http.StatusInternalServerError, "failed to fetch profile: %s", err.Error())
+
+			return
+		}
+
+		profile.ID = zcap.ID
+		profile.Zcap = ""
+		profile.Expiry = (*strfmt.DateTime)(zcap.Expires)
+	} else {
+		profile.ID = uuid.New().URN()
+
+		zcap, err = o.newProfileZCAP(profile.ID, *profile.Controller, expires)
+		if err != nil {
+			respondErrorf(w, http.StatusInternalServerError, "failed to create zcap: %s", err.Error())
+
+			return
+		}
+	}
+
 	err = save(o.storage.zcaps, profile.ID, zcap)
 	if err != nil {
 		respondErrorf(w, http.StatusInternalServerError, "failed to store zcap: %s", err.Error())
@@ -174,7 +352,7 @@ func (o *Operation) CreateProfile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	profile.Zcap, err = zcapld.CompressZCAP(zcap)
+	profile.Zcap, err = zcapld2.CompressZCAP(zcap, o.zcapCompression...)
 	if err != nil {
 		respondErrorf(w, http.StatusInternalServerError, "failed to compress zcap: %s", err.Error())
 
@@ -214,8 +392,11 @@ func (o *Operation) CreateQuery(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	switch query.(type) {
+	var docQuery *openapi.DocQuery
+
+	switch q := query.(type) {
 	case *openapi.DocQuery: // allow DocQuery
+		docQuery = q
 	case *openapi.RefQuery:
 		respondErrorf(w, http.StatusBadRequest, "query type not allowed: %s", query.Type())
 
@@ -228,6 +409,23 @@ func (o *Operation) CreateQuery(w http.ResponseWriter, r *http.Request) {
 
 	profileID := mux.Vars(r)["profileID"]
 
+	// queries without upstream auth are stored as is and use the profile's when they are resolved
+	if docQuery.UpstreamAuth == nil {
+		auth, err := o.profileUpstreamAuth(profileID)
+		if err != nil {
+			respondErrorf(w, http.StatusInternalServerError, "failed to fetch profile upstream auth: %s", err.Error())
+
+			return
+		}
+
+		if auth == nil {
+			respondErrorCodef(w, http.StatusBadRequest, model.ErrCodeInvalidRequest,
+				"invalid request: upstreamAuth is required, profile %s has none", profileID)
+
+			return
+		}
+	}
+
 	raw, err := json.Marshal(query)
 	if err != nil {
 		respondErrorf(w, http.StatusInternalServerError,
@@ -242,9 +440,11 @@ func (o *Operation) CreateQuery(w http.ResponseWriter, r *http.Request) {
 		Spec:      raw,
 	}
 
-	err = save(o.storage.queries, entity.ID, entity)
+	err = o.saveQuery(entity)
 	if err != nil {
 		respondErrorf(w, http.StatusInternalServerError, "failed to persist query: %s", err.Error())
+
+		return
 	}
 
 	headers := map[string]string{
@@ -255,6 +455,168 @@ func (o *Operation) CreateQuery(w http.ResponseWriter, r *http.Request) {
 	logger.Debugf("handled request")
 }
 
+// ListQueries swagger:route GET /hubstore/profiles/{profileID}/queries listQueriesReq
+//
+// Lists the queries of a profile, ordered by their IDs. The specs of the queries are included if `includeSpec` is
+// set. Queries created before they were indexed by profile are not listed.
+//
+// Produces:
+//   - application/json
+// Responses:
+//   200: listQueriesResp
+//   400: Error
+//   404: Error
+//   500: Error
+func (o *Operation) ListQueries(w http.ResponseWriter, r *http.Request) { // nolint:funlen
+	logger.Debugf("handling request")
+
+	var (
+		profileID = mux.Vars(r)["profileID"]
+		values    = r.URL.Query()
+		limit     = defaultQueryListLimit
+		withSpec  bool
+		err       error
+	)
+
+	if l := values.Get("limit"); l != "" {
+		limit, err = strconv.Atoi(l)
+		if err != nil || limit < 1 {
+			respondErrorf(w, http.StatusBadRequest, "invalid limit: %s", l)
+
+			return
+		}
+
+		if limit > maxQueryListLimit {
+			limit = maxQueryListLimit
+		}
+	}
+
+	if s := values.Get("includeSpec"); s != "" {
+		withSpec, err = strconv.ParseBool(s)
+		if err != nil {
+			respondErrorf(w, http.StatusBadRequest, "invalid includeSpec: %s", s)
+
+			return
+		}
+	}
+
+	after, err := base64.RawURLEncoding.DecodeString(values.Get("next"))
+	if err != nil {
+		respondErrorf(w, http.StatusBadRequest, "invalid continuation token: %s", err.Error())
+
+		return
+	}
+
+	_, err = o.storage.profiles.Get(profileID)
+	if errors.Is(err, storage.ErrDataNotFound) {
+		respondErrorf(w, http.StatusNotFound, "profile %s not found", profileID)
+
+		return
+	}
+
+	if err != nil {
+		respondErrorf(w, http.StatusInternalServerError, "failed to fetch profile: %s", err.Error())
+
+		return
+	}
+
+	queries, err := o.profileQueries(profileID)
+	if err != nil {
+		respondErrorf(w, http.StatusInternalServerError, "failed to list queries: %s", err.Error())
+
+		return
+	}
+
+	sort.Slice(queries, func(i, j int) bool { return queries[i].ID < queries[j].ID })
+
+	start := sort.Search(len(queries), func(i int) bool { return queries[i].ID > string(after) })
+	queries = queries[start:]
+
+	list := &openapi.QueryList{Queries: []*openapi.QueryListQueriesItems0{}}
+
+	if len(queries) > limit {
+		queries = queries[:limit]
+		list.Next = base64.RawURLEncoding.EncodeToString([]byte(queries[limit-1].ID))
+	}
+
+	for _, q := range queries {
+		entry := &openapi.QueryListQueriesItems0{ID: q.ID}
+
+		if withSpec {
+			entry.Spec = q.Spec
+		}
+
+		list.Queries = append(list.Queries, entry)
+	}
+
+	headers := map[string]string{
+		"Content-Type": "application/json",
+	}
+
+	respond(w, http.StatusOK, headers, list)
+	logger.Debugf("handled request")
+}
+
+// ValidateQuery swagger:route POST /hubstore/profiles/{profileID}/queries/validate validateQueryReq
+//
+// Validates a Query without storing it: the document is fetched from the Confidential Storage vault and the path
+// of the query is evaluated against it. Queries that cannot be resolved are reported with a diagnostic.
+//
+// Consumes:
+//   - application/json
+// Produces:
+//   - application/json
+// Responses:
+//   200: validateQueryResp
+//   400: Error
+//   500: Error
+func (o *Operation) ValidateQuery(w http.ResponseWriter, r *http.Request) {
+	logger.Debugf("handling request")
+
+	query, err := openapi.UnmarshalQuery(r.Body, runtime.JSONConsumer())
+	if err != nil {
+		respondErrorf(w, http.StatusBadRequest, "bad request: %s", err.Error())
+
+		return
+	}
+
+	docQuery, ok := query.(*openapi.DocQuery)
+	if !ok {
+		respondErrorf(w, http.StatusBadRequest, "query type not allowed: %s", query.Type())
+
+		return
+	}
+
+	err = o.applyProfileUpstreamAuth(docQuery, mux.Vars(r)["profileID"])
+	if err != nil {
+		respondErrorf(w, http.StatusInternalServerError, "failed to fetch profile upstream auth: %s", err.Error())
+
+		return
+	}
+
+	// the KMS authorization is optional: documents are decrypted with the CSH's keys without it
+	if docQuery.VaultID == nil || docQuery.DocID == nil || checkUpstreamAuth(docQuery) != nil {
+		respondErrorCodef(w, http.StatusBadRequest, model.ErrCodeInvalidRequest,
+			"invalid request: vaultID, docID and upstreamAuth.edv are required")
+
+		return
+	}
+
+	validation := &openapi.QueryValidation{Valid: true}
+
+	_, _, err = o.fetchDocumentWithMetadata(r.Context(), docQuery)
+	if err != nil {
+		validation = &openapi.QueryValidation{Diagnostic: queryDiagnostic(err), Message: err.Error()}
+	}
+
+	headers := map[string]string{
+		"Content-Type": "application/json",
+	}
+
+	respond(w, http.StatusOK, headers, validation)
+	logger.Debugf("handled request")
+}
+
 // CreateAuthorization swagger:route POST /hubstore/profiles/{profileID}/authorizations createAuthorizationReq
 //
 // Creates an Authorization.
@@ -281,13 +643,24 @@ func (o *Operation) CreateAuthorization(w http.ResponseWriter, _ *http.Request)
 //   - application/json
 // Responses:
 //   200: comparisonResp
+//   403: Error
 //   500: Error
+//   504: Error
 func (o *Operation) Compare(w http.ResponseWriter, r *http.Request) {
 	logger.Debugf("handling request")
 
+	ctx, cancel, err := requestContext(r)
+	if err != nil {
+		respondErrorf(w, http.StatusBadRequest, "bad request: %s", err.Error())
+
+		return
+	}
+
+	defer cancel()
+
 	request := &openapi.ComparisonRequest{}
 
-	err := json.NewDecoder(r.Body).Decode(request)
+	err = json.NewDecoder(r.Body).Decode(request)
 	if err != nil {
 		respondErrorf(w, http.StatusBadRequest, "bad request: %s", err.Error())
 
@@ -296,7 +669,7 @@ func (o *Operation) Compare(w http.ResponseWriter, r *http.Request) {
 
 	switch t := request.Op().(type) {
 	case *openapi.EqOp:
-		o.HandleEqOp(w, t)
+		o.HandleEqOp(ctx, w, t)
 	default:
 		respondErrorf(w, http.StatusNotImplemented, "operator not yet implemented: %s", request.Op().Type())
 	}
@@ -306,19 +679,37 @@ func (o *Operation) Compare(w http.ResponseWriter, r *http.Request) {
 
 // Extract swagger:route POST /hubstore/extract extractionReq
 //
-// Extracts the contents of a document.
+// Extracts the contents of a document. The extractions requested with a nonce are signed along with it, and those
+// requested with recipients are encrypted to all of them.
 //
 // Consumes:
 //   - application/json
 // Produces:
 //   - application/json
+//   - application/x-ndjson
 // Responses:
 //   200: extractionResp
 //   400: Error
+//   403: Error
 //   500: Error
-func (o *Operation) Extract(w http.ResponseWriter, r *http.Request) {
+//   504: Error
+func (o *Operation) Extract(w http.ResponseWriter, r *http.Request) { // nolint:funlen
 	logger.Debugf("handling request")
 
+	params, proceed := parseExtractionParams(w, r)
+	if !proceed {
+		return
+	}
+
+	ctx, cancel, err := requestContext(r)
+	if err != nil {
+		respondErrorf(w, http.StatusBadRequest, "bad request: %s", err.Error())
+
+		return
+	}
+
+	defer cancel()
+
 	queries, err := openapi.UnmarshalQuerySlice(r.Body, runtime.JSONConsumer())
 	if err != nil {
 		respondErrorf(w, http.StatusBadRequest, "bad request: %s", err.Error())
@@ -326,50 +717,208 @@ func (o *Operation) Extract(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var extractions openapi.ExtractionResponse
+	var (
+		extractions openapi.ExtractionResponse
+		stream      *ndjsonWriter
+		nonce       = r.URL.Query().Get("nonce")
+	)
+
+	// extractions are written as they are resolved if the client asked for NDJSON; errors
+	// past that point are reported in-band as a final line since the status is already sent
+	if strings.Contains(r.Header.Get("Accept"), ndjsonMediaType) {
+		if nonce != "" {
+			respondErrorf(w, http.StatusBadRequest, "bad request: streamed extractions cannot be signed with a nonce")
+
+			return
+		}
+
+		if len(params.recipients) > 0 {
+			respondErrorf(w, http.StatusBadRequest, "bad request: streamed extractions cannot be encrypted")
+
+			return
+		}
+
+		stream = &ndjsonWriter{ResponseWriter: w}
+		w = stream
+	}
 
 	for i := range queries {
 		query := queries[i]
 
-		var doc interface{}
+		var (
+			doc      interface{}
+			docQuery *openapi.DocQuery
+			metadata *openapi.ExtractionMetadata
+		)
 
 		switch q := query.(type) {
 		case *openapi.DocQuery:
 			var err error
 
-			doc, err = o.fetchDocument(q)
+			doc, metadata, err = o.extractDocument(ctx, q)
 			if err != nil {
-				respondErrorf(w, http.StatusInternalServerError,
+				respondFetchErrorf(w, err,
 					"failed to fetch document for DocQuery: %s", err.Error())
 
 				return
 			}
-		case *openapi.RefQuery:
-			var proceed bool
 
-			doc, proceed = o.resolveRefQuery(w, q)
+			docQuery = q
+		case *openapi.RefQuery:
+			spec, proceed := o.loadRefQuery(w, q)
 			if !proceed {
 				return
 			}
+
+			doc, metadata, err = o.extractDocument(ctx, spec)
+			if err != nil {
+				respondFetchErrorf(w, err,
+					"failed to fetch Confidential Storage document for refquery: %s", err.Error())
+
+				return
+			}
+
+			docQuery, _ = spec.(*openapi.DocQuery)
+		}
+
+		extraction := params.newExtraction(query.ID(), doc, docQuery, metadata)
+
+		if stream != nil {
+			stream.write(extraction)
+
+			continue
+		}
+
+		extractions = append(extractions, extraction)
+	}
+
+	if stream != nil {
+		stream.start()
+		logger.Debugf("handled request")
+
+		return
+	}
+
+	if nonce != "" {
+		signed, err := o.signExtractions(nonce, extractions)
+		if err != nil {
+			respondErrorf(w, http.StatusInternalServerError, "failed to sign extractions: %s", err.Error())
+
+			return
 		}
 
-		extractions = append(extractions, &openapi.ExtractionResponseItems0{
-			ID:       query.ID(),
-			Document: doc,
-		})
+		o.respondExtractions(w, params, signed)
+
+		return
 	}
 
+	o.respondExtractions(w, params, extractions)
+}
+
+// respondExtractions responds with the extractions, encrypted to the recipients of the request if it has any.
+func (o *Operation) respondExtractions(w http.ResponseWriter, params *extractionParams, response interface{}) {
 	headers := map[string]string{
 		"Content-Type": "application/json",
 	}
 
-	respond(w, http.StatusOK, headers, extractions)
+	if len(params.recipients) > 0 {
+		encrypted, err := o.encryptExtractions(params.recipients, response)
+		if err != nil {
+			respondErrorf(w, http.StatusInternalServerError, "failed to encrypt extractions: %s", err.Error())
+
+			return
+		}
+
+		response = encrypted
+	}
+
+	respond(w, http.StatusOK, headers, response)
 	logger.Debugf("handled request")
 }
 
+// extractionParams are the query parameters of the extraction endpoints.
+type extractionParams struct {
+	includeMetadata bool
+	redactions      []*RedactionPath
+	recipients      []*crypto.PublicKey
+}
+
+// parseExtractionParams responds with an error if the query parameters of an extraction request are invalid.
+func parseExtractionParams(w http.ResponseWriter, r *http.Request) (*extractionParams, bool) {
+	params := &extractionParams{
+		redactions: make([]*RedactionPath, 0, len(r.URL.Query()["redact"])),
+	}
+
+	if v := r.URL.Query().Get("include_metadata"); v != "" {
+		var err error
+
+		params.includeMetadata, err = strconv.ParseBool(v)
+		if err != nil {
+			respondErrorf(w, http.StatusBadRequest, "bad request: invalid include_metadata: %s", v)
+
+			return nil, false
+		}
+	}
+
+	for _, expr := range r.URL.Query()["redact"] {
+		path, err := CompileRedactionPath(expr)
+		if err != nil {
+			respondErrorCodef(w, http.StatusBadRequest, model.ErrCodeInvalidJSONPath,
+				"bad request: invalid redact: %s", err.Error())
+
+			return nil, false
+		}
+
+		params.redactions = append(params.redactions, path)
+	}
+
+	// the recipient list can only be empty if the parameter is absent, as extractions are otherwise sent in clear
+	if recipients, ok := r.URL.Query()["recipient"]; ok {
+		var err error
+
+		params.recipients, err = parseRecipients(recipients)
+		if err != nil {
+			respondErrorf(w, http.StatusBadRequest, "bad request: invalid recipient: %s", err.Error())
+
+			return nil, false
+		}
+	}
+
+	return params, true
+}
+
+// newExtraction redacts the document and, if requested, includes its metadata and its location, so that callers
+// can tell which vault and document each result came from.
+func (p *extractionParams) newExtraction(id string, doc interface{}, query *openapi.DocQuery,
+	metadata *openapi.ExtractionMetadata) *openapi.ExtractionResponseItems0 {
+	// values are redacted before leaving the hub
+	for _, path := range p.redactions {
+		doc = path.Redact(doc)
+	}
+
+	extraction := &openapi.ExtractionResponseItems0{
+		ID:       id,
+		Document: doc,
+	}
+
+	if !p.includeMetadata {
+		return extraction
+	}
+
+	extraction.Metadata = metadata
+
+	if query != nil {
+		extraction.VaultID = swag.StringValue(query.VaultID)
+		extraction.DocID = swag.StringValue(query.DocID)
+		extraction.Path = query.Path
+	}
+
+	return extraction
+}
+
 // TODO add support for caveats in zcap: https://github.com/trustbloc/edge-core/issues/134
 // TODO make supported crypto curves configurable: https://github.com/trustbloc/ace/issues/577
-func (o *Operation) newProfileZCAP(profileID, controller string) (*zcapld.Capability, error) {
+func (o *Operation) newProfileZCAP(profileID, controller string, expires *time.Time) (*zcapld2.Capability, error) {
 	identity, err := o.identityConfig()
 	if err != nil {
 		return nil, fmt.Errorf("failed to load identity: %w", err)
@@ -380,7 +929,7 @@ func (o *Operation) newProfileZCAP(profileID, controller string) (*zcapld.Capabi
 		return nil, fmt.Errorf("failed to fetch delegation key id [%s]: %w", identity.DelegationKeyID, err)
 	}
 
-	return zcapld.NewCapability(
+	return zcapld2.NewCapability(
 		&zcapld.Signer{
 			SignatureSuite: jsonwebsignature2020.New(suite.WithSigner(&signer{
 				c:  o.aries.Crypto,
@@ -390,7 +939,8 @@ func (o *Operation) newProfileZCAP(profileID, controller string) (*zcapld.Capabi
 			VerificationMethod: identity.DelegationKeyURL,
 			ProcessorOpts:      []jsonld.ProcessorOpts{jsonld.WithDocumentLoader(o.documentLoader)},
 		},
-		zcapld.WithInvocationTarget(profileID, "urn:confidentialstoragehub:profile"),
+		expires,
+		zcapld.WithInvocationTarget(profileID, profileZCAPType),
 		zcapld.WithID(profileID),
 		zcapld.WithAllowedActions(allActions()...),
 		zcapld.WithController(controller),
@@ -398,6 +948,35 @@ func (o *Operation) newProfileZCAP(profileID, controller string) (*zcapld.Capabi
 	)
 }
 
+// importProfileZCAP returns the root zcap of a profile created by another CSH instance, checking that it was
+// signed with a capability delegation key and that it is invoked by the controller of the profile.
+func (o *Operation) importProfileZCAP(compressed, controller string) (*zcapld2.Capability, error) {
+	zcap, err := zcapld2.DecompressZCAP(compressed)
+	if err != nil {
+		return nil, err
+	}
+
+	if zcap.Invoker != controller {
+		return nil, fmt.Errorf("invoker %s is not the controller %s", zcap.Invoker, controller)
+	}
+
+	if zcap.InvocationTarget.ID != zcap.ID || zcap.InvocationTarget.Type != profileZCAPType {
+		return nil, fmt.Errorf("not the root zcap of a profile: invocation target %s of type %s",
+			zcap.InvocationTarget.ID, zcap.InvocationTarget.Type)
+	}
+
+	if zcap.Expires != nil && !zcap.Expires.After(time.Now()) {
+		return nil, fmt.Errorf("expired at %s", zcap.Expires.Format(time.RFC3339))
+	}
+
+	err = zcapld2.VerifyCapability(zcap, o.supportedSignatureHashAlgorithms(), o.documentLoader)
+	if err != nil {
+		return nil, err
+	}
+
+	return zcap, nil
+}
+
 func (o *Operation) configure(cfg *Config) error {
 	var err error
 
@@ -408,7 +987,7 @@ func (o *Operation) configure(cfg *Config) error {
 
 	identity, err := o.identityConfig()
 	if errors.Is(err, storage.ErrDataNotFound) {
-		identity, err = o.newIdentity()
+		identity, err = o.newIdentity(identityRetry(cfg))
 		if err != nil {
 			return fmt.Errorf("failed to create new identity: %w", err)
 		}
@@ -423,6 +1002,12 @@ func (o *Operation) configure(cfg *Config) error {
 	return err
 }
 
+// SweepDIDCache drops the expired documents from the cache of resolved DID documents every TTL until the context
+// is done.
+func (o *Operation) SweepDIDCache(ctx context.Context) {
+	o.didCache.SweepEvery(ctx, o.didCacheTTL)
+}
+
 // TODO - control concurrency in a cluster.
 func (o *Operation) identityConfig() (*Identity, error) {
 	raw, err := o.storage.config.Get(identityKey)
@@ -435,8 +1020,46 @@ func (o *Operation) identityConfig() (*Identity, error) {
 	return config, json.Unmarshal(raw, config)
 }
 
-func (o *Operation) newIdentity() (*Identity, error) {
-	resolution, err := o.aries.PublicDIDCreator(o.aries.KMS)
+// identityRetry returns the backoff of the retries of the creation of the identity DID at startup.
+func identityRetry(cfg *Config) backoff.BackOff {
+	retries := cfg.IdentityRetries
+
+	switch {
+	case retries < 0:
+		return &backoff.StopBackOff{}
+	case retries == 0:
+		retries = DefaultIdentityRetries
+	}
+
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = cfg.IdentityRetryDelay
+	b.MaxElapsedTime = 0
+
+	if b.InitialInterval == 0 {
+		b.InitialInterval = DefaultIdentityRetryDelay
+	}
+
+	return backoff.WithMaxRetries(b, uint64(retries))
+}
+
+// newIdentity creates a new identity DID, retrying with the backoff as long as the creation fails. Once the retries
+// are exhausted, the error of the last attempt is returned.
+func (o *Operation) newIdentity(retry backoff.BackOff) (*Identity, error) {
+	var resolution *did.DocResolution
+
+	err := backoff.RetryNotify(
+		func() error {
+			var err error
+
+			resolution, err = o.aries.PublicDIDCreator(o.aries.KMS)
+
+			return err
+		},
+		retry,
+		func(err error, delay time.Duration) {
+			logger.Warnf("failed to create identity did, retrying in %s: %s", delay, err)
+		},
+	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create identity did: %w", err)
 	}
@@ -501,6 +1124,11 @@ func initStores(p storage.Provider) (*struct {
 		}
 	}
 
+	err := p.SetStoreConfig(queryStore, storage.StoreConfiguration{TagNames: []string{queryProfileTag}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure %s: %w", queryStore, err)
+	}
+
 	stores.profiles = s[0]
 	stores.zcaps = s[1]
 	stores.queries = s[2]
@@ -529,7 +1157,52 @@ func respond(w http.ResponseWriter, statusCode int, headers map[string]string, p
 	}
 }
 
+// ndjsonWriter writes each entry as a separate JSON line and flushes it to the client right away.
+type ndjsonWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+// WriteHeader is a no-op once the stream has started.
+func (n *ndjsonWriter) WriteHeader(statusCode int) {
+	if n.started {
+		return
+	}
+
+	n.started = true
+	n.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (n *ndjsonWriter) start() {
+	if n.started {
+		return
+	}
+
+	n.Header().Set("Content-Type", ndjsonMediaType)
+	n.WriteHeader(http.StatusOK)
+}
+
+func (n *ndjsonWriter) write(entry interface{}) {
+	n.start()
+
+	err := json.NewEncoder(n).Encode(entry)
+	if err != nil {
+		logger.Errorf("failed to write response entry: %s", err.Error())
+
+		return
+	}
+
+	if f, ok := n.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 func respondErrorf(w http.ResponseWriter, statusCode int, format string, args ...interface{}) {
+	respondErrorCodef(w, statusCode, model.StatusErrorCode(statusCode), format, args...)
+}
+
+// respondErrorCodef responds with an error code more specific than the one of the status code.
+func respondErrorCodef(w http.ResponseWriter, statusCode int, code model.ErrorCode, format string, args ...interface{}) {
 	msg := fmt.Sprintf(format, args...)
 
 	logger.Errorf(msg)
@@ -538,6 +1211,7 @@ func respondErrorf(w http.ResponseWriter, statusCode int, format string, args ..
 	w.WriteHeader(statusCode)
 
 	err := json.NewEncoder(w).Encode(&openapi.Error{
+		Code:       string(code),
 		ErrMessage: msg,
 	})
 	if err != nil {
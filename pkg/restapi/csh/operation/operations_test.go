@@ -8,6 +8,8 @@ package operation_test
 
 import (
 	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -17,16 +19,24 @@ import (
 	"net/url"
 	"path/filepath"
 	"reflect"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/go-openapi/strfmt"
 	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
 	"github.com/hyperledger/aries-framework-go/component/storageutil/mock"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/jsonld"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/suite"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/suite/ed25519signature2018"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/util/kmsdidkey"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/util/signature"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/context"
 	"github.com/hyperledger/aries-framework-go/pkg/kms"
 	mockcrypto "github.com/hyperledger/aries-framework-go/pkg/mock/crypto"
 	mockkms "github.com/hyperledger/aries-framework-go/pkg/mock/kms"
@@ -42,6 +52,8 @@ import (
 	"github.com/trustbloc/ace/pkg/internal/testutil"
 	"github.com/trustbloc/ace/pkg/restapi/csh/operation"
 	openapi "github.com/trustbloc/ace/pkg/restapi/csh/operation/models"
+	zcapld2 "github.com/trustbloc/ace/pkg/restapi/csh/operation/zcapld"
+	"github.com/trustbloc/ace/pkg/restapi/model"
 )
 
 func TestNew(t *testing.T) {
@@ -59,14 +71,71 @@ func TestNew(t *testing.T) {
 		require.ErrorIs(t, err, expected)
 	})
 
+	t.Run("error configuring the query store", func(t *testing.T) {
+		expected := errors.New("test")
+		config := config(t)
+		config.StoreProvider = &storage.MockProvider{
+			Stores: map[string]spi.Store{
+				"profile": &mock.Store{},
+				"zcap":    &mock.Store{},
+				"queries": &mock.Store{},
+				"config":  &mock.Store{},
+			},
+			SetConfigErr: expected,
+		}
+		_, err := operation.New(config)
+		require.ErrorIs(t, err, expected)
+	})
+
 	t.Run("error if cannot create public DID", func(t *testing.T) {
 		expected := errors.New("test")
+		attempts := 0
+		config := config(t)
+		config.IdentityRetries = 2
+		config.IdentityRetryDelay = time.Millisecond
+		config.Aries.PublicDIDCreator = func(kms.KeyManager) (*did.DocResolution, error) {
+			attempts++
+
+			return nil, expected
+		}
+		_, err := operation.New(config)
+		require.ErrorIs(t, err, expected)
+		require.Equal(t, 3, attempts)
+	})
+
+	t.Run("retries the creation of the public DID", func(t *testing.T) {
+		attempts := 0
+		config := config(t)
+		config.IdentityRetryDelay = time.Millisecond
+		createDID := config.Aries.PublicDIDCreator
+		config.Aries.PublicDIDCreator = func(k kms.KeyManager) (*did.DocResolution, error) {
+			attempts++
+
+			if attempts < 3 {
+				return nil, errors.New("orb unavailable")
+			}
+
+			return createDID(k)
+		}
+		o, err := operation.New(config)
+		require.NoError(t, err)
+		require.NotNil(t, o)
+		require.Equal(t, 3, attempts)
+	})
+
+	t.Run("no retry if retries are disabled", func(t *testing.T) {
+		expected := errors.New("test")
+		attempts := 0
 		config := config(t)
+		config.IdentityRetries = -1
 		config.Aries.PublicDIDCreator = func(kms.KeyManager) (*did.DocResolution, error) {
+			attempts++
+
 			return nil, expected
 		}
 		_, err := operation.New(config)
 		require.ErrorIs(t, err, expected)
+		require.Equal(t, 1, attempts)
 	})
 
 	t.Run("error if public DID is missing a required verification method", func(t *testing.T) {
@@ -144,6 +213,99 @@ func TestOperation_CreateProfile(t *testing.T) {
 		require.Equal(t, controller, *response.Controller)
 		require.NotEmpty(t, response.ID)
 		require.NotEmpty(t, response.Zcap)
+
+		expires, err := zcapld2.Expires(response.Zcap)
+		require.NoError(t, err)
+		require.Nil(t, expires)
+	})
+
+	t.Run("creates a profile with an expiry", func(t *testing.T) {
+		expiry := strfmt.DateTime(time.Now().Add(time.Hour).UTC().Truncate(time.Second))
+		o := newOp(t)
+		result := httptest.NewRecorder()
+		o.CreateProfile(result, newReq(t,
+			http.MethodPost,
+			"/profiles",
+			&openapi.Profile{
+				Controller: controller(),
+				Expiry:     &expiry,
+			},
+		))
+		require.Equal(t, http.StatusCreated, result.Code)
+		response := &openapi.Profile{}
+
+		err := json.NewDecoder(result.Body).Decode(response)
+		require.NoError(t, err)
+
+		expires, err := zcapld2.Expires(response.Zcap)
+		require.NoError(t, err)
+		require.NotNil(t, expires)
+		require.True(t, time.Time(expiry).Equal(*expires))
+		require.Equal(t, response.ID, decompressZCAP(t, response.Zcap).ID)
+	})
+
+	t.Run("does not compress zcaps below the compression threshold", func(t *testing.T) {
+		config := config(t)
+		config.ZCAPCompression = []zcapld2.CompressOpt{zcapld2.WithCompressionThreshold(1 << 20)}
+		o, err := operation.New(config)
+		require.NoError(t, err)
+
+		result := httptest.NewRecorder()
+		o.CreateProfile(result, newReq(t,
+			http.MethodPost,
+			"/profiles",
+			&openapi.Profile{
+				Controller: controller(),
+			},
+		))
+		require.Equal(t, http.StatusCreated, result.Code)
+		response := &openapi.Profile{}
+
+		err = json.NewDecoder(result.Body).Decode(response)
+		require.NoError(t, err)
+
+		raw, err := base64.URLEncoding.DecodeString(response.Zcap)
+		require.NoError(t, err)
+		require.True(t, json.Valid(raw))
+
+		zcap, err := zcapld2.DecompressZCAP(response.Zcap)
+		require.NoError(t, err)
+		require.Equal(t, response.ID, zcap.ID)
+	})
+
+	t.Run("err internalservererror if the compression level is invalid", func(t *testing.T) {
+		config := config(t)
+		config.ZCAPCompression = []zcapld2.CompressOpt{zcapld2.WithCompressionLevel(42)}
+		o, err := operation.New(config)
+		require.NoError(t, err)
+
+		result := httptest.NewRecorder()
+		o.CreateProfile(result, newReq(t,
+			http.MethodPost,
+			"/profiles",
+			&openapi.Profile{
+				Controller: controller(),
+			},
+		))
+		require.Equal(t, http.StatusInternalServerError, result.Code)
+		require.Contains(t, result.Body.String(), "failed to compress zcap")
+	})
+
+	t.Run("err badrequest if expiry is not in the future", func(t *testing.T) {
+		expiry := strfmt.DateTime(time.Now().Add(-time.Minute))
+		o := newOp(t)
+		result := httptest.NewRecorder()
+		o.CreateProfile(result, newReq(t,
+			http.MethodPost,
+			"/profiles",
+			&openapi.Profile{
+				Controller: controller(),
+				Expiry:     &expiry,
+			},
+		))
+
+		require.Equal(t, http.StatusBadRequest, result.Code)
+		require.Contains(t, result.Body.String(), "expiry must be in the future")
 	})
 
 	t.Run("err InternalServerError if identity is not configured", func(t *testing.T) {
@@ -175,6 +337,43 @@ func TestOperation_CreateProfile(t *testing.T) {
 		require.Contains(t, result.Body.String(), "failed to load identity")
 	})
 
+	t.Run("creates a profile with upstream auth", func(t *testing.T) {
+		auth := &openapi.ProfileUpstreamAuth{
+			Edv: &openapi.UpstreamAuthorization{BaseURL: "https://edv.example.com", Zcap: "edv-zcap"},
+		}
+
+		result := httptest.NewRecorder()
+		newOp(t).CreateProfile(result, newReq(t,
+			http.MethodPost,
+			"/profiles",
+			&openapi.Profile{
+				Controller:   controller(),
+				UpstreamAuth: auth,
+			},
+		))
+		require.Equal(t, http.StatusCreated, result.Code)
+
+		response := &openapi.Profile{}
+		unmarshal(t, response, result.Body.Bytes())
+		require.Equal(t, auth, response.UpstreamAuth)
+	})
+
+	t.Run("err badrequest if upstream auth has no edv", func(t *testing.T) {
+		result := httptest.NewRecorder()
+		newOp(t).CreateProfile(result, newReq(t,
+			http.MethodPost,
+			"/profiles",
+			&openapi.Profile{
+				Controller: controller(),
+				UpstreamAuth: &openapi.ProfileUpstreamAuth{
+					Kms: &openapi.UpstreamAuthorization{BaseURL: "https://kms.example.com"},
+				},
+			},
+		))
+		require.Equal(t, http.StatusBadRequest, result.Code)
+		require.Contains(t, result.Body.String(), "invalid upstreamAuth: edv is required")
+	})
+
 	t.Run("err badrequest if controller is missing", func(t *testing.T) {
 		o := newOp(t)
 		result := httptest.NewRecorder()
@@ -304,6 +503,111 @@ func TestOperation_CreateProfile(t *testing.T) {
 		)
 		require.NoError(t, err)
 	})
+
+	t.Run("err forbidden if the DID method of the controller is not allowed", func(t *testing.T) {
+		policy, err := zcapld2.NewDIDMethodPolicy(nil, []string{"example"})
+		require.NoError(t, err)
+
+		config := config(t)
+		config.DIDMethods = policy
+
+		result := httptest.NewRecorder()
+		newOperation(t, config).CreateProfile(result, newReq(t,
+			http.MethodPost,
+			"/profiles",
+			&openapi.Profile{
+				Controller: controller(),
+			},
+		))
+
+		require.Equal(t, http.StatusForbidden, result.Code)
+		require.Contains(t, result.Body.String(), "uses did:example")
+	})
+
+	t.Run("imports the root zcap of a profile", func(t *testing.T) {
+		expires := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+		imported := newRootZCAP(t, newAgent(t), *controller(), &expires)
+
+		config := agentConfig(newAgent(t))
+		config.DocumentLoader = testutil.DocumentLoader(t)
+		o := newOperation(t, config)
+
+		create := func() *httptest.ResponseRecorder {
+			result := httptest.NewRecorder()
+			o.CreateProfile(result, newReq(t,
+				http.MethodPost,
+				"/profiles",
+				&openapi.Profile{
+					Controller: &imported.Invoker,
+					Zcap:       compressRootZCAP(t, imported),
+				},
+			))
+
+			return result
+		}
+
+		result := create()
+		require.Equal(t, http.StatusCreated, result.Code, result.Body.String())
+
+		response := &openapi.Profile{}
+		unmarshal(t, response, result.Body.Bytes())
+		require.Equal(t, imported.ID, response.ID)
+		require.True(t, expires.Equal(time.Time(*response.Expiry)))
+		require.Equal(t, imported.ID, decompressZCAP(t, response.Zcap).ID)
+
+		result = create()
+		require.Equal(t, http.StatusConflict, result.Code)
+		require.Contains(t, result.Body.String(), "already exists")
+	})
+
+	t.Run("err badrequest if the imported zcap is invalid", func(t *testing.T) {
+		agent := newAgent(t)
+
+		tampered := newRootZCAP(t, agent, *controller(), nil)
+		tampered.AllowedAction = append(tampered.AllowedAction, "admin")
+
+		otherTarget := newRootZCAP(t, agent, *controller(), nil)
+		otherTarget.InvocationTarget.Type = "urn:hubstore:query"
+
+		unsigned := newRootZCAP(t, agent, *controller(), nil)
+		unsigned.Proof = nil
+
+		valid := newRootZCAP(t, agent, *controller(), nil)
+
+		config := agentConfig(newAgent(t))
+		config.DocumentLoader = testutil.DocumentLoader(t)
+		o := newOperation(t, config)
+
+		for _, tc := range []struct {
+			name       string
+			controller string
+			zcap       string
+			err        string
+		}{
+			{name: "tampered", controller: tampered.Invoker, zcap: compressRootZCAP(t, tampered),
+				err: "invalid signature"},
+			{name: "other target", controller: otherTarget.Invoker, zcap: compressRootZCAP(t, otherTarget),
+				err: "not the root zcap of a profile"},
+			{name: "unsigned", controller: unsigned.Invoker, zcap: compressRootZCAP(t, unsigned),
+				err: "zcap has no proof"},
+			{name: "other controller", controller: *controller(), zcap: compressRootZCAP(t, valid),
+				err: "is not the controller"},
+			{name: "not a zcap", controller: *controller(), zcap: "invalid",
+				err: "failed to decompress zcap"},
+		} {
+			result := httptest.NewRecorder()
+			o.CreateProfile(result, newReq(t,
+				http.MethodPost,
+				"/profiles",
+				&openapi.Profile{
+					Controller: &tc.controller, // nolint:gosec
+					Zcap:       tc.zcap,
+				},
+			))
+			require.Equal(t, http.StatusBadRequest, result.Code, tc.name)
+			require.Contains(t, result.Body.String(), tc.err, tc.name)
+		}
+	})
 }
 
 func TestOperation_CreateQuery(t *testing.T) {
@@ -346,6 +650,42 @@ func TestOperation_CreateQuery(t *testing.T) {
 		require.NotEmpty(t, relative)
 	})
 
+	t.Run("creates a query without upstream auth if its profile has some", func(t *testing.T) {
+		config := config(t)
+		profileID := saveProfile(t, config, &openapi.ProfileUpstreamAuth{
+			Edv: &openapi.UpstreamAuthorization{BaseURL: "https://edv.example.com"},
+		})
+
+		query := docQuery(nil, nil)
+		query.UpstreamAuth = nil
+
+		result := httptest.NewRecorder()
+		newOperation(t, config).CreateQuery(result, mux.SetURLVars(
+			httptest.NewRequest(http.MethodPost, "/test", bytes.NewReader(marshal(t, query))),
+			map[string]string{"profileID": profileID},
+		))
+		require.Equal(t, http.StatusCreated, result.Code)
+	})
+
+	t.Run("error BadRequest if neither the query nor its profile has upstream auth", func(t *testing.T) {
+		config := config(t)
+		profileID := saveProfile(t, config, nil)
+
+		query := docQuery(nil, nil)
+		query.UpstreamAuth = nil
+
+		for _, id := range []string{profileID, uuid.New().URN()} {
+			result := httptest.NewRecorder()
+			newOperation(t, config).CreateQuery(result, mux.SetURLVars(
+				httptest.NewRequest(http.MethodPost, "/test", bytes.NewReader(marshal(t, query))),
+				map[string]string{"profileID": id},
+			))
+			require.Equal(t, http.StatusBadRequest, result.Code)
+			require.Contains(t, result.Body.String(), "upstreamAuth is required")
+			require.Contains(t, result.Body.String(), `"code":"INVALID_REQUEST"`)
+		}
+	})
+
 	t.Run("error BadRequest if request is malformed", func(t *testing.T) {
 		o := newOperation(t, config(t))
 		result := httptest.NewRecorder()
@@ -409,7 +749,8 @@ func TestOperation_CreateQuery(t *testing.T) {
 
 		o.CreateQuery(
 			result,
-			httptest.NewRequest(http.MethodPost, "/test", bytes.NewReader(marshal(t, &openapi.DocQuery{}))),
+			httptest.NewRequest(http.MethodPost, "/test",
+				bytes.NewReader(marshal(t, docQuery(&openapi.UpstreamAuthorization{}, nil)))),
 		)
 
 		require.Equal(t, http.StatusInternalServerError, result.Code)
@@ -417,137 +758,1188 @@ func TestOperation_CreateQuery(t *testing.T) {
 	})
 }
 
-func TestOperation_CreateAuthorization(t *testing.T) {
-	t.Run("TODO - creates an authorization", func(t *testing.T) {
-		o := newOp(t)
+func TestOperation_ListQueries(t *testing.T) {
+	listQueries := func(t *testing.T, o *operation.Operation, profileID, query string) *httptest.ResponseRecorder {
+		t.Helper()
+
 		result := httptest.NewRecorder()
-		o.CreateAuthorization(result, nil)
-		require.Equal(t, http.StatusCreated, result.Code)
-	})
-}
+		o.ListQueries(result, mux.SetURLVars(
+			httptest.NewRequest(http.MethodGet, "/test?"+query, nil),
+			map[string]string{"profileID": profileID},
+		))
 
-func TestOperation_Compare(t *testing.T) {
-	t.Run("equal documents", func(t *testing.T) {
-		doc := randomDoc(t)
-		agent := newAgent(t)
+		return result
+	}
 
-		jwe1 := encryptedJWE(t, agent, doc)
-		jwe2 := encryptedJWE(t, agent, doc)
+	newProfileQuery := func(t *testing.T, o *operation.Operation, profileID string) string {
+		t.Helper()
 
-		config := agentConfig(agent)
-		config.EDVClient = func(string, ...edv.Option) vault.ConfidentialStorageDocReader {
-			return newMockEDVClient(t, nil, jwe1, jwe2)
-		}
+		result := httptest.NewRecorder()
+		o.CreateQuery(result, mux.SetURLVars(
+			httptest.NewRequest(http.MethodPost, "/test",
+				bytes.NewReader(marshal(t, docQuery(&openapi.UpstreamAuthorization{}, nil)))),
+			map[string]string{"profileID": profileID},
+		))
+		require.Equal(t, http.StatusCreated, result.Code)
 
-		payload := marshal(t, map[string]interface{}{
-			"op": newEqOp(t,
-				docQuery(&openapi.UpstreamAuthorization{
-					BaseURL: "https://edv.example.com",
-				}, nil),
-				docQuery(&openapi.UpstreamAuthorization{
-					BaseURL: "https://edv.example.com",
-				}, nil),
-			),
-		})
+		location := result.Header().Get("Location")
 
-		request := httptest.NewRequest(http.MethodPost, "/test", bytes.NewReader(payload))
+		return location[strings.LastIndex(location, "/")+1:]
+	}
 
-		o := newOperation(t, config)
-		result := httptest.NewRecorder()
+	t.Run("lists no queries for a profile without any", func(t *testing.T) {
+		config := config(t)
+		profileID := saveProfile(t, config, nil)
 
-		o.Compare(result, request)
+		result := listQueries(t, newOperation(t, config), profileID, "")
 		require.Equal(t, http.StatusOK, result.Code)
-		requireCompareResult(t, true, result.Body)
+		require.JSONEq(t, `{"queries":[]}`, result.Body.String())
 	})
 
-	t.Run("error BadRequest if cannot parse request", func(t *testing.T) {
-		o := newOperation(t, agentConfig(newAgent(t)))
-		result := httptest.NewRecorder()
+	t.Run("lists the queries of the profile", func(t *testing.T) {
+		config := config(t)
+		profileID := saveProfile(t, config, nil)
+		o := newOperation(t, config)
 
-		request := httptest.NewRequest(http.MethodPost, "/test", bytes.NewReader([]byte("'}")))
+		var expected []string
 
-		o.Compare(result, request)
-		require.Equal(t, http.StatusBadRequest, result.Code)
-		require.Contains(t, result.Body.String(), "bad request")
-	})
-}
+		for i := 0; i < 3; i++ {
+			expected = append(expected, newProfileQuery(t, o, profileID))
+		}
 
-func TestOperation_Extract(t *testing.T) {
-	t.Run("performs an extraction", func(t *testing.T) {
-		doc1 := randomDoc(t)
-		doc2 := randomDoc(t)
-		agent := newAgent(t)
+		// the queries of other profiles are not listed
+		newProfileQuery(t, o, saveProfile(t, config, nil))
 
-		queryID := uuid.New().String()
+		sort.Strings(expected)
 
-		jwe1 := encryptedJWE(t, agent, doc1)
-		jwe2 := encryptedJWE(t, agent, doc2)
+		result := listQueries(t, o, profileID, "")
+		require.Equal(t, http.StatusOK, result.Code)
 
-		edvClient := newMockEDVClient(t, nil, jwe1, jwe2)
+		list := &openapi.QueryList{}
+		require.NoError(t, json.Unmarshal(result.Body.Bytes(), list))
+		require.Empty(t, list.Next)
+		require.Len(t, list.Queries, 3)
 
-		config := agentConfig(agent)
-		config.EDVClient = func(string, ...edv.Option) vault.ConfidentialStorageDocReader {
-			return edvClient
+		for i, q := range list.Queries {
+			require.Equal(t, expected[i], q.ID)
+			require.Nil(t, q.Spec)
 		}
+	})
 
-		queriesStore, err := mem.NewProvider().OpenStore("querystore")
-		require.NoError(t, err)
+	t.Run("includes the specs of the queries", func(t *testing.T) {
+		config := config(t)
+		profileID := saveProfile(t, config, nil)
+		o := newOperation(t, config)
 
-		err = queriesStore.Put(queryID, marshal(t, &operation.Query{
-			ID:        queryID,
-			ProfileID: uuid.New().URN(),
-			Spec: marshal(t, docQuery(&openapi.UpstreamAuthorization{
-				BaseURL: "https://edv.example.com",
-			}, nil)),
-		}))
-		require.NoError(t, err)
+		newProfileQuery(t, o, profileID)
 
-		config.StoreProvider = &storage.MockProvider{
-			Stores: map[string]spi.Store{
-				"profile": &mock.Store{},
-				"zcap":    &mock.Store{},
-				"queries": queriesStore,
+		result := listQueries(t, o, profileID, "includeSpec=true")
+		require.Equal(t, http.StatusOK, result.Code)
+
+		list := &openapi.QueryList{}
+		require.NoError(t, json.Unmarshal(result.Body.Bytes(), list))
+		require.Len(t, list.Queries, 1)
+
+		spec, ok := list.Queries[0].Spec.(map[string]interface{})
+		require.True(t, ok)
+		require.Equal(t, "DocQuery", spec["type"])
+	})
+
+	t.Run("pages through the queries", func(t *testing.T) {
+		config := config(t)
+		profileID := saveProfile(t, config, nil)
+		o := newOperation(t, config)
+
+		var expected []string
+
+		for i := 0; i < 5; i++ {
+			expected = append(expected, newProfileQuery(t, o, profileID))
+		}
+
+		sort.Strings(expected)
+
+		var (
+			listed []string
+			next   string
+		)
+
+		for {
+			result := listQueries(t, o, profileID, "limit=2&next="+next)
+			require.Equal(t, http.StatusOK, result.Code)
+
+			list := &openapi.QueryList{}
+			require.NoError(t, json.Unmarshal(result.Body.Bytes(), list))
+			require.LessOrEqual(t, len(list.Queries), 2)
+
+			for _, q := range list.Queries {
+				listed = append(listed, q.ID)
+			}
+
+			if list.Next == "" {
+				break
+			}
+
+			next = list.Next
+		}
+
+		require.Equal(t, expected, listed)
+	})
+
+	t.Run("error BadRequest if the query is invalid", func(t *testing.T) {
+		config := config(t)
+		profileID := saveProfile(t, config, nil)
+		o := newOperation(t, config)
+
+		for query, msg := range map[string]string{
+			"limit=abc":       "invalid limit",
+			"limit=0":         "invalid limit",
+			"includeSpec=abc": "invalid includeSpec",
+			"next=!":          "invalid continuation token",
+		} {
+			result := listQueries(t, o, profileID, query)
+			require.Equal(t, http.StatusBadRequest, result.Code, query)
+			require.Contains(t, result.Body.String(), msg)
+		}
+	})
+
+	t.Run("error NotFound if the profile does not exist", func(t *testing.T) {
+		result := listQueries(t, newOperation(t, config(t)), uuid.New().URN(), "")
+		require.Equal(t, http.StatusNotFound, result.Code)
+	})
+
+	t.Run("error InternalServerError if cannot list the queries", func(t *testing.T) {
+		config := config(t)
+		config.StoreProvider = &storage.MockProvider{
+			Stores: map[string]spi.Store{
+				"queries": &mock.Store{
+					ErrQuery: errors.New("test error"),
+				},
 				"config": &mock.Store{
 					GetReturn: marshal(t, &operation.Identity{}),
 				},
+				"profile": &mock.Store{
+					GetReturn: marshal(t, &openapi.Profile{}),
+				},
+				"zcap": &mock.Store{},
 			},
 		}
 
-		o := newOperation(t, config)
+		result := listQueries(t, newOperation(t, config), uuid.New().URN(), "")
+		require.Equal(t, http.StatusInternalServerError, result.Code)
+		require.Contains(t, result.Body.String(), "test error")
+	})
+}
 
-		payload := marshal(t, []interface{}{
-			docQuery(&openapi.UpstreamAuthorization{
-				BaseURL: "https://edv.example.com",
-			}, nil),
-			refQuery(queryID),
+func TestOperation_ValidateQuery(t *testing.T) {
+	validate := func(t *testing.T, config *operation.Config, query interface{}) *httptest.ResponseRecorder {
+		t.Helper()
+
+		result := httptest.NewRecorder()
+
+		newOperation(t, config).ValidateQuery(
+			result,
+			httptest.NewRequest(http.MethodPost, "/test", bytes.NewReader(marshal(t, query))),
+		)
+
+		return result
+	}
+
+	requireValidation := func(t *testing.T, result *httptest.ResponseRecorder, diagnostic string) {
+		t.Helper()
+
+		require.Equal(t, http.StatusOK, result.Code)
+
+		validation := &openapi.QueryValidation{}
+		unmarshal(t, validation, result.Body.Bytes())
+
+		require.Equal(t, diagnostic == "", validation.Valid)
+		require.Equal(t, diagnostic, validation.Diagnostic)
+
+		if diagnostic != "" {
+			require.NotEmpty(t, validation.Message)
+		}
+	}
+
+	edvConfig := func(t *testing.T, err error, docs ...[]byte) *operation.Config {
+		t.Helper()
+
+		agent := newAgent(t)
+
+		jwes := make([]*jose.JSONWebEncryption, len(docs))
+		for i := range docs {
+			jwes[i] = encryptedJWE(t, agent, docs[i])
+		}
+
+		edvClient := newMockEDVClient(t, err, jwes...)
+
+		config := agentConfig(agent)
+		config.EDVClient = func(string, ...edv.Option) vault.ConfidentialStorageDocReader {
+			return edvClient
+		}
+
+		return config
+	}
+
+	t.Run("valid query is not persisted", func(t *testing.T) {
+		config := edvConfig(t, nil, randomDoc(t))
+		config.StoreProvider = &storage.MockProvider{
+			Stores: map[string]spi.Store{
+				"queries": &mock.Store{
+					ErrPut: errors.New("test error"),
+				},
+				"config": &mock.Store{
+					GetReturn: marshal(t, &operation.Identity{}),
+				},
+				"profile": &mock.Store{},
+				"zcap":    &mock.Store{},
+			},
+		}
+
+		query := docQuery(&openapi.UpstreamAuthorization{BaseURL: "https://edv.example.com"}, nil)
+		query.Path = "$.content"
+
+		requireValidation(t, validate(t, config, query), "")
+	})
+
+	t.Run("diagnostics", func(t *testing.T) {
+		tests := []struct {
+			name       string
+			edvErr     error
+			path       string
+			diagnostic string
+		}{
+			{
+				name:       "path not found",
+				path:       "$.missing",
+				diagnostic: operation.DiagnosticPathNotFound,
+			},
+			{
+				name:       "invalid path",
+				path:       "$[",
+				diagnostic: operation.DiagnosticInvalidPath,
+			},
+			{
+				name: "document not found",
+				edvErr: errors.New("the EDV server returned status code 404 along with the following message: " +
+					"specified document could not be found."),
+				diagnostic: operation.DiagnosticDocNotFound,
+			},
+			{
+				name: "authorization rejected",
+				edvErr: errors.New("the EDV server returned status code 403 along with the following message: " +
+					"unauthorized"),
+				diagnostic: operation.DiagnosticAuthFailed,
+			},
+			{
+				name:       "other failures",
+				edvErr:     errors.New("connection refused"),
+				diagnostic: operation.DiagnosticFetchFailed,
+			},
+		}
+
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				query := docQuery(&openapi.UpstreamAuthorization{BaseURL: "https://edv.example.com"}, nil)
+				query.Path = tc.path
+
+				requireValidation(t, validate(t, edvConfig(t, tc.edvErr, randomDoc(t)), query), tc.diagnostic)
+			})
+		}
+	})
+
+	t.Run("expired zcap", func(t *testing.T) {
+		agent := newAgent(t)
+
+		query := docQuery(&openapi.UpstreamAuthorization{
+			BaseURL: "https://edv.example.com",
+			Zcap:    compressWithExpiry(t, newZCAP(t, agent, agent), time.Now().Add(-time.Minute)),
+		}, nil)
+
+		requireValidation(t, validate(t, edvConfig(t, nil, randomDoc(t)), query), operation.DiagnosticAuthFailed)
+	})
+
+	t.Run("error BadRequest if request is malformed", func(t *testing.T) {
+		result := httptest.NewRecorder()
+
+		newOperation(t, config(t)).ValidateQuery(
+			result,
+			httptest.NewRequest(http.MethodPost, "/test", bytes.NewReader([]byte("'}"))),
+		)
+
+		require.Equal(t, http.StatusBadRequest, result.Code)
+		require.Contains(t, result.Body.String(), "bad request")
+	})
+
+	t.Run("error BadRequest for RefQuery", func(t *testing.T) {
+		result := validate(t, config(t), refQuery(uuid.New().String()))
+
+		require.Equal(t, http.StatusBadRequest, result.Code)
+		require.Contains(t, result.Body.String(), "query type not allowed")
+	})
+
+	t.Run("falls back to the upstream auth of the profile", func(t *testing.T) {
+		config := edvConfig(t, nil, randomDoc(t))
+		profileID := saveProfile(t, config, &openapi.ProfileUpstreamAuth{
+			Edv: &openapi.UpstreamAuthorization{BaseURL: "https://edv.example.com"},
+		})
+
+		query := docQuery(nil, nil)
+		query.UpstreamAuth = nil
+
+		result := httptest.NewRecorder()
+		newOperation(t, config).ValidateQuery(result, mux.SetURLVars(
+			httptest.NewRequest(http.MethodPost, "/test", bytes.NewReader(marshal(t, query))),
+			map[string]string{"profileID": profileID},
+		))
+
+		requireValidation(t, result, "")
+	})
+
+	t.Run("error BadRequest if DocQuery is incomplete", func(t *testing.T) {
+		result := validate(t, config(t), docQuery(nil, nil))
+
+		require.Equal(t, http.StatusBadRequest, result.Code)
+		require.Contains(t, result.Body.String(), "vaultID, docID and upstreamAuth.edv are required")
+		require.Contains(t, result.Body.String(), `"code":"INVALID_REQUEST"`)
+	})
+}
+
+func TestOperation_CreateAuthorization(t *testing.T) {
+	t.Run("TODO - creates an authorization", func(t *testing.T) {
+		o := newOp(t)
+		result := httptest.NewRecorder()
+		o.CreateAuthorization(result, nil)
+		require.Equal(t, http.StatusCreated, result.Code)
+	})
+}
+
+func TestOperation_Compare(t *testing.T) {
+	t.Run("equal documents", func(t *testing.T) {
+		doc := randomDoc(t)
+		agent := newAgent(t)
+
+		jwe1 := encryptedJWE(t, agent, doc)
+		jwe2 := encryptedJWE(t, agent, doc)
+
+		config := agentConfig(agent)
+		config.EDVClient = func(string, ...edv.Option) vault.ConfidentialStorageDocReader {
+			return newMockEDVClient(t, nil, jwe1, jwe2)
+		}
+
+		payload := marshal(t, map[string]interface{}{
+			"op": newEqOp(t,
+				docQuery(&openapi.UpstreamAuthorization{
+					BaseURL: "https://edv.example.com",
+				}, nil),
+				docQuery(&openapi.UpstreamAuthorization{
+					BaseURL: "https://edv.example.com",
+				}, nil),
+			),
 		})
+
 		request := httptest.NewRequest(http.MethodPost, "/test", bytes.NewReader(payload))
 
+		o := newOperation(t, config)
+		result := httptest.NewRecorder()
+
+		o.Compare(result, request)
+		require.Equal(t, http.StatusOK, result.Code)
+		requireCompareResult(t, true, result.Body)
+	})
+
+	t.Run("DocQuery and RefQuery", func(t *testing.T) {
+		for _, equal := range []bool{true, false} {
+			doc1 := randomDoc(t)
+			doc2 := doc1
+
+			if !equal {
+				doc2 = randomDoc(t)
+			}
+
+			agent := newAgent(t)
+
+			jwe1 := encryptedJWE(t, agent, doc1)
+			jwe2 := encryptedJWE(t, agent, doc2)
+
+			edvClient := newMockEDVClient(t, nil, jwe1, jwe2)
+
+			config := agentConfig(agent)
+			config.EDVClient = func(string, ...edv.Option) vault.ConfidentialStorageDocReader {
+				return edvClient
+			}
+
+			o := newOperation(t, config)
+			ref := createQuery(t, o, docQuery(&openapi.UpstreamAuthorization{BaseURL: "https://edv.example.com"}, nil))
+
+			payload := marshal(t, map[string]interface{}{
+				"op": newEqOp(t,
+					docQuery(&openapi.UpstreamAuthorization{BaseURL: "https://edv.example.com"}, nil),
+					refQuery(ref),
+				),
+			})
+
+			result := httptest.NewRecorder()
+
+			o.Compare(result, httptest.NewRequest(http.MethodPost, "/test", bytes.NewReader(payload)))
+			require.Equal(t, http.StatusOK, result.Code)
+			requireCompareResult(t, equal, result.Body)
+		}
+	})
+
+	t.Run("RefQuery on both sides", func(t *testing.T) {
+		doc := randomDoc(t)
+		agent := newAgent(t)
+
+		jwe1 := encryptedJWE(t, agent, doc)
+		jwe2 := encryptedJWE(t, agent, doc)
+
+		edvClient := newMockEDVClient(t, nil, jwe1, jwe2)
+
+		config := agentConfig(agent)
+		config.EDVClient = func(string, ...edv.Option) vault.ConfidentialStorageDocReader {
+			return edvClient
+		}
+
+		o := newOperation(t, config)
+		ref1 := createQuery(t, o, docQuery(&openapi.UpstreamAuthorization{BaseURL: "https://edv.example.com"}, nil))
+		ref2 := createQuery(t, o, docQuery(&openapi.UpstreamAuthorization{BaseURL: "https://edv.example.com"}, nil))
+
+		payload := marshal(t, map[string]interface{}{
+			"op": newEqOp(t, refQuery(ref1), refQuery(ref2)),
+		})
+
+		result := httptest.NewRecorder()
+
+		o.Compare(result, httptest.NewRequest(http.MethodPost, "/test", bytes.NewReader(payload)))
+		require.Equal(t, http.StatusOK, result.Code)
+		requireCompareResult(t, true, result.Body)
+	})
+
+	t.Run("error BadRequest if cannot parse request", func(t *testing.T) {
+		o := newOperation(t, agentConfig(newAgent(t)))
+		result := httptest.NewRecorder()
+
+		request := httptest.NewRequest(http.MethodPost, "/test", bytes.NewReader([]byte("'}")))
+
+		o.Compare(result, request)
+		require.Equal(t, http.StatusBadRequest, result.Code)
+		require.Contains(t, result.Body.String(), "bad request")
+	})
+
+	t.Run("error Forbidden if a zcap was revoked", func(t *testing.T) {
+		agent := newAgent(t)
+		zcap := newZCAP(t, newAgent(t), agent)
+
+		config := agentConfig(agent)
+		config.Revocations = revocationList{zcap.ID: true}
+		config.EDVClient = func(string, ...edv.Option) vault.ConfidentialStorageDocReader {
+			return newMockEDVClient(t, nil, encryptedJWE(t, agent, randomDoc(t)))
+		}
+
+		o := newOperation(t, config)
+		query := docQuery(&openapi.UpstreamAuthorization{
+			BaseURL: "https://edv.example.com",
+			Zcap:    compress(t, marshal(t, zcap)),
+		}, nil)
+
+		for _, q := range []openapi.Query{query, refQuery(createQuery(t, o, query))} {
+			payload := marshal(t, map[string]interface{}{
+				"op": newEqOp(t, docQuery(&openapi.UpstreamAuthorization{BaseURL: "https://edv.example.com"}, nil), q),
+			})
+
+			result := httptest.NewRecorder()
+
+			o.Compare(result, httptest.NewRequest(http.MethodPost, "/test", bytes.NewReader(payload)))
+			require.Equal(t, http.StatusForbidden, result.Code, result.Body.String())
+			require.Contains(t, result.Body.String(), zcapld2.ErrRevoked.Error())
+		}
+	})
+
+	t.Run("error GatewayTimeout if the deadline expires", func(t *testing.T) {
+		edvURL, _ := slowEDVServer(t)
+
+		config := agentConfig(newAgent(t))
+		config.EDVClient = func(url string, opts ...edv.Option) vault.ConfidentialStorageDocReader {
+			return edv.New(url, opts...)
+		}
+
+		payload := marshal(t, map[string]interface{}{
+			"op": newEqOp(t,
+				docQuery(&openapi.UpstreamAuthorization{BaseURL: edvURL}, nil),
+				docQuery(&openapi.UpstreamAuthorization{BaseURL: edvURL}, nil),
+			),
+		})
+
+		o := newOperation(t, config)
+		result := httptest.NewRecorder()
+
+		o.Compare(result, httptest.NewRequest(http.MethodPost, "/test?deadline=0.05", bytes.NewReader(payload)))
+		require.Equal(t, http.StatusGatewayTimeout, result.Code)
+		require.Contains(t, result.Body.String(), operation.ErrDeadlineExceeded.Error())
+	})
+}
+
+// slowEDVServer returns the URL of an EDV server answering no request until it is canceled, which is reported on
+// the channel.
+func slowEDVServer(t *testing.T) (string, <-chan struct{}) {
+	t.Helper()
+
+	canceled := make(chan struct{}, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			canceled <- struct{}{}
+		case <-time.After(5 * time.Second):
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv.URL, canceled
+}
+
+func TestOperation_Extract(t *testing.T) {
+	t.Run("performs an extraction", func(t *testing.T) {
+		doc1 := randomDoc(t)
+		doc2 := randomDoc(t)
+		agent := newAgent(t)
+
+		queryID := uuid.New().String()
+
+		jwe1 := encryptedJWE(t, agent, doc1)
+		jwe2 := encryptedJWE(t, agent, doc2)
+
+		edvClient := newMockEDVClient(t, nil, jwe1, jwe2)
+
+		config := agentConfig(agent)
+		config.EDVClient = func(string, ...edv.Option) vault.ConfidentialStorageDocReader {
+			return edvClient
+		}
+
+		queriesStore, err := mem.NewProvider().OpenStore("querystore")
+		require.NoError(t, err)
+
+		savedQuery := docQuery(&openapi.UpstreamAuthorization{
+			BaseURL: "https://edv.example.com",
+		}, nil)
+		savedQuery.Path = "$"
+
+		err = queriesStore.Put(queryID, marshal(t, &operation.Query{
+			ID:        queryID,
+			ProfileID: uuid.New().URN(),
+			Spec:      marshal(t, savedQuery),
+		}))
+		require.NoError(t, err)
+
+		config.StoreProvider = &storage.MockProvider{
+			Stores: map[string]spi.Store{
+				"profile": &mock.Store{},
+				"zcap":    &mock.Store{},
+				"queries": queriesStore,
+				"config": &mock.Store{
+					GetReturn: marshal(t, &operation.Identity{}),
+				},
+			},
+		}
+
+		o := newOperation(t, config)
+
+		inlineQuery := docQuery(&openapi.UpstreamAuthorization{
+			BaseURL: "https://edv.example.com",
+		}, nil)
+
+		payload := marshal(t, []interface{}{
+			inlineQuery,
+			refQuery(queryID),
+		})
+		request := httptest.NewRequest(http.MethodPost, "/test", bytes.NewReader(payload))
+
+		result := httptest.NewRecorder()
+		o.Extract(result, request)
+		require.Equal(t, http.StatusOK, result.Code)
+
+		var extractions openapi.ExtractionResponse
+
+		err = json.NewDecoder(result.Body).Decode(&extractions)
+		require.NoError(t, err)
+		require.Len(t, extractions, 2)
+
+		// the location of the documents is metadata, only included if requested
+		for _, extraction := range extractions {
+			require.Empty(t, extraction.VaultID)
+			require.Empty(t, extraction.DocID)
+			require.Empty(t, extraction.Path)
+			require.Nil(t, extraction.Metadata)
+		}
+
+		require.NotContains(t, result.Body.String(), *inlineQuery.VaultID)
+		require.NotContains(t, result.Body.String(), *savedQuery.VaultID)
+
+		for _, doc := range [][]byte{doc1, doc2} {
+			d := &models.StructuredDocument{}
+
+			unmarshal(t, d, doc)
+
+			found := false
+
+			for _, extract := range extractions {
+				found = reflect.DeepEqual(d.Content, extract.Document)
+				if found {
+					break
+				}
+			}
+
+			require.True(t, found)
+		}
+	})
+
+	t.Run("extracts queries sharing an upstream authorization", func(t *testing.T) {
+		agent := newAgent(t)
+		edvServer := newAgent(t)
+
+		docs := [][]byte{randomDoc(t), randomDoc(t), randomDoc(t)}
+
+		edvClient := newMockEDVClient(t, nil,
+			encryptedJWE(t, agent, docs[0]), encryptedJWE(t, agent, docs[1]), encryptedJWE(t, agent, docs[2]))
+
+		config := agentConfig(agent)
+		config.EDVClient = func(string, ...edv.Option) vault.ConfidentialStorageDocReader {
+			return edvClient
+		}
+
+		edvAuth := &openapi.UpstreamAuthorization{
+			BaseURL: "https://edv.example.com",
+			Zcap:    compressWithExpiry(t, newZCAP(t, edvServer, agent), time.Now().Add(time.Hour)),
+		}
+
+		queries := make([]interface{}, len(docs))
+
+		for i := range queries {
+			query := docQuery(edvAuth, nil)
+			query.Path = "$"
+			queries[i] = query
+		}
+
+		request := httptest.NewRequest(http.MethodPost, "/test", bytes.NewReader(marshal(t, queries)))
+		result := httptest.NewRecorder()
+
+		o := newOperation(t, config)
+		o.Extract(result, request)
+		require.Equal(t, http.StatusOK, result.Code, result.Body.String())
+
+		var extractions openapi.ExtractionResponse
+
+		require.NoError(t, json.NewDecoder(result.Body).Decode(&extractions))
+		require.Len(t, extractions, len(docs))
+
+		expected := make([]interface{}, len(docs))
+		extracted := make([]interface{}, len(extractions))
+
+		for i := range docs {
+			d := &models.StructuredDocument{}
+
+			unmarshal(t, d, docs[i])
+
+			expected[i] = d.Content
+			extracted[i] = extractions[i].Document
+		}
+
+		require.ElementsMatch(t, expected, extracted)
+	})
+
+	t.Run("includes the document metadata if requested", func(t *testing.T) {
+		agent := newAgent(t)
+
+		edvClient := newMockEDVClient(t, nil, encryptedJWE(t, agent, randomDoc(t)))
+		edvClient.docs[0].Sequence = 7
+
+		config := agentConfig(agent)
+		config.EDVClient = func(string, ...edv.Option) vault.ConfidentialStorageDocReader {
+			return edvClient
+		}
+
+		query := docQuery(&openapi.UpstreamAuthorization{
+			BaseURL: "https://edv.example.com/encrypted-data-vaults/",
+		}, nil)
+		query.Path = "$"
+
+		request := httptest.NewRequest(http.MethodPost, "/test?include_metadata=true",
+			bytes.NewReader(marshal(t, []interface{}{query})))
+		result := httptest.NewRecorder()
+
+		o := newOperation(t, config)
+		o.Extract(result, request)
+		require.Equal(t, http.StatusOK, result.Code)
+		require.NotContains(t, result.Body.String(), "jwe")
+
+		var extractions openapi.ExtractionResponse
+
+		require.NoError(t, json.NewDecoder(result.Body).Decode(&extractions))
+		require.Len(t, extractions, 1)
+		require.Equal(t, *query.VaultID, extractions[0].VaultID)
+		require.Equal(t, *query.DocID, extractions[0].DocID)
+		require.Equal(t, query.Path, extractions[0].Path)
+		require.Equal(t, &openapi.ExtractionMetadata{
+			EdvDocURI: fmt.Sprintf("https://edv.example.com/encrypted-data-vaults/%s/documents/%s",
+				url.PathEscape(*query.VaultID), url.PathEscape(*query.DocID)),
+			Sequence: 7,
+		}, extractions[0].Metadata)
+	})
+
+	t.Run("redacts the extracted documents", func(t *testing.T) {
+		agent := newAgent(t)
+
+		doc, err := json.Marshal(&models.StructuredDocument{
+			ID: uuid.New().String(),
+			Content: map[string]interface{}{
+				"name": "Alice",
+				"ssn":  "123-45-6789",
+				"address": map[string]interface{}{
+					"city":   "Toronto",
+					"street": "1 Main St",
+				},
+			},
+		})
+		require.NoError(t, err)
+
+		config := agentConfig(agent)
+		config.EDVClient = func(string, ...edv.Option) vault.ConfidentialStorageDocReader {
+			return newMockEDVClient(t, nil, encryptedJWE(t, agent, doc))
+		}
+
+		request := httptest.NewRequest(http.MethodPost,
+			"/test?redact=$.ssn&redact=$.address.street&redact=$.missing.field",
+			bytes.NewReader(marshal(t, []interface{}{docQuery(&openapi.UpstreamAuthorization{}, nil)})))
+		result := httptest.NewRecorder()
+
+		o := newOperation(t, config)
+		o.Extract(result, request)
+		require.Equal(t, http.StatusOK, result.Code)
+		require.NotContains(t, result.Body.String(), "123-45-6789")
+		require.NotContains(t, result.Body.String(), "1 Main St")
+
+		var extractions openapi.ExtractionResponse
+
+		require.NoError(t, json.NewDecoder(result.Body).Decode(&extractions))
+		require.Len(t, extractions, 1)
+		require.Equal(t, map[string]interface{}{
+			"name":    "Alice",
+			"address": map[string]interface{}{"city": "Toronto"},
+		}, extractions[0].Document)
+	})
+
+	t.Run("never extracts denied paths", func(t *testing.T) {
+		agent := newAgent(t)
+
+		doc, err := json.Marshal(&models.StructuredDocument{
+			ID: uuid.New().String(),
+			Content: map[string]interface{}{
+				"name": "Alice",
+				"ssn":  "123-45-6789",
+				"cards": []interface{}{
+					map[string]interface{}{"type": "visa", "number": "4111111111111111"},
+				},
+			},
+		})
+		require.NoError(t, err)
+
+		extract := func(t *testing.T, mode operation.DenyMode, path string) *httptest.ResponseRecorder {
+			t.Helper()
+
+			denyList, err := operation.NewDenyList([]string{"$.ssn", "$.cards[*].number"}, mode)
+			require.NoError(t, err)
+
+			config := agentConfig(agent)
+			config.DenyList = denyList
+			config.EDVClient = func(string, ...edv.Option) vault.ConfidentialStorageDocReader {
+				return newMockEDVClient(t, nil, encryptedJWE(t, agent, doc))
+			}
+
+			query := docQuery(&openapi.UpstreamAuthorization{}, nil)
+			query.Path = path
+
+			request := httptest.NewRequest(http.MethodPost, "/test",
+				bytes.NewReader(marshal(t, []interface{}{query})))
+			result := httptest.NewRecorder()
+
+			newOperation(t, config).Extract(result, request)
+
+			return result
+		}
+
+		t.Run("strip", func(t *testing.T) {
+			result := extract(t, operation.DenyModeStrip, "")
+			require.Equal(t, http.StatusOK, result.Code, result.Body.String())
+
+			var extractions openapi.ExtractionResponse
+
+			require.NoError(t, json.NewDecoder(result.Body).Decode(&extractions))
+			require.Len(t, extractions, 1)
+			require.Equal(t, map[string]interface{}{
+				"name":  "Alice",
+				"cards": []interface{}{map[string]interface{}{"type": "visa"}},
+			}, extractions[0].Document)
+
+			result = extract(t, operation.DenyModeStrip, "$.ssn")
+			require.Equal(t, http.StatusBadRequest, result.Code)
+			require.NotContains(t, result.Body.String(), "123-45-6789")
+
+			result = extract(t, operation.DenyModeStrip, `$.cards[?(@.number == "4111111111111111")].type`)
+			require.Equal(t, http.StatusOK, result.Code, result.Body.String())
+			require.NotContains(t, result.Body.String(), "visa")
+		})
+
+		t.Run("refuse", func(t *testing.T) {
+			for _, path := range []string{
+				"", "$.ssn", "$.cards", `$.cards[?(@.number == "4111111111111111")].type`,
+			} {
+				result := extract(t, operation.DenyModeRefuse, path)
+				require.Equal(t, http.StatusForbidden, result.Code, path)
+				require.Contains(t, result.Body.String(), string(model.ErrCodeForbidden), path)
+				require.NotContains(t, result.Body.String(), "123-45-6789", path)
+				require.NotContains(t, result.Body.String(), "4111111111111111", path)
+			}
+
+			result := extract(t, operation.DenyModeRefuse, "$.name")
+			require.Equal(t, http.StatusOK, result.Code, result.Body.String())
+
+			var extractions openapi.ExtractionResponse
+
+			require.NoError(t, json.NewDecoder(result.Body).Decode(&extractions))
+			require.Len(t, extractions, 1)
+			require.Equal(t, "Alice", extractions[0].Document)
+		})
+	})
+
+	t.Run("signs the extractions along with the nonce", func(t *testing.T) {
+		agent := newAgent(t)
+
+		keyID, pubKey, err := agent.KMS().CreateAndExportPubKeyBytes(kms.ED25519Type)
+		require.NoError(t, err)
+
+		config := agentConfig(agent)
+		config.EDVClient = func(string, ...edv.Option) vault.ConfidentialStorageDocReader {
+			return newMockEDVClient(t, nil, encryptedJWE(t, agent, randomDoc(t)))
+		}
+
+		// the identity authenticates with a key of the KMS
+		createDID := config.Aries.PublicDIDCreator
+		config.Aries.PublicDIDCreator = func(k kms.KeyManager) (*did.DocResolution, error) {
+			resolution, err := createDID(k)
+			require.NoError(t, err)
+
+			resolution.DIDDocument.Authentication[0].VerificationMethod.ID = "did:example:123#" + keyID
+
+			return resolution, nil
+		}
+
+		request := httptest.NewRequest(http.MethodPost, "/test?nonce=n-0S6_WzA2Mj",
+			bytes.NewReader(marshal(t, []interface{}{docQuery(&openapi.UpstreamAuthorization{}, nil)})))
+		result := httptest.NewRecorder()
+
+		o := newOperation(t, config)
+		o.Extract(result, request)
+		require.Equal(t, http.StatusOK, result.Code)
+
+		var signed operation.SignedExtraction
+
+		require.NoError(t, json.NewDecoder(result.Body).Decode(&signed))
+
+		jws, err := jose.ParseJWS(signed.JWS, jose.SignatureVerifierFunc(
+			func(headers jose.Headers, _, signingInput, signature []byte) error {
+				kid, _ := headers.KeyID()
+				require.Equal(t, "did:example:123#"+keyID, kid)
+
+				alg, _ := headers.Algorithm()
+				require.Equal(t, "EdDSA", alg)
+
+				if !ed25519.Verify(pubKey, signingInput, signature) {
+					return errors.New("invalid signature")
+				}
+
+				return nil
+			}))
+		require.NoError(t, err)
+
+		var payload operation.SignedExtractionPayload
+
+		require.NoError(t, json.Unmarshal(jws.Payload, &payload))
+		require.Equal(t, "n-0S6_WzA2Mj", payload.Nonce)
+		require.Len(t, payload.Extractions, 1)
+
+		// the signature covers the nonce
+		parts := strings.Split(signed.JWS, ".")
+		tampered := bytes.Replace(jws.Payload, []byte("n-0S6_WzA2Mj"), []byte("another"), 1)
+		parts[1] = base64.RawURLEncoding.EncodeToString(tampered)
+
+		_, err = jose.ParseJWS(strings.Join(parts, "."), jose.SignatureVerifierFunc(
+			func(_ jose.Headers, _, signingInput, signature []byte) error {
+				if !ed25519.Verify(pubKey, signingInput, signature) {
+					return errors.New("invalid signature")
+				}
+
+				return nil
+			}))
+		require.Error(t, err)
+	})
+
+	t.Run("error if the extractions cannot be signed", func(t *testing.T) {
+		agent := newAgent(t)
+
+		config := agentConfig(agent)
+		config.EDVClient = func(string, ...edv.Option) vault.ConfidentialStorageDocReader {
+			return newMockEDVClient(t, nil, encryptedJWE(t, agent, randomDoc(t)))
+		}
+
+		request := httptest.NewRequest(http.MethodPost, "/test?nonce=123",
+			bytes.NewReader(marshal(t, []interface{}{docQuery(&openapi.UpstreamAuthorization{}, nil)})))
+		result := httptest.NewRecorder()
+
+		// the authentication key of the identity is not in the KMS
+		o := newOperation(t, config)
+		o.Extract(result, request)
+		require.Equal(t, http.StatusInternalServerError, result.Code)
+		require.Contains(t, result.Body.String(), "failed to sign extractions")
+	})
+
+	t.Run("error if streamed extractions are requested with a nonce", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodPost, "/test?nonce=123",
+			bytes.NewReader(marshal(t, []interface{}{docQuery(&openapi.UpstreamAuthorization{}, nil)})))
+		request.Header.Set("Accept", "application/x-ndjson")
+
+		result := httptest.NewRecorder()
+
+		newOp(t).Extract(result, request)
+		require.Equal(t, http.StatusBadRequest, result.Code)
+		require.Contains(t, result.Body.String(), "cannot be signed with a nonce")
+	})
+
+	t.Run("encrypts the extractions to all the recipients", func(t *testing.T) {
+		agent := newAgent(t)
+
+		config := agentConfig(agent)
+		config.EDVClient = func(string, ...edv.Option) vault.ConfidentialStorageDocReader {
+			return newMockEDVClient(t, nil, encryptedJWE(t, agent, randomDoc(t)))
+		}
+
+		// each recipient holds its key in its own KMS
+		alice, aliceDID := newRecipient(t, kms.X25519ECDHKWType)
+		bob, bobDID := newRecipient(t, kms.NISTP256ECDHKWType)
+
+		request := httptest.NewRequest(http.MethodPost,
+			"/test?recipient="+url.QueryEscape(aliceDID)+"&recipient="+url.QueryEscape(bobDID),
+			bytes.NewReader(marshal(t, []interface{}{docQuery(&openapi.UpstreamAuthorization{}, nil)})))
+		result := httptest.NewRecorder()
+
+		newOperation(t, config).Extract(result, request)
+		require.Equal(t, http.StatusOK, result.Code, result.Body.String())
+
+		var encrypted operation.EncryptedExtraction
+
+		require.NoError(t, json.NewDecoder(result.Body).Decode(&encrypted))
+
+		for _, recipient := range []*context.Provider{alice, bob} {
+			jwe, err := jose.Deserialize(encrypted.JWE)
+			require.NoError(t, err)
+			require.Len(t, jwe.Recipients, 2)
+
+			plaintext, err := jose.NewJWEDecrypt(nil, recipient.Crypto(), recipient.KMS()).Decrypt(jwe)
+			require.NoError(t, err)
+
+			var extractions openapi.ExtractionResponse
+
+			require.NoError(t, json.Unmarshal(plaintext, &extractions))
+			require.Len(t, extractions, 1)
+			require.NotNil(t, extractions[0].Document)
+		}
+
+		// a key that is not a recipient cannot decrypt the extractions
+		eve, _ := newRecipient(t, kms.X25519ECDHKWType)
+
+		jwe, err := jose.Deserialize(encrypted.JWE)
+		require.NoError(t, err)
+
+		_, err = jose.NewJWEDecrypt(nil, eve.Crypto(), eve.KMS()).Decrypt(jwe)
+		require.Error(t, err)
+	})
+
+	t.Run("error BadRequest if the recipients are invalid", func(t *testing.T) {
+		_, ed25519DID := newRecipient(t, kms.ED25519Type)
+
+		for _, query := range []string{
+			"recipient=",
+			"recipient=did:example:123",
+			"recipient=" + url.QueryEscape(ed25519DID),
+		} {
+			request := httptest.NewRequest(http.MethodPost, "/test?"+query,
+				bytes.NewReader(marshal(t, []interface{}{docQuery(&openapi.UpstreamAuthorization{}, nil)})))
+			result := httptest.NewRecorder()
+
+			newOp(t).Extract(result, request)
+			require.Equal(t, http.StatusBadRequest, result.Code, query)
+			require.Contains(t, result.Body.String(), "invalid recipient", query)
+		}
+	})
+
+	t.Run("error if streamed extractions are requested with recipients", func(t *testing.T) {
+		_, recipient := newRecipient(t, kms.X25519ECDHKWType)
+
+		request := httptest.NewRequest(http.MethodPost, "/test?recipient="+url.QueryEscape(recipient),
+			bytes.NewReader(marshal(t, []interface{}{docQuery(&openapi.UpstreamAuthorization{}, nil)})))
+		request.Header.Set("Accept", "application/x-ndjson")
+
+		result := httptest.NewRecorder()
+
+		newOp(t).Extract(result, request)
+		require.Equal(t, http.StatusBadRequest, result.Code)
+		require.Contains(t, result.Body.String(), "cannot be encrypted")
+	})
+
+	t.Run("extracts large integers exactly with exact numbers", func(t *testing.T) {
+		agent := newAgent(t)
+
+		doc := []byte(`{"id":"doc","content":{"serial":12345678901234567891,"ratio":0.1}}`)
+
+		config := agentConfig(agent)
+		config.ExactNumbers = true
+		config.EDVClient = func(string, ...edv.Option) vault.ConfidentialStorageDocReader {
+			return newMockEDVClient(t, nil, encryptedJWE(t, agent, doc))
+		}
+
+		query := docQuery(&openapi.UpstreamAuthorization{}, nil)
+		query.Path = "$.serial"
+
+		request := httptest.NewRequest(http.MethodPost, "/test", bytes.NewReader(marshal(t, []interface{}{query})))
+		result := httptest.NewRecorder()
+
+		o := newOperation(t, config)
+		o.Extract(result, request)
+		require.Equal(t, http.StatusOK, result.Code)
+		require.Contains(t, result.Body.String(), `"document":12345678901234567891`)
+	})
+
+	t.Run("streams the extractions as NDJSON if requested", func(t *testing.T) {
+		agent := newAgent(t)
+		docs := [][]byte{randomDoc(t), randomDoc(t)}
+		result := httptest.NewRecorder()
+		reads := 0
+
+		edvClient := &onReadEDVClient{
+			mockEDVClient: newMockEDVClient(t, nil, encryptedJWE(t, agent, docs[0]), encryptedJWE(t, agent, docs[1])),
+			onRead: func() {
+				// the first extraction must already be on the wire when the second document is fetched
+				if reads == 1 {
+					require.True(t, result.Flushed)
+					require.Equal(t, 1, bytes.Count(result.Body.Bytes(), []byte("\n")))
+				}
+
+				reads++
+			},
+		}
+
+		config := agentConfig(agent)
+		config.EDVClient = func(string, ...edv.Option) vault.ConfidentialStorageDocReader {
+			return edvClient
+		}
+
+		request := httptest.NewRequest(http.MethodPost, "/test", bytes.NewReader(marshal(t, []interface{}{
+			docQuery(&openapi.UpstreamAuthorization{}, nil), docQuery(&openapi.UpstreamAuthorization{}, nil),
+		})))
+		request.Header.Set("Accept", "application/x-ndjson")
+
+		o := newOperation(t, config)
+		o.Extract(result, request)
+		require.Equal(t, http.StatusOK, result.Code)
+		require.Equal(t, "application/x-ndjson", result.Header().Get("Content-Type"))
+		require.Equal(t, 2, reads)
+
+		decoder := json.NewDecoder(result.Body)
+
+		for i := range docs {
+			expected := &models.StructuredDocument{}
+			unmarshal(t, expected, docs[i])
+
+			extraction := &openapi.ExtractionResponseItems0{}
+
+			require.NoError(t, decoder.Decode(extraction))
+			require.Equal(t, expected.Content, extraction.Document)
+		}
+
+		require.False(t, decoder.More())
+	})
+
+	t.Run("reports errors in-band once streaming has started", func(t *testing.T) {
+		agent := newAgent(t)
+		edvClient := newMockEDVClient(t, nil, encryptedJWE(t, agent, randomDoc(t)))
+
+		config := agentConfig(agent)
+		config.EDVClient = func(string, ...edv.Option) vault.ConfidentialStorageDocReader {
+			return edvClient
+		}
+
+		request := httptest.NewRequest(http.MethodPost, "/test", bytes.NewReader(marshal(t, []interface{}{
+			docQuery(&openapi.UpstreamAuthorization{}, nil), docQuery(&openapi.UpstreamAuthorization{}, nil),
+		})))
+		request.Header.Set("Accept", "application/x-ndjson")
+		result := httptest.NewRecorder()
+
+		o := newOperation(t, config)
+		o.Extract(result, request)
+		require.Equal(t, http.StatusOK, result.Code)
+
+		decoder := json.NewDecoder(result.Body)
+
+		require.NoError(t, decoder.Decode(&openapi.ExtractionResponseItems0{}))
+
+		errMsg := &openapi.Error{}
+
+		require.NoError(t, decoder.Decode(errMsg))
+		require.Contains(t, errMsg.ErrMessage, "docs exhausted")
+	})
+
+	t.Run("error InternalServerError if the first document cannot be streamed", func(t *testing.T) {
+		config := agentConfig(newAgent(t))
+		config.EDVClient = func(string, ...edv.Option) vault.ConfidentialStorageDocReader {
+			return newMockEDVClient(t, errors.New("test error"))
+		}
+
+		request := httptest.NewRequest(http.MethodPost, "/test", bytes.NewReader(marshal(t, []interface{}{
+			docQuery(&openapi.UpstreamAuthorization{}, nil),
+		})))
+		request.Header.Set("Accept", "application/x-ndjson")
 		result := httptest.NewRecorder()
-		o.Extract(result, request)
-		require.Equal(t, http.StatusOK, result.Code)
 
-		var extractions openapi.ExtractionResponse
+		o := newOperation(t, config)
+		o.Extract(result, request)
+		require.Equal(t, http.StatusInternalServerError, result.Code)
+		require.Equal(t, "application/json", result.Header().Get("Content-Type"))
+		require.Contains(t, result.Body.String(), "test error")
+	})
 
-		err = json.NewDecoder(result.Body).Decode(&extractions)
-		require.NoError(t, err)
+	t.Run("error BadRequest if include_metadata is invalid", func(t *testing.T) {
+		o := newOperation(t, agentConfig(newAgent(t)))
+		result := httptest.NewRecorder()
 
-		for _, doc := range [][]byte{doc1, doc2} {
-			d := &models.StructuredDocument{}
+		request := httptest.NewRequest(http.MethodPost, "/test?include_metadata=maybe",
+			bytes.NewReader(marshal(t, []interface{}{docQuery(&openapi.UpstreamAuthorization{}, nil)})))
 
-			unmarshal(t, d, doc)
+		o.Extract(result, request)
+		require.Equal(t, http.StatusBadRequest, result.Code)
+		require.Contains(t, result.Body.String(), "invalid include_metadata")
+	})
 
-			found := false
+	t.Run("error BadRequest if a redact path is invalid", func(t *testing.T) {
+		o := newOperation(t, agentConfig(newAgent(t)))
+		result := httptest.NewRecorder()
 
-			for _, extract := range extractions {
-				found = reflect.DeepEqual(d.Content, extract.Document)
-				if found {
-					break
-				}
-			}
+		request := httptest.NewRequest(http.MethodPost, "/test?redact=$..ssn",
+			bytes.NewReader(marshal(t, []interface{}{docQuery(&openapi.UpstreamAuthorization{}, nil)})))
 
-			require.True(t, found)
-		}
+		o.Extract(result, request)
+		require.Equal(t, http.StatusBadRequest, result.Code)
+		require.Contains(t, result.Body.String(), "invalid redact")
+		require.Contains(t, result.Body.String(), string(model.ErrCodeInvalidJSONPath))
 	})
 
 	t.Run("error BadRequest if request is malformed", func(t *testing.T) {
@@ -580,6 +1972,348 @@ func TestOperation_Extract(t *testing.T) {
 		require.Contains(t, result.Body.String(), expected.Error())
 	})
 
+	t.Run("error ServiceUnavailable if the EDV server is still unavailable", func(t *testing.T) {
+		expected := errors.New("the EDV server returned status code 503 along with the following message: " +
+			"service unavailable")
+		config := agentConfig(newAgent(t))
+		config.EDVClient = func(string, ...edv.Option) vault.ConfidentialStorageDocReader {
+			return newMockEDVClient(t, expected)
+		}
+
+		request := httptest.NewRequest(http.MethodPost, "/test", bytes.NewReader(marshal(t, []interface{}{
+			docQuery(&openapi.UpstreamAuthorization{}, nil),
+		})))
+		result := httptest.NewRecorder()
+
+		o := newOperation(t, config)
+		o.Extract(result, request)
+
+		require.Equal(t, http.StatusServiceUnavailable, result.Code)
+		require.Contains(t, result.Body.String(), "status code 503")
+	})
+
+	t.Run("error GatewayTimeout if the deadline expires", func(t *testing.T) {
+		edvURL, canceled := slowEDVServer(t)
+
+		config := agentConfig(newAgent(t))
+		config.EDVClient = func(url string, opts ...edv.Option) vault.ConfidentialStorageDocReader {
+			return edv.New(url, opts...)
+		}
+
+		request := httptest.NewRequest(http.MethodPost, "/test?deadline=0.05", bytes.NewReader(marshal(t, []interface{}{
+			docQuery(&openapi.UpstreamAuthorization{BaseURL: edvURL}, nil),
+		})))
+		result := httptest.NewRecorder()
+
+		start := time.Now()
+
+		o := newOperation(t, config)
+		o.Extract(result, request)
+
+		require.Equal(t, http.StatusGatewayTimeout, result.Code)
+		require.Less(t, int64(time.Since(start)), int64(time.Second))
+
+		var errResp *model.ErrorResponse
+
+		require.NoError(t, json.NewDecoder(result.Body).Decode(&errResp))
+		require.Equal(t, model.ErrCodeDeadlineExceeded, errResp.Code)
+		require.Contains(t, errResp.Message, operation.ErrDeadlineExceeded.Error())
+
+		select {
+		case <-canceled:
+		case <-time.After(time.Second):
+			require.Fail(t, "the EDV request was not canceled")
+		}
+	})
+
+	t.Run("error BadRequest if the deadline is invalid", func(t *testing.T) {
+		for _, deadline := range []string{"soon", "0", "-1"} {
+			request := httptest.NewRequest(http.MethodPost, "/test?deadline="+deadline, bytes.NewReader(marshal(t,
+				[]interface{}{docQuery(&openapi.UpstreamAuthorization{}, nil)})))
+			result := httptest.NewRecorder()
+
+			o := newOperation(t, agentConfig(newAgent(t)))
+			o.Extract(result, request)
+
+			require.Equal(t, http.StatusBadRequest, result.Code, deadline)
+			require.Contains(t, result.Body.String(), "invalid deadline", deadline)
+		}
+	})
+
+	t.Run("error Forbidden if zcap has expired", func(t *testing.T) {
+		agent := newAgent(t)
+		config := agentConfig(agent)
+		config.EDVClient = func(string, ...edv.Option) vault.ConfidentialStorageDocReader {
+			require.FailNow(t, "EDV must not be invoked with an expired zcap")
+
+			return nil
+		}
+
+		request := httptest.NewRequest(http.MethodPost, "/test", bytes.NewReader(marshal(t, []interface{}{
+			docQuery(&openapi.UpstreamAuthorization{
+				BaseURL: "https://edv.example.com",
+				Zcap:    compressWithExpiry(t, newZCAP(t, newAgent(t), agent), time.Now().Add(-time.Minute)),
+			}, nil),
+		})))
+		result := httptest.NewRecorder()
+
+		o := newOperation(t, config)
+		o.Extract(result, request)
+
+		require.Equal(t, http.StatusForbidden, result.Code)
+		require.Contains(t, result.Body.String(), "zcap has expired")
+	})
+
+	t.Run("error Forbidden if the DID method of the zcap invoker is not allowed", func(t *testing.T) {
+		for method, allowed := range map[string]bool{"key": true, "orb": false} {
+			agent := newAgent(t)
+			invoked := false
+
+			policy, err := zcapld2.NewDIDMethodPolicy([]string{method}, nil)
+			require.NoError(t, err)
+
+			config := agentConfig(agent)
+			config.DIDMethods = policy
+			config.EDVClient = func(string, ...edv.Option) vault.ConfidentialStorageDocReader {
+				invoked = true
+
+				return newMockEDVClient(t, nil, encryptedJWE(t, agent, randomDoc(t)))
+			}
+
+			request := httptest.NewRequest(http.MethodPost, "/test", bytes.NewReader(marshal(t, []interface{}{
+				docQuery(&openapi.UpstreamAuthorization{
+					BaseURL: "https://edv.example.com",
+					Zcap:    compress(t, marshal(t, newZCAP(t, newAgent(t), agent))),
+				}, nil),
+			})))
+			result := httptest.NewRecorder()
+
+			newOperation(t, config).Extract(result, request)
+			require.Equal(t, allowed, invoked, method)
+
+			if allowed {
+				require.Equal(t, http.StatusOK, result.Code, result.Body.String())
+
+				continue
+			}
+
+			require.Equal(t, http.StatusForbidden, result.Code)
+
+			var errResp *model.ErrorResponse
+
+			require.NoError(t, json.NewDecoder(result.Body).Decode(&errResp))
+			require.Equal(t, model.ErrCodeForbidden, errResp.Code)
+			require.Contains(t, errResp.Message, zcapld2.ErrDIDMethodNotAllowed.Error())
+		}
+	})
+
+	t.Run("error Forbidden if the zcap was delegated more times than allowed", func(t *testing.T) {
+		for depth, allowed := range map[int]bool{
+			zcapld2.DefaultMaxChainDepth:     true,
+			zcapld2.DefaultMaxChainDepth + 1: false,
+		} {
+			agent := newAgent(t)
+			invoked := false
+
+			config := agentConfig(agent)
+			config.EDVClient = func(string, ...edv.Option) vault.ConfidentialStorageDocReader {
+				invoked = true
+
+				return newMockEDVClient(t, nil, encryptedJWE(t, agent, randomDoc(t)))
+			}
+
+			zcap := newZCAP(t, newAgent(t), agent)
+			chain := make([]interface{}, depth)
+
+			for i := range chain {
+				chain[i] = uuid.New().String()
+			}
+
+			zcap.Proof[0]["capabilityChain"] = chain
+
+			request := httptest.NewRequest(http.MethodPost, "/test", bytes.NewReader(marshal(t, []interface{}{
+				docQuery(&openapi.UpstreamAuthorization{
+					BaseURL: "https://edv.example.com",
+					Zcap:    compress(t, marshal(t, zcap)),
+				}, nil),
+			})))
+			result := httptest.NewRecorder()
+
+			newOperation(t, config).Extract(result, request)
+			require.Equal(t, allowed, invoked, depth)
+
+			if allowed {
+				require.Equal(t, http.StatusOK, result.Code, result.Body.String())
+
+				continue
+			}
+
+			require.Equal(t, http.StatusForbidden, result.Code)
+
+			var errResp *model.ErrorResponse
+
+			require.NoError(t, json.NewDecoder(result.Body).Decode(&errResp))
+			require.Equal(t, model.ErrCodeForbidden, errResp.Code)
+			require.Contains(t, errResp.Message, zcapld2.ErrChainTooDeep.Error())
+		}
+	})
+
+	t.Run("error Forbidden if a zcap was revoked", func(t *testing.T) {
+		agent := newAgent(t)
+		zcap := newZCAP(t, newAgent(t), agent)
+		revoked := uuid.New().URN()
+		invoked := false
+
+		// the zcap was delegated from a revoked zcap
+		zcap.Proof[0]["capabilityChain"] = []interface{}{revoked}
+
+		config := agentConfig(agent)
+		config.Revocations = revocationList{revoked: true}
+		config.EDVClient = func(string, ...edv.Option) vault.ConfidentialStorageDocReader {
+			invoked = true
+
+			return newMockEDVClient(t, nil, encryptedJWE(t, agent, randomDoc(t)))
+		}
+
+		o := newOperation(t, config)
+		query := docQuery(&openapi.UpstreamAuthorization{
+			BaseURL: "https://edv.example.com",
+			Zcap:    compress(t, marshal(t, zcap)),
+		}, nil)
+
+		for _, q := range []openapi.Query{query, refQuery(createQuery(t, o, query))} {
+			request := httptest.NewRequest(http.MethodPost, "/test", bytes.NewReader(marshal(t, []interface{}{q})))
+			result := httptest.NewRecorder()
+
+			o.Extract(result, request)
+			require.Equal(t, http.StatusForbidden, result.Code)
+
+			var errResp *model.ErrorResponse
+
+			require.NoError(t, json.NewDecoder(result.Body).Decode(&errResp))
+			require.Equal(t, model.ErrCodeForbidden, errResp.Code)
+			require.Contains(t, errResp.Message, zcapld2.ErrRevoked.Error())
+		}
+
+		require.False(t, invoked)
+	})
+
+	t.Run("error InternalServerError if the revocation of a zcap cannot be checked", func(t *testing.T) {
+		agent := newAgent(t)
+
+		config := agentConfig(agent)
+		config.Revocations = failingRevocationList{}
+
+		request := httptest.NewRequest(http.MethodPost, "/test", bytes.NewReader(marshal(t, []interface{}{
+			docQuery(&openapi.UpstreamAuthorization{
+				BaseURL: "https://edv.example.com",
+				Zcap:    compress(t, marshal(t, newZCAP(t, newAgent(t), agent))),
+			}, nil),
+		})))
+		result := httptest.NewRecorder()
+
+		newOperation(t, config).Extract(result, request)
+		require.Equal(t, http.StatusInternalServerError, result.Code)
+		require.Contains(t, result.Body.String(), "failed to check the revocation of zcap")
+	})
+
+	t.Run("error RequestEntityTooLarge if the document exceeds the max doc size", func(t *testing.T) {
+		agent := newAgent(t)
+		doc := randomDoc(t)
+
+		for maxDocSize, status := range map[int]int{
+			len(doc):     http.StatusOK,
+			len(doc) - 1: http.StatusRequestEntityTooLarge,
+		} {
+			edvClient := newMockEDVClient(t, nil, encryptedJWE(t, agent, doc))
+
+			config := agentConfig(agent)
+			config.MaxDocSize = int64(maxDocSize)
+			config.EDVClient = func(string, ...edv.Option) vault.ConfidentialStorageDocReader {
+				return edvClient
+			}
+
+			request := httptest.NewRequest(http.MethodPost, "/test", bytes.NewReader(marshal(t, []interface{}{
+				docQuery(&openapi.UpstreamAuthorization{BaseURL: "https://edv.example.com"}, nil),
+			})))
+			result := httptest.NewRecorder()
+
+			o := newOperation(t, config)
+			o.Extract(result, request)
+
+			require.Equal(t, status, result.Code)
+
+			if status == http.StatusRequestEntityTooLarge {
+				require.Contains(t, result.Body.String(),
+					fmt.Sprintf("document too large: exceeds %d bytes", maxDocSize))
+			}
+		}
+	})
+
+	t.Run("falls back to the upstream auth of the profile of a stored query", func(t *testing.T) {
+		doc := randomDoc(t)
+		agent := newAgent(t)
+
+		edvClient := newMockEDVClient(t, nil, encryptedJWE(t, agent, doc))
+
+		var edvURL string
+
+		config := agentConfig(agent)
+		config.EDVClient = func(url string, _ ...edv.Option) vault.ConfidentialStorageDocReader {
+			edvURL = url
+
+			return edvClient
+		}
+
+		profileID := saveProfile(t, config, &openapi.ProfileUpstreamAuth{
+			Edv: &openapi.UpstreamAuthorization{BaseURL: "https://edv.example.com/profile"},
+		})
+
+		o := newOperation(t, config)
+
+		query := docQuery(nil, nil)
+		query.UpstreamAuth = nil
+
+		result := httptest.NewRecorder()
+		o.CreateQuery(result, mux.SetURLVars(
+			httptest.NewRequest(http.MethodPost, "/test", bytes.NewReader(marshal(t, query))),
+			map[string]string{"profileID": profileID},
+		))
+		require.Equal(t, http.StatusCreated, result.Code)
+
+		location := result.Header().Get("Location")
+		ref := location[strings.LastIndex(location, "/")+1:]
+
+		result = httptest.NewRecorder()
+		o.Extract(result, httptest.NewRequest(http.MethodPost, "/test",
+			bytes.NewReader(marshal(t, []interface{}{refQuery(ref)}))))
+		require.Equal(t, http.StatusOK, result.Code)
+		require.Equal(t, "https://edv.example.com/profile", edvURL)
+
+		var extractions openapi.ExtractionResponse
+
+		unmarshal(t, &extractions, result.Body.Bytes())
+		require.Len(t, extractions, 1)
+
+		expected := &models.StructuredDocument{}
+		unmarshal(t, expected, doc)
+		require.Equal(t, expected.Content, extractions[0].Document)
+	})
+
+	t.Run("error BadRequest if a DocQuery has no upstream auth", func(t *testing.T) {
+		query := docQuery(nil, nil)
+		query.UpstreamAuth = nil
+
+		request := httptest.NewRequest(http.MethodPost, "/test", bytes.NewReader(marshal(t, []interface{}{query})))
+		result := httptest.NewRecorder()
+
+		newOperation(t, agentConfig(newAgent(t))).Extract(result, request)
+
+		require.Equal(t, http.StatusBadRequest, result.Code)
+		require.Contains(t, result.Body.String(), "upstreamAuth.edv is required")
+		require.Contains(t, result.Body.String(), `"code":"INVALID_REQUEST"`)
+	})
+
 	t.Run("error BadRequest if queryRef does not exist", func(t *testing.T) {
 		config := agentConfig(newAgent(t))
 
@@ -675,6 +2409,21 @@ func controller() *string {
 	return &c
 }
 
+// newRecipient returns the agent holding a new key and the did:key of the key.
+func newRecipient(t *testing.T, keyType kms.KeyType) (*context.Provider, string) {
+	t.Helper()
+
+	agent := newAgent(t)
+
+	_, pubKey, err := agent.KMS().CreateAndExportPubKeyBytes(keyType)
+	require.NoError(t, err)
+
+	didKey, err := kmsdidkey.BuildDIDKeyByKeyType(pubKey, keyType)
+	require.NoError(t, err)
+
+	return agent, didKey
+}
+
 func randomDoc(t *testing.T) []byte {
 	t.Helper()
 
@@ -692,6 +2441,39 @@ func randomDoc(t *testing.T) []byte {
 	return raw
 }
 
+// createQuery saves the query and returns its ID.
+func createQuery(t *testing.T, o *operation.Operation, query openapi.Query) string {
+	t.Helper()
+
+	result := httptest.NewRecorder()
+
+	o.CreateQuery(result, httptest.NewRequest(http.MethodPost, "/test", bytes.NewReader(marshal(t, query))))
+	require.Equal(t, http.StatusCreated, result.Code)
+
+	location := result.Header().Get("Location")
+	require.NotEmpty(t, location)
+
+	return location[strings.LastIndex(location, "/")+1:]
+}
+
+// saveProfile saves a profile with the upstream auth in the store of the config and returns its ID.
+func saveProfile(t *testing.T, config *operation.Config, auth *openapi.ProfileUpstreamAuth) string {
+	t.Helper()
+
+	profiles, err := config.StoreProvider.OpenStore("profile")
+	require.NoError(t, err)
+
+	profile := &openapi.Profile{
+		ID:           uuid.New().URN(),
+		Controller:   controller(),
+		UpstreamAuth: auth,
+	}
+
+	require.NoError(t, profiles.Put(profile.ID, marshal(t, profile)))
+
+	return profile.ID
+}
+
 func decompressZCAP(t *testing.T, encoded string) *zcapld.Capability {
 	t.Helper()
 
@@ -700,3 +2482,51 @@ func decompressZCAP(t *testing.T, encoded string) *zcapld.Capability {
 
 	return zcap
 }
+
+// newRootZCAP returns the root zcap of a profile created by another CSH instance with the agent's delegation key.
+func newRootZCAP(t *testing.T, agent *context.Provider, controller string, expires *time.Time) *zcapld2.Capability {
+	t.Helper()
+
+	signer, err := signature.NewCryptoSigner(agent.Crypto(), agent.KMS(), kms.ED25519Type)
+	require.NoError(t, err)
+
+	id := uuid.New().URN()
+
+	zcap, err := zcapld2.NewCapability(
+		&zcapld.Signer{
+			SignatureSuite:     ed25519signature2018.New(suite.WithSigner(signer)),
+			SuiteType:          ed25519signature2018.SignatureType,
+			VerificationMethod: didKeyURL(signer.PublicKeyBytes()),
+			ProcessorOpts:      []jsonld.ProcessorOpts{jsonld.WithDocumentLoader(testutil.DocumentLoader(t))},
+		},
+		expires,
+		zcapld.WithID(id),
+		zcapld.WithInvocationTarget(id, "urn:confidentialstoragehub:profile"),
+		zcapld.WithAllowedActions("reference", "compare"),
+		zcapld.WithController(controller),
+		zcapld.WithInvoker(controller),
+	)
+	require.NoError(t, err)
+
+	return zcap
+}
+
+func compressRootZCAP(t *testing.T, zcap *zcapld2.Capability) string {
+	t.Helper()
+
+	compressed, err := zcapld2.CompressZCAP(zcap)
+	require.NoError(t, err)
+
+	return compressed
+}
+
+type onReadEDVClient struct {
+	*mockEDVClient
+	onRead func()
+}
+
+func (c *onReadEDVClient) ReadDocument(vaultID, docID string, opts ...edv.ReqOption) (*models.EncryptedDocument, error) {
+	c.onRead()
+
+	return c.mockEDVClient.ReadDocument(vaultID, docID, opts...)
+}
@@ -8,85 +8,349 @@ package operation
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
+	"time"
 
 	"github.com/hyperledger/aries-framework-go/pkg/doc/jose"
 	"github.com/hyperledger/aries-framework-go/pkg/kms/webkms"
-	"github.com/igor-pavlenko/httpsignatures-go"
-	"github.com/trustbloc/edge-core/pkg/zcapld"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
 	edv "github.com/trustbloc/edv/pkg/client"
+	"github.com/trustbloc/edv/pkg/restapi/messages"
+	edvmodels "github.com/trustbloc/edv/pkg/restapi/models"
 
 	"github.com/trustbloc/ace/pkg/client/vault"
-	"github.com/trustbloc/ace/pkg/internal/zcapldutil"
+	"github.com/trustbloc/ace/pkg/client/zcap"
 	openapi "github.com/trustbloc/ace/pkg/restapi/csh/operation/models"
 	zcapld2 "github.com/trustbloc/ace/pkg/restapi/csh/operation/zcapld"
 )
 
+// ErrDocumentTooLarge is returned when a decrypted document exceeds the maximum document size.
+var ErrDocumentTooLarge = errors.New("document too large")
+
+// ErrMissingUpstreamAuth is returned when a DocQuery has no EDV authorization, neither its own nor its profile's.
+var ErrMissingUpstreamAuth = errors.New("missing upstream authorization")
+
+// ErrQueryNotFound is returned when a RefQuery references a query that does not exist.
+var ErrQueryNotFound = errors.New("no such query")
+
+// queryProfileTag indexes the stored queries by the hash of the ID of their profile: profile IDs are URNs, and
+// colons are not allowed in tag values.
+const queryProfileTag = "profile"
+
+func profileIndex(profileID string) string {
+	sum := sha256.Sum256([]byte(profileID))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// saveQuery stores the query, indexed by its profile.
+func (o *Operation) saveQuery(query *Query) error {
+	raw, err := json.Marshal(query)
+	if err != nil {
+		return fmt.Errorf("failed to marshal: %w", err)
+	}
+
+	return o.storage.queries.Put(query.ID, raw, storage.Tag{Name: queryProfileTag, Value: profileIndex(query.ProfileID)})
+}
+
+// profileQueries returns the queries of the profile. Queries stored before they were indexed by profile are not
+// returned.
+func (o *Operation) profileQueries(profileID string) ([]*Query, error) {
+	iter, err := o.storage.queries.Query(fmt.Sprintf("%s:%s", queryProfileTag, profileIndex(profileID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query queries: %w", err)
+	}
+
+	defer func() {
+		if errClose := iter.Close(); errClose != nil {
+			logger.Errorf("failed to close iterator: %s", errClose)
+		}
+	}()
+
+	var queries []*Query
+
+	for {
+		ok, err := iter.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate queries: %w", err)
+		}
+
+		if !ok {
+			return queries, nil
+		}
+
+		raw, err := iter.Value()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read query: %w", err)
+		}
+
+		query := &Query{}
+
+		err = json.Unmarshal(raw, query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse query: %w", err)
+		}
+
+		queries = append(queries, query)
+	}
+}
+
 // ReadDocQuery resolves a DocQuery to the contents of a Confidential Storage document.
 func (o *Operation) ReadDocQuery(query *openapi.DocQuery) ([]byte, error) {
-	edvOptions, err := o.edvOptions(query)
+	contents, _, err := o.readDocQuery(context.Background(), query)
+
+	return contents, err
+}
+
+// readDocQuery also returns the encrypted document as read from the Confidential Storage vault. The upstream
+// requests are sent with the context.
+func (o *Operation) readDocQuery(ctx context.Context,
+	query *openapi.DocQuery) ([]byte, *edvmodels.EncryptedDocument, error) {
+	err := checkUpstreamAuth(query)
 	if err != nil {
-		return nil, fmt.Errorf("failed to determine edv client options: %w", err)
+		return nil, nil, err
 	}
 
-	docReaderOptions, err := o.documentReaderOptions(query)
+	httpClient := o.upstreamClient(ctx)
+
+	zcaps := zcapCacheOf(ctx)
+
+	edvOptions, err := o.edvOptions(query, zcaps, httpClient)
 	if err != nil {
-		return nil, fmt.Errorf("failed to determine Confidential Storage document reader options: %w", err)
+		return nil, nil, fmt.Errorf("failed to determine edv client options: %w", err)
 	}
 
-	contents := vault.NewDocumentReader(
-		*query.VaultID,
-		*query.DocID,
-		o.edvClient(
+	docReaderOptions, err := o.documentReaderOptions(query, zcaps, httpClient)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to determine Confidential Storage document reader options: %w", err)
+	}
+
+	err = o.checkUpstreamZCAPs(ctx, query, zcaps)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	edvClient := &recordingDocReader{
+		ConfidentialStorageDocReader: o.edvClient(
 			query.UpstreamAuth.Edv.BaseURL, // TODO EDV url should not be optional
 			edvOptions...,
 		),
+	}
+
+	contents := vault.NewDocumentReader(
+		*query.VaultID,
+		*query.DocID,
+		edvClient,
 		docReaderOptions...,
 	)
 
 	document := bytes.NewBuffer(nil)
 
-	_, err = io.Copy(document, contents)
+	_, err = io.Copy(document, io.LimitReader(contents, o.maxDocSize+1))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if int64(document.Len()) > o.maxDocSize {
+		return nil, nil, fmt.Errorf("%w: exceeds %d bytes", ErrDocumentTooLarge, o.maxDocSize)
+	}
+
+	return document.Bytes(), edvClient.doc, nil
+}
+
+// Diagnostics of the queries that failed validation.
+const (
+	DiagnosticAuthFailed   = "AUTH_FAILED"
+	DiagnosticDocNotFound  = "DOC_NOT_FOUND"
+	DiagnosticInvalidPath  = "INVALID_PATH"
+	DiagnosticPathNotFound = "PATH_NOT_FOUND"
+	DiagnosticDocTooLarge  = "DOC_TOO_LARGE"
+	DiagnosticFetchFailed  = "FETCH_FAILED"
+)
+
+// queryDiagnostic tells why the document of a query could not be fetched. The EDV and KMS clients only report the
+// status of their failed requests in their error messages.
+func queryDiagnostic(err error) string {
+	msg := err.Error()
+
+	switch {
+	case errors.Is(err, ErrInvalidJSONPath):
+		return DiagnosticInvalidPath
+	case errors.Is(err, ErrJSONPathNotFound):
+		return DiagnosticPathNotFound
+	case errors.Is(err, ErrDocumentTooLarge):
+		return DiagnosticDocTooLarge
+	case errors.Is(err, zcapld2.ErrExpired), errors.Is(err, zcapld2.ErrDIDMethodNotAllowed),
+		errors.Is(err, zcapld2.ErrChainTooDeep), errors.Is(err, zcapld2.ErrRevoked),
+		strings.Contains(msg, "status code 401"), strings.Contains(msg, "status code 403"),
+		strings.Contains(msg, "http error: 401"), strings.Contains(msg, "http error: 403"):
+		return DiagnosticAuthFailed
+	case strings.Contains(msg, "status code 404"),
+		strings.HasSuffix(msg, messages.ErrDocumentNotFound.Error()+"."),
+		strings.HasSuffix(msg, messages.ErrVaultNotFound.Error()+"."):
+		return DiagnosticDocNotFound
+	default:
+		return DiagnosticFetchFailed
+	}
+}
 
-	return document.Bytes(), err
+// recordingDocReader keeps the last encrypted document read.
+type recordingDocReader struct {
+	vault.ConfidentialStorageDocReader
+	doc *edvmodels.EncryptedDocument
 }
 
-func (o *Operation) edvOptions(query *openapi.DocQuery) ([]edv.Option, error) {
-	opts := []edv.Option{edv.WithHTTPClient(o.httpClient)}
+func (r *recordingDocReader) ReadDocument(vaultID, docID string,
+	opts ...edv.ReqOption) (*edvmodels.EncryptedDocument, error) {
+	doc, err := r.ConfidentialStorageDocReader.ReadDocument(vaultID, docID, opts...)
+	if err == nil {
+		r.doc = doc
+	}
+
+	return doc, err
+}
+
+// applyProfileUpstreamAuth sets the upstream auth of the profile on the query if it is a DocQuery without its own.
+// The query is left as is if the profile has no upstream auth.
+func (o *Operation) applyProfileUpstreamAuth(query openapi.Query, profileID string) error {
+	docQuery, ok := query.(*openapi.DocQuery)
+	if !ok || docQuery.UpstreamAuth != nil {
+		return nil
+	}
+
+	auth, err := o.profileUpstreamAuth(profileID)
+	if err != nil {
+		return err
+	}
+
+	docQuery.UpstreamAuth = auth
+
+	return nil
+}
+
+// profileUpstreamAuth returns the upstream auth of the profile, nil if the profile has none or does not exist.
+func (o *Operation) profileUpstreamAuth(profileID string) (*openapi.DocQueryAO1UpstreamAuth, error) {
+	raw, err := o.storage.profiles.Get(profileID)
+	if errors.Is(err, storage.ErrDataNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch profile %s: %w", profileID, err)
+	}
+
+	profile := &openapi.Profile{}
+
+	err = json.Unmarshal(raw, profile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse profile %s: %w", profileID, err)
+	}
+
+	if profile.UpstreamAuth == nil {
+		return nil, nil
+	}
+
+	return &openapi.DocQueryAO1UpstreamAuth{
+		Edv: profile.UpstreamAuth.Edv,
+		Kms: profile.UpstreamAuth.Kms,
+	}, nil
+}
+
+// checkUpstreamAuth rejects a DocQuery without an EDV authorization. The KMS authorization is optional: documents
+// are decrypted with the CSH's keys without it.
+func checkUpstreamAuth(query *openapi.DocQuery) error {
+	if query.UpstreamAuth == nil || query.UpstreamAuth.Edv == nil {
+		return fmt.Errorf("%w: upstreamAuth.edv is required, either in the query or in its profile",
+			ErrMissingUpstreamAuth)
+	}
+
+	return nil
+}
+
+// checkUpstreamZCAPs rejects a DocQuery with an expired zcap, one invoked by a DID whose method is not allowed, one
+// delegated more times than allowed, or one that was revoked, before the EDV or KMS are invoked.
+func (o *Operation) checkUpstreamZCAPs(ctx context.Context, query *openapi.DocQuery,
+	zcaps *zcapld2.ZCAPCache) error {
+	upstream := []struct {
+		name string
+		auth *openapi.UpstreamAuthorization
+	}{
+		{name: "EDV", auth: query.UpstreamAuth.Edv},
+		{name: "KMS", auth: query.UpstreamAuth.Kms},
+	}
+
+	for _, u := range upstream {
+		if u.auth == nil || u.auth.Zcap == "" {
+			continue
+		}
+
+		err := zcaps.CheckExpiry(u.auth.Zcap, time.Now())
+		if err != nil {
+			return fmt.Errorf("invalid %s zcap: %w", u.name, err)
+		}
+
+		verMethod, err := invoker(zcaps, u.auth.Zcap)
+		if err != nil {
+			return fmt.Errorf("invalid %s zcap: %w", u.name, err)
+		}
+
+		err = o.didMethods.CheckInvoker(verMethod)
+		if err != nil {
+			return fmt.Errorf("invalid %s zcap: %w", u.name, err)
+		}
+
+		zcap, err := zcaps.Parse(u.auth.Zcap)
+		if err != nil {
+			return fmt.Errorf("invalid %s zcap: %w", u.name, err)
+		}
+
+		err = zcapld2.CheckChainDepth(zcap, o.maxChainDepth)
+		if err != nil {
+			return fmt.Errorf("invalid %s zcap: %w", u.name, err)
+		}
+
+		if o.revocations == nil {
+			continue
+		}
+
+		err = zcapld2.CheckRevocation(ctx, zcap, o.revocations)
+		if err != nil {
+			return fmt.Errorf("invalid %s zcap: %w", u.name, err)
+		}
+	}
+
+	return nil
+}
+
+func (o *Operation) edvOptions(query *openapi.DocQuery, zcaps *zcapld2.ZCAPCache,
+	httpClient *http.Client) ([]edv.Option, error) {
+	opts := []edv.Option{edv.WithHTTPClient(httpClient)}
 
 	if query.UpstreamAuth.Edv == nil || query.UpstreamAuth.Edv.Zcap == "" {
 		return opts, nil
 	}
 
-	verMethod, err := invoker(query.UpstreamAuth.Edv.Zcap)
+	verMethod, err := invoker(zcaps, query.UpstreamAuth.Edv.Zcap)
 	if err != nil {
 		return nil, fmt.Errorf("failed to determine EDV verification method: %w", err)
 	}
 
-	opts = append(opts, edv.WithHeaders(zcapld2.NewHTTPSigner(
-		verMethod,
-		query.UpstreamAuth.Edv.Zcap,
-		func(r *http.Request) (string, error) {
-			action := "write"
-
-			if r.Method == http.MethodGet {
-				action = "read"
-			}
-
-			return action, nil
-		},
-		o.supportedSecrets(),
-		o.supportedSignatureHashAlgorithms(),
-	)))
+	opts = append(opts, zcap.EDVSigner(o.zcapInvoker(verMethod, query.UpstreamAuth.Edv.Zcap)))
 
 	return opts, nil
 }
 
-func (o *Operation) documentReaderOptions(query *openapi.DocQuery) ([]vault.ReaderOption, error) {
+func (o *Operation) documentReaderOptions(query *openapi.DocQuery, zcaps *zcapld2.ZCAPCache,
+	httpClient *http.Client) ([]vault.ReaderOption, error) {
 	opts := make([]vault.ReaderOption, 0)
 
 	if query.UpstreamAuth.Kms == nil {
@@ -100,23 +364,15 @@ func (o *Operation) documentReaderOptions(query *openapi.DocQuery) ([]vault.Read
 	kmsOptions := make([]webkms.Opt, 0)
 
 	if query.UpstreamAuth.Kms.Zcap != "" {
-		verMethod, err := invoker(query.UpstreamAuth.Kms.Zcap)
+		verMethod, err := invoker(zcaps, query.UpstreamAuth.Kms.Zcap)
 		if err != nil {
 			return nil, fmt.Errorf("failed to determine KMS verification method: %w", err)
 		}
 
-		kmsOptions = append(kmsOptions,
-			webkms.WithHeaders(zcapld2.NewHTTPSigner(
-				verMethod,
-				query.UpstreamAuth.Kms.Zcap,
-				zcapldutil.CapabilityInvocationAction,
-				o.supportedSecrets(),
-				o.supportedSignatureHashAlgorithms(),
-			),
-			))
+		kmsOptions = append(kmsOptions, zcap.KMSSigner(o.zcapInvoker(verMethod, query.UpstreamAuth.Kms.Zcap)))
 	}
 
-	path, err := keystorePath(query.UpstreamAuth.Kms.Zcap)
+	path, err := keystorePath(zcaps, query.UpstreamAuth.Kms.Zcap)
 	if err != nil {
 		return nil, fmt.Errorf("failed to determine remote keystore relative path: %w", err)
 	}
@@ -132,12 +388,12 @@ func (o *Operation) documentReaderOptions(query *openapi.DocQuery) ([]vault.Read
 			nil,
 			o.aries.WebCrypto(
 				keystoreURL,
-				o.httpClient,
+				httpClient,
 				kmsOptions...,
 			),
 			o.aries.WebKMS(
 				keystoreURL,
-				o.httpClient,
+				httpClient,
 				kmsOptions...,
 			),
 		),
@@ -146,21 +402,43 @@ func (o *Operation) documentReaderOptions(query *openapi.DocQuery) ([]vault.Read
 	return opts, nil
 }
 
-// TODO make supported zcapld algorithms and secret stores configurable.
-func (o *Operation) supportedSecrets() httpsignatures.Secrets {
-	return &zcapld.AriesDIDKeySecrets{}
+// zcapInvoker signs the upstream requests invoking the zcap on behalf of the verification method. The DID of the
+// verification method is resolved with the resolvers of the CSH rather than a single VDR.
+func (o *Operation) zcapInvoker(verMethod, compressedZCAP string) *zcap.Invoker {
+	return &zcap.Invoker{
+		Controller: verMethod,
+		ZCAP:       compressedZCAP,
+		Algorithm:  o.supportedSignatureHashAlgorithms(),
+	}
 }
 
-func (o *Operation) supportedSignatureHashAlgorithms() httpsignatures.SignatureHashAlgorithm {
+// TODO make supported zcapld algorithms configurable.
+func (o *Operation) supportedSignatureHashAlgorithms() *zcapld2.DIDSignatureHashAlgorithms {
 	return &zcapld2.DIDSignatureHashAlgorithms{
 		KMS:       o.aries.KMS,
 		Crypto:    o.aries.Crypto,
 		Resolvers: o.aries.DIDResolvers,
+		Cache:     o.didCache,
 	}
 }
 
-func invoker(compressedZCAP string) (string, error) {
-	zcap, err := zcapld.DecompressZCAP(compressedZCAP)
+// zcapCacheKey is the context key of the cache of the zcaps parsed while serving a request.
+type zcapCacheKey struct{}
+
+// withZCAPCache returns a context caching the zcaps parsed by the upstream requests made with it.
+func withZCAPCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, zcapCacheKey{}, zcapld2.NewZCAPCache())
+}
+
+// zcapCacheOf returns the cache of zcaps of the context, nil if it has none: nil caches parse the zcaps every time.
+func zcapCacheOf(ctx context.Context) *zcapld2.ZCAPCache {
+	zcaps, _ := ctx.Value(zcapCacheKey{}).(*zcapld2.ZCAPCache)
+
+	return zcaps
+}
+
+func invoker(zcaps *zcapld2.ZCAPCache, compressedZCAP string) (string, error) {
+	zcap, err := zcaps.Parse(compressedZCAP)
 	if err != nil {
 		return "", fmt.Errorf("failed to parse zcap: %w", err)
 	}
@@ -176,8 +454,8 @@ func invoker(compressedZCAP string) (string, error) {
 	return "", errors.New("zcap does not specify a controller nor an invoker")
 }
 
-func keystorePath(compressedZCAP string) (string, error) {
-	zcap, err := zcapld.DecompressZCAP(compressedZCAP)
+func keystorePath(zcaps *zcapld2.ZCAPCache, compressedZCAP string) (string, error) {
+	zcap, err := zcaps.Parse(compressedZCAP)
 	if err != nil {
 		return "", fmt.Errorf("failed to parse zcap: %w", err)
 	}
@@ -11,11 +11,14 @@ import (
 	"compress/gzip"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
@@ -29,6 +32,7 @@ import (
 	"github.com/hyperledger/aries-framework-go/pkg/doc/util/signature"
 	"github.com/hyperledger/aries-framework-go/pkg/framework/aries"
 	"github.com/hyperledger/aries-framework-go/pkg/framework/context"
+	vdrapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr"
 	"github.com/hyperledger/aries-framework-go/pkg/kms"
 	"github.com/hyperledger/aries-framework-go/pkg/kms/webkms"
 	"github.com/hyperledger/aries-framework-go/pkg/vdr/fingerprint"
@@ -364,6 +368,60 @@ func TestOperation_ReadDocQuery(t *testing.T) {
 		require.Contains(t, err.Error(), "failed to parse zcap: failed to base64URL-decode value INVALID")
 	})
 
+	t.Run("fails if the EDV zcap has expired", func(t *testing.T) {
+		chsServer := newAgent(t)
+		edvServer := newAgent(t)
+
+		config := agentConfig(chsServer)
+		config.EDVClient = func(string, ...edv.Option) vault.ConfidentialStorageDocReader {
+			return &mockEDVClient{err: errors.New("EDV must not be invoked with an expired zcap")}
+		}
+
+		o := newOperation(t, config)
+
+		query := docQuery(&openapi.UpstreamAuthorization{
+			BaseURL: "https://edv.example.com",
+			Zcap:    compressWithExpiry(t, newZCAP(t, edvServer, chsServer), time.Now().Add(-time.Minute)),
+		}, nil)
+
+		_, err := o.ReadDocQuery(query)
+		require.ErrorIs(t, err, zcapld2.ErrExpired)
+		require.Contains(t, err.Error(), "invalid EDV zcap")
+	})
+
+	t.Run("fails if the KMS zcap has expired", func(t *testing.T) {
+		chsServer := newAgent(t)
+		edvServer := newAgent(t)
+
+		config := agentConfig(chsServer)
+		config.EDVClient = func(string, ...edv.Option) vault.ConfidentialStorageDocReader {
+			return &mockEDVClient{err: errors.New("EDV must not be invoked with an expired zcap")}
+		}
+		config.Aries.WebKMS = func(url string, c webkms.HTTPClient, opts ...webkms.Opt) kms.KeyManager {
+			return webkms.New(url, c, opts...)
+		}
+		config.Aries.WebCrypto = func(url string, c remotecrypto.HTTPClient, opts ...webkms.Opt) crypto.Crypto {
+			return remotecrypto.New(url, c, opts...)
+		}
+
+		o := newOperation(t, config)
+
+		query := docQuery(
+			&openapi.UpstreamAuthorization{
+				BaseURL: "https://edv.example.com",
+				Zcap:    compressWithExpiry(t, newZCAP(t, edvServer, chsServer), time.Now().Add(time.Hour)),
+			},
+			&openapi.UpstreamAuthorization{
+				BaseURL: "https://kms.example.com",
+				Zcap:    compressWithExpiry(t, newZCAP(t, edvServer, chsServer), time.Now().Add(-time.Minute)),
+			},
+		)
+
+		_, err := o.ReadDocQuery(query)
+		require.ErrorIs(t, err, zcapld2.ErrExpired)
+		require.Contains(t, err.Error(), "invalid KMS zcap")
+	})
+
 	t.Run("fails if the KMS zcap has a malformed invocation target ID", func(t *testing.T) {
 		chsServer := newAgent(t)
 		edvServer := newAgent(t)
@@ -392,6 +450,73 @@ func TestOperation_ReadDocQuery(t *testing.T) {
 	})
 }
 
+func TestOperation_DIDCache(t *testing.T) {
+	expected := []byte(uuid.New().String())
+	edvServer := newAgent(t)
+	chs := newAgent(t)
+	jwe := encryptedJWE(t, chs, expected)
+
+	// the CSH invokes the EDV zcap with its identity
+	edvZCAP := newZCAP(t, edvServer, chs)
+	identityDID := strings.Split(edvZCAP.Invoker, "#")[0]
+
+	resolver := &countingResolver{DIDResolver: key.New()}
+
+	config := agentConfig(chs)
+	config.Aries.DIDResolvers = []zcapld2.DIDResolver{resolver}
+	config.EDVClient = func(url string, options ...edv.Option) vault.ConfidentialStorageDocReader {
+		return edv.New(url, options...)
+	}
+
+	createDID := config.Aries.PublicDIDCreator
+	config.Aries.PublicDIDCreator = func(k kms.KeyManager) (*did.DocResolution, error) {
+		resolution, err := createDID(k)
+		require.NoError(t, err)
+
+		if identityDID != "" {
+			resolution.DIDDocument.ID, identityDID = identityDID, ""
+		}
+
+		return resolution, nil
+	}
+
+	o := newOperation(t, config)
+
+	edvURL := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write(marshal(t, &models.EncryptedDocument{JWE: serializeFull(t, jwe)}))
+		require.NoError(t, err)
+	})
+
+	query := docQuery(&openapi.UpstreamAuthorization{
+		BaseURL: edvURL,
+		Zcap:    compress(t, marshal(t, edvZCAP)),
+	}, nil)
+
+	for i := 0; i < 2; i++ {
+		result, err := o.ReadDocQuery(query)
+		require.NoError(t, err)
+		require.Equal(t, expected, result)
+	}
+
+	// the DID document of the CSH's identity is resolved once
+	require.Equal(t, 1, resolver.reads)
+}
+
+// countingResolver counts the DID documents read.
+type countingResolver struct {
+	zcapld2.DIDResolver
+	mu    sync.Mutex
+	reads int
+}
+
+func (r *countingResolver) Read(didID string, opts ...vdrapi.DIDMethodOption) (*did.DocResolution, error) {
+	r.mu.Lock()
+	r.reads++
+	r.mu.Unlock()
+
+	return r.DIDResolver.Read(didID, opts...)
+}
+
 func newServer(t *testing.T, handlerFunc http.HandlerFunc) string {
 	t.Helper()
 
@@ -682,6 +807,18 @@ func compress(t *testing.T, msg []byte) string {
 	return base64.URLEncoding.EncodeToString(compressed.Bytes())
 }
 
+// compressWithExpiry adds an `expires` field to the zcap before compressing it.
+func compressWithExpiry(t *testing.T, zcap *zcapld.Capability, expires time.Time) string {
+	t.Helper()
+
+	raw := make(map[string]interface{})
+	unmarshal(t, &raw, marshal(t, zcap))
+
+	raw["expires"] = expires.UTC().Format(time.RFC3339)
+
+	return compress(t, marshal(t, raw))
+}
+
 type unwrapRequest struct {
 	WrappedKey   crypto.RecipientWrappedKey `json:"wrapped_key"`
 	SenderPubKey *crypto.PublicKey          `json:"sender_pub_key,omitempty"`
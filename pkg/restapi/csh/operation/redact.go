@@ -0,0 +1,183 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RedactionPath is a JSONPath expression locating values to remove from a document. Only child members and array
+// elements can be selected, by name, index or wildcard, eg. `$.credentialSubject.ssn`, `$['given name']` or
+// `$.items[*].price`.
+type RedactionPath struct {
+	expr     string
+	segments []pathSegment
+}
+
+type pathSegment struct {
+	name     string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// CompileRedactionPath compiles the JSONPath expression. Recursive descent, filters and slices are not supported,
+// nor is redacting the whole document.
+func CompileRedactionPath(expr string) (*RedactionPath, error) {
+	invalid := func(reason string) error {
+		return fmt.Errorf("%w [%s]: %s", ErrInvalidJSONPath, expr, reason)
+	}
+
+	if !strings.HasPrefix(expr, "$") {
+		return nil, invalid("must start with $")
+	}
+
+	var (
+		segments []pathSegment
+		rest     = expr[1:]
+	)
+
+	for rest != "" {
+		var (
+			seg pathSegment
+			err error
+		)
+
+		switch rest[0] {
+		case '.':
+			seg, rest, err = parseMemberSegment(rest[1:])
+		case '[':
+			seg, rest, err = parseBracketSegment(rest[1:])
+		default:
+			err = fmt.Errorf("unexpected %q", rest[0])
+		}
+
+		if err != nil {
+			return nil, invalid(err.Error())
+		}
+
+		segments = append(segments, seg)
+	}
+
+	if len(segments) == 0 {
+		return nil, invalid("cannot redact the whole document")
+	}
+
+	return &RedactionPath{expr: expr, segments: segments}, nil
+}
+
+// parseMemberSegment parses `name` or `*` following a dot.
+func parseMemberSegment(s string) (pathSegment, string, error) {
+	end := strings.IndexAny(s, ".[")
+	if end < 0 {
+		end = len(s)
+	}
+
+	name := s[:end]
+
+	switch name {
+	case "":
+		return pathSegment{}, "", fmt.Errorf("missing member name")
+	case "*":
+		return pathSegment{wildcard: true}, s[end:], nil
+	}
+
+	return pathSegment{name: name}, s[end:], nil
+}
+
+// parseBracketSegment parses `'name']`, `"name"]`, `index]` or `*]` following an opening bracket.
+func parseBracketSegment(s string) (pathSegment, string, error) {
+	if s != "" && (s[0] == '\'' || s[0] == '"') {
+		end := strings.IndexByte(s[1:], s[0])
+		if end < 0 || !strings.HasPrefix(s[end+2:], "]") {
+			return pathSegment{}, "", fmt.Errorf("unterminated member name")
+		}
+
+		return pathSegment{name: s[1 : end+1]}, s[end+3:], nil
+	}
+
+	end := strings.IndexByte(s, ']')
+	if end < 0 {
+		return pathSegment{}, "", fmt.Errorf("missing ]")
+	}
+
+	if s[:end] == "*" {
+		return pathSegment{wildcard: true}, s[end+1:], nil
+	}
+
+	index, err := strconv.Atoi(s[:end])
+	if err != nil || index < 0 {
+		return pathSegment{}, "", fmt.Errorf("unsupported selector [%s]", s[:end])
+	}
+
+	return pathSegment{index: index, isIndex: true}, s[end+1:], nil
+}
+
+// String returns the JSONPath expression.
+func (p *RedactionPath) String() string {
+	return p.expr
+}
+
+// Redact removes the values the path selects from the document, which must be made of the types produced by
+// json.Unmarshal into an interface{}, and returns the redacted document. Maps are modified in place.
+// Redacting values that do not exist is a no-op.
+func (p *RedactionPath) Redact(document interface{}) interface{} {
+	return redact(document, p.segments)
+}
+
+func redact(node interface{}, segments []pathSegment) interface{} {
+	seg, last := segments[0], len(segments) == 1
+
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if seg.isIndex {
+			return n
+		}
+
+		for key, value := range n {
+			if !seg.wildcard && key != seg.name {
+				continue
+			}
+
+			if last {
+				delete(n, key)
+			} else {
+				n[key] = redact(value, segments[1:])
+			}
+		}
+
+		return n
+	case []interface{}:
+		if !seg.isIndex && !seg.wildcard {
+			return n
+		}
+
+		if last {
+			if seg.wildcard {
+				return []interface{}{}
+			}
+
+			if seg.index < len(n) {
+				return append(n[:seg.index:seg.index], n[seg.index+1:]...)
+			}
+
+			return n
+		}
+
+		for i := range n {
+			if seg.wildcard || i == seg.index {
+				n[i] = redact(n[i], segments[1:])
+			}
+		}
+
+		return n
+	default:
+		return node
+	}
+}
@@ -0,0 +1,101 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/ace/pkg/restapi/csh/operation"
+)
+
+func TestRedactionPath(t *testing.T) {
+	newDoc := func(t *testing.T) interface{} {
+		t.Helper()
+
+		var doc interface{}
+
+		require.NoError(t, json.Unmarshal([]byte(`{
+			"credentialSubject": {
+				"name": "Alice",
+				"ssn": "123-45-6789",
+				"given name": "Al",
+				"scores": [1, 2, 3],
+				"items": [{"id": "a", "price": 1}, {"id": "b", "price": 2}]
+			}
+		}`), &doc))
+
+		return doc
+	}
+
+	redact := func(t *testing.T, expr string) interface{} {
+		t.Helper()
+
+		path, err := operation.CompileRedactionPath(expr)
+		require.NoError(t, err)
+		require.Equal(t, expr, path.String())
+
+		return path.Redact(newDoc(t))
+	}
+
+	subject := func(doc interface{}) map[string]interface{} {
+		return doc.(map[string]interface{})["credentialSubject"].(map[string]interface{}) // nolint:forcetypeassert
+	}
+
+	t.Run("removes members", func(t *testing.T) {
+		for _, expr := range []string{"$.credentialSubject.ssn", "$['credentialSubject'][\"ssn\"]"} {
+			s := subject(redact(t, expr))
+			require.NotContains(t, s, "ssn", expr)
+			require.Equal(t, "Alice", s["name"], expr)
+		}
+
+		require.NotContains(t, subject(redact(t, "$.credentialSubject['given name']")), "given name")
+		require.Empty(t, subject(redact(t, "$.credentialSubject.*")))
+	})
+
+	t.Run("removes array elements", func(t *testing.T) {
+		require.Equal(t, []interface{}{float64(1), float64(3)},
+			subject(redact(t, "$.credentialSubject.scores[1]"))["scores"])
+		require.Equal(t, []interface{}{}, subject(redact(t, "$.credentialSubject.scores[*]"))["scores"])
+		require.Equal(t, []interface{}{
+			map[string]interface{}{"id": "a"}, map[string]interface{}{"id": "b"},
+		}, subject(redact(t, "$.credentialSubject.items[*].price"))["items"])
+		require.Equal(t, []interface{}{
+			map[string]interface{}{"id": "a", "price": float64(1)}, map[string]interface{}{"id": "b"},
+		}, subject(redact(t, "$.credentialSubject.items[1].price"))["items"])
+	})
+
+	t.Run("redacting values that do not exist is a no-op", func(t *testing.T) {
+		for _, expr := range []string{
+			"$.missing",
+			"$.credentialSubject.name.first",
+			"$.credentialSubject.scores[7]",
+			"$.credentialSubject.scores.length",
+			"$.credentialSubject[0]",
+			"$[0]",
+		} {
+			require.Equal(t, newDoc(t), redact(t, expr), expr)
+		}
+
+		path, err := operation.CompileRedactionPath("$.ssn")
+		require.NoError(t, err)
+		require.Equal(t, "Alice", path.Redact("Alice"))
+	})
+
+	t.Run("error if the path is malformed or unsupported", func(t *testing.T) {
+		for _, expr := range []string{
+			"", "$", "credentialSubject.ssn", "$.", "$..ssn", "$.a[", "$.a['b]", "$.a['b'", "$.a[-1]",
+			"$.a[0:2]", "$.a[?(@.b)]", "$a",
+		} {
+			_, err := operation.CompileRedactionPath(expr)
+			require.True(t, errors.Is(err, operation.ErrInvalidJSONPath), expr)
+		}
+	})
+}
@@ -0,0 +1,131 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultUpstreamRetries is the default number of times a request to an upstream EDV or KMS server is retried when
+// the server is overloaded or unavailable.
+const DefaultUpstreamRetries = 3
+
+const (
+	// retryBaseDelay is the delay before the first retry of a response without Retry-After, doubled on every retry.
+	retryBaseDelay = 200 * time.Millisecond
+	// maxRetryDelay caps the delays requested by Retry-After headers.
+	maxRetryDelay = 10 * time.Second
+)
+
+// RetryTransport retries the requests that upstream servers reject with 429 Too Many Requests or 503 Service
+// Unavailable. It waits as long as their Retry-After header asks for, up to 10s, or backs off exponentially.
+// Once the retries are exhausted, the last response is returned.
+type RetryTransport struct {
+	Base    http.RoundTripper
+	Retries int
+}
+
+// RoundTrip sends the request, retrying it as needed. Requests whose body cannot be replayed are not retried.
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	backoff := retryBaseDelay
+
+	for attempt := 0; ; attempt++ {
+		resp, err := t.Base.RoundTrip(req)
+		if err != nil || !retryable(resp.StatusCode) || attempt >= t.Retries {
+			return resp, err
+		}
+
+		if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+			return resp, nil
+		}
+
+		delay := retryAfter(resp.Header.Get("Retry-After"), backoff)
+		backoff *= 2
+
+		logger.Warnf("%s %s returned %d, retrying in %s", req.Method, req.URL.Redacted(), resp.StatusCode, delay)
+
+		// the connection can be reused once the body is drained
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+
+		select {
+		case <-req.Context().Done():
+			return nil, fmt.Errorf("retry %s %s: %w", req.Method, req.URL.Redacted(), req.Context().Err())
+		case <-time.After(delay):
+		}
+
+		req, err = rewind(req)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable
+}
+
+// retryAfter returns the delay requested by a Retry-After header, either in seconds or as an HTTP date, capped at
+// maxRetryDelay. It returns the backoff if the header is absent or invalid.
+func retryAfter(header string, backoff time.Duration) time.Duration {
+	var delay time.Duration
+
+	if seconds, err := strconv.Atoi(strings.TrimSpace(header)); err == nil && seconds >= 0 {
+		delay = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(header); err == nil {
+		delay = time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+	} else {
+		delay = backoff
+	}
+
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+
+	return delay
+}
+
+// rewind returns a copy of the request with a fresh body.
+func rewind(req *http.Request) (*http.Request, error) {
+	if req.GetBody == nil {
+		return req, nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, fmt.Errorf("retry %s %s: get body: %w", req.Method, req.URL.Redacted(), err)
+	}
+
+	retry := req.Clone(req.Context())
+	retry.Body = body
+
+	return retry, nil
+}
+
+// upstreamUnavailable reports whether an upstream EDV or KMS server was still overloaded or unavailable once the
+// retries were exhausted. The EDV and KMS clients only report the status of their failed requests in their error
+// messages.
+func upstreamUnavailable(err error) bool {
+	msg := err.Error()
+
+	for _, status := range []int{http.StatusTooManyRequests, http.StatusServiceUnavailable} {
+		if strings.Contains(msg, fmt.Sprintf("status code %d", status)) ||
+			strings.Contains(msg, fmt.Sprintf("http error: %d", status)) {
+			return true
+		}
+	}
+
+	return false
+}
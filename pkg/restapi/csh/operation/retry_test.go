@@ -0,0 +1,144 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	edv "github.com/trustbloc/edv/pkg/client"
+	"github.com/trustbloc/edv/pkg/restapi/models"
+
+	"github.com/trustbloc/ace/pkg/restapi/csh/operation"
+)
+
+func TestRetryTransport(t *testing.T) {
+	newServer := func(t *testing.T, respond func(attempt int32, w http.ResponseWriter, r *http.Request)) (
+		*httptest.Server, *int32) {
+		t.Helper()
+
+		var attempts int32
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			respond(atomic.AddInt32(&attempts, 1), w, r)
+		}))
+		t.Cleanup(srv.Close)
+
+		return srv, &attempts
+	}
+
+	newClient := func(retries int) *http.Client {
+		return &http.Client{Transport: &operation.RetryTransport{Base: http.DefaultTransport, Retries: retries}}
+	}
+
+	t.Run("EDV returning 503 then 200", func(t *testing.T) {
+		expected := &models.EncryptedDocument{ID: "doc1", Sequence: 1}
+
+		srv, attempts := newServer(t, func(attempt int32, w http.ResponseWriter, _ *http.Request) {
+			if attempt == 1 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusServiceUnavailable)
+
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			require.NoError(t, json.NewEncoder(w).Encode(expected))
+		})
+
+		client := edv.New(srv.URL, edv.WithHTTPClient(newClient(1)))
+
+		doc, err := client.ReadDocument("vault1", "doc1")
+		require.NoError(t, err)
+		require.Equal(t, expected.ID, doc.ID)
+		require.Equal(t, int32(2), atomic.LoadInt32(attempts))
+	})
+
+	t.Run("gives up after the last retry", func(t *testing.T) {
+		srv, attempts := newServer(t, func(_ int32, w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		})
+
+		resp, err := newClient(2).Get(srv.URL)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+		require.Equal(t, int32(3), atomic.LoadInt32(attempts))
+	})
+
+	t.Run("does not retry other failures", func(t *testing.T) {
+		srv, attempts := newServer(t, func(_ int32, w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		})
+
+		resp, err := newClient(2).Get(srv.URL)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		require.Equal(t, int32(1), atomic.LoadInt32(attempts))
+	})
+
+	t.Run("resends the request body", func(t *testing.T) {
+		srv, attempts := newServer(t, func(attempt int32, w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			require.Equal(t, `{"message":"Hello World!"}`, string(body))
+
+			if attempt == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		})
+
+		resp, err := newClient(1).Post(srv.URL, "application/json", bytes.NewBufferString(`{"message":"Hello World!"}`))
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, int32(2), atomic.LoadInt32(attempts))
+	})
+
+	t.Run("honors Retry-After dates", func(t *testing.T) {
+		srv, attempts := newServer(t, func(attempt int32, w http.ResponseWriter, _ *http.Request) {
+			if attempt == 1 {
+				w.Header().Set("Retry-After", time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat))
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		})
+
+		resp, err := newClient(1).Get(srv.URL)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, int32(2), atomic.LoadInt32(attempts))
+	})
+
+	t.Run("stops waiting when the request is canceled", func(t *testing.T) {
+		srv, attempts := newServer(t, func(_ int32, w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Retry-After", "5")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+		require.NoError(t, err)
+
+		_, err = newClient(1).Do(req) // nolint:bodyclose // no response on error
+		require.Error(t, err)
+		require.Contains(t, err.Error(), context.DeadlineExceeded.Error())
+		require.Equal(t, int32(1), atomic.LoadInt32(attempts))
+	})
+}
@@ -0,0 +1,85 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose"
+
+	did2 "github.com/trustbloc/ace/pkg/did"
+	openapi "github.com/trustbloc/ace/pkg/restapi/csh/operation/models"
+)
+
+// jwsAlgorithm is the algorithm of the signatures of the hub, whose identity keys are Ed25519 keys.
+const jwsAlgorithm = "EdDSA"
+
+// SignedExtraction is the response of the extractions requested with a nonce.
+type SignedExtraction struct {
+	// JWS is the compact JWS of a SignedExtractionPayload, signed with the authentication key of the hub's DID.
+	// Its kid header is the DID URL of the key.
+	JWS string `json:"jws"`
+}
+
+// SignedExtractionPayload binds extractions to the request they answer: relying parties check that the nonce is
+// the one they sent, so that a signed response cannot be replayed to them.
+type SignedExtractionPayload struct {
+	Nonce       string                     `json:"nonce"`
+	Extractions openapi.ExtractionResponse `json:"extractions"`
+}
+
+// signExtractions signs the extractions along with the nonce of their request.
+func (o *Operation) signExtractions(nonce string, extractions openapi.ExtractionResponse) (*SignedExtraction, error) {
+	identity, err := o.identityConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load identity: %w", err)
+	}
+
+	authentication, err := did2.VerificationMethods(identity.DIDDoc, did.Authentication)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the authentication key of the identity: %w", err)
+	}
+
+	handle, err := o.aries.KMS.Get(identity.AuthKeyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch authentication key id [%s]: %w", identity.AuthKeyID, err)
+	}
+
+	payload, err := json.Marshal(&SignedExtractionPayload{Nonce: nonce, Extractions: extractions})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal signed extractions: %w", err)
+	}
+
+	jws, err := jose.NewJWS(nil, nil, payload, &jwsSigner{
+		signer: signer{c: o.aries.Crypto, kh: handle},
+		kid:    authentication[0].ID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign extractions: %w", err)
+	}
+
+	compact, err := jws.SerializeCompact(false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize signed extractions: %w", err)
+	}
+
+	return &SignedExtraction{JWS: compact}, nil
+}
+
+type jwsSigner struct {
+	signer
+	kid string
+}
+
+func (s *jwsSigner) Headers() jose.Headers {
+	return jose.Headers{
+		jose.HeaderAlgorithm: jwsAlgorithm,
+		jose.HeaderKeyID:     s.kid,
+	}
+}
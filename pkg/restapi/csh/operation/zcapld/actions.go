@@ -0,0 +1,111 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package zcapld
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// ErrNoAction is returned by ActionMapper when none of its rules match the request.
+var ErrNoAction = errors.New("no invocation action for request")
+
+// ActionRule maps requests to a capability invocation action.
+type ActionRule struct {
+	// Method is the HTTP method of the request. An empty method matches all methods.
+	Method string
+	// Path is a pattern of '/'-separated segments, each matched with path.Match, against the trailing segments of
+	// the request's path (eg. "keys/*/sign"). A pattern starting with '/' must match the whole path instead.
+	// An empty pattern matches all paths.
+	Path string
+	// Action is the capability invocation action of the matched requests.
+	Action string
+}
+
+// ActionMapper determines capability invocation actions from a declarative table of rules.
+// Rules are evaluated in order and the first match wins.
+type ActionMapper struct {
+	rules []ActionRule
+}
+
+// NewActionMapper returns a new ActionMapper with the given rules.
+func NewActionMapper(rules ...ActionRule) *ActionMapper {
+	return &ActionMapper{rules: rules}
+}
+
+// EDVActions maps GET requests to the "read" action and all other requests to "write".
+func EDVActions() *ActionMapper {
+	return NewActionMapper(
+		ActionRule{Method: http.MethodGet, Action: "read"},
+		ActionRule{Action: "write"},
+	)
+}
+
+// KMSActions maps the WebKMS keystore operations to their actions.
+func KMSActions() *ActionMapper {
+	return NewActionMapper(
+		ActionRule{Method: http.MethodPost, Path: "keys/*/sign", Action: "sign"},
+		ActionRule{Method: http.MethodPost, Path: "keys/*/wrap", Action: "wrap"},
+		ActionRule{Method: http.MethodPost, Path: "wrap", Action: "wrap"},
+		ActionRule{Method: http.MethodPost, Path: "keys/*/unwrap", Action: "unwrap"},
+		ActionRule{Method: http.MethodGet, Path: "keys/*/export", Action: "exportKey"},
+		ActionRule{Method: http.MethodPost, Path: "keys", Action: "createKey"},
+	)
+}
+
+// Action returns the action of the first rule matching the request. It is meant to be passed to NewHTTPSigner.
+func (m *ActionMapper) Action(r *http.Request) (string, error) {
+	for i := range m.rules {
+		rule := m.rules[i]
+
+		if rule.Method != "" && !strings.EqualFold(rule.Method, r.Method) {
+			continue
+		}
+
+		matched, err := matchPath(rule.Path, r.URL.Path)
+		if err != nil {
+			return "", fmt.Errorf("invalid path pattern [%s]: %w", rule.Path, err)
+		}
+
+		if matched {
+			return rule.Action, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: %s %s", ErrNoAction, r.Method, r.URL.Path)
+}
+
+func matchPath(pattern, urlPath string) (bool, error) {
+	if pattern == "" {
+		return true, nil
+	}
+
+	patternSegments := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(urlPath, "/"), "/")
+
+	if strings.HasPrefix(pattern, "/") && len(patternSegments) != len(pathSegments) {
+		return false, nil
+	}
+
+	if len(patternSegments) > len(pathSegments) {
+		return false, nil
+	}
+
+	pathSegments = pathSegments[len(pathSegments)-len(patternSegments):]
+
+	for i := range patternSegments {
+		matched, err := path.Match(patternSegments[i], pathSegments[i])
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
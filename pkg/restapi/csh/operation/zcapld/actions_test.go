@@ -0,0 +1,112 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package zcapld_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/ace/pkg/restapi/csh/operation/zcapld"
+)
+
+func TestEDVActions(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		action string
+	}{
+		{http.MethodGet, "/encrypted-data-vaults/vault1/documents/doc1", "read"},
+		{http.MethodPost, "/encrypted-data-vaults/vault1/documents", "write"},
+		{http.MethodPost, "/encrypted-data-vaults/vault1/query", "write"},
+		{http.MethodPut, "/encrypted-data-vaults/vault1/documents/doc1", "write"},
+	}
+
+	for _, test := range tests {
+		action, err := zcapld.EDVActions().Action(httptest.NewRequest(test.method, test.path, nil))
+		require.NoError(t, err)
+		require.Equal(t, test.action, action, "%s %s", test.method, test.path)
+	}
+}
+
+func TestKMSActions(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		tests := []struct {
+			method string
+			path   string
+			action string
+		}{
+			{http.MethodPost, "/v1/keystores/ks1/keys", "createKey"},
+			{http.MethodPost, "/v1/keystores/ks1/keys/key1/sign", "sign"},
+			{http.MethodPost, "/v1/keystores/ks1/wrap", "wrap"},
+			{http.MethodPost, "/v1/keystores/ks1/keys/key1/wrap", "wrap"},
+			{http.MethodPost, "/v1/keystores/ks1/keys/key1/unwrap", "unwrap"},
+			{http.MethodGet, "/v1/keystores/ks1/keys/key1/export", "exportKey"},
+		}
+
+		for _, test := range tests {
+			action, err := zcapld.KMSActions().Action(httptest.NewRequest(test.method, test.path, nil))
+			require.NoError(t, err)
+			require.Equal(t, test.action, action, "%s %s", test.method, test.path)
+		}
+	})
+
+	t.Run("error if no rule matches", func(t *testing.T) {
+		_, err := zcapld.KMSActions().Action(httptest.NewRequest(http.MethodGet, "/v1/keystores/ks1/keys/key1/sign", nil))
+		require.True(t, errors.Is(err, zcapld.ErrNoAction))
+
+		_, err = zcapld.KMSActions().Action(httptest.NewRequest(http.MethodPost, "/v1/keystores/ks1/keys/key1/decrypt", nil))
+		require.True(t, errors.Is(err, zcapld.ErrNoAction))
+	})
+}
+
+func TestActionMapper_Action(t *testing.T) {
+	t.Run("first matching rule wins", func(t *testing.T) {
+		mapper := zcapld.NewActionMapper(
+			zcapld.ActionRule{Path: "docs/*", Action: "first"},
+			zcapld.ActionRule{Path: "docs/*", Action: "second"},
+		)
+
+		action, err := mapper.Action(httptest.NewRequest(http.MethodGet, "/vaults/v1/docs/d1", nil))
+		require.NoError(t, err)
+		require.Equal(t, "first", action)
+	})
+
+	t.Run("anchored patterns match the whole path", func(t *testing.T) {
+		mapper := zcapld.NewActionMapper(
+			zcapld.ActionRule{Path: "/docs/*", Action: "anchored"},
+			zcapld.ActionRule{Path: "docs/*", Action: "suffix"},
+		)
+
+		action, err := mapper.Action(httptest.NewRequest(http.MethodGet, "/docs/d1", nil))
+		require.NoError(t, err)
+		require.Equal(t, "anchored", action)
+
+		action, err = mapper.Action(httptest.NewRequest(http.MethodGet, "/vaults/v1/docs/d1", nil))
+		require.NoError(t, err)
+		require.Equal(t, "suffix", action)
+	})
+
+	t.Run("methods are case insensitive", func(t *testing.T) {
+		mapper := zcapld.NewActionMapper(zcapld.ActionRule{Method: "get", Action: "read"})
+
+		action, err := mapper.Action(httptest.NewRequest(http.MethodGet, "/docs", nil))
+		require.NoError(t, err)
+		require.Equal(t, "read", action)
+	})
+
+	t.Run("error if pattern is invalid", func(t *testing.T) {
+		mapper := zcapld.NewActionMapper(zcapld.ActionRule{Path: "[", Action: "read"})
+
+		_, err := mapper.Action(httptest.NewRequest(http.MethodGet, "/docs", nil))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid path pattern")
+	})
+}
@@ -0,0 +1,276 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package zcapld
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr"
+)
+
+// DID URL query parameters selecting a version of the DID document. They are passed on to resolvers as options.
+const (
+	versionIDParam   = "versionId"
+	versionTimeParam = "versionTime"
+)
+
+// DIDCacheStats counts the lookups of a DIDCache.
+type DIDCacheStats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+// DIDCache caches resolved DID documents for a TTL. It is safe for concurrent use and meant to be shared by all
+// DIDSignatureHashAlgorithms. It holds at most a maximum number of documents: once full, expired documents are
+// dropped and then the documents resolved first. Expired documents are also dropped by Sweep, eg. run periodically
+// with SweepEvery.
+//
+// Documents resolved for a versionId or versionTime are cached apart from the latest version of the DID. The latest
+// version is only reused for such lookups when it is known to be the requested version, and is dropped when a
+// versionId it does not match is requested: the DID was likely updated, eg. to rotate its keys.
+type DIDCache struct {
+	ttl     time.Duration
+	maxSize int
+	now     func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]map[didVersion]*didCacheEntry
+	// size is the number of cached documents, all versions included.
+	size int
+
+	hits   uint64
+	misses uint64
+}
+
+type didCacheEntry struct {
+	resolution *did.DocResolution
+	resolved   time.Time
+}
+
+// didVersion selects a version of a DID document. The zero value selects the latest version.
+type didVersion struct {
+	ID   string
+	Time string
+}
+
+func versionOf(query url.Values) didVersion {
+	return didVersion{ID: query.Get(versionIDParam), Time: query.Get(versionTimeParam)}
+}
+
+func (v didVersion) options() []vdr.DIDMethodOption {
+	var opts []vdr.DIDMethodOption
+
+	if v.ID != "" {
+		opts = append(opts, vdr.WithOption(versionIDParam, v.ID))
+	}
+
+	if v.Time != "" {
+		opts = append(opts, vdr.WithOption(versionTimeParam, v.Time))
+	}
+
+	return opts
+}
+
+// NewDIDCache returns a DIDCache keeping at most maxSize documents for the TTL. The size is not limited if maxSize
+// is not positive.
+func NewDIDCache(ttl time.Duration, maxSize int) *DIDCache {
+	return &DIDCache{
+		ttl:     ttl,
+		maxSize: maxSize,
+		now:     time.Now,
+		entries: make(map[string]map[didVersion]*didCacheEntry),
+	}
+}
+
+// Invalidate drops all cached versions of the DID document.
+func (c *DIDCache) Invalidate(didID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.size -= len(c.entries[didID])
+
+	delete(c.entries, didID)
+}
+
+// Len returns the number of cached documents, all versions included.
+func (c *DIDCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.size
+}
+
+// Sweep drops the expired documents and returns their number.
+func (c *DIDCache) Sweep() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.sweep()
+}
+
+// SweepEvery runs Sweep every interval until the context is done.
+func (c *DIDCache) SweepEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if dropped := c.Sweep(); dropped > 0 {
+			logger.Debugf("swept %d expired DID documents from the cache", dropped)
+		}
+	}
+}
+
+// Stats returns the number of lookups served from the cache and the number of lookups that were not.
+func (c *DIDCache) Stats() DIDCacheStats {
+	return DIDCacheStats{
+		Hits:   atomic.LoadUint64(&c.hits),
+		Misses: atomic.LoadUint64(&c.misses),
+	}
+}
+
+func (c *DIDCache) get(didID string, version didVersion) (*did.DocResolution, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	resolution := c.lookup(didID, version)
+	if resolution == nil {
+		atomic.AddUint64(&c.misses, 1)
+
+		return nil, false
+	}
+
+	atomic.AddUint64(&c.hits, 1)
+
+	return resolution, true
+}
+
+func (c *DIDCache) lookup(didID string, version didVersion) *did.DocResolution {
+	versions := c.entries[didID]
+	now := c.now()
+
+	for v, e := range versions {
+		if now.Sub(e.resolved) >= c.ttl {
+			c.drop(didID, v)
+		}
+	}
+
+	if e, ok := versions[version]; ok {
+		return e.resolution
+	}
+
+	latest, ok := versions[didVersion{}]
+	if !ok {
+		return nil
+	}
+
+	switch {
+	case version.ID != "":
+		if latest.resolution.DocumentMetadata != nil && latest.resolution.DocumentMetadata.VersionID == version.ID {
+			return latest.resolution
+		}
+
+		c.drop(didID, didVersion{})
+	case version.Time != "":
+		// the latest version was already current at versionTime if it was updated before
+		t, err := time.Parse(time.RFC3339, version.Time)
+		updated := latest.resolution.DIDDocument.Updated
+
+		if err == nil && updated != nil && !updated.After(t) && !t.After(latest.resolved) {
+			return latest.resolution
+		}
+	}
+
+	return nil
+}
+
+func (c *DIDCache) put(didID string, version didVersion, resolution *did.DocResolution) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[didID][version]; !ok {
+		if c.maxSize > 0 && c.size >= c.maxSize {
+			c.sweep()
+		}
+
+		for c.maxSize > 0 && c.size >= c.maxSize {
+			c.evictOldest()
+		}
+
+		c.size++
+	}
+
+	if c.entries[didID] == nil {
+		c.entries[didID] = make(map[didVersion]*didCacheEntry)
+	}
+
+	c.entries[didID][version] = &didCacheEntry{resolution: resolution, resolved: c.now()}
+}
+
+// sweep drops the expired documents and returns their number. It must be called with the lock held.
+func (c *DIDCache) sweep() int {
+	now := c.now()
+	dropped := 0
+
+	for didID, versions := range c.entries {
+		for v, e := range versions {
+			if now.Sub(e.resolved) >= c.ttl {
+				c.drop(didID, v)
+
+				dropped++
+			}
+		}
+	}
+
+	return dropped
+}
+
+// evictOldest drops the document resolved first. It must be called with the lock held.
+func (c *DIDCache) evictOldest() {
+	var (
+		oldestID      string
+		oldestVersion didVersion
+		oldest        *didCacheEntry
+	)
+
+	for didID, versions := range c.entries {
+		for v, e := range versions {
+			if oldest == nil || e.resolved.Before(oldest.resolved) {
+				oldestID, oldestVersion, oldest = didID, v, e
+			}
+		}
+	}
+
+	if oldest != nil {
+		c.drop(oldestID, oldestVersion)
+	}
+}
+
+// drop drops a version of the DID document. It must be called with the lock held.
+func (c *DIDCache) drop(didID string, version didVersion) {
+	versions := c.entries[didID]
+	if _, ok := versions[version]; !ok {
+		return
+	}
+
+	delete(versions, version)
+	c.size--
+
+	if len(versions) == 0 {
+		delete(c.entries, didID)
+	}
+}
@@ -0,0 +1,276 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package zcapld_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/hyperledger/aries-framework-go/pkg/vdr/fingerprint"
+	"github.com/hyperledger/aries-framework-go/pkg/vdr/key"
+	"github.com/igor-pavlenko/httpsignatures-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/ace/pkg/restapi/csh/operation/zcapld"
+)
+
+func TestDIDCache(t *testing.T) {
+	t.Run("hit and miss", func(t *testing.T) {
+		agent := newAgent(t)
+		resolver := &versionedResolver{DIDResolver: key.New()}
+		cache := zcapld.NewDIDCache(time.Minute, 0)
+
+		a := &zcapld.DIDSignatureHashAlgorithms{
+			KMS:       agent.KMS(),
+			Crypto:    agent.Crypto(),
+			Resolvers: []zcapld.DIDResolver{resolver},
+			Cache:     cache,
+		}
+
+		secret := httpsignatures.Secret{KeyID: newVerMethod(t, agent.KMS())}
+
+		signature, err := a.Create(secret, []byte("hello world"))
+		require.NoError(t, err)
+
+		err = a.Verify(secret, []byte("hello world"), signature)
+		require.NoError(t, err)
+
+		require.Equal(t, 1, resolver.reads)
+		require.Equal(t, zcapld.DIDCacheStats{Hits: 1, Misses: 1}, cache.Stats())
+
+		other := httpsignatures.Secret{KeyID: newVerMethod(t, agent.KMS())}
+
+		_, err = a.Create(other, []byte("hello world"))
+		require.NoError(t, err)
+
+		require.Equal(t, 2, resolver.reads)
+		require.Equal(t, zcapld.DIDCacheStats{Hits: 1, Misses: 2}, cache.Stats())
+	})
+
+	t.Run("invalidation", func(t *testing.T) {
+		agent := newAgent(t)
+		resolver := &versionedResolver{DIDResolver: key.New()}
+		cache := zcapld.NewDIDCache(time.Minute, 0)
+
+		a := &zcapld.DIDSignatureHashAlgorithms{
+			KMS:       agent.KMS(),
+			Crypto:    agent.Crypto(),
+			Resolvers: []zcapld.DIDResolver{resolver},
+			Cache:     cache,
+		}
+
+		_, pubKeyBytes, err := agent.KMS().CreateAndExportPubKeyBytes(kms.ED25519Type)
+		require.NoError(t, err)
+
+		didKey, didKeyURL := fingerprint.CreateDIDKey(pubKeyBytes)
+		secret := httpsignatures.Secret{KeyID: didKeyURL}
+
+		_, err = a.Create(secret, nil)
+		require.NoError(t, err)
+
+		cache.Invalidate(didKey)
+
+		_, err = a.Create(secret, nil)
+		require.NoError(t, err)
+
+		require.Equal(t, 2, resolver.reads)
+		require.Equal(t, zcapld.DIDCacheStats{Misses: 2}, cache.Stats())
+	})
+
+	t.Run("expiry", func(t *testing.T) {
+		agent := newAgent(t)
+		resolver := &versionedResolver{DIDResolver: key.New()}
+
+		a := &zcapld.DIDSignatureHashAlgorithms{
+			KMS:       agent.KMS(),
+			Crypto:    agent.Crypto(),
+			Resolvers: []zcapld.DIDResolver{resolver},
+			Cache:     zcapld.NewDIDCache(time.Nanosecond, 0),
+		}
+
+		secret := httpsignatures.Secret{KeyID: newVerMethod(t, agent.KMS())}
+
+		_, err := a.Create(secret, nil)
+		require.NoError(t, err)
+
+		time.Sleep(time.Millisecond)
+
+		_, err = a.Create(secret, nil)
+		require.NoError(t, err)
+
+		require.Equal(t, 2, resolver.reads)
+	})
+
+	t.Run("honors versionId", func(t *testing.T) {
+		resolver := &versionedResolver{latest: "1"}
+		cache := zcapld.NewDIDCache(time.Minute, 0)
+
+		a := &zcapld.DIDSignatureHashAlgorithms{Resolvers: []zcapld.DIDResolver{resolver}, Cache: cache}
+
+		resolve := func(keyID string) string {
+			_, err := a.Create(httpsignatures.Secret{KeyID: keyID}, nil)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "unsupported verificationMethod type")
+
+			return resolver.versionID
+		}
+
+		require.Empty(t, resolve("did:example:123#key1"))
+		require.Equal(t, 1, resolver.reads)
+
+		// the cached latest version is the requested one
+		resolve("did:example:123?versionId=1#key1")
+		require.Equal(t, 1, resolver.reads)
+
+		// the DID was updated: neither the requested version nor the latest one are served from the cache
+		resolver.latest = "2"
+
+		require.Equal(t, "2", resolve("did:example:123?versionId=2#key1"))
+		require.Equal(t, 2, resolver.reads)
+
+		require.Empty(t, resolve("did:example:123#key1"))
+		require.Equal(t, 3, resolver.reads)
+
+		// a pinned version is cached on its own
+		resolve("did:example:123?versionId=2#key1")
+		require.Equal(t, 3, resolver.reads)
+		require.Equal(t, zcapld.DIDCacheStats{Hits: 2, Misses: 3}, cache.Stats())
+	})
+
+	t.Run("honors versionTime", func(t *testing.T) {
+		updated := time.Now().Add(-time.Hour).UTC()
+		resolver := &versionedResolver{latest: "1", updated: &updated}
+
+		a := &zcapld.DIDSignatureHashAlgorithms{
+			Resolvers: []zcapld.DIDResolver{resolver},
+			Cache:     zcapld.NewDIDCache(time.Minute, 0),
+		}
+
+		for _, keyID := range []string{
+			"did:example:123#key1",
+			// the latest version was already current
+			"did:example:123?versionTime=" + updated.Add(time.Minute).Format(time.RFC3339) + "#key1",
+			// the latest version was not yet current
+			"did:example:123?versionTime=" + updated.Add(-time.Minute).Format(time.RFC3339) + "#key1",
+		} {
+			_, err := a.Create(httpsignatures.Secret{KeyID: keyID}, nil)
+			require.Error(t, err)
+		}
+
+		require.Equal(t, 2, resolver.reads)
+		require.NotEmpty(t, resolver.versionTime)
+	})
+
+	t.Run("size cap", func(t *testing.T) {
+		resolver := &versionedResolver{latest: "1"}
+		cache := zcapld.NewDIDCache(time.Minute, 1)
+
+		a := &zcapld.DIDSignatureHashAlgorithms{Resolvers: []zcapld.DIDResolver{resolver}, Cache: cache}
+
+		for _, keyID := range []string{"did:example:123#key1", "did:example:456#key1", "did:example:123#key1"} {
+			_, err := a.Create(httpsignatures.Secret{KeyID: keyID}, nil)
+			require.Error(t, err)
+		}
+
+		// the first DID was evicted to cache the second one
+		require.Equal(t, 3, resolver.reads)
+		require.Equal(t, 1, cache.Len())
+	})
+
+	t.Run("sweep", func(t *testing.T) {
+		resolver := &versionedResolver{latest: "1"}
+		cache := zcapld.NewDIDCache(time.Millisecond, 0)
+
+		a := &zcapld.DIDSignatureHashAlgorithms{Resolvers: []zcapld.DIDResolver{resolver}, Cache: cache}
+
+		for _, keyID := range []string{"did:example:123#key1", "did:example:456#key1"} {
+			_, err := a.Create(httpsignatures.Secret{KeyID: keyID}, nil)
+			require.Error(t, err)
+		}
+
+		require.Equal(t, 2, cache.Len())
+
+		time.Sleep(2 * time.Millisecond)
+
+		require.Equal(t, 2, cache.Sweep())
+		require.Zero(t, cache.Len())
+	})
+
+	t.Run("sweep every interval", func(t *testing.T) {
+		resolver := &versionedResolver{latest: "1"}
+		cache := zcapld.NewDIDCache(time.Millisecond, 0)
+
+		a := &zcapld.DIDSignatureHashAlgorithms{Resolvers: []zcapld.DIDResolver{resolver}, Cache: cache}
+
+		_, err := a.Create(httpsignatures.Secret{KeyID: "did:example:123#key1"}, nil)
+		require.Error(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go cache.SweepEvery(ctx, time.Millisecond)
+
+		require.Eventually(t, func() bool { return cache.Len() == 0 }, time.Second, time.Millisecond)
+	})
+}
+
+// versionedResolver counts reads. Unless it wraps another resolver, it resolves did:example DIDs whose latest
+// version is latest.
+type versionedResolver struct {
+	zcapld.DIDResolver
+	latest      string
+	updated     *time.Time
+	reads       int
+	versionID   string
+	versionTime string
+}
+
+func (r *versionedResolver) Accept(method string) bool {
+	if r.DIDResolver != nil {
+		return r.DIDResolver.Accept(method)
+	}
+
+	return method == "example"
+}
+
+func (r *versionedResolver) Read(didID string, opts ...vdr.DIDMethodOption) (*did.DocResolution, error) {
+	r.reads++
+
+	if r.DIDResolver != nil {
+		return r.DIDResolver.Read(didID, opts...)
+	}
+
+	options := &vdr.DIDMethodOpts{Values: map[string]interface{}{}}
+
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	r.versionID, _ = options.Values["versionId"].(string)
+	r.versionTime, _ = options.Values["versionTime"].(string)
+
+	version := r.versionID
+	if version == "" {
+		version = r.latest
+	}
+
+	return &did.DocResolution{
+		DIDDocument: &did.Doc{
+			ID:      didID,
+			Updated: r.updated,
+			CapabilityDelegation: []did.Verification{{
+				VerificationMethod: did.VerificationMethod{ID: "#key1", Type: "UNSUPPORTED"},
+				Relationship:       did.CapabilityDelegation,
+			}},
+		},
+		DocumentMetadata: &did.DocumentMetadata{VersionID: version},
+	}, nil
+}
@@ -0,0 +1,248 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package zcapld
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/jsonld"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/proof"
+	ariessigner "github.com/hyperledger/aries-framework-go/pkg/doc/signature/signer"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/suite"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/suite/ed25519signature2018"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/suite/jsonwebsignature2020"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/verifier"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/piprate/json-gold/ld"
+	"github.com/trustbloc/edge-core/pkg/zcapld"
+)
+
+const nonceSize = 64
+
+// Capability is a zcap that may expire. The `expires` timestamp is covered by the capability's proof.
+type Capability struct {
+	*zcapld.Capability
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
+// NewCapability constructs a new, signed Capability with the options provided. The capability expires
+// at the given time unless it is nil.
+func NewCapability(signer *zcapld.Signer, expires *time.Time,
+	options ...zcapld.CapabilityOption) (*Capability, error) {
+	if expires == nil {
+		zcap, err := zcapld.NewCapability(signer, options...)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Capability{Capability: zcap}, nil
+	}
+
+	if signer == nil {
+		return nil, errors.New("must provide a signer")
+	}
+
+	opts := &zcapld.CapabilityOptions{
+		ID: uuid.New().URN(),
+	}
+
+	for i := range options {
+		options[i](opts)
+	}
+
+	exp := expires.UTC().Truncate(time.Second)
+
+	zcap := &Capability{
+		Capability: &zcapld.Capability{
+			Context:          zcapld.SecurityContextV2,
+			ID:               opts.ID,
+			Invoker:          opts.Invoker,
+			Controller:       opts.Controller,
+			Delegator:        opts.Delegator,
+			Parent:           opts.Parent,
+			AllowedAction:    opts.AllowedAction,
+			InvocationTarget: opts.InvocationTarget,
+			Caveats:          opts.Caveats,
+		},
+		Expires: &exp,
+	}
+
+	err := sign(zcap, signer, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign zcap: %w", err)
+	}
+
+	return zcap, nil
+}
+
+// CompressOpt configures the compression of a zcap.
+type CompressOpt func(*compressOpts)
+
+type compressOpts struct {
+	level     int
+	threshold int
+}
+
+// WithCompressionLevel sets the gzip level of the compression, from gzip.HuffmanOnly to gzip.BestCompression.
+// Defaults to gzip.DefaultCompression.
+func WithCompressionLevel(level int) CompressOpt {
+	return func(opts *compressOpts) {
+		opts.level = level
+	}
+}
+
+// WithCompressionThreshold leaves zcaps whose JSON encoding is shorter than size bytes uncompressed: gzip only
+// adds overhead to them. Defaults to 0, which compresses all zcaps.
+func WithCompressionThreshold(size int) CompressOpt {
+	return func(opts *compressOpts) {
+		opts.threshold = size
+	}
+}
+
+// CompressZCAP gzips and base64URL-encodes the zcap, including its expiry. Zcaps below the compression threshold
+// are base64URL-encoded as is; DecompressZCAP tells both encodings apart.
+func CompressZCAP(zcap *Capability, options ...CompressOpt) (string, error) {
+	opts := &compressOpts{level: gzip.DefaultCompression}
+
+	for i := range options {
+		options[i](opts)
+	}
+
+	raw, err := json.Marshal(zcap)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal zcap: %w", err)
+	}
+
+	if len(raw) < opts.threshold {
+		return base64.URLEncoding.EncodeToString(raw), nil
+	}
+
+	compressed := bytes.NewBuffer(nil)
+
+	w, err := gzip.NewWriterLevel(compressed, opts.level)
+	if err != nil {
+		return "", fmt.Errorf("failed to init gzip writer: %w", err)
+	}
+
+	_, err = w.Write(raw)
+	if err != nil {
+		return "", fmt.Errorf("failed to compress zcap: %w", err)
+	}
+
+	err = w.Close()
+	if err != nil {
+		return "", fmt.Errorf("failed to compress zcap: %w", err)
+	}
+
+	return base64.URLEncoding.EncodeToString(compressed.Bytes()), nil
+}
+
+// DecompressZCAP parses a zcap compressed by CompressZCAP, including its expiry.
+func DecompressZCAP(compressed string) (*Capability, error) {
+	raw, err := decompress(compressed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress zcap: %w", err)
+	}
+
+	zcap := &Capability{}
+
+	err = json.Unmarshal(raw, zcap)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse zcap: %w", err)
+	}
+
+	if zcap.Capability == nil || zcap.InvocationTarget.ID == "" {
+		return nil, errors.New("failed to parse zcap: missing invocation target")
+	}
+
+	return zcap, nil
+}
+
+// VerifyCapability verifies the proofs of the zcap, including its expiry, with the keys of their verification
+// methods.
+func VerifyCapability(zcap *Capability, keys PublicKeyResolver, documentLoader ld.DocumentLoader) error {
+	if len(zcap.Proof) == 0 {
+		return errors.New("zcap has no proof")
+	}
+
+	v, err := verifier.New(keys,
+		ed25519signature2018.New(suite.WithVerifier(ed25519signature2018.NewPublicKeyVerifier())),
+		jsonwebsignature2020.New(suite.WithVerifier(jsonwebsignature2020.NewPublicKeyVerifier())),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to init verifier: %w", err)
+	}
+
+	raw, err := json.Marshal(zcap)
+	if err != nil {
+		return fmt.Errorf("failed to marshal zcap: %w", err)
+	}
+
+	err = v.Verify(raw, jsonld.WithDocumentLoader(documentLoader))
+	if err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+
+	return nil
+}
+
+// sign mirrors the framework's signing of new capabilities, which cannot include `expires`.
+func sign(zcap *Capability, signer *zcapld.Signer, opts *zcapld.CapabilityOptions) error {
+	raw, err := json.Marshal(zcap)
+	if err != nil {
+		return fmt.Errorf("failed to marshal zcap: %w", err)
+	}
+
+	nonce := make([]byte, nonceSize)
+
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return fmt.Errorf("failed to generate a nonce: %w", err)
+	}
+
+	now := time.Now()
+
+	signed, err := ariessigner.New(signer).Sign(
+		&ariessigner.Context{
+			SignatureType:           signer.SuiteType,
+			SignatureRepresentation: proof.SignatureJWS,
+			Created:                 &now,
+			Domain:                  opts.Domain,
+			Nonce:                   nonce,
+			VerificationMethod:      signer.VerificationMethod,
+			Challenge:               opts.Challenge,
+			Purpose:                 zcapld.ProofPurpose,
+			CapabilityChain:         opts.CapabilityChain,
+		},
+		raw,
+		signer.ProcessorOpts...,
+	)
+	if err != nil {
+		return fmt.Errorf("document signer failed to sign zcap: %w", err)
+	}
+
+	proofs := &struct {
+		Proof []verifiable.Proof `json:"proof,omitempty"`
+	}{}
+
+	err = json.Unmarshal(signed, proofs)
+	if err != nil {
+		return fmt.Errorf("failed to parse proof for zcap: %w", err)
+	}
+
+	zcap.Proof = proofs.Proof
+
+	return nil
+}
@@ -0,0 +1,195 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package zcapld_test
+
+import (
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/jsonld"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/suite"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/suite/ed25519signature2018"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/verifier"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/util/signature"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/stretchr/testify/require"
+	zcapld2 "github.com/trustbloc/edge-core/pkg/zcapld"
+
+	"github.com/trustbloc/ace/pkg/internal/testutil"
+	"github.com/trustbloc/ace/pkg/restapi/csh/operation/zcapld"
+)
+
+func TestNewCapability(t *testing.T) {
+	t.Run("signs the expiry", func(t *testing.T) {
+		signer, pubKey := newZCAPSigner(t)
+		expires := time.Now().Add(time.Hour)
+
+		zcap, err := zcapld.NewCapability(signer, &expires,
+			zcapld2.WithID("urn:uuid:123"),
+			zcapld2.WithInvocationTarget("urn:uuid:123", "urn:test"),
+		)
+		require.NoError(t, err)
+		require.Equal(t, "urn:uuid:123", zcap.ID)
+		require.True(t, expires.Truncate(time.Second).Equal(*zcap.Expires))
+		require.NotEmpty(t, zcap.Proof)
+
+		compressed, err := zcapld.CompressZCAP(zcap)
+		require.NoError(t, err)
+
+		result, err := zcapld.Expires(compressed)
+		require.NoError(t, err)
+		require.True(t, zcap.Expires.Equal(*result))
+
+		raw, err := json.Marshal(zcap)
+		require.NoError(t, err)
+		require.NoError(t, verifyProof(t, raw, pubKey))
+
+		tampered := make(map[string]interface{})
+		require.NoError(t, json.Unmarshal(raw, &tampered))
+
+		tampered["expires"] = expires.Add(time.Hour).UTC().Format(time.RFC3339)
+
+		raw, err = json.Marshal(tampered)
+		require.NoError(t, err)
+		require.Error(t, verifyProof(t, raw, pubKey))
+	})
+
+	t.Run("does not expire by default", func(t *testing.T) {
+		signer, _ := newZCAPSigner(t)
+
+		zcap, err := zcapld.NewCapability(signer, nil, zcapld2.WithInvocationTarget("urn:uuid:123", "urn:test"))
+		require.NoError(t, err)
+		require.Nil(t, zcap.Expires)
+
+		compressed, err := zcapld.CompressZCAP(zcap)
+		require.NoError(t, err)
+
+		result, err := zcapld.Expires(compressed)
+		require.NoError(t, err)
+		require.Nil(t, result)
+	})
+
+	t.Run("error if signer is missing", func(t *testing.T) {
+		expires := time.Now().Add(time.Hour)
+
+		_, err := zcapld.NewCapability(nil, &expires)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "must provide a signer")
+	})
+}
+
+func TestCompressZCAP(t *testing.T) {
+	signer, _ := newZCAPSigner(t)
+	expires := time.Now().Add(time.Hour)
+
+	small := &zcapld.Capability{Capability: &zcapld2.Capability{
+		ID:               "urn:uuid:123",
+		InvocationTarget: zcapld2.InvocationTarget{ID: "urn:uuid:123", Type: "urn:test"},
+	}}
+
+	large, err := zcapld.NewCapability(signer, &expires,
+		zcapld2.WithInvocationTarget("urn:uuid:123", "urn:test"),
+		zcapld2.WithAllowedActions("read", "write", "delete"),
+	)
+	require.NoError(t, err)
+
+	largeRaw, err := json.Marshal(large)
+	require.NoError(t, err)
+
+	threshold := zcapld.WithCompressionThreshold(len(largeRaw) / 2)
+
+	t.Run("round-trips small and large zcaps", func(t *testing.T) {
+		for _, zcap := range []*zcapld.Capability{small, large} {
+			for _, opts := range [][]zcapld.CompressOpt{
+				nil,
+				{threshold},
+				{zcapld.WithCompressionLevel(gzip.BestCompression), threshold},
+				{zcapld.WithCompressionLevel(gzip.NoCompression)},
+			} {
+				compressed, err := zcapld.CompressZCAP(zcap, opts...)
+				require.NoError(t, err)
+
+				result, err := zcapld.DecompressZCAP(compressed)
+				require.NoError(t, err)
+				require.Equal(t, zcap, result)
+			}
+		}
+	})
+
+	t.Run("does not compress zcaps below the threshold", func(t *testing.T) {
+		compressed, err := zcapld.CompressZCAP(small, threshold)
+		require.NoError(t, err)
+
+		raw, err := base64.URLEncoding.DecodeString(compressed)
+		require.NoError(t, err)
+
+		expected, err := json.Marshal(small)
+		require.NoError(t, err)
+		require.Equal(t, expected, raw)
+
+		compressed, err = zcapld.CompressZCAP(large, threshold)
+		require.NoError(t, err)
+
+		raw, err = base64.URLEncoding.DecodeString(compressed)
+		require.NoError(t, err)
+		require.Less(t, len(raw), len(largeRaw))
+	})
+
+	t.Run("compresses with the level", func(t *testing.T) {
+		fast, err := zcapld.CompressZCAP(large, zcapld.WithCompressionLevel(gzip.NoCompression))
+		require.NoError(t, err)
+
+		best, err := zcapld.CompressZCAP(large, zcapld.WithCompressionLevel(gzip.BestCompression))
+		require.NoError(t, err)
+		require.Less(t, len(best), len(fast))
+	})
+
+	t.Run("error if the level is invalid", func(t *testing.T) {
+		_, err := zcapld.CompressZCAP(large, zcapld.WithCompressionLevel(42))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to init gzip writer")
+	})
+}
+
+func newZCAPSigner(t *testing.T) (*zcapld2.Signer, []byte) {
+	t.Helper()
+
+	agent := newAgent(t)
+
+	s, err := signature.NewCryptoSigner(agent.Crypto(), agent.KMS(), kms.ED25519Type)
+	require.NoError(t, err)
+
+	return &zcapld2.Signer{
+		SignatureSuite:     ed25519signature2018.New(suite.WithSigner(s)),
+		SuiteType:          ed25519signature2018.SignatureType,
+		VerificationMethod: "did:example:123#key1",
+		ProcessorOpts:      []jsonld.ProcessorOpts{jsonld.WithDocumentLoader(testutil.DocumentLoader(t))},
+	}, s.PublicKeyBytes()
+}
+
+func verifyProof(t *testing.T, raw, pubKey []byte) error {
+	t.Helper()
+
+	v, err := verifier.New(
+		&staticKeyResolver{key: &verifier.PublicKey{Type: kms.ED25519, Value: pubKey}},
+		ed25519signature2018.New(suite.WithVerifier(ed25519signature2018.NewPublicKeyVerifier())),
+	)
+	require.NoError(t, err)
+
+	return v.Verify(raw, jsonld.WithDocumentLoader(testutil.DocumentLoader(t)))
+}
+
+type staticKeyResolver struct {
+	key *verifier.PublicKey
+}
+
+func (r *staticKeyResolver) Resolve(string) (*verifier.PublicKey, error) {
+	return r.key, nil
+}
@@ -0,0 +1,45 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package zcapld
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/trustbloc/edge-core/pkg/zcapld"
+)
+
+// DefaultMaxChainDepth is the default maximum number of delegations from a root zcap to the zcaps accepted.
+const DefaultMaxChainDepth = 10
+
+// ErrChainTooDeep is returned when a zcap was delegated more times than allowed from its root zcap.
+var ErrChainTooDeep = errors.New("capability chain too deep")
+
+// ChainDepth returns the number of delegations from the root zcap to the zcap: the length of the longest
+// `capabilityChain` of its proofs. Root zcaps have a depth of 0.
+func ChainDepth(zcap *zcapld.Capability) int {
+	depth := 0
+
+	for _, proof := range zcap.Proof {
+		chain, ok := proof["capabilityChain"].([]interface{})
+		if ok && len(chain) > depth {
+			depth = len(chain)
+		}
+	}
+
+	return depth
+}
+
+// CheckChainDepth fails with ErrChainTooDeep if the zcap is more than maxDepth delegations away from its root zcap,
+// before the zcaps of its chain are resolved.
+func CheckChainDepth(zcap *zcapld.Capability, maxDepth int) error {
+	if depth := ChainDepth(zcap); depth > maxDepth {
+		return fmt.Errorf("%w: %d delegations exceed the maximum of %d", ErrChainTooDeep, depth, maxDepth)
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package zcapld_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	zcapld2 "github.com/trustbloc/edge-core/pkg/zcapld"
+
+	"github.com/trustbloc/ace/pkg/restapi/csh/operation/zcapld"
+)
+
+func TestCheckChainDepth(t *testing.T) {
+	t.Run("accepts chains up to the maximum depth", func(t *testing.T) {
+		for _, depth := range []int{0, 1, zcapld.DefaultMaxChainDepth} {
+			zcap := delegatedZCAP(t, depth)
+			require.Equal(t, depth, zcapld.ChainDepth(zcap))
+			require.NoError(t, zcapld.CheckChainDepth(zcap, zcapld.DefaultMaxChainDepth))
+		}
+	})
+
+	t.Run("rejects chains beyond the maximum depth", func(t *testing.T) {
+		zcap := delegatedZCAP(t, zcapld.DefaultMaxChainDepth+1)
+
+		err := zcapld.CheckChainDepth(zcap, zcapld.DefaultMaxChainDepth)
+		require.ErrorIs(t, err, zcapld.ErrChainTooDeep)
+		require.Contains(t, err.Error(), "11 delegations exceed the maximum of 10")
+	})
+
+	t.Run("checks the longest chain of the proofs", func(t *testing.T) {
+		zcap := delegatedZCAP(t, 3)
+		zcap.Proof = append(zcap.Proof, delegatedZCAP(t, 1).Proof...)
+
+		require.Equal(t, 3, zcapld.ChainDepth(zcap))
+		require.ErrorIs(t, zcapld.CheckChainDepth(zcap, 2), zcapld.ErrChainTooDeep)
+	})
+}
+
+// delegatedZCAP returns a zcap parsed as it is received, `depth` delegations away from its root zcap.
+func delegatedZCAP(t *testing.T, depth int) *zcapld2.Capability {
+	t.Helper()
+
+	zcap := map[string]interface{}{"id": fmt.Sprintf("urn:uuid:%d", depth)}
+
+	if depth > 0 {
+		chain := make([]interface{}, depth)
+
+		for i := range chain {
+			chain[i] = fmt.Sprintf("urn:uuid:%d", i)
+		}
+
+		zcap["parentCapability"] = chain[depth-1]
+		zcap["proof"] = []interface{}{map[string]interface{}{
+			"proofPurpose":    "capabilityDelegation",
+			"capabilityChain": chain,
+		}}
+	}
+
+	parsed, err := zcapld2.DecompressZCAP(compressZCAP(t, zcap))
+	require.NoError(t, err)
+
+	return parsed
+}
@@ -8,6 +8,7 @@ package zcapld
 
 import (
 	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/verifier"
 	"github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr"
 	"github.com/hyperledger/aries-framework-go/pkg/kms"
 )
@@ -29,3 +30,8 @@ type Crypto interface {
 	Sign(msg []byte, kh interface{}) ([]byte, error)
 	Verify(sig, msg []byte, kh interface{}) error
 }
+
+// PublicKeyResolver resolves the public keys of verification methods.
+type PublicKeyResolver interface {
+	Resolve(verificationMethod string) (*verifier.PublicKey, error)
+}
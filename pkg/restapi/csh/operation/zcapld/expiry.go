@@ -0,0 +1,88 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package zcapld
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ErrExpired is returned when a zcap is invoked after its expiry.
+var ErrExpired = errors.New("zcap has expired")
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// CheckExpiry fails with ErrExpired if the compressed zcap's `expires` timestamp is not after `now`.
+// Zcaps without an `expires` field pass this check; expiry caveats are verified by the invocation target.
+func CheckExpiry(compressedZCAP string, now time.Time) error {
+	expires, err := Expires(compressedZCAP)
+	if err != nil {
+		return err
+	}
+
+	return checkExpires(expires, now)
+}
+
+func checkExpires(expires *time.Time, now time.Time) error {
+	if expires != nil && !now.Before(*expires) {
+		return fmt.Errorf("%w: expired at %s", ErrExpired, expires.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+// Expires returns the zcap's `expires` timestamp, or nil if it does not have one.
+func Expires(compressedZCAP string) (*time.Time, error) {
+	raw, err := decompress(compressedZCAP)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress zcap: %w", err)
+	}
+
+	// the framework's zcapld.Capability does not model `expires`
+	zcap := &struct {
+		Expires *time.Time `json:"expires,omitempty"`
+	}{}
+
+	err = json.Unmarshal(raw, zcap)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse zcap expiry: %w", err)
+	}
+
+	return zcap.Expires, nil
+}
+
+// decompress decodes a value encoded by CompressZCAP, which only gzips the values above its threshold.
+func decompress(value string) ([]byte, error) {
+	decoded, err := base64.URLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to base64URL-decode value: %w", err)
+	}
+
+	// JSON cannot start with the gzip magic number
+	if !bytes.HasPrefix(decoded, gzipMagic) {
+		return decoded, nil
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(decoded))
+	if err != nil {
+		return nil, fmt.Errorf("failed to init gzip reader: %w", err)
+	}
+
+	defer func() {
+		if closeErr := reader.Close(); closeErr != nil {
+			logger.Warnf("failed to close gzip reader: %s", closeErr.Error())
+		}
+	}()
+
+	return io.ReadAll(reader)
+}
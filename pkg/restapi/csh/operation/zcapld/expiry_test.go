@@ -0,0 +1,86 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package zcapld_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/ace/pkg/restapi/csh/operation/zcapld"
+)
+
+func TestCheckExpiry(t *testing.T) {
+	now := time.Now()
+
+	t.Run("passes if zcap has not expired", func(t *testing.T) {
+		err := zcapld.CheckExpiry(compressZCAP(t, map[string]interface{}{
+			"id":      "urn:uuid:123",
+			"expires": now.Add(time.Minute).Format(time.RFC3339),
+		}), now)
+		require.NoError(t, err)
+	})
+
+	t.Run("passes if zcap does not expire", func(t *testing.T) {
+		err := zcapld.CheckExpiry(compressZCAP(t, map[string]interface{}{"id": "urn:uuid:123"}), now)
+		require.NoError(t, err)
+	})
+
+	t.Run("fails if zcap has expired", func(t *testing.T) {
+		err := zcapld.CheckExpiry(compressZCAP(t, map[string]interface{}{
+			"id":      "urn:uuid:123",
+			"expires": now.Add(-time.Minute).Format(time.RFC3339),
+		}), now)
+		require.ErrorIs(t, err, zcapld.ErrExpired)
+	})
+
+	t.Run("passes if zcap is not compressed", func(t *testing.T) {
+		err := zcapld.CheckExpiry(base64.URLEncoding.EncodeToString([]byte("{}")), now)
+		require.NoError(t, err)
+	})
+
+	t.Run("fails if zcap is not a valid gzip stream", func(t *testing.T) {
+		err := zcapld.CheckExpiry(base64.URLEncoding.EncodeToString([]byte{0x1f, 0x8b, 0x00}), now)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to decompress zcap")
+	})
+
+	t.Run("fails if zcap is not base64URL encoded", func(t *testing.T) {
+		err := zcapld.CheckExpiry("INVALID", now)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to base64URL-decode value")
+	})
+
+	t.Run("fails if expiry is malformed", func(t *testing.T) {
+		err := zcapld.CheckExpiry(compressZCAP(t, map[string]interface{}{"expires": "tomorrow"}), now)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to parse zcap expiry")
+	})
+}
+
+func compressZCAP(t *testing.T, zcap map[string]interface{}) string {
+	t.Helper()
+
+	raw, err := json.Marshal(zcap)
+	require.NoError(t, err)
+
+	compressed := bytes.NewBuffer(nil)
+	compressor := gzip.NewWriter(compressed)
+
+	_, err = compressor.Write(raw)
+	require.NoError(t, err)
+
+	err = compressor.Close()
+	require.NoError(t, err)
+
+	return base64.URLEncoding.EncodeToString(compressed.Bytes())
+}
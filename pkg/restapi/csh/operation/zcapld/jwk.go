@@ -0,0 +1,94 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package zcapld
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr"
+)
+
+const (
+	jwkMethod = "jwk"
+	// jwkVerMethodID is the fragment of the only verification method of a did:jwk DID.
+	jwkVerMethodID = "0"
+)
+
+// JWKResolver resolves did:jwk DIDs. The public key is encoded in the DID itself, as a base64url encoded JWK:
+// no network resolution is needed.
+type JWKResolver struct{}
+
+// NewJWKResolver returns a new did:jwk resolver.
+func NewJWKResolver() *JWKResolver {
+	return &JWKResolver{}
+}
+
+// Accept returns true for the jwk method.
+func (r *JWKResolver) Accept(method string) bool {
+	return method == jwkMethod
+}
+
+// Read returns the DID document of the did:jwk DID. Its single verification method, "#0", is a JsonWebKey2020
+// usable for all verification relationships, unless the JWK is restricted to encryption.
+func (r *JWKResolver) Read(didID string, _ ...vdr.DIDMethodOption) (*did.DocResolution, error) {
+	parsed, err := did.Parse(didID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse DID [%s]: %w", didID, err)
+	}
+
+	if parsed.Method != jwkMethod {
+		return nil, fmt.Errorf("not a did:jwk DID: %s", didID)
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(parsed.MethodSpecificID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode did:jwk [%s]: %w", didID, err)
+	}
+
+	j := &jwk.JWK{}
+
+	err = j.UnmarshalJSON(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWK of did:jwk [%s]: %w", didID, err)
+	}
+
+	_, err = supportedJWKCurves(j)
+	if err != nil {
+		return nil, fmt.Errorf("did:jwk [%s]: %w", didID, err)
+	}
+
+	if !j.IsPublic() {
+		return nil, fmt.Errorf("JWK of did:jwk [%s] is not a public key", didID)
+	}
+
+	vm, err := did.NewVerificationMethodFromJWK(
+		parsed.String()+"#"+jwkVerMethodID, "JsonWebKey2020", parsed.String(), j,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create verification method of did:jwk [%s]: %w", didID, err)
+	}
+
+	doc := &did.Doc{
+		Context:            []string{did.ContextV1},
+		ID:                 parsed.String(),
+		VerificationMethod: []did.VerificationMethod{*vm},
+	}
+
+	if j.Use == "enc" {
+		doc.KeyAgreement = []did.Verification{*did.NewReferencedVerification(vm, did.KeyAgreement)}
+	} else {
+		doc.Authentication = []did.Verification{*did.NewReferencedVerification(vm, did.Authentication)}
+		doc.AssertionMethod = []did.Verification{*did.NewReferencedVerification(vm, did.AssertionMethod)}
+		doc.CapabilityInvocation = []did.Verification{*did.NewReferencedVerification(vm, did.CapabilityInvocation)}
+		doc.CapabilityDelegation = []did.Verification{*did.NewReferencedVerification(vm, did.CapabilityDelegation)}
+	}
+
+	return &did.DocResolution{DIDDocument: doc}, nil
+}
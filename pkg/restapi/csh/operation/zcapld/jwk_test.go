@@ -0,0 +1,152 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package zcapld_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk/jwksupport"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/hyperledger/aries-framework-go/pkg/vdr/key"
+	"github.com/igor-pavlenko/httpsignatures-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/ace/pkg/restapi/csh/operation/zcapld"
+)
+
+func TestJWKResolver(t *testing.T) {
+	t.Run("creates and verifies signatures in did:jwk", func(t *testing.T) {
+		for _, keyType := range []kms.KeyType{kms.ED25519Type, kms.ECDSAP256TypeIEEEP1363, kms.ECDSAP384TypeIEEEP1363} {
+			agent := newAgent(t)
+
+			_, pubKeyBytes, err := agent.KMS().CreateAndExportPubKeyBytes(keyType)
+			require.NoError(t, err)
+
+			j, err := jwksupport.PubKeyBytesToJWK(pubKeyBytes, keyType)
+			require.NoError(t, err)
+
+			a := &zcapld.DIDSignatureHashAlgorithms{
+				KMS:       agent.KMS(),
+				Crypto:    agent.Crypto(),
+				Resolvers: []zcapld.DIDResolver{key.New(), zcapld.NewJWKResolver()},
+			}
+
+			msg := []byte("hello world")
+			secret := httpsignatures.Secret{KeyID: didJWK(t, j) + "#0"}
+
+			signature, err := a.Create(secret, msg)
+			require.NoError(t, err, keyType)
+			require.NotEmpty(t, signature)
+
+			err = a.Verify(secret, msg, signature)
+			require.NoError(t, err, keyType)
+		}
+	})
+
+	t.Run("resolves the DID document", func(t *testing.T) {
+		j := newEd25519JWK(t)
+		didID := didJWK(t, j)
+
+		resolution, err := zcapld.NewJWKResolver().Read(didID)
+		require.NoError(t, err)
+
+		doc := resolution.DIDDocument
+		require.Equal(t, didID, doc.ID)
+		require.Len(t, doc.VerificationMethod, 1)
+		require.Equal(t, didID+"#0", doc.VerificationMethod[0].ID)
+		require.Equal(t, "JsonWebKey2020", doc.VerificationMethod[0].Type)
+		require.Equal(t, didID, doc.VerificationMethod[0].Controller)
+		require.Equal(t, "Ed25519", doc.VerificationMethod[0].JSONWebKey().Crv)
+
+		for _, rel := range []did.VerificationRelationship{
+			did.Authentication, did.AssertionMethod, did.CapabilityInvocation, did.CapabilityDelegation,
+		} {
+			require.Len(t, doc.VerificationMethods(rel)[rel], 1)
+		}
+
+		require.Empty(t, doc.KeyAgreement)
+	})
+
+	t.Run("encryption keys are only for key agreement", func(t *testing.T) {
+		j := newEd25519JWK(t)
+		j.Use = "enc"
+
+		resolution, err := zcapld.NewJWKResolver().Read(didJWK(t, j))
+		require.NoError(t, err)
+		require.Len(t, resolution.DIDDocument.KeyAgreement, 1)
+		require.Empty(t, resolution.DIDDocument.CapabilityDelegation)
+
+		a := &zcapld.DIDSignatureHashAlgorithms{Resolvers: []zcapld.DIDResolver{zcapld.NewJWKResolver()}}
+
+		_, err = a.Create(httpsignatures.Secret{KeyID: didJWK(t, j) + "#0"}, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unable to dereference")
+	})
+
+	t.Run("accepts only the jwk method", func(t *testing.T) {
+		require.True(t, zcapld.NewJWKResolver().Accept("jwk"))
+		require.False(t, zcapld.NewJWKResolver().Accept("key"))
+
+		_, err := zcapld.NewJWKResolver().Read("did:key:z6MkiTBz1ymuepAQ4HEHYSF1H8quG5GLVVQR3djdX3mDooWp")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "not a did:jwk DID")
+	})
+
+	t.Run("fails on invalid DIDs", func(t *testing.T) {
+		x25519, err := jwksupport.JWKFromX25519Key(make([]byte, 32))
+		require.NoError(t, err)
+
+		_, privKey, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		private, err := jwksupport.JWKFromKey(privKey)
+		require.NoError(t, err)
+
+		for didID, expected := range map[string]string{
+			"did:jwk":                   "failed to parse DID",
+			"did:jwk:not.base64":        "failed to decode did:jwk",
+			"did:jwk:" + b64("hello"):   "failed to parse JWK",
+			didJWK(t, x25519):           "unsupported JsonWebKey2020 crv: X25519",
+			didJWK(t, private):          "is not a public key",
+			"did:jwk:" + b64(`{"a":1}`): "failed to parse JWK",
+		} {
+			_, err := zcapld.NewJWKResolver().Read(didID)
+			require.Error(t, err, didID)
+			require.Contains(t, err.Error(), expected, didID)
+		}
+	})
+}
+
+func newEd25519JWK(t *testing.T) *jwk.JWK {
+	t.Helper()
+
+	pubKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	j, err := jwksupport.JWKFromKey(pubKey)
+	require.NoError(t, err)
+
+	return j
+}
+
+func didJWK(t *testing.T, j *jwk.JWK) string {
+	t.Helper()
+
+	raw, err := j.MarshalJSON()
+	require.NoError(t, err)
+
+	return "did:jwk:" + b64(string(raw))
+}
+
+func b64(s string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
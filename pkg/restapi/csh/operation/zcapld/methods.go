@@ -0,0 +1,69 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package zcapld
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+)
+
+// ErrDIDMethodNotAllowed is returned when a zcap is invoked by a DID whose method is not allowed.
+var ErrDIDMethodNotAllowed = errors.New("DID method not allowed")
+
+// didMethodName is the syntax of DID method names: lowercase letters and digits.
+var didMethodName = regexp.MustCompile(`^[a-z0-9]+$`)
+
+// DIDMethodPolicy restricts the DID methods of zcap invokers. A method is allowed if it is in Allowed, or if Allowed
+// is empty, and it is not in Denied. A nil policy allows all methods.
+type DIDMethodPolicy struct {
+	Allowed []string
+	Denied  []string
+}
+
+// NewDIDMethodPolicy returns the policy allowing and denying the given DID methods, eg. "key" for did:key.
+func NewDIDMethodPolicy(allowed, denied []string) (*DIDMethodPolicy, error) {
+	for _, method := range append(append([]string{}, allowed...), denied...) {
+		if !didMethodName.MatchString(method) {
+			return nil, fmt.Errorf("invalid DID method name: %q", method)
+		}
+	}
+
+	return &DIDMethodPolicy{Allowed: allowed, Denied: denied}, nil
+}
+
+// CheckInvoker fails with ErrDIDMethodNotAllowed if the invoker, a DID or a DID URL, uses a method the policy does
+// not allow.
+func (p *DIDMethodPolicy) CheckInvoker(invoker string) error {
+	if p == nil || (len(p.Allowed) == 0 && len(p.Denied) == 0) {
+		return nil
+	}
+
+	id, err := did.Parse(strings.Split(invoker, "#")[0])
+	if err != nil {
+		return fmt.Errorf("%w: invoker %s is not a DID", ErrDIDMethodNotAllowed, invoker)
+	}
+
+	if (len(p.Allowed) > 0 && !contains(p.Allowed, id.Method)) || contains(p.Denied, id.Method) {
+		return fmt.Errorf("%w: invoker %s uses did:%s", ErrDIDMethodNotAllowed, invoker, id.Method)
+	}
+
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,63 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package zcapld_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/ace/pkg/restapi/csh/operation/zcapld"
+)
+
+func TestDIDMethodPolicy_CheckInvoker(t *testing.T) {
+	t.Run("allows all methods without a policy", func(t *testing.T) {
+		var policy *zcapld.DIDMethodPolicy
+
+		require.NoError(t, policy.CheckInvoker("did:web:example.com#key1"))
+		require.NoError(t, (&zcapld.DIDMethodPolicy{}).CheckInvoker("not a DID"))
+	})
+
+	t.Run("allow-list", func(t *testing.T) {
+		policy, err := zcapld.NewDIDMethodPolicy([]string{"orb", "key"}, nil)
+		require.NoError(t, err)
+
+		require.NoError(t, policy.CheckInvoker("did:key:z6MkiTBz1ymuepAQ4HEHYSF1H8quG5GLVVQR3djdX3mDooWp"))
+		require.NoError(t, policy.CheckInvoker("did:orb:uAAA:EiD3#key1"))
+
+		for _, invoker := range []string{"did:web:example.com#key1", "did:example:123", "not a DID"} {
+			err = policy.CheckInvoker(invoker)
+			require.True(t, errors.Is(err, zcapld.ErrDIDMethodNotAllowed), invoker)
+		}
+	})
+
+	t.Run("deny-list", func(t *testing.T) {
+		policy, err := zcapld.NewDIDMethodPolicy(nil, []string{"web"})
+		require.NoError(t, err)
+
+		require.NoError(t, policy.CheckInvoker("did:example:123#key1"))
+
+		err = policy.CheckInvoker("did:web:example.com#key1")
+		require.True(t, errors.Is(err, zcapld.ErrDIDMethodNotAllowed))
+		require.Contains(t, err.Error(), "uses did:web")
+	})
+
+	t.Run("denied methods take precedence over allowed ones", func(t *testing.T) {
+		policy, err := zcapld.NewDIDMethodPolicy([]string{"web", "key"}, []string{"web"})
+		require.NoError(t, err)
+
+		require.True(t, errors.Is(policy.CheckInvoker("did:web:example.com"), zcapld.ErrDIDMethodNotAllowed))
+	})
+
+	t.Run("invalid method names", func(t *testing.T) {
+		for _, method := range []string{"", "did:key", "Key"} {
+			_, err := zcapld.NewDIDMethodPolicy([]string{method}, nil)
+			require.Error(t, err, method)
+		}
+	})
+}
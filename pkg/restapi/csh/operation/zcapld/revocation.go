@@ -0,0 +1,75 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package zcapld
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/trustbloc/edge-core/pkg/zcapld"
+)
+
+// ErrRevoked is returned when a zcap, or a zcap it was delegated from, was revoked.
+var ErrRevoked = errors.New("zcap has been revoked")
+
+// RevocationList tells whether zcaps were revoked, eg. the client of the vault server that issued them.
+type RevocationList interface {
+	IsRevoked(ctx context.Context, zcapID string) (bool, error)
+}
+
+// CheckRevocation fails with ErrRevoked if the zcap, its parent or any zcap of its `capabilityChain` was revoked:
+// revoking a zcap also revokes the zcaps delegated from it.
+func CheckRevocation(ctx context.Context, zcap *zcapld.Capability, revocations RevocationList) error {
+	for _, id := range chainIDs(zcap) {
+		revoked, err := revocations.IsRevoked(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to check the revocation of zcap %s: %w", id, err)
+		}
+
+		if revoked {
+			return fmt.Errorf("%w: %s", ErrRevoked, id)
+		}
+	}
+
+	return nil
+}
+
+// chainIDs returns the IDs of the zcap and of the zcaps it was delegated from, without duplicates. The entries of
+// a `capabilityChain` are either IDs or embedded zcaps.
+func chainIDs(zcap *zcapld.Capability) []string {
+	var ids []string
+
+	seen := make(map[string]bool)
+
+	add := func(id string) {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	add(zcap.ID)
+	add(zcap.Parent)
+
+	for _, proof := range zcap.Proof {
+		chain, _ := proof["capabilityChain"].([]interface{})
+
+		for _, entry := range chain {
+			switch e := entry.(type) {
+			case string:
+				add(e)
+			case map[string]interface{}:
+				id, _ := e["id"].(string)
+
+				add(id)
+			}
+		}
+	}
+
+	return ids
+}
@@ -0,0 +1,75 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package zcapld_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/stretchr/testify/require"
+	zcapld2 "github.com/trustbloc/edge-core/pkg/zcapld"
+
+	"github.com/trustbloc/ace/pkg/restapi/csh/operation/zcapld"
+)
+
+func TestCheckRevocation(t *testing.T) {
+	t.Run("accepts zcaps that were not revoked", func(t *testing.T) {
+		revocations := revocationList{}
+
+		require.NoError(t, zcapld.CheckRevocation(context.Background(), delegatedZCAP(t, 2), revocations))
+	})
+
+	t.Run("rejects revoked zcaps", func(t *testing.T) {
+		revocations := revocationList{"urn:uuid:2": true}
+
+		err := zcapld.CheckRevocation(context.Background(), delegatedZCAP(t, 2), revocations)
+		require.ErrorIs(t, err, zcapld.ErrRevoked)
+		require.Contains(t, err.Error(), "urn:uuid:2")
+	})
+
+	t.Run("rejects zcaps delegated from revoked zcaps", func(t *testing.T) {
+		revocations := revocationList{"urn:uuid:0": true}
+
+		err := zcapld.CheckRevocation(context.Background(), delegatedZCAP(t, 2), revocations)
+		require.ErrorIs(t, err, zcapld.ErrRevoked)
+		require.Contains(t, err.Error(), "urn:uuid:0")
+	})
+
+	t.Run("checks embedded zcaps of the chain", func(t *testing.T) {
+		zcap := &zcapld2.Capability{
+			ID: "urn:uuid:1",
+			Proof: []verifiable.Proof{{
+				"capabilityChain": []interface{}{map[string]interface{}{"id": "urn:uuid:0"}},
+			}},
+		}
+
+		err := zcapld.CheckRevocation(context.Background(), zcap, revocationList{"urn:uuid:0": true})
+		require.ErrorIs(t, err, zcapld.ErrRevoked)
+	})
+
+	t.Run("fails if the revocations cannot be checked", func(t *testing.T) {
+		err := zcapld.CheckRevocation(context.Background(), delegatedZCAP(t, 0), failingRevocationList{})
+		require.Error(t, err)
+		require.NotErrorIs(t, err, zcapld.ErrRevoked)
+		require.Contains(t, err.Error(), "failed to check the revocation of zcap urn:uuid:0")
+	})
+}
+
+// revocationList holds the IDs of the revoked zcaps.
+type revocationList map[string]bool
+
+func (l revocationList) IsRevoked(_ context.Context, zcapID string) (bool, error) {
+	return l[zcapID], nil
+}
+
+type failingRevocationList struct{}
+
+func (failingRevocationList) IsRevoked(context.Context, string) (bool, error) {
+	return false, errors.New("test")
+}
@@ -0,0 +1,128 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package zcapld
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/trustbloc/edge-core/pkg/zcapld"
+)
+
+// ZCAPCacheStats counts the lookups of a ZCAPCache.
+type ZCAPCacheStats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+// ZCAPCache caches the zcaps parsed from their compressed form, along with the failures to parse them. It is meant
+// to live as long as a request, so that an upstream authorization reused by several queries of the request is only
+// decompressed and parsed once. It is safe for concurrent use.
+//
+// A nil ZCAPCache parses the zcaps on every call.
+type ZCAPCache struct {
+	mu      sync.Mutex
+	entries map[string]*zcapCacheEntry
+
+	hits   uint64
+	misses uint64
+}
+
+// zcapCacheEntry holds the results of parsing a compressed zcap. The zcap and its expiry are parsed on first use.
+type zcapCacheEntry struct {
+	zcap    *zcapld.Capability
+	zcapErr error
+	parsed  bool
+
+	expires    *time.Time
+	expiresErr error
+	expiryRead bool
+}
+
+// NewZCAPCache returns an empty ZCAPCache.
+func NewZCAPCache() *ZCAPCache {
+	return &ZCAPCache{entries: make(map[string]*zcapCacheEntry)}
+}
+
+// Parse returns the zcap compressed by the edge-core zcapld package, as its DecompressZCAP does. The zcap is shared
+// by all the callers and must not be modified.
+func (c *ZCAPCache) Parse(compressed string) (*zcapld.Capability, error) {
+	if c == nil {
+		return zcapld.DecompressZCAP(compressed)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e := c.entry(compressed, func(e *zcapCacheEntry) bool { return e.parsed })
+	if !e.parsed {
+		e.zcap, e.zcapErr = zcapld.DecompressZCAP(compressed)
+		e.parsed = true
+	}
+
+	return e.zcap, e.zcapErr
+}
+
+// Expires returns the compressed zcap's `expires` timestamp, as Expires does.
+func (c *ZCAPCache) Expires(compressed string) (*time.Time, error) {
+	if c == nil {
+		return Expires(compressed)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e := c.entry(compressed, func(e *zcapCacheEntry) bool { return e.expiryRead })
+	if !e.expiryRead {
+		e.expires, e.expiresErr = Expires(compressed)
+		e.expiryRead = true
+	}
+
+	return e.expires, e.expiresErr
+}
+
+// CheckExpiry fails with ErrExpired if the compressed zcap's `expires` timestamp is not after `now`, as CheckExpiry
+// does.
+func (c *ZCAPCache) CheckExpiry(compressed string, now time.Time) error {
+	expires, err := c.Expires(compressed)
+	if err != nil {
+		return err
+	}
+
+	return checkExpires(expires, now)
+}
+
+// Stats returns the number of lookups served from the cache and the number of lookups that parsed the zcap.
+func (c *ZCAPCache) Stats() ZCAPCacheStats {
+	if c == nil {
+		return ZCAPCacheStats{}
+	}
+
+	return ZCAPCacheStats{
+		Hits:   atomic.LoadUint64(&c.hits),
+		Misses: atomic.LoadUint64(&c.misses),
+	}
+}
+
+// entry returns the entry of the compressed zcap, counting a hit if cached tells it holds the result looked up.
+// It must be called with the lock held.
+func (c *ZCAPCache) entry(compressed string, cached func(*zcapCacheEntry) bool) *zcapCacheEntry {
+	e, ok := c.entries[compressed]
+	if !ok {
+		e = &zcapCacheEntry{}
+		c.entries[compressed] = e
+	}
+
+	if cached(e) {
+		atomic.AddUint64(&c.hits, 1)
+	} else {
+		atomic.AddUint64(&c.misses, 1)
+	}
+
+	return e
+}
@@ -0,0 +1,177 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package zcapld_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	zcapld2 "github.com/trustbloc/edge-core/pkg/zcapld"
+
+	"github.com/trustbloc/ace/pkg/restapi/csh/operation/zcapld"
+)
+
+func TestZCAPCache(t *testing.T) {
+	now := time.Now()
+
+	t.Run("parses zcaps as they are parsed without the cache", func(t *testing.T) {
+		compressed := compressZCAP(t, map[string]interface{}{
+			"id":               "urn:uuid:123",
+			"invoker":          "did:example:invoker",
+			"invocationTarget": map[string]interface{}{"id": "https://edv.example.com/encrypted-data-vaults/123"},
+			"expires":          now.Add(time.Minute).Format(time.RFC3339),
+		})
+
+		expected, err := zcapld2.DecompressZCAP(compressed)
+		require.NoError(t, err)
+
+		expectedExpiry, err := zcapld.Expires(compressed)
+		require.NoError(t, err)
+
+		cache := zcapld.NewZCAPCache()
+
+		for i := 0; i < 2; i++ {
+			zcap, err := cache.Parse(compressed)
+			require.NoError(t, err)
+			require.Equal(t, expected, zcap)
+
+			expires, err := cache.Expires(compressed)
+			require.NoError(t, err)
+			require.Equal(t, expectedExpiry, expires)
+
+			require.NoError(t, cache.CheckExpiry(compressed, now))
+		}
+
+		first, err := cache.Parse(compressed)
+		require.NoError(t, err)
+
+		second, err := cache.Parse(compressed)
+		require.NoError(t, err)
+		require.Same(t, first, second)
+
+		require.Equal(t, zcapld.ZCAPCacheStats{Hits: 6, Misses: 2}, cache.Stats())
+	})
+
+	t.Run("caches the failures to parse zcaps", func(t *testing.T) {
+		cache := zcapld.NewZCAPCache()
+
+		for _, compressed := range []string{"INVALID", compressZCAP(t, map[string]interface{}{"expires": "tomorrow"})} {
+			_, expected := zcapld2.DecompressZCAP(compressed)
+			_, expectedExpiryErr := zcapld.Expires(compressed)
+
+			for i := 0; i < 2; i++ {
+				_, err := cache.Parse(compressed)
+				require.Equal(t, expected, err)
+
+				_, err = cache.Expires(compressed)
+				require.Error(t, err)
+				require.Equal(t, expectedExpiryErr.Error(), err.Error())
+			}
+		}
+
+		require.Equal(t, zcapld.ZCAPCacheStats{Hits: 4, Misses: 4}, cache.Stats())
+	})
+
+	t.Run("fails if the zcap has expired", func(t *testing.T) {
+		cache := zcapld.NewZCAPCache()
+
+		compressed := compressZCAP(t, map[string]interface{}{
+			"id":      "urn:uuid:123",
+			"expires": now.Add(-time.Minute).Format(time.RFC3339),
+		})
+
+		require.ErrorIs(t, cache.CheckExpiry(compressed, now), zcapld.ErrExpired)
+		require.ErrorIs(t, cache.CheckExpiry(compressed, now), zcapld.ErrExpired)
+		require.NoError(t, cache.CheckExpiry(compressed, now.Add(-time.Hour)))
+	})
+
+	t.Run("a nil cache parses zcaps every time", func(t *testing.T) {
+		var cache *zcapld.ZCAPCache
+
+		compressed := compressZCAP(t, map[string]interface{}{"id": "urn:uuid:123"})
+
+		first, err := cache.Parse(compressed)
+		require.NoError(t, err)
+
+		second, err := cache.Parse(compressed)
+		require.NoError(t, err)
+		require.Equal(t, first, second)
+		require.NotSame(t, first, second)
+
+		require.NoError(t, cache.CheckExpiry(compressed, now))
+		require.Equal(t, zcapld.ZCAPCacheStats{}, cache.Stats())
+	})
+}
+
+// BenchmarkZCAPCache parses the upstream zcaps of a batch of queries sharing a few authorizations, as a request
+// does: the invoker of each zcap is read twice and its expiry once. The parses/op metric counts the zcaps parsed.
+func BenchmarkZCAPCache(b *testing.B) {
+	const (
+		queries = 100
+		tokens  = 2
+	)
+
+	compressed := make([]string, tokens)
+
+	for i := range compressed {
+		raw, err := zcapld.CompressZCAP(&zcapld.Capability{
+			Capability: &zcapld2.Capability{
+				Context: zcapld2.SecurityContextV2,
+				ID:      fmt.Sprintf("urn:uuid:%d", i),
+				Invoker: "did:example:invoker",
+				InvocationTarget: zcapld2.InvocationTarget{
+					ID:   fmt.Sprintf("https://edv.example.com/encrypted-data-vaults/%d", i),
+					Type: "urn:edv:vault",
+				},
+			},
+		})
+		require.NoError(b, err)
+
+		compressed[i] = raw
+	}
+
+	for _, bench := range []struct {
+		name  string
+		cache func() *zcapld.ZCAPCache
+	}{
+		{name: "without cache", cache: func() *zcapld.ZCAPCache { return nil }},
+		{name: "with request cache", cache: zcapld.NewZCAPCache},
+	} {
+		bench := bench
+
+		b.Run(bench.name, func(b *testing.B) {
+			parses := 0
+
+			for n := 0; n < b.N; n++ {
+				cache := bench.cache()
+
+				for i := 0; i < queries; i++ {
+					zcap := compressed[i%tokens]
+
+					_, err := cache.Parse(zcap)
+					require.NoError(b, err)
+
+					_, err = cache.Parse(zcap)
+					require.NoError(b, err)
+
+					_, err = cache.Expires(zcap)
+					require.NoError(b, err)
+				}
+
+				if cache == nil {
+					parses += 3 * queries
+				} else {
+					parses += int(cache.Stats().Misses)
+				}
+			}
+
+			b.ReportMetric(float64(parses)/float64(b.N), "parses/op")
+		})
+	}
+}
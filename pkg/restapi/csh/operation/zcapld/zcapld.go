@@ -7,15 +7,19 @@ SPDX-License-Identifier: Apache-2.0
 package zcapld
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/verifier"
 	"github.com/hyperledger/aries-framework-go/pkg/kms"
 	"github.com/hyperledger/aries-framework-go/pkg/kms/localkms"
 	"github.com/igor-pavlenko/httpsignatures-go"
+	"github.com/trustbloc/edge-core/pkg/log"
 	"github.com/trustbloc/edge-core/pkg/zcapld"
 )
 
@@ -23,7 +27,13 @@ import (
 //  https://github.com/trustbloc/ace/issues/614.
 const signatureHashAlgorithm = "https://github.com/hyperledger/aries-framework-go/zcaps"
 
-// NewHTTPSigner returns a ZCAP-LD based HTTP signer.
+var logger = log.New("csh-zcapld")
+
+// signedHeaders are the headers covered by the signatures of NewHTTPSigner.
+var signedHeaders = []string{"(request-target)", "(created)", "(expires)", zcapld.CapabilityInvocationHTTPHeader}
+
+// NewHTTPSigner returns a ZCAP-LD based HTTP signer. The signature covers the request target, its creation and
+// expiry times and the capability invocation, so that it cannot be replayed to other endpoints or later.
 func NewHTTPSigner(
 	verMethod, capability string, action func(*http.Request) (string, error), secrets httpsignatures.Secrets,
 	algorithm httpsignatures.SignatureHashAlgorithm) func(*http.Request) (*http.Header, error) {
@@ -31,6 +41,7 @@ func NewHTTPSigner(
 		hs := httpsignatures.NewHTTPSignatures(secrets)
 
 		hs.SetSignatureHashAlgorithm(algorithm)
+		hs.SetDefaultSignatureHeaders(signedHeaders)
 
 		a, err := action(r)
 		if err != nil {
@@ -58,11 +69,13 @@ type DIDSecrets struct {
 
 // Get resolves the DID URL into a secret.
 func (c *DIDSecrets) Get(didURL string) (httpsignatures.Secret, error) {
-	id, _, err := parseDIDURL(didURL)
+	parsed, err := parseDIDURL(didURL)
 	if err != nil {
 		return httpsignatures.Secret{}, fmt.Errorf("failed to parse [%s]: %w", didURL, err)
 	}
 
+	id := parsed.DID
+
 	secrets, supported := c.Secrets[id.Method]
 	if !supported {
 		return httpsignatures.Secret{}, fmt.Errorf("unsupported DID method: %s", id.Method)
@@ -84,6 +97,8 @@ type DIDSignatureHashAlgorithms struct {
 	KMS       KMS
 	Crypto    Crypto
 	Resolvers []DIDResolver
+	// Cache is optional.
+	Cache *DIDCache
 }
 
 // Algorithm returns a custom algorithm identifier for the httpsignatures API.
@@ -147,35 +162,37 @@ func (a *DIDSignatureHashAlgorithms) Verify(secret httpsignatures.Secret, data,
 	return nil
 }
 
-func (a *DIDSignatureHashAlgorithms) derefVerMethod(
-	didURL string, rel did.VerificationRelationship) (*did.VerificationMethod, error) {
-	id, fragment, err := parseDIDURL(didURL)
+// Resolve returns the public key of the capability delegation verification method, so that the proofs of zcaps
+// can be verified.
+func (a *DIDSignatureHashAlgorithms) Resolve(didURL string) (*verifier.PublicKey, error) {
+	verificationMethod, err := a.derefVerMethod(didURL, did.CapabilityDelegation)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse DID URL: %w", err)
+		return nil, fmt.Errorf("failed to dereference verificationMethod from didURL %s: %w", didURL, err)
 	}
 
-	var resolver DIDResolver
-
-	for _, r := range a.Resolvers {
-		if r.Accept(id.Method) {
-			resolver = r
+	return &verifier.PublicKey{
+		Type:  verificationMethod.Type,
+		Value: verificationMethod.Value,
+		JWK:   verificationMethod.JSONWebKey(),
+	}, nil
+}
 
-			break
-		}
+func (a *DIDSignatureHashAlgorithms) derefVerMethod(
+	didURL string, rel did.VerificationRelationship) (*did.VerificationMethod, error) {
+	parsed, err := parseDIDURL(didURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse DID URL: %w", err)
 	}
 
-	if resolver == nil {
-		return nil, fmt.Errorf("no resolver configured for method [%s]", id.Method)
-	}
+	id := parsed.DID
 
-	// TODO resolve options
-	resolution, err := resolver.Read(id.String())
+	resolution, err := a.resolve(id, versionOf(parsed.Query))
 	if err != nil {
-		return nil, fmt.Errorf("failed to resolve [%s]: %w", id.String(), err)
+		return nil, err
 	}
 
 	for _, vm := range resolution.DIDDocument.VerificationMethods(rel)[rel] {
-		if fragment == vm.VerificationMethod.ID || didURL == vm.VerificationMethod.ID {
+		if parsed.matches(vm.VerificationMethod.ID) {
 			return &vm.VerificationMethod, nil
 		}
 	}
@@ -186,20 +203,115 @@ func (a *DIDSignatureHashAlgorithms) derefVerMethod(
 	)
 }
 
-func parseDIDURL(didURL string) (id *did.DID, fragment string, err error) {
-	const numParts = 2
+// didURL is a DID URL split into its components: did-url = did path-abempty [ "?" query ] [ "#" fragment ].
+type didURL struct {
+	DID      *did.DID
+	Path     string
+	Query    url.Values
+	Fragment string
+}
+
+// matches returns true if the verification method ID refers to the same key as this DID URL. The verification
+// method ID may be either relative (eg. "#key1" or "key1") or absolute (eg. "did:example:123#key1").
+func (u *didURL) matches(vmID string) bool {
+	return vmID == u.Fragment || vmID == "#"+u.Fragment || vmID == u.DID.String()+"#"+u.Fragment
+}
+
+// resolve tries each resolver accepting the DID's method in the configured order. It fails only if all of them
+// fail, in which case their errors are aggregated. Resolutions are served from the cache, if any.
+func (a *DIDSignatureHashAlgorithms) resolve(id *did.DID, version didVersion) (*did.DocResolution, error) {
+	if a.Cache != nil {
+		if resolution, ok := a.Cache.get(id.String(), version); ok {
+			return resolution, nil
+		}
+	}
+
+	var errs resolveErrors
+
+	for i, r := range a.Resolvers {
+		if !r.Accept(id.Method) {
+			continue
+		}
+
+		resolution, err := r.Read(id.String(), version.options()...)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("resolver %d: %w", i, err))
+
+			continue
+		}
+
+		if len(errs) > 0 {
+			logger.Warnf("resolved [%s] after %d failed attempts: %s", id.String(), len(errs), errs.Error())
+		}
+
+		if a.Cache != nil {
+			a.Cache.put(id.String(), version, resolution)
+		}
+
+		return resolution, nil
+	}
+
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("no resolver configured for method [%s]", id.Method)
+	}
+
+	return nil, fmt.Errorf("failed to resolve [%s]: %w", id.String(), errs)
+}
+
+// resolveErrors aggregates the errors of all resolvers that failed to resolve a DID.
+type resolveErrors []error
+
+func (e resolveErrors) Error() string {
+	msgs := make([]string, len(e))
+
+	for i := range e {
+		msgs[i] = e[i].Error()
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// Is reports whether any of the aggregated errors matches the target.
+func (e resolveErrors) Is(target error) bool {
+	for i := range e {
+		if errors.Is(e[i], target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseDIDURL parses a DID URL identifying a verification method. The fragment is mandatory.
+func parseDIDURL(raw string) (*didURL, error) {
+	rest, fragment, found := strings.Cut(raw, "#")
+	if !found || fragment == "" || strings.Contains(fragment, "#") {
+		return nil, fmt.Errorf("not a did URL: %s", raw)
+	}
+
+	rest, rawQuery, _ := strings.Cut(rest, "?")
+
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("not a did URL: %s: invalid query: %w", raw, err)
+	}
 
-	parts := strings.Split(didURL, "#")
-	if len(parts) != numParts {
-		return nil, "", fmt.Errorf("not a did URL: %s", didURL)
+	didPart, path, hasPath := strings.Cut(rest, "/")
+	if hasPath {
+		path = "/" + path
 	}
 
-	id, err = did.Parse(parts[0])
+	id, err := did.Parse(didPart)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to parse DID [%s]: %w", parts[0], err)
+		return nil, fmt.Errorf("failed to parse DID [%s]: %w", didPart, err)
 	}
 
-	return id, parts[1], nil
+	return &didURL{
+		DID:      id,
+		Path:     path,
+		Query:    query,
+		Fragment: fragment,
+	}, nil
 }
 
 func kmsKeyType(verMethod *did.VerificationMethod) (kms.KeyType, error) {
@@ -13,6 +13,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/google/uuid"
@@ -59,6 +60,10 @@ func TestNewHTTPSigner(t *testing.T) {
 		require.NotEmpty(t, headers.Get("capability-invocation"))
 		require.NotEmpty(t, headers.Get("signature"))
 
+		sig, errParse := httpsignatures.NewParser().ParseSignatureHeader(headers.Get("signature"))
+		require.Nil(t, errParse)
+		require.Equal(t, []string{"(request-target)", "(created)", "(expires)", "capability-invocation"}, sig.Headers)
+
 		request.Header = *headers
 
 		hs := httpsignatures.NewHTTPSignatures(&zcapld.DIDSecrets{
@@ -140,6 +145,41 @@ func TestDIDSecrets_Get(t *testing.T) {
 		require.Equal(t, expected.PrivateKey, result.PrivateKey)
 	})
 
+	t.Run("returns secret for DID URL with path and query", func(t *testing.T) {
+		expected := httpsignatures.Secret{KeyID: uuid.New().String()}
+		d := &zcapld.DIDSecrets{
+			Secrets: map[string]httpsignatures.Secrets{
+				"web": &mockSecrets{s: expected},
+			},
+		}
+
+		for _, didURL := range []string{
+			"did:web:example.com:123?versionId=1#key1",
+			"did:web:example.com:123/some/path#key1",
+			"did:web:example.com:123/some/path?versionId=1&versionTime=2022-01-01T00:00:00Z#key1",
+		} {
+			result, err := d.Get(didURL)
+			require.NoError(t, err, didURL)
+			require.Equal(t, expected.KeyID, result.KeyID)
+		}
+	})
+
+	t.Run("error if not a did URL", func(t *testing.T) {
+		d := &zcapld.DIDSecrets{}
+
+		for _, didURL := range []string{
+			"did:example:abc",
+			"did:example:abc#",
+			"did:example:abc#key1#key2",
+			"did:example:abc?versionId=1",
+			"did:example:abc?versionId=%zz#key1",
+		} {
+			_, err := d.Get(didURL)
+			require.Error(t, err, didURL)
+			require.Contains(t, err.Error(), "not a did URL", didURL)
+		}
+	})
+
 	t.Run("error if did method not supported", func(t *testing.T) {
 		d := &zcapld.DIDSecrets{}
 		_, err := d.Get("did:example:abc#123")
@@ -202,9 +242,81 @@ func TestDIDSignatureHashAlgorithms_CommonTests(t *testing.T) {
 				err = a.Verify(secret, msg, signature)
 				require.NoError(t, err)
 			})
+
+			t.Run("in did:key URL with path and query", func(t *testing.T) {
+				agent := newAgent(t)
+
+				_, pubKeyBytes, err := agent.KMS().CreateAndExportPubKeyBytes(kms.ED25519Type)
+				require.NoError(t, err)
+
+				didKey, didKeyURL := fingerprint.CreateDIDKey(pubKeyBytes)
+				fragment := strings.Split(didKeyURL, "#")[1]
+
+				a := &zcapld.DIDSignatureHashAlgorithms{
+					KMS:       agent.KMS(),
+					Crypto:    agent.Crypto(),
+					Resolvers: []zcapld.DIDResolver{key.New()},
+				}
+
+				msg := []byte("hello world")
+
+				for _, keyID := range []string{
+					didKey + "?versionId=1#" + fragment,
+					didKey + "/some/path#" + fragment,
+					didKey + "/some/path?versionId=1#" + fragment,
+				} {
+					secret := httpsignatures.Secret{KeyID: keyID}
+
+					signature, err := a.Create(secret, msg)
+					require.NoError(t, err, keyID)
+					require.NotEmpty(t, signature)
+
+					err = a.Verify(secret, msg, signature)
+					require.NoError(t, err, keyID)
+				}
+			})
 		})
 	})
 
+	t.Run("resolves the DID without path and query", func(t *testing.T) {
+		resolver := &mockDIDResolver{method: "example", readErr: errors.New("test")}
+		a := &zcapld.DIDSignatureHashAlgorithms{Resolvers: []zcapld.DIDResolver{resolver}}
+
+		_, err := a.Create(httpsignatures.Secret{KeyID: "did:example:123/path?versionId=1#key1"}, nil)
+		require.Error(t, err)
+		require.Equal(t, "did:example:123", resolver.readDID)
+	})
+
+	t.Run("dereferences verification method by relative fragment", func(t *testing.T) {
+		agent := newAgent(t)
+
+		const doc = `{
+  			"@context": ["https://w3id.org/did/v1"],
+  			"id": "did:example:21tDAKCERh95uGgKbJNHYp",
+  			"capabilityDelegation": [{
+      			"id": "#key1",
+      			"type": "UNSUPPORTED",
+      			"controller": "did:example:21tDAKCERh95uGgKbJNHYp",
+      			"publicKeyHex": "02b97c30de767f084ce3080168ee293053ba33b235d7116a3263d29f1450936b71"
+  			}]
+		}`
+		ddoc, err := did.ParseDocument([]byte(doc))
+		require.NoError(t, err)
+
+		a := &zcapld.DIDSignatureHashAlgorithms{
+			KMS:    agent.KMS(),
+			Crypto: agent.Crypto(),
+			Resolvers: []zcapld.DIDResolver{&mockDIDResolver{
+				method:    "example",
+				readValue: &did.DocResolution{DIDDocument: ddoc},
+			}},
+		}
+
+		_, err = a.Create(httpsignatures.Secret{KeyID: "did:example:21tDAKCERh95uGgKbJNHYp?versionId=1#key1"}, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unsupported verificationMethod type")
+	})
+
 	t.Run("fails if keyID is not a didURL", func(t *testing.T) {
 		a := &zcapld.DIDSignatureHashAlgorithms{}
 		secret := httpsignatures.Secret{KeyID: "invalid"}
@@ -254,6 +366,54 @@ func TestDIDSignatureHashAlgorithms_CommonTests(t *testing.T) {
 		require.True(t, errors.Is(err, expected))
 	})
 
+	t.Run("falls back to the next resolver accepting the method", func(t *testing.T) {
+		agent := newAgent(t)
+
+		_, pubKeyBytes, err := agent.KMS().CreateAndExportPubKeyBytes(kms.ED25519Type)
+		require.NoError(t, err)
+
+		_, didKeyURL := fingerprint.CreateDIDKey(pubKeyBytes)
+
+		failing := &mockDIDResolver{method: "key", readErr: errors.New("primary down")}
+		a := &zcapld.DIDSignatureHashAlgorithms{
+			KMS:    agent.KMS(),
+			Crypto: agent.Crypto(),
+			Resolvers: []zcapld.DIDResolver{
+				&mockDIDResolver{method: "other"},
+				failing,
+				key.New(),
+			},
+		}
+
+		msg := []byte("hello world")
+		secret := httpsignatures.Secret{KeyID: didKeyURL}
+
+		signature, err := a.Create(secret, msg)
+		require.NoError(t, err)
+		require.NotEmpty(t, signature)
+		require.NotEmpty(t, failing.readDID)
+
+		err = a.Verify(secret, msg, signature)
+		require.NoError(t, err)
+	})
+
+	t.Run("aggregates errors if all resolvers fail", func(t *testing.T) {
+		first := errors.New("first")
+		second := errors.New("second")
+		a := &zcapld.DIDSignatureHashAlgorithms{
+			Resolvers: []zcapld.DIDResolver{
+				&mockDIDResolver{method: "test", readErr: first},
+				&mockDIDResolver{method: "test", readErr: second},
+			},
+		}
+
+		_, err := a.Create(httpsignatures.Secret{KeyID: "did:test:abc#123"}, nil)
+		require.Error(t, err)
+		require.ErrorIs(t, err, first)
+		require.ErrorIs(t, err, second)
+		require.Contains(t, err.Error(), "resolver 0: first; resolver 1: second")
+	})
+
 	t.Run("fails if verification method cannot be de-referenced from did doc", func(t *testing.T) {
 		agent := newAgent(t)
 		method, err := peer.New(mem.NewProvider())
@@ -451,13 +611,16 @@ type mockDIDResolver struct {
 	method    string
 	readValue *did.DocResolution
 	readErr   error
+	readDID   string
 }
 
 func (m *mockDIDResolver) Accept(method string) bool {
 	return m.method == method
 }
 
-func (m *mockDIDResolver) Read(string, ...vdr.DIDMethodOption) (*did.DocResolution, error) {
+func (m *mockDIDResolver) Read(didID string, _ ...vdr.DIDMethodOption) (*did.DocResolution, error) {
+	m.readDID = didID
+
 	return m.readValue, m.readErr
 }
 
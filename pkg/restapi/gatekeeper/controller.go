@@ -35,6 +35,11 @@ type Config struct {
 	VDR                    vdr.Registry
 	VCIssuer               *vcissuer.Service
 	ConfidentialStorageHub operations.ClientService
+	// ReleaseWorkers is the number of ticket authorizations processed concurrently. Default: release.DefaultWorkers.
+	ReleaseWorkers int
+	// ReleaseQueueDepth is the number of ticket authorizations waiting to be processed at most, beyond which they
+	// are rejected with 503. Default: release.DefaultQueueDepth.
+	ReleaseQueueDepth int
 }
 
 // New returns a new Controller instance.
@@ -49,6 +54,7 @@ func New(cfg *Config) (*Controller, error) {
 		VaultClient:   cfg.VaultClient,
 		VDR:           cfg.VDR,
 		VCIssuer:      cfg.VCIssuer,
+		PolicyService: policyService,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("create protect service: %w", err)
@@ -63,6 +69,11 @@ func New(cfg *Config) (*Controller, error) {
 		return nil, fmt.Errorf("create release service: %w", err)
 	}
 
+	releasePool := release.NewPool(releaseService, &release.PoolConfig{
+		Workers:    cfg.ReleaseWorkers,
+		QueueDepth: cfg.ReleaseQueueDepth,
+	})
+
 	collectService := collect.NewService(
 		cfg.ConfigService,
 		cfg.VaultClient,
@@ -75,12 +86,13 @@ func New(cfg *Config) (*Controller, error) {
 		PolicyService:   policyService,
 		ProtectService:  protectService,
 		ReleaseService:  releaseService,
+		ReleaseQueue:    releasePool,
 		CollectService:  collectService,
 		ExtractService:  extractService,
 		SubjectResolver: &subjectDIDResolver{},
 	}
 
-	return &Controller{handlers: op.GetRESTHandlers()}, nil
+	return &Controller{handlers: op.GetRESTHandlers(), releasePool: releasePool}, nil
 }
 
 type subjectDIDResolver struct{}
@@ -96,10 +108,17 @@ func (r *subjectDIDResolver) Resolve(ctx context.Context) (string, error) {
 
 // Controller contains handlers for controller.
 type Controller struct {
-	handlers []handler.Handler
+	handlers    []handler.Handler
+	releasePool *release.Pool
 }
 
 // GetOperations returns all controller endpoints.
 func (c *Controller) GetOperations() []handler.Handler {
 	return c.handlers
 }
+
+// Close stops accepting ticket authorizations and waits until the queued ones are processed, or until the context
+// is done.
+func (c *Controller) Close(ctx context.Context) error {
+	return c.releasePool.Close(ctx)
+}
@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package gatekeeper_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/hyperledger/aries-framework-go/pkg/mock/storage"
@@ -26,5 +27,7 @@ func TestController_New(t *testing.T) {
 		ops := controller.GetOperations()
 
 		require.Greater(t, len(ops), 0)
+
+		require.NoError(t, controller.Close(context.Background()))
 	})
 }
@@ -6,6 +6,10 @@ SPDX-License-Identifier: Apache-2.0
 
 package operation
 
+import (
+	"github.com/trustbloc/ace/pkg/gatekeeper/policy"
+)
+
 // createPolicyReq model
 //
 // swagger:parameters createPolicyReq
@@ -16,19 +20,67 @@ type createPolicyReq struct { //nolint:unused,deadcode
 	// required: true
 	PolicyID string `json:"policy_id"`
 
+	// The expected version of the policy. The policy is saved only if it exists with this version.
+	//
+	// in: header
+	IfMatch string `json:"If-Match"`
+
 	// in: body
 	Body struct {
 		Collectors   []string `json:"collectors"`
 		Handlers     []string `json:"handlers"`
 		Approvers    []string `json:"approvers"`
 		MinApprovers int      `json:"min_approvers"`
+		TicketExpiry int      `json:"ticket_expiry"`
 	}
 }
 
-// createPolicyResp model
+// getPolicyReq model
+//
+// swagger:parameters getPolicyReq
+type getPolicyReq struct { //nolint:unused,deadcode
+	// Policy ID.
+	//
+	// in: path
+	// required: true
+	PolicyID string `json:"policy_id"`
+}
+
+// policyResp model
+//
+// swagger:response policyResp
+type policyResp struct { //nolint:unused,deadcode
+	// The version of the policy.
+	//
+	// in: header
+	ETag string `json:"ETag"`
+
+	// in: body
+	Body struct {
+		policy.Policy
+	}
+}
+
+// deletePolicyReq model
+//
+// swagger:parameters deletePolicyReq
+type deletePolicyReq struct { //nolint:unused,deadcode
+	// Policy ID.
+	//
+	// in: path
+	// required: true
+	PolicyID string `json:"policy_id"`
+
+	// The expected version of the policy. The policy is deleted only if it exists with this version.
+	//
+	// in: header
+	IfMatch string `json:"If-Match"`
+}
+
+// deletePolicyResp model
 //
-// swagger:response createPolicyResp
-type createPolicyResp struct{} //nolint:unused,deadcode
+// swagger:response deletePolicyResp
+type deletePolicyResp struct{} //nolint:unused,deadcode
 
 // protectReq model
 //
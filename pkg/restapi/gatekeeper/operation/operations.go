@@ -7,7 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package operation
 
 //nolint:lll
-//go:generate mockgen -destination gomocks_test.go -package operation_test -source=operations.go -mock_names policyService=MockPolicyService,protectService=MockProtectService,releaseService=MockReleaseService,subjectResolver=MockSubjectResolver,collectService=MockCollectService,extractService=MockExtractService
+//go:generate mockgen -destination gomocks_test.go -package operation_test -source=operations.go -mock_names policyService=MockPolicyService,protectService=MockProtectService,releaseService=MockReleaseService,subjectResolver=MockSubjectResolver,collectService=MockCollectService,extractService=MockExtractService,releaseQueue=MockReleaseQueue
 
 import (
 	"context"
@@ -15,6 +15,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gorilla/mux"
@@ -23,6 +24,7 @@ import (
 
 	"github.com/trustbloc/ace/pkg/gatekeeper/policy"
 	"github.com/trustbloc/ace/pkg/gatekeeper/protect"
+	"github.com/trustbloc/ace/pkg/gatekeeper/release"
 	"github.com/trustbloc/ace/pkg/gatekeeper/release/ticket"
 	"github.com/trustbloc/ace/pkg/restapi/handler"
 	"github.com/trustbloc/ace/pkg/restapi/model"
@@ -39,12 +41,17 @@ const (
 	ticketStatusEndpoint = releaseEndpoint + "/{" + ticketIDVarName + "}/status"
 	collectEndpoint      = releaseEndpoint + "/{" + ticketIDVarName + "}/collect"
 	extractEndpoint      = baseV1Path + "/extract"
+
+	// retryAfterSeconds is the delay after which clients retry the authorizations the queue had no room for.
+	retryAfterSeconds = "1"
 )
 
 var logger = log.New("gatekeeper")
 
 type policyService interface {
-	Save(ctx context.Context, doc *policy.Policy) error
+	Save(ctx context.Context, doc *policy.Policy, opts ...policy.Opt) error
+	Get(ctx context.Context, policyID string) (*policy.Policy, error)
+	Delete(ctx context.Context, policyID string, opts ...policy.Opt) error
 	Check(ctx context.Context, policyID, did string, role policy.Role) error
 }
 
@@ -54,9 +61,14 @@ type protectService interface {
 }
 
 type releaseService interface {
-	Release(ctx context.Context, did string) (*ticket.Ticket, error)
+	Release(ctx context.Context, did, requestingParty string) (*ticket.Ticket, error)
 	Get(ctx context.Context, ticketID string) (*ticket.Ticket, error)
 	Authorize(ctx context.Context, ticketID, approverDID string) error
+	Collect(ctx context.Context, ticketID string) error
+}
+
+type releaseQueue interface {
+	Authorize(ctx context.Context, ticketID, approverDID string) error
 }
 
 type collectService interface {
@@ -79,12 +91,17 @@ type Operation struct {
 	ReleaseService  releaseService
 	CollectService  collectService
 	ExtractService  extractService
+	// ReleaseQueue processes the authorizations of tickets in the background, optional: without it, tickets are
+	// authorized before the response.
+	ReleaseQueue releaseQueue
 }
 
 // GetRESTHandlers get all controller API handler available for this service.
 func (o *Operation) GetRESTHandlers() []handler.Handler {
 	return []handler.Handler{
 		handler.NewHTTPHandler(policyEndpoint, http.MethodPut, o.createPolicyHandler, handler.WithAuth(handler.AuthToken)),
+		handler.NewHTTPHandler(policyEndpoint, http.MethodGet, o.getPolicyHandler, handler.WithAuth(handler.AuthToken)),
+		handler.NewHTTPHandler(policyEndpoint, http.MethodDelete, o.deletePolicyHandler, handler.WithAuth(handler.AuthToken)),
 		handler.NewHTTPHandler(protectEndpoint, http.MethodPost, o.protectHandler, handler.WithAuth(handler.AuthHTTPSig)),
 		handler.NewHTTPHandler(releaseEndpoint, http.MethodPost, o.releaseHandler, handler.WithAuth(handler.AuthHTTPSig)),
 		handler.NewHTTPHandler(authorizeEndpoint, http.MethodPost, o.authorizeHandler, handler.WithAuth(handler.AuthHTTPSig)),
@@ -96,17 +113,27 @@ func (o *Operation) GetRESTHandlers() []handler.Handler {
 
 // createPolicyHandler swagger:route PUT /v1/policy/{policy_id} gatekeeper createPolicyReq
 //
-// Creates policy configuration for storing and releasing protected data.
+// Creates or updates policy configuration for storing and releasing protected data. With an If-Match header, the
+// policy is updated only if its current version is the given one.
 //
 // Authorization: Bearer token
 //
 // Responses:
-//     200: createPolicyResp
+//     200: policyResp
+//     400: errorResp
+//     409: errorResp
 //     default: errorResp
 func (o *Operation) createPolicyHandler(rw http.ResponseWriter, r *http.Request) {
+	opts, err := policyOpts(r)
+	if err != nil {
+		respondError(rw, http.StatusBadRequest, err)
+
+		return
+	}
+
 	var p policy.Policy
 
-	err := json.NewDecoder(r.Body).Decode(&p)
+	err = json.NewDecoder(r.Body).Decode(&p)
 	if err != nil {
 		respondError(rw, http.StatusBadRequest, err)
 
@@ -115,9 +142,61 @@ func (o *Operation) createPolicyHandler(rw http.ResponseWriter, r *http.Request)
 
 	p.ID = strings.ToLower(mux.Vars(r)[policyIDVarName])
 
-	err = o.PolicyService.Save(r.Context(), &p)
+	err = o.PolicyService.Save(r.Context(), &p, opts...)
+	if err != nil {
+		respondError(rw, policyErrorStatus(err), fmt.Errorf("save policy: %w", err))
+
+		return
+	}
+
+	respondPolicy(rw, &p)
+}
+
+// getPolicyHandler swagger:route GET /v1/policy/{policy_id} gatekeeper getPolicyReq
+//
+// Gets policy configuration. Its version is also returned in the ETag header.
+//
+// Authorization: Bearer token
+//
+// Responses:
+//     200: policyResp
+//     404: errorResp
+//     default: errorResp
+func (o *Operation) getPolicyHandler(rw http.ResponseWriter, r *http.Request) {
+	p, err := o.PolicyService.Get(r.Context(), strings.ToLower(mux.Vars(r)[policyIDVarName]))
+	if err != nil {
+		respondError(rw, policyErrorStatus(err), err)
+
+		return
+	}
+
+	respondPolicy(rw, p)
+}
+
+// deletePolicyHandler swagger:route DELETE /v1/policy/{policy_id} gatekeeper deletePolicyReq
+//
+// Deletes policy configuration. With an If-Match header, the policy is deleted only if its current version is the
+// given one. The data protected with the policy keeps its snapshot of the policy, but cannot be released until the
+// policy is created again.
+//
+// Authorization: Bearer token
+//
+// Responses:
+//     200: deletePolicyResp
+//     404: errorResp
+//     409: errorResp
+//     default: errorResp
+func (o *Operation) deletePolicyHandler(rw http.ResponseWriter, r *http.Request) {
+	opts, err := policyOpts(r)
+	if err != nil {
+		respondError(rw, http.StatusBadRequest, err)
+
+		return
+	}
+
+	err = o.PolicyService.Delete(r.Context(), strings.ToLower(mux.Vars(r)[policyIDVarName]), opts...)
 	if err != nil {
-		respondError(rw, http.StatusInternalServerError, fmt.Errorf("save policy: %w", err))
+		respondError(rw, policyErrorStatus(err), fmt.Errorf("delete policy: %w", err))
 
 		return
 	}
@@ -125,14 +204,50 @@ func (o *Operation) createPolicyHandler(rw http.ResponseWriter, r *http.Request)
 	respond(rw, http.StatusOK, nil)
 }
 
+// policyOpts returns the options of a policy request. The If-Match header holds the expected version of the
+// policy, optionally quoted as an entity tag.
+func policyOpts(r *http.Request) ([]policy.Opt, error) {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		return nil, nil
+	}
+
+	version, err := strconv.Atoi(strings.Trim(ifMatch, `"`))
+	if err != nil {
+		return nil, fmt.Errorf("invalid If-Match header: %s", ifMatch)
+	}
+
+	return []policy.Opt{policy.WithExpectedVersion(version)}, nil
+}
+
+func policyErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, policy.ErrInvalidPolicy):
+		return http.StatusBadRequest
+	case errors.Is(err, policy.ErrVersionMismatch):
+		return http.StatusConflict
+	case errors.Is(err, storage.ErrDataNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func respondPolicy(rw http.ResponseWriter, p *policy.Policy) {
+	rw.Header().Set("ETag", strconv.Quote(strconv.Itoa(p.Version)))
+
+	respond(rw, http.StatusOK, p)
+}
+
 // protectHandler swagger:route POST /v1/protect gatekeeper protectReq
 //
-// Converts a social media handle (or other sensitive string data) into a DID.
+// Converts a social media handle (or other sensitive string data) into a DID, under an existing policy.
 //
 // Authorization: HTTP Signatures (headers="(request-target) date digest")
 //
 // Responses:
 //     200: protectResp
+//     404: errorResp
 //     default: errorResp
 func (o *Operation) protectHandler(rw http.ResponseWriter, r *http.Request) {
 	var req ProtectRequest
@@ -152,7 +267,7 @@ func (o *Operation) protectHandler(rw http.ResponseWriter, r *http.Request) {
 
 	protectedData, err := o.ProtectService.Protect(r.Context(), req.Target, req.Policy)
 	if err != nil {
-		respondError(rw, http.StatusInternalServerError, err)
+		respondError(rw, policyErrorStatus(err), err)
 
 		return
 	}
@@ -162,7 +277,7 @@ func (o *Operation) protectHandler(rw http.ResponseWriter, r *http.Request) {
 
 // releaseHandler swagger:route POST /v1/release gatekeeper releaseReq
 //
-// Creates a new release transaction (ticket) on a DID.
+// Creates a new release transaction (ticket) on a DID, or returns the open ticket of the requesting party on the DID.
 //
 // Authorization: HTTP Signatures (headers="(request-target) date")
 //
@@ -186,13 +301,14 @@ func (o *Operation) releaseHandler(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if _, err = o.checkPolicy(r.Context(), protectedData.PolicyID, policy.Handler); err != nil {
+	sub, err := o.checkPolicy(r.Context(), protectedData.PolicyID, policy.Handler)
+	if err != nil {
 		respondError(rw, err.(*policyError).status, err) //nolint:errorlint,forcetypeassert
 
 		return
 	}
 
-	t, err := o.ReleaseService.Release(r.Context(), req.DID)
+	t, err := o.ReleaseService.Release(r.Context(), req.DID, sub)
 	if err != nil {
 		respondError(rw, http.StatusInternalServerError, err)
 
@@ -204,12 +320,18 @@ func (o *Operation) releaseHandler(rw http.ResponseWriter, r *http.Request) {
 
 // authorizeHandler swagger:route POST /v1/release/{ticket_id}/authorize gatekeeper authorizeReq
 //
-// Authorizes release transaction (ticket).
+// Authorizes release transaction (ticket). The authorization may be processed after the response: the status of the
+// ticket reflects it once it is.
 //
 // Authorization: HTTP Signatures (headers="(request-target) date")
 //
 // Responses:
 //     200: authorizeResp
+//     202: authorizeResp
+//     400: errorResp
+//     403: errorResp
+//     409: errorResp
+//     503: errorResp
 //     default: errorResp
 func (o *Operation) authorizeHandler(rw http.ResponseWriter, r *http.Request) {
 	ticketID := mux.Vars(r)[ticketIDVarName]
@@ -218,6 +340,8 @@ func (o *Operation) authorizeHandler(rw http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		if errors.Is(err, storage.ErrDataNotFound) {
 			respondError(rw, http.StatusBadRequest, err)
+
+			return
 		}
 
 		respondError(rw, http.StatusInternalServerError, err)
@@ -240,7 +364,19 @@ func (o *Operation) authorizeHandler(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if o.ReleaseQueue != nil {
+		o.enqueueAuthorization(r.Context(), rw, ticketID, sub)
+
+		return
+	}
+
 	if err = o.ReleaseService.Authorize(r.Context(), ticketID, sub); err != nil {
+		if errors.Is(err, release.ErrInvalidTransition) {
+			respondError(rw, http.StatusConflict, err)
+
+			return
+		}
+
 		respondError(rw, http.StatusInternalServerError, err)
 
 		return
@@ -249,6 +385,28 @@ func (o *Operation) authorizeHandler(rw http.ResponseWriter, r *http.Request) {
 	respond(rw, http.StatusOK, nil)
 }
 
+// enqueueAuthorization responds with 202 once the authorization is queued. Authorizations of unknown tickets, of
+// closed tickets and by DIDs that are not approvers of the ticket are rejected before they are queued.
+func (o *Operation) enqueueAuthorization(ctx context.Context, rw http.ResponseWriter, ticketID, approverDID string) {
+	err := o.ReleaseQueue.Authorize(ctx, ticketID, approverDID)
+
+	switch {
+	case err == nil:
+		respond(rw, http.StatusAccepted, nil)
+	case errors.Is(err, storage.ErrDataNotFound):
+		respondError(rw, http.StatusBadRequest, err)
+	case errors.Is(err, release.ErrNotApprover):
+		respondError(rw, http.StatusForbidden, err)
+	case errors.Is(err, release.ErrInvalidTransition):
+		respondError(rw, http.StatusConflict, err)
+	case errors.Is(err, release.ErrQueueFull), errors.Is(err, release.ErrPoolClosed):
+		rw.Header().Set("Retry-After", retryAfterSeconds)
+		respondError(rw, http.StatusServiceUnavailable, fmt.Errorf("enqueue authorization: %w", err))
+	default:
+		respondError(rw, http.StatusInternalServerError, fmt.Errorf("enqueue authorization: %w", err))
+	}
+}
+
 // ticketStatusHandler swagger:route GET /v1/release/{ticket_id}/status gatekeeper ticketStatusReq
 //
 // Gets the status of the ticket.
@@ -290,7 +448,7 @@ func (o *Operation) ticketStatusHandler(rw http.ResponseWriter, r *http.Request)
 
 // collectHandler swagger:route POST /v1/release/{ticket_id}/collect gatekeeper collectReq
 //
-// Generates extract query for the ticket that has completed authorization process.
+// Generates extract query for the ticket that has completed authorization process. A ticket is collected once.
 //
 // Authorization: HTTP Signatures (headers="(request-target) date")
 //
@@ -327,6 +485,19 @@ func (o *Operation) collectHandler(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// the ticket is marked collected first, so that concurrent requests cannot collect it twice
+	if err = o.ReleaseService.Collect(r.Context(), ticketID); err != nil {
+		if errors.Is(err, release.ErrInvalidTransition) {
+			respondError(rw, http.StatusUnauthorized, errors.New("not authorized to access ticket"))
+
+			return
+		}
+
+		respondError(rw, http.StatusInternalServerError, fmt.Errorf("fail to collect ticket: %w", err))
+
+		return
+	}
+
 	queryID, err := o.CollectService.Collect(r.Context(), protectedData, subDID)
 	if err != nil {
 		respondError(rw, http.StatusInternalServerError, fmt.Errorf("fail to collect data: %w", err))
@@ -389,7 +560,7 @@ func (o *Operation) checkPolicy(ctx context.Context, policyID string, role polic
 			return "", &policyError{status: http.StatusUnauthorized, err: err}
 		}
 
-		return "", &policyError{status: http.StatusInternalServerError, err: err}
+		return "", &policyError{status: policyErrorStatus(err), err: err}
 	}
 
 	return sub, nil
@@ -416,7 +587,10 @@ func respondError(w http.ResponseWriter, statusCode int, err error) {
 
 	w.WriteHeader(statusCode)
 
-	if encErr := json.NewEncoder(w).Encode(&model.ErrorResponse{Message: errorMessage}); encErr != nil {
+	if encErr := json.NewEncoder(w).Encode(&model.ErrorResponse{
+		Message: errorMessage,
+		Code:    model.StatusErrorCode(statusCode),
+	}); encErr != nil {
 		logger.Errorf("Failed to write error response: %s", err.Error())
 	}
 }
@@ -11,6 +11,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -23,6 +24,7 @@ import (
 
 	"github.com/trustbloc/ace/pkg/gatekeeper/policy"
 	"github.com/trustbloc/ace/pkg/gatekeeper/protect"
+	"github.com/trustbloc/ace/pkg/gatekeeper/release"
 	"github.com/trustbloc/ace/pkg/gatekeeper/release/ticket"
 	"github.com/trustbloc/ace/pkg/restapi/gatekeeper/operation"
 )
@@ -74,6 +76,7 @@ func TestProtectHandler(t *testing.T) {
 			bytes.NewBufferString("invalid json"))
 
 		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), `"code":"MALFORMED_REQUEST"`)
 	})
 
 	t.Run("Fail to resolve subject DID from context", func(t *testing.T) {
@@ -131,6 +134,34 @@ func TestProtectHandler(t *testing.T) {
 		require.Equal(t, http.StatusUnauthorized, rr.Code)
 	})
 
+	t.Run("Fail to check policy: policy not found", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		protectService := NewMockProtectService(ctrl)
+		protectService.EXPECT().Protect(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+		policyService := NewMockPolicyService(ctrl)
+		policyService.EXPECT().Check(gomock.Any(), req.Policy, subjectDID, policy.Collector).
+			Return(fmt.Errorf("get policy: %w", storage.ErrDataNotFound))
+
+		subjectResolver := NewMockSubjectResolver(ctrl)
+		subjectResolver.EXPECT().Resolve(gomock.Any()).Return(subjectDID, nil)
+
+		op := &operation.Operation{
+			ProtectService:  protectService,
+			PolicyService:   policyService,
+			SubjectResolver: subjectResolver,
+		}
+
+		body, err := json.Marshal(req)
+		require.NoError(t, err)
+
+		rr := handleRequest(t, op, "/v1/protect", http.MethodPost, bytes.NewReader(body))
+
+		require.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
 	t.Run("Fail to check policy: internal error", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
@@ -250,6 +281,245 @@ func TestCreatePolicyHandler(t *testing.T) {
 
 		require.Equal(t, http.StatusInternalServerError, rr.Code)
 	})
+
+	t.Run("Success with expected version", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		policyService := NewMockPolicyService(ctrl)
+		policyService.EXPECT().Save(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, doc *policy.Policy, opts ...policy.Opt) error {
+				require.Equal(t, "containment-policy", doc.ID)
+				require.Len(t, opts, 1)
+
+				doc.Version = 4
+
+				return nil
+			})
+
+		op := &operation.Operation{
+			PolicyService: policyService,
+		}
+
+		body, err := json.Marshal(p)
+		require.NoError(t, err)
+
+		rr := handleRequestWithHeader(t, op, "/v1/policy/containment-policy", http.MethodPut, bytes.NewReader(body),
+			http.Header{"If-Match": []string{`"3"`}})
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, `"4"`, rr.Header().Get("ETag"))
+
+		var saved policy.Policy
+
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&saved))
+		require.Equal(t, 4, saved.Version)
+	})
+
+	t.Run("Fail to save policy", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			err    error
+			status int
+		}{
+			"Version mismatch": {
+				err:    fmt.Errorf("%w: expected 3, current 4", policy.ErrVersionMismatch),
+				status: http.StatusConflict,
+			},
+			"Invalid policy": {
+				err:    fmt.Errorf("%w: ticket_expiry must not be negative", policy.ErrInvalidPolicy),
+				status: http.StatusBadRequest,
+			},
+		} {
+			tc := tc
+
+			t.Run(name, func(t *testing.T) {
+				ctrl := gomock.NewController(t)
+				defer ctrl.Finish()
+
+				policyService := NewMockPolicyService(ctrl)
+				policyService.EXPECT().Save(gomock.Any(), gomock.Any(), gomock.Any()).Return(tc.err)
+
+				op := &operation.Operation{
+					PolicyService: policyService,
+				}
+
+				body, err := json.Marshal(p)
+				require.NoError(t, err)
+
+				rr := handleRequestWithHeader(t, op, "/v1/policy/containment-policy", http.MethodPut,
+					bytes.NewReader(body), http.Header{"If-Match": []string{"3"}})
+
+				require.Equal(t, tc.status, rr.Code)
+			})
+		}
+	})
+
+	t.Run("Invalid If-Match header", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		policyService := NewMockPolicyService(ctrl)
+		policyService.EXPECT().Save(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+		op := &operation.Operation{
+			PolicyService: policyService,
+		}
+
+		body, err := json.Marshal(p)
+		require.NoError(t, err)
+
+		rr := handleRequestWithHeader(t, op, "/v1/policy/containment-policy", http.MethodPut, bytes.NewReader(body),
+			http.Header{"If-Match": []string{"*"}})
+
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestGetPolicyHandler(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		policyService := NewMockPolicyService(ctrl)
+		policyService.EXPECT().Get(gomock.Any(), "containment-policy").Return(&policy.Policy{
+			ID:           "containment-policy",
+			Collectors:   []string{"did:example:ray_stantz"},
+			TicketExpiry: 3600,
+			Version:      2,
+		}, nil)
+
+		op := &operation.Operation{
+			PolicyService: policyService,
+		}
+
+		rr := handleRequest(t, op, "/v1/policy/Containment-Policy", http.MethodGet, nil)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, `"2"`, rr.Header().Get("ETag"))
+
+		var p policy.Policy
+
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&p))
+		require.Equal(t, "containment-policy", p.ID)
+		require.Equal(t, 3600, p.TicketExpiry)
+		require.Equal(t, 2, p.Version)
+	})
+
+	t.Run("Policy not found", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		policyService := NewMockPolicyService(ctrl)
+		policyService.EXPECT().Get(gomock.Any(), "containment-policy").
+			Return(nil, fmt.Errorf("get policy: %w", storage.ErrDataNotFound))
+
+		op := &operation.Operation{
+			PolicyService: policyService,
+		}
+
+		rr := handleRequest(t, op, "/v1/policy/containment-policy", http.MethodGet, nil)
+
+		require.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("Fail to get policy", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		policyService := NewMockPolicyService(ctrl)
+		policyService.EXPECT().Get(gomock.Any(), "containment-policy").Return(nil, errors.New("get error"))
+
+		op := &operation.Operation{
+			PolicyService: policyService,
+		}
+
+		rr := handleRequest(t, op, "/v1/policy/containment-policy", http.MethodGet, nil)
+
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+}
+
+func TestDeletePolicyHandler(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		policyService := NewMockPolicyService(ctrl)
+		policyService.EXPECT().Delete(gomock.Any(), "containment-policy").Return(nil)
+
+		op := &operation.Operation{
+			PolicyService: policyService,
+		}
+
+		rr := handleRequest(t, op, "/v1/policy/containment-policy", http.MethodDelete, nil)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("Success with expected version", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		policyService := NewMockPolicyService(ctrl)
+		policyService.EXPECT().Delete(gomock.Any(), "containment-policy", gomock.Any()).Return(nil)
+
+		op := &operation.Operation{
+			PolicyService: policyService,
+		}
+
+		rr := handleRequestWithHeader(t, op, "/v1/policy/containment-policy", http.MethodDelete, nil,
+			http.Header{"If-Match": []string{`"2"`}})
+
+		require.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("Fail to delete policy", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			err    error
+			status int
+		}{
+			"Policy not found": {
+				err:    fmt.Errorf("get policy: %w", storage.ErrDataNotFound),
+				status: http.StatusNotFound,
+			},
+			"Version mismatch": {
+				err:    fmt.Errorf("%w: expected 2, current 3", policy.ErrVersionMismatch),
+				status: http.StatusConflict,
+			},
+			"Internal error": {
+				err:    errors.New("delete error"),
+				status: http.StatusInternalServerError,
+			},
+		} {
+			tc := tc
+
+			t.Run(name, func(t *testing.T) {
+				ctrl := gomock.NewController(t)
+				defer ctrl.Finish()
+
+				policyService := NewMockPolicyService(ctrl)
+				policyService.EXPECT().Delete(gomock.Any(), "containment-policy", gomock.Any()).Return(tc.err)
+
+				op := &operation.Operation{
+					PolicyService: policyService,
+				}
+
+				rr := handleRequestWithHeader(t, op, "/v1/policy/containment-policy", http.MethodDelete, nil,
+					http.Header{"If-Match": []string{"2"}})
+
+				require.Equal(t, tc.status, rr.Code)
+			})
+		}
+	})
+
+	t.Run("Invalid If-Match header", func(t *testing.T) {
+		op := &operation.Operation{}
+
+		rr := handleRequestWithHeader(t, op, "/v1/policy/containment-policy", http.MethodDelete, nil,
+			http.Header{"If-Match": []string{"latest"}})
+
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+	})
 }
 
 func TestReleaseHandler(t *testing.T) {
@@ -261,7 +531,7 @@ func TestReleaseHandler(t *testing.T) {
 		ctrl := gomock.NewController(t)
 
 		releaseService := NewMockReleaseService(ctrl)
-		releaseService.EXPECT().Release(gomock.Any(), targetDID).Return(&ticket.Ticket{}, nil).Times(1)
+		releaseService.EXPECT().Release(gomock.Any(), targetDID, subjectDID).Return(&ticket.Ticket{}, nil).Times(1)
 
 		protectService := NewMockProtectService(ctrl)
 		protectService.EXPECT().Get(gomock.Any(), targetDID).
@@ -292,7 +562,7 @@ func TestReleaseHandler(t *testing.T) {
 		ctrl := gomock.NewController(t)
 
 		svc := NewMockReleaseService(ctrl)
-		svc.EXPECT().Release(gomock.Any(), targetDID).Times(0)
+		svc.EXPECT().Release(gomock.Any(), targetDID, gomock.Any()).Times(0)
 
 		op := &operation.Operation{
 			ReleaseService: svc,
@@ -307,7 +577,7 @@ func TestReleaseHandler(t *testing.T) {
 		ctrl := gomock.NewController(t)
 
 		releaseService := NewMockReleaseService(ctrl)
-		releaseService.EXPECT().Release(gomock.Any(), gomock.Any()).Times(0)
+		releaseService.EXPECT().Release(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 
 		protectService := NewMockProtectService(ctrl)
 		protectService.EXPECT().Get(gomock.Any(), targetDID).Return(nil, errors.New("get error")).Times(1)
@@ -337,7 +607,7 @@ func TestReleaseHandler(t *testing.T) {
 		ctrl := gomock.NewController(t)
 
 		releaseService := NewMockReleaseService(ctrl)
-		releaseService.EXPECT().Release(gomock.Any(), gomock.Any()).Times(0)
+		releaseService.EXPECT().Release(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 
 		protectService := NewMockProtectService(ctrl)
 		protectService.EXPECT().Get(gomock.Any(), targetDID).
@@ -369,7 +639,7 @@ func TestReleaseHandler(t *testing.T) {
 		ctrl := gomock.NewController(t)
 
 		releaseService := NewMockReleaseService(ctrl)
-		releaseService.EXPECT().Release(gomock.Any(), targetDID).Return(nil, errors.New("release error"))
+		releaseService.EXPECT().Release(gomock.Any(), targetDID, subjectDID).Return(nil, errors.New("release error"))
 
 		protectService := NewMockProtectService(ctrl)
 		protectService.EXPECT().Get(gomock.Any(), targetDID).
@@ -551,6 +821,118 @@ func TestAuthorizeHandler(t *testing.T) {
 
 		require.Equal(t, http.StatusInternalServerError, rr.Code)
 	})
+
+	t.Run("Ticket is closed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		releaseService := NewMockReleaseService(ctrl)
+		releaseService.EXPECT().Get(gomock.Any(), testTicketID).Return(&ticket.Ticket{
+			ID:     testTicketID,
+			DID:    targetDID,
+			Status: ticket.Collected,
+		}, nil)
+		releaseService.EXPECT().Authorize(gomock.Any(), testTicketID, subjectDID).
+			Return(fmt.Errorf("update ticket: %w", release.ErrInvalidTransition))
+
+		protectService := NewMockProtectService(ctrl)
+		protectService.EXPECT().Get(gomock.Any(), targetDID).Return(&protect.ProtectedData{
+			PolicyID: testPolicyID,
+		}, nil)
+
+		policyService := NewMockPolicyService(ctrl)
+		policyService.EXPECT().Check(gomock.Any(), testPolicyID, subjectDID, policy.Approver).Return(nil)
+
+		subjectResolver := NewMockSubjectResolver(ctrl)
+		subjectResolver.EXPECT().Resolve(gomock.Any()).Return(subjectDID, nil)
+
+		op := &operation.Operation{
+			ReleaseService:  releaseService,
+			PolicyService:   policyService,
+			ProtectService:  protectService,
+			SubjectResolver: subjectResolver,
+		}
+
+		rr := handleRequest(t, op, "/v1/release/test-ticket/authorize", http.MethodPost, nil)
+
+		require.Equal(t, http.StatusConflict, rr.Code)
+	})
+
+	t.Run("Authorization queued", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			err    error
+			status int
+		}{
+			"Queued": {
+				status: http.StatusAccepted,
+			},
+			"Queue full": {
+				err:    release.ErrQueueFull,
+				status: http.StatusServiceUnavailable,
+			},
+			"Queue closed": {
+				err:    release.ErrPoolClosed,
+				status: http.StatusServiceUnavailable,
+			},
+			"Unknown ticket": {
+				err:    fmt.Errorf("get ticket to authorize: %w", storage.ErrDataNotFound),
+				status: http.StatusBadRequest,
+			},
+			"Not an approver": {
+				err:    fmt.Errorf("%w: %s", release.ErrNotApprover, subjectDID),
+				status: http.StatusForbidden,
+			},
+			"Closed ticket": {
+				err:    fmt.Errorf("%w: COLLECTED ticket cannot be authorized", release.ErrInvalidTransition),
+				status: http.StatusConflict,
+			},
+			"Fail to enqueue": {
+				err:    errors.New("enqueue error"),
+				status: http.StatusInternalServerError,
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				ctrl := gomock.NewController(t)
+
+				// the ticket is authorized by the queue only
+				releaseService := NewMockReleaseService(ctrl)
+				releaseService.EXPECT().Get(gomock.Any(), testTicketID).Return(&ticket.Ticket{
+					ID:     testTicketID,
+					DID:    targetDID,
+					Status: 0,
+				}, nil)
+
+				releaseQueue := NewMockReleaseQueue(ctrl)
+				releaseQueue.EXPECT().Authorize(gomock.Any(), testTicketID, subjectDID).Return(tc.err)
+
+				protectService := NewMockProtectService(ctrl)
+				protectService.EXPECT().Get(gomock.Any(), targetDID).Return(&protect.ProtectedData{
+					PolicyID: testPolicyID,
+				}, nil)
+
+				policyService := NewMockPolicyService(ctrl)
+				policyService.EXPECT().Check(gomock.Any(), testPolicyID, subjectDID, policy.Approver).Return(nil)
+
+				subjectResolver := NewMockSubjectResolver(ctrl)
+				subjectResolver.EXPECT().Resolve(gomock.Any()).Return(subjectDID, nil)
+
+				op := &operation.Operation{
+					ReleaseService:  releaseService,
+					ReleaseQueue:    releaseQueue,
+					PolicyService:   policyService,
+					ProtectService:  protectService,
+					SubjectResolver: subjectResolver,
+				}
+
+				rr := handleRequest(t, op, "/v1/release/test-ticket/authorize", http.MethodPost, nil)
+
+				require.Equal(t, tc.status, rr.Code)
+
+				if tc.status == http.StatusServiceUnavailable {
+					require.Equal(t, "1", rr.Header().Get("Retry-After"))
+				}
+			})
+		}
+	})
 }
 
 func TestTicketStatusHandler(t *testing.T) {
@@ -691,6 +1073,7 @@ func TestCollectHandler(t *testing.T) {
 		releaseService := NewMockReleaseService(ctrl)
 		releaseService.EXPECT().Get(gomock.Any(), testTicketID).
 			Return(&ticket.Ticket{DID: testDID, Status: ticket.ReadyToCollect}, nil)
+		releaseService.EXPECT().Collect(gomock.Any(), testTicketID).Return(nil)
 
 		collectService := NewMockCollectService(ctrl)
 		collectService.EXPECT().Collect(gomock.Any(), protectedData, subjectDID).Return(testQueryID, nil)
@@ -858,6 +1241,42 @@ func TestCollectHandler(t *testing.T) {
 		require.Equal(t, http.StatusUnauthorized, rr.Code)
 	})
 
+	t.Run("Ticket already collected", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		releaseService := NewMockReleaseService(ctrl)
+		releaseService.EXPECT().Get(gomock.Any(), testTicketID).
+			Return(&ticket.Ticket{DID: testDID, Status: ticket.ReadyToCollect}, nil)
+		releaseService.EXPECT().Collect(gomock.Any(), testTicketID).
+			Return(fmt.Errorf("update ticket: %w", release.ErrInvalidTransition))
+
+		collectService := NewMockCollectService(ctrl)
+		collectService.EXPECT().Collect(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+		protectService := NewMockProtectService(ctrl)
+		protectService.EXPECT().Get(gomock.Any(), testDID).Return(protectedData, nil)
+
+		policyService := NewMockPolicyService(ctrl)
+		policyService.EXPECT().Check(gomock.Any(), testPolicyID, subjectDID, policy.Handler).
+			Return(nil)
+
+		subjectResolver := NewMockSubjectResolver(ctrl)
+		subjectResolver.EXPECT().Resolve(gomock.Any()).Return(subjectDID, nil).AnyTimes()
+
+		op := &operation.Operation{
+			ReleaseService:  releaseService,
+			PolicyService:   policyService,
+			ProtectService:  protectService,
+			SubjectResolver: subjectResolver,
+			CollectService:  collectService,
+		}
+
+		rr := handleRequest(t, op, "/v1/release/"+testTicketID+"/collect", http.MethodPost, bytes.NewReader([]byte{}))
+
+		require.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
 	t.Run("Fail to collect data", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
@@ -865,6 +1284,7 @@ func TestCollectHandler(t *testing.T) {
 		releaseService := NewMockReleaseService(ctrl)
 		releaseService.EXPECT().Get(gomock.Any(), testTicketID).
 			Return(&ticket.Ticket{DID: testDID, Status: ticket.ReadyToCollect}, nil)
+		releaseService.EXPECT().Collect(gomock.Any(), testTicketID).Return(nil)
 
 		collectService := NewMockCollectService(ctrl)
 		collectService.EXPECT().Collect(gomock.Any(), protectedData, subjectDID).
@@ -961,6 +1381,14 @@ func handleRequest(t *testing.T, op *operation.Operation, path, method string, b
 ) *httptest.ResponseRecorder {
 	t.Helper()
 
+	return handleRequestWithHeader(t, op, path, method, body, nil)
+}
+
+func handleRequestWithHeader(t *testing.T, op *operation.Operation, path, method string, body io.Reader,
+	header http.Header,
+) *httptest.ResponseRecorder {
+	t.Helper()
+
 	router := mux.NewRouter()
 
 	for _, h := range op.GetRESTHandlers() {
@@ -970,6 +1398,10 @@ func handleRequest(t *testing.T, op *operation.Operation, path, method string, b
 	req, err := http.NewRequestWithContext(context.Background(), method, path, body)
 	require.NoError(t, err)
 
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
 	rr := httptest.NewRecorder()
 
 	router.ServeHTTP(rr, req)
@@ -11,10 +11,10 @@ import (
 )
 
 // New returns new controller instance.
-func New() *Controller {
+func New(opts ...operation.Option) *Controller {
 	var allHandlers []handler.Handler
 
-	rpService := operation.New()
+	rpService := operation.New(opts...)
 
 	handlers := rpService.GetRESTHandlers()
 
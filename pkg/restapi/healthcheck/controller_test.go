@@ -19,6 +19,6 @@ func TestController_New(t *testing.T) {
 		require.NotNil(t, controller)
 		ops := controller.GetOperations()
 
-		require.Equal(t, 1, len(ops))
+		require.Equal(t, 2, len(ops))
 	})
 }
@@ -20,36 +20,121 @@ var logger = log.New("healthcheck")
 // API endpoints.
 const (
 	healthCheckEndpoint = "/healthcheck"
+	livenessEndpoint    = "/livez"
+	readinessEndpoint   = "/readiness"
+)
+
+const (
+	statusSuccess  = "success"
+	statusDegraded = "degraded"
 )
 
 type healthCheckResp struct {
-	Status      string    `json:"status"`
-	CurrentTime time.Time `json:"currentTime"`
+	Status      string            `json:"status"`
+	CurrentTime time.Time         `json:"currentTime"`
+	Checks      map[string]string `json:"checks,omitempty"`
+}
+
+// ReadinessCheck reports whether a dependency of the service is ready. It returns nil if it is.
+type ReadinessCheck func() error
+
+type readinessCheck struct {
+	name  string
+	check ReadinessCheck
+}
+
+// Option configures the healthcheck operation.
+type Option func(o *Operation)
+
+// WithReadinessCheck adds a check to the readiness endpoint. The readiness endpoint is only served if at least
+// one check is configured.
+func WithReadinessCheck(name string, check ReadinessCheck) Option {
+	return func(o *Operation) {
+		o.readinessChecks = append(o.readinessChecks, &readinessCheck{name: name, check: check})
+	}
 }
 
 // New returns CreateCredential instance.
-func New() *Operation {
-	return &Operation{}
+func New(opts ...Option) *Operation {
+	o := &Operation{}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
 }
 
 // Operation defines handlers for rp operations.
-type Operation struct{}
+type Operation struct {
+	readinessChecks []*readinessCheck
+}
 
 // GetRESTHandlers get all controller API handler available for this service.
 func (o *Operation) GetRESTHandlers() []handler.Handler {
-	return []handler.Handler{
+	handlers := []handler.Handler{
 		handler.NewHTTPHandler(healthCheckEndpoint, http.MethodGet, o.healthCheckHandler),
+		handler.NewHTTPHandler(livenessEndpoint, http.MethodGet, o.livenessHandler),
+	}
+
+	if len(o.readinessChecks) > 0 {
+		handlers = append(handlers, handler.NewHTTPHandler(readinessEndpoint, http.MethodGet, o.readinessHandler))
 	}
+
+	return handlers
 }
 
 func (o *Operation) healthCheckHandler(rw http.ResponseWriter, r *http.Request) {
 	rw.WriteHeader(http.StatusOK)
 
 	err := json.NewEncoder(rw).Encode(&healthCheckResp{
-		Status:      "success",
+		Status:      statusSuccess,
 		CurrentTime: time.Now(),
 	})
 	if err != nil {
 		logger.Errorf("healthcheck response failure, %s", err)
 	}
 }
+
+// livenessHandler reports that the service can serve requests. It checks no dependency: a service whose
+// dependencies are unreachable is alive, only not ready.
+func (o *Operation) livenessHandler(rw http.ResponseWriter, r *http.Request) {
+	rw.WriteHeader(http.StatusOK)
+
+	err := json.NewEncoder(rw).Encode(&healthCheckResp{
+		Status:      statusSuccess,
+		CurrentTime: time.Now(),
+	})
+	if err != nil {
+		logger.Errorf("liveness response failure, %s", err)
+	}
+}
+
+func (o *Operation) readinessHandler(rw http.ResponseWriter, r *http.Request) {
+	resp := &healthCheckResp{
+		Status:      statusSuccess,
+		CurrentTime: time.Now(),
+		Checks:      make(map[string]string),
+	}
+
+	status := http.StatusOK
+
+	for _, c := range o.readinessChecks {
+		if err := c.check(); err != nil {
+			status = http.StatusServiceUnavailable
+			resp.Status = statusDegraded
+			resp.Checks[c.name] = err.Error()
+
+			continue
+		}
+
+		resp.Checks[c.name] = statusSuccess
+	}
+
+	rw.WriteHeader(status)
+
+	err := json.NewEncoder(rw).Encode(resp)
+	if err != nil {
+		logger.Errorf("readiness response failure, %s", err)
+	}
+}
@@ -6,6 +6,7 @@ SPDX-License-Identifier: Apache-2.0
 package operation_test
 
 import (
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -18,7 +19,7 @@ import (
 
 func TestGetRESTHandlers(t *testing.T) {
 	c := operation.New()
-	require.Equal(t, 1, len(c.GetRESTHandlers()))
+	require.Equal(t, 2, len(c.GetRESTHandlers()))
 }
 
 func TestHealthCheck(t *testing.T) {
@@ -39,3 +40,73 @@ func TestHealthCheck(t *testing.T) {
 
 	require.Equal(t, http.StatusOK, b.Code)
 }
+
+func TestLiveness(t *testing.T) {
+	c := operation.New(operation.WithReadinessCheck("failing", func() error { return errors.New("test error") }))
+
+	var hndl handler.Handler
+
+	for _, h := range c.GetRESTHandlers() {
+		if h.Path() == "/livez" {
+			hndl = h
+		}
+	}
+
+	require.NotNil(t, hndl)
+
+	b := httptest.NewRecorder()
+	hndl.Handle()(b, nil)
+	require.Equal(t, http.StatusOK, b.Code)
+	require.Contains(t, b.Body.String(), `"status":"success"`)
+	require.NotContains(t, b.Body.String(), "test error")
+}
+
+func TestReadiness(t *testing.T) {
+	t.Run("not served without checks", func(t *testing.T) {
+		for _, h := range operation.New().GetRESTHandlers() {
+			require.NotEqual(t, "/readiness", h.Path())
+		}
+	})
+
+	t.Run("ready", func(t *testing.T) {
+		c := operation.New(operation.WithReadinessCheck("test", func() error { return nil }))
+		require.Equal(t, 3, len(c.GetRESTHandlers()))
+
+		b := httptest.NewRecorder()
+
+		readinessHandler(t, c).Handle()(b, nil)
+
+		require.Equal(t, http.StatusOK, b.Code)
+		require.Contains(t, b.Body.String(), `"status":"success"`)
+	})
+
+	t.Run("degraded", func(t *testing.T) {
+		c := operation.New(
+			operation.WithReadinessCheck("ok", func() error { return nil }),
+			operation.WithReadinessCheck("failing", func() error { return errors.New("test error") }),
+		)
+
+		b := httptest.NewRecorder()
+
+		readinessHandler(t, c).Handle()(b, nil)
+
+		require.Equal(t, http.StatusServiceUnavailable, b.Code)
+		require.Contains(t, b.Body.String(), `"status":"degraded"`)
+		require.Contains(t, b.Body.String(), `"failing":"test error"`)
+		require.Contains(t, b.Body.String(), `"ok":"success"`)
+	})
+}
+
+func readinessHandler(t *testing.T, c *operation.Operation) handler.Handler {
+	t.Helper()
+
+	for _, h := range c.GetRESTHandlers() {
+		if h.Path() == "/readiness" {
+			return h
+		}
+	}
+
+	require.FailNow(t, "readiness handler not found")
+
+	return nil
+}
@@ -6,6 +6,8 @@ SPDX-License-Identifier: Apache-2.0
 
 package model
 
+import "net/http"
+
 // UNIRegistrar uni-registrar.
 type UNIRegistrar struct {
 	DriverURL string            `json:"driverURL,omitempty"`
@@ -16,4 +18,74 @@ type UNIRegistrar struct {
 type ErrorResponse struct {
 	// error message
 	Message string `json:"errMessage,omitempty"`
+	// machine-readable error code, eg. NOT_FOUND
+	Code ErrorCode `json:"code,omitempty"`
+	// upstream server that refused the request, eg. edv
+	Upstream string `json:"upstream,omitempty"`
+	// how to remediate the error, when known
+	Hint string `json:"hint,omitempty"`
+}
+
+// ErrorCode identifies the kind of error of an ErrorResponse. Unlike messages, codes are stable: clients
+// should branch on them rather than on messages.
+type ErrorCode string
+
+// Error codes shared by all services.
+const (
+	ErrCodeMalformedRequest ErrorCode = "MALFORMED_REQUEST"
+	ErrCodeInvalidRequest   ErrorCode = "INVALID_REQUEST"
+	ErrCodeUnauthorized     ErrorCode = "UNAUTHORIZED"
+	ErrCodeForbidden        ErrorCode = "FORBIDDEN"
+	ErrCodeNotFound         ErrorCode = "NOT_FOUND"
+	ErrCodeConflict         ErrorCode = "CONFLICT"
+	ErrCodePayloadTooLarge  ErrorCode = "PAYLOAD_TOO_LARGE"
+	ErrCodeInternal         ErrorCode = "INTERNAL_ERROR"
+	ErrCodeNotImplemented   ErrorCode = "NOT_IMPLEMENTED"
+	ErrCodeUpstream         ErrorCode = "UPSTREAM_ERROR"
+)
+
+// Error codes specific to a service.
+const (
+	// ErrCodeQueryNotFound is returned by the CSH when a query referenced by ID does not exist.
+	ErrCodeQueryNotFound ErrorCode = "QUERY_NOT_FOUND"
+	// ErrCodeInvalidJSONPath is returned by the CSH when a JSONPath is malformed or selects nothing.
+	ErrCodeInvalidJSONPath ErrorCode = "INVALID_JSON_PATH"
+	// ErrCodeVaultDeleting is returned by the vault server when writing to a vault being deleted.
+	ErrCodeVaultDeleting ErrorCode = "VAULT_DELETING"
+	// ErrCodeSequenceMismatch is returned by the vault server when a document was updated concurrently.
+	ErrCodeSequenceMismatch ErrorCode = "SEQUENCE_MISMATCH"
+	// ErrCodeUpstreamUnauthorized is returned by the vault server when the EDV or KMS server refuses the
+	// authorization of a vault.
+	ErrCodeUpstreamUnauthorized ErrorCode = "UPSTREAM_UNAUTHORIZED"
+	// ErrCodeDeadlineExceeded is returned by the CSH when the deadline of a request expires before its documents
+	// are fetched.
+	ErrCodeDeadlineExceeded ErrorCode = "DEADLINE_EXCEEDED"
+)
+
+// StatusErrorCode returns the code of errors responded with the given HTTP status and no more specific code.
+func StatusErrorCode(status int) ErrorCode {
+	switch status {
+	case http.StatusBadRequest:
+		return ErrCodeMalformedRequest
+	case http.StatusUnauthorized:
+		return ErrCodeUnauthorized
+	case http.StatusForbidden:
+		return ErrCodeForbidden
+	case http.StatusNotFound:
+		return ErrCodeNotFound
+	case http.StatusConflict:
+		return ErrCodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return ErrCodePayloadTooLarge
+	case http.StatusNotImplemented:
+		return ErrCodeNotImplemented
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return ErrCodeUpstream
+	}
+
+	if status >= http.StatusInternalServerError {
+		return ErrCodeInternal
+	}
+
+	return ErrCodeInvalidRequest
 }
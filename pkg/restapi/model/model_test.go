@@ -0,0 +1,34 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package model_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/ace/pkg/restapi/model"
+)
+
+func TestStatusErrorCode(t *testing.T) {
+	for status, code := range map[int]model.ErrorCode{
+		http.StatusBadRequest:            model.ErrCodeMalformedRequest,
+		http.StatusUnauthorized:          model.ErrCodeUnauthorized,
+		http.StatusForbidden:             model.ErrCodeForbidden,
+		http.StatusNotFound:              model.ErrCodeNotFound,
+		http.StatusConflict:              model.ErrCodeConflict,
+		http.StatusRequestEntityTooLarge: model.ErrCodePayloadTooLarge,
+		http.StatusUnprocessableEntity:   model.ErrCodeInvalidRequest,
+		http.StatusInternalServerError:   model.ErrCodeInternal,
+		http.StatusNotImplemented:        model.ErrCodeNotImplemented,
+		http.StatusBadGateway:            model.ErrCodeUpstream,
+		http.StatusServiceUnavailable:    model.ErrCodeInternal,
+	} {
+		require.Equal(t, code, model.StatusErrorCode(status), status)
+	}
+}
@@ -0,0 +1,183 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gzipmw
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	encodingGzip = "gzip"
+
+	// DefaultMaxDecompressedSize is the default maximum size, in bytes, of decompressed request bodies.
+	DefaultMaxDecompressedSize = 32 << 20
+)
+
+// New returns middleware that decompresses request bodies sent with `Content-Encoding: gzip` and compresses
+// responses to clients sending `Accept-Encoding: gzip`. Request bodies are decompressed as the handler reads them,
+// so the handler's own size limits apply to the decompressed content; reading more than maxDecompressedSize bytes
+// fails like a body limited by http.MaxBytesReader.
+func New(maxDecompressedSize int64) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.EqualFold(strings.TrimSpace(r.Header.Get("Content-Encoding")), encodingGzip) {
+				if err := decompressBody(w, r, maxDecompressedSize); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+
+					return
+				}
+			}
+
+			if !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			gw := &gzipResponseWriter{ResponseWriter: w}
+			defer gw.close()
+
+			next.ServeHTTP(gw, r)
+		})
+	}
+}
+
+// decompressBody replaces the body of the request with a reader decompressing it. Only the gzip header is read
+// up front; the size of the decompressed content is unknown until the handler has read it.
+func decompressBody(w http.ResponseWriter, r *http.Request, maxSize int64) error {
+	zr, err := gzip.NewReader(r.Body)
+	if err != nil {
+		return fmt.Errorf("invalid gzip body: %w", err)
+	}
+
+	r.Body = &gzipBody{
+		Reader: http.MaxBytesReader(w, zr, maxSize),
+		zr:     zr,
+		body:   r.Body,
+	}
+	r.ContentLength = -1
+	r.Header.Del("Content-Encoding")
+	r.Header.Del("Content-Length")
+
+	return nil
+}
+
+// gzipBody is a decompressed request body. Closing it closes the compressed body as well.
+type gzipBody struct {
+	io.Reader
+	zr   *gzip.Reader
+	body io.ReadCloser
+}
+
+func (b *gzipBody) Close() error {
+	b.zr.Close() // nolint:errcheck,gosec
+
+	return b.body.Close()
+}
+
+// acceptsGzip reports whether the Accept-Encoding header lists gzip without a zero quality value.
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+
+		if !strings.EqualFold(strings.TrimSpace(params[0]), encodingGzip) {
+			continue
+		}
+
+		for _, param := range params[1:] {
+			if q := strings.TrimSpace(param); strings.HasPrefix(q, "q=") {
+				v, err := strconv.ParseFloat(strings.TrimPrefix(q, "q="), 64)
+				if err != nil || v == 0 {
+					return false
+				}
+			}
+		}
+
+		return true
+	}
+
+	return false
+}
+
+// gzipResponseWriter compresses the response body. The status is held back until the body is first written
+// so that empty responses are sent uncompressed.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	status int
+	gz     *gzip.Writer
+}
+
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if w.status == 0 && w.gz == nil {
+		w.status = status
+	}
+}
+
+func (w *gzipResponseWriter) Write(p []byte) (int, error) {
+	if w.gz == nil {
+		if len(p) == 0 {
+			return 0, nil
+		}
+
+		h := w.Header()
+
+		// the content type must be detected from the uncompressed content
+		if h.Get("Content-Type") == "" {
+			h.Set("Content-Type", http.DetectContentType(p))
+		}
+
+		h.Set("Content-Encoding", encodingGzip)
+		h.Add("Vary", "Accept-Encoding")
+		h.Del("Content-Length")
+
+		w.ResponseWriter.WriteHeader(w.statusOrOK())
+
+		w.gz = gzip.NewWriter(w.ResponseWriter)
+	}
+
+	return w.gz.Write(p)
+}
+
+// Flush sends the data compressed so far to the client.
+func (w *gzipResponseWriter) Flush() {
+	if w.gz != nil {
+		if err := w.gz.Flush(); err != nil {
+			return
+		}
+	}
+
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *gzipResponseWriter) close() {
+	if w.gz == nil {
+		if w.status != 0 {
+			w.ResponseWriter.WriteHeader(w.status)
+		}
+
+		return
+	}
+
+	w.gz.Close() // nolint:errcheck,gosec
+}
+
+func (w *gzipResponseWriter) statusOrOK() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+
+	return w.status
+}
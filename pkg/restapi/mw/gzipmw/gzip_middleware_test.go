@@ -0,0 +1,219 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gzipmw_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/ace/pkg/restapi/mw/gzipmw"
+)
+
+func TestMiddleware(t *testing.T) {
+	t.Run("decompresses gzip request bodies", func(t *testing.T) {
+		h := &echoHandler{}
+
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "http://example.com/extract",
+			bytes.NewReader(compress(t, []byte(`[{"type":"DocQuery"}]`))))
+		req.Header.Set("Content-Encoding", "gzip")
+
+		gzipmw.New(gzipmw.DefaultMaxDecompressedSize)(h).ServeHTTP(rw, req)
+
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.Equal(t, `[{"type":"DocQuery"}]`, rw.Body.String())
+		require.Empty(t, h.request.Header.Get("Content-Encoding"))
+		require.Equal(t, int64(-1), h.request.ContentLength)
+		require.Empty(t, h.request.Header.Get("Content-Length"))
+	})
+
+	t.Run("passes uncompressed request bodies through", func(t *testing.T) {
+		h := &echoHandler{}
+
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "http://example.com/extract", strings.NewReader(`{}`))
+
+		gzipmw.New(gzipmw.DefaultMaxDecompressedSize)(h).ServeHTTP(rw, req)
+
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.Equal(t, `{}`, rw.Body.String())
+		require.Empty(t, rw.Header().Get("Content-Encoding"))
+	})
+
+	t.Run("compressed round trip", func(t *testing.T) {
+		payload := strings.Repeat(`{"id":"q1","document":{"secret":"value"}}`, 100)
+
+		srv := httptest.NewServer(gzipmw.New(gzipmw.DefaultMaxDecompressedSize)(&echoHandler{status: http.StatusCreated}))
+		defer srv.Close()
+
+		req, err := http.NewRequest(http.MethodPost, srv.URL, bytes.NewReader(compress(t, []byte(payload)))) // nolint:noctx
+		require.NoError(t, err)
+
+		req.Header.Set("Content-Encoding", "gzip")
+		req.Header.Set("Accept-Encoding", "gzip")
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+
+		defer resp.Body.Close() // nolint:errcheck
+
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		require.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
+		require.Equal(t, "text/plain; charset=utf-8", resp.Header.Get("Content-Type"))
+
+		compressed, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Less(t, len(compressed), len(payload))
+		require.Equal(t, payload, string(decompress(t, compressed)))
+	})
+
+	t.Run("does not compress responses if gzip is not accepted", func(t *testing.T) {
+		for _, accept := range []string{"", "deflate", "gzip;q=0", "br, gzip; q=0"} {
+			rw := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "http://example.com/extract", strings.NewReader(`{}`))
+			req.Header.Set("Accept-Encoding", accept)
+
+			gzipmw.New(gzipmw.DefaultMaxDecompressedSize)(&echoHandler{}).ServeHTTP(rw, req)
+
+			require.Empty(t, rw.Header().Get("Content-Encoding"), accept)
+			require.Equal(t, `{}`, rw.Body.String(), accept)
+		}
+	})
+
+	t.Run("sends empty responses uncompressed", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodDelete, "http://example.com/docs/1", http.NoBody)
+		req.Header.Set("Accept-Encoding", "gzip, deflate")
+
+		gzipmw.New(gzipmw.DefaultMaxDecompressedSize)(&echoHandler{status: http.StatusNoContent}).ServeHTTP(rw, req)
+
+		require.Equal(t, http.StatusNoContent, rw.Code)
+		require.Empty(t, rw.Header().Get("Content-Encoding"))
+		require.Empty(t, rw.Body.Bytes())
+	})
+
+	t.Run("flushes compressed data", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "http://example.com/extract", strings.NewReader("line\n"))
+		req.Header.Set("Accept-Encoding", "gzip")
+
+		gzipmw.New(gzipmw.DefaultMaxDecompressedSize)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, err := io.Copy(w, r.Body)
+			require.NoError(t, err)
+
+			f, ok := w.(http.Flusher)
+			require.True(t, ok)
+
+			f.Flush()
+
+			require.True(t, rw.Flushed)
+			require.NotEmpty(t, rw.Body.Bytes())
+		})).ServeHTTP(rw, req)
+
+		require.Equal(t, "line\n", string(decompress(t, rw.Body.Bytes())))
+	})
+
+	t.Run("error if the body is not gzip", func(t *testing.T) {
+		h := &echoHandler{}
+
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "http://example.com/extract", strings.NewReader(`{}`))
+		req.Header.Set("Content-Encoding", "gzip")
+
+		gzipmw.New(gzipmw.DefaultMaxDecompressedSize)(h).ServeHTTP(rw, req)
+
+		require.Equal(t, http.StatusBadRequest, rw.Code)
+		require.Contains(t, rw.Body.String(), "invalid gzip body")
+		require.Nil(t, h.request)
+	})
+
+	t.Run("error if the decompressed body is too large", func(t *testing.T) {
+		h := &echoHandler{}
+
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "http://example.com/extract",
+			bytes.NewReader(compress(t, make([]byte, 1025))))
+		req.Header.Set("Content-Encoding", "gzip")
+
+		gzipmw.New(1024)(h).ServeHTTP(rw, req)
+
+		require.Equal(t, http.StatusInternalServerError, rw.Code)
+		require.EqualError(t, h.err, "http: request body too large")
+	})
+
+	t.Run("handler limits apply to the decompressed body", func(t *testing.T) {
+		var readErr error
+
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "http://example.com/extract",
+			bytes.NewReader(compress(t, make([]byte, 1025))))
+		req.Header.Set("Content-Encoding", "gzip")
+
+		gzipmw.New(gzipmw.DefaultMaxDecompressedSize)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, readErr = io.ReadAll(http.MaxBytesReader(w, r.Body, 1024))
+		})).ServeHTTP(rw, req)
+
+		require.EqualError(t, readErr, "http: request body too large")
+	})
+}
+
+type echoHandler struct {
+	status  int
+	request *http.Request
+	err     error
+}
+
+func (h *echoHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.request = r
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.err = err
+
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	if h.status != 0 {
+		w.WriteHeader(h.status)
+	}
+
+	w.Write(body) // nolint:errcheck,gosec
+}
+
+func compress(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	w := gzip.NewWriter(buf)
+
+	_, err := w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	return buf.Bytes()
+}
+
+func decompress(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	result, err := io.ReadAll(r)
+	require.NoError(t, err)
+
+	return result
+}
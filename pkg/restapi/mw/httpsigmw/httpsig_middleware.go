@@ -63,8 +63,8 @@ func New(cfg *Config) mux.MiddlewareFunc {
 }
 
 func (h *mwHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	signVerifier := httpsig.NewVerifier(&pubKeyResolver{
-		vdr: h.vdr,
+	signVerifier := httpsig.NewVerifier(&PublicKeyResolver{
+		VDR: h.vdr,
 	})
 
 	verified, subjectDID := signVerifier.VerifyRequest(r)
@@ -83,11 +83,13 @@ func (h *mwHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	h.next.ServeHTTP(w, r.WithContext(ctx))
 }
 
-type pubKeyResolver struct {
-	vdr vdrRegistry
+// PublicKeyResolver resolves the keys of HTTP signatures from the authentication methods of the signers' DIDs.
+type PublicKeyResolver struct {
+	VDR vdrRegistry
 }
 
-func (r *pubKeyResolver) Resolve(keyID string) (*verifier.PublicKey, error) {
+// Resolve returns the public key identified by the key ID, a DID URL.
+func (r *PublicKeyResolver) Resolve(keyID string) (*verifier.PublicKey, error) {
 	keyIDParts := strings.Split(keyID, "#")
 
 	if len(keyIDParts) != 2 { //nolint:gomnd
@@ -96,7 +98,7 @@ func (r *pubKeyResolver) Resolve(keyID string) (*verifier.PublicKey, error) {
 
 	subjectDID := keyIDParts[0]
 
-	docResolution, err := r.vdr.Resolve(subjectDID)
+	docResolution, err := r.VDR.Resolve(subjectDID)
 	if err != nil {
 		return nil, fmt.Errorf("resolve DID %s: %w", subjectDID, err)
 	}
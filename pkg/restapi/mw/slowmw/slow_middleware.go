@@ -0,0 +1,93 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package slowmw
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Logger logs the slow requests.
+type Logger interface {
+	Warnf(msg string, args ...interface{})
+}
+
+// New returns middleware that logs a warning for every request taking longer than threshold to handle, with its
+// route, status and elapsed time. Bodies are never logged. A zero threshold disables the middleware.
+func New(threshold time.Duration, logger Logger) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		if threshold <= 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			sw := &statusWriter{ResponseWriter: w}
+
+			next.ServeHTTP(sw, r)
+
+			elapsed := time.Since(start)
+			if elapsed <= threshold {
+				return
+			}
+
+			logger.Warnf("slow request: %s %s responded %d in %s (threshold %s)",
+				r.Method, route(r), sw.statusOrOK(), elapsed, threshold)
+		})
+	}
+}
+
+// route returns the path template of the matched route so that identifiers in paths are not logged, or the path
+// of the request if no route matched.
+func route(r *http.Request) string {
+	if current := mux.CurrentRoute(r); current != nil {
+		if tpl, err := current.GetPathTemplate(); err == nil {
+			return tpl
+		}
+	}
+
+	return r.URL.Path
+}
+
+// statusWriter records the status of the response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush sends the data written so far to the client, for streamed responses.
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *statusWriter) statusOrOK() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+
+	return w.status
+}
@@ -0,0 +1,95 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package slowmw_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/log/mocklogger"
+
+	"github.com/trustbloc/ace/pkg/restapi/mw/slowmw"
+)
+
+func TestMiddleware(t *testing.T) {
+	t.Run("logs nothing for fast requests", func(t *testing.T) {
+		logger := &mocklogger.MockLogger{}
+
+		rw := serve(t, slowmw.New(time.Minute, logger), &stubHandler{status: http.StatusCreated},
+			httptest.NewRequest(http.MethodPost, "http://example.com/vaults", strings.NewReader(`{}`)))
+
+		require.Equal(t, http.StatusCreated, rw.Code)
+		require.Empty(t, logger.AllLogContents)
+	})
+
+	t.Run("logs a warning for slow requests", func(t *testing.T) {
+		logger := &mocklogger.MockLogger{}
+
+		rw := serve(t, slowmw.New(time.Millisecond, logger),
+			&stubHandler{status: http.StatusBadGateway, delay: 10 * time.Millisecond},
+			httptest.NewRequest(http.MethodPost, "http://example.com/vaults/did:example:123/docs",
+				strings.NewReader(`{"secret":"value"}`)))
+
+		require.Equal(t, http.StatusBadGateway, rw.Code)
+		require.Contains(t, logger.WarnLogContents, "slow request: POST /vaults/{vaultID}/docs responded 502 in")
+		require.Contains(t, logger.WarnLogContents, "(threshold 1ms)")
+		require.NotContains(t, logger.AllLogContents, "did:example:123")
+		require.NotContains(t, logger.AllLogContents, "secret")
+	})
+
+	t.Run("responses without explicit status are OK", func(t *testing.T) {
+		logger := &mocklogger.MockLogger{}
+
+		serve(t, slowmw.New(time.Millisecond, logger), &stubHandler{delay: 10 * time.Millisecond},
+			httptest.NewRequest(http.MethodGet, "http://example.com/vaults", nil))
+
+		require.Contains(t, logger.WarnLogContents, "responded 200")
+	})
+
+	t.Run("zero threshold disables the middleware", func(t *testing.T) {
+		logger := &mocklogger.MockLogger{}
+
+		serve(t, slowmw.New(0, logger), &stubHandler{delay: 10 * time.Millisecond},
+			httptest.NewRequest(http.MethodGet, "http://example.com/vaults", nil))
+
+		require.Empty(t, logger.AllLogContents)
+	})
+}
+
+func serve(t *testing.T, mw mux.MiddlewareFunc, h http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	t.Helper()
+
+	router := mux.NewRouter()
+	router.Handle("/vaults", h)
+	router.Handle("/vaults/{vaultID}/docs", h)
+	router.Use(mw)
+
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, req)
+
+	return rw
+}
+
+type stubHandler struct {
+	status int
+	delay  time.Duration
+}
+
+func (h *stubHandler) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	time.Sleep(h.delay)
+
+	if h.status != 0 {
+		w.WriteHeader(h.status)
+	}
+
+	w.Write([]byte("ok")) // nolint:errcheck,gosec
+}
@@ -0,0 +1,407 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	edv "github.com/trustbloc/edv/pkg/client"
+	"github.com/trustbloc/edv/pkg/edvutils"
+	"github.com/trustbloc/edv/pkg/restapi/models"
+)
+
+// ErrInvalidArchive is returned by ImportVault when the archive does not start with the archived vault.
+var ErrInvalidArchive = errors.New("invalid vault archive")
+
+// ErrSourceKeystoreUnavailable is returned by ImportVault when the documents of the archive cannot be decrypted:
+// the exported vault is not held by this server, or its keystore was deleted.
+var ErrSourceKeystoreUnavailable = errors.New("the keystore of the exported vault is not available")
+
+// ArchiveEntry is an entry of a vault archive. Archives are streams of JSON entries: the first one holds the
+// archived vault and the following ones hold its documents, as encrypted in EDV.
+type ArchiveEntry struct {
+	Vault    *VaultInfo        `json:"vault,omitempty"`
+	Document *ArchivedDocument `json:"document,omitempty"`
+	// Error ends archives whose export failed after it started.
+	Error string `json:"error,omitempty"`
+}
+
+// ArchivedDocument is an encrypted document of the exported vault along with its metadata.
+type ArchivedDocument struct {
+	ID        string          `json:"docID"`
+	EDVID     string          `json:"edvDocID"`
+	EncKeyURI string          `json:"encKeyURI"`
+	Created   time.Time       `json:"created"`
+	Updated   time.Time       `json:"updated"`
+	Sequence  uint64          `json:"sequence"`
+	JWE       json.RawMessage `json:"jwe"`
+	// ContentDigest is omitted for documents saved without one.
+	ContentDigest string `json:"contentDigest,omitempty"`
+	// Chunks are the encrypted chunks of the document if it was saved in chunks, in order.
+	Chunks []*ArchivedChunk `json:"chunks,omitempty"`
+	// ControllerKeyID is the key agreement key of the controller the document is also encrypted to, if any.
+	ControllerKeyID string `json:"controllerKeyID,omitempty"`
+}
+
+// ArchivedChunk is an encrypted chunk of an archived document.
+type ArchivedChunk struct {
+	EDVID string          `json:"edvDocID"`
+	JWE   json.RawMessage `json:"jwe"`
+}
+
+// VaultExport reads the documents of an exported vault one at a time.
+type VaultExport struct {
+	Vault *VaultInfo
+
+	client     *Client
+	info       *vaultInfo
+	backend    *edvBackend
+	edvVaultID string
+	docs       []*metaDocInfo
+}
+
+// VaultImport reports the import of a vault archive.
+type VaultImport struct {
+	*CreatedVault
+	// SourceID is the ID of the exported vault.
+	SourceID string `json:"sourceID"`
+	Imported int    `json:"imported"`
+	// Failures lists the documents that were not imported.
+	Failures []*ImportFailure `json:"failures,omitempty"`
+}
+
+// ImportFailure reports a document that was not imported.
+type ImportFailure struct {
+	// DocID is empty if the entry of the document could not be read.
+	DocID string `json:"docID,omitempty"`
+	Error string `json:"error"`
+}
+
+// ExportVault exports the vault along with its documents. Documents are read from EDV as they are exported and
+// are never decrypted.
+func (c *Client) ExportVault(vaultID string) (*VaultExport, error) {
+	info, err := c.getVaultInfo(vaultID)
+	if err != nil {
+		return nil, fmt.Errorf("get vault info: %w", err)
+	}
+
+	backend, err := c.edvBackend(info)
+	if err != nil {
+		return nil, err
+	}
+
+	docs, err := c.queryMetaDocInfos(vaultID)
+	if err != nil {
+		return nil, fmt.Errorf("query meta doc infos: %w", err)
+	}
+
+	sort.Slice(docs, func(i, j int) bool { return docs[i].DocID < docs[j].DocID })
+
+	return &VaultExport{
+		Vault:      newVaultInfo(vaultID, info),
+		client:     c,
+		info:       info,
+		backend:    backend,
+		edvVaultID: lastElm(info.Auth.EDV.URI, "/"),
+		docs:       docs,
+	}, nil
+}
+
+// Next reads the next document from EDV. It returns io.EOF once all documents are read.
+func (e *VaultExport) Next() (*ArchivedDocument, error) {
+	if len(e.docs) == 0 {
+		return nil, io.EOF
+	}
+
+	d := e.docs[0]
+	e.docs = e.docs[1:]
+
+	encDoc, err := e.backend.client.ReadDocument(e.edvVaultID, d.EdvID, edv.WithRequestHeader(
+		e.client.edvSign(e.info.DidURL, e.info.Auth.EDV)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("read document %s: %w", d.DocID, err)
+	}
+
+	var chunks []*ArchivedChunk
+
+	for _, chunkID := range d.Chunks {
+		encChunk, err := e.backend.client.ReadDocument(e.edvVaultID, chunkID, edv.WithRequestHeader(
+			e.client.edvSign(e.info.DidURL, e.info.Auth.EDV)),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("read chunk %s of document %s: %w", chunkID, d.DocID, err)
+		}
+
+		chunks = append(chunks, &ArchivedChunk{EDVID: chunkID, JWE: json.RawMessage(encChunk.JWE)})
+	}
+
+	return &ArchivedDocument{
+		ID:              d.DocID,
+		EDVID:           d.EdvID,
+		EncKeyURI:       d.KidURL,
+		Created:         d.Created,
+		Updated:         d.Updated,
+		Sequence:        d.Sequence,
+		JWE:             json.RawMessage(encDoc.JWE),
+		ContentDigest:   d.ContentDigest,
+		Chunks:          chunks,
+		ControllerKeyID: d.ControllerKeyID,
+	}, nil
+}
+
+// ImportVault creates a new vault holding the documents of the archive. The documents are decrypted with the
+// keystore of the exported vault, which must be held by this server, and re-encrypted to new keys of the keystore of
+// the new vault. Documents that cannot be imported are reported in the VaultImport and do not fail the import.
+func (c *Client) ImportVault(archive io.Reader) (*VaultImport, error) { // nolint:funlen
+	dec := json.NewDecoder(archive)
+
+	var header ArchiveEntry
+
+	err := dec.Decode(&header)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidArchive, err)
+	}
+
+	if header.Vault == nil {
+		return nil, fmt.Errorf("%w: the first entry must be the vault", ErrInvalidArchive)
+	}
+
+	source, err := c.getVaultInfo(header.Vault.ID)
+	if errors.Is(err, storage.ErrDataNotFound) {
+		return nil, fmt.Errorf("%w: unknown vault %s", ErrSourceKeystoreUnavailable, header.Vault.ID)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("get vault info: %w", err)
+	}
+
+	if source.KMSDeleted {
+		return nil, fmt.Errorf("%w: vault %s was deleted", ErrSourceKeystoreUnavailable, header.Vault.ID)
+	}
+
+	created, err := c.CreateVault()
+	if err != nil {
+		return nil, fmt.Errorf("create vault: %w", err)
+	}
+
+	result := &VaultImport{CreatedVault: created, SourceID: header.Vault.ID}
+
+	info, err := c.getVaultInfo(created.ID)
+	if err != nil {
+		return nil, fmt.Errorf("get vault info: %w", err)
+	}
+
+	imported := make(map[string]bool)
+
+	for {
+		var entry ArchiveEntry
+
+		err = dec.Decode(&entry)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			// the rest of the archive cannot be read
+			result.Failures = append(result.Failures, &ImportFailure{Error: fmt.Sprintf("read entry: %s", err)})
+
+			break
+		}
+
+		if entry.Error != "" {
+			result.Failures = append(result.Failures, &ImportFailure{Error: "incomplete archive: " + entry.Error})
+
+			break
+		}
+
+		if entry.Document == nil {
+			result.Failures = append(result.Failures, &ImportFailure{Error: "entry is not a document"})
+
+			continue
+		}
+
+		err = c.importDoc(created.ID, info, source, entry.Document, imported)
+		if err != nil {
+			result.Failures = append(result.Failures, &ImportFailure{DocID: entry.Document.ID, Error: err.Error()})
+
+			continue
+		}
+
+		imported[entry.Document.ID] = true
+		result.Imported++
+	}
+
+	err = c.addDocCount(created.ID, result.Imported)
+	if err != nil {
+		return nil, fmt.Errorf("update doc count: %w", err)
+	}
+
+	return result, nil
+}
+
+// importDoc decrypts the archived document with the keystore of the exported vault and stores it encrypted to a new
+// key of the keystore of the vault. The controller of the vault is another one than the controller of the exported
+// vault: documents are no longer encrypted to the controller.
+func (c *Client) importDoc(vaultID string, info, source *vaultInfo, doc *ArchivedDocument, // nolint:funlen
+	imported map[string]bool,
+) error {
+	if strings.TrimSpace(doc.ID) == "" {
+		return errors.New("missing document ID")
+	}
+
+	if imported[doc.ID] {
+		return errors.New("duplicate document ID")
+	}
+
+	err := edvutils.CheckIfBase58Encoded128BitValue(doc.EDVID)
+	if err != nil {
+		return fmt.Errorf("EDV document ID is not EDV-compatible: %w", err)
+	}
+
+	if len(doc.JWE) == 0 || string(doc.JWE) == "null" {
+		return errors.New("missing JWE")
+	}
+
+	plain, err := decryptDocument(c.webKMS(source), c.webCrypto(source), doc.JWE)
+	if err != nil {
+		return fmt.Errorf("decrypt document: %w", err)
+	}
+
+	manifestChunks, chunked, err := docChunks(plain)
+	if err != nil {
+		return fmt.Errorf("read document: %w", err)
+	}
+
+	if len(manifestChunks) != len(doc.Chunks) {
+		return fmt.Errorf("the archive has %d chunks of the document, not %d", len(doc.Chunks), len(manifestChunks))
+	}
+
+	backend, err := c.edvBackend(info)
+	if err != nil {
+		return err
+	}
+
+	kidURL, err := newDocKey(c.webKMS(info), info.keyType())
+	if err != nil {
+		return fmt.Errorf("create key: %w", err)
+	}
+
+	keyURI := c.buildKMSURL(info, kidURL)
+
+	chunks, err := c.importChunks(info, source, backend, keyURI, doc.Chunks)
+	if err != nil {
+		return err
+	}
+
+	if chunked {
+		plain.Meta[chunksField] = chunks
+	}
+
+	encContent, err := encryptToKey(c.webKMS(info), c.webCrypto(info), keyURI, plain)
+	if err != nil {
+		c.deleteStaleChunks(info, backend, chunks)
+
+		return fmt.Errorf("encrypt document: %w", err)
+	}
+
+	_, err = backend.client.CreateDocument(lastElm(info.Auth.EDV.URI, "/"), &models.EncryptedDocument{
+		ID:       doc.EDVID,
+		Sequence: doc.Sequence,
+		JWE:      []byte(encContent),
+	}, edv.WithRequestHeader(c.edvSign(info.DidURL, info.Auth.EDV)))
+	if err != nil {
+		c.deleteStaleChunks(info, backend, chunks)
+
+		return fmt.Errorf("create document: %w", err)
+	}
+
+	dInfo := &metaDocInfo{
+		EdvID:    doc.EDVID,
+		KidURL:   keyURI,
+		DocID:    doc.ID,
+		Created:  doc.Created,
+		Updated:  doc.Updated,
+		Sequence: doc.Sequence,
+		Chunks:   chunks,
+	}
+
+	if !c.noContentDigests {
+		dInfo.ContentDigest = doc.ContentDigest
+	}
+
+	if dInfo.Created.IsZero() {
+		dInfo.Created = time.Now().UTC()
+		dInfo.Updated = dInfo.Created
+	}
+
+	err = c.saveMetaDocInfo(vaultID, doc.ID, dInfo)
+	if err != nil {
+		return fmt.Errorf("save meta doc info: %w", err)
+	}
+
+	return nil
+}
+
+// importChunks decrypts the chunks of an archived document with the keystore of the exported vault and stores them
+// encrypted to the key. It returns the EDV IDs of the stored chunks, in order.
+func (c *Client) importChunks(info, source *vaultInfo, backend *edvBackend, keyURI string,
+	chunks []*ArchivedChunk,
+) ([]string, error) {
+	var (
+		edvVaultID = lastElm(info.Auth.EDV.URI, "/")
+		imported   []string
+	)
+
+	for i, chunk := range chunks {
+		if len(chunk.JWE) == 0 || string(chunk.JWE) == "null" {
+			c.deleteStaleChunks(info, backend, imported)
+
+			return nil, fmt.Errorf("missing JWE of chunk %d", i)
+		}
+
+		plain, err := decryptDocument(c.webKMS(source), c.webCrypto(source), chunk.JWE)
+		if err != nil {
+			c.deleteStaleChunks(info, backend, imported)
+
+			return nil, fmt.Errorf("decrypt chunk %d: %w", i, err)
+		}
+
+		encoded, ok := plain.Content[dataField].(string)
+		if !ok {
+			c.deleteStaleChunks(info, backend, imported)
+
+			return nil, fmt.Errorf("chunk %d has no %s", i, dataField)
+		}
+
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			c.deleteStaleChunks(info, backend, imported)
+
+			return nil, fmt.Errorf("decode chunk %d: %w", i, err)
+		}
+
+		chunkID, err := c.storeChunk(info, backend, edvVaultID, keyURI, nil, decoded)
+		if err != nil {
+			c.deleteStaleChunks(info, backend, imported)
+
+			return nil, fmt.Errorf("store chunk %d: %w", i, err)
+		}
+
+		imported = append(imported, chunkID)
+	}
+
+	return imported, nil
+}
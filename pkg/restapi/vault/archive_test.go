@@ -0,0 +1,288 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/ace/pkg/internal/testutil"
+	"github.com/trustbloc/ace/pkg/restapi/vault"
+)
+
+func TestClient_ExportVault(t *testing.T) {
+	loader := testutil.DocumentLoader(t)
+
+	t.Run("Success", func(t *testing.T) {
+		client, vID := newRoundTripVaultClient(t, loader)
+
+		_, err := client.SaveDoc(vID, "doc2", []byte(`{"message":"Hello World!"}`))
+		require.NoError(t, err)
+
+		_, err = client.SaveBinaryDoc(vID, "doc1", "text/plain", []byte("Hello World!"))
+		require.NoError(t, err)
+
+		_, err = client.SaveBinaryDoc(vID, "doc1", "text/plain", []byte("Hello again!"))
+		require.NoError(t, err)
+
+		export, err := client.ExportVault(vID)
+		require.NoError(t, err)
+		require.Equal(t, vID, export.Vault.ID)
+		require.Equal(t, 2, export.Vault.DocCount)
+
+		for _, docID := range []string{"doc1", "doc2"} {
+			doc, err := export.Next()
+			require.NoError(t, err)
+			require.Equal(t, docID, doc.ID)
+			require.NotEmpty(t, doc.EDVID)
+			require.NotEmpty(t, doc.EncKeyURI)
+			require.NotZero(t, doc.Created)
+
+			// documents are exported as encrypted in EDV
+			require.NotContains(t, string(doc.JWE), "Hello")
+			require.Contains(t, string(doc.JWE), `"ciphertext"`)
+
+			meta, err := client.GetDocMetadata(vID, docID)
+			require.NoError(t, err)
+			require.Equal(t, meta.Sequence, doc.Sequence)
+		}
+
+		_, err = export.Next()
+		require.True(t, errors.Is(err, io.EOF))
+	})
+
+	t.Run("Vault not found", func(t *testing.T) {
+		client, _ := newRoundTripVaultClient(t, loader)
+
+		_, err := client.ExportVault("did:example:unknown")
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+	})
+}
+
+func TestClient_ImportVault(t *testing.T) {
+	loader := testutil.DocumentLoader(t)
+
+	t.Run("Success (export round trip)", func(t *testing.T) {
+		client, vID := newRoundTripVaultClient(t, loader, vault.WithChunkSize(testChunkSize))
+
+		_, err := client.SaveDoc(vID, "doc1", []byte(`{"message":"Hello World!"}`))
+		require.NoError(t, err)
+
+		_, err = client.SaveBinaryDoc(vID, "doc2", "application/pdf", []byte("%PDF-1.7"))
+		require.NoError(t, err)
+
+		scan := randomContent(t, 100)
+
+		_, err = client.SaveBinaryDocStream(vID, "scan", "application/dicom", bytes.NewReader(scan))
+		require.NoError(t, err)
+
+		archive := exportArchive(t, client, vID)
+
+		result, err := client.ImportVault(bytes.NewReader(archive))
+		require.NoError(t, err)
+		require.NotEqual(t, vID, result.ID)
+		require.Equal(t, vID, result.SourceID)
+		require.NotEmpty(t, result.EDV.AuthToken)
+		require.NotEmpty(t, result.KMS.AuthToken)
+		require.Equal(t, 3, result.Imported)
+		require.Empty(t, result.Failures)
+
+		info, err := client.GetVaultInfo(result.ID)
+		require.NoError(t, err)
+		require.Equal(t, 3, info.DocCount)
+
+		list, err := client.ListDocs(result.ID, 0, "")
+		require.NoError(t, err)
+		require.Len(t, list.Documents, 3)
+
+		// the documents are re-encrypted to keys of the keystore of the new vault, which alone serves its keys
+		for _, docID := range []string{"doc1", "doc2", "scan"} {
+			source, err := client.GetDocMetadata(vID, docID)
+			require.NoError(t, err)
+
+			meta, err := client.GetDocMetadata(result.ID, docID)
+			require.NoError(t, err)
+			require.True(t, strings.HasPrefix(meta.EncKeyURI, info.KMSURI+"/keys/"), meta.EncKeyURI)
+			require.NotEqual(t, source.EncKeyURI, meta.EncKeyURI)
+			require.Equal(t, source.ContentDigest, meta.ContentDigest)
+		}
+
+		doc, err := client.GetDoc(result.ID, "doc1")
+		require.NoError(t, err)
+		require.JSONEq(t, `{"message":"Hello World!"}`, string(doc))
+
+		content, err := client.GetDocContent(result.ID, "doc2")
+		require.NoError(t, err)
+		require.Equal(t, "application/pdf", content.MediaType)
+		require.Equal(t, []byte("%PDF-1.7"), content.Data)
+
+		content, err = client.GetDocContent(result.ID, "scan")
+		require.NoError(t, err)
+		require.Equal(t, "application/dicom", content.MediaType)
+		require.Equal(t, scan, content.Data)
+	})
+
+	t.Run("Error if the keystore of the exported vault is not available", func(t *testing.T) {
+		client, vID := newRoundTripVaultClient(t, loader)
+
+		var entries []*vault.ArchiveEntry
+
+		require.NoError(t, decodeArchive(exportArchive(t, client, vID), &entries))
+
+		entries[0].Vault.ID = "did:example:unknown"
+
+		_, err := client.ImportVault(bytes.NewReader(encodeArchive(t, entries...)))
+		require.True(t, errors.Is(err, vault.ErrSourceKeystoreUnavailable))
+
+		archive := exportArchive(t, client, vID)
+
+		_, err = client.DeleteVault(vID)
+		require.NoError(t, err)
+
+		_, err = client.ImportVault(bytes.NewReader(archive))
+		require.True(t, errors.Is(err, vault.ErrSourceKeystoreUnavailable))
+	})
+
+	t.Run("Reports documents that cannot be imported", func(t *testing.T) {
+		client, vID := newRoundTripVaultClient(t, loader)
+
+		_, err := client.SaveDoc(vID, "doc1", []byte(`{"message":"Hello World!"}`))
+		require.NoError(t, err)
+
+		var entries []*vault.ArchiveEntry
+
+		require.NoError(t, decodeArchive(exportArchive(t, client, vID), &entries))
+		require.Len(t, entries, 2)
+
+		valid := entries[1].Document
+
+		invalidEDVID := *valid
+		invalidEDVID.ID = "doc2"
+		invalidEDVID.EDVID = "not-base58"
+
+		missingJWE := *valid
+		missingJWE.ID = "doc3"
+		missingJWE.JWE = nil
+
+		missingID := *valid
+		missingID.ID = " "
+
+		archive := encodeArchive(t,
+			entries[0],
+			&vault.ArchiveEntry{Document: valid},
+			&vault.ArchiveEntry{Document: valid},
+			&vault.ArchiveEntry{Document: &invalidEDVID},
+			&vault.ArchiveEntry{Document: &missingJWE},
+			&vault.ArchiveEntry{Document: &missingID},
+			&vault.ArchiveEntry{Vault: entries[0].Vault},
+			&vault.ArchiveEntry{Error: "read document doc4: EDV unavailable"},
+			&vault.ArchiveEntry{Document: valid},
+		)
+
+		result, err := client.ImportVault(bytes.NewReader(archive))
+		require.NoError(t, err)
+		require.Equal(t, 1, result.Imported)
+		require.Len(t, result.Failures, 6)
+
+		errs := map[string]string{}
+
+		for _, f := range result.Failures {
+			errs[f.DocID] += f.Error + ";"
+		}
+
+		require.Contains(t, errs["doc1"], "duplicate document ID")
+		require.Contains(t, errs["doc2"], "EDV document ID is not EDV-compatible")
+		require.Contains(t, errs["doc3"], "missing JWE")
+		require.Contains(t, errs[" "], "missing document ID")
+		require.Contains(t, errs[""], "entry is not a document")
+		require.Contains(t, errs[""], "incomplete archive: read document doc4: EDV unavailable")
+
+		info, err := client.GetVaultInfo(result.ID)
+		require.NoError(t, err)
+		require.Equal(t, 1, info.DocCount)
+	})
+
+	t.Run("Reports unreadable entries", func(t *testing.T) {
+		client, vID := newRoundTripVaultClient(t, loader)
+
+		archive := append(exportArchive(t, client, vID), []byte(`{"document":`)...)
+
+		result, err := client.ImportVault(bytes.NewReader(archive))
+		require.NoError(t, err)
+		require.Zero(t, result.Imported)
+		require.Len(t, result.Failures, 1)
+		require.Contains(t, result.Failures[0].Error, "read entry")
+	})
+
+	t.Run("Error if the archive does not start with the vault", func(t *testing.T) {
+		client, _ := newRoundTripVaultClient(t, loader)
+
+		for _, archive := range []string{"", "{", `{"document":{"docID":"doc1"}}`} {
+			_, err := client.ImportVault(strings.NewReader(archive))
+			require.True(t, errors.Is(err, vault.ErrInvalidArchive), archive)
+		}
+	})
+}
+
+func exportArchive(t *testing.T, client *vault.Client, vaultID string) []byte {
+	t.Helper()
+
+	export, err := client.ExportVault(vaultID)
+	require.NoError(t, err)
+
+	entries := []*vault.ArchiveEntry{{Vault: export.Vault}}
+
+	for {
+		doc, err := export.Next()
+		if errors.Is(err, io.EOF) {
+			return encodeArchive(t, entries...)
+		}
+
+		require.NoError(t, err)
+
+		entries = append(entries, &vault.ArchiveEntry{Document: doc})
+	}
+}
+
+func encodeArchive(t *testing.T, entries ...*vault.ArchiveEntry) []byte {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+
+	for _, entry := range entries {
+		require.NoError(t, enc.Encode(entry))
+	}
+
+	return buf.Bytes()
+}
+
+func decodeArchive(archive []byte, entries *[]*vault.ArchiveEntry) error {
+	dec := json.NewDecoder(bytes.NewReader(archive))
+
+	for {
+		var entry vault.ArchiveEntry
+
+		err := dec.Decode(&entry)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		*entries = append(*entries, &entry)
+	}
+}
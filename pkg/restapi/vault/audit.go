@@ -0,0 +1,203 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+)
+
+// Types of the audit events of a vault.
+const (
+	AuditAuthorizationCreated   = "AuthorizationCreated"
+	AuditAuthorizationRetrieved = "AuthorizationRetrieved"
+	AuditAuthorizationUsed      = "AuthorizationUsed"
+	AuditAuthorizationDeleted   = "AuthorizationDeleted"
+)
+
+const (
+	auditEventFormat = "audit_%s_%s"
+	vaultAuditTag    = "vault_audit"
+)
+
+// AuditEvent records that an authorization of a vault was granted, retrieved, used or deleted. It never contains
+// the content of documents nor the tokens of authorizations.
+type AuditEvent struct {
+	// ID sorts in the order the events were recorded in.
+	ID              string               `json:"id"`
+	Type            string               `json:"type"`
+	VaultID         string               `json:"vaultID"`
+	AuthorizationID string               `json:"authorizationID"`
+	RequestingParty string               `json:"requestingParty,omitempty"`
+	Scope           *AuthorizationsScope `json:"scope,omitempty"`
+	// Uses is the number of uses of the authorization, for AuditAuthorizationUsed events.
+	Uses      uint64    `json:"uses,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// AuditQuery filters the audit events of a vault.
+type AuditQuery struct {
+	// Since restricts the list to the events recorded at or after this time, if not zero.
+	Since time.Time
+	// Until restricts the list to the events recorded before this time, if not zero.
+	Until time.Time
+	// Limit is the maximum number of events returned. Defaults to 100, at most 1000.
+	Limit int
+	// Next is the continuation token of the page to return.
+	Next string
+}
+
+// AuditTrail is a page of the audit events of a vault, oldest first.
+type AuditTrail struct {
+	Events []*AuditEvent `json:"events"`
+	// Next is the continuation token of the next page, empty on the last page.
+	Next string `json:"next,omitempty"`
+}
+
+// recordAudit appends an event about the authorization to the audit trail of the vault. Events are never updated:
+// they outlive the authorizations they are about.
+func (c *Client) recordAudit(eventType, vaultID string, a *CreatedAuthorization) error {
+	now := time.Now().UTC()
+
+	event := &AuditEvent{
+		// the zero-padded timestamp orders the IDs
+		ID:              fmt.Sprintf("%020d-%s", now.UnixNano(), uuid.New().String()),
+		Type:            eventType,
+		VaultID:         vaultID,
+		AuthorizationID: a.ID,
+		RequestingParty: a.RequestingParty,
+		Scope:           a.Scope,
+		Timestamp:       now,
+	}
+
+	if eventType == AuditAuthorizationUsed {
+		event.Uses = a.Uses
+	}
+
+	src, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshal audit event: %w", err)
+	}
+
+	err = c.store.Put(fmt.Sprintf(auditEventFormat, vaultID, event.ID), src,
+		storage.Tag{Name: vaultAuditTag, Value: vaultIndex(vaultID)},
+	)
+	if err != nil {
+		return fmt.Errorf("put audit event: %w", err)
+	}
+
+	return nil
+}
+
+// GetAuditTrail returns a page of the audit events of the vault recorded in the period of the query, oldest first.
+// The page following the one returned is requested by passing its AuditTrail.Next token.
+func (c *Client) GetAuditTrail(vaultID string, query *AuditQuery) (*AuditTrail, error) {
+	_, err := c.getVaultInfo(vaultID)
+	if err != nil {
+		return nil, fmt.Errorf("get vault info: %w", err)
+	}
+
+	if query == nil {
+		query = &AuditQuery{}
+	}
+
+	limit := query.Limit
+
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+
+	if limit > maxListLimit {
+		limit = maxListLimit
+	}
+
+	after, err := base64.RawURLEncoding.DecodeString(query.Next)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidContinuationToken, err)
+	}
+
+	all, err := c.queryAuditEvents(vaultID)
+	if err != nil {
+		return nil, fmt.Errorf("query audit events: %w", err)
+	}
+
+	var events []*AuditEvent
+
+	for _, e := range all {
+		if !query.Since.IsZero() && e.Timestamp.Before(query.Since) {
+			continue
+		}
+
+		if !query.Until.IsZero() && !e.Timestamp.Before(query.Until) {
+			continue
+		}
+
+		events = append(events, e)
+	}
+
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+
+	start := sort.Search(len(events), func(i int) bool { return events[i].ID > string(after) })
+	events = events[start:]
+
+	trail := &AuditTrail{Events: []*AuditEvent{}}
+
+	if len(events) > limit {
+		events = events[:limit]
+		trail.Next = base64.RawURLEncoding.EncodeToString([]byte(events[limit-1].ID))
+	}
+
+	trail.Events = append(trail.Events, events...)
+
+	return trail, nil
+}
+
+func (c *Client) queryAuditEvents(vID string) ([]*AuditEvent, error) {
+	iter, err := c.store.Query(fmt.Sprintf("%s:%s", vaultAuditTag, vaultIndex(vID)))
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	defer func() {
+		if errClose := iter.Close(); errClose != nil {
+			logger.Errorf("failed to close iterator: %s", errClose)
+		}
+	}()
+
+	var events []*AuditEvent
+
+	for {
+		ok, err := iter.Next()
+		if err != nil {
+			return nil, fmt.Errorf("iterator next: %w", err)
+		}
+
+		if !ok {
+			return events, nil
+		}
+
+		src, err := iter.Value()
+		if err != nil {
+			return nil, fmt.Errorf("iterator value: %w", err)
+		}
+
+		var e *AuditEvent
+
+		err = json.Unmarshal(src, &e)
+		if err != nil {
+			return nil, fmt.Errorf("unmarshal: %w", err)
+		}
+
+		events = append(events, e)
+	}
+}
@@ -0,0 +1,133 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/ace/pkg/internal/testutil"
+	"github.com/trustbloc/ace/pkg/restapi/vault"
+)
+
+func TestClient_GetAuditTrail(t *testing.T) {
+	loader := testutil.DocumentLoader(t)
+
+	client, _ := newKeyTypeVaultClient(t, loader)
+
+	created, err := client.CreateVault()
+	require.NoError(t, err)
+
+	start := time.Now()
+
+	auth, err := client.CreateAuthorization(created.ID, "did:example:rp", &vault.AuthorizationsScope{
+		Target:  "doc",
+		Actions: []string{"read"},
+	})
+	require.NoError(t, err)
+
+	_, err = client.GetAuthorization(created.ID, auth.ID)
+	require.NoError(t, err)
+
+	_, err = client.UseAuthorization(created.ID, auth.ID)
+	require.NoError(t, err)
+
+	require.NoError(t, client.DeleteAuthorization(created.ID, auth.ID))
+
+	t.Run("Records the grants and uses of the authorizations", func(t *testing.T) {
+		trail, err := client.GetAuditTrail(created.ID, nil)
+		require.NoError(t, err)
+		require.Empty(t, trail.Next)
+		require.Len(t, trail.Events, 4)
+
+		for i, eventType := range []string{
+			vault.AuditAuthorizationCreated,
+			vault.AuditAuthorizationRetrieved,
+			vault.AuditAuthorizationUsed,
+			vault.AuditAuthorizationDeleted,
+		} {
+			event := trail.Events[i]
+
+			require.Equal(t, eventType, event.Type)
+			require.Equal(t, created.ID, event.VaultID)
+			require.Equal(t, auth.ID, event.AuthorizationID)
+			require.Equal(t, "did:example:rp", event.RequestingParty)
+			require.Equal(t, "doc", event.Scope.Target)
+			require.False(t, event.Timestamp.Before(start.Truncate(time.Second)))
+		}
+
+		require.Equal(t, uint64(1), trail.Events[2].Uses)
+
+		// the events do not disclose the tokens of the authorization
+		src, err := json.Marshal(trail)
+		require.NoError(t, err)
+		require.NotContains(t, string(src), auth.Tokens.EDV)
+		require.NotContains(t, string(src), auth.Tokens.KMS)
+	})
+
+	t.Run("Filters the events by time", func(t *testing.T) {
+		all, err := client.GetAuditTrail(created.ID, nil)
+		require.NoError(t, err)
+
+		trail, err := client.GetAuditTrail(created.ID, &vault.AuditQuery{Since: all.Events[2].Timestamp})
+		require.NoError(t, err)
+		require.Len(t, trail.Events, 2)
+		require.Equal(t, vault.AuditAuthorizationUsed, trail.Events[0].Type)
+
+		trail, err = client.GetAuditTrail(created.ID, &vault.AuditQuery{Until: all.Events[2].Timestamp})
+		require.NoError(t, err)
+		require.Len(t, trail.Events, 2)
+		require.Equal(t, vault.AuditAuthorizationRetrieved, trail.Events[1].Type)
+
+		trail, err = client.GetAuditTrail(created.ID, &vault.AuditQuery{Since: time.Now().Add(time.Hour)})
+		require.NoError(t, err)
+		require.Empty(t, trail.Events)
+	})
+
+	t.Run("Pages through the events", func(t *testing.T) {
+		var types []string
+
+		query := &vault.AuditQuery{Limit: 3}
+
+		for {
+			trail, err := client.GetAuditTrail(created.ID, query)
+			require.NoError(t, err)
+
+			for _, event := range trail.Events {
+				types = append(types, event.Type)
+			}
+
+			if trail.Next == "" {
+				break
+			}
+
+			query.Next = trail.Next
+		}
+
+		require.Equal(t, []string{
+			vault.AuditAuthorizationCreated,
+			vault.AuditAuthorizationRetrieved,
+			vault.AuditAuthorizationUsed,
+			vault.AuditAuthorizationDeleted,
+		}, types)
+	})
+
+	t.Run("Error if the continuation token is invalid", func(t *testing.T) {
+		_, err := client.GetAuditTrail(created.ID, &vault.AuditQuery{Next: "!"})
+		require.True(t, errors.Is(err, vault.ErrInvalidContinuationToken))
+	})
+
+	t.Run("Error if the vault does not exist", func(t *testing.T) {
+		_, err := client.GetAuditTrail("did:example:unknown", nil)
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+	})
+}
@@ -0,0 +1,200 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	edv "github.com/trustbloc/edv/pkg/client"
+)
+
+// DefaultEDVBackend is the name of the EDV backend at the URL given to NewClient. Vaults created before backends
+// were named are stored in it.
+const DefaultEDVBackend = "default"
+
+// ErrUnknownEDVBackend is returned when a vault is created in, or is recorded in, an EDV backend the client does
+// not know.
+var ErrUnknownEDVBackend = errors.New("unknown EDV backend")
+
+// ErrDocumentNotFound is returned when the EDV server does not find a document, or the EDV vault it is in.
+var ErrDocumentNotFound = errors.New("document not found")
+
+// ErrVaultNotFound is returned when the EDV server does not find the EDV vault of a vault.
+var ErrVaultNotFound = errors.New("vault not found")
+
+// errDuplicateDocument is returned when the EDV server already has a document with the ID of the document to create.
+var errDuplicateDocument = errors.New("duplicate EDV document")
+
+// edvBackend is a named EDV server vaults are created in.
+type edvBackend struct {
+	name   string
+	url    string
+	scheme string
+	host   string
+	client *edv.Client
+}
+
+// CreateVaultOpt represents an option of CreateVault and CreateVaultWithController.
+type CreateVaultOpt func(*createVaultOpts)
+
+type createVaultOpts struct {
+	edvBackend  string
+	keyType     kms.KeyType
+	referenceID string
+	kmsURL      string
+	owner       *http.Request
+}
+
+// WithEDVBackends registers EDV backends in addition to DefaultEDVBackend, a map of names to base URLs, and selects
+// the backend new vaults are created in. Existing vaults keep using the backend they were created in.
+func WithEDVBackends(backends map[string]string, defaultBackend string) Opt {
+	return func(vault *Client) {
+		for name, edvURL := range backends {
+			vault.edvURLs[name] = edvURL
+		}
+
+		vault.defaultEDVBackend = defaultBackend
+	}
+}
+
+// WithEDVBackend creates the vault in the named EDV backend instead of the default one.
+func WithEDVBackend(name string) CreateVaultOpt {
+	return func(opts *createVaultOpts) {
+		opts.edvBackend = name
+	}
+}
+
+// initEDVBackends creates the EDV clients of the backends.
+func (c *Client) initEDVBackends() error {
+	c.edvBackends = make(map[string]*edvBackend, len(c.edvURLs))
+
+	for name, edvURL := range c.edvURLs {
+		u, err := url.Parse(edvURL)
+		if err != nil {
+			return fmt.Errorf("url parse: %w", err)
+		}
+
+		c.edvBackends[name] = &edvBackend{
+			name:   name,
+			url:    edvURL,
+			scheme: u.Scheme,
+			host:   u.Host,
+			client: edv.New(edvURL, edv.WithHTTPClient(&statusHTTPClient{
+				base:     &upstreamAuthHTTPClient{base: c.httpClient, upstream: UpstreamEDV},
+				basePath: u.Path,
+			})),
+		}
+	}
+
+	if _, ok := c.edvBackends[c.defaultEDVBackend]; !ok {
+		return fmt.Errorf("default EDV backend: %w: %s", ErrUnknownEDVBackend, c.defaultEDVBackend)
+	}
+
+	return nil
+}
+
+// newVaultEDVBackend returns the EDV backend to create a vault in.
+func (c *Client) newVaultEDVBackend(opts []CreateVaultOpt) (*edvBackend, error) {
+	options := &createVaultOpts{edvBackend: c.defaultEDVBackend}
+
+	for _, fn := range opts {
+		fn(options)
+	}
+
+	backend, ok := c.edvBackends[options.edvBackend]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEDVBackend, options.edvBackend)
+	}
+
+	return backend, nil
+}
+
+// edvBackend returns the EDV backend the vault was created in. Fails with ErrExternallyControlled for the vaults
+// controlled by an existing DID.
+func (c *Client) edvBackend(info *vaultInfo) (*edvBackend, error) {
+	if info.External {
+		return nil, fmt.Errorf("%w: %s", ErrExternallyControlled, info.controller())
+	}
+
+	name := info.EDVBackend
+	if name == "" {
+		name = DefaultEDVBackend
+	}
+
+	backend, ok := c.edvBackends[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is not configured", ErrUnknownEDVBackend, name)
+	}
+
+	return backend, nil
+}
+
+// docURI returns the URI of the document in the EDV vault.
+func (b *edvBackend) docURI(edvVaultID, edvDocID string) string {
+	return buildEDVDocURI(b.scheme, b.host, edvVaultID, edvDocID)
+}
+
+// statusHTTPClient turns the 404 responses of an EDV server into errors wrapping ErrDocumentNotFound or
+// ErrVaultNotFound, and its 409 responses to document creations into errors wrapping errDuplicateDocument, which the
+// EDV client passes on, since the messages of its errors are not stable.
+type statusHTTPClient struct {
+	base     edv.HTTPClient
+	basePath string
+}
+
+func (c *statusHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	resp, err := c.base.Do(req)
+	if err != nil {
+		return resp, err
+	}
+
+	// the data vault creation endpoint is the only one not under a vault
+	path := strings.Trim(strings.TrimPrefix(req.URL.Path, c.basePath), "/")
+	if path == "" {
+		return resp, nil
+	}
+
+	segments := strings.Split(path, "/")
+
+	var sentinel error
+
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		sentinel = ErrVaultNotFound
+		if len(segments) > 1 && segments[len(segments)-2] == "documents" {
+			sentinel = ErrDocumentNotFound
+		}
+	case http.StatusConflict:
+		if req.Method == http.MethodPost && segments[len(segments)-1] == "documents" {
+			sentinel = errDuplicateDocument
+		}
+	}
+
+	if sentinel == nil {
+		return resp, nil
+	}
+
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil {
+			logger.Errorf("failed to close response body: %s", errClose)
+		}
+	}()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response body: %w", err)
+	}
+
+	return nil, fmt.Errorf("%w: the EDV server returned status code %d along with the following message: %s",
+		sentinel, resp.StatusCode, body)
+}
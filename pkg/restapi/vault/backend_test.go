@@ -0,0 +1,134 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/ace/pkg/internal/testutil"
+	"github.com/trustbloc/ace/pkg/restapi/vault"
+)
+
+func TestClient_EDVBackends(t *testing.T) {
+	loader := testutil.DocumentLoader(t)
+
+	newEDVServer := func(t *testing.T) (*httptest.Server, *int32) {
+		t.Helper()
+
+		var (
+			requests int32
+			handler  = newEDVHandler(t)
+		)
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+
+			handler(w, r)
+		}))
+		t.Cleanup(srv.Close)
+
+		return srv, &requests
+	}
+
+	t.Run("Routes vaults to the backend they were created in", func(t *testing.T) {
+		newEDV, newRequests := newEDVServer(t)
+
+		client, legacyID := newRoundTripVaultClient(t, loader,
+			vault.WithEDVBackends(map[string]string{"new": newEDV.URL}, "new"))
+
+		created, err := client.CreateVault()
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(created.EDV.URI, newEDV.URL+"/"), created.EDV.URI)
+
+		info, err := client.GetVaultInfo(created.ID)
+		require.NoError(t, err)
+		require.Equal(t, "new", info.EDVBackend)
+
+		docMeta, err := client.SaveDoc(created.ID, "doc1", []byte(`{"message":"Hello World!"}`))
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(docMeta.URI, newEDV.URL+"/"), docMeta.URI)
+
+		content, err := client.GetDoc(created.ID, "doc1")
+		require.NoError(t, err)
+		require.JSONEq(t, `{"message":"Hello World!"}`, string(content))
+
+		// vaults created before backends were named stay in the default backend
+		before := atomic.LoadInt32(newRequests)
+
+		info, err = client.GetVaultInfo(legacyID)
+		require.NoError(t, err)
+		require.Equal(t, vault.DefaultEDVBackend, info.EDVBackend)
+
+		docMeta, err = client.SaveDoc(legacyID, "doc1", []byte(`{"message":"Hello World!"}`))
+		require.NoError(t, err)
+		require.False(t, strings.HasPrefix(docMeta.URI, newEDV.URL+"/"), docMeta.URI)
+
+		_, err = client.GetDocMetadata(legacyID, "doc1")
+		require.NoError(t, err)
+		require.Equal(t, before, atomic.LoadInt32(newRequests))
+	})
+
+	t.Run("Pins the backend of a new vault", func(t *testing.T) {
+		newEDV, _ := newEDVServer(t)
+
+		client, _ := newRoundTripVaultClient(t, loader, vault.WithEDVBackends(map[string]string{"new": newEDV.URL},
+			vault.DefaultEDVBackend))
+
+		created, err := client.CreateVault(vault.WithEDVBackend("new"))
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(created.EDV.URI, newEDV.URL+"/"), created.EDV.URI)
+
+		created, err = client.CreateVault()
+		require.NoError(t, err)
+		require.False(t, strings.HasPrefix(created.EDV.URI, newEDV.URL+"/"), created.EDV.URI)
+
+		_, err = client.CreateVault(vault.WithEDVBackend("unknown"))
+		require.True(t, errors.Is(err, vault.ErrUnknownEDVBackend))
+	})
+
+	t.Run("Unknown backend in the vault record", func(t *testing.T) {
+		provider := mem.NewProvider()
+
+		client, err := vault.NewClient("", "https://edv.example.com", nil, provider, loader)
+		require.NoError(t, err)
+
+		store, err := provider.OpenStore("vault")
+		require.NoError(t, err)
+
+		require.NoError(t, store.Put("info_did:example:123", []byte(`{"did_url":"did:example:123#key-1",`+
+			`"auth":{"edv":{"uri":"/encrypted-data-vaults/DWPPbEVn1afJY4We3kpQmq"},`+
+			`"kms":{"uri":"/v1/keystores/c0ekinlioud42c84qs7g"}},"edv_backend":"retired"}`)))
+
+		_, err = client.SaveDoc("did:example:123", "doc1", []byte(`{"message":"Hello World!"}`))
+		require.True(t, errors.Is(err, vault.ErrUnknownEDVBackend))
+		require.Contains(t, err.Error(), "retired is not configured")
+
+		_, err = client.GetDoc("did:example:123", "doc1")
+		require.True(t, errors.Is(err, vault.ErrUnknownEDVBackend))
+
+		_, err = client.ListDocs("did:example:123", 0, "")
+		require.True(t, errors.Is(err, vault.ErrUnknownEDVBackend))
+
+		info, err := client.GetVaultInfo("did:example:123")
+		require.NoError(t, err)
+		require.Equal(t, "retired", info.EDVBackend)
+	})
+
+	t.Run("Unknown default backend", func(t *testing.T) {
+		_, err := vault.NewClient("", "", nil, mem.NewProvider(), loader,
+			vault.WithEDVBackends(map[string]string{"new": "https://edv.example.com"}, "other"))
+		require.True(t, errors.Is(err, vault.ErrUnknownEDVBackend))
+	})
+}
@@ -0,0 +1,270 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/verifier"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+)
+
+// DefaultChallengeTTL is how long an authorization challenge can be answered unless configured otherwise.
+const DefaultChallengeTTL = 5 * time.Minute
+
+const (
+	challengeFormat            = "authorization_challenge_%s"
+	authorizationChallengesTag = "authorization_challenges"
+	challengeSize              = 32
+)
+
+// ErrInvalidKeyProof is returned when an authorization is created with a proof of key control which is missing
+// while required, or does not prove that the requesting party controls a key of its DID.
+var ErrInvalidKeyProof = errors.New("invalid proof of key control")
+
+// WithRequiredKeyProof makes CreateAuthorization require a proof, see WithKeyProof, that the requesting party
+// controls a key of its DID. Without it, proofs are verified if given.
+func WithRequiredKeyProof() Opt {
+	return func(vault *Client) {
+		vault.requireKeyProof = true
+	}
+}
+
+// WithChallengeTTL sets how long authorization challenges can be answered, DefaultChallengeTTL by default.
+func WithChallengeTTL(ttl time.Duration) Opt {
+	return func(vault *Client) {
+		vault.challengeTTL = ttl
+	}
+}
+
+// AuthorizationChallenge is a nonce to sign with a key of the requesting party of an authorization, to prove that
+// it controls its DID. It can be answered once, before it expires.
+type AuthorizationChallenge struct {
+	Challenge string    `json:"challenge"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// KeyProof proves that the requesting party of an authorization controls a key of its DID.
+type KeyProof struct {
+	// Challenge is the challenge issued for the vault by CreateAuthorizationChallenge.
+	Challenge string `json:"challenge"`
+	// VerificationMethod is the DID URL of the key, in the DID document of the requesting party.
+	VerificationMethod string `json:"verificationMethod"`
+	// Signature is the base64url-encoded signature of the challenge with the key.
+	Signature string `json:"signature"`
+}
+
+// CreateAuthorizationOpt represents an option of CreateAuthorization.
+type CreateAuthorizationOpt func(*createAuthorizationOpts)
+
+type createAuthorizationOpts struct {
+	keyProof *KeyProof
+}
+
+// WithKeyProof proves that the requesting party controls a key of its DID.
+func WithKeyProof(proof *KeyProof) CreateAuthorizationOpt {
+	return func(opts *createAuthorizationOpts) {
+		opts.keyProof = proof
+	}
+}
+
+type challengeRecord struct {
+	VaultID   string    `json:"vaultID"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// CreateAuthorizationChallenge issues a challenge for the requesting party of an authorization of the vault to sign.
+func (c *Client) CreateAuthorizationChallenge(vaultID string) (*AuthorizationChallenge, error) {
+	if _, err := c.getVaultInfo(vaultID); err != nil {
+		return nil, fmt.Errorf("get vault info: %w", err)
+	}
+
+	// expired challenges are swept here rather than when answered, as most are never answered twice
+	c.deleteExpiredChallenges()
+
+	nonce := make([]byte, challengeSize)
+
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generate challenge: %w", err)
+	}
+
+	challenge := &AuthorizationChallenge{
+		Challenge: base64.RawURLEncoding.EncodeToString(nonce),
+		ExpiresAt: time.Now().Add(c.challengeTTL).UTC(),
+	}
+
+	src, err := json.Marshal(&challengeRecord{VaultID: vaultID, ExpiresAt: challenge.ExpiresAt})
+	if err != nil {
+		return nil, fmt.Errorf("marshal challenge: %w", err)
+	}
+
+	err = c.store.Put(fmt.Sprintf(challengeFormat, challenge.Challenge), src,
+		storage.Tag{Name: authorizationChallengesTag, Value: vaultIndex(vaultID)},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("put challenge: %w", err)
+	}
+
+	return challenge, nil
+}
+
+// verifyKeyProof checks that the proof answers a challenge of the vault with a key of the requesting party. The
+// challenge cannot be answered again.
+func (c *Client) verifyKeyProof(vaultID, requestingParty string, proof *KeyProof) error {
+	if proof == nil {
+		return fmt.Errorf("%w: missing", ErrInvalidKeyProof)
+	}
+
+	if err := c.consumeChallenge(vaultID, proof.Challenge); err != nil {
+		return err
+	}
+
+	did, fragment, ok := strings.Cut(proof.VerificationMethod, "#")
+	if !ok || fragment == "" {
+		return fmt.Errorf("%w: verification method %s is not a key", ErrInvalidKeyProof, proof.VerificationMethod)
+	}
+
+	if did != strings.Split(requestingParty, "#")[0] {
+		return fmt.Errorf("%w: verification method %s is not of the requesting party", ErrInvalidKeyProof,
+			proof.VerificationMethod)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(proof.Signature)
+	if err != nil {
+		return fmt.Errorf("%w: decode signature: %s", ErrInvalidKeyProof, err)
+	}
+
+	pubKey, err := verifiable.NewVDRKeyResolver(c.registry).PublicKeyFetcher()(did, "#"+fragment)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidKeyProof, err)
+	}
+
+	err = keyProofVerifier(pubKey).Verify(pubKey, []byte(proof.Challenge), signature)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidKeyProof, err)
+	}
+
+	return nil
+}
+
+// keyProofVerifier returns the verifier of signatures with the key: keys without a JWK are Ed25519 keys.
+func keyProofVerifier(pubKey *verifier.PublicKey) *verifier.PublicKeyVerifier {
+	if pubKey.JWK == nil {
+		return verifier.NewPublicKeyVerifier(verifier.NewEd25519SignatureVerifier())
+	}
+
+	return verifier.NewCompositePublicKeyVerifier([]verifier.SignatureVerifier{
+		verifier.NewEd25519SignatureVerifier(),
+		verifier.NewECDSAES256SignatureVerifier(),
+		verifier.NewECDSAES384SignatureVerifier(),
+		verifier.NewECDSAES521SignatureVerifier(),
+		verifier.NewECDSASecp256k1SignatureVerifier(),
+	})
+}
+
+// consumeChallenge deletes the challenge, checking that it was issued for the vault and has not expired.
+func (c *Client) consumeChallenge(vaultID, challenge string) error {
+	key := fmt.Sprintf(challengeFormat, challenge)
+
+	src, err := c.store.Get(key)
+	if errors.Is(err, storage.ErrDataNotFound) {
+		return fmt.Errorf("%w: unknown challenge", ErrInvalidKeyProof)
+	}
+
+	if err != nil {
+		return fmt.Errorf("get challenge: %w", err)
+	}
+
+	err = c.store.Delete(key)
+	if err != nil {
+		return fmt.Errorf("delete challenge: %w", err)
+	}
+
+	var record challengeRecord
+
+	if err = json.Unmarshal(src, &record); err != nil {
+		return fmt.Errorf("unmarshal challenge: %w", err)
+	}
+
+	if record.VaultID != vaultID {
+		return fmt.Errorf("%w: challenge not issued for the vault", ErrInvalidKeyProof)
+	}
+
+	if !time.Now().Before(record.ExpiresAt) {
+		return fmt.Errorf("%w: challenge expired", ErrInvalidKeyProof)
+	}
+
+	return nil
+}
+
+// deleteExpiredChallenges deletes the challenges which expired unanswered. Failures are only logged: they are
+// retried with the next challenge.
+func (c *Client) deleteExpiredChallenges() {
+	iter, err := c.store.Query(authorizationChallengesTag)
+	if err != nil {
+		logger.Warnf("failed to query challenges: %s", err)
+
+		return
+	}
+
+	defer func() {
+		if errClose := iter.Close(); errClose != nil {
+			logger.Errorf("failed to close iterator: %s", errClose)
+		}
+	}()
+
+	var (
+		now     = time.Now()
+		expired []string
+	)
+
+	for {
+		ok, err := iter.Next()
+		if err != nil {
+			logger.Warnf("failed to iterate over challenges: %s", err)
+
+			break
+		}
+
+		if !ok {
+			break
+		}
+
+		key, err := iter.Key()
+		if err != nil {
+			logger.Warnf("failed to read challenge key: %s", err)
+
+			break
+		}
+
+		src, err := iter.Value()
+		if err != nil {
+			logger.Warnf("failed to read challenge %s: %s", key, err)
+
+			continue
+		}
+
+		var record challengeRecord
+
+		if err = json.Unmarshal(src, &record); err != nil || !now.Before(record.ExpiresAt) {
+			expired = append(expired, key)
+		}
+	}
+
+	for _, key := range expired {
+		if err := c.store.Delete(key); err != nil {
+			logger.Warnf("failed to delete expired challenge %s: %s", key, err)
+		}
+	}
+}
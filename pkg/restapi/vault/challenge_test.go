@@ -0,0 +1,205 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/vdr/fingerprint"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/ace/pkg/internal/testutil"
+	"github.com/trustbloc/ace/pkg/restapi/vault"
+)
+
+func TestClient_KeyProof(t *testing.T) {
+	loader := testutil.DocumentLoader(t)
+
+	scope := &vault.AuthorizationsScope{Target: "doc", Actions: []string{"read"}}
+
+	newVault := func(t *testing.T, opts ...vault.Opt) (*vault.Client, string) {
+		t.Helper()
+
+		client, _ := newKeyTypeVaultClient(t, loader, opts...)
+
+		created, err := client.CreateVault()
+		require.NoError(t, err)
+
+		return client, created.ID
+	}
+
+	t.Run("Creates the authorization of a requesting party proving key control", func(t *testing.T) {
+		client, vaultID := newVault(t, vault.WithRequiredKeyProof())
+		rp := newRequestingParty(t)
+
+		challenge, err := client.CreateAuthorizationChallenge(vaultID)
+		require.NoError(t, err)
+		require.NotEmpty(t, challenge.Challenge)
+		require.True(t, challenge.ExpiresAt.After(time.Now()))
+
+		proof := rp.prove(challenge.Challenge)
+
+		auth, err := client.CreateAuthorization(vaultID, rp.did, scope, vault.WithKeyProof(proof))
+		require.NoError(t, err)
+		require.Equal(t, rp.did, auth.RequestingParty)
+
+		_, err = client.CreateAuthorization(vaultID, rp.did, scope, vault.WithKeyProof(proof))
+		require.True(t, errors.Is(err, vault.ErrInvalidKeyProof))
+		require.Contains(t, err.Error(), "unknown challenge")
+	})
+
+	t.Run("Proofs are verified if given while not required", func(t *testing.T) {
+		client, vaultID := newVault(t)
+		rp := newRequestingParty(t)
+
+		_, err := client.CreateAuthorization(vaultID, rp.did, scope)
+		require.NoError(t, err)
+
+		_, err = client.CreateAuthorization(vaultID, rp.did, scope, vault.WithKeyProof(rp.prove("unknown")))
+		require.True(t, errors.Is(err, vault.ErrInvalidKeyProof))
+	})
+
+	t.Run("Error if the proof is missing", func(t *testing.T) {
+		client, vaultID := newVault(t, vault.WithRequiredKeyProof())
+
+		_, err := client.CreateAuthorization(vaultID, newRequestingParty(t).did, scope)
+		require.True(t, errors.Is(err, vault.ErrInvalidKeyProof))
+		require.Contains(t, err.Error(), "missing")
+	})
+
+	t.Run("Error if the proof is invalid", func(t *testing.T) {
+		client, vaultID := newVault(t, vault.WithRequiredKeyProof())
+		rp := newRequestingParty(t)
+		other := newRequestingParty(t)
+
+		for name, tc := range map[string]struct {
+			tamper func(proof *vault.KeyProof)
+			err    string
+		}{
+			"Signed by another key": {
+				tamper: func(proof *vault.KeyProof) {
+					proof.Signature = other.prove(proof.Challenge).Signature
+				},
+				err: "invalid signature",
+			},
+			"Key of another DID": {
+				tamper: func(proof *vault.KeyProof) {
+					*proof = *other.prove(proof.Challenge)
+				},
+				err: "not of the requesting party",
+			},
+			"Not a key": {
+				tamper: func(proof *vault.KeyProof) {
+					proof.VerificationMethod = rp.did
+				},
+				err: "is not a key",
+			},
+			"Key not in the DID document": {
+				tamper: func(proof *vault.KeyProof) {
+					proof.VerificationMethod = rp.did + "#unknown"
+				},
+				err: "not found",
+			},
+			"Signature not encoded": {
+				tamper: func(proof *vault.KeyProof) {
+					proof.Signature = "%"
+				},
+				err: "decode signature",
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				challenge, err := client.CreateAuthorizationChallenge(vaultID)
+				require.NoError(t, err)
+
+				proof := rp.prove(challenge.Challenge)
+				tc.tamper(proof)
+
+				_, err = client.CreateAuthorization(vaultID, rp.did, scope, vault.WithKeyProof(proof))
+				require.True(t, errors.Is(err, vault.ErrInvalidKeyProof))
+				require.Contains(t, err.Error(), tc.err)
+			})
+		}
+	})
+
+	t.Run("Error if the challenge was issued for another vault", func(t *testing.T) {
+		client, vaultID := newVault(t, vault.WithRequiredKeyProof())
+		rp := newRequestingParty(t)
+
+		other, err := client.CreateVault()
+		require.NoError(t, err)
+
+		challenge, err := client.CreateAuthorizationChallenge(other.ID)
+		require.NoError(t, err)
+
+		_, err = client.CreateAuthorization(vaultID, rp.did, scope, vault.WithKeyProof(rp.prove(challenge.Challenge)))
+		require.True(t, errors.Is(err, vault.ErrInvalidKeyProof))
+		require.Contains(t, err.Error(), "not issued for the vault")
+	})
+
+	t.Run("Challenges expire", func(t *testing.T) {
+		client, vaultID := newVault(t, vault.WithRequiredKeyProof(), vault.WithChallengeTTL(50*time.Millisecond))
+		rp := newRequestingParty(t)
+
+		expired, err := client.CreateAuthorizationChallenge(vaultID)
+		require.NoError(t, err)
+
+		swept, err := client.CreateAuthorizationChallenge(vaultID)
+		require.NoError(t, err)
+
+		time.Sleep(100 * time.Millisecond)
+
+		_, err = client.CreateAuthorization(vaultID, rp.did, scope, vault.WithKeyProof(rp.prove(expired.Challenge)))
+		require.True(t, errors.Is(err, vault.ErrInvalidKeyProof))
+		require.Contains(t, err.Error(), "challenge expired")
+
+		// issuing a challenge deletes the expired ones
+		_, err = client.CreateAuthorizationChallenge(vaultID)
+		require.NoError(t, err)
+
+		_, err = client.CreateAuthorization(vaultID, rp.did, scope, vault.WithKeyProof(rp.prove(swept.Challenge)))
+		require.True(t, errors.Is(err, vault.ErrInvalidKeyProof))
+		require.Contains(t, err.Error(), "unknown challenge")
+	})
+
+	t.Run("Error if the vault does not exist", func(t *testing.T) {
+		client, _ := newVault(t)
+
+		_, err := client.CreateAuthorizationChallenge("did:key:unknown")
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+	})
+}
+
+type requestingParty struct {
+	did        string
+	keyID      string
+	privateKey ed25519.PrivateKey
+}
+
+func newRequestingParty(t *testing.T) *requestingParty {
+	t.Helper()
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	did, keyID := fingerprint.CreateDIDKey(pub)
+
+	return &requestingParty{did: did, keyID: keyID, privateKey: priv}
+}
+
+func (rp *requestingParty) prove(challenge string) *vault.KeyProof {
+	return &vault.KeyProof{
+		Challenge:          challenge,
+		VerificationMethod: rp.keyID,
+		Signature:          base64.RawURLEncoding.EncodeToString(ed25519.Sign(rp.privateKey, []byte(challenge))),
+	}
+}
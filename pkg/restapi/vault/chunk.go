@@ -0,0 +1,410 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+
+	edv "github.com/trustbloc/edv/pkg/client"
+	"github.com/trustbloc/edv/pkg/edvutils"
+	"github.com/trustbloc/edv/pkg/restapi/models"
+)
+
+// DefaultChunkSize is the size, in bytes, of the chunks binary documents are split into unless configured otherwise.
+const DefaultChunkSize = 1 << 20
+
+// chunksField lists, in the metadata of the manifest of a chunked document, the EDV IDs of its chunks.
+const chunksField = "chunks"
+
+// WithChunkSize sets the size, in bytes, of the chunks binary documents are split into. Content larger than a chunk
+// is encrypted and stored one chunk at a time, each in its own EDV document, so that saving it never holds more
+// than a chunk in memory. Defaults to DefaultChunkSize.
+func WithChunkSize(size int) Opt {
+	return func(vault *Client) {
+		vault.chunkSize = size
+	}
+}
+
+// SaveBinaryDocStream saves content of any media type read from the reader. Content larger than the chunk size of
+// the client is read, encrypted and stored in EDV one chunk at a time, and listed by a manifest stored as the
+// document: GetDoc and GetDocContent reassemble it. Smaller content is saved as by SaveBinaryDoc.
+func (c *Client) SaveBinaryDocStream(vaultID, id, mediaType string, content io.Reader,
+	opts ...SaveDocOpt,
+) (*DocumentMetadata, error) {
+	info, err := c.getVaultInfo(vaultID)
+	if err != nil {
+		return nil, fmt.Errorf("get vault info: %w", err)
+	}
+
+	var (
+		r   = bufio.NewReader(content)
+		buf = make([]byte, c.chunkSize)
+	)
+
+	chunk, more, err := nextChunk(r, buf)
+	if err != nil {
+		return nil, err
+	}
+
+	if !more {
+		return c.saveDoc(vaultID, id, info, &models.StructuredDocument{
+			Meta:    map[string]interface{}{mediaTypeField: mediaType},
+			Content: map[string]interface{}{dataField: base64.StdEncoding.EncodeToString(chunk)},
+		}, opts)
+	}
+
+	return c.saveChunkedDoc(vaultID, id, info, mediaType, r, buf, opts)
+}
+
+// saveChunkedDoc stores the content, whose first chunk was read into buf, one chunk at a time. The chunks and the
+// manifest are encrypted to the same new key. The chunks are deleted if the document cannot be saved.
+func (c *Client) saveChunkedDoc(vaultID, id string, info *vaultInfo, mediaType string, r *bufio.Reader,
+	buf []byte, opts []SaveDocOpt,
+) (*DocumentMetadata, error) {
+	if info.Deleting {
+		return nil, ErrVaultDeleting
+	}
+
+	backend, err := c.edvBackend(info)
+	if err != nil {
+		return nil, err
+	}
+
+	controller, err := c.saveDocRecipient(vaultID, info, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	wKMS, wCrypto := c.webKMS(info), c.webCrypto(info)
+
+	kidURL, err := newDocKey(wKMS, info.keyType())
+	if err != nil {
+		return nil, fmt.Errorf("encrypt key: %w", err)
+	}
+
+	var digest *binaryDigest
+
+	if !c.noContentDigests {
+		digest, err = newBinaryDigest(mediaType)
+		if err != nil {
+			return nil, fmt.Errorf("content digest: %w", err)
+		}
+	}
+
+	chunks, err := c.storeChunks(info, backend, kidURL, controller, r, buf, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &models.StructuredDocument{
+		Meta:    map[string]interface{}{mediaTypeField: mediaType, chunksField: chunks},
+		Content: map[string]interface{}{},
+	}
+
+	manifest.ID, err = edvutils.GenerateEDVCompatibleID()
+	if err != nil {
+		c.deleteStaleChunks(info, backend, chunks)
+
+		return nil, fmt.Errorf("failed to generate an EDV document ID: %w", err)
+	}
+
+	encContent, err := encryptToKey(wKMS, wCrypto, kidURL, manifest, controller.publicKeys()...)
+	if err != nil {
+		c.deleteStaleChunks(info, backend, chunks)
+
+		return nil, fmt.Errorf("encrypt manifest: %w", err)
+	}
+
+	enc := &encryptedDoc{kidURL: kidURL, jwe: encContent, chunks: chunks, controllerKeyID: controller.id()}
+
+	if digest != nil {
+		enc.digest = digest.sum()
+	}
+
+	meta, err := c.storeDoc(vaultID, id, info, backend, enc, opts)
+	if err != nil {
+		c.deleteStaleChunks(info, backend, chunks)
+
+		return nil, err
+	}
+
+	return meta, nil
+}
+
+// storeChunks reads the rest of the content, encrypting and storing each chunk as soon as it is read. It returns
+// the EDV IDs of the chunks, in order. Those already stored are deleted if one cannot be.
+func (c *Client) storeChunks(info *vaultInfo, backend *edvBackend, kidURL string, controller *recipient,
+	r *bufio.Reader, buf []byte, digest *binaryDigest,
+) ([]string, error) {
+	var (
+		edvVaultID = lastElm(info.Auth.EDV.URI, "/")
+		chunks     []string
+		chunk      = buf
+		more       = true
+	)
+
+	for {
+		if digest != nil {
+			digest.write(chunk)
+		}
+
+		chunkID, err := c.storeChunk(info, backend, edvVaultID, kidURL, controller, chunk)
+		if err != nil {
+			c.deleteStaleChunks(info, backend, chunks)
+
+			return nil, fmt.Errorf("store chunk %d: %w", len(chunks), err)
+		}
+
+		chunks = append(chunks, chunkID)
+
+		if !more {
+			return chunks, nil
+		}
+
+		chunk, more, err = nextChunk(r, buf)
+		if err != nil {
+			c.deleteStaleChunks(info, backend, chunks)
+
+			return nil, err
+		}
+	}
+}
+
+func (c *Client) storeChunk(info *vaultInfo, backend *edvBackend, edvVaultID, kidURL string, controller *recipient,
+	chunk []byte,
+) (string, error) {
+	chunkID, err := edvutils.GenerateEDVCompatibleID()
+	if err != nil {
+		return "", fmt.Errorf("generate EDV compatible id: %w", err)
+	}
+
+	encContent, err := encryptToKey(c.webKMS(info), c.webCrypto(info), kidURL, &models.StructuredDocument{
+		ID:      chunkID,
+		Content: map[string]interface{}{dataField: base64.StdEncoding.EncodeToString(chunk)},
+	}, controller.publicKeys()...)
+	if err != nil {
+		return "", fmt.Errorf("encrypt: %w", err)
+	}
+
+	_, err = backend.client.CreateDocument(edvVaultID, &models.EncryptedDocument{
+		ID:  chunkID,
+		JWE: []byte(encContent),
+	}, edv.WithRequestHeader(c.edvSign(info.DidURL, info.Auth.EDV)))
+	if err != nil {
+		return "", fmt.Errorf("create document: %w", err)
+	}
+
+	return chunkID, nil
+}
+
+// nextChunk reads the next chunk of the content into buf. It reports whether more content follows.
+func nextChunk(r *bufio.Reader, buf []byte) ([]byte, bool, error) {
+	n, err := io.ReadFull(r, buf)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return buf[:n], false, nil
+	}
+
+	if err != nil {
+		return nil, false, fmt.Errorf("read content: %w", err)
+	}
+
+	_, err = r.Peek(1)
+	if errors.Is(err, io.EOF) {
+		return buf, false, nil
+	}
+
+	if err != nil {
+		return nil, false, fmt.Errorf("read content: %w", err)
+	}
+
+	return buf, true, nil
+}
+
+// docChunks returns the EDV IDs of the chunks of the document if it is the manifest of a chunked document.
+func docChunks(doc *models.StructuredDocument) ([]string, bool, error) {
+	value, ok := doc.Meta[chunksField]
+	if !ok {
+		return nil, false, nil
+	}
+
+	src, err := json.Marshal(value)
+	if err != nil {
+		return nil, false, fmt.Errorf("marshal %s: %w", chunksField, err)
+	}
+
+	var chunks []string
+
+	err = json.Unmarshal(src, &chunks)
+	if err != nil {
+		return nil, false, fmt.Errorf("unmarshal %s: %w", chunksField, err)
+	}
+
+	return chunks, true, nil
+}
+
+// readChunkedContent reassembles the content of the chunked document whose manifest is given.
+func (c *Client) readChunkedContent(info *vaultInfo, backend *edvBackend, manifest *models.StructuredDocument,
+	chunks []string,
+) (*DocumentContent, error) {
+	mediaType, ok := manifest.Meta[mediaTypeField].(string)
+	if !ok {
+		return nil, fmt.Errorf("chunked document has no %s", mediaTypeField)
+	}
+
+	var data bytes.Buffer
+
+	for i, chunkID := range chunks {
+		chunk, err := c.readChunk(info, backend, chunkID)
+		if err != nil {
+			return nil, fmt.Errorf("read chunk %d: %w", i, err)
+		}
+
+		encoded, ok := chunk.Content[dataField].(string)
+		if !ok {
+			return nil, fmt.Errorf("chunk %d has no %s", i, dataField)
+		}
+
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("decode chunk %d: %w", i, err)
+		}
+
+		data.Write(decoded)
+	}
+
+	return &DocumentContent{MediaType: mediaType, Data: data.Bytes()}, nil
+}
+
+func (c *Client) readChunk(info *vaultInfo, backend *edvBackend, chunkID string) (*models.StructuredDocument, error) {
+	encDoc, err := backend.client.ReadDocument(lastElm(info.Auth.EDV.URI, "/"), chunkID, edv.WithRequestHeader(
+		c.edvSign(info.DidURL, info.Auth.EDV)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("read document: %w", err)
+	}
+
+	doc, err := decryptDocument(c.webKMS(info), c.webCrypto(info), encDoc.JWE)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt document: %w", err)
+	}
+
+	return doc, nil
+}
+
+// rekeyChunks stores a copy of each chunk encrypted to the key. It returns the EDV IDs of the copies, in order.
+// The chunks themselves are left to the caller to delete.
+func (c *Client) rekeyChunks(info *vaultInfo, backend *edvBackend, chunks []string, keyURI string,
+	controller *recipient,
+) ([]string, error) {
+	var (
+		edvVaultID = lastElm(info.Auth.EDV.URI, "/")
+		rekeyed    []string
+	)
+
+	for i, chunkID := range chunks {
+		chunk, err := c.readChunk(info, backend, chunkID)
+		if err != nil {
+			c.deleteStaleChunks(info, backend, rekeyed)
+
+			return nil, fmt.Errorf("read chunk %d: %w", i, err)
+		}
+
+		encoded, ok := chunk.Content[dataField].(string)
+		if !ok {
+			c.deleteStaleChunks(info, backend, rekeyed)
+
+			return nil, fmt.Errorf("chunk %d has no %s", i, dataField)
+		}
+
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			c.deleteStaleChunks(info, backend, rekeyed)
+
+			return nil, fmt.Errorf("decode chunk %d: %w", i, err)
+		}
+
+		newID, err := c.storeChunk(info, backend, edvVaultID, keyURI, controller, decoded)
+		if err != nil {
+			c.deleteStaleChunks(info, backend, rekeyed)
+
+			return nil, fmt.Errorf("store chunk %d: %w", i, err)
+		}
+
+		rekeyed = append(rekeyed, newID)
+	}
+
+	return rekeyed, nil
+}
+
+// deleteChunks deletes the chunks from EDV. Chunks already gone are skipped.
+func (c *Client) deleteChunks(info *vaultInfo, backend *edvBackend, chunks []string) error {
+	edvVaultID := lastElm(info.Auth.EDV.URI, "/")
+
+	for _, chunkID := range chunks {
+		err := backend.client.DeleteDocument(edvVaultID, chunkID, edv.WithRequestHeader(
+			c.edvSign(info.DidURL, info.Auth.EDV)),
+		)
+		if err != nil && !edvResourceGone(err) {
+			return fmt.Errorf("delete chunk %s: %w", chunkID, err)
+		}
+	}
+
+	return nil
+}
+
+// deleteStaleChunks deletes chunks no document refers to. Failures are only logged: the chunks are left behind.
+func (c *Client) deleteStaleChunks(info *vaultInfo, backend *edvBackend, chunks []string) {
+	if err := c.deleteChunks(info, backend, chunks); err != nil {
+		logger.Warnf("failed to delete stale chunks: %v", err)
+	}
+}
+
+// binaryDigest computes, as the content is read, the digest contentDigest returns for the binary document holding
+// the whole content: chunked documents have the digests they would have if saved in one piece.
+type binaryDigest struct {
+	hash    hash.Hash
+	encoder io.WriteCloser
+	suffix  []byte
+}
+
+func newBinaryDigest(mediaType string) (*binaryDigest, error) {
+	src, err := json.Marshal(&models.StructuredDocument{
+		Meta:    map[string]interface{}{mediaTypeField: mediaType},
+		Content: map[string]interface{}{dataField: ""},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal: %w", err)
+	}
+
+	// the base64-encoded content goes between the quotes of the empty data, the last member
+	i := bytes.LastIndex(src, []byte(`""`)) + 1
+
+	h := sha256.New()
+	h.Write(src[:i])
+
+	return &binaryDigest{hash: h, encoder: base64.NewEncoder(base64.StdEncoding, h), suffix: src[i:]}, nil
+}
+
+func (d *binaryDigest) write(p []byte) {
+	d.encoder.Write(p) // nolint:errcheck,gosec // writes to a hash never fail
+}
+
+func (d *binaryDigest) sum() string {
+	d.encoder.Close() // nolint:errcheck,gosec // writes to a hash never fail
+	d.hash.Write(d.suffix)
+
+	return hex.EncodeToString(d.hash.Sum(nil))
+}
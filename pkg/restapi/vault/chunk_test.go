@@ -0,0 +1,290 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/piprate/json-gold/ld"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edv/pkg/restapi/models"
+
+	"github.com/trustbloc/ace/pkg/internal/testutil"
+	"github.com/trustbloc/ace/pkg/restapi/vault"
+)
+
+const testChunkSize = 16
+
+func TestClient_SaveBinaryDocStream(t *testing.T) {
+	loader := testutil.DocumentLoader(t)
+
+	t.Run("Saves content larger than a chunk in chunks", func(t *testing.T) {
+		client, vID, stored := newChunkVaultClient(t, loader)
+
+		content := randomContent(t, 100)
+
+		meta, err := client.SaveBinaryDocStream(vID, "scan", "application/dicom", bytes.NewReader(content))
+		require.NoError(t, err)
+		require.Equal(t, binaryDigest(t, "application/dicom", content), meta.ContentDigest)
+
+		// 7 chunks and the manifest
+		require.Equal(t, 8, stored())
+
+		result, err := client.GetDocContent(vID, "scan")
+		require.NoError(t, err)
+		require.Equal(t, "application/dicom", result.MediaType)
+		require.Equal(t, content, result.Data)
+	})
+
+	t.Run("Content of one chunk is not split", func(t *testing.T) {
+		client, vID, stored := newChunkVaultClient(t, loader)
+
+		content := randomContent(t, testChunkSize)
+
+		meta, err := client.SaveBinaryDoc(vID, "small", "application/octet-stream", content)
+		require.NoError(t, err)
+		require.Equal(t, binaryDigest(t, "application/octet-stream", content), meta.ContentDigest)
+		require.Equal(t, 1, stored())
+
+		result, err := client.GetDoc(vID, "small")
+		require.NoError(t, err)
+		require.Equal(t, content, result)
+	})
+
+	t.Run("Updates and deletions delete the chunks", func(t *testing.T) {
+		client, vID, stored := newChunkVaultClient(t, loader)
+
+		_, err := client.SaveBinaryDoc(vID, "doc", "application/octet-stream", randomContent(t, 100))
+		require.NoError(t, err)
+		require.Equal(t, 8, stored())
+
+		content := randomContent(t, 40)
+
+		_, err = client.SaveBinaryDoc(vID, "doc", "application/octet-stream", content)
+		require.NoError(t, err)
+		require.Equal(t, 4, stored())
+
+		result, err := client.GetDoc(vID, "doc")
+		require.NoError(t, err)
+		require.Equal(t, content, result)
+
+		_, err = client.SaveBinaryDoc(vID, "doc", "application/octet-stream", []byte("small"))
+		require.NoError(t, err)
+		require.Equal(t, 1, stored())
+
+		_, err = client.SaveBinaryDoc(vID, "doc", "application/octet-stream", randomContent(t, 100))
+		require.NoError(t, err)
+		require.Equal(t, 8, stored())
+
+		require.NoError(t, client.DeleteDoc(vID, "doc"))
+		require.Zero(t, stored())
+	})
+
+	t.Run("Rekeys the chunks", func(t *testing.T) {
+		client, vID, stored := newChunkVaultClient(t, loader)
+
+		content := randomContent(t, 100)
+
+		_, err := client.SaveBinaryDoc(vID, "doc", "application/octet-stream", content)
+		require.NoError(t, err)
+
+		rekey, err := client.RekeyVault(vID)
+		require.NoError(t, err)
+		require.True(t, rekey.Complete)
+		require.Equal(t, 1, rekey.Rekeyed)
+		require.Equal(t, 8, stored())
+
+		result, err := client.GetDoc(vID, "doc")
+		require.NoError(t, err)
+		require.Equal(t, content, result)
+	})
+
+	t.Run("Exports and imports the chunks", func(t *testing.T) {
+		client, vID, _ := newChunkVaultClient(t, loader)
+
+		content := randomContent(t, 100)
+
+		_, err := client.SaveBinaryDoc(vID, "doc", "application/octet-stream", content)
+		require.NoError(t, err)
+
+		var entries []*vault.ArchiveEntry
+
+		archive := exportArchive(t, client, vID)
+
+		require.NoError(t, decodeArchive(archive, &entries))
+		require.Len(t, entries, 2)
+		require.Len(t, entries[1].Document.Chunks, 7)
+
+		result, err := client.ImportVault(bytes.NewReader(archive))
+		require.NoError(t, err)
+		require.Equal(t, 1, result.Imported)
+		require.Empty(t, result.Failures)
+
+		doc, err := client.GetDoc(result.ID, "doc")
+		require.NoError(t, err)
+		require.Equal(t, content, doc)
+	})
+
+	t.Run("Error reading the content deletes the stored chunks", func(t *testing.T) {
+		client, vID, stored := newChunkVaultClient(t, loader)
+
+		content := io.MultiReader(bytes.NewReader(randomContent(t, 40)), iotest.ErrReader(errors.New("read failed")))
+
+		_, err := client.SaveBinaryDocStream(vID, "doc", "application/octet-stream", content)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "read failed")
+		require.Zero(t, stored())
+
+		_, err = client.GetDoc(vID, "doc")
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+	})
+
+	t.Run("Error if the expected sequence does not match", func(t *testing.T) {
+		client, vID, stored := newChunkVaultClient(t, loader)
+
+		_, err := client.SaveBinaryDoc(vID, "doc", "application/octet-stream", randomContent(t, 100),
+			vault.WithExpectedSequence(0))
+		require.ErrorIs(t, err, vault.ErrExpectedDocumentNotFound)
+		require.Zero(t, stored())
+	})
+}
+
+// newChunkVaultClient returns a client splitting binary documents into chunks of testChunkSize bytes, and
+// a function counting the documents stored in its EDV.
+func newChunkVaultClient(t *testing.T, loader ld.DocumentLoader) (*vault.Client, string, func() int) {
+	t.Helper()
+
+	remoteKMS := httptest.NewServer(newKMSHandler(t))
+	t.Cleanup(remoteKMS.Close)
+
+	edvHandler, stored := newDeletingEDVHandler(t)
+
+	edv := httptest.NewServer(edvHandler)
+	t.Cleanup(edv.Close)
+
+	provider := mem.NewProvider()
+
+	lKMS := newLocalKms(t, provider)
+	client, err := vault.NewClient(remoteKMS.URL, edv.URL, lKMS, provider, loader,
+		vault.WithChunkSize(testChunkSize))
+	require.NoError(t, err)
+
+	created, err := client.CreateVault()
+	require.NoError(t, err)
+
+	return client, created.ID, stored
+}
+
+// newDeletingEDVHandler returns a fake EDV server that also deletes documents, and a function counting the
+// documents it stores.
+func newDeletingEDVHandler(t *testing.T) (http.HandlerFunc, func() int) {
+	t.Helper()
+
+	var (
+		mu     sync.Mutex
+		stored = map[string]bool{}
+	)
+
+	edvHandler := newEDVHandler(t)
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		id := lastPathElement(r.URL.Path)
+
+		switch {
+		case r.Method == http.MethodDelete:
+			mu.Lock()
+			found := stored[id]
+			delete(stored, id)
+			mu.Unlock()
+
+			if !found {
+				w.WriteHeader(http.StatusNotFound)
+
+				return
+			}
+
+			w.WriteHeader(http.StatusOK)
+
+			return
+		case r.Method == http.MethodGet:
+			mu.Lock()
+			found := stored[id]
+			mu.Unlock()
+
+			if !found {
+				w.WriteHeader(http.StatusNotFound)
+
+				return
+			}
+		case strings.Contains(r.URL.Path, "/documents"):
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+
+			var encDoc models.EncryptedDocument
+
+			require.NoError(t, json.Unmarshal(body, &encDoc))
+
+			mu.Lock()
+			stored[encDoc.ID] = true
+			mu.Unlock()
+
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		edvHandler(w, r)
+	}
+
+	count := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return len(stored)
+	}
+
+	return handler, count
+}
+
+func randomContent(t *testing.T, size int) []byte {
+	t.Helper()
+
+	content := make([]byte, size)
+
+	_, err := rand.Read(content)
+	require.NoError(t, err)
+
+	return content
+}
+
+// binaryDigest returns the digest of the content saved in one piece.
+func binaryDigest(t *testing.T, mediaType string, content []byte) string {
+	t.Helper()
+
+	src, err := json.Marshal(&models.StructuredDocument{
+		Meta:    map[string]interface{}{"mediaType": mediaType},
+		Content: map[string]interface{}{"data": base64.StdEncoding.EncodeToString(content)},
+	})
+	require.NoError(t, err)
+
+	sum := sha256.Sum256(src)
+
+	return hex.EncodeToString(sum[:])
+}
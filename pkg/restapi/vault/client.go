@@ -8,14 +8,18 @@ package vault
 
 import (
 	"bytes"
+	"context"
 	"crypto/ed25519"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
-	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -39,10 +43,10 @@ import (
 	"github.com/hyperledger/aries-framework-go/spi/storage"
 	"github.com/igor-pavlenko/httpsignatures-go"
 	"github.com/piprate/json-gold/ld"
+	"github.com/trustbloc/edge-core/pkg/log"
 	"github.com/trustbloc/edge-core/pkg/zcapld"
 	edv "github.com/trustbloc/edv/pkg/client"
 	"github.com/trustbloc/edv/pkg/edvutils"
-	"github.com/trustbloc/edv/pkg/restapi/messages"
 	"github.com/trustbloc/edv/pkg/restapi/models"
 
 	"github.com/trustbloc/ace/pkg/doc/vc/crypto"
@@ -55,15 +59,69 @@ const (
 	authorizationFormat = "authorization_%s_%s"
 	metaDocInfoFormat   = "meta_doc_info_%s_%s"
 	infoFormat          = "info_%s"
+	revocationFormat    = "revocation_%s"
+
+	authorizationTargetTag = "authorization_target"
+	vaultDocsTag           = "vault_docs"
+	vaultAuthorizationsTag = "vault_authorizations"
+
+	deleteKeyStoreAction = "deleteKeyStore"
+
+	// binary documents are base64-encoded in the data field of the structured document's content
+	mediaTypeField = "mediaType"
+	dataField      = "data"
+	jsonMediaType  = "application/json"
+
+	defaultListLimit = 100
+	maxListLimit     = 1000
+
+	// vaultInfoVersion is the version of the vaultInfo schema. Version 1 added the creation time and doc count,
+	// version 2 indexed the vaults by controller, version 3 indexed their documents and authorizations in list
+	// indexes.
+	vaultInfoVersion = 3
 )
 
+var logger = log.New("vault")
+
 // Vault defines vault client interface.
 type Vault interface {
-	CreateVault() (*CreatedVault, error)
-	SaveDoc(vaultID, id string, content []byte) (*DocumentMetadata, error)
+	CreateVault(opts ...CreateVaultOpt) (*CreatedVault, error)
+	CreateVaultWithController(controller, verificationMethod string, req *http.Request,
+		opts ...CreateVaultOpt) (*CreatedVault, error)
+	GetVaultInfo(vaultID string) (*VaultInfo, error)
+	ListVaults(controller string, limit int, next string) (*VaultList, error)
+	DeleteVault(vaultID string) (*VaultDeletion, error)
+	SaveDoc(vaultID, id string, content []byte, opts ...SaveDocOpt) (*DocumentMetadata, error)
+	SaveBinaryDoc(vaultID, id, mediaType string, content []byte, opts ...SaveDocOpt) (*DocumentMetadata, error)
+	SaveBinaryDocStream(vaultID, id, mediaType string, content io.Reader,
+		opts ...SaveDocOpt) (*DocumentMetadata, error)
 	GetDocMetadata(vaultID, docID string) (*DocumentMetadata, error)
-	CreateAuthorization(vaultID, requestingParty string, scope *AuthorizationsScope) (*CreatedAuthorization, error)
+	GetDocMetadataByURI(uri string) (*DocumentMetadata, error)
+	PatchDoc(vaultID, docID string, patch []byte, opts ...SaveDocOpt) (*DocumentMetadata, error)
+	JSONPatchDoc(vaultID, docID string, patch []byte, opts ...SaveDocOpt) (*DocumentMetadata, error)
+	GetDoc(vaultID, docID string) ([]byte, error)
+	GetDocContent(vaultID, docID string) (*DocumentContent, error)
+	DeleteDoc(vaultID, docID string) error
+	ListDocs(vaultID string, limit int, next string) (*DocumentList, error)
+	CreateAuthorizationChallenge(vaultID string) (*AuthorizationChallenge, error)
+	CreateAuthorization(vaultID, requestingParty string, scope *AuthorizationsScope,
+		opts ...CreateAuthorizationOpt) (*CreatedAuthorization, error)
 	GetAuthorization(vaultID, id string) (*CreatedAuthorization, error)
+	UseAuthorization(vaultID, id string) (*CreatedAuthorization, error)
+	ListAuthorizations(vaultID string, query *AuthorizationQuery) (*AuthorizationList, error)
+	DeleteAuthorization(vaultID, id string) error
+	GetAuditTrail(vaultID string, query *AuditQuery) (*AuditTrail, error)
+	GetRevocation(zcapID string) (*Revocation, error)
+	ExportVault(vaultID string) (*VaultExport, error)
+	ImportVault(archive io.Reader) (*VaultImport, error)
+	RekeyVault(vaultID string) (*VaultRekey, error)
+	RotateTokens(vaultID string) (*CreatedVault, error)
+	RekeyDoc(vaultID, docID string) (*DocumentMetadata, error)
+	GetRekeyStatus(vaultID string) (*VaultRekey, error)
+	FindDocs(vaultID, name, value string) (*DocumentList, error)
+	CreateWebhook(vaultID, webhookURL, secret string) (*Webhook, error)
+	ListWebhooks(vaultID string) (*WebhookList, error)
+	DeleteWebhook(vaultID, id string) error
 }
 
 // KeyManager KMS alias.
@@ -78,14 +136,120 @@ type HTTPClient interface {
 type CreatedVault struct {
 	ID string `json:"id"`
 	*Authorization
+	// Existing is set if the vault was created before with the same reference ID. Its authorization is then the
+	// current one of the vault.
+	Existing bool `json:"-"`
+}
+
+// VaultInfo describes a vault. Authorization tokens are not included.
+type VaultInfo struct {
+	ID         string `json:"id"`
+	Controller string `json:"controller"`
+	// Created is nil for vaults created before the creation time was recorded.
+	Created  *time.Time `json:"created,omitempty"`
+	DocCount int        `json:"documentCount"`
+	EDVURI   string     `json:"edvURI"`
+	KMSURI   string     `json:"kmsURI"`
+	// EDVBackend is the name of the EDV backend the vault was created in.
+	EDVBackend string `json:"edvBackend"`
+	// KeyType is the type of the key-wrapping keys the documents of the vault are encrypted to.
+	KeyType kms.KeyType `json:"keyType"`
 }
 
-// CreatedAuthorization represents success response of CreateAuthorization function.
+// VaultDeletion reports the deletion of a vault from its backends.
+type VaultDeletion struct {
+	ID string `json:"id"`
+	// Complete is true once the data of the vault is deleted from all backends along with its local records.
+	Complete bool            `json:"complete"`
+	EDV      *DeletionStatus `json:"edv"`
+	KMS      *DeletionStatus `json:"kms"`
+}
+
+// DeletionStatus is the outcome of the deletion of a vault from one backend.
+type DeletionStatus struct {
+	Deleted bool   `json:"deleted"`
+	Error   string `json:"error,omitempty"`
+}
+
+// CreatedAuthorization represents success response of CreateAuthorization function. It is stored as is, so
+// GetAuthorization returns the same scope, tokens and validity; only Status, the uses and the remaining validity
+// are computed when it is read.
 type CreatedAuthorization struct {
 	ID              string               `json:"id"`
 	Scope           *AuthorizationsScope `json:"scope"`
 	RequestingParty string               `json:"requestingParty"`
 	Tokens          *Tokens              `json:"authTokens"`
+	Revoked         bool                 `json:"revoked,omitempty"`
+	Created         *time.Time           `json:"created,omitempty"`
+	ExpiresAt       *time.Time           `json:"expiresAt,omitempty"`
+	Status          string               `json:"status,omitempty"`
+	// Uses is the number of uses recorded with UseAuthorization.
+	Uses uint64 `json:"uses,omitempty"`
+	// RemainingUses is nil unless the authorization has a usage caveat.
+	RemainingUses *uint64 `json:"remainingUses,omitempty"`
+	// SecondsUntilExpiry is nil unless the authorization has an expiry caveat, 0 once it has expired.
+	SecondsUntilExpiry *int64 `json:"secondsUntilExpiry,omitempty"`
+}
+
+// Authorization statuses.
+const (
+	AuthorizationStatusActive  = "active"
+	AuthorizationStatusExpired = "expired"
+	AuthorizationStatusRevoked = "revoked"
+)
+
+// expired reports whether the authorization's expiry caveat has lapsed.
+func (a *CreatedAuthorization) expired(now time.Time) bool {
+	return a.ExpiresAt != nil && !now.Before(*a.ExpiresAt)
+}
+
+// status returns the status of the authorization at the given time.
+func (a *CreatedAuthorization) status(now time.Time) string {
+	switch {
+	case a.Revoked:
+		return AuthorizationStatusRevoked
+	case a.expired(now):
+		return AuthorizationStatusExpired
+	case a.RemainingUses != nil && *a.RemainingUses == 0:
+		return AuthorizationStatusExhausted
+	default:
+		return AuthorizationStatusActive
+	}
+}
+
+// AuthorizationQuery selects the authorizations returned by ListAuthorizations.
+type AuthorizationQuery struct {
+	// RequestingParty restricts the list to the authorizations granted to this party, if not empty.
+	RequestingParty string
+	// IncludeExpired includes the authorizations whose expiry caveat has lapsed.
+	IncludeExpired bool
+	// Limit is the maximum number of authorizations returned. Defaults to 100, at most 1000.
+	Limit int
+	// Next is the continuation token of the page to return.
+	Next string
+}
+
+// AuthorizationList is a page of the authorizations created for a vault.
+type AuthorizationList struct {
+	Authorizations []*AuthorizationListEntry `json:"authorizations"`
+	// Next is the continuation token of the next page. It is empty on the last page.
+	Next string `json:"next,omitempty"`
+}
+
+// AuthorizationListEntry describes an authorization of an AuthorizationList. Authorization tokens are not included.
+type AuthorizationListEntry struct {
+	ID              string               `json:"id"`
+	RequestingParty string               `json:"requestingParty"`
+	Scope           *AuthorizationsScope `json:"scope"`
+	Created         *time.Time           `json:"created,omitempty"`
+	ExpiresAt       *time.Time           `json:"expiresAt,omitempty"`
+	Status          string               `json:"status"`
+}
+
+// Revocation records that a zcap issued by the vault server was revoked.
+type Revocation struct {
+	ZcapID    string    `json:"zcapID"`
+	RevokedAt time.Time `json:"revokedAt"`
 }
 
 // Tokens zcap tokens.
@@ -106,6 +270,8 @@ type AuthorizationsScope struct {
 type Caveat struct {
 	Type     string `json:"type,omitempty"`
 	Duration uint64 `json:"duration,omitempty"`
+	// Uses is the number of times the authorization can be used, for caveats of type CaveatTypeUsage.
+	Uses uint64 `json:"uses,omitempty"`
 }
 
 // Authorization consists of info needed for the authorization.
@@ -125,23 +291,119 @@ type DocumentMetadata struct {
 	ID        string `json:"docID"`
 	URI       string `json:"edvDocURI"`
 	EncKeyURI string `json:"encKeyURI"`
+	// Sequence is incremented on every update of the document, starting at 0.
+	Sequence uint64 `json:"sequence"`
+	// CreatedAt and UpdatedAt are omitted for documents saved before they were recorded.
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+	// ContentDigest is the hex-encoded SHA-256 digest of the plaintext document, see WithoutContentDigests. It is
+	// omitted for documents saved before it was recorded, or while digests are disabled.
+	ContentDigest string `json:"contentDigest,omitempty"`
+	// Recipients are the keys the document is encrypted to: the key at EncKeyURI, followed by the DID URL of the
+	// key agreement key of the controller if the document was saved with WithControllerRecipient.
+	Recipients []string `json:"recipients,omitempty"`
+	// EDVURL is the base URL of the EDV server the document is stored in, along with the IDs of its EDV vault and
+	// EDV document, so that clients of the EDV server need not parse the URI.
+	EDVURL     string `json:"edvURL,omitempty"`
+	EDVVaultID string `json:"edvVaultID,omitempty"`
+	EDVDocID   string `json:"edvDocID,omitempty"`
+}
+
+// DocumentContent is the decrypted content of a document.
+type DocumentContent struct {
+	MediaType string
+	Data      []byte
+}
+
+// DocumentList is a page of the documents stored in a vault.
+type DocumentList struct {
+	Documents []*DocumentListEntry `json:"documents"`
+	// Next is the continuation token of the next page. It is empty on the last page.
+	Next string `json:"next,omitempty"`
+}
+
+// DocumentListEntry describes a document of a DocumentList.
+type DocumentListEntry struct {
+	ID      string    `json:"docID"`
+	URI     string    `json:"edvDocURI"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+}
+
+// ErrInvalidContinuationToken is returned by ListDocs and ListAuthorizations when the continuation token is malformed.
+var ErrInvalidContinuationToken = errors.New("invalid continuation token")
+
+// ErrVaultDeleting is returned when writing to a vault whose deletion has started.
+var ErrVaultDeleting = errors.New("vault is being deleted")
+
+// ErrInvalidController is returned when the controller of a new vault does not resolve or lacks the verification
+// method.
+var ErrInvalidController = errors.New("invalid controller")
+
+// ErrVaultExists is returned when creating a vault for a controller that already has one.
+var ErrVaultExists = errors.New("vault already exists")
+
+// ErrExternallyControlled is returned when acting on the EDV data vault or WebKMS keystore of a vault controlled by
+// an existing DID, which the vault server cannot sign requests for.
+var ErrExternallyControlled = errors.New("the vault is controlled by an existing DID: " +
+	"access its EDV data vault and WebKMS keystore with the vault's authorization tokens")
+
+// ErrSequenceMismatch is returned when saving a document whose sequence is not the expected one.
+var ErrSequenceMismatch = errors.New("sequence mismatch")
+
+// ErrExpectedDocumentNotFound is returned when saving a document with an expected sequence if the document does not
+// exist. It matches ErrDocumentNotFound with errors.Is.
+var ErrExpectedDocumentNotFound = fmt.Errorf("expected %w", ErrDocumentNotFound)
+
+// SequenceMismatchError reports the current sequence of a document that was updated concurrently.
+// It matches ErrSequenceMismatch with errors.Is.
+type SequenceMismatchError struct {
+	Expected uint64
+	Current  uint64
+}
+
+func (e *SequenceMismatchError) Error() string {
+	return fmt.Sprintf("%s: expected %d, current %d", ErrSequenceMismatch, e.Expected, e.Current)
+}
+
+// Is reports whether target is ErrSequenceMismatch.
+func (e *SequenceMismatchError) Is(target error) bool {
+	return target == ErrSequenceMismatch // nolint:errorlint
 }
 
 // Client vault`s client.
 type Client struct {
-	remoteKMSURL    string
-	edvHost         string
-	edvScheme       string
-	didMethod       string
-	didDomain       string
-	didAnchorOrigin string
-	kms             KeyManager
-	crypto          ariescrypto.Crypto
-	edvClient       *edv.Client
-	httpClient      HTTPClient
-	store           storage.Store
-	registry        vdr.Registry
-	documentLoader  ld.DocumentLoader
+	remoteKMSURL      string
+	kmsURLs           []string
+	edvURLs           map[string]string
+	edvBackends       map[string]*edvBackend
+	defaultEDVBackend string
+	didMethod         string
+	didDomain         string
+	didAnchorOrigin   string
+	kms               KeyManager
+	crypto            ariescrypto.Crypto
+	httpClient        HTTPClient
+	store             storage.Store
+	registry          vdr.Registry
+	documentLoader    ld.DocumentLoader
+	referenceMu       keyedMutex
+	usageMu           sync.Mutex
+	docMu             keyedMutex
+	indexMu           keyedMutex
+	vaultMu           keyedMutex
+	webhookAttempts   int
+	webhookBackoff    time.Duration
+	webhookWorkers    int
+	webhookClient     HTTPClient
+	webhooks          *webhookDispatcher
+	noContentDigests  bool
+	verifyCredentials bool
+	keyType           kms.KeyType
+	chunkSize         int
+	docMetaCache      *docMetaCache
+	requireKeyProof   bool
+	challengeTTL      time.Duration
 }
 
 // Opt represents Client`s option.
@@ -182,6 +444,32 @@ func WithRegistry(registry vdr.Registry) Opt {
 	}
 }
 
+// WithoutContentDigests disables the content digests of the saved documents, for deployments considering that
+// revealing whether two documents are equal is sensitive. Documents saved meanwhile have no digest.
+func WithoutContentDigests() Opt {
+	return func(vault *Client) {
+		vault.noContentDigests = true
+	}
+}
+
+// SaveDocOpt represents an option of SaveDoc and SaveBinaryDoc.
+type SaveDocOpt func(*saveDocOpts)
+
+type saveDocOpts struct {
+	expectedSequence    *uint64
+	indexTags           map[string]string
+	indexed             []models.IndexedAttributeCollection
+	controllerRecipient bool
+}
+
+// WithExpectedSequence makes the save fail with a SequenceMismatchError unless the document exists and its
+// current sequence is the given one. Without it, documents are overwritten regardless of concurrent updates.
+func WithExpectedSequence(sequence uint64) SaveDocOpt {
+	return func(opts *saveDocOpts) {
+		opts.expectedSequence = &sequence
+	}
+}
+
 // NewClient creates a new vault client.
 func NewClient(kmsURL, edvURL string, kmsClient kms.KeyManager, db storage.Provider, loader ld.DocumentLoader,
 	opts ...Opt,
@@ -191,23 +479,12 @@ func NewClient(kmsURL, edvURL string, kmsClient kms.KeyManager, db storage.Provi
 		return nil, fmt.Errorf("tinkcrypto new: %w", err)
 	}
 
-	u, err := url.Parse(edvURL)
-	if err != nil {
-		return nil, fmt.Errorf("url parse: %w", err)
-	}
-
-	store, err := db.OpenStore(storeName)
-	if err != nil {
-		return nil, fmt.Errorf("open store: %w", err)
-	}
-
 	client := &Client{
-		remoteKMSURL: kmsURL,
-		edvHost:      u.Host,
-		edvScheme:    u.Scheme,
-		kms:          kmsClient,
-		crypto:       cryptoService,
-		store:        store,
+		remoteKMSURL:      kmsURL,
+		edvURLs:           map[string]string{DefaultEDVBackend: edvURL},
+		defaultEDVBackend: DefaultEDVBackend,
+		kms:               kmsClient,
+		crypto:            cryptoService,
 		httpClient: &http.Client{
 			Timeout: time.Minute,
 		},
@@ -215,322 +492,1640 @@ func NewClient(kmsURL, edvURL string, kmsClient kms.KeyManager, db storage.Provi
 		registry: ariesvdr.New(
 			ariesvdr.WithVDR(vdrkey.New()),
 		),
-		documentLoader: loader,
+		documentLoader:  loader,
+		webhookAttempts: defaultWebhookAttempts,
+		webhookBackoff:  defaultWebhookBackoff,
+		webhookWorkers:  defaultWebhookWorkers,
+		webhookClient:   NewWebhookHTTPClient(nil),
+		webhooks:        newWebhookDispatcher(),
+		keyType:         DefaultKeyType,
+		chunkSize:       DefaultChunkSize,
+		challengeTTL:    DefaultChallengeTTL,
 	}
 
 	for _, fn := range opts {
 		fn(client)
 	}
 
-	client.edvClient = edv.New(edvURL, edv.WithHTTPClient(client.httpClient))
+	err = CheckKeyType(client.keyType)
+	if err != nil {
+		return nil, err
+	}
+
+	err = client.initEDVBackends()
+	if err != nil {
+		return nil, err
+	}
+
+	client.store, err = db.OpenStore(storeName)
+	if err != nil {
+		return nil, fmt.Errorf("open store: %w", err)
+	}
+
+	err = db.SetStoreConfig(storeName, storage.StoreConfiguration{
+		TagNames: []string{
+			authorizationTargetTag, vaultDocsTag, vaultAuthorizationsTag, vaultWebhooksTag, vaultDeadLettersTag,
+			authorizationChallengesTag, edvDocsTag, vaultAuditTag, vaultControllerTag,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("set store config: %w", err)
+	}
 
 	return client, nil
 }
 
 // CreateVault creates a new vault and KMS store bases on generated DIDKey.
-func (c *Client) CreateVault() (*CreatedVault, error) {
+func (c *Client) CreateVault(opts ...CreateVaultOpt) (*CreatedVault, error) {
+	backend, err := c.newVaultEDVBackend(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	keyType, err := c.newVaultKeyType(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	kmsURL, err := c.newVaultKMSURL(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	owner, err := c.newVaultOwner(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	reference, err := vaultReferenceKey(owner, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// checked before creating the DID of the vault, which is wasted otherwise
+	existing, err := c.referencedVault(reference)
+	if err != nil || existing != nil {
+		return existing, err
+	}
+
 	didKey, didURL, kid, err := c.createDIDKey(c.didMethod)
 	if err != nil {
 		return nil, fmt.Errorf("create DID key: %w", err)
 	}
 
-	kmsURI, kmsZCAP, err := webkms.CreateKeyStore(c.httpClient, c.remoteKMSURL, didURL, "", nil)
+	return c.createReferencedVault(reference, func() (*CreatedVault, error) {
+		return c.createVault(backend, keyType, kmsURL, reference, didKey, didURL, kid, owner)
+	})
+}
+
+// CreateVaultWithController creates a vault controlled by an existing DID instead of a new one: the DID is the ID
+// of the vault and the verification method controls its EDV data vault and WebKMS keystore. The request creating
+// the vault must carry an HTTP signature made with the verification method, which must then be an authentication
+// method of the DID. No key is created for the vault, so the vault server cannot sign the EDV and KMS requests of
+// the vault: its documents are accessed by the controller with the returned authorization tokens, and the document
+// API fails with ErrExternallyControlled.
+func (c *Client) CreateVaultWithController(controller, verificationMethod string, req *http.Request,
+	opts ...CreateVaultOpt) (*CreatedVault, error) {
+	backend, err := c.newVaultEDVBackend(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	keyType, err := c.newVaultKeyType(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	kmsURL, err := c.newVaultKMSURL(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	didURL, err := c.resolveVerificationMethod(controller, verificationMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.verifyMethodSignature(didURL, req)
+	if err != nil {
+		return nil, err
+	}
+
+	reference, err := vaultReferenceKey(controller, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := c.referencedVault(reference)
+	if err != nil || existing != nil {
+		return existing, err
+	}
+
+	_, err = c.store.Get(fmt.Sprintf(infoFormat, controller))
+	if err == nil {
+		return nil, fmt.Errorf("%w: %s", ErrVaultExists, controller)
+	}
+
+	if !errors.Is(err, storage.ErrDataNotFound) {
+		return nil, fmt.Errorf("get vault info: %w", err)
+	}
+
+	return c.createReferencedVault(reference, func() (*CreatedVault, error) {
+		return c.createVault(backend, keyType, kmsURL, reference, controller, didURL, "", "")
+	})
+}
+
+// resolveVerificationMethod returns the absolute URL of the verification method of the controller. Verification
+// methods may be given relative to the controller.
+func (c *Client) resolveVerificationMethod(controller, verificationMethod string) (string, error) {
+	if controller == "" || verificationMethod == "" {
+		return "", fmt.Errorf("%w: controller and verificationMethod are required", ErrInvalidController)
+	}
+
+	didURL := verificationMethod
+	if strings.HasPrefix(didURL, "#") {
+		didURL = controller + didURL
+	}
+
+	if !strings.HasPrefix(didURL, controller+"#") {
+		return "", fmt.Errorf("%w: verification method %s does not belong to %s",
+			ErrInvalidController, verificationMethod, controller)
+	}
+
+	docResolution, err := c.registry.Resolve(controller)
+	if err != nil {
+		return "", fmt.Errorf("%w: resolve %s: %s", ErrInvalidController, controller, err)
+	}
+
+	doc := docResolution.DIDDocument
+
+	for _, verifications := range doc.VerificationMethods() {
+		for _, v := range verifications {
+			if v.VerificationMethod.ID == didURL || doc.ID+v.VerificationMethod.ID == didURL {
+				return didURL, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("%w: verification method %s not found in %s",
+		ErrInvalidController, verificationMethod, controller)
+}
+
+func (c *Client) createVault(backend *edvBackend, keyType kms.KeyType, kmsURL, reference, vaultID, didURL, kid,
+	owner string) (*CreatedVault, error) {
+	kmsURI, kmsZCAP, err := webkms.CreateKeyStore(c.kmsHTTPClient(), kmsURL, didURL, "", nil)
 	if err != nil {
 		return nil, fmt.Errorf("create key store: %w", err)
 	}
 
-	edvLoc, err := c.createDataVault(didURL)
+	edvLoc, err := c.createDataVault(backend, didURL)
 	if err != nil {
 		return nil, fmt.Errorf("create data vault: %w", err)
 	}
 
-	edvLoc.URI = buildEDVURI(c.edvScheme, c.edvHost, lastElm(edvLoc.URI, "/"))
+	edvLoc.URI = buildEDVURI(backend.scheme, backend.host, lastElm(edvLoc.URI, "/"))
 
 	auth := &Authorization{
 		KMS: &Location{
-			URI:       c.buildKMSURL(kmsURI),
+			URI:       resolveKMSURL(kmsURL, kmsURI),
 			AuthToken: base64.URLEncoding.EncodeToString(kmsZCAP),
 		},
 		EDV: edvLoc,
 	}
 
-	err = c.saveVaultInfo(didKey, &vaultInfo{Auth: auth, KID: kid, DidURL: didURL})
+	info := &vaultInfo{
+		Auth:       auth,
+		KID:        kid,
+		External:   kid == "",
+		DidURL:     didURL,
+		Created:    time.Now().UTC(),
+		Version:    vaultInfoVersion,
+		EDVBackend: backend.name,
+		KeyType:    keyType,
+		KMSURL:     kmsURL,
+		Reference:  reference,
+		Owner:      owner,
+	}
+
+	if owner != "" {
+		auth, err = c.issueTokens(info, owner)
+		if err != nil {
+			return nil, fmt.Errorf("issue tokens: %w", err)
+		}
+
+		info.IssuedAuth = auth
+	}
+
+	err = c.saveVaultInfo(vaultID, info)
 	if err != nil {
 		return nil, fmt.Errorf("save vault info: %w", err)
 	}
 
 	return &CreatedVault{
-		ID:            didKey,
+		ID:            vaultID,
 		Authorization: auth,
 	}, nil
 }
 
-// CreateAuthorization creates a new authorization.
-// nolint: funlen
-func (c *Client) CreateAuthorization(vaultID, requestingParty string, scope *AuthorizationsScope,
-) (*CreatedAuthorization, error) {
+// GetVaultInfo returns the creation time, controller, document count and EDV/KMS URIs of the vault.
+func (c *Client) GetVaultInfo(vaultID string) (*VaultInfo, error) {
 	info, err := c.getVaultInfo(vaultID)
 	if err != nil {
 		return nil, fmt.Errorf("get vault info: %w", err)
 	}
 
-	kh, err := c.kms.Get(info.KID)
+	return newVaultInfo(vaultID, info), nil
+}
+
+func newVaultInfo(vaultID string, info *vaultInfo) *VaultInfo {
+	result := &VaultInfo{
+		ID:         vaultID,
+		Controller: info.controller(),
+		DocCount:   info.DocCount,
+		EDVURI:     info.Auth.EDV.URI,
+		KMSURI:     info.Auth.KMS.URI,
+		EDVBackend: info.EDVBackend,
+		KeyType:    info.keyType(),
+	}
+
+	if result.EDVBackend == "" {
+		result.EDVBackend = DefaultEDVBackend
+	}
+
+	if !info.Created.IsZero() {
+		created := info.Created
+		result.Created = &created
+	}
+
+	return result
+}
+
+// DeleteVault deletes the documents of the vault from EDV, its WebKMS keystore and the local records of the vault.
+// The EDV API does not support deleting the EDV vault itself, so only its documents are deleted.
+//
+// Once started, the deletion cannot be cancelled: the vault no longer accepts new documents and authorizations.
+// Documents and keystores that no longer exist are considered deleted.
+// If a backend fails, the VaultDeletion reports it and the vault is kept until the call is retried. Deletions
+// that already succeeded are not repeated.
+func (c *Client) DeleteVault(vaultID string) (*VaultDeletion, error) {
+	var info *vaultInfo
+
+	err := c.updateVaultInfo(vaultID, func(current *vaultInfo) error {
+		current.Deleting = true
+		info = current
+
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("kms get: %w", err)
+		return nil, fmt.Errorf("get vault info: %w", err)
 	}
 
-	kmsCapability, err := zcapld.DecompressZCAP(info.Auth.KMS.AuthToken)
+	result := &VaultDeletion{ID: vaultID, KMS: &DeletionStatus{Deleted: info.KMSDeleted}}
+
+	var left int
+
+	result.EDV, left = c.deleteEDVDocs(vaultID, info)
+
+	if !info.KMSDeleted {
+		result.KMS = c.deleteKeyStore(info)
+	}
+
+	if !result.EDV.Deleted || !result.KMS.Deleted {
+		err = c.updateVaultInfo(vaultID, func(current *vaultInfo) error {
+			current.DocCount = left
+			current.KMSDeleted = result.KMS.Deleted
+
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("save vault info: %w", err)
+		}
+
+		return result, nil
+	}
+
+	for _, tag := range []string{
+		vaultAuthorizationsTag, vaultWebhooksTag, vaultDeadLettersTag, authorizationChallengesTag, vaultAuditTag,
+	} {
+		err = c.deleteVaultRecords(tag, vaultID)
+		if err != nil {
+			return nil, fmt.Errorf("delete %s: %w", tag, err)
+		}
+	}
+
+	for _, idx := range []*listIndex{docsIndex, authorizationsIndex} {
+		err = c.deleteListIndex(idx, vaultID)
+		if err != nil {
+			return nil, fmt.Errorf("delete %s index: %w", idx.name, err)
+		}
+	}
+
+	err = c.store.Delete(fmt.Sprintf(rekeyFormat, vaultID))
+	if err != nil && !errors.Is(err, storage.ErrDataNotFound) {
+		return nil, fmt.Errorf("delete rekey: %w", err)
+	}
+
+	if info.Reference != "" {
+		err = c.store.Delete(info.Reference)
+		if err != nil && !errors.Is(err, storage.ErrDataNotFound) {
+			return nil, fmt.Errorf("delete vault reference: %w", err)
+		}
+	}
+
+	err = c.store.Delete(fmt.Sprintf(infoFormat, vaultID))
 	if err != nil {
-		return nil, fmt.Errorf("kms uncompressZCAP: %w", err)
+		return nil, fmt.Errorf("delete vault info: %w", err)
 	}
 
-	kmsNewCapability, err := zcapld.NewCapability(&zcapld.Signer{
-		SignatureSuite:     ed25519signature2018.New(suite.WithSigner(newSigner(c.crypto, kh))),
-		SuiteType:          ed25519signature2018.SignatureType,
-		VerificationMethod: info.DidURL,
-		ProcessorOpts:      []jsonld.ProcessorOpts{jsonld.WithDocumentLoader(c.documentLoader)},
-	}, zcapld.WithParent(c.buildKMSURL(kmsCapability.ID)), zcapld.WithInvoker(requestingParty),
-		zcapld.WithAllowedActions("unwrap"),
-		zcapld.WithInvocationTarget(c.buildKMSURL(kmsCapability.InvocationTarget.ID), kmsCapability.InvocationTarget.Type),
-		zcapld.WithCaveats(toZCaveats(scope.Caveats)...),
-		zcapld.WithCapabilityChain(c.buildKMSURL(kmsCapability.ID)))
+	result.Complete = true
+
+	return result, nil
+}
+
+// deleteEDVDocs deletes the documents of the vault from EDV along with their metadata. It returns the number of
+// documents left. The documents of legacy vaults are only deleted once the legacy records are tagged: the untagged
+// ones would be left behind.
+func (c *Client) deleteEDVDocs(vaultID string, info *vaultInfo) (*DeletionStatus, int) {
+	if info.Legacy {
+		tagged, err := c.legacyRecordsTagged()
+		if err != nil {
+			return &DeletionStatus{Error: err.Error()}, info.DocCount
+		}
+
+		if !tagged {
+			return &DeletionStatus{Error: ErrUntaggedRecords.Error()}, info.DocCount
+		}
+	}
+
+	backend, err := c.edvBackend(info)
 	if err != nil {
-		return nil, fmt.Errorf("kms new capability: %w", err)
+		return &DeletionStatus{Error: err.Error()}, info.DocCount
 	}
 
-	kmsCompressedCapability, err := zcapld.CompressZCAP(kmsNewCapability)
+	docs, err := c.queryMetaDocInfos(vaultID)
 	if err != nil {
-		return nil, fmt.Errorf("kms compressZCAP: %w", err)
+		return &DeletionStatus{Error: fmt.Sprintf("query meta doc infos: %s", err)}, info.DocCount
 	}
 
-	edvCapability, err := zcapld.DecompressZCAP(info.Auth.EDV.AuthToken)
+	var (
+		edvVaultID = lastElm(info.Auth.EDV.URI, "/")
+		firstErr   error
+		left       int
+	)
+
+	for _, d := range docs {
+		err = backend.client.DeleteDocument(edvVaultID, d.EdvID, edv.WithRequestHeader(
+			c.edvSign(info.DidURL, info.Auth.EDV)),
+		)
+		if err == nil || edvResourceGone(err) {
+			err = c.deleteChunks(info, backend, d.Chunks)
+		}
+
+		if err == nil {
+			err = c.store.Delete(fmt.Sprintf(metaDocInfoFormat, vaultID, d.DocID))
+		}
+
+		if err == nil {
+			err = c.indexRemove(docsIndex, vaultID, d.DocID)
+		}
+
+		c.docMetaCache.invalidate(vaultID, d.DocID)
+
+		if err != nil {
+			left++
+
+			if firstErr == nil {
+				firstErr = fmt.Errorf("delete document %s: %w", d.DocID, err)
+			}
+		}
+	}
+
+	if firstErr != nil {
+		return &DeletionStatus{
+			Error: fmt.Sprintf("failed to delete %d of %d documents: %s", left, len(docs), firstErr),
+		}, left
+	}
+
+	return &DeletionStatus{Deleted: true}, 0
+}
+
+// edvResourceGone reports whether the EDV failed to delete a document because it, or its EDV vault, does not exist.
+func edvResourceGone(err error) bool {
+	return errors.Is(err, ErrDocumentNotFound) || errors.Is(err, ErrVaultNotFound)
+}
+
+// deleteKeyStore deletes the WebKMS keystore of the vault. A keystore that does not exist is considered deleted.
+func (c *Client) deleteKeyStore(info *vaultInfo) *DeletionStatus {
+	req, err := http.NewRequestWithContext(context.Background(),
+		http.MethodDelete, c.buildKMSURL(info, info.Auth.KMS.URI), http.NoBody)
 	if err != nil {
-		return nil, fmt.Errorf("edv uncompressZCAP: %w", err)
+		return &DeletionStatus{Error: fmt.Sprintf("new request: %s", err)}
 	}
 
-	edvNewCapability, err := zcapld.NewCapability(&zcapld.Signer{
-		SignatureSuite:     ed25519signature2018.New(suite.WithSigner(newSigner(c.crypto, kh))),
-		SuiteType:          ed25519signature2018.SignatureType,
-		VerificationMethod: info.DidURL,
-		ProcessorOpts:      []jsonld.ProcessorOpts{jsonld.WithDocumentLoader(c.documentLoader)},
-	}, zcapld.WithParent(edvCapability.ID), zcapld.WithInvoker(requestingParty),
-		zcapld.WithAllowedActions(scope.Actions...),
-		zcapld.WithInvocationTarget(edvCapability.InvocationTarget.ID, edvCapability.InvocationTarget.Type),
-		zcapld.WithCaveats(toZCaveats(scope.Caveats)...),
-		zcapld.WithCapabilityChain(edvCapability.Parent, edvCapability.ID))
+	_, err = c.sign(req, info.DidURL, deleteKeyStoreAction, info.Auth.KMS.AuthToken)
+	if err != nil {
+		return &DeletionStatus{Error: err.Error()}
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return &DeletionStatus{Error: fmt.Sprintf("delete key store: %s", err)}
+	}
+
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil {
+			logger.Errorf("failed to close response body: %s", errClose)
+		}
+	}()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return &DeletionStatus{Deleted: true}
+	default:
+		return &DeletionStatus{Error: fmt.Sprintf("delete key store: unexpected status %d", resp.StatusCode)}
+	}
+}
+
+// deleteVaultRecords deletes the records of the vault with the given tag, eg. its authorizations.
+func (c *Client) deleteVaultRecords(tag, vID string) error {
+	keys, err := c.queryVaultKeys(tag, vID)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		err = c.store.Delete(key)
+		if err != nil {
+			return fmt.Errorf("delete %s: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+// queryVaultKeys returns the keys of the records of the vault with the tag.
+func (c *Client) queryVaultKeys(tag, vID string) ([]string, error) {
+	iter, err := c.store.Query(fmt.Sprintf("%s:%s", tag, vaultIndex(vID)))
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	defer func() {
+		if errClose := iter.Close(); errClose != nil {
+			logger.Errorf("failed to close iterator: %s", errClose)
+		}
+	}()
+
+	var keys []string
+
+	for {
+		ok, err := iter.Next()
+		if err != nil {
+			return nil, fmt.Errorf("iterator next: %w", err)
+		}
+
+		if !ok {
+			return keys, nil
+		}
+
+		key, err := iter.Key()
+		if err != nil {
+			return nil, fmt.Errorf("iterator key: %w", err)
+		}
+
+		keys = append(keys, key)
+	}
+}
+
+// CreateAuthorization creates a new authorization. A proof of key control of the requesting party, see
+// WithKeyProof, is verified if given, and required with WithRequiredKeyProof.
+// nolint: funlen,gocyclo
+func (c *Client) CreateAuthorization(vaultID, requestingParty string, scope *AuthorizationsScope,
+	opts ...CreateAuthorizationOpt,
+) (*CreatedAuthorization, error) {
+	options := &createAuthorizationOpts{}
+
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	info, err := c.getVaultInfo(vaultID)
+	if err != nil {
+		return nil, fmt.Errorf("get vault info: %w", err)
+	}
+
+	if info.Deleting {
+		return nil, ErrVaultDeleting
+	}
+
+	if c.requireKeyProof || options.keyProof != nil {
+		err = c.verifyKeyProof(vaultID, requestingParty, options.keyProof)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// the zcaps are signed after this instant so their own expiry never precedes the recorded one
+	created := time.Now().UTC().Truncate(time.Second)
+
+	tokens, err := c.delegate(info, requestingParty, scope.Actions, []string{"unwrap"}, toZCaveats(scope.Caveats))
+	if err != nil {
+		return nil, err
+	}
+
+	res := &CreatedAuthorization{
+		ID:              uuid.New().String(),
+		Scope:           scope,
+		RequestingParty: requestingParty,
+		Tokens:          tokens,
+		Created:         &created,
+		ExpiresAt:       expiresAt(created, scope.Caveats),
+	}
+
+	err = c.saveAuthorization(vaultID, res)
+	if err != nil {
+		return nil, fmt.Errorf("save authorization: %w", err)
+	}
+
+	err = c.recordAudit(AuditAuthorizationCreated, vaultID, res)
+	if err != nil {
+		return nil, fmt.Errorf("record audit event: %w", err)
+	}
+
+	res.setValidity(&authorizationUsage{}, created)
+
+	return res, nil
+}
+
+// delegate returns the EDV and KMS zcaps of the vault delegated to the invoker for the actions, signed with the key
+// of the vault.
+func (c *Client) delegate(info *vaultInfo, invoker string, edvActions, kmsActions []string,
+	caveats []zcapld.Caveat) (*Tokens, error) {
+	if info.External {
+		return nil, fmt.Errorf("%w: %s", ErrExternallyControlled, info.controller())
+	}
+
+	kh, err := c.kms.Get(info.KID)
+	if err != nil {
+		return nil, fmt.Errorf("kms get: %w", err)
+	}
+
+	signer := &zcapld.Signer{
+		SignatureSuite:     ed25519signature2018.New(suite.WithSigner(newSigner(c.crypto, kh))),
+		SuiteType:          ed25519signature2018.SignatureType,
+		VerificationMethod: info.DidURL,
+		ProcessorOpts:      []jsonld.ProcessorOpts{jsonld.WithDocumentLoader(c.documentLoader)},
+	}
+
+	kmsCapability, err := zcapld.DecompressZCAP(info.Auth.KMS.AuthToken)
+	if err != nil {
+		return nil, fmt.Errorf("kms uncompressZCAP: %w", err)
+	}
+
+	kmsNewCapability, err := zcapld.NewCapability(signer,
+		zcapld.WithParent(c.buildKMSURL(info, kmsCapability.ID)), zcapld.WithInvoker(invoker),
+		zcapld.WithAllowedActions(kmsActions...),
+		zcapld.WithInvocationTarget(c.buildKMSURL(info, kmsCapability.InvocationTarget.ID),
+			kmsCapability.InvocationTarget.Type),
+		zcapld.WithCaveats(caveats...),
+		zcapld.WithCapabilityChain(c.buildKMSURL(info, kmsCapability.ID)))
+	if err != nil {
+		return nil, fmt.Errorf("kms new capability: %w", err)
+	}
+
+	kmsCompressedCapability, err := zcapld.CompressZCAP(kmsNewCapability)
+	if err != nil {
+		return nil, fmt.Errorf("kms compressZCAP: %w", err)
+	}
+
+	edvCapability, err := zcapld.DecompressZCAP(info.Auth.EDV.AuthToken)
+	if err != nil {
+		return nil, fmt.Errorf("edv uncompressZCAP: %w", err)
+	}
+
+	edvNewCapability, err := zcapld.NewCapability(signer,
+		zcapld.WithParent(edvCapability.ID), zcapld.WithInvoker(invoker),
+		zcapld.WithAllowedActions(edvActions...),
+		zcapld.WithInvocationTarget(edvCapability.InvocationTarget.ID, edvCapability.InvocationTarget.Type),
+		zcapld.WithCaveats(caveats...),
+		zcapld.WithCapabilityChain(edvCapability.Parent, edvCapability.ID))
+	if err != nil {
+		return nil, fmt.Errorf("edv new capability: %w", err)
+	}
+
+	edvCompressedCapability, err := zcapld.CompressZCAP(edvNewCapability)
+	if err != nil {
+		return nil, fmt.Errorf("edv compressZCAP: %w", err)
+	}
+
+	return &Tokens{
+		KMS: kmsCompressedCapability,
+		EDV: edvCompressedCapability,
+	}, nil
+}
+
+// expiresAt returns the time at which the earliest expiry caveat lapses, or nil if there is none.
+func expiresAt(created time.Time, caveats []Caveat) *time.Time {
+	var res *time.Time
+
+	for _, caveat := range caveats {
+		if caveat.Type != zcapld.CaveatTypeExpiry {
+			continue
+		}
+
+		t := created.Add(time.Duration(caveat.Duration) * time.Second)
+
+		if res == nil || t.Before(*res) {
+			res = &t
+		}
+	}
+
+	return res
+}
+
+func toZCaveats(caveats []Caveat) []zcapld.Caveat {
+	zCaveats := make([]zcapld.Caveat, 0, len(caveats))
+
+	for _, caveat := range caveats {
+		// the vault server enforces usage caveats itself
+		if caveat.Type == CaveatTypeUsage {
+			continue
+		}
+
+		zCaveats = append(zCaveats, zcapld.Caveat{
+			Type:     caveat.Type,
+			Duration: caveat.Duration,
+		})
+	}
+
+	return zCaveats
+}
+
+// GetAuthorization returns an authorization by given id, and records its retrieval in the audit trail of the vault.
+// The status of the authorization reflects whether it has expired, been revoked or used up, and its remaining
+// validity is computed from its caveats and recorded uses.
+func (c *Client) GetAuthorization(vaultID, id string) (*CreatedAuthorization, error) {
+	a, err := c.getAuthorization(vaultID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.recordAudit(AuditAuthorizationRetrieved, vaultID, a)
+	if err != nil {
+		return nil, fmt.Errorf("record audit event: %w", err)
+	}
+
+	err = c.loadValidity(vaultID, a, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// ListAuthorizations returns a page of the authorizations created for the vault, ordered by ID. Expired
+// authorizations are left out unless the query includes them. The page following the one returned is requested
+// by passing its AuthorizationList.Next token.
+func (c *Client) ListAuthorizations(vaultID string, query *AuthorizationQuery) (*AuthorizationList, error) {
+	_, err := c.getVaultInfo(vaultID)
+	if err != nil {
+		return nil, fmt.Errorf("get vault info: %w", err)
+	}
+
+	if query == nil {
+		query = &AuthorizationQuery{}
+	}
+
+	limit := query.Limit
+
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+
+	if limit > maxListLimit {
+		limit = maxListLimit
+	}
+
+	after, err := base64.RawURLEncoding.DecodeString(query.Next)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidContinuationToken, err)
+	}
+
+	now := time.Now()
+
+	var auths []*CreatedAuthorization
+
+	// one more authorization than the limit tells whether there is a next page
+	err = c.indexRecords(authorizationsIndex, vaultID, string(after), func(_ string, src []byte) (bool, error) {
+		var a *CreatedAuthorization
+
+		if errUnmarshal := json.Unmarshal(src, &a); errUnmarshal != nil {
+			return false, fmt.Errorf("unmarshal: %w", errUnmarshal)
+		}
+
+		if (query.RequestingParty == "" || a.RequestingParty == query.RequestingParty) &&
+			(query.IncludeExpired || !a.expired(now)) {
+			auths = append(auths, a)
+		}
+
+		return len(auths) > limit, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list authorizations: %w", err)
+	}
+
+	list := &AuthorizationList{Authorizations: []*AuthorizationListEntry{}}
+
+	if len(auths) > limit {
+		auths = auths[:limit]
+		list.Next = base64.RawURLEncoding.EncodeToString([]byte(auths[limit-1].ID))
+	}
+
+	for _, a := range auths {
+		a.Status = a.status(now)
+
+		// only the status of the authorizations with a usage caveat depends on their uses
+		if maxUses(scopeCaveats(a.Scope)) != nil {
+			err = c.loadValidity(vaultID, a, now)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		list.Authorizations = append(list.Authorizations, &AuthorizationListEntry{
+			ID:              a.ID,
+			RequestingParty: a.RequestingParty,
+			Scope:           a.Scope,
+			Created:         a.Created,
+			ExpiresAt:       a.ExpiresAt,
+			Status:          a.Status,
+		})
+	}
+
+	return list, nil
+}
+
+// DeleteAuthorization deletes an authorization and records its zcaps as revoked.
+// The EDV and KMS do not support revocation, so they keep honoring the zcaps until their expiry caveat lapses.
+// Parties that accept the zcaps on behalf of the requesting party must check GetRevocation.
+func (c *Client) DeleteAuthorization(vaultID, id string) error {
+	a, err := c.getAuthorization(vaultID, id)
+	if err != nil {
+		return err
+	}
+
+	if a.Tokens != nil {
+		for _, token := range []string{a.Tokens.EDV, a.Tokens.KMS} {
+			err = c.revoke(token)
+			if err != nil {
+				return fmt.Errorf("revoke: %w", err)
+			}
+		}
+	}
+
+	err = c.store.Delete(fmt.Sprintf(authorizationFormat, vaultID, id))
+	if err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	err = c.indexRemove(authorizationsIndex, vaultID, id)
+	if err != nil {
+		return fmt.Errorf("unindex: %w", err)
+	}
+
+	err = c.store.Delete(fmt.Sprintf(authorizationUsageFormat, vaultID, id))
+	if err != nil && !errors.Is(err, storage.ErrDataNotFound) {
+		return fmt.Errorf("delete usage: %w", err)
+	}
+
+	err = c.recordAudit(AuditAuthorizationDeleted, vaultID, a)
+	if err != nil {
+		return fmt.Errorf("record audit event: %w", err)
+	}
+
+	return nil
+}
+
+// GetRevocation returns the revocation of the given zcap. Zcaps that were not revoked are not found.
+func (c *Client) GetRevocation(zcapID string) (*Revocation, error) {
+	src, err := c.store.Get(fmt.Sprintf(revocationFormat, zcapID))
+	if err != nil {
+		return nil, fmt.Errorf("get: %w", err)
+	}
+
+	var res *Revocation
+
+	err = json.Unmarshal(src, &res)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal: %w", err)
+	}
+
+	return res, nil
+}
+
+func (c *Client) revoke(token string) error {
+	op, err := revocationOperation(token)
+	if err != nil {
+		return err
+	}
+
+	return c.store.Put(op.Key, op.Value)
+}
+
+// revocationOperation returns the operation recording the zcap as revoked, eg. in a batch.
+func revocationOperation(token string) (storage.Operation, error) {
+	zcap, err := zcapld.DecompressZCAP(token)
+	if err != nil {
+		return storage.Operation{}, fmt.Errorf("uncompressZCAP: %w", err)
+	}
+
+	src, err := json.Marshal(&Revocation{
+		ZcapID:    zcap.ID,
+		RevokedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return storage.Operation{}, fmt.Errorf("marshal: %w", err)
+	}
+
+	return storage.Operation{Key: fmt.Sprintf(revocationFormat, zcap.ID), Value: src}, nil
+}
+
+func (c *Client) saveAuthorization(vID string, a *CreatedAuthorization) error {
+	src, err := json.Marshal(a)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	tags := []storage.Tag{{Name: vaultAuthorizationsTag, Value: vaultIndex(vID)}}
+
+	if a.Scope != nil && a.Scope.Target != "" {
+		tags = append(tags, storage.Tag{Name: authorizationTargetTag, Value: targetIndex(vID, a.Scope.Target)})
+	}
+
+	err = c.store.Put(fmt.Sprintf(authorizationFormat, vID, a.ID), src, tags...)
+	if err != nil {
+		return fmt.Errorf("store put: %w", err)
+	}
+
+	err = c.indexAdd(authorizationsIndex, vID, a.ID)
+	if err != nil {
+		return fmt.Errorf("index: %w", err)
+	}
+
+	return nil
+}
+
+// revokeAuthorizations flags the authorizations targeting the given document as revoked.
+func (c *Client) revokeAuthorizations(vID, docID string) error {
+	iter, err := c.store.Query(fmt.Sprintf("%s:%s", authorizationTargetTag, targetIndex(vID, docID)))
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+
+	defer func() {
+		if errClose := iter.Close(); errClose != nil {
+			logger.Errorf("failed to close iterator: %s", errClose)
+		}
+	}()
+
+	for {
+		ok, err := iter.Next()
+		if err != nil {
+			return fmt.Errorf("iterator next: %w", err)
+		}
+
+		if !ok {
+			return nil
+		}
+
+		src, err := iter.Value()
+		if err != nil {
+			return fmt.Errorf("iterator value: %w", err)
+		}
+
+		var a *CreatedAuthorization
+
+		err = json.Unmarshal(src, &a)
+		if err != nil {
+			return fmt.Errorf("unmarshal: %w", err)
+		}
+
+		a.Revoked = true
+
+		err = c.saveAuthorization(vID, a)
+		if err != nil {
+			return fmt.Errorf("save authorization: %w", err)
+		}
+	}
+}
+
+// vaultIndex returns the tag value of the documents and authorizations of the given vault.
+// Vault IDs are DIDs and tag values must not contain colons, so the value is hashed.
+func vaultIndex(vID string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf(infoFormat, vID)))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// targetIndex returns the tag value of the authorizations targeting the given document.
+// Vault IDs are DIDs and tag values must not contain colons, so the value is hashed.
+func targetIndex(vID, docID string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf(metaDocInfoFormat, vID, docID)))
+
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *Client) getAuthorization(vID, id string) (*CreatedAuthorization, error) {
+	src, err := c.store.Get(fmt.Sprintf(authorizationFormat, vID, id))
+	if err != nil {
+		return nil, fmt.Errorf("get: %w", err)
+	}
+
+	var res *CreatedAuthorization
+
+	err = json.Unmarshal(src, &res)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal: %w", err)
+	}
+
+	return res, nil
+}
+
+// GetDocMetadata returns document`s metadata. With WithDocMetadataCache, metadata returned within its TTL is
+// returned again without reading the store or EDV.
+func (c *Client) GetDocMetadata(vaultID, docID string) (*DocumentMetadata, error) {
+	if meta := c.docMetaCache.get(vaultID, docID); meta != nil {
+		return meta, nil
+	}
+
+	info, err := c.getVaultInfo(vaultID)
+	if err != nil {
+		return nil, fmt.Errorf("get vault info: %w", err)
+	}
+
+	backend, err := c.edvBackend(info)
+	if err != nil {
+		return nil, err
+	}
+
+	edvVaultID := lastElm(info.Auth.EDV.URI, "/")
+
+	dInfo, err := c.getMetaDocInfo(vaultID, docID)
+	if err != nil {
+		return nil, fmt.Errorf("get meta doc info: %w", err)
+	}
+
+	_, err = backend.client.ReadDocument(edvVaultID, dInfo.EdvID, edv.WithRequestHeader(
+		c.edvSign(info.DidURL, info.Auth.EDV)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("read document: %w", err)
+	}
+
+	meta := docMetadata(backend, edvVaultID, docID, dInfo)
+
+	c.docMetaCache.put(vaultID, docID, meta)
+
+	return meta, nil
+}
+
+// GetDoc reads the document from EDV, decrypts it and returns its content.
+func (c *Client) GetDoc(vaultID, docID string) ([]byte, error) {
+	content, err := c.GetDocContent(vaultID, docID)
+	if err != nil {
+		return nil, err
+	}
+
+	return content.Data, nil
+}
+
+// GetDocContent reads the document from EDV, decrypts it and returns its content along with its media type.
+// The content of binary documents is returned as originally saved.
+func (c *Client) GetDocContent(vaultID, docID string) (*DocumentContent, error) {
+	info, err := c.getVaultInfo(vaultID)
+	if err != nil {
+		return nil, fmt.Errorf("get vault info: %w", err)
+	}
+
+	backend, err := c.edvBackend(info)
+	if err != nil {
+		return nil, err
+	}
+
+	dInfo, err := c.getMetaDocInfo(vaultID, docID)
+	if err != nil {
+		return nil, fmt.Errorf("get meta doc info: %w", err)
+	}
+
+	encDoc, err := backend.client.ReadDocument(lastElm(info.Auth.EDV.URI, "/"), dInfo.EdvID, edv.WithRequestHeader(
+		c.edvSign(info.DidURL, info.Auth.EDV)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("read document: %w", err)
+	}
+
+	doc, err := decryptDocument(
+		c.webKMS(info),
+		c.webCrypto(info),
+		encDoc.JWE,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt document: %w", err)
+	}
+
+	chunks, chunked, err := docChunks(doc)
+	if err != nil {
+		return nil, fmt.Errorf("read document content: %w", err)
+	}
+
+	if chunked {
+		return c.readChunkedContent(info, backend, doc, chunks)
+	}
+
+	content, err := documentContent(doc)
+	if err != nil {
+		return nil, fmt.Errorf("read document content: %w", err)
+	}
+
+	return content, nil
+}
+
+// documentContent unwraps the content of binary documents. Other documents are JSON.
+func documentContent(doc *models.StructuredDocument) (*DocumentContent, error) {
+	mediaType, ok := doc.Meta[mediaTypeField].(string)
+	if !ok {
+		data, err := json.Marshal(doc.Content)
+		if err != nil {
+			return nil, fmt.Errorf("marshal: %w", err)
+		}
+
+		return &DocumentContent{MediaType: jsonMediaType, Data: data}, nil
+	}
+
+	encoded, ok := doc.Content[dataField].(string)
+	if !ok {
+		return nil, fmt.Errorf("binary document has no %s", dataField)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", dataField, err)
+	}
+
+	return &DocumentContent{MediaType: mediaType, Data: data}, nil
+}
+
+// DeleteDoc deletes the document from EDV along with its metadata and revokes the authorizations targeting it.
+func (c *Client) DeleteDoc(vaultID, docID string) error {
+	defer c.docMetaCache.invalidate(vaultID, docID)
+
+	info, err := c.getVaultInfo(vaultID)
+	if err != nil {
+		return fmt.Errorf("get vault info: %w", err)
+	}
+
+	backend, err := c.edvBackend(info)
+	if err != nil {
+		return err
+	}
+
+	unlock := c.docMu.lock(docLockKey(vaultID, docID))
+	defer unlock()
+
+	dInfo, err := c.getMetaDocInfo(vaultID, docID)
+	if err != nil {
+		return fmt.Errorf("get meta doc info: %w", err)
+	}
+
+	err = backend.client.DeleteDocument(lastElm(info.Auth.EDV.URI, "/"), dInfo.EdvID, edv.WithRequestHeader(
+		c.edvSign(info.DidURL, info.Auth.EDV)),
+	)
+	// the metadata is purged even if the EDV document is already gone
+	if err != nil && !errors.Is(err, ErrDocumentNotFound) {
+		return fmt.Errorf("delete document: %w", err)
+	}
+
+	err = c.deleteChunks(info, backend, dInfo.Chunks)
+	if err != nil {
+		return err
+	}
+
+	err = c.revokeAuthorizations(vaultID, docID)
+	if err != nil {
+		return fmt.Errorf("revoke authorizations: %w", err)
+	}
+
+	err = c.store.Delete(fmt.Sprintf(metaDocInfoFormat, vaultID, docID))
+	if err != nil {
+		return fmt.Errorf("delete meta doc info: %w", err)
+	}
+
+	err = c.indexRemove(docsIndex, vaultID, docID)
+	if err != nil {
+		return fmt.Errorf("unindex document: %w", err)
+	}
+
+	err = c.addDocCount(vaultID, -1)
 	if err != nil {
-		return nil, fmt.Errorf("edv new capability: %w", err)
+		return fmt.Errorf("update doc count: %w", err)
 	}
 
-	edvCompressedCapability, err := zcapld.CompressZCAP(edvNewCapability)
+	return nil
+}
+
+// ListDocs returns a page of at most limit documents stored in the vault, ordered by docID. The page following
+// the one returned is requested by passing its DocumentList.Next token. Document contents are not included.
+func (c *Client) ListDocs(vaultID string, limit int, next string) (*DocumentList, error) {
+	info, err := c.getVaultInfo(vaultID)
 	if err != nil {
-		return nil, fmt.Errorf("edv compressZCAP: %w", err)
+		return nil, fmt.Errorf("get vault info: %w", err)
 	}
 
-	res := &CreatedAuthorization{
-		ID:              uuid.New().String(),
-		Scope:           scope,
-		RequestingParty: requestingParty,
-		Tokens: &Tokens{
-			KMS: kmsCompressedCapability,
-			EDV: edvCompressedCapability,
-		},
+	if limit <= 0 {
+		limit = defaultListLimit
 	}
 
-	err = c.saveAuthorization(vaultID, res)
+	if limit > maxListLimit {
+		limit = maxListLimit
+	}
+
+	after, err := base64.RawURLEncoding.DecodeString(next)
 	if err != nil {
-		return nil, fmt.Errorf("save authorization: %w", err)
+		return nil, fmt.Errorf("%w: %s", ErrInvalidContinuationToken, err)
 	}
 
-	return res, nil
-}
+	backend, err := c.edvBackend(info)
+	if err != nil {
+		return nil, err
+	}
 
-func toZCaveats(caveats []Caveat) []zcapld.Caveat {
-	zCaveats := make([]zcapld.Caveat, len(caveats))
+	var docs []*metaDocInfo
 
-	for i, caveat := range caveats {
-		zCaveats[i] = zcapld.Caveat{
-			Type:     caveat.Type,
-			Duration: caveat.Duration,
+	// one more document than the limit tells whether there is a next page
+	err = c.indexRecords(docsIndex, vaultID, string(after), func(id string, src []byte) (bool, error) {
+		var d *metaDocInfo
+
+		if errUnmarshal := json.Unmarshal(src, &d); errUnmarshal != nil {
+			return false, fmt.Errorf("unmarshal: %w", errUnmarshal)
 		}
+
+		// documents saved before their ID was recorded
+		d.DocID = id
+		docs = append(docs, d)
+
+		return len(docs) > limit, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list documents: %w", err)
 	}
 
-	return zCaveats
-}
+	list := &DocumentList{Documents: []*DocumentListEntry{}}
 
-// GetAuthorization returns an authorization by given id.
-func (c *Client) GetAuthorization(vaultID, id string) (*CreatedAuthorization, error) {
-	return c.getAuthorization(vaultID, id)
-}
+	if len(docs) > limit {
+		docs = docs[:limit]
+		list.Next = base64.RawURLEncoding.EncodeToString([]byte(docs[limit-1].DocID))
+	}
 
-func (c *Client) saveAuthorization(vID string, a *CreatedAuthorization) error {
-	src, err := json.Marshal(a)
-	if err != nil {
-		return fmt.Errorf("marshal: %w", err)
+	edvVaultID := lastElm(info.Auth.EDV.URI, "/")
+
+	for _, d := range docs {
+		list.Documents = append(list.Documents, &DocumentListEntry{
+			ID:      d.DocID,
+			URI:     backend.docURI(edvVaultID, d.EdvID),
+			Created: d.Created,
+			Updated: d.Updated,
+		})
 	}
 
-	return c.store.Put(fmt.Sprintf(authorizationFormat, vID, a.ID), src)
+	return list, nil
 }
 
-func (c *Client) getAuthorization(vID, id string) (*CreatedAuthorization, error) {
-	src, err := c.store.Get(fmt.Sprintf(authorizationFormat, vID, id))
+func (c *Client) queryMetaDocInfos(vID string) ([]*metaDocInfo, error) {
+	iter, err := c.store.Query(fmt.Sprintf("%s:%s", vaultDocsTag, vaultIndex(vID)))
 	if err != nil {
-		return nil, fmt.Errorf("get: %w", err)
+		return nil, fmt.Errorf("query: %w", err)
 	}
 
-	var res *CreatedAuthorization
+	defer func() {
+		if errClose := iter.Close(); errClose != nil {
+			logger.Errorf("failed to close iterator: %s", errClose)
+		}
+	}()
 
-	err = json.Unmarshal(src, &res)
-	if err != nil {
-		return nil, fmt.Errorf("unmarshal: %w", err)
-	}
+	var docs []*metaDocInfo
 
-	return res, nil
+	for {
+		ok, err := iter.Next()
+		if err != nil {
+			return nil, fmt.Errorf("iterator next: %w", err)
+		}
+
+		if !ok {
+			return docs, nil
+		}
+
+		src, err := iter.Value()
+		if err != nil {
+			return nil, fmt.Errorf("iterator value: %w", err)
+		}
+
+		var info *metaDocInfo
+
+		err = json.Unmarshal(src, &info)
+		if err != nil {
+			return nil, fmt.Errorf("unmarshal: %w", err)
+		}
+
+		docs = append(docs, info)
+	}
 }
 
-// GetDocMetadata returns document`s metadata.
-func (c *Client) GetDocMetadata(vaultID, docID string) (*DocumentMetadata, error) {
+// SaveDoc saves a JSON document by encrypting it and storing it in the vault.
+func (c *Client) SaveDoc(vaultID, id string, content []byte, opts ...SaveDocOpt) (*DocumentMetadata, error) {
 	info, err := c.getVaultInfo(vaultID)
 	if err != nil {
 		return nil, fmt.Errorf("get vault info: %w", err)
 	}
 
-	edvVaultID := lastElm(info.Auth.EDV.URI, "/")
+	docContents := make(map[string]interface{})
 
-	dInfo, err := c.getMetaDocInfo(vaultID, docID)
+	err = json.NewDecoder(bytes.NewReader(content)).Decode(&docContents)
 	if err != nil {
-		return nil, fmt.Errorf("get meta doc info: %w", err)
+		return nil, fmt.Errorf("failed to decode content: %w", err)
 	}
 
-	_, err = c.edvClient.ReadDocument(edvVaultID, dInfo.EdvID, edv.WithRequestHeader(
-		c.edvSign(info.DidURL, info.Auth.EDV)),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("read document: %w", err)
+	if c.verifyCredentials && isCredential(docContents) {
+		err = c.verifyCredential(content)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return &DocumentMetadata{
-		ID:        docID,
-		URI:       buildEDVDocURI(c.edvScheme, c.edvHost, edvVaultID, dInfo.EdvID),
-		EncKeyURI: dInfo.KidURL,
-	}, nil
+	return c.saveDoc(vaultID, id, info, &models.StructuredDocument{Content: docContents}, opts)
 }
 
-// SaveDoc saves a document by encrypting it and storing it in the vault.
-func (c *Client) SaveDoc(vaultID, id string, content []byte) (*DocumentMetadata, error) { // nolint:funlen
-	info, err := c.getVaultInfo(vaultID)
-	if err != nil {
-		return nil, fmt.Errorf("get vault info: %w", err)
+// SaveBinaryDoc saves content of any media type, eg. a PDF, by encrypting it and storing it in the vault.
+// The content is base64-encoded in the structured document, whose metadata records the media type. Content
+// larger than the chunk size of the client is split into chunks, as by SaveBinaryDocStream.
+func (c *Client) SaveBinaryDoc(vaultID, id, mediaType string, content []byte,
+	opts ...SaveDocOpt,
+) (*DocumentMetadata, error) {
+	return c.SaveBinaryDocStream(vaultID, id, mediaType, bytes.NewReader(content), opts...)
+}
+
+func (c *Client) saveDoc(vaultID, id string, info *vaultInfo,
+	doc *models.StructuredDocument, opts []SaveDocOpt,
+) (*DocumentMetadata, error) {
+	if info.Deleting {
+		return nil, ErrVaultDeleting
 	}
 
-	docID, err := edvutils.GenerateEDVCompatibleID()
+	backend, err := c.edvBackend(info)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate an EDV document ID: %w", err)
+		return nil, err
 	}
 
-	docContents := make(map[string]interface{})
+	controller, err := c.saveDocRecipient(vaultID, info, opts)
+	if err != nil {
+		return nil, err
+	}
 
-	err = json.NewDecoder(bytes.NewReader(content)).Decode(&docContents)
+	doc.ID, err = edvutils.GenerateEDVCompatibleID()
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode content: %w", err)
+		return nil, fmt.Errorf("failed to generate an EDV document ID: %w", err)
 	}
 
 	kidURL, encContent, err := encryptContent(
-		c.webKMS(info.DidURL, info.Auth.KMS),
-		c.webCrypto(info.DidURL, info.Auth.KMS),
-		&models.StructuredDocument{
-			ID:      docID,
-			Content: docContents,
-		},
+		c.webKMS(info),
+		c.webCrypto(info),
+		info.keyType(),
+		doc,
+		controller.publicKeys()...,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("encrypt key: %w", err)
 	}
 
+	enc := &encryptedDoc{kidURL: kidURL, jwe: encContent, controllerKeyID: controller.id()}
+
+	if !c.noContentDigests {
+		enc.digest, err = contentDigest(doc)
+		if err != nil {
+			return nil, fmt.Errorf("content digest: %w", err)
+		}
+	}
+
+	return c.storeDoc(vaultID, id, info, backend, enc, opts)
+}
+
+// encryptedDoc is a document encrypted to the key at kidURL, along with the digest of its plaintext. The chunks
+// are set if the document is the manifest of a chunked document. The controller key ID is set if the document is
+// also encrypted to the key agreement key of the controller.
+type encryptedDoc struct {
+	kidURL          string
+	jwe             string
+	digest          string
+	chunks          []string
+	controllerKeyID string
+}
+
+// storeDoc stores the encrypted document in EDV and records its metadata. The chunks of the previous version of the
+// document, if any, are deleted.
+func (c *Client) storeDoc(vaultID, id string, info *vaultInfo, backend *edvBackend, // nolint:funlen,gocyclo
+	enc *encryptedDoc, opts []SaveDocOpt,
+) (*DocumentMetadata, error) {
+	// dropped once saved, or once the save failed midway
+	defer c.docMetaCache.invalidate(vaultID, id)
+
+	options := &saveDocOpts{}
+
+	for _, fn := range opts {
+		fn(options)
+	}
+
+	var (
+		err     error
+		indexed []models.IndexedAttributeCollection
+		// stale are the chunks of the previous version of the document
+		stale []string
+	)
+
+	indexed = options.indexed
+
+	if len(options.indexTags) > 0 {
+		indexed, err = c.indexAttributeCollections(vaultID, info, options.indexTags)
+		if err != nil {
+			return nil, fmt.Errorf("index tags: %w", err)
+		}
+	}
+
+	// the expected sequence is checked and the document written under the lock of the document, so that concurrent
+	// saves expecting the same sequence do not both succeed
+	unlock := c.docMu.lock(docLockKey(vaultID, id))
+	defer unlock()
+
 	dInfo, err := c.getMetaDocInfo(vaultID, id)
+	if options.expectedSequence != nil && errors.Is(err, storage.ErrDataNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrExpectedDocumentNotFound, id)
+	}
+
 	if err != nil && !errors.Is(err, storage.ErrDataNotFound) {
 		return nil, fmt.Errorf("get meta doc info: %w", err)
 	}
 
-	if errors.Is(err, storage.ErrDataNotFound) {
-		dInfo, err = c.createMetaDocInfo(vaultID, id, kidURL)
+	if options.expectedSequence != nil && *options.expectedSequence != dInfo.Sequence {
+		return nil, &SequenceMismatchError{Expected: *options.expectedSequence, Current: dInfo.Sequence}
+	}
+
+	created := errors.Is(err, storage.ErrDataNotFound)
+
+	if created {
+		dInfo, err = newMetaDocInfo(id, c.buildKMSURL(info, enc.kidURL), enc)
 		if err != nil {
 			return nil, fmt.Errorf("create meta doc info: %w", err)
 		}
+	} else {
+		stale = dInfo.Chunks
+
+		// updated documents are encrypted to a new key
+		dInfo.KidURL = c.buildKMSURL(info, enc.kidURL)
+		dInfo.ContentDigest = enc.digest
+		dInfo.Chunks = enc.chunks
+		dInfo.ControllerKeyID = enc.controllerKeyID
 	}
 
 	edvVaultID := lastElm(info.Auth.EDV.URI, "/")
 
-	_, err = c.edvClient.CreateDocument(edvVaultID, &models.EncryptedDocument{
-		ID:  dInfo.EdvID,
-		JWE: []byte(encContent),
-	}, edv.WithRequestHeader(c.edvSign(info.DidURL, info.Auth.EDV)))
-	if err == nil {
-		return &DocumentMetadata{
-			URI:       buildEDVDocURI(c.edvScheme, c.edvHost, edvVaultID, dInfo.EdvID),
-			ID:        id,
-			EncKeyURI: dInfo.KidURL,
-		}, nil
+	// the sequence of the metadata is only incremented once the document is written
+	edvDoc := &models.EncryptedDocument{
+		ID:                          dInfo.EdvID,
+		Sequence:                    dInfo.Sequence,
+		IndexedAttributeCollections: indexed,
+		JWE:                         []byte(enc.jwe),
 	}
 
-	if !strings.HasSuffix(err.Error(), messages.ErrDuplicateDocument.Error()+".") {
-		return nil, fmt.Errorf("create document: %w", err)
+	if !created {
+		edvDoc.Sequence++
 	}
 
-	err = c.edvClient.UpdateDocument(edvVaultID, dInfo.EdvID, &models.EncryptedDocument{
-		ID:  dInfo.EdvID,
-		JWE: []byte(encContent),
-	}, edv.WithRequestHeader(c.edvSign(info.DidURL, info.Auth.EDV)))
+	_, err = backend.client.CreateDocument(edvVaultID, edvDoc, edv.WithRequestHeader(c.edvSign(info.DidURL,
+		info.Auth.EDV)))
 	if err != nil {
-		return nil, fmt.Errorf("update document: %w", err)
+		if !errors.Is(err, errDuplicateDocument) {
+			return nil, fmt.Errorf("create document: %w", err)
+		}
+
+		err = backend.client.UpdateDocument(edvVaultID, dInfo.EdvID, edvDoc,
+			edv.WithRequestHeader(c.edvSign(info.DidURL, info.Auth.EDV)))
+		if err != nil {
+			return nil, fmt.Errorf("update document: %w", err)
+		}
 	}
 
-	return &DocumentMetadata{
-		ID:        id,
-		URI:       buildEDVDocURI(c.edvScheme, c.edvHost, edvVaultID, dInfo.EdvID),
-		EncKeyURI: dInfo.KidURL,
-	}, nil
+	if created {
+		err = c.saveMetaDocInfo(vaultID, id, dInfo)
+		if err != nil {
+			return nil, fmt.Errorf("create meta doc info: %w", err)
+		}
+
+		err = c.addDocCount(vaultID, 1)
+		if err != nil {
+			return nil, fmt.Errorf("update doc count: %w", err)
+		}
+	} else {
+		dInfo.Sequence = edvDoc.Sequence
+
+		err = c.touchMetaDocInfo(vaultID, id, dInfo)
+		if err != nil {
+			return nil, fmt.Errorf("update meta doc info: %w", err)
+		}
+	}
+
+	c.deleteStaleChunks(info, backend, stale)
+
+	meta := docMetadata(backend, edvVaultID, id, dInfo)
+
+	c.notifyDocSaved(vaultID, meta)
+
+	return meta, nil
+}
+
+// contentDigest returns the hex-encoded SHA-256 digest of the plaintext document. Its ID, generated on every save,
+// is left out, and its members are sorted: documents with equal contents have equal digests.
+func contentDigest(doc *models.StructuredDocument) (string, error) {
+	src, err := json.Marshal(&models.StructuredDocument{Meta: doc.Meta, Content: doc.Content})
+	if err != nil {
+		return "", fmt.Errorf("marshal: %w", err)
+	}
+
+	sum := sha256.Sum256(src)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// docMetadata returns the metadata of the document. Timestamps are omitted if they were not recorded.
+func docMetadata(backend *edvBackend, edvVaultID, docID string, d *metaDocInfo) *DocumentMetadata {
+	meta := &DocumentMetadata{
+		ID:            docID,
+		URI:           backend.docURI(edvVaultID, d.EdvID),
+		EncKeyURI:     d.KidURL,
+		Sequence:      d.Sequence,
+		ContentDigest: d.ContentDigest,
+		Recipients:    []string{d.KidURL},
+		EDVURL:        backend.url,
+		EDVVaultID:    edvVaultID,
+		EDVDocID:      d.EdvID,
+	}
+
+	if d.ControllerKeyID != "" {
+		meta.Recipients = append(meta.Recipients, d.ControllerKeyID)
+	}
+
+	if !d.Created.IsZero() {
+		created := d.Created
+		meta.CreatedAt = &created
+	}
+
+	if !d.Updated.IsZero() {
+		updated := d.Updated
+		meta.UpdatedAt = &updated
+	}
+
+	return meta
 }
 
 type vaultInfo struct {
-	KID    string         `json:"kid"`
-	DidURL string         `json:"did_url"`
-	Auth   *Authorization `json:"auth"`
+	KID      string         `json:"kid"`
+	DidURL   string         `json:"did_url"`
+	Auth     *Authorization `json:"auth"`
+	Created  time.Time      `json:"created"`
+	DocCount int            `json:"doc_count"`
+	Version  int            `json:"version,omitempty"`
+	// Deleting is set once the deletion of the vault has started.
+	Deleting   bool `json:"deleting,omitempty"`
+	KMSDeleted bool `json:"kms_deleted,omitempty"`
+	// HMACKeyURL is the key of the encrypted indexes of the vault's documents, created once a document is tagged.
+	HMACKeyURL string `json:"hmac_key_url,omitempty"`
+	// EDVBackend is empty for vaults created before EDV backends were named, in DefaultEDVBackend.
+	EDVBackend string `json:"edv_backend,omitempty"`
+	// KeyType is empty for vaults created before key types were configurable, with DefaultKeyType.
+	KeyType kms.KeyType `json:"key_type,omitempty"`
+	// Reference is the key of the record of the reference ID the vault was created with, if any.
+	Reference string `json:"reference,omitempty"`
+	// KMSURL is the base URL of the WebKMS of the vault. Vaults created before it was recorded use the one of the
+	// client.
+	KMSURL string `json:"kms_url,omitempty"`
+	// IssuedAuth holds the tokens last issued to the controller by RotateTokens. The vault server keeps invoking
+	// EDV and KMS with Auth, the tokens the vault was created with.
+	IssuedAuth *Authorization `json:"issued_auth,omitempty"`
+	// Owner is the verification method the tokens of the vault are issued to instead of the key of the vault, see
+	// WithVaultOwner.
+	Owner string `json:"owner,omitempty"`
+	// External is set for vaults controlled by an existing DID, whose EDV and KMS requests the vault server cannot
+	// sign.
+	External bool `json:"external,omitempty"`
+	// Legacy is set for vaults created before their records were tagged, which may hold documents and
+	// authorizations the store cannot find until TagLegacyRecords tags them.
+	Legacy bool `json:"legacy,omitempty"`
+}
+
+// controller returns the DID controlling the vault.
+func (info *vaultInfo) controller() string {
+	return strings.Split(info.DidURL, "#")[0]
 }
 
 func (c *Client) saveVaultInfo(id string, info *vaultInfo) error {
+	op, err := vaultInfoOperation(id, info)
+	if err != nil {
+		return err
+	}
+
+	return c.store.Put(op.Key, op.Value, op.Tags...)
+}
+
+// vaultInfoOperation returns the operation saving the info of the vault, eg. in a batch.
+func vaultInfoOperation(id string, info *vaultInfo) (storage.Operation, error) {
 	src, err := json.Marshal(info)
 	if err != nil {
-		return fmt.Errorf("marshal: %w", err)
+		return storage.Operation{}, fmt.Errorf("marshal: %w", err)
 	}
 
-	return c.store.Put(fmt.Sprintf(infoFormat, id), src)
+	return storage.Operation{
+		Key:   fmt.Sprintf(infoFormat, id),
+		Value: src,
+		Tags:  []storage.Tag{{Name: vaultControllerTag, Value: controllerIndex(info.controller())}},
+	}, nil
 }
 
 type metaDocInfo struct {
-	EdvID  string `json:"edv_id"`
-	KidURL string `json:"kid_url"`
+	EdvID   string    `json:"edv_id"`
+	KidURL  string    `json:"kid_url"`
+	DocID   string    `json:"doc_id,omitempty"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+	// Sequence is the sequence of the EDV document. It is 0 for documents saved before it was tracked.
+	Sequence uint64 `json:"sequence,omitempty"`
+	// ContentDigest is the digest of the plaintext document, empty for documents saved without one.
+	ContentDigest string `json:"content_digest,omitempty"`
+	// Chunks are the EDV IDs of the chunks of the document if it was saved in chunks.
+	Chunks []string `json:"chunks,omitempty"`
+	// ControllerKeyID is the DID URL of the key agreement key of the controller if the document is also encrypted
+	// to it.
+	ControllerKeyID string `json:"controller_key_id,omitempty"`
+	// VaultID is empty for documents last saved before it was recorded.
+	VaultID string `json:"vault_id,omitempty"`
 }
 
-func (c *Client) createMetaDocInfo(vid, id, kid string) (*metaDocInfo, error) {
+// newMetaDocInfo returns the metadata of a new document, stored in a new EDV document.
+func newMetaDocInfo(id, kid string, enc *encryptedDoc) (*metaDocInfo, error) {
 	edvID, err := edvutils.GenerateEDVCompatibleID()
 	if err != nil {
 		return nil, fmt.Errorf("generate EDV compatible id: %w", err)
 	}
 
-	info := &metaDocInfo{EdvID: edvID, KidURL: c.buildKMSURL(kid)}
+	now := time.Now().UTC()
+
+	return &metaDocInfo{
+		EdvID: edvID, KidURL: kid, DocID: id, Created: now, Updated: now,
+		ContentDigest: enc.digest, Chunks: enc.chunks, ControllerKeyID: enc.controllerKeyID,
+	}, nil
+}
+
+// touchMetaDocInfo records the update of an existing document.
+func (c *Client) touchMetaDocInfo(vid, id string, info *metaDocInfo) error {
+	info.DocID = id
+	info.Updated = time.Now().UTC()
+
+	// documents saved before timestamps were recorded
+	if info.Created.IsZero() {
+		info.Created = info.Updated
+	}
+
+	return c.saveMetaDocInfo(vid, id, info)
+}
+
+func (c *Client) saveMetaDocInfo(vid, id string, info *metaDocInfo) error {
+	info.VaultID = vid
 
 	src, err := json.Marshal(info)
 	if err != nil {
-		return nil, fmt.Errorf("marshal: %w", err)
+		return fmt.Errorf("marshal: %w", err)
 	}
 
-	err = c.store.Put(fmt.Sprintf(metaDocInfoFormat, vid, id), src)
+	err = c.store.Put(fmt.Sprintf(metaDocInfoFormat, vid, id), src,
+		storage.Tag{Name: vaultDocsTag, Value: vaultIndex(vid)},
+		storage.Tag{Name: edvDocsTag, Value: edvDocIndex(info.EdvID)},
+	)
 	if err != nil {
-		return nil, fmt.Errorf("store put: %w", err)
+		return fmt.Errorf("store put: %w", err)
 	}
 
-	return info, nil
+	err = c.indexAdd(docsIndex, vid, id)
+	if err != nil {
+		return fmt.Errorf("index: %w", err)
+	}
+
+	return nil
 }
 
 func (c *Client) getMetaDocInfo(vid, id string) (*metaDocInfo, error) {
@@ -550,6 +2145,64 @@ func (c *Client) getMetaDocInfo(vid, id string) (*metaDocInfo, error) {
 }
 
 func (c *Client) getVaultInfo(id string) (*vaultInfo, error) {
+	info, err := c.readVaultInfo(id)
+	if err != nil || info.Version >= vaultInfoVersion {
+		return info, err
+	}
+
+	// upgraded once, under the lock of the vault
+	info, unlock, err := c.lockVaultInfo(id)
+	if err != nil {
+		return nil, err
+	}
+
+	unlock()
+
+	return info, nil
+}
+
+// updateVaultInfo applies the update to the info of the vault and saves it, under the lock of the vault: the
+// updates of the same vault, eg. of its doc count, would otherwise overwrite each other.
+func (c *Client) updateVaultInfo(id string, update func(info *vaultInfo) error) error {
+	info, unlock, err := c.lockVaultInfo(id)
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	err = update(info)
+	if err != nil {
+		return err
+	}
+
+	return c.saveVaultInfo(id, info)
+}
+
+// lockVaultInfo locks the info of the vault and returns it, upgraded, with the function unlocking it.
+func (c *Client) lockVaultInfo(id string) (*vaultInfo, func(), error) {
+	unlock := c.vaultMu.lock(fmt.Sprintf(infoFormat, id))
+
+	info, err := c.readVaultInfo(id)
+	if err != nil {
+		unlock()
+
+		return nil, nil, err
+	}
+
+	if info.Version < vaultInfoVersion {
+		err = c.migrateVaultInfo(id, info)
+		if err != nil {
+			unlock()
+
+			return nil, nil, fmt.Errorf("migrate: %w", err)
+		}
+	}
+
+	return info, unlock, nil
+}
+
+func (c *Client) readVaultInfo(id string) (*vaultInfo, error) {
 	src, err := c.store.Get(fmt.Sprintf(infoFormat, id))
 	if err != nil {
 		return nil, fmt.Errorf("get: %w", err)
@@ -565,27 +2218,59 @@ func (c *Client) getVaultInfo(id string) (*vaultInfo, error) {
 	return info, nil
 }
 
-func (c *Client) webKMS(controller string, auth *Location) *webkms.RemoteKMS {
-	return webkms.New(
-		c.buildKMSURL(auth.URI),
-		c.httpClient,
-		webkms.WithHeaders(c.kmsSign(controller, auth)),
-	)
-}
+// migrateVaultInfo upgrades a vaultInfo saved by an older version, under the lock of the vault. The creation time of
+// vaults older than version 1 is unknown and stays zero, their doc count is initialized from the stored document
+// metadata, which only counts the documents tagged so far: see TagLegacyRecords. The documents and authorizations
+// of vaults older than version 3 are added to their list indexes. Saving the upgraded vaultInfo indexes the vault by
+// controller.
+func (c *Client) migrateVaultInfo(id string, info *vaultInfo) error {
+	if info.Version < 1 {
+		docs, err := c.queryMetaDocInfos(id)
+		if err != nil {
+			return fmt.Errorf("query meta doc infos: %w", err)
+		}
+
+		info.DocCount = len(docs)
+		info.Legacy = true
+	}
 
-func (c *Client) buildKMSURL(uri string) string {
-	if strings.HasPrefix(uri, "/") {
-		return c.remoteKMSURL + uri
+	if info.Version < listIndexVersion {
+		err := c.indexVaultRecords(id)
+		if err != nil {
+			return fmt.Errorf("index vault records: %w", err)
+		}
 	}
 
-	return uri
+	info.Version = vaultInfoVersion
+
+	return c.saveVaultInfo(id, info)
+}
+
+func (c *Client) addDocCount(id string, delta int) error {
+	return c.updateVaultInfo(id, func(info *vaultInfo) error {
+		info.DocCount += delta
+
+		if info.DocCount < 0 {
+			info.DocCount = 0
+		}
+
+		return nil
+	})
+}
+
+func (c *Client) webKMS(info *vaultInfo) *webkms.RemoteKMS {
+	return webkms.New(
+		c.buildKMSURL(info, info.Auth.KMS.URI),
+		c.kmsHTTPClient(),
+		webkms.WithHeaders(c.kmsSign(info.DidURL, info.Auth.KMS)),
+	)
 }
 
-func (c *Client) webCrypto(controller string, auth *Location) *webcrypto.RemoteCrypto {
+func (c *Client) webCrypto(info *vaultInfo) *webcrypto.RemoteCrypto {
 	return webcrypto.New(
-		c.buildKMSURL(auth.URI),
-		c.httpClient,
-		webkms.WithHeaders(c.kmsSign(controller, auth)),
+		c.buildKMSURL(info, info.Auth.KMS.URI),
+		c.kmsHTTPClient(),
+		webkms.WithHeaders(c.kmsSign(info.DidURL, info.Auth.KMS)),
 	)
 }
 
@@ -675,8 +2360,8 @@ func newKey(k kms.KeyManager) (string, ed25519.PublicKey, error) {
 	return keyID, bits, nil
 }
 
-func (c *Client) createDataVault(didKey string) (*Location, error) {
-	vaultURI, rawCapability, err := c.edvClient.CreateDataVault(&models.DataVaultConfiguration{
+func (c *Client) createDataVault(backend *edvBackend, didKey string) (*Location, error) {
+	vaultURI, rawCapability, err := backend.client.CreateDataVault(&models.DataVaultConfiguration{
 		Controller:  didKey,
 		ReferenceID: uuid.New().String(),
 		KEK:         models.IDTypePair{ID: uuid.New().URN(), Type: "AesKeyWrappingKey2019"},
@@ -756,51 +2441,103 @@ func buildEDVURI(s, h, vid string) string {
 	return fmt.Sprintf("%s://%s/encrypted-data-vaults/%s", s, h, vid)
 }
 
-func encryptContent(wKMS KeyManager, wCrypto ariescrypto.Crypto, content interface{}) (string, string, error) {
-	src, err := json.Marshal(content)
+// encryptContent encrypts the content to a new key-wrapping key of the type, and to the other keys if any.
+func encryptContent(wKMS KeyManager, wCrypto ariescrypto.Crypto, keyType kms.KeyType,
+	content interface{}, others ...*ariescrypto.PublicKey) (string, string, error) {
+	kidURL, err := newDocKey(wKMS, keyType)
+	if err != nil {
+		return "", "", err
+	}
+
+	eContent, err := encryptToKey(wKMS, wCrypto, kidURL, content, others...)
 	if err != nil {
-		return "", "", fmt.Errorf("marshal: %w", err)
+		return "", "", err
 	}
 
-	_, kidURL, err := wKMS.Create(kms.NISTP256ECDHKW)
+	return kidURL, eContent, nil
+}
+
+// newDocKey creates a new key-wrapping key of the type and returns its URL.
+func newDocKey(wKMS KeyManager, keyType kms.KeyType) (string, error) {
+	_, kidURL, err := wKMS.Create(keyType)
 	if err != nil {
-		return "", "", fmt.Errorf("create: %w", err)
+		return "", fmt.Errorf("create: %w", err)
 	}
 
 	kidURLStr, ok := kidURL.(string)
 	if !ok {
-		return "", "", fmt.Errorf("kidURL is not a string")
+		return "", fmt.Errorf("kidURL is not a string")
+	}
+
+	return kidURLStr, nil
+}
+
+// encryptToKey encrypts the content to an existing key of the keystore, and to the other keys if any: the JWE then
+// has a recipient per key, the key of the keystore first.
+func encryptToKey(wKMS KeyManager, wCrypto ariescrypto.Crypto, kidURL string, content interface{},
+	others ...*ariescrypto.PublicKey,
+) (string, error) {
+	src, err := json.Marshal(content)
+	if err != nil {
+		return "", fmt.Errorf("marshal: %w", err)
 	}
 
-	pubKeyBytes, _, err := wKMS.ExportPubKeyBytes(lastElm(kidURLStr, "/"))
+	pubKeyBytes, _, err := wKMS.ExportPubKeyBytes(lastElm(kidURL, "/"))
 	if err != nil {
-		return "", "", fmt.Errorf("export pubKey bytes: %w", err)
+		return "", fmt.Errorf("export pubKey bytes: %w", err)
 	}
 
 	var ecPubKey *ariescrypto.PublicKey
 
 	err = json.Unmarshal(pubKeyBytes, &ecPubKey)
 	if err != nil {
-		return "", "", fmt.Errorf("unmarshal: %w", err)
+		return "", fmt.Errorf("unmarshal: %w", err)
 	}
 
 	encrypter, err := jose.NewJWEEncrypt(jose.A256GCM, jose.A256GCMALG, "", "", nil,
-		[]*ariescrypto.PublicKey{ecPubKey}, wCrypto)
+		append([]*ariescrypto.PublicKey{ecPubKey}, others...), wCrypto)
 	if err != nil {
-		return "", "", fmt.Errorf("new JWE encrypt: %w", err)
+		return "", fmt.Errorf("new JWE encrypt: %w", err)
 	}
 
 	jwe, err := encrypter.Encrypt(src)
 	if err != nil {
-		return "", "", fmt.Errorf("encrypt: %w", err)
+		return "", fmt.Errorf("encrypt: %w", err)
 	}
 
 	eContent, err := jwe.FullSerialize(json.Marshal)
 	if err != nil {
-		return "", "", fmt.Errorf("full serialize: %w", err)
+		return "", fmt.Errorf("full serialize: %w", err)
+	}
+
+	return eContent, nil
+}
+
+func decryptDocument(wKMS KeyManager, wCrypto ariescrypto.Crypto, src []byte) (*models.StructuredDocument, error) {
+	jwe, err := jose.Deserialize(string(src))
+	if err != nil {
+		return nil, fmt.Errorf("deserialize: %w", err)
+	}
+
+	cr := &unwrapErrCrypto{Crypto: wCrypto}
+
+	plaintext, err := jose.NewJWEDecrypt(nil, cr, wKMS).Decrypt(jwe)
+	if err != nil {
+		if cr.refusal != nil {
+			return nil, fmt.Errorf("decrypt: %w", cr.refusal)
+		}
+
+		return nil, fmt.Errorf("decrypt: %w", err)
+	}
+
+	var doc models.StructuredDocument
+
+	err = json.Unmarshal(plaintext, &doc)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal: %w", err)
 	}
 
-	return kidURLStr, eContent, nil
+	return &doc, nil
 }
 
 type signer struct {
@@ -7,17 +7,29 @@ SPDX-License-Identifier: Apache-2.0
 package vault_test
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	"github.com/hyperledger/aries-framework-go/pkg/crypto"
 	"github.com/hyperledger/aries-framework-go/pkg/crypto/tinkcrypto"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/util/signature"
 	"github.com/hyperledger/aries-framework-go/pkg/kms"
 	"github.com/hyperledger/aries-framework-go/pkg/kms/localkms"
@@ -27,14 +39,26 @@ import (
 	"github.com/hyperledger/aries-framework-go/pkg/secretlock/noop"
 	"github.com/hyperledger/aries-framework-go/pkg/vdr/fingerprint"
 	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/piprate/json-gold/ld"
 	"github.com/stretchr/testify/require"
 	"github.com/trustbloc/edge-core/pkg/zcapld"
 	"github.com/trustbloc/edv/pkg/restapi/messages"
+	"github.com/trustbloc/edv/pkg/restapi/models"
 
+	"github.com/trustbloc/ace/pkg/httpsig"
 	"github.com/trustbloc/ace/pkg/internal/testutil"
 	"github.com/trustbloc/ace/pkg/restapi/vault"
 )
 
+// nolint: lll
+const vaultAuth = `{"edv":{"authToken":"H4sIAAAAAAAA_5SSTW-rOBSG_8u5y4EWTEzAq0lDm9CbkC86SbmqKmNs4obGyBhSUvW_j3JbzYxm1_XRq_O8H-_wJ1NHw98MENgbUzfk-vrkyeJK6fK64azV0vTXHQILZAEEWn0kbSsLwvzQ911U2MJDwh4MWWjnrnBt5oicD7AIHFRcRMdOHbgGAoUsyIH35OzPD6_bRHY5bqb7szvsRK3Lzekh54lIV_O7t7l8GGC6FssNNn7_47sCsKCmmh_NmNY0l5U0_X_Bh57Ic8dBduFxegFHNi280PZCQQd5Hg7CIQMLaFWpEy9GzEh1BPILNKcXQyctDYenT2eMXq4p1SU3QN4hjoDAKFjRaCdkbTKdJJnG_s0pmoAFaV_zLxJedKSjbWXgw4JaKyWA_HoH9g_xeE_l77ff436ygGlODb90hRzk2g6yXZQ6AcEecf2r0B8EeOBi9IeDiOOABS-nBgjw_n6fT5hcyPu77HadrjZxE7_GKBnHfvZ61zD00MSvSU93K7moGvn48ujElRteXWEeJ7vWa26mcn0ug90aLX6mtvhrHy_VgtJe5MvmnCos19l0hnDAEtv2d3py9vE4Ww690-oxUtWsb5-nCzraOH2A8_EKLDiqI7vkNdfjw8R7fKui2UyHyQOqh4dbJ2LzMw2j-Hm2510yG-KRzG-rdJuImyJ4jm1P-8FYJZkcuWrbbOee9Dc_R7lWKHNLl47gK_dlq2vVXP78G37EK17-rhYsMJ-t3RYIYzfcyPJITas5ctwALOi4lkJ-7mDOzV4V_5t6jYMunCy3y1K_pQbjjL4EyqujpAvbKO9e2LScNmxzz-6b-Y_vCuDj6ePvAAAA___BBC2CwwMAAA=="},"kms":{"authToken":"H4sIAAAAAAAA_6RTS3PiOBj8L98c18SP2EB02oADhmBexkPC1BxkWbaFH_JIMuCk8t-3HMIc9jY1J7VK3dVSt753-JfwStGLAgSZUrVEun6-Z_EdF6kuKWkEU61-skADFn9xkK4XnOAi41KhYX_Y1_NS6jltpeKCSp0YRyuqHMabOCp-WQXPzLTTVyeeUwEIYhajnLbore_n5X7JTpEjvezNHJySWqTBOYzoMtlt_MnFZ6Ht4G2yDhzVb7_9qQA0wEXBzzR-JIrxCtAPIIJiRZ9pd0gvNRfqiiVLK9DgRAVLuv1Z4Bo0aKovQHhZN4r6j-PfrCumFRFtrUCDmN5QU8dY0Sf3-xjXOGIFU592WN6WVU07N0lx8Ql_XvMhuLvmDouUKkDvMHP_LvNdW1NA0IgK5aVENz58aFALzhNAP96_EunatQzL7BlWz7R2xhA598js3z3Y9mBg25b1j2EhwwANjmcJCGg7z6IpYSs2nxyetrtNMJOzcmYtx7P-oZxIYoVyVi5b_LJhq0Ky1-OrMSvMh7u7-7bc7UfHqTf2pjuflA8Ofr2EbzQ4L5wiOdkqtFthH9hiHDYsOZ1nrb-I3eeel2wHi2gxx6Itm01vaPV77ps52Z9Gw_V4AxpUvCLdc19W46jxh-SpyAO1fQ5ar12sKm-0dh97CWkm4Xo3GA2NMFv5wSR3cUKku_dl4k0qtrcP5uTyPVu-FL8WwZT0RvTRPKy3VWfwmdm6ETWXnQ_5Xa5LC5p-dgcaqGvoT7HlOOZDwNIKq0ZQyzCHt6_DrkX7VGU8_t9EpMfsudkfS1r1s-ZyGWfePA_WYYnvPfe8SQ6jUZZGWz4_TBPr258K4OPnx38BAAD__xy0S3b1AwAA"}}`
+
+// keystoreResponse is the response of the WebKMS server to the creation of a keystore.
+const keystoreResponse = `{"key_store_url":"/v1/keystores/c0b9em5ioud57602s7og","capability":"H4sIAAAAAAAA/6xTTXOjOBD9Lz1XYgP+5rSOwQQ7jklsJ2OmprZk0cYyAmFJmJBU/vsW4ziztbep2gPFa6m7pfde6x3+oiLX+KrBgYPWhXLa7arD4paQSVshLSXTdftsgwEs/ldOmqmWlqXSOy5oiwtKuDM0B/322WqnWCstJKo27Re9Hs1GnX5MBx16FKlImk75WaQowYGYxU6KtfPWX6SqO/MGkb+uXqZjz5Oh/z2tD52T1pvbcL2fZfOqrwdyPvHnefHtTwvAAMK5qDAeU81EDs4PoBKJxjnWYAC+FkLqC2bZb6xYkoMBZ5Rs38RUZEWpcTGefK1eMOZU1oUGA2K8IiSq/vwtC2z6KCT8ClmSL0qu2e9Gn1GMkp0xlELsv/auUSVJw6XMr6CIiUbPfZ6QguwYZ7qGnxeFKWmIrolMUIPzDoH7f/m3rgsEB0qZO2mmnGs+fBhAyRmJVuDkJecGFL+u7fx4/xS7GSHbtK0by74xR2tr5Ji2Y5mtvm0Nm28UgQHHSoEDWM8OO5+yJZtNI+9p/bgKVJAF9sMk6EfZVFF7o4LsoSbfH9mSK7Y9bs2AW6NWi5uH8Vrpp9dFHi7nZHm6Rzfi7qpTrZ729/KePIxva3/zMhiedLx07WBPnoNwWC5PfTN7q7Lu7VMYdzfVZlWZwl5l4yKcjsdgQC5y2hC/G95Z3Ve3x9fVXRZNKY8S82ZKN9UunQ3+LqvNdFJa1mjUqYuN1pPq6KdLi8Xe9hC9RP629N9yv9YZz+vDyh+697PuPLMe4VOusJSFUM059MtTFzkmv/wEA/RFfi+2ez1rtGJJTnQp0Tat4XVe2MX8BeqDiP/zzpLnF3zcrdicHQ/arbGbJhNPlNrDU76t3AQfdP8U7vRpEYtvf1oAHz8//gkAAP//g9t+P1UEAAA="}` //nolint: lll
+
+// edvCapability is the zcap returned by the EDV server on the creation of a vault.
+const edvCapability = `{"@context":"https://w3id.org/security/v2","id":"urn:uuid:293817e5-3a47-4685-9bd3-51eba3d5e928","invoker":"did:key:z6MkqknydjnZe6ZqXNGEvjYTPxwmUzAkzS17LAJTuYsMQsyr#z6MkqknydjnZe6ZqXNGEvjYTPxwmUzAkzS17LAJTuYsMQsyr","parentCapability":"urn:uuid:3e7f55ea-2e2c-41bd-a167-3cb71db9ca14","allowedAction":["read","write"],"invocationTarget":{"ID":"DWPPbEVn1afJY4We3kpQmq","Type":"urn:edv:vault"},"proof":[{"capabilityChain":["urn:uuid:3e7f55ea-2e2c-41bd-a167-3cb71db9ca14"],"created":"2021-01-31T13:41:13.863452194+02:00","jws":"eyJhbGciOiJFZERTQSIsImI2NCI6ZmFsc2UsImNyaXQiOlsiYjY0Il19..NfznOmAi16H7fXJ1lI3-JzzHlOMopAhdGnBaF_FYK_F5BHbJMpH0u1aZ_JMgrG2XHUFMLNCBxG91DA-tJn2gDQ","nonce":"ZjtzLnBIpSNLteskV4bgTI8LOwrqrETpDI31qPglCNT_V-78ZmChHhqksMEu59WhkA_hofadF8saneziAhCDRA","proofPurpose":"capabilityDelegation","type":"Ed25519Signature2018","verificationMethod":"did:key:z6Mkpi5ZtFzsZv5UQhLzejwaNM5YX38cHBuMopUkayU13zyn#z6Mkpi5ZtFzsZv5UQhLzejwaNM5YX38cHBuMopUkayU13zyn"}]}` // nolint: lll
+
 const kmsResponse = `
 {
   "kid": "Y61VJzsZCwH99LG86cjUiyL1-odvkzTWs7U9OJNsUW4",
@@ -165,7 +189,7 @@ func TestClient_CreateVault(t *testing.T) {
 		remoteKMS := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusOK)
 
-			_, err := w.Write([]byte(`{"key_store_url":"/v1/keystores/c0b9em5ioud57602s7og","capability":"H4sIAAAAAAAA/6xTTXOjOBD9Lz1XYgP+5rSOwQQ7jklsJ2OmprZk0cYyAmFJmJBU/vsW4ziztbep2gPFa6m7pfde6x3+oiLX+KrBgYPWhXLa7arD4paQSVshLSXTdftsgwEs/ldOmqmWlqXSOy5oiwtKuDM0B/322WqnWCstJKo27Re9Hs1GnX5MBx16FKlImk75WaQowYGYxU6KtfPWX6SqO/MGkb+uXqZjz5Oh/z2tD52T1pvbcL2fZfOqrwdyPvHnefHtTwvAAMK5qDAeU81EDs4PoBKJxjnWYAC+FkLqC2bZb6xYkoMBZ5Rs38RUZEWpcTGefK1eMOZU1oUGA2K8IiSq/vwtC2z6KCT8ClmSL0qu2e9Gn1GMkp0xlELsv/auUSVJw6XMr6CIiUbPfZ6QguwYZ7qGnxeFKWmIrolMUIPzDoH7f/m3rgsEB0qZO2mmnGs+fBhAyRmJVuDkJecGFL+u7fx4/xS7GSHbtK0by74xR2tr5Ji2Y5mtvm0Nm28UgQHHSoEDWM8OO5+yJZtNI+9p/bgKVJAF9sMk6EfZVFF7o4LsoSbfH9mSK7Y9bs2AW6NWi5uH8Vrpp9dFHi7nZHm6Rzfi7qpTrZ729/KePIxva3/zMhiedLx07WBPnoNwWC5PfTN7q7Lu7VMYdzfVZlWZwl5l4yKcjsdgQC5y2hC/G95Z3Ve3x9fVXRZNKY8S82ZKN9UunQ3+LqvNdFJa1mjUqYuN1pPq6KdLi8Xe9hC9RP629N9yv9YZz+vDyh+697PuPLMe4VOusJSFUM059MtTFzkmv/wEA/RFfi+2ez1rtGJJTnQp0Tat4XVe2MX8BeqDiP/zzpLnF3zcrdicHQ/arbGbJhNPlNrDU76t3AQfdP8U7vRpEYtvf1oAHz8//gkAAP//g9t+P1UEAAA="}`)) //nolint: lll
+			_, err := w.Write([]byte(keystoreResponse))
 			require.NoError(t, err)
 		}))
 
@@ -173,7 +197,7 @@ func TestClient_CreateVault(t *testing.T) {
 			w.Header().Set("Location", "localhost:7777/encrypted-data-vaults/DWPPbEVn1afJY4We3kpQmq")
 			w.WriteHeader(http.StatusCreated)
 
-			_, err := w.Write([]byte(`{"@context":"https://w3id.org/security/v2","id":"urn:uuid:293817e5-3a47-4685-9bd3-51eba3d5e928","invoker":"did:key:z6MkqknydjnZe6ZqXNGEvjYTPxwmUzAkzS17LAJTuYsMQsyr#z6MkqknydjnZe6ZqXNGEvjYTPxwmUzAkzS17LAJTuYsMQsyr","parentCapability":"urn:uuid:3e7f55ea-2e2c-41bd-a167-3cb71db9ca14","allowedAction":["read","write"],"invocationTarget":{"ID":"DWPPbEVn1afJY4We3kpQmq","Type":"urn:edv:vault"},"proof":[{"capabilityChain":["urn:uuid:3e7f55ea-2e2c-41bd-a167-3cb71db9ca14"],"created":"2021-01-31T13:41:13.863452194+02:00","jws":"eyJhbGciOiJFZERTQSIsImI2NCI6ZmFsc2UsImNyaXQiOlsiYjY0Il19..NfznOmAi16H7fXJ1lI3-JzzHlOMopAhdGnBaF_FYK_F5BHbJMpH0u1aZ_JMgrG2XHUFMLNCBxG91DA-tJn2gDQ","nonce":"ZjtzLnBIpSNLteskV4bgTI8LOwrqrETpDI31qPglCNT_V-78ZmChHhqksMEu59WhkA_hofadF8saneziAhCDRA","proofPurpose":"capabilityDelegation","type":"Ed25519Signature2018","verificationMethod":"did:key:z6Mkpi5ZtFzsZv5UQhLzejwaNM5YX38cHBuMopUkayU13zyn#z6Mkpi5ZtFzsZv5UQhLzejwaNM5YX38cHBuMopUkayU13zyn"}]}`)) // nolint: lll
+			_, err := w.Write([]byte(edvCapability))
 			require.NoError(t, err)
 		}))
 
@@ -199,7 +223,7 @@ func TestClient_CreateVault(t *testing.T) {
 		remoteKMS := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusOK)
 
-			_, err := w.Write([]byte(`{"key_store_url":"/v1/keystores/c0b9em5ioud57602s7og","capability":"H4sIAAAAAAAA/6xTTXOjOBD9Lz1XYgP+5rSOwQQ7jklsJ2OmprZk0cYyAmFJmJBU/vsW4ziztbep2gPFa6m7pfde6x3+oiLX+KrBgYPWhXLa7arD4paQSVshLSXTdftsgwEs/ldOmqmWlqXSOy5oiwtKuDM0B/322WqnWCstJKo27Re9Hs1GnX5MBx16FKlImk75WaQowYGYxU6KtfPWX6SqO/MGkb+uXqZjz5Oh/z2tD52T1pvbcL2fZfOqrwdyPvHnefHtTwvAAMK5qDAeU81EDs4PoBKJxjnWYAC+FkLqC2bZb6xYkoMBZ5Rs38RUZEWpcTGefK1eMOZU1oUGA2K8IiSq/vwtC2z6KCT8ClmSL0qu2e9Gn1GMkp0xlELsv/auUSVJw6XMr6CIiUbPfZ6QguwYZ7qGnxeFKWmIrolMUIPzDoH7f/m3rgsEB0qZO2mmnGs+fBhAyRmJVuDkJecGFL+u7fx4/xS7GSHbtK0by74xR2tr5Ji2Y5mtvm0Nm28UgQHHSoEDWM8OO5+yJZtNI+9p/bgKVJAF9sMk6EfZVFF7o4LsoSbfH9mSK7Y9bs2AW6NWi5uH8Vrpp9dFHi7nZHm6Rzfi7qpTrZ729/KePIxva3/zMhiedLx07WBPnoNwWC5PfTN7q7Lu7VMYdzfVZlWZwl5l4yKcjsdgQC5y2hC/G95Z3Ve3x9fVXRZNKY8S82ZKN9UunQ3+LqvNdFJa1mjUqYuN1pPq6KdLi8Xe9hC9RP629N9yv9YZz+vDyh+697PuPLMe4VOusJSFUM059MtTFzkmv/wEA/RFfi+2ez1rtGJJTnQp0Tat4XVe2MX8BeqDiP/zzpLnF3zcrdicHQ/arbGbJhNPlNrDU76t3AQfdP8U7vRpEYtvf1oAHz8//gkAAP//g9t+P1UEAAA="}`)) //nolint: lll
+			_, err := w.Write([]byte(keystoreResponse))
 			require.NoError(t, err)
 		}))
 
@@ -207,7 +231,7 @@ func TestClient_CreateVault(t *testing.T) {
 			w.Header().Set("Location", "localhost:7777/encrypted-data-vaults/DWPPbEVn1afJY4We3kpQmq")
 			w.WriteHeader(http.StatusCreated)
 
-			_, err := w.Write([]byte(`{"@context":"https://w3id.org/security/v2","id":"urn:uuid:293817e5-3a47-4685-9bd3-51eba3d5e928","invoker":"did:key:z6MkqknydjnZe6ZqXNGEvjYTPxwmUzAkzS17LAJTuYsMQsyr#z6MkqknydjnZe6ZqXNGEvjYTPxwmUzAkzS17LAJTuYsMQsyr","parentCapability":"urn:uuid:3e7f55ea-2e2c-41bd-a167-3cb71db9ca14","allowedAction":["read","write"],"invocationTarget":{"ID":"DWPPbEVn1afJY4We3kpQmq","Type":"urn:edv:vault"},"proof":[{"capabilityChain":["urn:uuid:3e7f55ea-2e2c-41bd-a167-3cb71db9ca14"],"created":"2021-01-31T13:41:13.863452194+02:00","jws":"eyJhbGciOiJFZERTQSIsImI2NCI6ZmFsc2UsImNyaXQiOlsiYjY0Il19..NfznOmAi16H7fXJ1lI3-JzzHlOMopAhdGnBaF_FYK_F5BHbJMpH0u1aZ_JMgrG2XHUFMLNCBxG91DA-tJn2gDQ","nonce":"ZjtzLnBIpSNLteskV4bgTI8LOwrqrETpDI31qPglCNT_V-78ZmChHhqksMEu59WhkA_hofadF8saneziAhCDRA","proofPurpose":"capabilityDelegation","type":"Ed25519Signature2018","verificationMethod":"did:key:z6Mkpi5ZtFzsZv5UQhLzejwaNM5YX38cHBuMopUkayU13zyn#z6Mkpi5ZtFzsZv5UQhLzejwaNM5YX38cHBuMopUkayU13zyn"}]}`)) // nolint: lll
+			_, err := w.Write([]byte(edvCapability))
 			require.NoError(t, err)
 		}))
 
@@ -229,6 +253,123 @@ func TestClient_CreateVault(t *testing.T) {
 		require.NotEmpty(t, result.EDV.AuthToken)
 		require.NotEmpty(t, result.KMS.URI)
 		require.NotEmpty(t, result.KMS.AuthToken)
+
+		info, err := client.GetVaultInfo(result.ID)
+		require.NoError(t, err)
+		require.NotNil(t, info.Created)
+		require.Zero(t, info.DocCount)
+		require.Equal(t, result.EDV.URI, info.EDVURI)
+		require.Equal(t, result.KMS.URI, info.KMSURI)
+	})
+}
+
+func TestClient_CreateVaultWithController(t *testing.T) {
+	loader := testutil.DocumentLoader(t)
+
+	t.Run("Success", func(t *testing.T) {
+		for _, vm := range []string{"#key-1", "key-1"} {
+			doc, priv := newSigningDIDDoc(t)
+			client, _ := newRoundTripVaultClient(t, loader, vault.WithRegistry(&vdr.MockVDRegistry{ResolveValue: doc}))
+
+			if vm == "key-1" {
+				vm = doc.ID + "#key-1"
+			}
+
+			result, err := client.CreateVaultWithController(doc.ID, vm, newSignedRequest(t, priv, doc.ID+"#key-1"))
+			require.NoError(t, err)
+			require.Equal(t, doc.ID, result.ID)
+			require.NotEmpty(t, result.EDV.AuthToken)
+			require.NotEmpty(t, result.KMS.AuthToken)
+
+			info, err := client.GetVaultInfo(doc.ID)
+			require.NoError(t, err)
+			require.Equal(t, doc.ID, info.ID)
+
+			_, err = client.CreateVaultWithController(doc.ID, vm, newSignedRequest(t, priv, doc.ID+"#key-1"))
+			require.True(t, errors.Is(err, vault.ErrVaultExists))
+		}
+	})
+
+	t.Run("Request not signed with the verification method", func(t *testing.T) {
+		doc, priv := newSigningDIDDoc(t)
+		client, _ := newRoundTripVaultClient(t, loader, vault.WithRegistry(&vdr.MockVDRegistry{ResolveValue: doc}))
+
+		_, otherPriv, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		unsigned, err := http.NewRequest(http.MethodPost, "https://vault.example.com/vaults", nil)
+		require.NoError(t, err)
+
+		for _, tc := range []struct {
+			req *http.Request
+			msg string
+		}{
+			{nil, "an HTTP signature by " + doc.ID + "#key-1 is required"},
+			{unsigned, "an HTTP signature by " + doc.ID + "#key-1 is required"},
+			{newSignedRequest(t, otherPriv, doc.ID+"#key-1"), "invalid HTTP signature"},
+			{newSignedRequest(t, priv, doc.ID+"#key-2"), "signed with " + doc.ID + "#key-2 instead of"},
+		} {
+			_, err = client.CreateVaultWithController(doc.ID, "#key-1", tc.req)
+			require.True(t, errors.Is(err, vault.ErrUnauthorizedInvocation))
+			require.Contains(t, err.Error(), tc.msg)
+		}
+
+		_, err = client.GetVaultInfo(doc.ID)
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+	})
+
+	t.Run("Documents are accessed by the controller", func(t *testing.T) {
+		doc, priv := newSigningDIDDoc(t)
+		client, _ := newRoundTripVaultClient(t, loader, vault.WithRegistry(&vdr.MockVDRegistry{ResolveValue: doc}))
+
+		_, err := client.CreateVaultWithController(doc.ID, "#key-1", newSignedRequest(t, priv, doc.ID+"#key-1"))
+		require.NoError(t, err)
+
+		_, err = client.SaveDoc(doc.ID, "doc", []byte(`{"message":"Hello World!"}`))
+		require.True(t, errors.Is(err, vault.ErrExternallyControlled))
+		require.Contains(t, err.Error(), "authorization tokens")
+
+		_, err = client.GetDoc(doc.ID, "doc")
+		require.True(t, errors.Is(err, vault.ErrExternallyControlled))
+
+		_, err = client.CreateAuthorization(doc.ID, "did:example:rp", &vault.AuthorizationsScope{
+			Target:  "doc",
+			Actions: []string{"read"},
+		})
+		require.True(t, errors.Is(err, vault.ErrExternallyControlled))
+
+		info, err := client.GetVaultInfo(doc.ID)
+		require.NoError(t, err)
+		require.Zero(t, info.DocCount)
+	})
+
+	t.Run("Invalid controller", func(t *testing.T) {
+		doc := newDIDDoc()
+
+		client, err := vault.NewClient("", "", nil, mem.NewProvider(), loader,
+			vault.WithRegistry(&vdr.MockVDRegistry{ResolveValue: doc}))
+		require.NoError(t, err)
+
+		for _, tc := range []struct{ controller, vm, msg string }{
+			{"", "#key-1", "controller and verificationMethod are required"},
+			{doc.ID, "", "controller and verificationMethod are required"},
+			{doc.ID, "did:example:other#key-1", "does not belong to"},
+			{doc.ID, "#key-2", "not found in"},
+		} {
+			_, err = client.CreateVaultWithController(tc.controller, tc.vm, nil)
+			require.True(t, errors.Is(err, vault.ErrInvalidController))
+			require.Contains(t, err.Error(), tc.msg)
+		}
+	})
+
+	t.Run("Resolve error", func(t *testing.T) {
+		client, err := vault.NewClient("", "", nil, mem.NewProvider(), loader,
+			vault.WithRegistry(&vdr.MockVDRegistry{ResolveErr: errors.New("test")}))
+		require.NoError(t, err)
+
+		_, err = client.CreateVaultWithController("did:example:123", "#key-1", nil)
+		require.True(t, errors.Is(err, vault.ErrInvalidController))
+		require.Contains(t, err.Error(), "resolve did:example:123: test")
 	})
 }
 
@@ -274,6 +415,33 @@ func TestClient_GetAuthorization(t *testing.T) {
 		res, err := client.GetAuthorization("vid", "id")
 		require.NoError(t, err)
 		require.NotNil(t, res)
+		require.Equal(t, vault.AuthorizationStatusActive, res.Status)
+	})
+
+	t.Run("Status", func(t *testing.T) {
+		past := time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+		future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+
+		client, err := vault.NewClient("", "", nil, &mockstorage.MockStoreProvider{
+			Store: &mockstorage.MockStore{
+				Store: map[string]mockstorage.DBEntry{
+					"authorization_vid_active":  {Value: []byte(`{"expiresAt":"` + future + `"}`)},
+					"authorization_vid_expired": {Value: []byte(`{"expiresAt":"` + past + `"}`)},
+					"authorization_vid_revoked": {Value: []byte(`{"expiresAt":"` + future + `","revoked":true}`)},
+				},
+			},
+		}, loader)
+		require.NoError(t, err)
+
+		for id, status := range map[string]string{
+			"active":  vault.AuthorizationStatusActive,
+			"expired": vault.AuthorizationStatusExpired,
+			"revoked": vault.AuthorizationStatusRevoked,
+		} {
+			res, err := client.GetAuthorization("vid", id)
+			require.NoError(t, err)
+			require.Equal(t, status, res.Status)
+		}
 	})
 }
 
@@ -355,14 +523,19 @@ func TestClient_SaveDoc(t *testing.T) {
 			}
 		}))
 
+		// the metadata is written once the document is written to EDV
+		edv := httptest.NewServer(newEDVHandler(t))
+		t.Cleanup(edv.Close)
+
 		lKMS := newLocalKms(t, store)
-		client, err := vault.NewClient(remoteKMS.URL, "", lKMS, store, loader)
+		client, err := vault.NewClient(remoteKMS.URL, edv.URL, lKMS, store, loader)
 		require.NoError(t, err)
 
 		vID, dURL, _ := createVaultID(t, lKMS)
 
 		data["info_"+vID] = mockstorage.DBEntry{
-			Value: []byte(`{"did_url":"` + dURL + `", "auth":{"edv":{},"kms":{"uri":"/v1/keystores/c0ekinlioud42c84qs7g"}}}`),
+			Value: []byte(`{"did_url":"` + dURL + `", "auth":{"edv":{"uri":"/encrypted-data-vaults/DWPPbEVn1afJY4We3kpQmq"},` +
+				`"kms":{"uri":"/v1/keystores/c0ekinlioud42c84qs7g"}}}`),
 		}
 
 		_, err = client.SaveDoc(vID, docID, data["info_"+vID].Value)
@@ -501,7 +674,7 @@ func TestClient_SaveDoc(t *testing.T) {
 			w.Header().Set("Location", "localhost:7777/encrypted-data-vaults/DWPPbEVn1afJY4We3kpQmq")
 			w.WriteHeader(http.StatusCreated)
 
-			_, err := w.Write([]byte(`{"@context":"https://w3id.org/security/v2","id":"urn:uuid:293817e5-3a47-4685-9bd3-51eba3d5e928","invoker":"did:key:z6MkqknydjnZe6ZqXNGEvjYTPxwmUzAkzS17LAJTuYsMQsyr#z6MkqknydjnZe6ZqXNGEvjYTPxwmUzAkzS17LAJTuYsMQsyr","parentCapability":"urn:uuid:3e7f55ea-2e2c-41bd-a167-3cb71db9ca14","allowedAction":["read","write"],"invocationTarget":{"ID":"DWPPbEVn1afJY4We3kpQmq","Type":"urn:edv:vault"},"proof":[{"capabilityChain":["urn:uuid:3e7f55ea-2e2c-41bd-a167-3cb71db9ca14"],"created":"2021-01-31T13:41:13.863452194+02:00","jws":"eyJhbGciOiJFZERTQSIsImI2NCI6ZmFsc2UsImNyaXQiOlsiYjY0Il19..NfznOmAi16H7fXJ1lI3-JzzHlOMopAhdGnBaF_FYK_F5BHbJMpH0u1aZ_JMgrG2XHUFMLNCBxG91DA-tJn2gDQ","nonce":"ZjtzLnBIpSNLteskV4bgTI8LOwrqrETpDI31qPglCNT_V-78ZmChHhqksMEu59WhkA_hofadF8saneziAhCDRA","proofPurpose":"capabilityDelegation","type":"Ed25519Signature2018","verificationMethod":"did:key:z6Mkpi5ZtFzsZv5UQhLzejwaNM5YX38cHBuMopUkayU13zyn#z6Mkpi5ZtFzsZv5UQhLzejwaNM5YX38cHBuMopUkayU13zyn"}]}`)) // nolint: lll
+			_, err := w.Write([]byte(edvCapability))
 			require.NoError(t, err)
 		}))
 
@@ -577,7 +750,7 @@ func TestClient_SaveDoc(t *testing.T) {
 			w.Header().Set("Location", "localhost:7777/encrypted-data-vaults/DWPPbEVn1afJY4We3kpQmq")
 			w.WriteHeader(http.StatusOK)
 
-			_, err := w.Write([]byte(`{"@context":"https://w3id.org/security/v2","id":"urn:uuid:293817e5-3a47-4685-9bd3-51eba3d5e928","invoker":"did:key:z6MkqknydjnZe6ZqXNGEvjYTPxwmUzAkzS17LAJTuYsMQsyr#z6MkqknydjnZe6ZqXNGEvjYTPxwmUzAkzS17LAJTuYsMQsyr","parentCapability":"urn:uuid:3e7f55ea-2e2c-41bd-a167-3cb71db9ca14","allowedAction":["read","write"],"invocationTarget":{"ID":"DWPPbEVn1afJY4We3kpQmq","Type":"urn:edv:vault"},"proof":[{"capabilityChain":["urn:uuid:3e7f55ea-2e2c-41bd-a167-3cb71db9ca14"],"created":"2021-01-31T13:41:13.863452194+02:00","jws":"eyJhbGciOiJFZERTQSIsImI2NCI6ZmFsc2UsImNyaXQiOlsiYjY0Il19..NfznOmAi16H7fXJ1lI3-JzzHlOMopAhdGnBaF_FYK_F5BHbJMpH0u1aZ_JMgrG2XHUFMLNCBxG91DA-tJn2gDQ","nonce":"ZjtzLnBIpSNLteskV4bgTI8LOwrqrETpDI31qPglCNT_V-78ZmChHhqksMEu59WhkA_hofadF8saneziAhCDRA","proofPurpose":"capabilityDelegation","type":"Ed25519Signature2018","verificationMethod":"did:key:z6Mkpi5ZtFzsZv5UQhLzejwaNM5YX38cHBuMopUkayU13zyn#z6Mkpi5ZtFzsZv5UQhLzejwaNM5YX38cHBuMopUkayU13zyn"}]}`)) // nolint: lll
+			_, err := w.Write([]byte(edvCapability))
 			require.NoError(t, err)
 		}
 
@@ -626,6 +799,218 @@ func TestClient_SaveDoc(t *testing.T) {
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "failed to decode content")
 	})
+
+	t.Run("Updates with the expected sequence", func(t *testing.T) {
+		client, vID := newRoundTripVaultClient(t, loader)
+
+		_, err := client.SaveDoc(vID, docID, []byte(`{"count":1}`), vault.WithExpectedSequence(0))
+		require.ErrorIs(t, err, vault.ErrExpectedDocumentNotFound)
+		require.ErrorIs(t, err, vault.ErrDocumentNotFound)
+
+		docMeta, err := client.SaveDoc(vID, docID, []byte(`{"count":1}`))
+		require.NoError(t, err)
+		require.Equal(t, uint64(0), docMeta.Sequence)
+
+		docMeta, err = client.SaveDoc(vID, docID, []byte(`{"count":2}`), vault.WithExpectedSequence(0))
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), docMeta.Sequence)
+
+		_, err = client.SaveBinaryDoc(vID, docID, "text/plain", []byte("3"), vault.WithExpectedSequence(0))
+		require.True(t, errors.Is(err, vault.ErrSequenceMismatch))
+
+		var mismatch *vault.SequenceMismatchError
+
+		require.True(t, errors.As(err, &mismatch))
+		require.Equal(t, uint64(0), mismatch.Expected)
+		require.Equal(t, uint64(1), mismatch.Current)
+
+		doc, err := client.GetDoc(vID, docID)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"count":2}`, string(doc))
+
+		// without an expected sequence, documents are overwritten
+		_, err = client.SaveDoc(vID, docID, []byte(`{"count":3}`))
+		require.NoError(t, err)
+
+		docMeta, err = client.GetDocMetadata(vID, docID)
+		require.NoError(t, err)
+		require.Equal(t, uint64(2), docMeta.Sequence)
+	})
+
+	t.Run("Concurrent updates with the same expected sequence", func(t *testing.T) {
+		client, vID := newRoundTripVaultClient(t, loader)
+
+		_, err := client.SaveDoc(vID, docID, []byte(`{"count":0}`))
+		require.NoError(t, err)
+
+		const saves = 10
+
+		var (
+			wg   sync.WaitGroup
+			errs = make([]error, saves)
+		)
+
+		for i := 0; i < saves; i++ {
+			wg.Add(1)
+
+			go func(i int) {
+				defer wg.Done()
+
+				_, errs[i] = client.SaveDoc(vID, docID, []byte(fmt.Sprintf(`{"count":%d}`, i+1)),
+					vault.WithExpectedSequence(0))
+			}(i)
+		}
+
+		wg.Wait()
+
+		winner := -1
+
+		for i, err := range errs {
+			if err == nil {
+				require.Equal(t, -1, winner, "several saves expecting the same sequence succeeded")
+
+				winner = i
+
+				continue
+			}
+
+			require.True(t, errors.Is(err, vault.ErrSequenceMismatch))
+		}
+
+		require.NotEqual(t, -1, winner)
+
+		docMeta, err := client.GetDocMetadata(vID, docID)
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), docMeta.Sequence)
+
+		doc, err := client.GetDoc(vID, docID)
+		require.NoError(t, err)
+		require.JSONEq(t, fmt.Sprintf(`{"count":%d}`, winner+1), string(doc))
+	})
+
+	t.Run("The sequence is kept if EDV fails", func(t *testing.T) {
+		var failing int32
+
+		edvHandler := newEDVHandler(t)
+
+		edv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.LoadInt32(&failing) == 1 && r.Method != http.MethodGet {
+				w.WriteHeader(http.StatusInternalServerError)
+
+				return
+			}
+
+			edvHandler(w, r)
+		}))
+		t.Cleanup(edv.Close)
+
+		remoteKMS := httptest.NewServer(newKMSHandler(t))
+		t.Cleanup(remoteKMS.Close)
+
+		provider := mem.NewProvider()
+
+		client, err := vault.NewClient(remoteKMS.URL, edv.URL, newLocalKms(t, provider), provider, loader)
+		require.NoError(t, err)
+
+		created, err := client.CreateVault()
+		require.NoError(t, err)
+
+		_, err = client.SaveDoc(created.ID, docID, []byte(`{"count":1}`))
+		require.NoError(t, err)
+
+		atomic.StoreInt32(&failing, 1)
+
+		_, err = client.SaveDoc(created.ID, docID, []byte(`{"count":2}`), vault.WithExpectedSequence(0))
+		require.Error(t, err)
+
+		atomic.StoreInt32(&failing, 0)
+
+		docMeta, err := client.GetDocMetadata(created.ID, docID)
+		require.NoError(t, err)
+		require.Equal(t, uint64(0), docMeta.Sequence)
+
+		doc, err := client.GetDoc(created.ID, docID)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"count":1}`, string(doc))
+
+		// the save is retried with the sequence it expected
+		docMeta, err = client.SaveDoc(created.ID, docID, []byte(`{"count":2}`), vault.WithExpectedSequence(0))
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), docMeta.Sequence)
+	})
+
+	t.Run("Records timestamps", func(t *testing.T) {
+		client, vID := newRoundTripVaultClient(t, loader)
+
+		before := time.Now().UTC()
+
+		created, err := client.SaveDoc(vID, docID, []byte(`{"count":1}`))
+		require.NoError(t, err)
+		require.NotNil(t, created.CreatedAt)
+		require.False(t, created.CreatedAt.Before(before))
+		require.Equal(t, created.CreatedAt, created.UpdatedAt)
+
+		updated, err := client.SaveDoc(vID, docID, []byte(`{"count":2}`))
+		require.NoError(t, err)
+		require.Equal(t, created.CreatedAt, updated.CreatedAt)
+		require.False(t, updated.UpdatedAt.Before(*created.UpdatedAt))
+
+		docMeta, err := client.GetDocMetadata(vID, docID)
+		require.NoError(t, err)
+		require.True(t, created.CreatedAt.Equal(*docMeta.CreatedAt))
+		require.True(t, updated.UpdatedAt.Equal(*docMeta.UpdatedAt))
+		require.Equal(t, uint64(1), docMeta.Sequence)
+	})
+
+	t.Run("Records content digests", func(t *testing.T) {
+		client, vID := newRoundTripVaultClient(t, loader)
+
+		first, err := client.SaveDoc(vID, docID, []byte(`{"a":1,"b":"two"}`))
+		require.NoError(t, err)
+		require.Len(t, first.ContentDigest, 64)
+
+		// equal contents, in any formatting, have equal digests
+		other, err := client.SaveDoc(vID, "other", []byte(`{ "b": "two", "a": 1 }`))
+		require.NoError(t, err)
+		require.Equal(t, first.ContentDigest, other.ContentDigest)
+
+		updated, err := client.SaveDoc(vID, docID, []byte(`{"a":2,"b":"two"}`))
+		require.NoError(t, err)
+		require.NotEqual(t, first.ContentDigest, updated.ContentDigest)
+
+		docMeta, err := client.GetDocMetadata(vID, docID)
+		require.NoError(t, err)
+		require.Equal(t, updated.ContentDigest, docMeta.ContentDigest)
+
+		binary, err := client.SaveBinaryDoc(vID, "binary", "text/plain", []byte("two"))
+		require.NoError(t, err)
+		require.Len(t, binary.ContentDigest, 64)
+
+		// the media type is part of the document
+		csv, err := client.SaveBinaryDoc(vID, "binary", "text/csv", []byte("two"))
+		require.NoError(t, err)
+		require.NotEqual(t, binary.ContentDigest, csv.ContentDigest)
+
+		docMeta, err = client.GetDocMetadata(vID, "binary")
+		require.NoError(t, err)
+		require.Equal(t, csv.ContentDigest, docMeta.ContentDigest)
+	})
+
+	t.Run("Records no content digests if disabled", func(t *testing.T) {
+		client, vID := newRoundTripVaultClient(t, loader, vault.WithoutContentDigests())
+
+		docMeta, err := client.SaveDoc(vID, docID, []byte(`{"count":1}`))
+		require.NoError(t, err)
+		require.Empty(t, docMeta.ContentDigest)
+
+		docMeta, err = client.GetDocMetadata(vID, docID)
+		require.NoError(t, err)
+		require.Empty(t, docMeta.ContentDigest)
+
+		src, err := json.Marshal(docMeta)
+		require.NoError(t, err)
+		require.NotContains(t, string(src), "contentDigest")
+	})
 }
 
 func TestClient_CreateAuthorization(t *testing.T) {
@@ -717,34 +1102,88 @@ func TestClient_CreateAuthorization(t *testing.T) {
 		vID, dURL, kid := createVaultID(t, lKMS)
 
 		data["info_"+vID] = mockstorage.DBEntry{
-			Value: []byte(`{"did_url":"` + dURL + `", "kid":"` + kid + `","auth":{"edv":{"authToken":"H4sIAAAAAAAA_5SSTW-rOBSG_8u5y4EWTEzAq0lDm9CbkC86SbmqKmNs4obGyBhSUvW_j3JbzYxm1_XRq_O8H-_wJ1NHw98MENgbUzfk-vrkyeJK6fK64azV0vTXHQILZAEEWn0kbSsLwvzQ911U2MJDwh4MWWjnrnBt5oicD7AIHFRcRMdOHbgGAoUsyIH35OzPD6_bRHY5bqb7szvsRK3Lzekh54lIV_O7t7l8GGC6FssNNn7_47sCsKCmmh_NmNY0l5U0_X_Bh57Ic8dBduFxegFHNi280PZCQQd5Hg7CIQMLaFWpEy9GzEh1BPILNKcXQyctDYenT2eMXq4p1SU3QN4hjoDAKFjRaCdkbTKdJJnG_s0pmoAFaV_zLxJedKSjbWXgw4JaKyWA_HoH9g_xeE_l77ff436ygGlODb90hRzk2g6yXZQ6AcEecf2r0B8EeOBi9IeDiOOABS-nBgjw_n6fT5hcyPu77HadrjZxE7_GKBnHfvZ61zD00MSvSU93K7moGvn48ujElRteXWEeJ7vWa26mcn0ug90aLX6mtvhrHy_VgtJe5MvmnCos19l0hnDAEtv2d3py9vE4Ww690-oxUtWsb5-nCzraOH2A8_EKLDiqI7vkNdfjw8R7fKui2UyHyQOqh4dbJ2LzMw2j-Hm2510yG-KRzG-rdJuImyJ4jm1P-8FYJZkcuWrbbOee9Dc_R7lWKHNLl47gK_dlq2vVXP78G37EK17-rhYsMJ-t3RYIYzfcyPJITas5ctwALOi4lkJ-7mDOzV4V_5t6jYMunCy3y1K_pQbjjL4EyqujpAvbKO9e2LScNmxzz-6b-Y_vCuDj6ePvAAAA___BBC2CwwMAAA=="},"kms":{"authToken":"H4sIAAAAAAAA_6RTS3PiOBj8L98c18SP2EB02oADhmBexkPC1BxkWbaFH_JIMuCk8t-3HMIc9jY1J7VK3dVSt753-JfwStGLAgSZUrVEun6-Z_EdF6kuKWkEU61-skADFn9xkK4XnOAi41KhYX_Y1_NS6jltpeKCSp0YRyuqHMabOCp-WQXPzLTTVyeeUwEIYhajnLbore_n5X7JTpEjvezNHJySWqTBOYzoMtlt_MnFZ6Ht4G2yDhzVb7_9qQA0wEXBzzR-JIrxCtAPIIJiRZ9pd0gvNRfqiiVLK9DgRAVLuv1Z4Bo0aKovQHhZN4r6j-PfrCumFRFtrUCDmN5QU8dY0Sf3-xjXOGIFU592WN6WVU07N0lx8Ql_XvMhuLvmDouUKkDvMHP_LvNdW1NA0IgK5aVENz58aFALzhNAP96_EunatQzL7BlWz7R2xhA598js3z3Y9mBg25b1j2EhwwANjmcJCGg7z6IpYSs2nxyetrtNMJOzcmYtx7P-oZxIYoVyVi5b_LJhq0Ky1-OrMSvMh7u7-7bc7UfHqTf2pjuflA8Ofr2EbzQ4L5wiOdkqtFthH9hiHDYsOZ1nrb-I3eeel2wHi2gxx6Itm01vaPV77ps52Z9Gw_V4AxpUvCLdc19W46jxh-SpyAO1fQ5ar12sKm-0dh97CWkm4Xo3GA2NMFv5wSR3cUKku_dl4k0qtrcP5uTyPVu-FL8WwZT0RvTRPKy3VWfwmdm6ETWXnQ_5Xa5LC5p-dgcaqGvoT7HlOOZDwNIKq0ZQyzCHt6_DrkX7VGU8_t9EpMfsudkfS1r1s-ZyGWfePA_WYYnvPfe8SQ6jUZZGWz4_TBPr258K4OPnx38BAAD__xy0S3b1AwAA"}}}`), // nolint: lll
+			Value: []byte(`{"did_url":"` + dURL + `", "kid":"` + kid + `","auth":` + vaultAuth + `}`),
 		}
 
+		before := time.Now().Truncate(time.Second)
+
 		created, err := client.CreateAuthorization(vID, vID, &vault.AuthorizationsScope{
 			Actions: []string{"read"},
-			Caveats: []vault.Caveat{{Type: zcapld.CaveatTypeExpiry, Duration: 100}},
+			Caveats: []vault.Caveat{
+				{Type: zcapld.CaveatTypeExpiry, Duration: 100},
+				{Type: zcapld.CaveatTypeExpiry, Duration: 50},
+			},
 		})
 		require.NoError(t, err)
 		require.NotEmpty(t, created.Tokens.EDV)
 		require.NotEmpty(t, created.Tokens.KMS)
+		require.Equal(t, vault.AuthorizationStatusActive, created.Status)
+		require.NotNil(t, created.ExpiresAt)
+		require.False(t, created.ExpiresAt.Before(before.Add(50*time.Second)))
+		require.False(t, created.ExpiresAt.After(time.Now().Add(50*time.Second)))
+
+		for _, token := range []string{created.Tokens.EDV, created.Tokens.KMS} {
+			zcap, err := zcapld.DecompressZCAP(token)
+			require.NoError(t, err)
+			require.Len(t, zcap.Caveats, 2)
+		}
+
+		stored, err := client.GetAuthorization(vID, created.ID)
+		require.NoError(t, err)
+		require.True(t, created.ExpiresAt.Equal(*stored.ExpiresAt))
 	})
-}
 
-func TestClient_GetDocMetadata(t *testing.T) {
-	loader := testutil.DocumentLoader(t)
+	t.Run("Persisted with its scope, tokens and validity", func(t *testing.T) {
+		provider := mem.NewProvider()
 
-	t.Run("No authorization", func(t *testing.T) {
-		client, err := vault.NewClient("", "", nil, &mockstorage.MockStoreProvider{
-			Store: &mockstorage.MockStore{},
-		}, loader)
+		lKMS := newLocalKms(t, provider)
+		client, err := vault.NewClient("", "", lKMS, provider, loader)
 		require.NoError(t, err)
 
-		_, err = client.GetDocMetadata("vID", "docID")
-		require.Error(t, err)
-		require.Contains(t, err.Error(), "get vault info: get: data not found")
+		vID, dURL, kid := createVaultID(t, lKMS)
+
+		store, err := provider.OpenStore("vault")
+		require.NoError(t, err)
+
+		require.NoError(t, store.Put("info_"+vID,
+			[]byte(`{"did_url":"`+dURL+`", "kid":"`+kid+`","auth":`+vaultAuth+`}`)))
+
+		created, err := client.CreateAuthorization(vID, "did:example:rp#key1", &vault.AuthorizationsScope{
+			Target:     "docID",
+			TargetAttr: "$.address",
+			Actions:    []string{"read"},
+			Caveats:    []vault.Caveat{{Type: zcapld.CaveatTypeExpiry, Duration: 600}},
+		})
+		require.NoError(t, err)
+
+		// a new client on the same store stands for a restarted server
+		restarted, err := vault.NewClient("", "", lKMS, provider, loader)
+		require.NoError(t, err)
+
+		stored, err := restarted.GetAuthorization(vID, created.ID)
+		require.NoError(t, err)
+
+		// the remaining validity is computed when the authorization is read
+		require.InDelta(t, *created.SecondsUntilExpiry, *stored.SecondsUntilExpiry, 1)
+		stored.SecondsUntilExpiry = created.SecondsUntilExpiry
+
+		require.Equal(t, created, stored)
+		require.Equal(t, "did:example:rp#key1", stored.RequestingParty)
+		require.Equal(t, "$.address", stored.Scope.TargetAttr)
+		require.Equal(t, created.Created.Add(600*time.Second), *stored.ExpiresAt)
+
+		list, err := restarted.ListAuthorizations(vID, nil)
+		require.NoError(t, err)
+		require.Len(t, list.Authorizations, 1)
+
+		raw, err := json.Marshal(list)
+		require.NoError(t, err)
+		require.NotContains(t, string(raw), created.Tokens.EDV)
+		require.NotContains(t, string(raw), created.Tokens.KMS)
+		require.Equal(t, created.Scope, list.Authorizations[0].Scope)
 	})
 
-	t.Run("No meta doc info", func(t *testing.T) {
+	t.Run("Success without expiry", func(t *testing.T) {
 		data := map[string]mockstorage.DBEntry{}
 
 		store := &mockstorage.MockStoreProvider{
@@ -755,18 +1194,26 @@ func TestClient_GetDocMetadata(t *testing.T) {
 		client, err := vault.NewClient("", "", lKMS, store, loader)
 		require.NoError(t, err)
 
-		vID, _, _ := createVaultID(t, lKMS)
+		vID, dURL, kid := createVaultID(t, lKMS)
 
 		data["info_"+vID] = mockstorage.DBEntry{
-			Value: []byte(`{"auth":{"edv":{},"kms":{}}}`),
+			Value: []byte(`{"did_url":"` + dURL + `", "kid":"` + kid + `","auth":` + vaultAuth + `}`),
 		}
 
-		_, err = client.GetDocMetadata(vID, "docID")
-		require.Error(t, err)
-		require.Contains(t, err.Error(), "get meta doc info: store get: data not found")
+		created, err := client.CreateAuthorization(vID, vID, &vault.AuthorizationsScope{Actions: []string{"read"}})
+		require.NoError(t, err)
+		require.Nil(t, created.ExpiresAt)
+		require.NotNil(t, created.Created)
+		require.Equal(t, vault.AuthorizationStatusActive, created.Status)
 	})
+}
+
+func TestClient_ListAuthorizations(t *testing.T) {
+	loader := testutil.DocumentLoader(t)
+
+	newClient := func(t *testing.T) (*vault.Client, vault.KeyManager, string) {
+		t.Helper()
 
-	t.Run("Bad meta info", func(t *testing.T) {
 		data := map[string]mockstorage.DBEntry{}
 
 		store := &mockstorage.MockStoreProvider{
@@ -777,65 +1224,1378 @@ func TestClient_GetDocMetadata(t *testing.T) {
 		client, err := vault.NewClient("", "", lKMS, store, loader)
 		require.NoError(t, err)
 
-		vID, _, _ := createVaultID(t, lKMS)
+		vID, dURL, kid := createVaultID(t, lKMS)
 
 		data["info_"+vID] = mockstorage.DBEntry{
-			Value: []byte(`{"auth":{"edv":{},"kms":{}}}`),
-		}
-		data["meta_doc_info_"+vID+"_docID"] = mockstorage.DBEntry{
-			Value: []byte(`{`),
+			Value: []byte(`{"did_url":"` + dURL + `", "kid":"` + kid + `","auth":` + vaultAuth + `}`),
 		}
 
-		_, err = client.GetDocMetadata(vID, "docID")
-		require.Error(t, err)
-		require.Contains(t, err.Error(), "get meta doc info: store get: unexpected end of JSON")
-	})
+		return client, lKMS, vID
+	}
 
-	t.Run("Success", func(t *testing.T) {
-		edvHandlers := make(chan func(w http.ResponseWriter, r *http.Request), 1)
-		edvHandlers <- func(w http.ResponseWriter, _ *http.Request) {
-			w.Header().Set("Location", "localhost:7777/encrypted-data-vaults/DWPPbEVn1afJY4We3kpQmq")
-			w.WriteHeader(http.StatusOK)
+	t.Run("Unknown vault", func(t *testing.T) {
+		client, _, _ := newClient(t)
 
-			_, err := w.Write([]byte(`{"@context":"https://w3id.org/security/v2","id":"urn:uuid:293817e5-3a47-4685-9bd3-51eba3d5e928","invoker":"did:key:z6MkqknydjnZe6ZqXNGEvjYTPxwmUzAkzS17LAJTuYsMQsyr#z6MkqknydjnZe6ZqXNGEvjYTPxwmUzAkzS17LAJTuYsMQsyr","parentCapability":"urn:uuid:3e7f55ea-2e2c-41bd-a167-3cb71db9ca14","allowedAction":["read","write"],"invocationTarget":{"ID":"DWPPbEVn1afJY4We3kpQmq","Type":"urn:edv:vault"},"proof":[{"capabilityChain":["urn:uuid:3e7f55ea-2e2c-41bd-a167-3cb71db9ca14"],"created":"2021-01-31T13:41:13.863452194+02:00","jws":"eyJhbGciOiJFZERTQSIsImI2NCI6ZmFsc2UsImNyaXQiOlsiYjY0Il19..NfznOmAi16H7fXJ1lI3-JzzHlOMopAhdGnBaF_FYK_F5BHbJMpH0u1aZ_JMgrG2XHUFMLNCBxG91DA-tJn2gDQ","nonce":"ZjtzLnBIpSNLteskV4bgTI8LOwrqrETpDI31qPglCNT_V-78ZmChHhqksMEu59WhkA_hofadF8saneziAhCDRA","proofPurpose":"capabilityDelegation","type":"Ed25519Signature2018","verificationMethod":"did:key:z6Mkpi5ZtFzsZv5UQhLzejwaNM5YX38cHBuMopUkayU13zyn#z6Mkpi5ZtFzsZv5UQhLzejwaNM5YX38cHBuMopUkayU13zyn"}]}`)) // nolint: lll
-			require.NoError(t, err)
-		}
+		_, err := client.ListAuthorizations("did:example:unknown", nil)
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+	})
 
-		edv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			select {
-			case fn := <-edvHandlers:
-				fn(w, r)
-			default:
-				t.Error("no handler")
-			}
-		}))
+	t.Run("No authorizations", func(t *testing.T) {
+		client, _, vID := newClient(t)
 
-		const docID = "docID"
+		list, err := client.ListAuthorizations(vID, nil)
+		require.NoError(t, err)
+		require.Empty(t, list.Next)
 
-		data := map[string]mockstorage.DBEntry{}
+		raw, err := json.Marshal(list)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"authorizations":[]}`, string(raw))
+	})
 
-		store := &mockstorage.MockStoreProvider{
-			Store: &mockstorage.MockStore{Store: data},
-		}
+	t.Run("Invalid continuation token", func(t *testing.T) {
+		client, _, vID := newClient(t)
 
-		lKMS := newLocalKms(t, store)
-		client, err := vault.NewClient("", edv.URL, lKMS, store, loader)
-		require.NoError(t, err)
+		_, err := client.ListAuthorizations(vID, &vault.AuthorizationQuery{Next: "!"})
+		require.True(t, errors.Is(err, vault.ErrInvalidContinuationToken))
+	})
 
-		vID, dURL, _ := createVaultID(t, lKMS)
+	t.Run("Success (pagination)", func(t *testing.T) {
+		client, _, vID := newClient(t)
+
+		ids := make([]string, 5)
+
+		for i := range ids {
+			a, err := client.CreateAuthorization(vID, vID, &vault.AuthorizationsScope{
+				Target:  fmt.Sprintf("doc%d", i),
+				Actions: []string{"read"},
+			})
+			require.NoError(t, err)
+
+			ids[i] = a.ID
+		}
+
+		sort.Strings(ids)
+
+		var (
+			listed []string
+			next   string
+			pages  int
+		)
+
+		for {
+			list, err := client.ListAuthorizations(vID, &vault.AuthorizationQuery{Limit: 2, Next: next})
+			require.NoError(t, err)
+			require.LessOrEqual(t, len(list.Authorizations), 2)
+
+			pages++
+
+			for _, a := range list.Authorizations {
+				listed = append(listed, a.ID)
+				require.Equal(t, vID, a.RequestingParty)
+				require.Equal(t, []string{"read"}, a.Scope.Actions)
+				require.NotNil(t, a.Created)
+				require.Equal(t, vault.AuthorizationStatusActive, a.Status)
+			}
+
+			if list.Next == "" {
+				break
+			}
+
+			next = list.Next
+		}
+
+		require.Equal(t, 3, pages)
+		require.Equal(t, ids, listed)
+	})
+
+	t.Run("Success (filters)", func(t *testing.T) {
+		client, lKMS, vID := newClient(t)
+
+		other, _, _ := createVaultID(t, lKMS)
+
+		active, err := client.CreateAuthorization(vID, vID, &vault.AuthorizationsScope{
+			Target:  "doc1",
+			Actions: []string{"read"},
+		})
+		require.NoError(t, err)
+
+		granted, err := client.CreateAuthorization(vID, other, &vault.AuthorizationsScope{
+			Target:  "doc1",
+			Actions: []string{"read"},
+		})
+		require.NoError(t, err)
+
+		expired, err := client.CreateAuthorization(vID, vID, &vault.AuthorizationsScope{
+			Target:  "doc2",
+			Actions: []string{"read"},
+			Caveats: []vault.Caveat{{Type: zcapld.CaveatTypeExpiry, Duration: 0}},
+		})
+		require.NoError(t, err)
+
+		list, err := client.ListAuthorizations(vID, nil)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{active.ID, granted.ID}, listIDs(list))
+
+		list, err = client.ListAuthorizations(vID, &vault.AuthorizationQuery{RequestingParty: other})
+		require.NoError(t, err)
+		require.Equal(t, []string{granted.ID}, listIDs(list))
+
+		list, err = client.ListAuthorizations(vID, &vault.AuthorizationQuery{RequestingParty: vID, IncludeExpired: true})
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{active.ID, expired.ID}, listIDs(list))
+
+		for _, a := range list.Authorizations {
+			if a.ID == expired.ID {
+				require.Equal(t, vault.AuthorizationStatusExpired, a.Status)
+				require.NotNil(t, a.ExpiresAt)
+			}
+		}
+
+		raw, err := json.Marshal(list)
+		require.NoError(t, err)
+		require.NotContains(t, string(raw), "authTokens")
+	})
+}
+
+func listIDs(list *vault.AuthorizationList) []string {
+	var ids []string
+
+	for _, a := range list.Authorizations {
+		ids = append(ids, a.ID)
+	}
+
+	return ids
+}
+
+func TestClient_DeleteAuthorization(t *testing.T) {
+	loader := testutil.DocumentLoader(t)
+
+	t.Run("Unknown authorization", func(t *testing.T) {
+		client, err := vault.NewClient("", "", nil, &mockstorage.MockStoreProvider{
+			Store: &mockstorage.MockStore{Store: map[string]mockstorage.DBEntry{}},
+		}, loader)
+		require.NoError(t, err)
+
+		err = client.DeleteAuthorization("vid", "id")
+		require.ErrorIs(t, err, storage.ErrDataNotFound)
+	})
+
+	t.Run("Malformed token", func(t *testing.T) {
+		client, err := vault.NewClient("", "", nil, &mockstorage.MockStoreProvider{
+			Store: &mockstorage.MockStore{
+				Store: map[string]mockstorage.DBEntry{
+					"authorization_vid_id": {Value: []byte(`{"authTokens":{"edv":"invalid"}}`)},
+				},
+			},
+		}, loader)
+		require.NoError(t, err)
+
+		err = client.DeleteAuthorization("vid", "id")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "revoke: uncompressZCAP")
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		data := map[string]mockstorage.DBEntry{}
+
+		store := &mockstorage.MockStoreProvider{
+			Store: &mockstorage.MockStore{Store: data},
+		}
+
+		lKMS := newLocalKms(t, store)
+		client, err := vault.NewClient("", "", lKMS, store, loader)
+		require.NoError(t, err)
+
+		vID, dURL, kid := createVaultID(t, lKMS)
+
+		data["info_"+vID] = mockstorage.DBEntry{
+			Value: []byte(`{"did_url":"` + dURL + `", "kid":"` + kid + `","auth":` + vaultAuth + `}`),
+		}
+
+		created, err := client.CreateAuthorization(vID, vID, &vault.AuthorizationsScope{Actions: []string{"read"}})
+		require.NoError(t, err)
+
+		for _, token := range []string{created.Tokens.EDV, created.Tokens.KMS} {
+			zcap, err := zcapld.DecompressZCAP(token)
+			require.NoError(t, err)
+
+			_, err = client.GetRevocation(zcap.ID)
+			require.ErrorIs(t, err, storage.ErrDataNotFound)
+		}
+
+		require.NoError(t, client.DeleteAuthorization(vID, created.ID))
+
+		_, err = client.GetAuthorization(vID, created.ID)
+		require.ErrorIs(t, err, storage.ErrDataNotFound)
+
+		for _, token := range []string{created.Tokens.EDV, created.Tokens.KMS} {
+			zcap, err := zcapld.DecompressZCAP(token)
+			require.NoError(t, err)
+
+			revocation, err := client.GetRevocation(zcap.ID)
+			require.NoError(t, err)
+			require.Equal(t, zcap.ID, revocation.ZcapID)
+			require.WithinDuration(t, time.Now(), revocation.RevokedAt, time.Minute)
+		}
+
+		err = client.DeleteAuthorization(vID, created.ID)
+		require.ErrorIs(t, err, storage.ErrDataNotFound)
+	})
+}
+
+func TestClient_GetDocMetadata(t *testing.T) {
+	loader := testutil.DocumentLoader(t)
+
+	t.Run("No authorization", func(t *testing.T) {
+		client, err := vault.NewClient("", "", nil, &mockstorage.MockStoreProvider{
+			Store: &mockstorage.MockStore{},
+		}, loader)
+		require.NoError(t, err)
+
+		_, err = client.GetDocMetadata("vID", "docID")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "get vault info: get: data not found")
+	})
+
+	t.Run("No meta doc info", func(t *testing.T) {
+		data := map[string]mockstorage.DBEntry{}
+
+		store := &mockstorage.MockStoreProvider{
+			Store: &mockstorage.MockStore{Store: data},
+		}
+
+		lKMS := newLocalKms(t, store)
+		client, err := vault.NewClient("", "", lKMS, store, loader)
+		require.NoError(t, err)
+
+		vID, _, _ := createVaultID(t, lKMS)
+
+		data["info_"+vID] = mockstorage.DBEntry{
+			Value: []byte(`{"auth":{"edv":{},"kms":{}}}`),
+		}
+
+		_, err = client.GetDocMetadata(vID, "docID")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "get meta doc info: store get: data not found")
+	})
+
+	t.Run("Bad meta info", func(t *testing.T) {
+		data := map[string]mockstorage.DBEntry{}
+
+		store := &mockstorage.MockStoreProvider{
+			Store: &mockstorage.MockStore{Store: data},
+		}
+
+		lKMS := newLocalKms(t, store)
+		client, err := vault.NewClient("", "", lKMS, store, loader)
+		require.NoError(t, err)
+
+		vID, _, _ := createVaultID(t, lKMS)
+
+		data["info_"+vID] = mockstorage.DBEntry{
+			Value: []byte(`{"auth":{"edv":{},"kms":{}}}`),
+		}
+		data["meta_doc_info_"+vID+"_docID"] = mockstorage.DBEntry{
+			Value: []byte(`{`),
+		}
+
+		_, err = client.GetDocMetadata(vID, "docID")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "get meta doc info: store get: unexpected end of JSON")
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		edvHandlers := make(chan func(w http.ResponseWriter, r *http.Request), 1)
+		edvHandlers <- func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Location", "localhost:7777/encrypted-data-vaults/DWPPbEVn1afJY4We3kpQmq")
+			w.WriteHeader(http.StatusOK)
+
+			_, err := w.Write([]byte(edvCapability))
+			require.NoError(t, err)
+		}
+
+		edv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case fn := <-edvHandlers:
+				fn(w, r)
+			default:
+				t.Error("no handler")
+			}
+		}))
+
+		const docID = "docID"
+
+		data := map[string]mockstorage.DBEntry{}
+
+		store := &mockstorage.MockStoreProvider{
+			Store: &mockstorage.MockStore{Store: data},
+		}
+
+		lKMS := newLocalKms(t, store)
+		client, err := vault.NewClient("", edv.URL, lKMS, store, loader)
+		require.NoError(t, err)
+
+		vID, dURL, _ := createVaultID(t, lKMS)
+
+		data["info_"+vID] = mockstorage.DBEntry{
+			Value: []byte(`{"did_url":"` + dURL + `", "auth":{"edv":{},"kms":{}}}`),
+		}
+		data["meta_doc_info_"+vID+"_"+docID] = mockstorage.DBEntry{
+			Value: []byte(`{"edv_id":"eURL", "kid_url":"kURL"}`),
+		}
+
+		docMeta, err := client.GetDocMetadata(vID, docID)
+		require.NoError(t, err)
+		require.NotEmpty(t, docMeta.ID)
+		require.NotEmpty(t, docMeta.URI)
+		require.NotEmpty(t, docMeta.EncKeyURI)
+		// timestamps were not recorded
+		require.Nil(t, docMeta.CreatedAt)
+		require.Nil(t, docMeta.UpdatedAt)
+	})
+}
+
+func TestClient_GetDoc(t *testing.T) {
+	loader := testutil.DocumentLoader(t)
+
+	t.Run("Success (JSON round trip)", func(t *testing.T) {
+		client, vID := newRoundTripVaultClient(t, loader)
+
+		_, err := client.SaveDoc(vID, "doc1", []byte(`{"message":"Hello World!"}`))
+		require.NoError(t, err)
+
+		content, err := client.GetDocContent(vID, "doc1")
+		require.NoError(t, err)
+		require.Equal(t, "application/json", content.MediaType)
+		require.JSONEq(t, `{"message":"Hello World!"}`, string(content.Data))
+
+		doc, err := client.GetDoc(vID, "doc1")
+		require.NoError(t, err)
+		require.JSONEq(t, `{"message":"Hello World!"}`, string(doc))
+	})
+
+	t.Run("Success (binary round trip)", func(t *testing.T) {
+		client, vID := newRoundTripVaultClient(t, loader)
+
+		pdf := []byte("%PDF-1.7\n\x00\xff\xfe binary")
+
+		_, err := client.SaveBinaryDoc(vID, "doc1", "application/pdf", pdf)
+		require.NoError(t, err)
+
+		content, err := client.GetDocContent(vID, "doc1")
+		require.NoError(t, err)
+		require.Equal(t, "application/pdf", content.MediaType)
+		require.Equal(t, pdf, content.Data)
+	})
+
+	t.Run("No authorization", func(t *testing.T) {
+		client, err := vault.NewClient("", "", nil, &mockstorage.MockStoreProvider{
+			Store: &mockstorage.MockStore{},
+		}, loader)
+		require.NoError(t, err)
+
+		_, err = client.GetDoc("vID", "docID")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "get vault info: get: data not found")
+	})
+
+	t.Run("No meta doc info", func(t *testing.T) {
+		data := map[string]mockstorage.DBEntry{}
+
+		store := &mockstorage.MockStoreProvider{
+			Store: &mockstorage.MockStore{Store: data},
+		}
+
+		lKMS := newLocalKms(t, store)
+		client, err := vault.NewClient("", "", lKMS, store, loader)
+		require.NoError(t, err)
+
+		vID, _, _ := createVaultID(t, lKMS)
+
+		data["info_"+vID] = mockstorage.DBEntry{
+			Value: []byte(`{"auth":{"edv":{},"kms":{}}}`),
+		}
+
+		_, err = client.GetDoc(vID, "docID")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "get meta doc info: store get: data not found")
+	})
+
+	t.Run("Read document error", func(t *testing.T) {
+		edv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer edv.Close()
+
+		data := map[string]mockstorage.DBEntry{}
+
+		store := &mockstorage.MockStoreProvider{
+			Store: &mockstorage.MockStore{Store: data},
+		}
+
+		lKMS := newLocalKms(t, store)
+		client, err := vault.NewClient("", edv.URL, lKMS, store, loader)
+		require.NoError(t, err)
+
+		vID, dURL, _ := createVaultID(t, lKMS)
+
+		data["info_"+vID] = mockstorage.DBEntry{
+			Value: []byte(`{"did_url":"` + dURL + `", "auth":{"edv":{},"kms":{}}}`),
+		}
+		data["meta_doc_info_"+vID+"_docID"] = mockstorage.DBEntry{
+			Value: []byte(`{"edv_id":"eURL", "kid_url":"kURL"}`),
+		}
+
+		_, err = client.GetDoc(vID, "docID")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "read document")
+	})
+
+	t.Run("Document not found in the EDV", func(t *testing.T) {
+		for _, message := range []string{"specified document does not exist.", "no such document", ""} {
+			edv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+
+				_, err := w.Write([]byte(message))
+				require.NoError(t, err)
+			}))
+
+			provider := mem.NewProvider()
+
+			lKMS := newLocalKms(t, provider)
+			client, err := vault.NewClient("", edv.URL+"/encrypted-data-vaults", lKMS, provider, loader)
+			require.NoError(t, err)
+
+			vID, dURL, _ := createVaultID(t, lKMS)
+
+			store, err := provider.OpenStore("vault")
+			require.NoError(t, err)
+
+			require.NoError(t, store.Put("info_"+vID, []byte(`{"did_url":"`+dURL+`",`+
+				`"auth":{"edv":{"uri":"/encrypted-data-vaults/edvVaultID"},"kms":{}}}`)))
+			require.NoError(t, store.Put("meta_doc_info_"+vID+"_docID", []byte(`{"edv_id":"eURL", "kid_url":"kURL"}`)))
+
+			_, err = client.GetDoc(vID, "docID")
+			require.True(t, errors.Is(err, vault.ErrDocumentNotFound), message)
+			require.False(t, errors.Is(err, vault.ErrVaultNotFound), message)
+
+			edv.Close()
+		}
+	})
+
+	t.Run("Decrypt document error", func(t *testing.T) {
+		edv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+
+			_, err := w.Write([]byte(`{"id":"eURL","jwe":{}}`))
+			require.NoError(t, err)
+		}))
+		defer edv.Close()
+
+		data := map[string]mockstorage.DBEntry{}
+
+		store := &mockstorage.MockStoreProvider{
+			Store: &mockstorage.MockStore{Store: data},
+		}
+
+		lKMS := newLocalKms(t, store)
+		client, err := vault.NewClient("", edv.URL, lKMS, store, loader)
+		require.NoError(t, err)
+
+		vID, dURL, _ := createVaultID(t, lKMS)
+
+		data["info_"+vID] = mockstorage.DBEntry{
+			Value: []byte(`{"did_url":"` + dURL + `", "auth":{"edv":{},"kms":{}}}`),
+		}
+		data["meta_doc_info_"+vID+"_docID"] = mockstorage.DBEntry{
+			Value: []byte(`{"edv_id":"eURL", "kid_url":"kURL"}`),
+		}
+
+		_, err = client.GetDoc(vID, "docID")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "decrypt document: deserialize")
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		const docID = "docID"
+
+		// keys held by the remote KMS
+		kmsKeys := newLocalKms(t, mem.NewProvider())
+
+		kmsCrypto, err := tinkcrypto.New()
+		require.NoError(t, err)
+
+		jwe := encryptDoc(t, kmsKeys, kmsCrypto, &models.StructuredDocument{
+			ID:      "eURL",
+			Content: map[string]interface{}{"message": "Hello World!"},
+		})
+
+		remoteKMS := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var req struct {
+				WrappedKey crypto.RecipientWrappedKey `json:"wrapped_key"`
+			}
+
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+			kh, err := kmsKeys.Get(req.WrappedKey.KID)
+			require.NoError(t, err)
+
+			cek, err := kmsCrypto.UnwrapKey(&req.WrappedKey, kh)
+			require.NoError(t, err)
+
+			w.WriteHeader(http.StatusOK)
+			require.NoError(t, json.NewEncoder(w).Encode(map[string][]byte{"key": cek}))
+		}))
+		defer remoteKMS.Close()
+
+		edv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			require.NoError(t, json.NewEncoder(w).Encode(&models.EncryptedDocument{
+				ID:  "eURL",
+				JWE: []byte(jwe),
+			}))
+		}))
+		defer edv.Close()
+
+		data := map[string]mockstorage.DBEntry{}
+
+		store := &mockstorage.MockStoreProvider{
+			Store: &mockstorage.MockStore{Store: data},
+		}
+
+		lKMS := newLocalKms(t, store)
+		client, err := vault.NewClient(remoteKMS.URL, edv.URL, lKMS, store, loader)
+		require.NoError(t, err)
+
+		vID, dURL, _ := createVaultID(t, lKMS)
+
+		data["info_"+vID] = mockstorage.DBEntry{
+			Value: []byte(`{"did_url":"` + dURL + `", "auth":{"edv":{},"kms":{"uri":"/kms/keystores/ks1"}}}`),
+		}
+		data["meta_doc_info_"+vID+"_"+docID] = mockstorage.DBEntry{
+			Value: []byte(`{"edv_id":"eURL", "kid_url":"kURL"}`),
+		}
+
+		content, err := client.GetDoc(vID, docID)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"message":"Hello World!"}`, string(content))
+	})
+}
+
+func encryptDoc(t *testing.T, km vault.KeyManager, c crypto.Crypto, doc *models.StructuredDocument) string {
+	t.Helper()
+
+	kid, _, err := km.Create(kms.NISTP256ECDHKW)
+	require.NoError(t, err)
+
+	pubKeyBytes, _, err := km.ExportPubKeyBytes(kid)
+	require.NoError(t, err)
+
+	var pubKey *crypto.PublicKey
+
+	require.NoError(t, json.Unmarshal(pubKeyBytes, &pubKey))
+
+	pubKey.KID = kid
+
+	encrypter, err := jose.NewJWEEncrypt(jose.A256GCM, jose.A256GCMALG, "", "", nil,
+		[]*crypto.PublicKey{pubKey}, c)
+	require.NoError(t, err)
+
+	src, err := json.Marshal(doc)
+	require.NoError(t, err)
+
+	jwe, err := encrypter.Encrypt(src)
+	require.NoError(t, err)
+
+	serialized, err := jwe.FullSerialize(json.Marshal)
+	require.NoError(t, err)
+
+	return serialized
+}
+
+func TestClient_DeleteDoc(t *testing.T) {
+	loader := testutil.DocumentLoader(t)
+
+	t.Run("No meta doc info", func(t *testing.T) {
+		data := map[string]mockstorage.DBEntry{}
+
+		store := &mockstorage.MockStoreProvider{
+			Store: &mockstorage.MockStore{Store: data},
+		}
+
+		lKMS := newLocalKms(t, store)
+		client, err := vault.NewClient("", "", lKMS, store, loader)
+		require.NoError(t, err)
+
+		vID, _, _ := createVaultID(t, lKMS)
+
+		data["info_"+vID] = mockstorage.DBEntry{
+			Value: []byte(`{"auth":{"edv":{},"kms":{}}}`),
+		}
+
+		err = client.DeleteDoc(vID, "docID")
+		require.Error(t, err)
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+	})
+
+	t.Run("Delete document error", func(t *testing.T) {
+		edv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, http.MethodDelete, r.Method)
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer edv.Close()
+
+		data := map[string]mockstorage.DBEntry{}
+
+		store := &mockstorage.MockStoreProvider{
+			Store: &mockstorage.MockStore{Store: data},
+		}
+
+		lKMS := newLocalKms(t, store)
+		client, err := vault.NewClient("", edv.URL, lKMS, store, loader)
+		require.NoError(t, err)
+
+		vID, dURL, _ := createVaultID(t, lKMS)
 
 		data["info_"+vID] = mockstorage.DBEntry{
 			Value: []byte(`{"did_url":"` + dURL + `", "auth":{"edv":{},"kms":{}}}`),
 		}
-		data["meta_doc_info_"+vID+"_"+docID] = mockstorage.DBEntry{
-			Value: []byte(`{"edv_id":"eURL", "kid_url":"kURL"}`),
+		data["meta_doc_info_"+vID+"_docID"] = mockstorage.DBEntry{
+			Value: []byte(`{"edv_id":"eURL", "kid_url":"kURL"}`),
+		}
+
+		err = client.DeleteDoc(vID, "docID")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "delete document")
+		require.Contains(t, data, "meta_doc_info_"+vID+"_docID")
+	})
+
+	for _, resp := range []struct {
+		status  int
+		message string
+	}{
+		{status: http.StatusOK},
+		{status: http.StatusNotFound, message: messages.ErrDocumentNotFound.Error() + "."},
+		{status: http.StatusNotFound, message: "no such document"},
+	} {
+		resp := resp
+
+		t.Run(fmt.Sprintf("Success when EDV returns %d %q", resp.status, resp.message), func(t *testing.T) {
+			const docID = "docID"
+
+			edv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, http.MethodDelete, r.Method)
+				require.True(t, strings.HasSuffix(r.URL.Path, "/documents/eURL"))
+
+				w.WriteHeader(resp.status)
+
+				_, err := w.Write([]byte(resp.message))
+				require.NoError(t, err)
+			}))
+			defer edv.Close()
+
+			provider := mem.NewProvider()
+
+			lKMS := newLocalKms(t, provider)
+			client, err := vault.NewClient("", edv.URL, lKMS, provider, loader)
+			require.NoError(t, err)
+
+			vID, dURL, kid := createVaultID(t, lKMS)
+
+			store, err := provider.OpenStore("vault")
+			require.NoError(t, err)
+
+			require.NoError(t, store.Put("info_"+vID,
+				[]byte(`{"did_url":"`+dURL+`", "kid":"`+kid+`","auth":`+vaultAuth+`}`)))
+			require.NoError(t, store.Put("meta_doc_info_"+vID+"_"+docID,
+				[]byte(`{"edv_id":"eURL", "kid_url":"kURL"}`)))
+
+			docAuth, err := client.CreateAuthorization(vID, vID, &vault.AuthorizationsScope{
+				Target:  docID,
+				Actions: []string{"read"},
+			})
+			require.NoError(t, err)
+
+			otherAuth, err := client.CreateAuthorization(vID, vID, &vault.AuthorizationsScope{
+				Target:  "otherDocID",
+				Actions: []string{"read"},
+			})
+			require.NoError(t, err)
+
+			require.NoError(t, client.DeleteDoc(vID, docID))
+
+			_, err = client.GetDocMetadata(vID, docID)
+			require.True(t, errors.Is(err, storage.ErrDataNotFound))
+
+			err = client.DeleteDoc(vID, docID)
+			require.True(t, errors.Is(err, storage.ErrDataNotFound))
+
+			a, err := client.GetAuthorization(vID, docAuth.ID)
+			require.NoError(t, err)
+			require.True(t, a.Revoked)
+
+			a, err = client.GetAuthorization(vID, otherAuth.ID)
+			require.NoError(t, err)
+			require.False(t, a.Revoked)
+		})
+	}
+}
+
+func TestClient_ListDocs(t *testing.T) {
+	loader := testutil.DocumentLoader(t)
+
+	newClient := func(t *testing.T) (*vault.Client, string) {
+		t.Helper()
+
+		client, vID, _ := newLegacyVaultClient(t, loader, &backendFailures{})
+
+		return client, vID
+	}
+
+	t.Run("Unknown vault", func(t *testing.T) {
+		client, _ := newClient(t)
+
+		_, err := client.ListDocs("did:example:unknown", 0, "")
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+	})
+
+	t.Run("Empty vault", func(t *testing.T) {
+		client, vID := newClient(t)
+
+		list, err := client.ListDocs(vID, 0, "")
+		require.NoError(t, err)
+		require.NotNil(t, list.Documents)
+		require.Empty(t, list.Documents)
+		require.Empty(t, list.Next)
+
+		raw, err := json.Marshal(list)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"documents":[]}`, string(raw))
+	})
+
+	t.Run("Invalid continuation token", func(t *testing.T) {
+		client, vID := newClient(t)
+
+		_, err := client.ListDocs(vID, 0, "!")
+		require.True(t, errors.Is(err, vault.ErrInvalidContinuationToken))
+	})
+
+	t.Run("Success (pagination)", func(t *testing.T) {
+		client, vID := newClient(t)
+
+		for _, docID := range []string{"doc3", "doc1", "doc5", "doc2", "doc4"} {
+			_, err := client.SaveDoc(vID, docID, []byte(`{"secret":"value"}`))
+			require.NoError(t, err)
+		}
+
+		var (
+			docIDs []string
+			next   string
+			pages  int
+		)
+
+		for {
+			list, err := client.ListDocs(vID, 2, next)
+			require.NoError(t, err)
+			require.LessOrEqual(t, len(list.Documents), 2)
+
+			pages++
+
+			for _, d := range list.Documents {
+				docIDs = append(docIDs, d.ID)
+				require.Contains(t, d.URI, "/encrypted-data-vaults/DWPPbEVn1afJY4We3kpQmq/documents/")
+				require.False(t, d.Created.IsZero())
+				require.Equal(t, d.Created, d.Updated)
+			}
+
+			if list.Next == "" {
+				break
+			}
+
+			next = list.Next
+		}
+
+		require.Equal(t, 3, pages)
+		require.Equal(t, []string{"doc1", "doc2", "doc3", "doc4", "doc5"}, docIDs)
+	})
+
+	t.Run("Success (update)", func(t *testing.T) {
+		client, vID := newClient(t)
+
+		_, err := client.SaveDoc(vID, "doc1", []byte(`{"secret":"value"}`))
+		require.NoError(t, err)
+
+		_, err = client.SaveDoc(vID, "doc1", []byte(`{"secret":"new value"}`))
+		require.NoError(t, err)
+
+		list, err := client.ListDocs(vID, 0, "")
+		require.NoError(t, err)
+		require.Len(t, list.Documents, 1)
+		require.True(t, list.Documents[0].Updated.After(list.Documents[0].Created))
+
+		raw, err := json.Marshal(list)
+		require.NoError(t, err)
+		require.NotContains(t, string(raw), "value")
+	})
+}
+
+// backendFailures makes the fake EDV and KMS servers of newLegacyVaultClient fail deletions.
+// The servers count the deletion requests they receive.
+type backendFailures struct {
+	edv, kms bool
+	// gone makes the servers report the resources to delete as not found.
+	gone                   bool
+	edvDeletes, kmsDeletes int
+}
+
+// newLegacyVaultClient returns a client with a vault saved without the vault info schema version.
+func newLegacyVaultClient(t *testing.T, loader ld.DocumentLoader,
+	fail *backendFailures,
+) (*vault.Client, string, storage.Store) {
+	t.Helper()
+
+	remoteKMS := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			fail.kmsDeletes++
+
+			switch {
+			case fail.kms:
+				w.WriteHeader(http.StatusInternalServerError)
+			case fail.gone:
+				w.WriteHeader(http.StatusNotFound)
+			default:
+				w.WriteHeader(http.StatusOK)
+			}
+
+			return
+		}
+
+		var payload []byte
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/keys"):
+			payload = []byte(`{"key_url":"/v1/keystores/c0ekinlioud42c84qs7g/keys/GKszTDQcWrFlMS-BO7-asfNgaFfMZ96t6eeTjI__Y1c"}`) //nolint:lll
+		case strings.HasSuffix(r.URL.Path, "/export"):
+			var err error
+
+			payload, err = json.Marshal(map[string][]byte{"public_key": []byte(`{"kid":"GKszTDQcWrFlMS-BO7-asfNgaFfMZ96t6eeTjI__Y1c","x":"IM1/HfveJ4rbqAYzBOmVOnpys4h3J0yA3I238AjYzZc=","y":"S+h2S7IbWCZiQjOaNIhSvyqNcRnRKavdiC1BU8F2UU4=","curve":"NIST_P256","type":"EC"}`)}) // nolint: lll
+			require.NoError(t, err)
+		default:
+			payload = []byte(kmsResponse)
 		}
 
-		docMeta, err := client.GetDocMetadata(vID, docID)
+		w.WriteHeader(http.StatusOK)
+
+		_, err := w.Write(payload)
 		require.NoError(t, err)
-		require.NotEmpty(t, docMeta.ID)
-		require.NotEmpty(t, docMeta.URI)
-		require.NotEmpty(t, docMeta.EncKeyURI)
+	}))
+	t.Cleanup(remoteKMS.Close)
+
+	edv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			fail.edvDeletes++
+
+			switch {
+			case fail.edv:
+				w.WriteHeader(http.StatusInternalServerError)
+			case fail.gone:
+				w.WriteHeader(http.StatusNotFound)
+
+				_, err := w.Write([]byte("Failed to delete document: specified vault does not exist."))
+				require.NoError(t, err)
+			default:
+				w.WriteHeader(http.StatusOK)
+			}
+
+			return
+		}
+
+		require.Equal(t, http.MethodPost, r.Method)
+
+		w.Header().Set("Location", "localhost:7777/encrypted-data-vaults/DWPPbEVn1afJY4We3kpQmq/documents/id")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	t.Cleanup(edv.Close)
+
+	provider := mem.NewProvider()
+
+	lKMS := newLocalKms(t, provider)
+	client, err := vault.NewClient(remoteKMS.URL, edv.URL, lKMS, provider, loader)
+	require.NoError(t, err)
+
+	vID, dURL, _ := createVaultID(t, lKMS)
+
+	store, err := provider.OpenStore("vault")
+	require.NoError(t, err)
+
+	require.NoError(t, store.Put("info_"+vID, []byte(`{"did_url":"`+dURL+
+		`", "auth":{"edv":{"uri":"/encrypted-data-vaults/DWPPbEVn1afJY4We3kpQmq"},`+
+		`"kms":{"uri":"/v1/keystores/c0ekinlioud42c84qs7g"}}}`)))
+
+	// no untagged records
+	_, err = client.TagLegacyRecords(nil)
+	require.NoError(t, err)
+
+	return client, vID, store
+}
+
+// newRoundTripVaultClient returns a client whose fake EDV and KMS servers store documents and encryption keys,
+// so that saved documents can be read back.
+func newRoundTripVaultClient(t *testing.T, loader ld.DocumentLoader, opts ...vault.Opt) (*vault.Client, string) {
+	t.Helper()
+
+	remoteKMS := httptest.NewServer(newKMSHandler(t))
+	t.Cleanup(remoteKMS.Close)
+
+	edv := httptest.NewServer(newEDVHandler(t))
+	t.Cleanup(edv.Close)
+
+	provider := mem.NewProvider()
+
+	lKMS := newLocalKms(t, provider)
+	client, err := vault.NewClient(remoteKMS.URL, edv.URL, lKMS, provider, loader, opts...)
+	require.NoError(t, err)
+
+	vID, dURL, _ := createVaultID(t, lKMS)
+
+	store, err := provider.OpenStore("vault")
+	require.NoError(t, err)
+
+	require.NoError(t, store.Put("info_"+vID, []byte(`{"did_url":"`+dURL+
+		`", "auth":{"edv":{"uri":"/encrypted-data-vaults/DWPPbEVn1afJY4We3kpQmq"},`+
+		`"kms":{"uri":"/v1/keystores/c0ekinlioud42c84qs7g"}}}`)))
+
+	return client, vID
+}
+
+// newEDVHandler returns a fake EDV server keeping documents in memory.
+func newEDVHandler(t *testing.T) http.HandlerFunc {
+	t.Helper()
+
+	var (
+		mu   sync.Mutex
+		docs = map[string][]byte{}
+	)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if r.Method == http.MethodGet {
+			doc, ok := docs[lastPathElement(r.URL.Path)]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+
+				return
+			}
+
+			_, err := w.Write(doc)
+			require.NoError(t, err)
+
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/query") {
+			var query models.Query
+
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&query))
+
+			docURLs := []string{}
+
+			for id, doc := range docs {
+				var encDoc models.EncryptedDocument
+
+				require.NoError(t, json.Unmarshal(doc, &encDoc))
+
+				for _, c := range encDoc.IndexedAttributeCollections {
+					for _, attr := range c.IndexedAttributes {
+						if attr.Name == query.Name && attr.Value == query.Value {
+							docURLs = append(docURLs, strings.TrimSuffix(r.URL.Path, "/query")+"/documents/"+id)
+						}
+					}
+				}
+			}
+
+			require.NoError(t, json.NewEncoder(w).Encode(docURLs))
+
+			return
+		}
+
+		// vault creation
+		if r.URL.Path == "" || r.URL.Path == "/" {
+			w.Header().Set("Location", "localhost:7777/encrypted-data-vaults/DWPPbEVn1afJY4We3kpQmq")
+			w.WriteHeader(http.StatusCreated)
+
+			_, err := w.Write([]byte(edvCapability))
+			require.NoError(t, err)
+
+			return
+		}
+
+		doc, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		var encDoc models.EncryptedDocument
+
+		require.NoError(t, json.Unmarshal(doc, &encDoc))
+
+		docs[encDoc.ID] = doc
+
+		// document update
+		if lastPathElement(r.URL.Path) == encDoc.ID {
+			w.WriteHeader(http.StatusOK)
+
+			return
+		}
+
+		w.Header().Set("Location", r.URL.Path+"/"+encDoc.ID)
+		w.WriteHeader(http.StatusCreated)
+	}
+}
+
+// newKMSHandler returns a fake WebKMS server backed by a local KMS. Each created keystore gets its own URL, and keys
+// are only served from the keystore they were created in.
+func newKMSHandler(t *testing.T) http.HandlerFunc {
+	t.Helper()
+
+	keys := newLocalKms(t, mem.NewProvider())
+
+	cr, err := tinkcrypto.New()
+	require.NoError(t, err)
+
+	var (
+		mu sync.Mutex
+		// keystores are the paths of the keystores of the keys
+		keystores = map[string]string{}
+	)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var resp interface{}
+
+		if i := strings.Index(r.URL.Path, "/keys/"); i >= 0 {
+			kid := strings.SplitN(r.URL.Path[i+len("/keys/"):], "/", 2)[0]
+
+			mu.Lock()
+			keystore := keystores[kid]
+			mu.Unlock()
+
+			if keystore != r.URL.Path[:i] {
+				w.WriteHeader(http.StatusNotFound)
+
+				return
+			}
+		}
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/keystores"):
+			w.WriteHeader(http.StatusOK)
+
+			_, err := w.Write([]byte(strings.Replace(keystoreResponse, "c0b9em5ioud57602s7og", uuid.New().String(), 1)))
+			require.NoError(t, err)
+
+			return
+		case strings.HasSuffix(r.URL.Path, "/keys"):
+			var req struct {
+				KeyType kms.KeyType `json:"key_type"`
+			}
+
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+			kid, _, err := keys.Create(req.KeyType)
+			require.NoError(t, err)
+
+			mu.Lock()
+			keystores[kid] = strings.TrimSuffix(r.URL.Path, "/keys")
+			mu.Unlock()
+
+			resp = map[string]string{"key_url": r.URL.Path + "/" + kid}
+		case strings.HasSuffix(r.URL.Path, "/computemac"):
+			var req struct {
+				Data []byte `json:"data"`
+			}
+
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+			kh, err := keys.Get(lastPathElement(strings.TrimSuffix(r.URL.Path, "/computemac")))
+			require.NoError(t, err)
+
+			mac, err := cr.ComputeMAC(req.Data, kh)
+			require.NoError(t, err)
+
+			resp = map[string][]byte{"mac": mac}
+		case strings.HasSuffix(r.URL.Path, "/export"):
+			pub, _, err := keys.ExportPubKeyBytes(lastPathElement(strings.TrimSuffix(r.URL.Path, "/export")))
+			require.NoError(t, err)
+
+			resp = map[string][]byte{"public_key": pub}
+		case strings.HasSuffix(r.URL.Path, "/unwrap"):
+			var req struct {
+				WrappedKey crypto.RecipientWrappedKey `json:"wrapped_key"`
+			}
+
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+			kh, err := keys.Get(lastPathElement(strings.TrimSuffix(r.URL.Path, "/unwrap")))
+			require.NoError(t, err)
+
+			key, err := cr.UnwrapKey(&req.WrappedKey, kh)
+			require.NoError(t, err)
+
+			resp = map[string][]byte{"key": key}
+		case strings.HasSuffix(r.URL.Path, "/wrap"):
+			var req struct {
+				CEK             []byte            `json:"cek"`
+				APU             []byte            `json:"apu"`
+				APV             []byte            `json:"apv"`
+				RecipientPubKey *crypto.PublicKey `json:"recipient_pub_key"`
+			}
+
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+			resp, err = cr.WrapKey(req.CEK, req.APU, req.APV, req.RecipientPubKey)
+			require.NoError(t, err)
+		default:
+			w.WriteHeader(http.StatusOK)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		require.NoError(t, json.NewEncoder(w).Encode(resp))
+	}
+}
+
+func lastPathElement(p string) string {
+	return p[strings.LastIndex(p, "/")+1:]
+}
+
+func TestClient_GetVaultInfo(t *testing.T) {
+	loader := testutil.DocumentLoader(t)
+
+	t.Run("Unknown vault", func(t *testing.T) {
+		client, _, _ := newLegacyVaultClient(t, loader, &backendFailures{})
+
+		_, err := client.GetVaultInfo("did:example:unknown")
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+	})
+
+	t.Run("Doc count", func(t *testing.T) {
+		client, vID, _ := newLegacyVaultClient(t, loader, &backendFailures{})
+
+		info, err := client.GetVaultInfo(vID)
+		require.NoError(t, err)
+		require.Equal(t, vID, info.ID)
+		require.Equal(t, vID, info.Controller)
+		require.Nil(t, info.Created)
+		require.Zero(t, info.DocCount)
+		require.Equal(t, "/encrypted-data-vaults/DWPPbEVn1afJY4We3kpQmq", info.EDVURI)
+		require.Equal(t, "/v1/keystores/c0ekinlioud42c84qs7g", info.KMSURI)
+
+		for _, docID := range []string{"doc1", "doc2", "doc1"} {
+			_, err = client.SaveDoc(vID, docID, []byte(`{"secret":"value"}`))
+			require.NoError(t, err)
+		}
+
+		info, err = client.GetVaultInfo(vID)
+		require.NoError(t, err)
+		require.Equal(t, 2, info.DocCount)
+
+		require.NoError(t, client.DeleteDoc(vID, "doc1"))
+
+		info, err = client.GetVaultInfo(vID)
+		require.NoError(t, err)
+		require.Equal(t, 1, info.DocCount)
+
+		raw, err := json.Marshal(info)
+		require.NoError(t, err)
+		require.NotContains(t, string(raw), "authToken")
+	})
+
+	t.Run("Migration", func(t *testing.T) {
+		provider := mem.NewProvider()
+
+		lKMS := newLocalKms(t, provider)
+		client, err := vault.NewClient("", "http://localhost", lKMS, provider, loader)
+		require.NoError(t, err)
+
+		vID, dURL, _ := createVaultID(t, lKMS)
+
+		store, err := provider.OpenStore("vault")
+		require.NoError(t, err)
+
+		require.NoError(t, store.Put("info_"+vID, []byte(`{"did_url":"`+dURL+`", "auth":{"edv":{},"kms":{}}}`)))
+
+		index := sha256.Sum256([]byte("info_" + vID))
+
+		for _, docID := range []string{"doc1", "doc2", "doc3"} {
+			require.NoError(t, store.Put("meta_doc_info_"+vID+"_"+docID, []byte(`{"edv_id":"eURL"}`),
+				storage.Tag{Name: "vault_docs", Value: hex.EncodeToString(index[:])}))
+		}
+
+		info, err := client.GetVaultInfo(vID)
+		require.NoError(t, err)
+		require.Equal(t, 3, info.DocCount)
+		require.Nil(t, info.Created)
+
+		src, err := store.Get("info_" + vID)
+		require.NoError(t, err)
+		require.Contains(t, string(src), `"version":3`)
+		require.Contains(t, string(src), `"doc_count":3`)
+
+		docs, err := client.ListDocs(vID, 2, "")
+		require.NoError(t, err)
+		require.Len(t, docs.Documents, 2)
+		require.Equal(t, "doc1", docs.Documents[0].ID)
+		require.Equal(t, "doc2", docs.Documents[1].ID)
+
+		docs, err = client.ListDocs(vID, 2, docs.Next)
+		require.NoError(t, err)
+		require.Len(t, docs.Documents, 1)
+		require.Equal(t, "doc3", docs.Documents[0].ID)
+		require.Empty(t, docs.Next)
+	})
+}
+
+func TestClient_DeleteVault(t *testing.T) {
+	loader := testutil.DocumentLoader(t)
+
+	t.Run("Unknown vault", func(t *testing.T) {
+		client, _, _ := newLegacyVaultClient(t, loader, &backendFailures{})
+
+		_, err := client.DeleteVault("did:example:unknown")
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		fail := &backendFailures{}
+
+		client, vID, store := newLegacyVaultClient(t, loader, fail)
+
+		for _, docID := range []string{"doc1", "doc2"} {
+			_, err := client.SaveDoc(vID, docID, []byte(`{"secret":"value"}`))
+			require.NoError(t, err)
+		}
+
+		index := sha256.Sum256([]byte("info_" + vID))
+
+		require.NoError(t, store.Put("authorization_"+vID+"_authID", []byte(`{"id":"authID"}`),
+			storage.Tag{Name: "vault_authorizations", Value: hex.EncodeToString(index[:])}))
+
+		result, err := client.DeleteVault(vID)
+		require.NoError(t, err)
+		require.True(t, result.Complete)
+		require.True(t, result.EDV.Deleted)
+		require.True(t, result.KMS.Deleted)
+		require.Equal(t, 2, fail.edvDeletes)
+		require.Equal(t, 1, fail.kmsDeletes)
+
+		_, err = client.GetVaultInfo(vID)
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+
+		_, err = client.GetAuthorization(vID, "authID")
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+
+		_, err = store.Get("meta_doc_info_" + vID + "_doc1")
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+	})
+
+	t.Run("Success (backend resources already gone)", func(t *testing.T) {
+		fail := &backendFailures{gone: true}
+
+		client, vID, store := newLegacyVaultClient(t, loader, fail)
+
+		_, err := client.SaveDoc(vID, "doc1", []byte(`{"secret":"value"}`))
+		require.NoError(t, err)
+
+		result, err := client.DeleteVault(vID)
+		require.NoError(t, err)
+		require.True(t, result.Complete)
+		require.True(t, result.EDV.Deleted)
+		require.True(t, result.KMS.Deleted)
+		require.Equal(t, 1, fail.edvDeletes)
+		require.Equal(t, 1, fail.kmsDeletes)
+
+		_, err = store.Get("meta_doc_info_" + vID + "_doc1")
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+	})
+
+	t.Run("Legacy records not tagged", func(t *testing.T) {
+		fail := &backendFailures{}
+
+		client, vID, store := newLegacyVaultClient(t, loader, fail)
+
+		require.NoError(t, store.Delete("legacy_records_tagged"))
+
+		// saved by the first versions
+		require.NoError(t, store.Put("meta_doc_info_"+vID+"_doc1", []byte(`{"edv_id":"edv1"}`)))
+
+		result, err := client.DeleteVault(vID)
+		require.NoError(t, err)
+		require.False(t, result.Complete)
+		require.False(t, result.EDV.Deleted)
+		require.Equal(t, vault.ErrUntaggedRecords.Error(), result.EDV.Error)
+		require.Zero(t, fail.edvDeletes)
+
+		tagged, err := client.TagLegacyRecords([]string{"meta_doc_info_" + vID + "_doc1"})
+		require.NoError(t, err)
+		require.Equal(t, 1, tagged)
+
+		info, err := client.GetVaultInfo(vID)
+		require.NoError(t, err)
+		require.Equal(t, 1, info.DocCount)
+
+		result, err = client.DeleteVault(vID)
+		require.NoError(t, err)
+		require.True(t, result.Complete)
+		require.Equal(t, 1, fail.edvDeletes)
+
+		_, err = store.Get("meta_doc_info_" + vID + "_doc1")
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+	})
+
+	t.Run("Retry after backend failures", func(t *testing.T) {
+		fail := &backendFailures{edv: true, kms: true}
+
+		client, vID, _ := newLegacyVaultClient(t, loader, fail)
+
+		_, err := client.SaveDoc(vID, "doc1", []byte(`{"secret":"value"}`))
+		require.NoError(t, err)
+
+		result, err := client.DeleteVault(vID)
+		require.NoError(t, err)
+		require.False(t, result.Complete)
+		require.False(t, result.EDV.Deleted)
+		require.Contains(t, result.EDV.Error, "failed to delete 1 of 1 documents")
+		require.False(t, result.KMS.Deleted)
+		require.Contains(t, result.KMS.Error, "unexpected status 500")
+
+		info, err := client.GetVaultInfo(vID)
+		require.NoError(t, err)
+		require.Equal(t, 1, info.DocCount)
+
+		_, err = client.SaveDoc(vID, "doc2", []byte(`{"secret":"value"}`))
+		require.True(t, errors.Is(err, vault.ErrVaultDeleting))
+
+		_, err = client.CreateAuthorization(vID, "did:example:rp", &vault.AuthorizationsScope{})
+		require.True(t, errors.Is(err, vault.ErrVaultDeleting))
+
+		fail.kms = false
+
+		result, err = client.DeleteVault(vID)
+		require.NoError(t, err)
+		require.False(t, result.Complete)
+		require.False(t, result.EDV.Deleted)
+		require.True(t, result.KMS.Deleted)
+
+		fail.edv, fail.kms = false, true
+
+		result, err = client.DeleteVault(vID)
+		require.NoError(t, err)
+		require.True(t, result.Complete)
+		require.True(t, result.EDV.Deleted)
+		require.True(t, result.KMS.Deleted)
+		require.Equal(t, 3, fail.edvDeletes)
+		require.Equal(t, 2, fail.kmsDeletes)
 	})
 }
 
@@ -883,6 +2643,47 @@ func createVaultID(t *testing.T, k vault.KeyManager) (string, string, string) {
 	return didKey, didURL, cryptoSigner.KID()
 }
 
+// newSigningDIDDoc returns a DID document whose key-1 and key-2 are authentication keys, and key-1 a capability
+// delegation key too, along with their private key.
+func newSigningDIDDoc(t *testing.T) (*did.Doc, ed25519.PrivateKey) {
+	t.Helper()
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	doc := newDIDDoc()
+
+	for _, keyID := range []string{"#key-1", "#key-2"} {
+		doc.Authentication = append(doc.Authentication, did.Verification{
+			VerificationMethod: did.VerificationMethod{
+				ID:         doc.ID + keyID,
+				Type:       "Ed25519VerificationKey2018",
+				Controller: doc.ID,
+				Value:      pub,
+			},
+			Relationship: did.Authentication,
+			Embedded:     true,
+		})
+	}
+
+	doc.CapabilityDelegation[0].VerificationMethod = doc.Authentication[0].VerificationMethod
+
+	return doc, priv
+}
+
+// newSignedRequest returns a request creating a vault signed with the key.
+func newSignedRequest(t *testing.T, priv ed25519.PrivateKey, keyID string) *http.Request {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, "https://vault.example.com/vaults",
+		strings.NewReader(`{"controller":"did:example:123","verificationMethod":"#key-1"}`))
+	require.NoError(t, err)
+
+	require.NoError(t, httpsig.NewSigner(httpsig.DefaultPostSignerConfig(), priv).SignRequest(keyID, req))
+
+	return req
+}
+
 func newDIDDoc() *did.Doc {
 	id := fmt.Sprintf("did:example:%s", uuid.New().String())
 
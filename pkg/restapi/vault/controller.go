@@ -0,0 +1,446 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+)
+
+const (
+	vaultControllerTag = "vault_controller"
+
+	// legacyRecordsTaggedKey is saved once the records saved by the first versions are tagged.
+	legacyRecordsTaggedKey = "legacy_records_tagged"
+)
+
+// ErrUntaggedRecords is returned when deleting a vault created before its records were tagged while the legacy
+// records of the store have not been tagged yet: its untagged documents and authorizations would be left behind.
+var ErrUntaggedRecords = errors.New("the vault may hold documents or authorizations saved before they were " +
+	"tagged, which must be tagged before it is deleted")
+
+// VaultList is a page of the vaults of a controller, sorted by ID.
+type VaultList struct {
+	Vaults []*VaultListEntry `json:"vaults"`
+	// Next is the continuation token of the next page. It is empty on the last page.
+	Next string `json:"next,omitempty"`
+}
+
+// VaultListEntry describes a vault of a VaultList.
+type VaultListEntry struct {
+	ID string `json:"id"`
+	// Created is nil for vaults created before the creation time was recorded.
+	Created *time.Time `json:"created,omitempty"`
+	EDVURI  string     `json:"edvURI"`
+}
+
+// ListVaults returns a page of the vaults controlled by the DID, eg. for a wallet restored from its seed to find
+// its vaults again. Vaults being deleted are not listed. The page following the one returned is requested by
+// passing its VaultList.Next token.
+func (c *Client) ListVaults(controller string, limit int, next string) (*VaultList, error) {
+	if controller == "" {
+		return nil, fmt.Errorf("%w: controller is required", ErrInvalidController)
+	}
+
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+
+	if limit > maxListLimit {
+		limit = maxListLimit
+	}
+
+	after, err := base64.RawURLEncoding.DecodeString(next)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidContinuationToken, err)
+	}
+
+	vaults, err := c.queryControllerVaults(controller)
+	if err != nil {
+		return nil, fmt.Errorf("query controller vaults: %w", err)
+	}
+
+	sort.Slice(vaults, func(i, j int) bool { return vaults[i].ID < vaults[j].ID })
+
+	start := sort.Search(len(vaults), func(i int) bool { return vaults[i].ID > string(after) })
+	vaults = vaults[start:]
+
+	list := &VaultList{Vaults: []*VaultListEntry{}}
+
+	if len(vaults) > limit {
+		vaults = vaults[:limit]
+		list.Next = base64.RawURLEncoding.EncodeToString([]byte(vaults[limit-1].ID))
+	}
+
+	list.Vaults = append(list.Vaults, vaults...)
+
+	return list, nil
+}
+
+func (c *Client) queryControllerVaults(controller string) ([]*VaultListEntry, error) {
+	iter, err := c.store.Query(fmt.Sprintf("%s:%s", vaultControllerTag, controllerIndex(controller)))
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	defer func() {
+		if errClose := iter.Close(); errClose != nil {
+			logger.Errorf("failed to close iterator: %s", errClose)
+		}
+	}()
+
+	var vaults []*VaultListEntry
+
+	for {
+		ok, err := iter.Next()
+		if err != nil {
+			return nil, fmt.Errorf("iterator next: %w", err)
+		}
+
+		if !ok {
+			return vaults, nil
+		}
+
+		key, err := iter.Key()
+		if err != nil {
+			return nil, fmt.Errorf("iterator key: %w", err)
+		}
+
+		src, err := iter.Value()
+		if err != nil {
+			return nil, fmt.Errorf("iterator value: %w", err)
+		}
+
+		var info *vaultInfo
+
+		err = json.Unmarshal(src, &info)
+		if err != nil {
+			return nil, fmt.Errorf("unmarshal: %w", err)
+		}
+
+		if info.Deleting || info.controller() != controller {
+			continue
+		}
+
+		entry := &VaultListEntry{
+			ID:     strings.TrimPrefix(key, fmt.Sprintf(infoFormat, "")),
+			EDVURI: info.Auth.EDV.URI,
+		}
+
+		if !info.Created.IsZero() {
+			created := info.Created
+			entry.Created = &created
+		}
+
+		vaults = append(vaults, entry)
+	}
+}
+
+// MigrateVaults upgrades the records of the vaults saved by older versions, eg. so that ListVaults finds them, and
+// returns the number of vaults checked. It is meant to run once at startup.
+//
+// The store cannot list the records of the vaults themselves: the vaults are found from their documents,
+// authorizations, webhooks and audit events. The others are upgraded when they are next read.
+func (c *Client) MigrateVaults() (int, error) {
+	vaultIDs, err := c.knownVaultIDs()
+	if err != nil {
+		return 0, fmt.Errorf("find vaults: %w", err)
+	}
+
+	for _, vaultID := range vaultIDs {
+		// reading a vault upgrades its record
+		_, err = c.getVaultInfo(vaultID)
+		if err != nil && !errors.Is(err, storage.ErrDataNotFound) {
+			return 0, fmt.Errorf("migrate vault %s: %w", vaultID, err)
+		}
+	}
+
+	return len(vaultIDs), nil
+}
+
+// TagLegacyRecords tags the records saved by the first versions, which were not tagged by vault, given their keys,
+// eg. meta_doc_info_<vault ID>_<doc ID> or authorization_<vault ID>_<authorization ID>, and returns the number of
+// records tagged. Tagged, the records are listed, counted and deleted with their vaults. The store cannot find the
+// untagged records itself: their keys are read from the database by the operator. Records already tagged, or no
+// longer found, are skipped.
+//
+// The keys must include all the untagged records of the store: the vaults created by the first versions can only
+// be deleted once they are tagged, see ErrUntaggedRecords.
+func (c *Client) TagLegacyRecords(keys []string) (int, error) {
+	tagged := 0
+
+	for _, key := range keys {
+		var (
+			ok  bool
+			err error
+		)
+
+		docVaultID, docID := parseRecordKey(metaDocInfoFormat, key)
+		authVaultID, authID := parseRecordKey(authorizationFormat, key)
+
+		switch {
+		case docID != "":
+			ok, err = c.tagLegacyDoc(docVaultID, docID)
+		// the IDs of authorizations are UUIDs, unlike the suffixes of the keys of their uses and challenges
+		case authID != "" && !strings.Contains(authID, "_"):
+			ok, err = c.tagLegacyAuthorization(authVaultID, authID)
+		default:
+			return tagged, fmt.Errorf("unsupported record key %s", key)
+		}
+
+		if err != nil {
+			return tagged, fmt.Errorf("tag %s: %w", key, err)
+		}
+
+		if ok {
+			tagged++
+		}
+	}
+
+	err := c.store.Put(legacyRecordsTaggedKey, []byte(`{}`))
+	if err != nil {
+		return tagged, fmt.Errorf("save legacy records tagged: %w", err)
+	}
+
+	return tagged, nil
+}
+
+func (c *Client) legacyRecordsTagged() (bool, error) {
+	_, err := c.store.Get(legacyRecordsTaggedKey)
+	if errors.Is(err, storage.ErrDataNotFound) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("get legacy records tagged: %w", err)
+	}
+
+	return true, nil
+}
+
+func (c *Client) tagLegacyDoc(vaultID, docID string) (bool, error) {
+	unlock := c.docMu.lock(docLockKey(vaultID, docID))
+	defer unlock()
+
+	untagged, err := c.untagged(fmt.Sprintf(metaDocInfoFormat, vaultID, docID), vaultDocsTag)
+	if err != nil || !untagged {
+		return false, err
+	}
+
+	info, err := c.getMetaDocInfo(vaultID, docID)
+	if err != nil {
+		return false, fmt.Errorf("get meta doc info: %w", err)
+	}
+
+	// reading the vault first upgrades it: the doc count of a vault without one is initialized from its tagged
+	// documents only
+	_, err = c.getVaultInfo(vaultID)
+	if errors.Is(err, storage.ErrDataNotFound) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("get vault info: %w", err)
+	}
+
+	info.DocID = docID
+
+	err = c.saveMetaDocInfo(vaultID, docID, info)
+	if err != nil {
+		return false, fmt.Errorf("save meta doc info: %w", err)
+	}
+
+	err = c.addDocCount(vaultID, 1)
+	if err != nil {
+		return false, fmt.Errorf("add doc count: %w", err)
+	}
+
+	return true, nil
+}
+
+func (c *Client) tagLegacyAuthorization(vaultID, id string) (bool, error) {
+	untagged, err := c.untagged(fmt.Sprintf(authorizationFormat, vaultID, id), vaultAuthorizationsTag)
+	if err != nil || !untagged {
+		return false, err
+	}
+
+	a, err := c.getAuthorization(vaultID, id)
+	if err != nil {
+		return false, fmt.Errorf("get authorization: %w", err)
+	}
+
+	_, err = c.getVaultInfo(vaultID)
+	if errors.Is(err, storage.ErrDataNotFound) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("get vault info: %w", err)
+	}
+
+	a.ID = id
+
+	err = c.saveAuthorization(vaultID, a)
+	if err != nil {
+		return false, fmt.Errorf("save authorization: %w", err)
+	}
+
+	return true, nil
+}
+
+// untagged reports whether the record exists without the tag.
+func (c *Client) untagged(key, tag string) (bool, error) {
+	tags, err := c.store.GetTags(key)
+	if errors.Is(err, storage.ErrDataNotFound) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("get tags: %w", err)
+	}
+
+	for _, t := range tags {
+		if t.Name == tag {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// parseRecordKey splits a key of the given format into the vault ID, which contains no underscore, and the ID of
+// the record. Both are empty if the key is not of the format.
+func parseRecordKey(format, key string) (string, string) {
+	prefix := strings.SplitN(format, "%s", 2)[0]
+	if !strings.HasPrefix(key, prefix) {
+		return "", ""
+	}
+
+	i := strings.Index(key[len(prefix):], "_")
+	if i <= 0 || len(prefix)+i+1 == len(key) {
+		return "", ""
+	}
+
+	return key[len(prefix) : len(prefix)+i], key[len(prefix)+i+1:]
+}
+
+// knownVaultIDs returns the IDs of the vaults that have tagged records, sorted.
+func (c *Client) knownVaultIDs() ([]string, error) {
+	found := make(map[string]struct{})
+
+	// the keys of authorizations and webhooks end with a UUID, the documents and audit events record their vault
+	for _, source := range []struct {
+		tag     string
+		vaultID func(key string, src []byte) string
+	}{
+		{vaultAuthorizationsTag, keyVaultID(authorizationFormat)},
+		{vaultWebhooksTag, keyVaultID(webhookFormat)},
+		{vaultDocsTag, func(_ string, src []byte) string {
+			var info metaDocInfo
+
+			// documents last saved before their vault was recorded are skipped
+			_ = json.Unmarshal(src, &info) // nolint:errcheck
+
+			return info.VaultID
+		}},
+		{vaultAuditTag, func(_ string, src []byte) string {
+			var event AuditEvent
+
+			_ = json.Unmarshal(src, &event) // nolint:errcheck
+
+			return event.VaultID
+		}},
+	} {
+		err := c.queryTagged(source.tag, func(key string, src []byte) {
+			if vaultID := source.vaultID(key, src); vaultID != "" {
+				found[vaultID] = struct{}{}
+			}
+		})
+		if err != nil {
+			return nil, fmt.Errorf("query %s: %w", source.tag, err)
+		}
+	}
+
+	vaultIDs := make([]string, 0, len(found))
+
+	for vaultID := range found {
+		vaultIDs = append(vaultIDs, vaultID)
+	}
+
+	sort.Strings(vaultIDs)
+
+	return vaultIDs, nil
+}
+
+// keyVaultID returns a function parsing the vault ID out of the keys of the given format, whose second parameter
+// contains no underscore.
+func keyVaultID(format string) func(key string, src []byte) string {
+	prefix := strings.SplitN(format, "%s", 2)[0]
+
+	return func(key string, _ []byte) string {
+		i := strings.LastIndex(key, "_")
+		if !strings.HasPrefix(key, prefix) || i < len(prefix) {
+			return ""
+		}
+
+		return key[len(prefix):i]
+	}
+}
+
+// queryTagged calls fn with the key and value of every record with the tag, whatever its value.
+func (c *Client) queryTagged(tag string, fn func(key string, src []byte)) error {
+	iter, err := c.store.Query(tag)
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+
+	defer func() {
+		if errClose := iter.Close(); errClose != nil {
+			logger.Errorf("failed to close iterator: %s", errClose)
+		}
+	}()
+
+	for {
+		ok, err := iter.Next()
+		if err != nil {
+			return fmt.Errorf("iterator next: %w", err)
+		}
+
+		if !ok {
+			return nil
+		}
+
+		key, err := iter.Key()
+		if err != nil {
+			return fmt.Errorf("iterator key: %w", err)
+		}
+
+		src, err := iter.Value()
+		if err != nil {
+			return fmt.Errorf("iterator value: %w", err)
+		}
+
+		fn(key, src)
+	}
+}
+
+// controllerIndex returns the tag value of the vaults of the given controller.
+// Controllers are DIDs and tag values must not contain colons, so the value is hashed.
+func controllerIndex(controller string) string {
+	sum := sha256.Sum256([]byte(controller))
+
+	return hex.EncodeToString(sum[:])
+}
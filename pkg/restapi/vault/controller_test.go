@@ -0,0 +1,322 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/ace/pkg/internal/testutil"
+	"github.com/trustbloc/ace/pkg/restapi/vault"
+)
+
+func TestClient_ListVaults(t *testing.T) {
+	loader := testutil.DocumentLoader(t)
+
+	client, _ := newKeyTypeVaultClient(t, loader)
+
+	created, err := client.CreateVault()
+	require.NoError(t, err)
+
+	_, err = client.CreateVault()
+	require.NoError(t, err)
+
+	info, err := client.GetVaultInfo(created.ID)
+	require.NoError(t, err)
+
+	t.Run("Lists the vaults of the controller", func(t *testing.T) {
+		list, err := client.ListVaults(info.Controller, 0, "")
+		require.NoError(t, err)
+		require.Empty(t, list.Next)
+		require.Len(t, list.Vaults, 1)
+		require.Equal(t, created.ID, list.Vaults[0].ID)
+		require.Equal(t, created.EDV.URI, list.Vaults[0].EDVURI)
+		require.NotNil(t, list.Vaults[0].Created)
+		require.True(t, info.Created.Equal(*list.Vaults[0].Created))
+	})
+
+	t.Run("No vaults", func(t *testing.T) {
+		list, err := client.ListVaults("did:example:unknown", 0, "")
+		require.NoError(t, err)
+		require.Empty(t, list.Vaults)
+		require.NotNil(t, list.Vaults)
+	})
+
+	t.Run("Deleted vaults are not listed", func(t *testing.T) {
+		deleted, err := client.CreateVault()
+		require.NoError(t, err)
+
+		deletedInfo, err := client.GetVaultInfo(deleted.ID)
+		require.NoError(t, err)
+
+		_, err = client.DeleteVault(deleted.ID)
+		require.NoError(t, err)
+
+		list, err := client.ListVaults(deletedInfo.Controller, 0, "")
+		require.NoError(t, err)
+		require.Empty(t, list.Vaults)
+	})
+
+	t.Run("Error if the controller is missing", func(t *testing.T) {
+		_, err := client.ListVaults("", 0, "")
+		require.True(t, errors.Is(err, vault.ErrInvalidController))
+	})
+
+	t.Run("Error if the continuation token is invalid", func(t *testing.T) {
+		_, err := client.ListVaults(info.Controller, 0, "!")
+		require.True(t, errors.Is(err, vault.ErrInvalidContinuationToken))
+	})
+}
+
+func TestClient_MigrateVaults(t *testing.T) {
+	const controller = "did:example:controller"
+
+	provider := mem.NewProvider()
+
+	client, err := vault.NewClient("", "https://edv.example.com", nil, provider, testutil.DocumentLoader(t))
+	require.NoError(t, err)
+
+	store, err := provider.OpenStore("vault")
+	require.NoError(t, err)
+
+	index := func(vaultID string) string {
+		sum := sha256.Sum256([]byte("info_" + vaultID))
+
+		return hex.EncodeToString(sum[:])
+	}
+
+	// vaults saved before they were indexed by controller
+	putInfo := func(t *testing.T, vaultID, extra string) {
+		t.Helper()
+
+		require.NoError(t, store.Put("info_"+vaultID, []byte(`{"did_url":"`+controller+`#key1",`+extra+
+			`"auth":{"edv":{"uri":"https://edv.example.com/encrypted-data-vaults/`+vaultID+`"},"kms":{}}}`)))
+	}
+
+	putInfo(t, "did:example:vault1", `"version":1,`)
+	require.NoError(t, store.Put("authorization_did:example:vault1_"+uuid.New().String(), []byte(`{}`),
+		storage.Tag{Name: "vault_authorizations", Value: index("did:example:vault1")}))
+
+	putInfo(t, "did:example:vault2", "")
+	require.NoError(t, store.Put("meta_doc_info_did:example:vault2_doc_1",
+		[]byte(`{"edv_id":"edv1","vault_id":"did:example:vault2"}`),
+		storage.Tag{Name: "vault_docs", Value: index("did:example:vault2")}))
+
+	putInfo(t, "did:example:vault3", `"version":1,`)
+	require.NoError(t, store.Put("audit_did:example:vault3_1", []byte(`{"vaultID":"did:example:vault3"}`),
+		storage.Tag{Name: "vault_audit", Value: index("did:example:vault3")}))
+
+	putInfo(t, "did:example:vault4", `"version":1,"deleting":true,`)
+	require.NoError(t, store.Put("webhook_did:example:vault4_"+uuid.New().String(), []byte(`{}`),
+		storage.Tag{Name: "vault_webhooks", Value: index("did:example:vault4")}))
+
+	// neither found nor read by the migration
+	putInfo(t, "did:example:vault5", `"version":1,`)
+
+	// left by a deleted vault
+	require.NoError(t, store.Put("meta_doc_info_did:example:vault6_doc1",
+		[]byte(`{"edv_id":"edv2","vault_id":"did:example:vault6"}`),
+		storage.Tag{Name: "vault_docs", Value: index("did:example:vault6")}))
+
+	list, err := client.ListVaults(controller, 0, "")
+	require.NoError(t, err)
+	require.Empty(t, list.Vaults)
+
+	migrated, err := client.MigrateVaults()
+	require.NoError(t, err)
+	require.Equal(t, 5, migrated)
+
+	listed := func(t *testing.T) []string {
+		t.Helper()
+
+		var ids []string
+
+		next := ""
+
+		for {
+			list, err := client.ListVaults(controller, 2, next)
+			require.NoError(t, err)
+			require.LessOrEqual(t, len(list.Vaults), 2)
+
+			for _, v := range list.Vaults {
+				require.Equal(t, "https://edv.example.com/encrypted-data-vaults/"+v.ID, v.EDVURI)
+				require.Nil(t, v.Created)
+
+				ids = append(ids, v.ID)
+			}
+
+			if list.Next == "" {
+				return ids
+			}
+
+			next = list.Next
+		}
+	}
+
+	require.Equal(t, []string{"did:example:vault1", "did:example:vault2", "did:example:vault3"}, listed(t))
+
+	t.Run("Vaults not found by the migration are indexed when read", func(t *testing.T) {
+		_, err := client.GetVaultInfo("did:example:vault5")
+		require.NoError(t, err)
+
+		require.Equal(t, []string{
+			"did:example:vault1", "did:example:vault2", "did:example:vault3", "did:example:vault5",
+		}, listed(t))
+	})
+
+	t.Run("Migrating again changes nothing", func(t *testing.T) {
+		migrated, err := client.MigrateVaults()
+		require.NoError(t, err)
+		require.Equal(t, 5, migrated)
+		require.Len(t, listed(t), 4)
+	})
+}
+
+func TestClient_TagLegacyRecords(t *testing.T) {
+	const vaultID = "did:example:vault1"
+
+	provider := mem.NewProvider()
+
+	client, err := vault.NewClient("", "https://edv.example.com", nil, provider, testutil.DocumentLoader(t))
+	require.NoError(t, err)
+
+	store, err := provider.OpenStore("vault")
+	require.NoError(t, err)
+
+	index := sha256.Sum256([]byte("info_" + vaultID))
+
+	require.NoError(t, store.Put("info_"+vaultID, []byte(`{"did_url":"did:example:controller#key1",`+
+		`"auth":{"edv":{"uri":"https://edv.example.com/encrypted-data-vaults/vault1"},"kms":{}}}`)))
+	require.NoError(t, store.Put("meta_doc_info_"+vaultID+"_doc1", []byte(`{"edv_id":"edv1"}`),
+		storage.Tag{Name: "vault_docs", Value: hex.EncodeToString(index[:])}))
+
+	// saved by the first versions
+	require.NoError(t, store.Put("meta_doc_info_"+vaultID+"_doc_2", []byte(`{"edv_id":"edv2"}`)))
+
+	authID := uuid.New().String()
+
+	require.NoError(t, store.Put("authorization_"+vaultID+"_"+authID,
+		[]byte(`{"id":"`+authID+`","requestingParty":"did:example:rp","scope":{"target":"doc_2"}}`)))
+
+	listed := func(t *testing.T) []string {
+		t.Helper()
+
+		list, err := client.ListDocs(vaultID, 0, "")
+		require.NoError(t, err)
+
+		var ids []string
+
+		for _, d := range list.Documents {
+			ids = append(ids, d.ID)
+		}
+
+		return ids
+	}
+
+	require.Equal(t, []string{"doc1"}, listed(t))
+
+	auths, err := client.ListAuthorizations(vaultID, &vault.AuthorizationQuery{})
+	require.NoError(t, err)
+	require.Empty(t, auths.Authorizations)
+
+	tagged, err := client.TagLegacyRecords([]string{
+		"meta_doc_info_" + vaultID + "_doc1",
+		"meta_doc_info_" + vaultID + "_doc_2",
+		"meta_doc_info_" + vaultID + "_unknown",
+		"meta_doc_info_did:example:deleted_doc1",
+		"authorization_" + vaultID + "_" + authID,
+		"authorization_" + vaultID + "_" + uuid.New().String(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, tagged)
+
+	require.Equal(t, []string{"doc1", "doc_2"}, listed(t))
+
+	auths, err = client.ListAuthorizations(vaultID, &vault.AuthorizationQuery{})
+	require.NoError(t, err)
+	require.Len(t, auths.Authorizations, 1)
+	require.Equal(t, authID, auths.Authorizations[0].ID)
+	require.Equal(t, "did:example:rp", auths.Authorizations[0].RequestingParty)
+
+	info, err := client.GetVaultInfo(vaultID)
+	require.NoError(t, err)
+	require.Equal(t, 2, info.DocCount)
+
+	t.Run("Tagging again changes nothing", func(t *testing.T) {
+		tagged, err := client.TagLegacyRecords([]string{"meta_doc_info_" + vaultID + "_doc_2"})
+		require.NoError(t, err)
+		require.Zero(t, tagged)
+
+		info, err := client.GetVaultInfo(vaultID)
+		require.NoError(t, err)
+		require.Equal(t, 2, info.DocCount)
+	})
+
+	t.Run("Lists many documents a page at a time", func(t *testing.T) {
+		var keys, want []string
+
+		for i := 0; i < 600; i++ {
+			docID := fmt.Sprintf("many%03d", i)
+			key := "meta_doc_info_" + vaultID + "_" + docID
+
+			require.NoError(t, store.Put(key, []byte(`{"edv_id":"edv"}`)))
+
+			keys = append(keys, key)
+			want = append(want, docID)
+		}
+
+		// tagged out of order
+		sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+
+		tagged, err := client.TagLegacyRecords(keys)
+		require.NoError(t, err)
+		require.Equal(t, 600, tagged)
+
+		want = append([]string{"doc1", "doc_2"}, want...)
+
+		var ids []string
+
+		next := ""
+
+		for {
+			list, err := client.ListDocs(vaultID, 100, next)
+			require.NoError(t, err)
+
+			for _, d := range list.Documents {
+				ids = append(ids, d.ID)
+			}
+
+			if list.Next == "" {
+				break
+			}
+
+			next = list.Next
+		}
+
+		require.Equal(t, want, ids)
+	})
+
+	t.Run("Unsupported key", func(t *testing.T) {
+		for _, key := range []string{
+			"info_" + vaultID,
+			"authorization_usage_" + vaultID + "_" + authID,
+			"meta_doc_info_" + vaultID,
+		} {
+			_, err := client.TagLegacyRecords([]string{key})
+			require.EqualError(t, err, "unsupported record key "+key)
+		}
+	})
+}
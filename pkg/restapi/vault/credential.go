@@ -0,0 +1,70 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+)
+
+const credentialType = "VerifiableCredential"
+
+// ErrInvalidCredential is returned when a verifiable credential saved with credential verification enabled has no
+// valid proof of its issuer.
+var ErrInvalidCredential = errors.New("invalid verifiable credential")
+
+// WithCredentialVerification makes SaveDoc verify the proofs of the verifiable credentials it saves against the
+// DIDs of their issuers, resolved with the registry of the client. Other documents are saved as they are.
+func WithCredentialVerification() Opt {
+	return func(vault *Client) {
+		vault.verifyCredentials = true
+	}
+}
+
+// isCredential tells whether the content of a document is a verifiable credential.
+func isCredential(content map[string]interface{}) bool {
+	switch types := content["type"].(type) {
+	case string:
+		return types == credentialType
+	case []interface{}:
+		for _, t := range types {
+			if t == credentialType {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// verifyCredential checks that the credential has proofs, all valid and made by its issuer.
+func (c *Client) verifyCredential(content []byte) error {
+	vc, err := verifiable.ParseCredential(content,
+		verifiable.WithPublicKeyFetcher(verifiable.NewVDRKeyResolver(c.registry).PublicKeyFetcher()),
+		verifiable.WithJSONLDDocumentLoader(c.documentLoader),
+	)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidCredential, err)
+	}
+
+	if len(vc.Proofs) == 0 {
+		return fmt.Errorf("%w: no proof", ErrInvalidCredential)
+	}
+
+	for _, proof := range vc.Proofs {
+		method, _ := proof["verificationMethod"].(string) // nolint:errcheck
+
+		if strings.Split(method, "#")[0] != vc.Issuer.ID {
+			return fmt.Errorf("%w: proof not made by the issuer %s", ErrInvalidCredential, vc.Issuer.ID)
+		}
+	}
+
+	return nil
+}
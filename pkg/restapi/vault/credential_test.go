@@ -0,0 +1,167 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/jsonld"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/suite"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/suite/ed25519signature2018"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/util"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/util/signature"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/hyperledger/aries-framework-go/pkg/vdr/fingerprint"
+	"github.com/piprate/json-gold/ld"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/ace/pkg/internal/testutil"
+	"github.com/trustbloc/ace/pkg/restapi/vault"
+)
+
+func TestClient_SaveDoc_CredentialVerification(t *testing.T) {
+	loader := testutil.DocumentLoader(t)
+
+	newVault := func(t *testing.T, opts ...vault.Opt) (*vault.Client, string) {
+		t.Helper()
+
+		client, _ := newKeyTypeVaultClient(t, loader, opts...)
+
+		created, err := client.CreateVault()
+		require.NoError(t, err)
+
+		return client, created.ID
+	}
+
+	t.Run("Saves credentials signed by their issuer", func(t *testing.T) {
+		client, vaultID := newVault(t, vault.WithCredentialVerification())
+
+		vc := newSignedVC(t, loader, "")
+
+		_, err := client.SaveDoc(vaultID, "vc", vc)
+		require.NoError(t, err)
+
+		doc, err := client.GetDoc(vaultID, "vc")
+		require.NoError(t, err)
+		require.JSONEq(t, string(vc), string(doc))
+	})
+
+	t.Run("Saves documents that are not credentials", func(t *testing.T) {
+		client, vaultID := newVault(t, vault.WithCredentialVerification())
+
+		_, err := client.SaveDoc(vaultID, "doc", []byte(`{"message":"Hello World!"}`))
+		require.NoError(t, err)
+	})
+
+	t.Run("Error if the credential was tampered with", func(t *testing.T) {
+		client, vaultID := newVault(t, vault.WithCredentialVerification())
+
+		_, err := client.SaveDoc(vaultID, "vc", tamperVC(t, newSignedVC(t, loader, "")))
+		require.True(t, errors.Is(err, vault.ErrInvalidCredential))
+		require.Contains(t, err.Error(), "invalid signature")
+	})
+
+	t.Run("Error if the credential is not signed by its issuer", func(t *testing.T) {
+		client, vaultID := newVault(t, vault.WithCredentialVerification())
+
+		pub, _, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		issuer, _ := fingerprint.CreateDIDKey(pub)
+
+		_, err = client.SaveDoc(vaultID, "vc", newSignedVC(t, loader, issuer))
+		require.True(t, errors.Is(err, vault.ErrInvalidCredential))
+		require.Contains(t, err.Error(), "proof not made by the issuer")
+	})
+
+	t.Run("Error if the credential has no proof", func(t *testing.T) {
+		client, vaultID := newVault(t, vault.WithCredentialVerification())
+
+		vc, err := json.Marshal(map[string]interface{}{
+			"@context":          []string{verifiable.ContextURI},
+			"id":                uuid.New().URN(),
+			"type":              verifiable.VCType,
+			"issuer":            "did:example:issuer",
+			"issuanceDate":      "2022-01-01T00:00:00Z",
+			"credentialSubject": map[string]interface{}{"id": "did:example:subject"},
+		})
+		require.NoError(t, err)
+
+		_, err = client.SaveDoc(vaultID, "vc", vc)
+		require.True(t, errors.Is(err, vault.ErrInvalidCredential))
+		require.Contains(t, err.Error(), "no proof")
+	})
+
+	t.Run("Credentials are not verified by default", func(t *testing.T) {
+		client, vaultID := newVault(t)
+
+		_, err := client.SaveDoc(vaultID, "vc", tamperVC(t, newSignedVC(t, loader, "")))
+		require.NoError(t, err)
+	})
+}
+
+// newSignedVC returns a credential signed with a new did:key, issued by the issuer if set or else by that did:key.
+func newSignedVC(t *testing.T, loader ld.DocumentLoader, issuer string) []byte {
+	t.Helper()
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	did, kid := fingerprint.CreateDIDKey(pub)
+
+	if issuer == "" {
+		issuer = did
+	}
+
+	vc := &verifiable.Credential{
+		ID:      uuid.New().URN(),
+		Context: []string{verifiable.ContextURI},
+		Types:   []string{verifiable.VCType},
+		Issuer:  verifiable.Issuer{ID: issuer},
+		Issued:  util.NewTime(time.Now().UTC().Truncate(time.Second)),
+		Subject: verifiable.Subject{ID: uuid.New().URN()},
+	}
+
+	err = vc.AddLinkedDataProof(
+		&verifiable.LinkedDataProofContext{
+			SignatureType:           ed25519signature2018.SignatureType,
+			Suite:                   ed25519signature2018.New(suite.WithSigner(signature.GetEd25519Signer(priv, pub))),
+			SignatureRepresentation: verifiable.SignatureJWS,
+			Purpose:                 "assertionMethod",
+			VerificationMethod:      kid,
+		},
+		jsonld.WithDocumentLoader(loader),
+	)
+	require.NoError(t, err)
+
+	src, err := vc.MarshalJSON()
+	require.NoError(t, err)
+
+	return src
+}
+
+// tamperVC changes the subject of the credential after it was signed.
+func tamperVC(t *testing.T, vc []byte) []byte {
+	t.Helper()
+
+	var doc map[string]interface{}
+
+	require.NoError(t, json.Unmarshal(vc, &doc))
+
+	doc["credentialSubject"] = map[string]interface{}{"id": uuid.New().URN()}
+
+	src, err := json.Marshal(doc)
+	require.NoError(t, err)
+
+	return src
+}
@@ -0,0 +1,108 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+)
+
+// edvDocsTag is the tag of the metadata of documents, whose value is the index of their EDV document ID.
+const edvDocsTag = "edv_docs"
+
+// ErrInvalidDocURI is returned when the URI of a document is not a valid URI.
+var ErrInvalidDocURI = errors.New("invalid document URI")
+
+// GetDocMetadataByURI returns the metadata of the document of any vault whose EDV document is at the URI, the URI
+// of its metadata. Fails with ErrDocumentNotFound if there is none: documents last saved before the EDV document
+// IDs were indexed are only found once saved again.
+func (c *Client) GetDocMetadataByURI(uri string) (*DocumentMetadata, error) {
+	_, meta, err := c.findDocByURI(uri)
+
+	return meta, err
+}
+
+// findDocByURI returns the ID of the vault of the document whose EDV document is at the URI, along with its metadata.
+func (c *Client) findDocByURI(uri string) (string, *DocumentMetadata, error) {
+	u, err := url.Parse(uri)
+	if err != nil || !u.IsAbs() {
+		return "", nil, fmt.Errorf("%w: %s", ErrInvalidDocURI, uri)
+	}
+
+	// the EDV document ID is the last segment of the URI whatever the path the EDV server is mounted under, the URI
+	// is then checked against the metadata of the documents
+	infos, err := c.queryEDVDocInfos(path.Base(u.Path))
+	if err != nil {
+		return "", nil, err
+	}
+
+	for _, info := range infos {
+		meta, err := c.GetDocMetadata(info.VaultID, info.DocID)
+		if err != nil {
+			return "", nil, fmt.Errorf("get doc metadata: %w", err)
+		}
+
+		if meta.URI == uri {
+			return info.VaultID, meta, nil
+		}
+	}
+
+	return "", nil, fmt.Errorf("%w: %s", ErrDocumentNotFound, uri)
+}
+
+// queryEDVDocInfos returns the metadata of the documents stored in EDV documents with the ID.
+func (c *Client) queryEDVDocInfos(edvDocID string) ([]*metaDocInfo, error) {
+	iter, err := c.store.Query(fmt.Sprintf("%s:%s", edvDocsTag, edvDocIndex(edvDocID)))
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	defer func() {
+		if errClose := iter.Close(); errClose != nil {
+			logger.Errorf("failed to close iterator: %s", errClose)
+		}
+	}()
+
+	var infos []*metaDocInfo
+
+	for {
+		ok, err := iter.Next()
+		if err != nil {
+			return nil, fmt.Errorf("iterator next: %w", err)
+		}
+
+		if !ok {
+			return infos, nil
+		}
+
+		src, err := iter.Value()
+		if err != nil {
+			return nil, fmt.Errorf("iterator value: %w", err)
+		}
+
+		var info *metaDocInfo
+
+		if err = json.Unmarshal(src, &info); err != nil {
+			return nil, fmt.Errorf("unmarshal: %w", err)
+		}
+
+		infos = append(infos, info)
+	}
+}
+
+// edvDocIndex returns the tag value of the metadata of the documents stored in the EDV document. IDs taken from
+// URIs may contain colons, which tag values must not, so the value is hashed.
+func edvDocIndex(edvDocID string) string {
+	sum := sha256.Sum256([]byte(edvDocID))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,131 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	mockstorage "github.com/hyperledger/aries-framework-go/pkg/mock/storage"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/ace/pkg/internal/testutil"
+	"github.com/trustbloc/ace/pkg/restapi/vault"
+)
+
+func TestClient_GetDocMetadataByURI(t *testing.T) {
+	loader := testutil.DocumentLoader(t)
+
+	remoteKMS := httptest.NewServer(newKMSHandler(t))
+	t.Cleanup(remoteKMS.Close)
+
+	edv := httptest.NewServer(newEDVHandler(t))
+	t.Cleanup(edv.Close)
+
+	provider := mem.NewProvider()
+
+	client, err := vault.NewClient(remoteKMS.URL, edv.URL, newLocalKms(t, provider), provider, loader)
+	require.NoError(t, err)
+
+	first, err := client.CreateVault()
+	require.NoError(t, err)
+
+	second, err := client.CreateVault()
+	require.NoError(t, err)
+
+	saved, err := client.SaveDoc(first.ID, "doc", []byte(`{"message":"Hello World!"}`))
+	require.NoError(t, err)
+
+	_, err = client.SaveDoc(second.ID, "doc", []byte(`{"message":"Hello World!"}`))
+	require.NoError(t, err)
+
+	t.Run("Returns the metadata of the document", func(t *testing.T) {
+		meta, err := client.GetDocMetadataByURI(saved.URI)
+		require.NoError(t, err)
+		require.Equal(t, saved, meta)
+
+		expected, err := client.GetDocMetadata(first.ID, "doc")
+		require.NoError(t, err)
+		require.Equal(t, expected, meta)
+
+		// the EDV document is located without parsing its URI
+		require.Equal(t, edv.URL, meta.EDVURL)
+		require.NotEmpty(t, meta.EDVVaultID)
+		require.NotEmpty(t, meta.EDVDocID)
+		require.True(t, strings.HasSuffix(meta.URI, "/"+meta.EDVVaultID+"/documents/"+meta.EDVDocID))
+	})
+
+	t.Run("Updates keep their URI", func(t *testing.T) {
+		_, err := client.SaveDoc(first.ID, "doc", []byte(`{"message":"Bye!"}`))
+		require.NoError(t, err)
+
+		meta, err := client.GetDocMetadataByURI(saved.URI)
+		require.NoError(t, err)
+		require.Equal(t, "doc", meta.ID)
+		require.Equal(t, uint64(1), meta.Sequence)
+	})
+
+	t.Run("Document not found", func(t *testing.T) {
+		for _, uri := range []string{
+			// another EDV server
+			strings.Replace(saved.URI, "127.0.0.1", "localhost", 1),
+			// another EDV vault
+			strings.Replace(saved.URI, saved.EDVVaultID, "HwtZ1bUn4SzXoQRoX9br6m", 1),
+			// another document
+			strings.Replace(saved.URI, saved.EDVDocID, "M3aS9xwj8ybCwHkEiCJJR1", 1),
+		} {
+			_, err := client.GetDocMetadataByURI(uri)
+			require.True(t, errors.Is(err, vault.ErrDocumentNotFound), uri)
+		}
+	})
+
+	t.Run("Invalid URI", func(t *testing.T) {
+		for _, uri := range []string{"documents/M3aS9xwj8ybCwHkEiCJJR1", "%zz"} {
+			_, err := client.GetDocMetadataByURI(uri)
+			require.True(t, errors.Is(err, vault.ErrInvalidDocURI), uri)
+		}
+	})
+
+	t.Run("Verifies the invocation on the vault of the document", func(t *testing.T) {
+		newRequest := func(t *testing.T, capability string) *http.Request {
+			t.Helper()
+
+			req, err := http.NewRequest(http.MethodGet, "https://vault.example.com/vaults/docs/metadata", nil)
+			require.NoError(t, err)
+
+			req.Header.Set("Capability-Invocation", `zcap capability="`+capability+`",action="read"`)
+
+			return req
+		}
+
+		// the capability of the vault is resolved, but the request is not signed by its invoker
+		err := client.VerifyDocURIInvocation(saved.URI, newRequest(t, first.EDV.AuthToken))
+		require.True(t, errors.Is(err, vault.ErrUnauthorizedInvocation))
+		require.Contains(t, err.Error(), "an HTTP signature by")
+
+		err = client.VerifyDocURIInvocation(saved.URI, newRequest(t, first.KMS.AuthToken))
+		require.True(t, errors.Is(err, vault.ErrUnauthorizedInvocation))
+		require.Contains(t, err.Error(), "the capability is not the one of vault "+first.ID)
+
+		err = client.VerifyDocURIInvocation("%zz", newRequest(t, first.EDV.AuthToken))
+		require.True(t, errors.Is(err, vault.ErrInvalidDocURI))
+	})
+
+	t.Run("Store error", func(t *testing.T) {
+		c, err := vault.NewClient("", "", nil, &mockstorage.MockStoreProvider{
+			Store: &mockstorage.MockStore{ErrQuery: errors.New("test")},
+		}, loader)
+		require.NoError(t, err)
+
+		_, err = c.GetDocMetadataByURI(saved.URI)
+		require.EqualError(t, err, "query: test")
+	})
+}
@@ -0,0 +1,139 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+)
+
+const (
+	// DefaultAuthorizationGCInterval is the interval at which expired authorizations are swept unless configured
+	// otherwise.
+	DefaultAuthorizationGCInterval = time.Hour
+	// DefaultAuthorizationRetention is how long expired authorizations are kept unless configured otherwise.
+	DefaultAuthorizationRetention = 24 * time.Hour
+
+	// authorizationGCPageSize bounds the authorizations read from the store at once by a sweep.
+	authorizationGCPageSize = 100
+)
+
+// SweepExpiredAuthorizations deletes the authorizations whose expiry caveat lapsed more than retention ago, along
+// with their recorded uses, and returns how many were deleted. Their zcaps have expired: unlike
+// DeleteAuthorization, the sweep does not revoke them.
+// Authorizations without an expiry caveat, including revoked ones, are kept.
+func (c *Client) SweepExpiredAuthorizations(retention time.Duration) (int, error) {
+	iter, err := c.store.Query(vaultAuthorizationsTag, storage.WithPageSize(authorizationGCPageSize))
+	if err != nil {
+		return 0, fmt.Errorf("query: %w", err)
+	}
+
+	defer func() {
+		if errClose := iter.Close(); errClose != nil {
+			logger.Errorf("failed to close iterator: %s", errClose)
+		}
+	}()
+
+	deadline := time.Now().Add(-retention)
+	deleted := 0
+
+	for {
+		ok, err := iter.Next()
+		if err != nil {
+			return deleted, fmt.Errorf("iterator next: %w", err)
+		}
+
+		if !ok {
+			return deleted, nil
+		}
+
+		key, err := iter.Key()
+		if err != nil {
+			return deleted, fmt.Errorf("iterator key: %w", err)
+		}
+
+		src, err := iter.Value()
+		if err != nil {
+			return deleted, fmt.Errorf("iterator value: %w", err)
+		}
+
+		var a *CreatedAuthorization
+
+		err = json.Unmarshal(src, &a)
+		if err != nil {
+			return deleted, fmt.Errorf("unmarshal: %w", err)
+		}
+
+		if !a.expired(deadline) {
+			continue
+		}
+
+		err = c.deleteAuthorizationRecords(key)
+		if err != nil {
+			return deleted, err
+		}
+
+		deleted++
+	}
+}
+
+// SweepAuthorizations runs SweepExpiredAuthorizations every interval until the context is done. Failed sweeps are
+// logged and retried at the next interval.
+func (c *Client) SweepAuthorizations(ctx context.Context, interval, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		deleted, err := c.SweepExpiredAuthorizations(retention)
+		if err != nil {
+			logger.Errorf("failed to sweep expired authorizations, %d deleted: %s", deleted, err)
+
+			continue
+		}
+
+		logger.Infof("swept expired authorizations: %d deleted", deleted)
+	}
+}
+
+// deleteAuthorizationRecords deletes the authorization stored with the given key and its recorded uses.
+func (c *Client) deleteAuthorizationRecords(key string) error {
+	err := c.store.Delete(key)
+	if err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	// the keys of an authorization and of its uses only differ by their prefix, see authorizationFormat and
+	// authorizationUsageFormat
+	usageKey := "authorization_usage_" + strings.TrimPrefix(key, "authorization_")
+
+	err = c.store.Delete(usageKey)
+	if err != nil && !errors.Is(err, storage.ErrDataNotFound) {
+		return fmt.Errorf("delete usage: %w", err)
+	}
+
+	// the IDs of authorizations are UUIDs, without underscores
+	vaultID := keyVaultID(authorizationFormat)(key, nil)
+
+	err = c.indexRemove(authorizationsIndex, vaultID, key[strings.LastIndex(key, "_")+1:])
+	if err != nil {
+		return fmt.Errorf("unindex: %w", err)
+	}
+
+	return nil
+}
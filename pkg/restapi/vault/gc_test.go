@@ -0,0 +1,199 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	mockstorage "github.com/hyperledger/aries-framework-go/pkg/mock/storage"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/zcapld"
+
+	"github.com/trustbloc/ace/pkg/internal/testutil"
+	"github.com/trustbloc/ace/pkg/restapi/vault"
+)
+
+func TestClient_SweepExpiredAuthorizations(t *testing.T) {
+	loader := testutil.DocumentLoader(t)
+
+	newClient := func(t *testing.T) (*vault.Client, string, storage.Store) {
+		t.Helper()
+
+		remoteKMS := httptest.NewServer(newKMSHandler(t))
+		t.Cleanup(remoteKMS.Close)
+
+		edv := httptest.NewServer(newEDVHandler(t))
+		t.Cleanup(edv.Close)
+
+		provider := mem.NewProvider()
+
+		client, err := vault.NewClient(remoteKMS.URL, edv.URL, newLocalKms(t, provider), provider, loader)
+		require.NoError(t, err)
+
+		created, err := client.CreateVault()
+		require.NoError(t, err)
+
+		store, err := provider.OpenStore("vault")
+		require.NoError(t, err)
+
+		return client, created.ID, store
+	}
+
+	authorize := func(t *testing.T, client *vault.Client, vaultID string, caveats ...vault.Caveat) string {
+		t.Helper()
+
+		auth, err := client.CreateAuthorization(vaultID, "did:example:rp", &vault.AuthorizationsScope{
+			Target:  "doc",
+			Actions: []string{"read"},
+			Caveats: caveats,
+		})
+		require.NoError(t, err)
+
+		return auth.ID
+	}
+
+	t.Run("Deletes the authorizations expired beyond the retention", func(t *testing.T) {
+		client, vaultID, _ := newClient(t)
+
+		expired := authorize(t, client, vaultID, vault.Caveat{Type: zcapld.CaveatTypeExpiry, Duration: 0})
+		active := authorize(t, client, vaultID, vault.Caveat{Type: zcapld.CaveatTypeExpiry, Duration: 3600})
+		unlimited := authorize(t, client, vaultID)
+
+		deleted, err := client.SweepExpiredAuthorizations(time.Hour)
+		require.NoError(t, err)
+		require.Zero(t, deleted)
+
+		deleted, err = client.SweepExpiredAuthorizations(0)
+		require.NoError(t, err)
+		require.Equal(t, 1, deleted)
+
+		_, err = client.GetAuthorization(vaultID, expired)
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+
+		list, err := client.ListAuthorizations(vaultID, &vault.AuthorizationQuery{IncludeExpired: true})
+		require.NoError(t, err)
+		require.Len(t, list.Authorizations, 2)
+
+		for _, id := range []string{active, unlimited} {
+			_, err = client.GetAuthorization(vaultID, id)
+			require.NoError(t, err)
+		}
+	})
+
+	t.Run("Deletes the recorded uses", func(t *testing.T) {
+		client, vaultID, store := newClient(t)
+
+		id := authorize(t, client, vaultID,
+			vault.Caveat{Type: vault.CaveatTypeUsage, Uses: 2},
+			vault.Caveat{Type: zcapld.CaveatTypeExpiry, Duration: 1},
+		)
+
+		_, err := client.UseAuthorization(vaultID, id)
+		require.NoError(t, err)
+
+		_, err = store.Get(fmt.Sprintf("authorization_usage_%s_%s", vaultID, id))
+		require.NoError(t, err)
+
+		time.Sleep(1100 * time.Millisecond)
+
+		deleted, err := client.SweepExpiredAuthorizations(0)
+		require.NoError(t, err)
+		require.Equal(t, 1, deleted)
+
+		_, err = store.Get(fmt.Sprintf("authorization_usage_%s_%s", vaultID, id))
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+	})
+
+	t.Run("Sweeps backlogs larger than a page", func(t *testing.T) {
+		client, vaultID, store := newClient(t)
+
+		// authorizations are read a page at a time: the backlog spans several pages
+		const backlog = 250
+
+		expiresAt := time.Now().Add(-time.Minute)
+
+		for i := 0; i < backlog; i++ {
+			src, err := json.Marshal(&vault.CreatedAuthorization{ID: fmt.Sprint(i), ExpiresAt: &expiresAt})
+			require.NoError(t, err)
+
+			require.NoError(t, store.Put(fmt.Sprintf("authorization_%s_%d", vaultID, i), src,
+				storage.Tag{Name: "vault_authorizations", Value: "test"},
+			))
+		}
+
+		deleted, err := client.SweepExpiredAuthorizations(0)
+		require.NoError(t, err)
+		require.Equal(t, backlog, deleted)
+
+		iter, err := store.Query("vault_authorizations")
+		require.NoError(t, err)
+
+		n, err := iter.TotalItems()
+		require.NoError(t, err)
+		require.Zero(t, n)
+	})
+
+	t.Run("Sweeps every interval until stopped", func(t *testing.T) {
+		client, vaultID, _ := newClient(t)
+
+		id := authorize(t, client, vaultID, vault.Caveat{Type: zcapld.CaveatTypeExpiry, Duration: 0})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+
+		go func() {
+			client.SweepAuthorizations(ctx, 10*time.Millisecond, 0)
+			close(done)
+		}()
+
+		require.Eventually(t, func() bool {
+			_, err := client.GetAuthorization(vaultID, id)
+
+			return errors.Is(err, storage.ErrDataNotFound)
+		}, time.Second, 10*time.Millisecond)
+
+		cancel()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			require.Fail(t, "the sweeper did not stop")
+		}
+	})
+
+	t.Run("Store errors", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			store *mockstorage.MockStore
+			err   string
+		}{
+			"Query": {
+				store: &mockstorage.MockStore{ErrQuery: errors.New("test")},
+				err:   "query: test",
+			},
+			"Next": {
+				store: &mockstorage.MockStore{ErrNext: errors.New("test")},
+				err:   "iterator next: test",
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				client, err := vault.NewClient("", "", nil, &mockstorage.MockStoreProvider{Store: tc.store}, loader)
+				require.NoError(t, err)
+
+				_, err = client.SweepExpiredAuthorizations(0)
+				require.EqualError(t, err, tc.err)
+			})
+		}
+	})
+}
@@ -0,0 +1,208 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	edv "github.com/trustbloc/edv/pkg/client"
+	"github.com/trustbloc/edv/pkg/restapi/models"
+)
+
+// hmacKeyType is the type of the HMAC keys of EDV encrypted indexes.
+const hmacKeyType = "Sha256HmacKey2019"
+
+// ErrInvalidIndexTag is returned when an index tag has an empty name or value, or a name with a colon.
+var ErrInvalidIndexTag = errors.New("invalid index tag")
+
+// WithIndexTags indexes the document under the tags, a map of names to values. The tags are stored in EDV as
+// encrypted index attributes: their names and values are replaced with HMACs computed with the HMAC key of the
+// vault and are never stored in plaintext. Saving a document replaces its tags.
+func WithIndexTags(tags map[string]string) SaveDocOpt {
+	return func(opts *saveDocOpts) {
+		opts.indexTags = tags
+	}
+}
+
+// FindDocs returns the documents of the vault tagged with the name and value, ordered by docID.
+func (c *Client) FindDocs(vaultID, name, value string) (*DocumentList, error) {
+	err := validateIndexTag(name, value)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := c.getVaultInfo(vaultID)
+	if err != nil {
+		return nil, fmt.Errorf("get vault info: %w", err)
+	}
+
+	list := &DocumentList{Documents: []*DocumentListEntry{}}
+
+	// no document of the vault was ever tagged
+	if info.HMACKeyURL == "" {
+		return list, nil
+	}
+
+	attr, err := c.indexAttribute(info, name, value)
+	if err != nil {
+		return nil, err
+	}
+
+	backend, err := c.edvBackend(info)
+	if err != nil {
+		return nil, err
+	}
+
+	edvVaultID := lastElm(info.Auth.EDV.URI, "/")
+
+	docURLs, err := backend.client.QueryVault(edvVaultID, attr.Name, attr.Value, edv.WithRequestHeader(
+		c.edvSign(info.DidURL, info.Auth.EDV)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query vault: %w", err)
+	}
+
+	docs, err := c.queryMetaDocInfos(vaultID)
+	if err != nil {
+		return nil, fmt.Errorf("query meta doc infos: %w", err)
+	}
+
+	byEDVID := make(map[string]*metaDocInfo, len(docs))
+
+	for _, d := range docs {
+		byEDVID[d.EdvID] = d
+	}
+
+	for _, docURL := range docURLs {
+		d, ok := byEDVID[lastElm(docURL, "/")]
+		if !ok {
+			continue
+		}
+
+		list.Documents = append(list.Documents, &DocumentListEntry{
+			ID:      d.DocID,
+			URI:     backend.docURI(edvVaultID, d.EdvID),
+			Created: d.Created,
+			Updated: d.Updated,
+		})
+	}
+
+	sort.Slice(list.Documents, func(i, j int) bool { return list.Documents[i].ID < list.Documents[j].ID })
+
+	return list, nil
+}
+
+func validateIndexTag(name, value string) error {
+	if name == "" || value == "" {
+		return fmt.Errorf("%w: name and value are required", ErrInvalidIndexTag)
+	}
+
+	if strings.Contains(name, ":") {
+		return fmt.Errorf("%w: name must not contain ':'", ErrInvalidIndexTag)
+	}
+
+	return nil
+}
+
+// indexAttributeCollections returns the encrypted index attributes of the tags, computed with the HMAC key of the
+// vault.
+func (c *Client) indexAttributeCollections(vaultID string, info *vaultInfo,
+	tags map[string]string,
+) ([]models.IndexedAttributeCollection, error) {
+	names := make([]string, 0, len(tags))
+
+	for name, value := range tags {
+		err := validateIndexTag(name, value)
+		if err != nil {
+			return nil, err
+		}
+
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	err := c.ensureHMACKey(vaultID, info)
+	if err != nil {
+		return nil, fmt.Errorf("create HMAC key: %w", err)
+	}
+
+	attrs := make([]models.IndexedAttribute, 0, len(names))
+
+	for _, name := range names {
+		attr, err := c.indexAttribute(info, name, tags[name])
+		if err != nil {
+			return nil, err
+		}
+
+		attrs = append(attrs, *attr)
+	}
+
+	return []models.IndexedAttributeCollection{{
+		HMAC:              models.IDTypePair{ID: info.HMACKeyURL, Type: hmacKeyType},
+		IndexedAttributes: attrs,
+	}}, nil
+}
+
+// indexAttribute computes the encrypted index attribute of a tag. The value is bound to the name so that the same
+// value under different names cannot be told apart.
+func (c *Client) indexAttribute(info *vaultInfo, name, value string) (*models.IndexedAttribute, error) {
+	wCrypto := c.webCrypto(info)
+
+	nameMAC, err := wCrypto.ComputeMAC([]byte(name), info.HMACKeyURL)
+	if err != nil {
+		return nil, fmt.Errorf("compute MAC: %w", err)
+	}
+
+	valueMAC, err := wCrypto.ComputeMAC([]byte(name+":"+value), info.HMACKeyURL)
+	if err != nil {
+		return nil, fmt.Errorf("compute MAC: %w", err)
+	}
+
+	return &models.IndexedAttribute{
+		Name:  base64.RawURLEncoding.EncodeToString(nameMAC),
+		Value: base64.RawURLEncoding.EncodeToString(valueMAC),
+	}, nil
+}
+
+// ensureHMACKey creates the HMAC key of the vault in its keystore the first time a document is tagged.
+func (c *Client) ensureHMACKey(vaultID string, info *vaultInfo) error {
+	if info.HMACKeyURL != "" {
+		return nil
+	}
+
+	// another document may have been tagged in the meantime
+	err := c.updateVaultInfo(vaultID, func(current *vaultInfo) error {
+		if current.HMACKeyURL == "" {
+			_, kidURL, err := c.webKMS(info).Create(kms.HMACSHA256Tag256Type)
+			if err != nil {
+				return fmt.Errorf("create: %w", err)
+			}
+
+			kidURLStr, ok := kidURL.(string)
+			if !ok {
+				return fmt.Errorf("kidURL is not a string")
+			}
+
+			current.HMACKeyURL = c.buildKMSURL(info, kidURLStr)
+		}
+
+		info.HMACKeyURL = current.HMACKeyURL
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("update vault info: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,199 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/ace/pkg/internal/testutil"
+	"github.com/trustbloc/ace/pkg/restapi/vault"
+)
+
+func TestClient_FindDocs(t *testing.T) {
+	loader := testutil.DocumentLoader(t)
+
+	findDocIDs := func(t *testing.T, client *vault.Client, vaultID, name, value string) []string {
+		t.Helper()
+
+		list, err := client.FindDocs(vaultID, name, value)
+		require.NoError(t, err)
+
+		ids := []string{}
+
+		for _, d := range list.Documents {
+			require.NotEmpty(t, d.URI)
+
+			ids = append(ids, d.ID)
+		}
+
+		return ids
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		client, vID := newRoundTripVaultClient(t, loader)
+
+		for docID, tags := range map[string]map[string]string{
+			"doc1": {"type": "license", "country": "CA"},
+			"doc2": {"type": "license"},
+			"doc3": {"type": "passport", "country": "CA"},
+		} {
+			_, err := client.SaveDoc(vID, docID, []byte(`{"message":"Hello World!"}`), vault.WithIndexTags(tags))
+			require.NoError(t, err)
+		}
+
+		_, err := client.SaveDoc(vID, "doc4", []byte(`{"message":"Hello World!"}`))
+		require.NoError(t, err)
+
+		require.Equal(t, []string{"doc1", "doc2"}, findDocIDs(t, client, vID, "type", "license"))
+		require.Equal(t, []string{"doc1", "doc3"}, findDocIDs(t, client, vID, "country", "CA"))
+		require.Empty(t, findDocIDs(t, client, vID, "type", "CA"))
+		require.Empty(t, findDocIDs(t, client, vID, "unknown", "license"))
+
+		// saving a document replaces its tags
+		_, err = client.SaveDoc(vID, "doc1", []byte(`{"message":"Hello again!"}`),
+			vault.WithIndexTags(map[string]string{"type": "passport"}))
+		require.NoError(t, err)
+
+		require.Equal(t, []string{"doc2"}, findDocIDs(t, client, vID, "type", "license"))
+		require.Equal(t, []string{"doc1", "doc3"}, findDocIDs(t, client, vID, "type", "passport"))
+		require.Equal(t, []string{"doc3"}, findDocIDs(t, client, vID, "country", "CA"))
+
+		// tags are kept when documents are re-encrypted
+		rekey, err := client.RekeyVault(vID)
+		require.NoError(t, err)
+		require.True(t, rekey.Complete)
+
+		require.Equal(t, []string{"doc2"}, findDocIDs(t, client, vID, "type", "license"))
+	})
+
+	t.Run("Concurrent saves of tagged and untagged documents", func(t *testing.T) {
+		remoteKMS := httptest.NewServer(newKMSHandler(t))
+		t.Cleanup(remoteKMS.Close)
+
+		edv := httptest.NewServer(newEDVHandler(t))
+		t.Cleanup(edv.Close)
+
+		// slow reads of the vault info widen the read-modify-write cycles
+		provider := &slowInfoProvider{Provider: mem.NewProvider()}
+
+		client, err := vault.NewClient(remoteKMS.URL, edv.URL, newLocalKms(t, provider), provider, loader)
+		require.NoError(t, err)
+
+		created, err := client.CreateVault()
+		require.NoError(t, err)
+
+		const saves = 10
+
+		var (
+			wg   sync.WaitGroup
+			errs = make([]error, saves)
+			want []string
+		)
+
+		for i := 0; i < saves; i++ {
+			docID := fmt.Sprintf("doc%d", i)
+
+			var opts []vault.SaveDocOpt
+
+			// saving the untagged documents updates the doc count while the HMAC key is created
+			if i%2 == 0 {
+				opts = append(opts, vault.WithIndexTags(map[string]string{"type": "license"}))
+				want = append(want, docID)
+			}
+
+			wg.Add(1)
+
+			go func(i int) {
+				defer wg.Done()
+
+				_, errs[i] = client.SaveDoc(created.ID, docID, []byte(`{"message":"Hello World!"}`), opts...)
+			}(i)
+		}
+
+		wg.Wait()
+
+		for _, err := range errs {
+			require.NoError(t, err)
+		}
+
+		info, err := client.GetVaultInfo(created.ID)
+		require.NoError(t, err)
+		require.Equal(t, saves, info.DocCount)
+
+		require.Equal(t, want, findDocIDs(t, client, created.ID, "type", "license"))
+	})
+
+	t.Run("Vault without tagged documents", func(t *testing.T) {
+		client, vID := newRoundTripVaultClient(t, loader)
+
+		_, err := client.SaveDoc(vID, "doc1", []byte(`{"message":"Hello World!"}`))
+		require.NoError(t, err)
+
+		require.Empty(t, findDocIDs(t, client, vID, "type", "license"))
+	})
+
+	t.Run("Invalid tags", func(t *testing.T) {
+		client, vID := newRoundTripVaultClient(t, loader)
+
+		for _, tag := range [][2]string{{"", "license"}, {"type", ""}, {"doc:type", "license"}} {
+			_, err := client.SaveDoc(vID, "doc1", []byte(`{"message":"Hello World!"}`),
+				vault.WithIndexTags(map[string]string{tag[0]: tag[1]}))
+			require.True(t, errors.Is(err, vault.ErrInvalidIndexTag), tag)
+
+			_, err = client.FindDocs(vID, tag[0], tag[1])
+			require.True(t, errors.Is(err, vault.ErrInvalidIndexTag), tag)
+		}
+
+		_, err := client.GetDocMetadata(vID, "doc1")
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+	})
+
+	t.Run("Vault not found", func(t *testing.T) {
+		client, _ := newRoundTripVaultClient(t, loader)
+
+		_, err := client.FindDocs("did:example:unknown", "type", "license")
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+	})
+}
+
+// slowInfoProvider delays returning the vault info read from its stores.
+type slowInfoProvider struct {
+	storage.Provider
+}
+
+func (p *slowInfoProvider) OpenStore(name string) (storage.Store, error) { //nolint:ireturn
+	store, err := p.Provider.OpenStore(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &slowInfoStore{Store: store}, nil
+}
+
+type slowInfoStore struct {
+	storage.Store
+}
+
+func (s *slowInfoStore) Get(key string) ([]byte, error) {
+	src, err := s.Store.Get(key)
+
+	if strings.HasPrefix(key, "info_") {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return src, err
+}
@@ -0,0 +1,357 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/igor-pavlenko/httpsignatures-go"
+	"github.com/trustbloc/edge-core/pkg/zcapld"
+
+	"github.com/trustbloc/ace/pkg/httpsig"
+	"github.com/trustbloc/ace/pkg/restapi/mw/httpsigmw"
+)
+
+// ErrUnauthorizedInvocation is returned when a request neither presents the capability of a vault nor is signed by
+// its controller.
+var ErrUnauthorizedInvocation = errors.New("unauthorized invocation")
+
+// SignatureMaxAge is how long after its (created) time the HTTP signature of a request without (expires) is
+// accepted.
+const SignatureMaxAge = 5 * time.Minute
+
+const (
+	signatureHeader = "Signature"
+	readAction      = "read"
+)
+
+// VerifyInvocation checks that the request is authorized to act on the vault. The request must either invoke the
+// EDV authorization token of the vault in a `Capability-Invocation: zcap capability="...",action="..."` header and
+// be signed by the invoker of its zcap, or carry an HTTP signature made with an authentication key of the vault's
+// controller. The accepted tokens are the ones the vault was created with and the ones last issued by RotateTokens,
+// unless revoked. GET and HEAD requests may invoke the read action, others must invoke another action of the zcap.
+//
+// HTTP signatures must cover (request-target) and either (expires) or a (created) time at most SignatureMaxAge old,
+// and the Capability-Invocation header if any.
+func (c *Client) VerifyInvocation(vaultID string, req *http.Request) error {
+	info, err := c.getVaultInfo(vaultID)
+	if err != nil {
+		return fmt.Errorf("get vault info: %w", err)
+	}
+
+	if invocation := req.Header.Get(zcapld.CapabilityInvocationHTTPHeader); invocation != "" {
+		tokens := []string{info.Auth.EDV.AuthToken}
+
+		if info.IssuedAuth != nil {
+			tokens = append(tokens, info.IssuedAuth.EDV.AuthToken)
+		}
+
+		zcap, action, err := c.resolveInvocation(invocation, tokens, "vault "+vaultID)
+		if err != nil {
+			return err
+		}
+
+		err = c.verifyChain(zcap, info)
+		if err != nil {
+			return err
+		}
+
+		if action == readAction && req.Method != http.MethodGet && req.Method != http.MethodHead {
+			return fmt.Errorf("%w: the read action does not allow %s requests", ErrUnauthorizedInvocation, req.Method)
+		}
+
+		return c.verifyInvoker(zcap, req)
+	}
+
+	if req.Header.Get(signatureHeader) == "" {
+		return fmt.Errorf("%w: a capability invocation or an HTTP signature is required", ErrUnauthorizedInvocation)
+	}
+
+	signer, err := c.verifySignature(req)
+	if err != nil {
+		return err
+	}
+
+	if signer != info.controller() {
+		return fmt.Errorf("%w: %s is not the controller of vault %s", ErrUnauthorizedInvocation, signer, vaultID)
+	}
+
+	return nil
+}
+
+// VerifyAuthorizationInvocation checks that the request is made by the requesting party of the authorization: it
+// must invoke the EDV or KMS token of the authorization, see VerifyInvocation, and be signed by its requesting
+// party. The status of the authorization is left to the endpoint.
+func (c *Client) VerifyAuthorizationInvocation(vaultID, authID string, req *http.Request) error {
+	a, err := c.getAuthorization(vaultID, authID)
+	if err != nil {
+		return err
+	}
+
+	invocation := req.Header.Get(zcapld.CapabilityInvocationHTTPHeader)
+	if invocation == "" || a.Tokens == nil {
+		return fmt.Errorf("%w: the capability of authorization %s is required", ErrUnauthorizedInvocation, authID)
+	}
+
+	zcap, _, err := c.resolveInvocation(invocation, []string{a.Tokens.EDV, a.Tokens.KMS}, "authorization "+authID)
+	if err != nil {
+		return err
+	}
+
+	return c.verifyInvoker(zcap, req)
+}
+
+// WithVaultOwner issues the tokens of a vault whose key is held by the vault server to the verification method
+// which signed the request creating it, instead of the key of the vault, so that the owner can sign the requests
+// invoking them, see VerifyInvocation. The request must carry an HTTP signature made with the verification method,
+// an authentication method of its DID.
+func WithVaultOwner(req *http.Request) CreateVaultOpt {
+	return func(opts *createVaultOpts) {
+		opts.owner = req
+	}
+}
+
+// newVaultOwner returns the verification method which signed the request of WithVaultOwner, empty without one.
+func (c *Client) newVaultOwner(opts []CreateVaultOpt) (string, error) {
+	options := &createVaultOpts{}
+
+	for _, fn := range opts {
+		fn(options)
+	}
+
+	if options.owner == nil {
+		return "", nil
+	}
+
+	owner := httpsig.KeyID(options.owner)
+
+	err := c.verifyMethodSignature(owner, options.owner)
+	if err != nil {
+		return "", err
+	}
+
+	return owner, nil
+}
+
+// VerifyDocURIInvocation checks that the request is authorized to act on the vault of the document whose EDV
+// document is at the URI, see VerifyInvocation. Fails with ErrInvalidDocURI or ErrDocumentNotFound if the URI is
+// not the one of a document.
+func (c *Client) VerifyDocURIInvocation(uri string, req *http.Request) error {
+	vaultID, _, err := c.findDocByURI(uri)
+	if err != nil {
+		return err
+	}
+
+	return c.VerifyInvocation(vaultID, req)
+}
+
+// VerifyControllerInvocation checks that the request is authorized to act on all the vaults of the controller: it
+// must carry an HTTP signature made with an authentication key of the controller, see VerifyInvocation.
+func (c *Client) VerifyControllerInvocation(controller string, req *http.Request) error {
+	if req.Header.Get(signatureHeader) == "" {
+		return fmt.Errorf("%w: an HTTP signature is required", ErrUnauthorizedInvocation)
+	}
+
+	signer, err := c.verifySignature(req)
+	if err != nil {
+		return err
+	}
+
+	if signer != controller {
+		return fmt.Errorf("%w: %s is not %s", ErrUnauthorizedInvocation, signer, controller)
+	}
+
+	return nil
+}
+
+// resolveInvocation returns the zcap invoked by the Capability-Invocation header, which must be one of the tokens,
+// and the invoked action, which the zcap must allow. Tokens are compared as a whole, so that only the zcaps
+// recorded by the vault server are resolved. The holder of the tokens is named in errors.
+func (c *Client) resolveInvocation(invocation string, tokens []string,
+	holder string) (*zcapld.Capability, string, error) {
+	capability, action, ok := parseInvocation(invocation)
+	if !ok {
+		return nil, "", fmt.Errorf("%w: invalid capability invocation", ErrUnauthorizedInvocation)
+	}
+
+	var token string
+
+	for _, t := range tokens {
+		if t != "" && subtle.ConstantTimeCompare([]byte(capability), []byte(t)) == 1 {
+			token = t
+		}
+	}
+
+	if token == "" {
+		return nil, "", fmt.Errorf("%w: the capability is not the one of %s", ErrUnauthorizedInvocation, holder)
+	}
+
+	zcap, err := zcapld.DecompressZCAP(token)
+	if err != nil {
+		return nil, "", fmt.Errorf("uncompressZCAP: %w", err)
+	}
+
+	allowed := false
+
+	for _, a := range zcap.AllowedAction {
+		allowed = allowed || a == action
+	}
+
+	if !allowed {
+		return nil, "", fmt.Errorf("%w: the capability of %s does not allow the %q action",
+			ErrUnauthorizedInvocation, holder, action)
+	}
+
+	_, err = c.GetRevocation(zcap.ID)
+	if err == nil {
+		return nil, "", fmt.Errorf("%w: the capability of %s is revoked", ErrUnauthorizedInvocation, holder)
+	}
+
+	if !errors.Is(err, storage.ErrDataNotFound) {
+		return nil, "", fmt.Errorf("get revocation: %w", err)
+	}
+
+	return zcap, action, nil
+}
+
+// verifyChain checks that the zcap is the EDV zcap the vault was created with or was delegated from it.
+func (c *Client) verifyChain(zcap *zcapld.Capability, info *vaultInfo) error {
+	root, err := zcapld.DecompressZCAP(info.Auth.EDV.AuthToken)
+	if err != nil {
+		return fmt.Errorf("edv uncompressZCAP: %w", err)
+	}
+
+	if zcap.ID != root.ID && zcap.Parent != root.ID {
+		return fmt.Errorf("%w: the capability is not delegated from the one of the vault", ErrUnauthorizedInvocation)
+	}
+
+	return nil
+}
+
+// verifyInvoker checks that the request carries an HTTP signature, covering the Capability-Invocation header, made
+// by the invoker of the zcap: either its verification method or an authentication key of its DID.
+func (c *Client) verifyInvoker(zcap *zcapld.Capability, req *http.Request) error {
+	if req.Header.Get(signatureHeader) == "" {
+		return fmt.Errorf("%w: an HTTP signature by %s is required", ErrUnauthorizedInvocation, zcap.Invoker)
+	}
+
+	if _, err := c.verifySignature(req, zcapld.CapabilityInvocationHTTPHeader); err != nil {
+		return err
+	}
+
+	keyID := httpsig.KeyID(req)
+
+	if keyID != zcap.Invoker && strings.Split(keyID, "#")[0] != zcap.Invoker {
+		return fmt.Errorf("%w: the request is signed with %s instead of %s", ErrUnauthorizedInvocation, keyID,
+			zcap.Invoker)
+	}
+
+	return nil
+}
+
+// verifyMethodSignature checks that the request carries an HTTP signature made with the verification method.
+func (c *Client) verifyMethodSignature(didURL string, req *http.Request) error {
+	if req == nil || req.Header.Get(signatureHeader) == "" {
+		return fmt.Errorf("%w: an HTTP signature by %s is required", ErrUnauthorizedInvocation, didURL)
+	}
+
+	if _, err := c.verifySignature(req); err != nil {
+		return err
+	}
+
+	if keyID := httpsig.KeyID(req); keyID != didURL {
+		return fmt.Errorf("%w: the request is signed with %s instead of %s", ErrUnauthorizedInvocation, keyID, didURL)
+	}
+
+	return nil
+}
+
+// verifySignature returns the DID which signed the request. The signature must cover the headers, see
+// verifySignedHeaders.
+func (c *Client) verifySignature(req *http.Request, headers ...string) (string, error) {
+	err := verifySignedHeaders(req, headers...)
+	if err != nil {
+		return "", err
+	}
+
+	verified, signer := httpsig.NewVerifier(&httpsigmw.PublicKeyResolver{VDR: c.registry}).VerifyRequest(req)
+	if !verified {
+		return "", fmt.Errorf("%w: invalid HTTP signature", ErrUnauthorizedInvocation)
+	}
+
+	return signer, nil
+}
+
+// verifySignedHeaders checks that the HTTP signature of the request covers (request-target), the headers, and
+// either (expires), which is checked by the verifier, or a (created) time at most SignatureMaxAge old, so that
+// signed requests cannot be replayed to other endpoints or forever.
+func verifySignedHeaders(req *http.Request, headers ...string) error {
+	sig, errParse := httpsignatures.NewParser().ParseSignatureHeader(req.Header.Get(signatureHeader))
+	if errParse != nil {
+		return fmt.Errorf("%w: invalid HTTP signature: %s", ErrUnauthorizedInvocation, errParse.Error())
+	}
+
+	signed := map[string]bool{}
+
+	for _, h := range sig.Headers {
+		signed[strings.ToLower(h)] = true
+	}
+
+	for _, h := range append([]string{"(request-target)"}, headers...) {
+		if !signed[strings.ToLower(h)] {
+			return fmt.Errorf("%w: the HTTP signature does not cover %s", ErrUnauthorizedInvocation, h)
+		}
+	}
+
+	switch {
+	case signed["(expires)"]:
+		return nil
+	case !signed["(created)"]:
+		return fmt.Errorf("%w: the HTTP signature covers neither (created) nor (expires)", ErrUnauthorizedInvocation)
+	case time.Since(sig.Created) > SignatureMaxAge:
+		return fmt.Errorf("%w: the HTTP signature was created more than %s ago", ErrUnauthorizedInvocation,
+			SignatureMaxAge)
+	default:
+		return nil
+	}
+}
+
+// parseInvocation returns the capability and action parameters of a `zcap capability="...",action="..."` header.
+func parseInvocation(invocation string) (string, string, bool) {
+	const scheme = "zcap "
+
+	if len(invocation) < len(scheme) || !strings.EqualFold(invocation[:len(scheme)], scheme) {
+		return "", "", false
+	}
+
+	params := map[string]string{}
+
+	for _, param := range strings.Split(invocation[len(scheme):], ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok {
+			continue
+		}
+
+		unquoted, err := strconv.Unquote(value)
+		if err != nil {
+			return "", "", false
+		}
+
+		params[name] = unquoted
+	}
+
+	capability, action := params["capability"], params["action"]
+
+	return capability, action, capability != "" && action != ""
+}
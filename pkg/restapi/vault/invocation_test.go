@@ -0,0 +1,395 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	"github.com/hyperledger/aries-framework-go/pkg/vdr/fingerprint"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/zcapld"
+
+	"github.com/trustbloc/ace/pkg/httpsig"
+	"github.com/trustbloc/ace/pkg/internal/testutil"
+	"github.com/trustbloc/ace/pkg/restapi/vault"
+)
+
+func TestClient_VerifyInvocation(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	_, keyID := fingerprint.CreateDIDKey(pub)
+
+	ownerPub, ownerPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	_, ownerKeyID := fingerprint.CreateDIDKey(ownerPub)
+
+	provider := mem.NewProvider()
+
+	client, err := vault.NewClient("", "https://edv.example.com", nil, provider, testutil.DocumentLoader(t))
+	require.NoError(t, err)
+
+	store, err := provider.OpenStore("vault")
+	require.NoError(t, err)
+
+	root := newZCAP(t, "urn:uuid:root", "urn:uuid:edv", keyID)
+	issued := newZCAP(t, "urn:uuid:issued", "urn:uuid:root", ownerKeyID)
+	kms := newZCAP(t, "urn:uuid:kms", "urn:uuid:keystore", keyID)
+	stray := newZCAP(t, "urn:uuid:stray", "urn:uuid:other", ownerKeyID)
+
+	saveInfo := func(t *testing.T, vaultID, edvToken, issuedToken string) {
+		t.Helper()
+
+		require.NoError(t, store.Put("info_"+vaultID, []byte(`{"did_url":"`+keyID+`",`+
+			`"auth":{"edv":{"uri":"/encrypted-data-vaults/DWPPbEVn1afJY4We3kpQmq","authToken":"`+edvToken+`"},`+
+			`"kms":{"uri":"/v1/keystores/c0ekinlioud42c84qs7g","authToken":"`+kms+`"}},`+
+			`"issued_auth":{"edv":{"uri":"/encrypted-data-vaults/DWPPbEVn1afJY4We3kpQmq","authToken":"`+issuedToken+`"},`+
+			`"kms":{"uri":"/v1/keystores/c0ekinlioud42c84qs7g","authToken":"`+kms+`"}}}`)))
+	}
+
+	saveInfo(t, "vault1", root, issued)
+	saveInfo(t, "vault2", root, stray)
+
+	newRequest := func(t *testing.T, method string) *http.Request {
+		t.Helper()
+
+		req, err := http.NewRequest(method, "https://vault.example.com/vaults/vault1/docs",
+			bytes.NewBufferString(`{"id":"doc1","content":{}}`))
+		require.NoError(t, err)
+
+		return req
+	}
+
+	invocationHeaders := []string{"(request-target)", "(created)", "capability-invocation"}
+
+	invoke := func(t *testing.T, method, capability, action string, priv ed25519.PrivateKey,
+		keyID string) *http.Request {
+		t.Helper()
+
+		req := newRequest(t, method)
+		req.Header.Set("Capability-Invocation", `zcap capability="`+capability+`",action="`+action+`"`)
+
+		if priv != nil {
+			signRequestWith(t, req, invocationHeaders, priv, keyID)
+		}
+
+		return req
+	}
+
+	t.Run("Capability of the vault", func(t *testing.T) {
+		require.NoError(t, client.VerifyInvocation("vault1", invoke(t, http.MethodPost, root, "write", priv, keyID)))
+		require.NoError(t, client.VerifyInvocation("vault1", invoke(t, http.MethodGet, root, "read", priv, keyID)))
+	})
+
+	t.Run("Capability issued to the owner", func(t *testing.T) {
+		req := invoke(t, http.MethodPost, issued, "write", ownerPriv, ownerKeyID)
+		require.NoError(t, client.VerifyInvocation("vault1", req))
+
+		err := client.VerifyInvocation("vault1", invoke(t, http.MethodPost, issued, "write", priv, keyID))
+		require.True(t, errors.Is(err, vault.ErrUnauthorizedInvocation))
+		require.Contains(t, err.Error(), "the request is signed with "+keyID+" instead of "+ownerKeyID)
+	})
+
+	t.Run("Capability not signed by its invoker", func(t *testing.T) {
+		err := client.VerifyInvocation("vault1", invoke(t, http.MethodPost, root, "write", nil, ""))
+		require.True(t, errors.Is(err, vault.ErrUnauthorizedInvocation))
+		require.Contains(t, err.Error(), "an HTTP signature by "+keyID+" is required")
+
+		// a signature which does not cover the invocation could be moved to another one
+		req := invoke(t, http.MethodPost, root, "write", nil, "")
+		signRequest(t, req, priv, keyID)
+
+		err = client.VerifyInvocation("vault1", req)
+		require.True(t, errors.Is(err, vault.ErrUnauthorizedInvocation))
+		require.Contains(t, err.Error(), "the HTTP signature does not cover capability-invocation")
+	})
+
+	t.Run("Other capability", func(t *testing.T) {
+		for _, invocation := range []string{
+			`zcap capability="` + kms + `",action="write"`,
+			`zcap capability="` + stray + `",action="write"`,
+			`zcap action="write"`,
+			`zcap capability="` + root + `"`,
+			`zcap capability=` + root + `,action="write"`,
+			`bearer capability="` + root + `",action="write"`,
+		} {
+			req := newRequest(t, http.MethodPost)
+			req.Header.Set("Capability-Invocation", invocation)
+			signRequestWith(t, req, invocationHeaders, priv, keyID)
+
+			err := client.VerifyInvocation("vault1", req)
+			require.True(t, errors.Is(err, vault.ErrUnauthorizedInvocation), invocation)
+		}
+	})
+
+	t.Run("Capability not delegated from the one of the vault", func(t *testing.T) {
+		err := client.VerifyInvocation("vault2", invoke(t, http.MethodPost, stray, "write", ownerPriv, ownerKeyID))
+		require.True(t, errors.Is(err, vault.ErrUnauthorizedInvocation))
+		require.Contains(t, err.Error(), "the capability is not delegated from the one of the vault")
+	})
+
+	t.Run("Action not allowed", func(t *testing.T) {
+		err := client.VerifyInvocation("vault1", invoke(t, http.MethodPost, root, "delete", priv, keyID))
+		require.True(t, errors.Is(err, vault.ErrUnauthorizedInvocation))
+		require.Contains(t, err.Error(), `does not allow the "delete" action`)
+
+		err = client.VerifyInvocation("vault1", invoke(t, http.MethodPost, root, "read", priv, keyID))
+		require.True(t, errors.Is(err, vault.ErrUnauthorizedInvocation))
+		require.Contains(t, err.Error(), "the read action does not allow POST requests")
+	})
+
+	t.Run("Signed by the controller", func(t *testing.T) {
+		req := newRequest(t, http.MethodPost)
+		signRequest(t, req, priv, keyID)
+
+		require.NoError(t, client.VerifyInvocation("vault1", req))
+	})
+
+	t.Run("Signed by another DID", func(t *testing.T) {
+		otherPub, otherPriv, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		other, otherKeyID := fingerprint.CreateDIDKey(otherPub)
+
+		req := newRequest(t, http.MethodPost)
+		signRequest(t, req, otherPriv, otherKeyID)
+
+		err = client.VerifyInvocation("vault1", req)
+		require.True(t, errors.Is(err, vault.ErrUnauthorizedInvocation))
+		require.Contains(t, err.Error(), other+" is not the controller of vault vault1")
+	})
+
+	t.Run("Invalid signature", func(t *testing.T) {
+		req := newRequest(t, http.MethodPost)
+		signRequest(t, req, priv, keyID)
+		req.URL.Path = "/vaults/vault1/authorizations"
+
+		err := client.VerifyInvocation("vault1", req)
+		require.True(t, errors.Is(err, vault.ErrUnauthorizedInvocation))
+		require.Contains(t, err.Error(), "invalid HTTP signature")
+	})
+
+	t.Run("Signature that can be replayed", func(t *testing.T) {
+		for headers, msg := range map[string]string{
+			"(created),date":          "the HTTP signature does not cover (request-target)",
+			"(request-target),date":   "the HTTP signature covers neither (created) nor (expires)",
+			"(request-target),digest": "the HTTP signature covers neither (created) nor (expires)",
+		} {
+			req := newRequest(t, http.MethodPost)
+			signRequestWith(t, req, strings.Split(headers, ","), priv, keyID)
+
+			err := client.VerifyInvocation("vault1", req)
+			require.True(t, errors.Is(err, vault.ErrUnauthorizedInvocation), headers)
+			require.Contains(t, err.Error(), msg, headers)
+		}
+
+		req := newRequest(t, http.MethodPost)
+		signRequest(t, req, priv, keyID)
+
+		created := strconv.FormatInt(time.Now().Add(-vault.SignatureMaxAge-time.Minute).Unix(), 10)
+		req.Header.Set("Signature", regexp.MustCompile(`created=\d+`).ReplaceAllString(req.Header.Get("Signature"),
+			"created="+created))
+
+		err := client.VerifyInvocation("vault1", req)
+		require.True(t, errors.Is(err, vault.ErrUnauthorizedInvocation))
+		require.Contains(t, err.Error(), "the HTTP signature was created more than 5m0s ago")
+	})
+
+	t.Run("Revoked capability", func(t *testing.T) {
+		require.NoError(t, store.Put("revocation_urn:uuid:issued", []byte(`{"zcapID":"urn:uuid:issued"}`)))
+
+		err := client.VerifyInvocation("vault1", invoke(t, http.MethodPost, issued, "write", ownerPriv, ownerKeyID))
+		require.True(t, errors.Is(err, vault.ErrUnauthorizedInvocation))
+		require.Contains(t, err.Error(), "the capability of vault vault1 is revoked")
+	})
+
+	t.Run("Neither capability nor signature", func(t *testing.T) {
+		err := client.VerifyInvocation("vault1", newRequest(t, http.MethodPost))
+		require.True(t, errors.Is(err, vault.ErrUnauthorizedInvocation))
+	})
+
+	t.Run("Unknown vault", func(t *testing.T) {
+		err := client.VerifyInvocation("vault3", newRequest(t, http.MethodPost))
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+	})
+}
+
+func TestClient_VerifyAuthorizationInvocation(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	requestingParty, keyID := fingerprint.CreateDIDKey(pub)
+
+	provider := mem.NewProvider()
+
+	client, err := vault.NewClient("", "https://edv.example.com", nil, provider, testutil.DocumentLoader(t))
+	require.NoError(t, err)
+
+	store, err := provider.OpenStore("vault")
+	require.NoError(t, err)
+
+	edvToken := newZCAP(t, "urn:uuid:edv", "urn:uuid:root", requestingParty)
+	kmsToken := newZCAP(t, "urn:uuid:kms", "urn:uuid:keystore", requestingParty)
+
+	require.NoError(t, store.Put("authorization_vault1_auth1", []byte(`{"id":"auth1",`+
+		`"requestingParty":"`+requestingParty+`","authTokens":{"edv":"`+edvToken+`","kms":"`+kmsToken+`"}}`)))
+
+	invoke := func(t *testing.T, capability string, priv ed25519.PrivateKey, keyID string) *http.Request {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodPost, "https://vault.example.com/vaults/vault1/authorizations/auth1/uses",
+			nil)
+		require.NoError(t, err)
+
+		req.Header.Set("Capability-Invocation", `zcap capability="`+capability+`",action="read"`)
+		signRequestWith(t, req, []string{"(request-target)", "(created)", "capability-invocation"}, priv, keyID)
+
+		return req
+	}
+
+	t.Run("Capability of the authorization signed by its requesting party", func(t *testing.T) {
+		require.NoError(t, client.VerifyAuthorizationInvocation("vault1", "auth1", invoke(t, edvToken, priv, keyID)))
+		require.NoError(t, client.VerifyAuthorizationInvocation("vault1", "auth1", invoke(t, kmsToken, priv, keyID)))
+	})
+
+	t.Run("Signed by another DID", func(t *testing.T) {
+		otherPub, otherPriv, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		_, otherKeyID := fingerprint.CreateDIDKey(otherPub)
+
+		err = client.VerifyAuthorizationInvocation("vault1", "auth1", invoke(t, edvToken, otherPriv, otherKeyID))
+		require.True(t, errors.Is(err, vault.ErrUnauthorizedInvocation))
+		require.Contains(t, err.Error(), "instead of "+requestingParty)
+	})
+
+	t.Run("Other capability", func(t *testing.T) {
+		other := newZCAP(t, "urn:uuid:other", "urn:uuid:root", requestingParty)
+
+		err := client.VerifyAuthorizationInvocation("vault1", "auth1", invoke(t, other, priv, keyID))
+		require.True(t, errors.Is(err, vault.ErrUnauthorizedInvocation))
+		require.Contains(t, err.Error(), "the capability is not the one of authorization auth1")
+
+		req := invoke(t, edvToken, priv, keyID)
+		req.Header.Del("Capability-Invocation")
+
+		err = client.VerifyAuthorizationInvocation("vault1", "auth1", req)
+		require.True(t, errors.Is(err, vault.ErrUnauthorizedInvocation))
+	})
+
+	t.Run("Unknown authorization", func(t *testing.T) {
+		err := client.VerifyAuthorizationInvocation("vault1", "auth2", invoke(t, edvToken, priv, keyID))
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+	})
+}
+
+func TestClient_VerifyControllerInvocation(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	controller, keyID := fingerprint.CreateDIDKey(pub)
+
+	client, err := vault.NewClient("", "https://edv.example.com", nil, mem.NewProvider(),
+		testutil.DocumentLoader(t))
+	require.NoError(t, err)
+
+	newRequest := func(t *testing.T) *http.Request {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodGet, "https://vault.example.com/vaults?controller="+controller, nil)
+		require.NoError(t, err)
+
+		return req
+	}
+
+	t.Run("Signed by the controller", func(t *testing.T) {
+		req := newRequest(t)
+		signRequest(t, req, priv, keyID)
+
+		require.NoError(t, client.VerifyControllerInvocation(controller, req))
+	})
+
+	t.Run("Signed by another DID", func(t *testing.T) {
+		otherPub, otherPriv, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		other, otherKeyID := fingerprint.CreateDIDKey(otherPub)
+
+		req := newRequest(t)
+		signRequest(t, req, otherPriv, otherKeyID)
+
+		err = client.VerifyControllerInvocation(controller, req)
+		require.True(t, errors.Is(err, vault.ErrUnauthorizedInvocation))
+		require.Contains(t, err.Error(), other+" is not "+controller)
+	})
+
+	t.Run("Invalid signature", func(t *testing.T) {
+		req := newRequest(t)
+		signRequest(t, req, priv, keyID)
+		req.URL.RawQuery = "controller=did:example:other"
+
+		err := client.VerifyControllerInvocation(controller, req)
+		require.True(t, errors.Is(err, vault.ErrUnauthorizedInvocation))
+		require.Contains(t, err.Error(), "invalid HTTP signature")
+	})
+
+	t.Run("Capabilities are not accepted", func(t *testing.T) {
+		req := newRequest(t)
+		req.Header.Set("Capability-Invocation", `zcap capability="edv-token",action="read"`)
+
+		err := client.VerifyControllerInvocation(controller, req)
+		require.True(t, errors.Is(err, vault.ErrUnauthorizedInvocation))
+		require.Contains(t, err.Error(), "an HTTP signature is required")
+	})
+}
+
+func signRequest(t *testing.T, req *http.Request, priv ed25519.PrivateKey, keyID string) {
+	t.Helper()
+
+	config := httpsig.DefaultGetSignerConfig()
+	if req.Method != http.MethodGet {
+		config = httpsig.DefaultPostSignerConfig()
+	}
+
+	require.NoError(t, httpsig.NewSigner(config, priv).SignRequest(keyID, req))
+}
+
+func signRequestWith(t *testing.T, req *http.Request, headers []string, priv ed25519.PrivateKey, keyID string) {
+	t.Helper()
+
+	require.NoError(t, httpsig.NewSigner(httpsig.SignerConfig{Headers: headers}, priv).SignRequest(keyID, req))
+}
+
+// newZCAP returns a compressed EDV zcap allowing to read and write, without proof: the vault server only resolves
+// the zcaps it recorded.
+func newZCAP(t *testing.T, id, parent, invoker string) string {
+	t.Helper()
+
+	compressed, err := zcapld.CompressZCAP(&zcapld.Capability{
+		Context:          zcapld.SecurityContextV2,
+		ID:               id,
+		Invoker:          invoker,
+		Parent:           parent,
+		AllowedAction:    []string{"read", "write"},
+		InvocationTarget: zcapld.InvocationTarget{ID: "DWPPbEVn1afJY4We3kpQmq", Type: "urn:edv:vault"},
+	})
+	require.NoError(t, err)
+
+	return compressed
+}
@@ -0,0 +1,77 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+)
+
+// DefaultKeyType is the type of the key-wrapping keys the documents of new vaults are encrypted to, unless
+// configured otherwise. Vaults created before the key type was recorded use it.
+const DefaultKeyType = kms.NISTP256ECDHKWType
+
+// ErrUnsupportedKeyType is returned when a vault is created with a key-wrapping key type the vault server does not
+// support.
+var ErrUnsupportedKeyType = errors.New("unsupported key type")
+
+// SupportedKeyTypes are the types of key-wrapping keys documents can be encrypted to.
+// nolint:gochecknoglobals
+var SupportedKeyTypes = []kms.KeyType{kms.NISTP256ECDHKWType, kms.NISTP384ECDHKWType, kms.X25519ECDHKWType}
+
+// WithKeyType sets the type of the key-wrapping keys of new vaults, one of SupportedKeyTypes. Existing vaults keep
+// the type they were created with. Defaults to DefaultKeyType.
+func WithKeyType(keyType kms.KeyType) Opt {
+	return func(vault *Client) {
+		vault.keyType = keyType
+	}
+}
+
+// WithVaultKeyType creates the vault with the type of key-wrapping keys instead of the one of the client.
+func WithVaultKeyType(keyType kms.KeyType) CreateVaultOpt {
+	return func(opts *createVaultOpts) {
+		opts.keyType = keyType
+	}
+}
+
+// CheckKeyType fails with ErrUnsupportedKeyType if the key type is not one of SupportedKeyTypes.
+func CheckKeyType(keyType kms.KeyType) error {
+	for _, supported := range SupportedKeyTypes {
+		if keyType == supported {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s, must be one of %v", ErrUnsupportedKeyType, keyType, SupportedKeyTypes)
+}
+
+// newVaultKeyType returns the type of the key-wrapping keys of a new vault.
+func (c *Client) newVaultKeyType(opts []CreateVaultOpt) (kms.KeyType, error) {
+	options := &createVaultOpts{keyType: c.keyType}
+
+	for _, fn := range opts {
+		fn(options)
+	}
+
+	err := CheckKeyType(options.keyType)
+	if err != nil {
+		return "", err
+	}
+
+	return options.keyType, nil
+}
+
+// keyType returns the type of the key-wrapping keys of the vault.
+func (v *vaultInfo) keyType() kms.KeyType {
+	if v.KeyType == "" {
+		return DefaultKeyType
+	}
+
+	return v.KeyType
+}
@@ -0,0 +1,136 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/piprate/json-gold/ld"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/ace/pkg/internal/testutil"
+	"github.com/trustbloc/ace/pkg/restapi/vault"
+)
+
+func TestClient_KeyType(t *testing.T) {
+	loader := testutil.DocumentLoader(t)
+
+	for _, keyType := range vault.SupportedKeyTypes {
+		t.Run("Encrypts documents to keys of the type of the vault: "+string(keyType), func(t *testing.T) {
+			client, created := newKeyTypeVaultClient(t, loader, vault.WithKeyType(kms.X25519ECDHKWType))
+
+			result, err := client.CreateVault(vault.WithVaultKeyType(keyType))
+			require.NoError(t, err)
+
+			info, err := client.GetVaultInfo(result.ID)
+			require.NoError(t, err)
+			require.Equal(t, keyType, info.KeyType)
+
+			_, err = client.SaveDoc(result.ID, "doc", []byte(`{"message":"Hello World!"}`))
+			require.NoError(t, err)
+
+			doc, err := client.GetDoc(result.ID, "doc")
+			require.NoError(t, err)
+			require.JSONEq(t, `{"message":"Hello World!"}`, string(doc))
+
+			_, err = client.RekeyVault(result.ID)
+			require.NoError(t, err)
+
+			require.Equal(t, []kms.KeyType{keyType, keyType}, created())
+		})
+	}
+
+	t.Run("New vaults use the key type of the client", func(t *testing.T) {
+		client, _ := newKeyTypeVaultClient(t, loader, vault.WithKeyType(kms.NISTP384ECDHKWType))
+
+		result, err := client.CreateVault()
+		require.NoError(t, err)
+
+		info, err := client.GetVaultInfo(result.ID)
+		require.NoError(t, err)
+		require.Equal(t, kms.NISTP384ECDHKWType, info.KeyType)
+	})
+
+	t.Run("Vaults created before key types were recorded use the default", func(t *testing.T) {
+		client, vID := newRoundTripVaultClient(t, loader, vault.WithKeyType(kms.X25519ECDHKWType))
+
+		info, err := client.GetVaultInfo(vID)
+		require.NoError(t, err)
+		require.Equal(t, vault.DefaultKeyType, info.KeyType)
+	})
+
+	t.Run("Error if the key type is not supported", func(t *testing.T) {
+		client, _ := newKeyTypeVaultClient(t, loader)
+
+		_, err := client.CreateVault(vault.WithVaultKeyType(kms.ED25519Type))
+		require.True(t, errors.Is(err, vault.ErrUnsupportedKeyType))
+
+		_, err = vault.NewClient("", "", nil, mem.NewProvider(), loader, vault.WithKeyType("RSA"))
+		require.True(t, errors.Is(err, vault.ErrUnsupportedKeyType))
+	})
+}
+
+// newKeyTypeVaultClient returns a client creating vaults in fake EDV and WebKMS servers, along with a function
+// returning the types of the keys created in the WebKMS keystores.
+func newKeyTypeVaultClient(t *testing.T, loader ld.DocumentLoader,
+	opts ...vault.Opt) (*vault.Client, func() []kms.KeyType) {
+	t.Helper()
+
+	var (
+		mu       sync.Mutex
+		keyTypes []kms.KeyType
+	)
+
+	kmsHandler := newKMSHandler(t)
+
+	remoteKMS := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/keys") {
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+
+			var req struct {
+				KeyType kms.KeyType `json:"key_type"`
+			}
+
+			require.NoError(t, json.Unmarshal(body, &req))
+
+			mu.Lock()
+			keyTypes = append(keyTypes, req.KeyType)
+			mu.Unlock()
+
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		kmsHandler(w, r)
+	}))
+	t.Cleanup(remoteKMS.Close)
+
+	edv := httptest.NewServer(newEDVHandler(t))
+	t.Cleanup(edv.Close)
+
+	provider := mem.NewProvider()
+
+	client, err := vault.NewClient(remoteKMS.URL, edv.URL, newLocalKms(t, provider), provider, loader, opts...)
+	require.NoError(t, err)
+
+	return client, func() []kms.KeyType {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return append([]kms.KeyType{}, keyTypes...)
+	}
+}
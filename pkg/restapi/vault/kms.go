@@ -0,0 +1,78 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrKMSURLNotAllowed is returned when a vault is created with a WebKMS base URL that is not one of those allowed
+// with WithKMSURLs.
+var ErrKMSURLNotAllowed = errors.New("KMS URL not allowed")
+
+// WithKMSURLs allows vaults to be created with their own WebKMS base URL, one of the given ones, instead of the
+// one given to NewClient.
+func WithKMSURLs(urls []string) Opt {
+	return func(vault *Client) {
+		vault.kmsURLs = append(vault.kmsURLs, urls...)
+	}
+}
+
+// WithVaultKMSURL creates the keystore of the vault, and the keys of its documents, in the WebKMS at the base URL
+// instead of the one of the client. The URL must be allowed with WithKMSURLs.
+func WithVaultKMSURL(kmsURL string) CreateVaultOpt {
+	return func(opts *createVaultOpts) {
+		opts.kmsURL = kmsURL
+	}
+}
+
+// newVaultKMSURL returns the base URL of the WebKMS of a new vault.
+func (c *Client) newVaultKMSURL(opts []CreateVaultOpt) (string, error) {
+	options := &createVaultOpts{}
+
+	for _, fn := range opts {
+		fn(options)
+	}
+
+	if options.kmsURL == "" {
+		return c.remoteKMSURL, nil
+	}
+
+	kmsURL := strings.TrimSuffix(options.kmsURL, "/")
+
+	for _, allowed := range c.kmsURLs {
+		if kmsURL == strings.TrimSuffix(allowed, "/") {
+			return kmsURL, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrKMSURLNotAllowed, options.kmsURL)
+}
+
+// kmsURL returns the base URL of the WebKMS of the vault.
+func (c *Client) kmsURL(info *vaultInfo) string {
+	if info.KMSURL == "" {
+		return c.remoteKMSURL
+	}
+
+	return info.KMSURL
+}
+
+// buildKMSURL resolves the URI, returned by the WebKMS of the vault, against its base URL.
+func (c *Client) buildKMSURL(info *vaultInfo, uri string) string {
+	return resolveKMSURL(c.kmsURL(info), uri)
+}
+
+func resolveKMSURL(base, uri string) string {
+	if strings.HasPrefix(uri, "/") {
+		return base + uri
+	}
+
+	return uri
+}
@@ -0,0 +1,128 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/ace/pkg/internal/testutil"
+	"github.com/trustbloc/ace/pkg/restapi/vault"
+)
+
+func TestClient_VaultKMSURL(t *testing.T) {
+	loader := testutil.DocumentLoader(t)
+
+	newKMS := func(t *testing.T) (*httptest.Server, *int32) {
+		t.Helper()
+
+		var requests int32
+
+		kmsHandler := newKMSHandler(t)
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+
+			kmsHandler(w, r)
+		}))
+		t.Cleanup(server.Close)
+
+		return server, &requests
+	}
+
+	newClient := func(t *testing.T, kmsURL string, opts ...vault.Opt) *vault.Client {
+		t.Helper()
+
+		edv := httptest.NewServer(newEDVHandler(t))
+		t.Cleanup(edv.Close)
+
+		provider := mem.NewProvider()
+
+		client, err := vault.NewClient(kmsURL, edv.URL, newLocalKms(t, provider), provider, loader, opts...)
+		require.NoError(t, err)
+
+		return client
+	}
+
+	t.Run("Routes the KMS requests of the vault to its KMS", func(t *testing.T) {
+		defaultKMS, defaultRequests := newKMS(t)
+		otherKMS, otherRequests := newKMS(t)
+
+		client := newClient(t, defaultKMS.URL, vault.WithKMSURLs([]string{otherKMS.URL + "/"}))
+
+		result, err := client.CreateVault(vault.WithVaultKMSURL(otherKMS.URL))
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(result.KMS.URI, otherKMS.URL+"/"))
+
+		info, err := client.GetVaultInfo(result.ID)
+		require.NoError(t, err)
+		require.Equal(t, result.KMS.URI, info.KMSURI)
+
+		_, err = client.SaveDoc(result.ID, "doc", []byte(`{"message":"Hello World!"}`))
+		require.NoError(t, err)
+
+		doc, err := client.GetDoc(result.ID, "doc")
+		require.NoError(t, err)
+		require.JSONEq(t, `{"message":"Hello World!"}`, string(doc))
+
+		_, err = client.SaveDoc(result.ID, "tagged", []byte(`{}`),
+			vault.WithIndexTags(map[string]string{"kind": "test"}))
+		require.NoError(t, err)
+
+		_, err = client.CreateAuthorization(result.ID, "did:example:rp", &vault.AuthorizationsScope{
+			Target: "doc",
+			Caveats: []vault.Caveat{{
+				Type:     "expiry",
+				Duration: 600,
+			}},
+		})
+		require.NoError(t, err)
+
+		require.Zero(t, atomic.LoadInt32(defaultRequests))
+		require.NotZero(t, atomic.LoadInt32(otherRequests))
+	})
+
+	t.Run("Vaults use the KMS of the client by default", func(t *testing.T) {
+		defaultKMS, defaultRequests := newKMS(t)
+		otherKMS, otherRequests := newKMS(t)
+
+		client := newClient(t, defaultKMS.URL, vault.WithKMSURLs([]string{otherKMS.URL}))
+
+		result, err := client.CreateVault()
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(result.KMS.URI, defaultKMS.URL+"/"))
+
+		_, err = client.SaveDoc(result.ID, "doc", []byte(`{"message":"Hello World!"}`))
+		require.NoError(t, err)
+
+		require.NotZero(t, atomic.LoadInt32(defaultRequests))
+		require.Zero(t, atomic.LoadInt32(otherRequests))
+	})
+
+	t.Run("Error if the KMS URL is not allowed", func(t *testing.T) {
+		defaultKMS, _ := newKMS(t)
+		otherKMS, otherRequests := newKMS(t)
+
+		client := newClient(t, defaultKMS.URL)
+
+		_, err := client.CreateVault(vault.WithVaultKMSURL(otherKMS.URL))
+		require.True(t, errors.Is(err, vault.ErrKMSURLNotAllowed))
+
+		_, err = client.CreateVaultWithController("did:example:controller", "#key", nil,
+			vault.WithVaultKMSURL(otherKMS.URL))
+		require.True(t, errors.Is(err, vault.ErrKMSURLNotAllowed))
+
+		require.Zero(t, atomic.LoadInt32(otherRequests))
+	})
+}
@@ -0,0 +1,376 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+)
+
+const (
+	listIndexFormat     = "list_index_%s_%s"
+	listIndexPageFormat = "list_index_page_%s_%s_%s"
+
+	// listIndexPageSize is the number of IDs above which a page of a list index is split in two.
+	listIndexPageSize = 256
+
+	// listIndexVersion is the version of the vaultInfo schema from which the records of the vault are indexed.
+	listIndexVersion = 3
+)
+
+// listIndex keeps the IDs of the records of a vault, eg. its documents, sorted in pages, so that the records are
+// listed a page at a time from a cursor: the store can neither sort nor skip the results of its queries. Listing
+// a page reads the head of the index, the pages holding the IDs listed and their records, whatever the number of
+// records of the vault.
+type listIndex struct {
+	name string
+	// recordFormat is the format of the keys of the records, from the vault ID and the record ID.
+	recordFormat string
+}
+
+var (
+	docsIndex           = &listIndex{name: "docs", recordFormat: metaDocInfoFormat}
+	authorizationsIndex = &listIndex{name: "authorizations", recordFormat: authorizationFormat}
+)
+
+// listIndexHead lists the pages of an index ordered by their first IDs.
+type listIndexHead struct {
+	Pages []*listIndexPageRef `json:"pages"`
+}
+
+type listIndexPageRef struct {
+	ID    string `json:"id"`
+	First string `json:"first"`
+}
+
+type listIndexPage struct {
+	IDs []string `json:"ids"`
+}
+
+// pageOf returns the position of the page holding the ID, or where it belongs: the first page also holds the IDs
+// below its first ID.
+func (h *listIndexHead) pageOf(id string) int {
+	i := sort.Search(len(h.Pages), func(i int) bool { return h.Pages[i].First > id }) - 1
+	if i < 0 {
+		return 0
+	}
+
+	return i
+}
+
+func (c *Client) indexAdd(idx *listIndex, vaultID, id string) error {
+	unlock := c.indexMu.lock(fmt.Sprintf(listIndexFormat, idx.name, vaultID))
+	defer unlock()
+
+	head, err := c.listIndexHead(idx, vaultID)
+	if err != nil {
+		return err
+	}
+
+	if len(head.Pages) == 0 {
+		head.Pages = []*listIndexPageRef{{ID: uuid.New().String(), First: id}}
+	}
+
+	i := head.pageOf(id)
+	ref := head.Pages[i]
+
+	page, err := c.listIndexPage(idx, vaultID, ref.ID)
+	if err != nil {
+		return err
+	}
+
+	j := sort.SearchStrings(page.IDs, id)
+	if j < len(page.IDs) && page.IDs[j] == id {
+		return nil
+	}
+
+	page.IDs = append(page.IDs[:j], append([]string{id}, page.IDs[j:]...)...)
+	ref.First = page.IDs[0]
+
+	pages := map[string]*listIndexPage{ref.ID: page}
+
+	if len(page.IDs) > listIndexPageSize {
+		upper := &listIndexPage{IDs: append([]string(nil), page.IDs[len(page.IDs)/2:]...)}
+		page.IDs = page.IDs[:len(page.IDs)/2]
+
+		upperRef := &listIndexPageRef{ID: uuid.New().String(), First: upper.IDs[0]}
+		head.Pages = append(head.Pages[:i+1], append([]*listIndexPageRef{upperRef}, head.Pages[i+1:]...)...)
+		pages[upperRef.ID] = upper
+	}
+
+	return c.saveListIndex(idx, vaultID, head, pages)
+}
+
+func (c *Client) indexRemove(idx *listIndex, vaultID, id string) error {
+	unlock := c.indexMu.lock(fmt.Sprintf(listIndexFormat, idx.name, vaultID))
+	defer unlock()
+
+	head, err := c.listIndexHead(idx, vaultID)
+	if err != nil || len(head.Pages) == 0 {
+		return err
+	}
+
+	i := head.pageOf(id)
+	ref := head.Pages[i]
+
+	page, err := c.listIndexPage(idx, vaultID, ref.ID)
+	if err != nil {
+		return err
+	}
+
+	j := sort.SearchStrings(page.IDs, id)
+	if j == len(page.IDs) || page.IDs[j] != id {
+		return nil
+	}
+
+	page.IDs = append(page.IDs[:j], page.IDs[j+1:]...)
+
+	// a nil page is deleted
+	pages := map[string]*listIndexPage{ref.ID: page}
+
+	switch {
+	case len(page.IDs) > 0:
+		ref.First = page.IDs[0]
+	case len(head.Pages) > 1:
+		head.Pages = append(head.Pages[:i], head.Pages[i+1:]...)
+		pages[ref.ID] = nil
+	}
+
+	return c.saveListIndex(idx, vaultID, head, pages)
+}
+
+// indexRebuild adds the IDs to the index at once, eg. the IDs of the records of a vault saved before it was indexed.
+func (c *Client) indexRebuild(idx *listIndex, vaultID string, ids []string) error {
+	unlock := c.indexMu.lock(fmt.Sprintf(listIndexFormat, idx.name, vaultID))
+	defer unlock()
+
+	head, err := c.listIndexHead(idx, vaultID)
+	if err != nil {
+		return err
+	}
+
+	all := make(map[string]struct{}, len(ids))
+
+	for _, id := range ids {
+		all[id] = struct{}{}
+	}
+
+	// a nil page is deleted
+	pages := make(map[string]*listIndexPage)
+
+	for _, ref := range head.Pages {
+		page, err := c.listIndexPage(idx, vaultID, ref.ID)
+		if err != nil {
+			return err
+		}
+
+		for _, id := range page.IDs {
+			all[id] = struct{}{}
+		}
+
+		pages[ref.ID] = nil
+	}
+
+	sorted := make([]string, 0, len(all))
+
+	for id := range all {
+		sorted = append(sorted, id)
+	}
+
+	sort.Strings(sorted)
+
+	head.Pages = nil
+
+	for start := 0; start < len(sorted); start += listIndexPageSize / 2 {
+		end := start + listIndexPageSize/2
+		if end > len(sorted) {
+			end = len(sorted)
+		}
+
+		ref := &listIndexPageRef{ID: uuid.New().String(), First: sorted[start]}
+		head.Pages = append(head.Pages, ref)
+		pages[ref.ID] = &listIndexPage{IDs: sorted[start:end]}
+	}
+
+	return c.saveListIndex(idx, vaultID, head, pages)
+}
+
+// indexVaultRecords adds the documents and authorizations of the vault saved before they were indexed to their
+// indexes.
+func (c *Client) indexVaultRecords(vaultID string) error {
+	for _, source := range []struct {
+		idx *listIndex
+		tag string
+	}{
+		{idx: docsIndex, tag: vaultDocsTag},
+		{idx: authorizationsIndex, tag: vaultAuthorizationsTag},
+	} {
+		keys, err := c.queryVaultKeys(source.tag, vaultID)
+		if err != nil {
+			return fmt.Errorf("query %s: %w", source.tag, err)
+		}
+
+		prefix := fmt.Sprintf(source.idx.recordFormat, vaultID, "")
+		ids := make([]string, 0, len(keys))
+
+		for _, key := range keys {
+			ids = append(ids, strings.TrimPrefix(key, prefix))
+		}
+
+		err = c.indexRebuild(source.idx, vaultID, ids)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// indexRecords calls visit with the IDs above after, in order, and their records until it returns true. IDs whose
+// records no longer exist are skipped.
+func (c *Client) indexRecords(idx *listIndex, vaultID, after string,
+	visit func(id string, src []byte) (bool, error)) error {
+	unlock := c.indexMu.lock(fmt.Sprintf(listIndexFormat, idx.name, vaultID))
+	defer unlock()
+
+	head, err := c.listIndexHead(idx, vaultID)
+	if err != nil {
+		return err
+	}
+
+	for i := head.pageOf(after); i < len(head.Pages); i++ {
+		page, err := c.listIndexPage(idx, vaultID, head.Pages[i].ID)
+		if err != nil {
+			return err
+		}
+
+		ids := page.IDs[sort.SearchStrings(page.IDs, after):]
+		if len(ids) > 0 && ids[0] == after {
+			ids = ids[1:]
+		}
+
+		for _, id := range ids {
+			src, err := c.store.Get(fmt.Sprintf(idx.recordFormat, vaultID, id))
+			if errors.Is(err, storage.ErrDataNotFound) {
+				continue
+			}
+
+			if err != nil {
+				return fmt.Errorf("get: %w", err)
+			}
+
+			done, err := visit(id, src)
+			if err != nil || done {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// deleteListIndex deletes the index of the records of a deleted vault.
+func (c *Client) deleteListIndex(idx *listIndex, vaultID string) error {
+	unlock := c.indexMu.lock(fmt.Sprintf(listIndexFormat, idx.name, vaultID))
+	defer unlock()
+
+	head, err := c.listIndexHead(idx, vaultID)
+	if err != nil {
+		return err
+	}
+
+	ops := []storage.Operation{{Key: fmt.Sprintf(listIndexFormat, idx.name, vaultID)}}
+
+	for _, ref := range head.Pages {
+		ops = append(ops, storage.Operation{Key: fmt.Sprintf(listIndexPageFormat, idx.name, vaultID, ref.ID)})
+	}
+
+	err = c.store.Batch(ops)
+	if err != nil {
+		return fmt.Errorf("batch: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Client) listIndexHead(idx *listIndex, vaultID string) (*listIndexHead, error) {
+	head := &listIndexHead{}
+
+	err := c.getListIndexRecord(fmt.Sprintf(listIndexFormat, idx.name, vaultID), head)
+	if err != nil {
+		return nil, fmt.Errorf("get %s index: %w", idx.name, err)
+	}
+
+	return head, nil
+}
+
+func (c *Client) listIndexPage(idx *listIndex, vaultID, pageID string) (*listIndexPage, error) {
+	page := &listIndexPage{}
+
+	err := c.getListIndexRecord(fmt.Sprintf(listIndexPageFormat, idx.name, vaultID, pageID), page)
+	if err != nil {
+		return nil, fmt.Errorf("get %s index page: %w", idx.name, err)
+	}
+
+	return page, nil
+}
+
+// getListIndexRecord leaves v as is if the record does not exist.
+func (c *Client) getListIndexRecord(key string, v interface{}) error {
+	src, err := c.store.Get(key)
+	if errors.Is(err, storage.ErrDataNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("get: %w", err)
+	}
+
+	err = json.Unmarshal(src, v)
+	if err != nil {
+		return fmt.Errorf("unmarshal: %w", err)
+	}
+
+	return nil
+}
+
+// saveListIndex saves the head and the pages of the index at once. Nil pages are deleted.
+func (c *Client) saveListIndex(idx *listIndex, vaultID string, head *listIndexHead,
+	pages map[string]*listIndexPage) error {
+	src, err := json.Marshal(head)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	ops := []storage.Operation{{Key: fmt.Sprintf(listIndexFormat, idx.name, vaultID), Value: src}}
+
+	for id, page := range pages {
+		op := storage.Operation{Key: fmt.Sprintf(listIndexPageFormat, idx.name, vaultID, id)}
+
+		if page != nil {
+			op.Value, err = json.Marshal(page)
+			if err != nil {
+				return fmt.Errorf("marshal: %w", err)
+			}
+		}
+
+		ops = append(ops, op)
+	}
+
+	err = c.store.Batch(ops)
+	if err != nil {
+		return fmt.Errorf("batch: %w", err)
+	}
+
+	return nil
+}
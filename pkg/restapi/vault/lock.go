@@ -0,0 +1,64 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault
+
+import (
+	"fmt"
+	"sync"
+)
+
+// keyedMutex serializes the read-modify-write cycles on the same record, eg. the metadata of a document: the store
+// has no conditional writes. The zero value is ready to use.
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyedLock
+}
+
+type keyedLock struct {
+	sync.Mutex
+	// refs counts the holders and waiters of the lock, which is dropped once there are none.
+	refs int
+}
+
+// lock locks the key and returns the function unlocking it.
+func (m *keyedMutex) lock(key string) func() {
+	m.mu.Lock()
+
+	if m.locks == nil {
+		m.locks = make(map[string]*keyedLock)
+	}
+
+	l, ok := m.locks[key]
+	if !ok {
+		l = &keyedLock{}
+		m.locks[key] = l
+	}
+
+	l.refs++
+
+	m.mu.Unlock()
+
+	l.Lock()
+
+	return func() {
+		l.Unlock()
+
+		m.mu.Lock()
+
+		l.refs--
+		if l.refs == 0 {
+			delete(m.locks, key)
+		}
+
+		m.mu.Unlock()
+	}
+}
+
+// docLockKey returns the key of the lock of the document, the key of its metadata.
+func docLockKey(vaultID, docID string) string {
+	return fmt.Sprintf(metaDocInfoFormat, vaultID, docID)
+}
@@ -0,0 +1,136 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultDocMetadataCacheSize is the number of documents whose metadata is cached unless configured otherwise.
+const DefaultDocMetadataCacheSize = 1000
+
+// WithDocMetadataCache makes GetDocMetadata cache the metadata of at most size documents for ttl, defaulting to
+// DefaultDocMetadataCacheSize if size is not positive. Saving, re-encrypting or deleting a document drops its cached
+// metadata. The cache is disabled if ttl is not positive, as it is by default.
+func WithDocMetadataCache(ttl time.Duration, size int) Opt {
+	return func(vault *Client) {
+		vault.docMetaCache = newDocMetaCache(ttl, size)
+	}
+}
+
+type docMetaKey struct {
+	vaultID string
+	docID   string
+}
+
+type docMetaEntry struct {
+	meta    DocumentMetadata
+	expires time.Time
+}
+
+// docMetaCache caches the metadata of documents for a short time. A nil cache caches nothing.
+type docMetaCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	size    int
+	entries map[docMetaKey]*docMetaEntry
+}
+
+func newDocMetaCache(ttl time.Duration, size int) *docMetaCache {
+	if ttl <= 0 {
+		return nil
+	}
+
+	if size <= 0 {
+		size = DefaultDocMetadataCacheSize
+	}
+
+	return &docMetaCache{ttl: ttl, size: size, entries: make(map[docMetaKey]*docMetaEntry)}
+}
+
+// get returns a copy of the cached metadata of the document, nil if it is not cached or expired.
+func (c *docMetaCache) get(vaultID, docID string) *DocumentMetadata {
+	if c == nil {
+		return nil
+	}
+
+	key := docMetaKey{vaultID: vaultID, docID: docID}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+
+	if !time.Now().Before(entry.expires) {
+		delete(c.entries, key)
+
+		return nil
+	}
+
+	meta := entry.meta
+
+	return &meta
+}
+
+func (c *docMetaCache) put(vaultID, docID string, meta *DocumentMetadata) {
+	if c == nil {
+		return
+	}
+
+	key := docMetaKey{vaultID: vaultID, docID: docID}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.size {
+		c.evict(now)
+	}
+
+	c.entries[key] = &docMetaEntry{meta: *meta, expires: now.Add(c.ttl)}
+}
+
+// evict drops the expired entries, or the one expiring first if none is.
+func (c *docMetaCache) evict(now time.Time) {
+	var (
+		first *docMetaKey
+		at    time.Time
+	)
+
+	for key, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, key)
+
+			continue
+		}
+
+		if first == nil || entry.expires.Before(at) {
+			k := key
+			first, at = &k, entry.expires
+		}
+	}
+
+	if len(c.entries) >= c.size && first != nil {
+		delete(c.entries, *first)
+	}
+}
+
+func (c *docMetaCache) invalidate(vaultID, docID string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, docMetaKey{vaultID: vaultID, docID: docID})
+}
@@ -0,0 +1,176 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/ace/pkg/internal/testutil"
+	"github.com/trustbloc/ace/pkg/restapi/vault"
+)
+
+func TestClient_DocMetadataCache(t *testing.T) {
+	loader := testutil.DocumentLoader(t)
+
+	newClient := func(t *testing.T, opts ...vault.Opt) (*vault.Client, string, *int32) {
+		t.Helper()
+
+		remoteKMS := httptest.NewServer(newKMSHandler(t))
+		t.Cleanup(remoteKMS.Close)
+
+		edvHandler, _ := newDeletingEDVHandler(t)
+
+		edv := httptest.NewServer(edvHandler)
+		t.Cleanup(edv.Close)
+
+		provider := &metaCountingProvider{Provider: mem.NewProvider()}
+
+		client, err := vault.NewClient(remoteKMS.URL, edv.URL, newLocalKms(t, provider), provider, loader, opts...)
+		require.NoError(t, err)
+
+		created, err := client.CreateVault()
+		require.NoError(t, err)
+
+		_, err = client.SaveDoc(created.ID, "doc", []byte(`{"message":"Hello World!"}`))
+		require.NoError(t, err)
+
+		atomic.StoreInt32(&provider.metaGets, 0)
+
+		return client, created.ID, &provider.metaGets
+	}
+
+	t.Run("Repeated reads within the TTL consult the store once", func(t *testing.T) {
+		client, vaultID, gets := newClient(t, vault.WithDocMetadataCache(time.Minute, 0))
+
+		first, err := client.GetDocMetadata(vaultID, "doc")
+		require.NoError(t, err)
+
+		for i := 0; i < 5; i++ {
+			meta, err := client.GetDocMetadata(vaultID, "doc")
+			require.NoError(t, err)
+			require.Equal(t, first, meta)
+		}
+
+		require.Equal(t, int32(1), atomic.LoadInt32(gets))
+	})
+
+	t.Run("Concurrent reads", func(t *testing.T) {
+		client, vaultID, gets := newClient(t, vault.WithDocMetadataCache(time.Minute, 0))
+
+		_, err := client.GetDocMetadata(vaultID, "doc")
+		require.NoError(t, err)
+
+		var wg sync.WaitGroup
+
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				meta, err := client.GetDocMetadata(vaultID, "doc")
+				require.NoError(t, err)
+				require.Equal(t, "doc", meta.ID)
+			}()
+		}
+
+		wg.Wait()
+
+		require.Equal(t, int32(1), atomic.LoadInt32(gets))
+	})
+
+	t.Run("Saving the document invalidates its metadata", func(t *testing.T) {
+		client, vaultID, gets := newClient(t, vault.WithDocMetadataCache(time.Minute, 0))
+
+		meta, err := client.GetDocMetadata(vaultID, "doc")
+		require.NoError(t, err)
+		require.Zero(t, meta.Sequence)
+
+		_, err = client.SaveDoc(vaultID, "doc", []byte(`{"message":"Updated"}`))
+		require.NoError(t, err)
+
+		saved := atomic.LoadInt32(gets)
+
+		meta, err = client.GetDocMetadata(vaultID, "doc")
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), meta.Sequence)
+		require.Equal(t, saved+1, atomic.LoadInt32(gets))
+	})
+
+	t.Run("Deleting the document invalidates its metadata", func(t *testing.T) {
+		client, vaultID, _ := newClient(t, vault.WithDocMetadataCache(time.Minute, 0))
+
+		_, err := client.GetDocMetadata(vaultID, "doc")
+		require.NoError(t, err)
+
+		require.NoError(t, client.DeleteDoc(vaultID, "doc"))
+
+		_, err = client.GetDocMetadata(vaultID, "doc")
+		require.Error(t, err)
+	})
+
+	t.Run("Metadata expires after the TTL", func(t *testing.T) {
+		client, vaultID, gets := newClient(t, vault.WithDocMetadataCache(50*time.Millisecond, 0))
+
+		_, err := client.GetDocMetadata(vaultID, "doc")
+		require.NoError(t, err)
+
+		time.Sleep(100 * time.Millisecond)
+
+		_, err = client.GetDocMetadata(vaultID, "doc")
+		require.NoError(t, err)
+		require.Equal(t, int32(2), atomic.LoadInt32(gets))
+	})
+
+	t.Run("Metadata is not cached by default", func(t *testing.T) {
+		client, vaultID, gets := newClient(t)
+
+		for i := 0; i < 3; i++ {
+			_, err := client.GetDocMetadata(vaultID, "doc")
+			require.NoError(t, err)
+		}
+
+		require.Equal(t, int32(3), atomic.LoadInt32(gets))
+	})
+}
+
+// metaCountingProvider counts the reads of document metadata from its stores.
+type metaCountingProvider struct {
+	storage.Provider
+	metaGets int32
+}
+
+func (p *metaCountingProvider) OpenStore(name string) (storage.Store, error) { //nolint:ireturn
+	store, err := p.Provider.OpenStore(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &metaCountingStore{Store: store, gets: &p.metaGets}, nil
+}
+
+type metaCountingStore struct {
+	storage.Store
+	gets *int32
+}
+
+func (s *metaCountingStore) Get(key string) ([]byte, error) {
+	if strings.HasPrefix(key, "meta_doc_info_") {
+		atomic.AddInt32(s.gets, 1)
+	}
+
+	return s.Store.Get(key)
+}
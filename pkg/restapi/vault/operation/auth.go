@@ -0,0 +1,177 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+
+	"github.com/trustbloc/ace/pkg/restapi/vault"
+)
+
+// InvocationVerifier checks that requests are authorized to act on a vault.
+type InvocationVerifier interface {
+	// VerifyInvocation returns an error wrapping vault.ErrUnauthorizedInvocation if the request is not authorized.
+	VerifyInvocation(vaultID string, req *http.Request) error
+	// VerifyDocURIInvocation returns an error wrapping vault.ErrUnauthorizedInvocation if the request is not
+	// authorized to act on the vault of the document at the URI.
+	VerifyDocURIInvocation(uri string, req *http.Request) error
+	// VerifyAuthorizationInvocation returns an error wrapping vault.ErrUnauthorizedInvocation if the request is not
+	// made by the requesting party of the authorization.
+	VerifyAuthorizationInvocation(vaultID, authID string, req *http.Request) error
+	// VerifyControllerInvocation returns an error wrapping vault.ErrUnauthorizedInvocation if the request is not
+	// authorized to act on all the vaults of the controller.
+	VerifyControllerInvocation(controller string, req *http.Request) error
+}
+
+// WithInvocationVerifier requires the requests acting on a vault, its documents or its authorizations, or listing
+// the vaults of a controller, to be authorized by the verifier. Importing vaults then requires the admin token, and
+// creating vaults an HTTP signature by their controller or owner. Authorizations are used by their requesting party,
+// with their own capability. Challenges are issued to anyone: they are issued before the authorization exists.
+// Without a verifier, these endpoints are open to anyone who can reach the server, but listing the vaults of a
+// controller requires the admin token.
+func WithInvocationVerifier(verifier InvocationVerifier) Option {
+	return func(o *Operation) {
+		o.invocationVerifier = verifier
+	}
+}
+
+// authorized returns the handler of an endpoint acting on the vault in the path, checking the invocation first.
+func (o *Operation) authorized(handle http.HandlerFunc) http.HandlerFunc {
+	if o.invocationVerifier == nil {
+		return handle
+	}
+
+	return func(rw http.ResponseWriter, req *http.Request) {
+		vaultID := mux.Vars(req)["vaultID"]
+
+		if err := o.invocationVerifier.VerifyInvocation(vaultID, req); err != nil {
+			o.writeErrorResponse(rw, err, invocationErrorStatus(err))
+
+			return
+		}
+
+		handle(rw, req)
+	}
+}
+
+// authorizationAuthorized returns the handler of an endpoint used by the requesting party of the authorization in
+// the path, checking the invocation of the authorization's capability first.
+func (o *Operation) authorizationAuthorized(handle http.HandlerFunc) http.HandlerFunc {
+	if o.invocationVerifier == nil {
+		return handle
+	}
+
+	return func(rw http.ResponseWriter, req *http.Request) {
+		vars := mux.Vars(req)
+
+		err := o.invocationVerifier.VerifyAuthorizationInvocation(vars["vaultID"], vars["authID"], req)
+		if err != nil {
+			o.writeErrorResponse(rw, err, invocationErrorStatus(err))
+
+			return
+		}
+
+		handle(rw, req)
+	}
+}
+
+// docURIAuthorized returns the handler of an endpoint acting on the document at the URI in the query, checking the
+// invocation on its vault first.
+func (o *Operation) docURIAuthorized(handle http.HandlerFunc) http.HandlerFunc {
+	if o.invocationVerifier == nil {
+		return handle
+	}
+
+	return func(rw http.ResponseWriter, req *http.Request) {
+		uri := req.URL.Query().Get("uri")
+
+		// requests without a URI are rejected by the handler
+		if uri != "" {
+			err := o.invocationVerifier.VerifyDocURIInvocation(uri, req)
+
+			switch {
+			case errors.Is(err, vault.ErrInvalidDocURI):
+				o.writeErrorResponse(rw, err, http.StatusBadRequest)
+
+				return
+			case errors.Is(err, vault.ErrDocumentNotFound):
+				o.writeErrorResponse(rw, err, http.StatusNotFound)
+
+				return
+			case err != nil:
+				o.writeErrorResponse(rw, err, invocationErrorStatus(err))
+
+				return
+			}
+		}
+
+		handle(rw, req)
+	}
+}
+
+// adminAuthorized returns the handler of an endpoint acting on no existing vault, requiring the admin token once
+// invocations are verified.
+func (o *Operation) adminAuthorized(handle http.HandlerFunc) http.HandlerFunc {
+	if o.invocationVerifier == nil {
+		return handle
+	}
+
+	return func(rw http.ResponseWriter, req *http.Request) {
+		if !o.isAdmin(req) {
+			o.writeErrorResponse(rw, fmt.Errorf("%w: the admin token is required", vault.ErrUnauthorizedInvocation),
+				http.StatusUnauthorized)
+
+			return
+		}
+
+		handle(rw, req)
+	}
+}
+
+// controllerAuthorized returns the handler of an endpoint acting on the vaults of the controller in the query,
+// checking the invocation first unless the request carries the admin token. Without a verifier, nothing proves the
+// request comes from the controller: the admin token is required.
+func (o *Operation) controllerAuthorized(handle http.HandlerFunc) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		controller := req.URL.Query().Get("controller")
+
+		// requests without a controller are rejected by the handler
+		if controller != "" && !o.isAdmin(req) {
+			if o.invocationVerifier == nil {
+				o.writeErrorResponse(rw, fmt.Errorf("%w: the admin token is required", vault.ErrUnauthorizedInvocation),
+					http.StatusUnauthorized)
+
+				return
+			}
+
+			if err := o.invocationVerifier.VerifyControllerInvocation(controller, req); err != nil {
+				o.writeErrorResponse(rw, err, invocationErrorStatus(err))
+
+				return
+			}
+		}
+
+		handle(rw, req)
+	}
+}
+
+// invocationErrorStatus maps unauthorized invocations to 401 and unknown vaults to 404.
+func invocationErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, vault.ErrUnauthorizedInvocation):
+		return http.StatusUnauthorized
+	case errors.Is(err, storage.ErrDataNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
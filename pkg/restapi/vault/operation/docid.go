@@ -0,0 +1,116 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/trustbloc/edv/pkg/edvutils"
+)
+
+// DocIDStrategy selects how the IDs of saved documents are generated and which IDs callers may supply.
+type DocIDStrategy string
+
+// Document ID strategies.
+const (
+	// DocIDEDVCompatible generates base58-encoded 128-bit IDs. Caller-supplied IDs must have the same format.
+	DocIDEDVCompatible DocIDStrategy = "edv-compatible"
+	// DocIDUUID generates version 4 UUIDs. Caller-supplied IDs must be version 4 UUIDs as well.
+	DocIDUUID DocIDStrategy = "uuid"
+	// DocIDCallerSupplied never generates IDs: SaveDoc requests without an ID are rejected.
+	DocIDCallerSupplied DocIDStrategy = "caller-supplied-required"
+)
+
+// errInvalidDocID is returned when the ID supplied by the caller does not match the document ID strategy, or when
+// the DocIDCallerSupplied strategy is selected and a request has no ID.
+var errInvalidDocID = errors.New("invalid document ID")
+
+// ParseDocIDStrategy returns the named document ID strategy.
+func ParseDocIDStrategy(name string) (DocIDStrategy, error) {
+	switch s := DocIDStrategy(name); s {
+	case DocIDEDVCompatible, DocIDUUID, DocIDCallerSupplied:
+		return s, nil
+	default:
+		return "", fmt.Errorf("unsupported document ID strategy %q: must be one of %s, %s or %s",
+			name, DocIDEDVCompatible, DocIDUUID, DocIDCallerSupplied)
+	}
+}
+
+// WithDocIDStrategy sets how document IDs are generated and validates the IDs supplied by callers against it.
+// Without it, EDV-compatible IDs are generated and callers may supply IDs of any format.
+func WithDocIDStrategy(strategy DocIDStrategy) Option {
+	return func(o *Operation) {
+		switch strategy {
+		case DocIDEDVCompatible:
+			o.GenerateID = edvutils.GenerateEDVCompatibleID
+			o.validateID = validateEDVCompatibleID
+		case DocIDUUID:
+			o.GenerateID = generateUUID
+			o.validateID = validateUUID
+		case DocIDCallerSupplied:
+			o.GenerateID = func() (string, error) {
+				return "", fmt.Errorf("%w: an ID is required", errInvalidDocID)
+			}
+			o.validateID = validatePathSegment
+		}
+	}
+}
+
+// docID returns the ID supplied by the caller, if valid, or a generated one.
+func (o *Operation) docID(supplied string) (string, error) {
+	if supplied == "" {
+		return o.GenerateID()
+	}
+
+	if o.validateID == nil {
+		return supplied, nil
+	}
+
+	if err := o.validateID(supplied); err != nil {
+		return "", fmt.Errorf("%w %s: %v", errInvalidDocID, supplied, err) // nolint:errorlint
+	}
+
+	return supplied, nil
+}
+
+func generateUUID() (string, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return "", fmt.Errorf("generate uuid: %w", err)
+	}
+
+	return id.String(), nil
+}
+
+func validateEDVCompatibleID(id string) error {
+	return edvutils.CheckIfBase58Encoded128BitValue(id)
+}
+
+func validateUUID(id string) error {
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return fmt.Errorf("must be a UUID: %w", err)
+	}
+
+	if parsed.Version() != 4 || parsed.String() != strings.ToLower(id) { // nolint:gomnd
+		return errors.New("must be a version 4 UUID in its canonical form")
+	}
+
+	return nil
+}
+
+// validatePathSegment rejects IDs the documents could not be read back with, since they are part of the URL path.
+func validatePathSegment(id string) error {
+	if strings.TrimSpace(id) == "" || strings.Contains(id, "/") {
+		return errors.New("must not be blank or contain /")
+	}
+
+	return nil
+}
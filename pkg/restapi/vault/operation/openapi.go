@@ -24,7 +24,29 @@ type genericError struct { // nolint: unused,deadcode
 // createVaultReq model
 //
 // swagger:parameters createVaultReq
-type createVaultReq struct{} // nolint: unused,deadcode
+type createVaultReq struct { // nolint: unused,deadcode
+	// in: body
+	Request CreateVaultBody
+}
+
+// CreateVaultBody describes the optional body of the createVault request. If set, the vault is controlled by the
+// controller DID instead of a new did:key.
+type CreateVaultBody struct {
+	Controller string `json:"controller"`
+	// VerificationMethod is the DID URL, or fragment, of a verification method of the controller.
+	VerificationMethod string `json:"verificationMethod"`
+	// EDVBackend pins the EDV backend the vault is created in. Requires the admin token.
+	EDVBackend string `json:"edvBackend,omitempty"`
+	// KeyType is the type of the key-wrapping keys the documents of the vault are encrypted to, eg. X25519ECDHKW,
+	// instead of the one configured for the server.
+	KeyType string `json:"keyType,omitempty"`
+	// ReferenceID makes retries of the request idempotent: if the controller or owner already created a vault with
+	// it, the existing vault is returned with 200, with its current authorization.
+	ReferenceID string `json:"referenceId,omitempty"`
+	// KMSURL is the base URL of the WebKMS the keys of the vault are created in, instead of the one configured for
+	// the server. It must be one of the KMS URLs the server allows.
+	KMSURL string `json:"kmsURL,omitempty"`
+}
 
 // createVaultResp model
 //
@@ -34,12 +56,61 @@ type createVaultResp struct {
 	Body *vault.CreatedVault
 }
 
+// getVaultReq model
+//
+// swagger:parameters getVaultReq
+type getVaultReq struct { // nolint: unused,deadcode
+	// in: path
+	VaultID string `json:"vaultID"`
+}
+
+// getVaultResp model
+//
+// swagger:response getVaultResp
+type getVaultResp struct {
+	// in: body
+	Body *vault.VaultInfo
+}
+
+// listVaultsReq model
+//
+// swagger:parameters listVaultsReq
+type listVaultsReq struct { // nolint: unused,deadcode
+	// The DID controlling the vaults.
+	// in: query
+	// required: true
+	Controller string `json:"controller"`
+	// The maximum number of vaults to return. Defaults to 100, at most 1000.
+	// in: query
+	Limit int `json:"limit"`
+	// The continuation token returned with the previous page.
+	// in: query
+	Next string `json:"next"`
+}
+
+// listVaultsResp model
+//
+// swagger:response listVaultsResp
+type listVaultsResp struct {
+	// in: body
+	Body *vault.VaultList
+}
+
 // saveDocReq model
 //
 // swagger:parameters saveDocReq
 type saveDocReq struct {
 	// in: path
 	VaultID string `json:"vaultID"`
+	// The document's ID if the content is not JSON.
+	// in: query
+	DocID string `json:"id"`
+	// The expected sequence of the document. The document is saved only if it exists with this sequence.
+	// in: header
+	IfMatch string `json:"If-Match"`
+	// Also encrypt the document to the key agreement key of the vault's controller.
+	// in: query
+	ControllerRecipient bool `json:"controllerRecipient"`
 	// in: body
 	// required: true
 	Request SaveDocRequestBody
@@ -50,6 +121,8 @@ type SaveDocRequestBody struct {
 	ID      string          `json:"id"`
 	Content json.RawMessage `json:"content"`
 	Tags    []string        `json:"tags"`
+	// IndexTags are names and values the document can be looked up by. They are stored encrypted.
+	IndexTags map[string]string `json:"indexTags,omitempty"`
 }
 
 // saveDocResp model
@@ -60,6 +133,80 @@ type saveDocResp struct {
 	Body *vault.DocumentMetadata
 }
 
+// listDocsReq model
+//
+// swagger:parameters listDocsReq
+type listDocsReq struct { // nolint: unused,deadcode
+	// in: path
+	VaultID string `json:"vaultID"`
+	// The maximum number of documents to return. Defaults to 100, at most 1000.
+	// in: query
+	Limit int `json:"limit"`
+	// The continuation token returned with the previous page.
+	// in: query
+	Next string `json:"next"`
+	// Only lists the documents with the index tag, given as name:value. Limit and next are then ignored.
+	// in: query
+	Tag string `json:"tag"`
+}
+
+// listDocsResp model
+//
+// swagger:response listDocsResp
+type listDocsResp struct {
+	// in: body
+	Body *vault.DocumentList
+}
+
+// getDocReq model
+//
+// swagger:parameters getDocReq
+type getDocReq struct { // nolint: unused,deadcode
+	// in: path
+	VaultID string `json:"vaultID"`
+	// in: path
+	DocID string `json:"docID"`
+}
+
+// getDocResp model
+//
+// swagger:response getDocResp
+type getDocResp struct {
+	// in: body
+	Body json.RawMessage
+}
+
+// deleteDocReq model
+//
+// swagger:parameters deleteDocReq
+type deleteDocReq struct { // nolint: unused,deadcode
+	// in: path
+	VaultID string `json:"vaultID"`
+	// in: path
+	DocID string `json:"docID"`
+}
+
+// deleteDocResp model
+//
+// swagger:response deleteDocResp
+type deleteDocResp struct{} // nolint: unused,deadcode
+
+// SequenceConflict is the response to saving a document whose sequence is not the expected one.
+type SequenceConflict struct {
+	Message string          `json:"errMessage"`
+	Code    model.ErrorCode `json:"code"`
+	// The current sequence of the document.
+	Sequence uint64 `json:"sequence"`
+}
+
+// sequenceConflictResp model
+//
+// swagger:response sequenceConflictResp
+type sequenceConflictResp struct { // nolint: unused,deadcode
+	// in: body
+	Body SequenceConflict
+}
+
 // getDocMetadataReq model
 //
 // swagger:parameters getDocMetadataReq
@@ -70,6 +217,43 @@ type getDocMetadataReq struct { // nolint: unused,deadcode
 	DocID string `json:"docID"`
 }
 
+// patchDocReq model
+//
+// swagger:parameters patchDocReq
+type patchDocReq struct { // nolint: unused,deadcode
+	// in: path
+	VaultID string `json:"vaultID"`
+	// in: path
+	DocID string `json:"docID"`
+	// The expected sequence of the document. The patch is applied only if the document has this sequence.
+	// in: header
+	IfMatch string `json:"If-Match"`
+	// The JSON merge patch, a JSON object, or the JSON patch, an array of operations, if the content type is
+	// application/json-patch+json.
+	// in: body
+	// required: true
+	Patch interface{}
+}
+
+// patchDocResp model
+//
+// swagger:response patchDocResp
+type patchDocResp struct { // nolint: unused,deadcode
+	// in: body
+	Body *vault.DocumentMetadata
+}
+
+// getDocMetadataByURIReq model
+//
+// swagger:parameters getDocMetadataByURIReq
+type getDocMetadataByURIReq struct { // nolint: unused,deadcode
+	// The URI of the EDV document, the edvDocURI of the metadata.
+	//
+	// in: query
+	// required: true
+	URI string `json:"uri"`
+}
+
 // getDocMetadataResp model
 //
 // swagger:response getDocMetadataResp
@@ -93,6 +277,24 @@ type createAuthorizationsReq struct {
 type CreateAuthorizationsBody struct {
 	Scope           vault.AuthorizationsScope `json:"scope"`
 	RequestingParty string                    `json:"requestingParty"`
+	// Proof proves that the requesting party controls a key of its DID. It is required if the server enforces it.
+	Proof *vault.KeyProof `json:"proof,omitempty"`
+}
+
+// createChallengeReq model
+//
+// swagger:parameters createChallengeReq
+type createChallengeReq struct { // nolint: unused,deadcode
+	// in: path
+	VaultID string `json:"vaultID"`
+}
+
+// createChallengeResp model
+//
+// swagger:response createChallengeResp
+type createChallengeResp struct {
+	// in: body
+	Body *vault.AuthorizationChallenge
 }
 
 // createAuthorizationResp model
@@ -103,6 +305,123 @@ type createAuthorizationResp struct {
 	Body *vault.CreatedAuthorization
 }
 
+// createWebhookReq model
+//
+// swagger:parameters createWebhookReq
+type createWebhookReq struct { // nolint: unused,deadcode
+	// in: path
+	VaultID string `json:"vaultID"`
+	// in: body
+	// required: true
+	Request CreateWebhookBody
+}
+
+// CreateWebhookBody describes body for the createWebhook request.
+type CreateWebhookBody struct {
+	// URL is notified of the documents saved in the vault.
+	URL string `json:"url"`
+	// Secret, if any, keys the HMAC-SHA256 signature of the events.
+	Secret string `json:"secret,omitempty"`
+}
+
+// createWebhookResp model
+//
+// swagger:response createWebhookResp
+type createWebhookResp struct {
+	// in: body
+	Body *vault.Webhook
+}
+
+// listWebhooksReq model
+//
+// swagger:parameters listWebhooksReq
+type listWebhooksReq struct { // nolint: unused,deadcode
+	// in: path
+	VaultID string `json:"vaultID"`
+}
+
+// listWebhooksResp model
+//
+// swagger:response listWebhooksResp
+type listWebhooksResp struct {
+	// in: body
+	Body *vault.WebhookList
+}
+
+// deleteWebhookReq model
+//
+// swagger:parameters deleteWebhookReq
+type deleteWebhookReq struct { // nolint: unused,deadcode
+	// in: path
+	VaultID string `json:"vaultID"`
+	// in: path
+	WebhookID string `json:"webhookID"`
+}
+
+// deleteWebhookResp model
+//
+// swagger:response deleteWebhookResp
+type deleteWebhookResp struct{} // nolint: unused,deadcode
+
+// listAuthorizationsReq model
+//
+// swagger:parameters listAuthorizationsReq
+type listAuthorizationsReq struct { // nolint: unused,deadcode
+	// in: path
+	VaultID string `json:"vaultID"`
+	// Only list the authorizations granted to this requesting party.
+	// in: query
+	RequestingParty string `json:"requestingParty"`
+	// Include the authorizations that have expired.
+	// in: query
+	IncludeExpired bool `json:"includeExpired"`
+	// The maximum number of authorizations to return. Defaults to 100, at most 1000.
+	// in: query
+	Limit int `json:"limit"`
+	// The continuation token returned with the previous page.
+	// in: query
+	Next string `json:"next"`
+	// Only lists the documents with the index tag, given as name:value. Limit and next are then ignored.
+	// in: query
+	Tag string `json:"tag"`
+}
+
+// listAuthorizationsResp model
+//
+// swagger:response listAuthorizationsResp
+type listAuthorizationsResp struct {
+	// in: body
+	Body *vault.AuthorizationList
+}
+
+// getAuditTrailReq model
+//
+// swagger:parameters getAuditTrailReq
+type getAuditTrailReq struct { // nolint: unused,deadcode
+	// in: path
+	VaultID string `json:"vaultID"`
+	// Only list the events recorded at or after this time, in RFC 3339 format.
+	// in: query
+	Since string `json:"since"`
+	// Only list the events recorded before this time, in RFC 3339 format.
+	// in: query
+	Until string `json:"until"`
+	// The maximum number of events to return. Defaults to 100, at most 1000.
+	// in: query
+	Limit int `json:"limit"`
+	// The continuation token returned with the previous page.
+	// in: query
+	Next string `json:"next"`
+}
+
+// getAuditTrailResp model
+//
+// swagger:response getAuditTrailResp
+type getAuditTrailResp struct {
+	// in: body
+	Body *vault.AuditTrail
+}
+
 // getAuthorizationReq model
 //
 // swagger:parameters getAuthorizationReq
@@ -113,10 +432,20 @@ type getAuthorizationReq struct { // nolint: unused,deadcode
 	AuthorizationID string `json:"authID"`
 }
 
+// useAuthorizationReq model
+//
+// swagger:parameters useAuthorizationReq
+type useAuthorizationReq struct { // nolint: unused,deadcode
+	// in: path
+	VaultID string `json:"vaultID"`
+	// in: path
+	AuthorizationID string `json:"authID"`
+}
+
 // getAuthorizationResp model
 //
 // swagger:response getAuthorizationResp
-type getAuthorizationResp struct { // nolint: unused,deadcode
+type getAuthorizationResp struct {
 	// in: body
 	Body *vault.CreatedAuthorization
 }
@@ -136,15 +465,113 @@ type deleteAuthorizationReq struct { // nolint: unused,deadcode
 // swagger:response deleteAuthorizationResp
 type deleteAuthorizationResp struct{} // nolint: unused,deadcode
 
+// getRevocationReq model
+//
+// swagger:parameters getRevocationReq
+type getRevocationReq struct { // nolint: unused,deadcode
+	// in: path
+	ZcapID string `json:"zcapID"`
+}
+
+// getRevocationResp model
+//
+// swagger:response getRevocationResp
+type getRevocationResp struct {
+	// in: body
+	Body *vault.Revocation
+}
+
 // deleteVaultReq model
 //
 // swagger:parameters deleteVaultReq
 type deleteVaultReq struct { // nolint: unused,deadcode
 	// in: path
 	VaultID string `json:"vaultID"`
+	// Must be true to delete the vault.
+	// in: query
+	// required: true
+	Confirm bool `json:"confirm"`
 }
 
 // deleteVaultResp model
 //
 // swagger:response deleteVaultResp
-type deleteVaultResp struct{} // nolint: unused,deadcode
+type deleteVaultResp struct {
+	// in: body
+	Body *vault.VaultDeletion
+}
+
+// exportVaultReq model
+//
+// swagger:parameters exportVaultReq
+type exportVaultReq struct { // nolint: unused,deadcode
+	// in: path
+	VaultID string `json:"vaultID"`
+}
+
+// exportVaultResp model
+//
+// swagger:response exportVaultResp
+type exportVaultResp struct { // nolint: unused,deadcode
+	// in: body
+	Body []*vault.ArchiveEntry
+}
+
+// importVaultReq model
+//
+// swagger:parameters importVaultReq
+type importVaultReq struct { // nolint: unused,deadcode
+	// in: body
+	// required: true
+	Body []*vault.ArchiveEntry
+}
+
+// importVaultResp model
+//
+// swagger:response importVaultResp
+type importVaultResp struct {
+	// in: body
+	Body *vault.VaultImport
+}
+
+// rekeyVaultReq model
+//
+// swagger:parameters rekeyVaultReq
+type rekeyVaultReq struct { // nolint: unused,deadcode
+	// in: path
+	VaultID string `json:"vaultID"`
+}
+
+// rotateTokensReq model
+//
+// swagger:parameters rotateTokensReq
+type rotateTokensReq struct { // nolint: unused,deadcode
+	// in: path
+	VaultID string `json:"vaultID"`
+}
+
+// rekeyDocReq model
+//
+// swagger:parameters rekeyDocReq
+type rekeyDocReq struct { // nolint: unused,deadcode
+	// in: path
+	VaultID string `json:"vaultID"`
+	// in: path
+	DocID string `json:"docID"`
+}
+
+// getRekeyStatusReq model
+//
+// swagger:parameters getRekeyStatusReq
+type getRekeyStatusReq struct { // nolint: unused,deadcode
+	// in: path
+	VaultID string `json:"vaultID"`
+}
+
+// rekeyVaultResp model
+//
+// swagger:response rekeyVaultResp
+type rekeyVaultResp struct {
+	// in: body
+	Body *vault.VaultRekey
+}
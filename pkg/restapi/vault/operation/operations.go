@@ -7,16 +7,23 @@ SPDX-License-Identifier: Apache-2.0
 package operation
 
 import (
+	"bytes"
+	"crypto/subtle"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
 	"github.com/hyperledger/aries-framework-go/spi/storage"
 	"github.com/trustbloc/edge-core/pkg/log"
 	"github.com/trustbloc/edv/pkg/edvutils"
-	"github.com/trustbloc/edv/pkg/restapi/messages"
 
 	"github.com/trustbloc/ace/pkg/restapi/handler"
 	"github.com/trustbloc/ace/pkg/restapi/model"
@@ -27,12 +34,47 @@ import (
 const (
 	operationID             = "/vaults"
 	CreateVaultPath         = operationID
+	ListVaultsPath          = operationID
+	GetVaultPath            = operationID + "/{vaultID}"
 	DeleteVaultPath         = operationID + "/{vaultID}"
+	ExportVaultPath         = operationID + "/{vaultID}/export"
+	ImportVaultPath         = operationID + "/import"
+	RekeyVaultPath          = operationID + "/{vaultID}/rekey"
+	GetRekeyStatusPath      = operationID + "/{vaultID}/rekey"
+	RotateTokensPath        = operationID + "/{vaultID}/rotate-tokens"
 	SaveDocPath             = operationID + "/{vaultID}/docs"
+	ListDocsPath            = operationID + "/{vaultID}/docs"
+	GetDocPath              = operationID + "/{vaultID}/docs/{docID}"
+	DeleteDocPath           = operationID + "/{vaultID}/docs/{docID}"
+	PatchDocPath            = operationID + "/{vaultID}/docs/{docID}"
 	GetDocMetadataPath      = operationID + "/{vaultID}/docs/{docID}/metadata"
+	GetDocMetadataByURIPath = operationID + "/docs/metadata"
+	RekeyDocPath            = operationID + "/{vaultID}/docs/{docID}/rekey"
 	CreateAuthorizationPath = operationID + "/{vaultID}/authorizations"
+	CreateChallengePath     = operationID + "/{vaultID}/authorizations/challenges"
+	ListAuthorizationsPath  = operationID + "/{vaultID}/authorizations"
 	GetAuthorizationPath    = operationID + "/{vaultID}/authorizations/{authID}"
 	DeleteAuthorizationPath = operationID + "/{vaultID}/authorizations/{authID}"
+	UseAuthorizationPath    = operationID + "/{vaultID}/authorizations/{authID}/uses"
+	CreateWebhookPath       = operationID + "/{vaultID}/webhooks"
+	ListWebhooksPath        = operationID + "/{vaultID}/webhooks"
+	DeleteWebhookPath       = operationID + "/{vaultID}/webhooks/{webhookID}"
+	GetAuditTrailPath       = operationID + "/{vaultID}/audit"
+	GetRevocationPath       = "/revocations/{zcapID}"
+)
+
+const (
+	jsonMediaType = "application/json"
+	// archiveMediaType is the media type of vault archives, which are streams of JSON entries, one per line.
+	archiveMediaType = "application/x-ndjson"
+	// mergePatchMediaType is the media type of JSON merge patches.
+	mergePatchMediaType = "application/merge-patch+json"
+	// jsonPatchMediaType is the media type of JSON patches.
+	jsonPatchMediaType = "application/json-patch+json"
+	// DefaultMaxDocSize is the default maximum size, in bytes, of the body of SaveDoc requests.
+	DefaultMaxDocSize = 10 << 20
+	// maxCreateVaultBodySize is the maximum size, in bytes, of the body of CreateVault requests.
+	maxCreateVaultBodySize = 64 << 10
 )
 
 var logger = log.New("vault-operation")
@@ -41,40 +83,156 @@ var logger = log.New("vault-operation")
 type Operation struct {
 	vault      vault.Vault
 	GenerateID func() (string, error)
+	validateID func(id string) error
+	maxDocSize int64
+	adminToken string
+	// invocationVerifier is nil when the endpoints modifying vaults are open.
+	invocationVerifier InvocationVerifier
+}
+
+// Option configures the vault operation.
+type Option func(o *Operation)
+
+// WithMaxDocSize sets the maximum size, in bytes, of the body of SaveDoc requests. Defaults to DefaultMaxDocSize.
+func WithMaxDocSize(size int64) Option {
+	return func(o *Operation) {
+		o.maxDocSize = size
+	}
+}
+
+// WithAdminToken sets the bearer token of the administrators, required to pin the EDV backend of new vaults.
+// Without it, the EDV backend of new vaults cannot be pinned.
+func WithAdminToken(token string) Option {
+	return func(o *Operation) {
+		o.adminToken = token
+	}
 }
 
 // New returns operation instance.
-func New(v vault.Vault) *Operation {
-	return &Operation{
+func New(v vault.Vault, opts ...Option) *Operation {
+	o := &Operation{
 		vault:      v,
 		GenerateID: edvutils.GenerateEDVCompatibleID,
+		maxDocSize: DefaultMaxDocSize,
+	}
+
+	for _, opt := range opts {
+		opt(o)
 	}
+
+	return o
 }
 
 // GetRESTHandlers get all controller API handler available for this service.
 func (o *Operation) GetRESTHandlers() []handler.Handler {
 	return []handler.Handler{
 		handler.NewHTTPHandler(CreateVaultPath, http.MethodPost, o.CreateVault),
-		handler.NewHTTPHandler(DeleteVaultPath, http.MethodDelete, o.DeleteVault),
-		handler.NewHTTPHandler(SaveDocPath, http.MethodPost, o.SaveDoc),
-		handler.NewHTTPHandler(GetDocMetadataPath, http.MethodGet, o.GetDocMetadata),
-		handler.NewHTTPHandler(CreateAuthorizationPath, http.MethodPost, o.CreateAuthorization),
-		handler.NewHTTPHandler(GetAuthorizationPath, http.MethodGet, o.GetAuthorization),
-		handler.NewHTTPHandler(DeleteAuthorizationPath, http.MethodDelete, o.DeleteAuthorization),
+		handler.NewHTTPHandler(ListVaultsPath, http.MethodGet, o.controllerAuthorized(o.ListVaults)),
+		handler.NewHTTPHandler(GetVaultPath, http.MethodGet, o.authorized(o.GetVault)),
+		handler.NewHTTPHandler(DeleteVaultPath, http.MethodDelete, o.authorized(o.DeleteVault)),
+		handler.NewHTTPHandler(ExportVaultPath, http.MethodGet, o.authorized(o.ExportVault)),
+		handler.NewHTTPHandler(ImportVaultPath, http.MethodPost, o.adminAuthorized(o.ImportVault)),
+		handler.NewHTTPHandler(RekeyVaultPath, http.MethodPost, o.authorized(o.RekeyVault)),
+		handler.NewHTTPHandler(GetRekeyStatusPath, http.MethodGet, o.authorized(o.GetRekeyStatus)),
+		handler.NewHTTPHandler(RotateTokensPath, http.MethodPost, o.authorized(o.RotateTokens)),
+		handler.NewHTTPHandler(SaveDocPath, http.MethodPost, o.authorized(o.SaveDoc)),
+		handler.NewHTTPHandler(ListDocsPath, http.MethodGet, o.authorized(o.ListDocs)),
+		handler.NewHTTPHandler(GetDocPath, http.MethodGet, o.authorized(o.GetDoc)),
+		handler.NewHTTPHandler(DeleteDocPath, http.MethodDelete, o.authorized(o.DeleteDoc)),
+		handler.NewHTTPHandler(PatchDocPath, http.MethodPatch, o.authorized(o.PatchDoc)),
+		handler.NewHTTPHandler(GetDocMetadataPath, http.MethodGet, o.authorized(o.GetDocMetadata)),
+		handler.NewHTTPHandler(GetDocMetadataPath, http.MethodHead, o.authorized(o.GetDocMetadata)),
+		handler.NewHTTPHandler(GetDocMetadataByURIPath, http.MethodGet,
+			o.docURIAuthorized(o.GetDocMetadataByURI)),
+		handler.NewHTTPHandler(RekeyDocPath, http.MethodPost, o.authorized(o.RekeyDoc)),
+		handler.NewHTTPHandler(CreateAuthorizationPath, http.MethodPost, o.authorized(o.CreateAuthorization)),
+		handler.NewHTTPHandler(CreateChallengePath, http.MethodPost, o.CreateAuthorizationChallenge),
+		handler.NewHTTPHandler(ListAuthorizationsPath, http.MethodGet, o.authorized(o.ListAuthorizations)),
+		handler.NewHTTPHandler(GetAuthorizationPath, http.MethodGet, o.authorized(o.GetAuthorization)),
+		handler.NewHTTPHandler(DeleteAuthorizationPath, http.MethodDelete, o.authorized(o.DeleteAuthorization)),
+		handler.NewHTTPHandler(UseAuthorizationPath, http.MethodPost, o.authorizationAuthorized(o.UseAuthorization)),
+		handler.NewHTTPHandler(CreateWebhookPath, http.MethodPost, o.authorized(o.CreateWebhook)),
+		handler.NewHTTPHandler(ListWebhooksPath, http.MethodGet, o.authorized(o.ListWebhooks)),
+		handler.NewHTTPHandler(DeleteWebhookPath, http.MethodDelete, o.authorized(o.DeleteWebhook)),
+		handler.NewHTTPHandler(GetAuditTrailPath, http.MethodGet, o.authorized(o.GetAuditTrail)),
+		handler.NewHTTPHandler(GetRevocationPath, http.MethodGet, o.GetRevocation),
 	}
 }
 
 // CreateVault swagger:route POST /vaults vault createVaultReq
 //
-// Creates a new vault.
+// Creates a new vault. The vault is controlled by a new did:key unless a controller DID is given, in which case the
+// request must be signed with the verification method.
+// Responds with 200 and the existing vault if it was already created with the reference ID.
 //
 // Responses:
 //    default: genericError
+//        200: createVaultResp
 //        201: createVaultResp
-func (o *Operation) CreateVault(rw http.ResponseWriter, _ *http.Request) {
-	result, err := o.vault.CreateVault()
+//        413: genericError
+func (o *Operation) CreateVault(rw http.ResponseWriter, req *http.Request) {
+	var body CreateVaultBody
+
+	// the body is optional, and is read again to verify the signature of the requests giving a controller
+	if req.Body != nil {
+		raw, err := io.ReadAll(maxBytesReader(rw, req.Body, maxCreateVaultBodySize))
+		if err != nil {
+			o.writeReadBodyError(rw, err, "request body", maxCreateVaultBodySize)
+
+			return
+		}
+
+		if err = json.Unmarshal(raw, &body); err != nil && len(bytes.TrimSpace(raw)) != 0 {
+			o.writeErrorResponse(rw, err, http.StatusBadRequest)
+
+			return
+		}
+
+		req.Body = io.NopCloser(bytes.NewReader(raw))
+	}
+
+	var opts []vault.CreateVaultOpt
+
+	if body.EDVBackend != "" {
+		if !o.isAdmin(req) {
+			o.writeErrorResponse(rw, errors.New("edvBackend requires the admin token"), http.StatusUnauthorized)
+
+			return
+		}
+
+		opts = append(opts, vault.WithEDVBackend(body.EDVBackend))
+	}
+
+	if body.KeyType != "" {
+		opts = append(opts, vault.WithVaultKeyType(kms.KeyType(body.KeyType)))
+	}
+
+	if body.ReferenceID != "" {
+		opts = append(opts, vault.WithReferenceID(body.ReferenceID))
+	}
+
+	if body.KMSURL != "" {
+		opts = append(opts, vault.WithVaultKMSURL(body.KMSURL))
+	}
+
+	var (
+		result *vault.CreatedVault
+		err    error
+	)
+
+	switch {
+	case body.Controller != "" || body.VerificationMethod != "":
+		result, err = o.vault.CreateVaultWithController(body.Controller, body.VerificationMethod, req, opts...)
+	case req.Header.Get("Signature") != "":
+		result, err = o.vault.CreateVault(append(opts, vault.WithVaultOwner(req))...)
+	case o.invocationVerifier != nil:
+		err = fmt.Errorf("%w: an HTTP signature by the owner of the vault is required", vault.ErrUnauthorizedInvocation)
+	default:
+		result, err = o.vault.CreateVault(opts...)
+	}
+
 	if err != nil {
-		o.writeErrorResponse(rw, err, http.StatusInternalServerError)
+		o.writeErrorResponse(rw, err, createVaultErrorStatus(err))
 
 		return
 	}
@@ -82,84 +240,236 @@ func (o *Operation) CreateVault(rw http.ResponseWriter, _ *http.Request) {
 	var resp createVaultResp
 	resp.Body = result
 
+	if result.Existing {
+		o.WriteResponse(rw, resp.Body, http.StatusOK)
+
+		return
+	}
+
 	o.WriteResponse(rw, resp.Body, http.StatusCreated)
 }
 
+// GetVault swagger:route GET /vaults/{vaultID} vault getVaultReq
+//
+// Returns the vault`s creation time, controller, document count and EDV/KMS URIs.
+//
+// Responses:
+//    default: genericError
+//        200: getVaultResp
+func (o *Operation) GetVault(rw http.ResponseWriter, req *http.Request) {
+	result, err := o.vault.GetVaultInfo(mux.Vars(req)["vaultID"])
+	if err != nil {
+		o.writeErrorResponse(rw, err, docErrorStatus(err))
+
+		return
+	}
+
+	var resp getVaultResp
+	resp.Body = result
+
+	o.WriteResponse(rw, resp.Body, http.StatusOK)
+}
+
 // DeleteVault swagger:route DELETE /vaults/{vaultID} vault deleteVaultReq
 //
-// Deletes an existing vault.
+// Deletes an existing vault: its EDV documents, its WebKMS keystore and its local records.
+// Requires confirm=true. Responds with 502 if a backend deletion failed, in which case the request can be retried.
 //
 // Responses:
 //    default: genericError
 //        200: deleteVaultResp
-func (o *Operation) DeleteVault(rw http.ResponseWriter, _ *http.Request) {
-	rw.WriteHeader(http.StatusOK)
+//        502: deleteVaultResp
+func (o *Operation) DeleteVault(rw http.ResponseWriter, req *http.Request) {
+	if confirm, err := strconv.ParseBool(req.URL.Query().Get("confirm")); err != nil || !confirm {
+		o.writeErrorResponse(rw, errors.New("vault deletion must be confirmed with confirm=true"),
+			http.StatusBadRequest)
+
+		return
+	}
+
+	result, err := o.vault.DeleteVault(mux.Vars(req)["vaultID"])
+	if err != nil {
+		o.writeErrorResponse(rw, err, docErrorStatus(err))
+
+		return
+	}
+
+	status := http.StatusOK
+	if !result.Complete {
+		status = http.StatusBadGateway
+	}
+
+	var resp deleteVaultResp
+	resp.Body = result
+
+	o.WriteResponse(rw, resp.Body, status)
 }
 
-// SaveDoc swagger:route POST /vaults/{vaultID}/docs vault saveDocReq
+// ExportVault swagger:route GET /vaults/{vaultID}/export vault exportVaultReq
 //
-// Creates or updates a document by encrypting it and storing it in the vault.
+// Streams an archive of the vault: its metadata followed by its documents, as encrypted in EDV.
+// Nothing is decrypted. If the export fails once started, the archive ends with an error entry.
 //
 // Responses:
 //    default: genericError
-//        201: saveDocResp
-func (o *Operation) SaveDoc(rw http.ResponseWriter, req *http.Request) {
-	var doc saveDocReq
+//        200: exportVaultResp
+func (o *Operation) ExportVault(rw http.ResponseWriter, req *http.Request) {
+	export, err := o.vault.ExportVault(mux.Vars(req)["vaultID"])
+	if err != nil {
+		o.writeErrorResponse(rw, err, docErrorStatus(err))
 
-	if err := json.NewDecoder(req.Body).Decode(&doc.Request); err != nil {
-		o.writeErrorResponse(rw, err, http.StatusBadRequest)
+		return
+	}
+
+	rw.Header().Set("Content-Type", archiveMediaType)
+	rw.WriteHeader(http.StatusOK)
+
+	enc := json.NewEncoder(rw)
+
+	if err = enc.Encode(&vault.ArchiveEntry{Vault: export.Vault}); err != nil {
+		logger.Errorf("unable to send the vault archive: %v", err)
 
 		return
 	}
 
-	var (
-		vaultID    = mux.Vars(req)["vaultID"]
-		docID      = doc.Request.ID
-		docContent = doc.Request.Content
-	)
+	for {
+		doc, errNext := export.Next()
+		if errors.Is(errNext, io.EOF) {
+			return
+		}
 
-	if docID == "" {
-		var err error
+		entry := &vault.ArchiveEntry{Document: doc}
 
-		docID, err = o.GenerateID()
-		if err != nil {
-			o.writeErrorResponse(rw, err, http.StatusInternalServerError)
+		if errNext != nil {
+			logger.Errorf("export vault: %v", errNext)
+
+			entry = &vault.ArchiveEntry{Error: errNext.Error()}
+		}
+
+		if err = enc.Encode(entry); err != nil {
+			logger.Errorf("unable to send the vault archive: %v", err)
+
+			return
+		}
 
+		if errNext != nil {
 			return
 		}
+
+		if f, ok := rw.(http.Flusher); ok {
+			f.Flush()
+		}
+	}
+}
+
+// ImportVault swagger:route POST /vaults/import vault importVaultReq
+//
+// Creates a new vault holding the documents of a vault archive, re-encrypted to keys of the keystore of the new
+// vault. The exported vault must be held by this server. Documents that cannot be imported are reported in the
+// response and do not fail the import.
+//
+// Responses:
+//    default: genericError
+//        201: importVaultResp
+func (o *Operation) ImportVault(rw http.ResponseWriter, req *http.Request) {
+	result, err := o.vault.ImportVault(req.Body)
+	if errors.Is(err, vault.ErrInvalidArchive) || errors.Is(err, vault.ErrSourceKeystoreUnavailable) {
+		o.writeErrorResponse(rw, err, http.StatusBadRequest)
+
+		return
 	}
 
-	result, err := o.vault.SaveDoc(vaultID, docID, docContent)
 	if err != nil {
 		o.writeErrorResponse(rw, err, http.StatusInternalServerError)
 
 		return
 	}
 
-	var resp saveDocResp
+	var resp importVaultResp
 	resp.Body = result
 
 	o.WriteResponse(rw, resp.Body, http.StatusCreated)
 }
 
-// GetDocMetadata swagger:route GET /vaults/{vaultID}/docs/{docID}/metadata vault getDocMetadataReq
+// RekeyVault swagger:route POST /vaults/{vaultID}/rekey vault rekeyVaultReq
+//
+// Re-encrypts the documents of the vault to a new key of its keystore. Responds with 502 if a document could not be
+// re-encrypted, in which case the request can be retried to resume the re-encryption.
+//
+// Responses:
+//    default: genericError
+//        200: rekeyVaultResp
+//        502: rekeyVaultResp
+func (o *Operation) RekeyVault(rw http.ResponseWriter, req *http.Request) {
+	result, err := o.vault.RekeyVault(mux.Vars(req)["vaultID"])
+	if err != nil {
+		status := docErrorStatus(err)
+		if errors.Is(err, vault.ErrVaultDeleting) {
+			status = http.StatusConflict
+		}
+
+		o.writeErrorResponse(rw, err, status)
+
+		return
+	}
+
+	status := http.StatusOK
+	if !result.Complete {
+		status = http.StatusBadGateway
+	}
+
+	var resp rekeyVaultResp
+	resp.Body = result
+
+	o.WriteResponse(rw, resp.Body, status)
+}
+
+// RotateTokens swagger:route POST /vaults/{vaultID}/rotate-tokens vault rotateTokensReq
+//
+// Issues new EDV and KMS authorization tokens to the controller of the vault and revokes the previous ones, eg.
+// when a device holding them is lost. The previous tokens are kept if the new ones cannot be issued.
+//
+// Responses:
+//    default: genericError
+//        200: createVaultResp
+func (o *Operation) RotateTokens(rw http.ResponseWriter, req *http.Request) {
+	result, err := o.vault.RotateTokens(mux.Vars(req)["vaultID"])
+	if err != nil {
+		status := docErrorStatus(err)
+		if errors.Is(err, vault.ErrVaultDeleting) {
+			status = http.StatusConflict
+		}
+
+		o.writeErrorResponse(rw, err, status)
+
+		return
+	}
+
+	var resp createVaultResp
+	resp.Body = result
+
+	o.WriteResponse(rw, resp.Body, http.StatusOK)
+}
+
+// RekeyDoc swagger:route POST /vaults/{vaultID}/docs/{docID}/rekey vault rekeyDocReq
 //
-// Returns the document`s metadata by given docID.
+// Re-encrypts the document to the current key of the vault: the key of its last re-encryption. If the vault was never
+// re-encrypted, a re-encryption to a new key of its keystore is started.
 //
 // Responses:
 //    default: genericError
 //        200: getDocMetadataResp
-func (o *Operation) GetDocMetadata(rw http.ResponseWriter, req *http.Request) {
+func (o *Operation) RekeyDoc(rw http.ResponseWriter, req *http.Request) {
 	var (
 		vaultID = mux.Vars(req)["vaultID"]
 		docID   = mux.Vars(req)["docID"]
 	)
 
-	result, err := o.vault.GetDocMetadata(vaultID, docID)
+	result, err := o.vault.RekeyDoc(vaultID, docID)
 	if err != nil {
-		status := http.StatusInternalServerError
-		if strings.HasSuffix(err.Error(), messages.ErrDocumentNotFound.Error()+".") {
-			status = http.StatusNotFound
+		status := docErrorStatus(err)
+		if errors.Is(err, vault.ErrVaultDeleting) {
+			status = http.StatusConflict
 		}
 
 		o.writeErrorResponse(rw, err, status)
@@ -173,89 +483,1075 @@ func (o *Operation) GetDocMetadata(rw http.ResponseWriter, req *http.Request) {
 	o.WriteResponse(rw, resp.Body, http.StatusOK)
 }
 
-// CreateAuthorization swagger:route POST /vaults/{vaultID}/authorizations vault createAuthorizationsReq
+// GetRekeyStatus swagger:route GET /vaults/{vaultID}/rekey vault getRekeyStatusReq
 //
-// Creates an authorization.
+// Returns the progress of the last re-encryption of the vault.
 //
 // Responses:
 //    default: genericError
-//        201: createAuthorizationResp
-func (o *Operation) CreateAuthorization(rw http.ResponseWriter, req *http.Request) {
-	var doc createAuthorizationsReq
+//        200: rekeyVaultResp
+func (o *Operation) GetRekeyStatus(rw http.ResponseWriter, req *http.Request) {
+	result, err := o.vault.GetRekeyStatus(mux.Vars(req)["vaultID"])
+	if err != nil {
+		o.writeErrorResponse(rw, err, docErrorStatus(err))
 
-	if err := json.NewDecoder(req.Body).Decode(&doc.Request); err != nil {
+		return
+	}
+
+	var resp rekeyVaultResp
+	resp.Body = result
+
+	o.WriteResponse(rw, resp.Body, http.StatusOK)
+}
+
+// SaveDoc swagger:route POST /vaults/{vaultID}/docs vault saveDocReq
+//
+// Creates or updates a document by encrypting it and storing it in the vault.
+// Content of any media type other than application/json is stored as is, eg. a PDF.
+// With an If-Match header, the document is updated only if it exists and its current sequence is the given one.
+// With controllerRecipient, the document is also encrypted to the key agreement key of the vault's controller.
+//
+// Responses:
+//    default: genericError
+//        201: saveDocResp
+//        409: sequenceConflictResp
+//        412: genericError
+func (o *Operation) SaveDoc(rw http.ResponseWriter, req *http.Request) { // nolint:funlen
+	opts, err := saveDocOpts(req)
+	if err != nil {
+		o.writeErrorResponse(rw, err, http.StatusBadRequest)
+
+		return
+	}
+
+	mediaType := jsonMediaType
+
+	if ct := req.Header.Get("Content-Type"); ct != "" {
+		mediaType, _, err = mime.ParseMediaType(ct)
+		if err != nil {
+			o.writeErrorResponse(rw, fmt.Errorf("invalid content type: %w", err), http.StatusBadRequest)
+
+			return
+		}
+	}
+
+	if mediaType != jsonMediaType {
+		o.saveBinaryDoc(rw, req, mediaType, opts)
+
+		return
+	}
+
+	// the whole body is read before anything is saved: a body over the limit leaves nothing behind
+	body, err := io.ReadAll(maxBytesReader(rw, req.Body, o.maxDocSize))
+	if err != nil {
+		o.writeReadBodyError(rw, err, "document", o.maxDocSize)
+
+		return
+	}
+
+	var doc saveDocReq
+
+	if err := json.NewDecoder(bytes.NewReader(body)).Decode(&doc.Request); err != nil {
 		o.writeErrorResponse(rw, err, http.StatusBadRequest)
 
 		return
 	}
 
 	var (
-		vaultID         = mux.Vars(req)["vaultID"]
-		scope           = doc.Request.Scope
-		requestingParty = doc.Request.RequestingParty
+		vaultID    = mux.Vars(req)["vaultID"]
+		docID      = doc.Request.ID
+		docContent = doc.Request.Content
 	)
 
-	result, err := o.vault.CreateAuthorization(vaultID, requestingParty, &scope)
+	docID, err = o.docID(docID)
 	if err != nil {
-		o.writeErrorResponse(rw, err, http.StatusInternalServerError)
+		o.writeDocIDError(rw, err)
 
 		return
 	}
 
-	var resp createAuthorizationResp
+	if len(doc.Request.IndexTags) > 0 {
+		opts = append(opts, vault.WithIndexTags(doc.Request.IndexTags))
+	}
+
+	result, err := o.vault.SaveDoc(vaultID, docID, docContent, opts...)
+	if err != nil {
+		o.writeSaveDocError(rw, err)
+
+		return
+	}
+
+	var resp saveDocResp
 	resp.Body = result
 
 	o.WriteResponse(rw, resp.Body, http.StatusCreated)
 }
 
-// GetAuthorization swagger:route GET /vaults/{vaultID}/authorizations/{authID} vault getAuthorizationReq
-//
-// Fetches an authorization.
-//
-// Responses:
-//    default: genericError
-//        200: getAuthorizationResp
-func (o *Operation) GetAuthorization(rw http.ResponseWriter, req *http.Request) {
+// saveBinaryDoc saves the request body as the content of the document, streamed to the vault as it is read: the
+// body is never held in memory as a whole. The document ID is taken from the id query parameter, if any.
+func (o *Operation) saveBinaryDoc(rw http.ResponseWriter, req *http.Request, mediaType string,
+	opts []vault.SaveDocOpt,
+) {
 	var (
 		vaultID = mux.Vars(req)["vaultID"]
-		authID  = mux.Vars(req)["authID"]
+		docID   = req.URL.Query().Get("id")
+		err     error
 	)
 
-	result, err := o.vault.GetAuthorization(vaultID, authID)
+	docID, err = o.docID(docID)
 	if err != nil {
-		status := http.StatusInternalServerError
-		if errors.Is(err, storage.ErrDataNotFound) {
-			status = http.StatusNotFound
+		o.writeDocIDError(rw, err)
+
+		return
+	}
+
+	body := &bodyReader{Reader: maxBytesReader(rw, req.Body, o.maxDocSize)}
+
+	// the vault deletes what it stored of the content if the body cannot be read to the end
+	result, err := o.vault.SaveBinaryDocStream(vaultID, docID, mediaType, body, opts...)
+	if err != nil {
+		if body.err != nil {
+			o.writeReadBodyError(rw, body.err, "document", o.maxDocSize)
+
+			return
 		}
 
-		o.writeErrorResponse(rw, err, status)
+		o.writeSaveDocError(rw, err)
 
 		return
 	}
 
-	var resp createAuthorizationResp
+	var resp saveDocResp
 	resp.Body = result
 
-	o.WriteResponse(rw, resp.Body, http.StatusOK)
+	o.WriteResponse(rw, resp.Body, http.StatusCreated)
 }
 
-// DeleteAuthorization swagger:route DELETE /vaults/{vaultID}/authorizations/{authID} vault deleteAuthorizationReq
-//
-// Deletes an authorization.
-//
-// Responses:
-//    default: genericError
-//        200: deleteAuthorizationResp
-func (o *Operation) DeleteAuthorization(rw http.ResponseWriter, _ *http.Request) {
-	rw.WriteHeader(http.StatusOK)
+// bodyReader records the error reading the request body, if any, to tell it from the errors saving its content.
+type bodyReader struct {
+	io.Reader
+	err error
 }
 
-func (o *Operation) writeErrorResponse(rw http.ResponseWriter, err error, status int) {
-	logger.Errorf("%v", err)
+func (r *bodyReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if err != nil && !errors.Is(err, io.EOF) {
+		r.err = err
+	}
 
-	o.WriteResponse(rw, model.ErrorResponse{
-		Message: err.Error(),
-	}, status)
+	return n, err
+}
+
+// writeDocIDError responds with 400 if the document ID does not match the document ID strategy, 500 if it could not
+// be generated.
+func (o *Operation) writeDocIDError(rw http.ResponseWriter, err error) {
+	if errors.Is(err, errInvalidDocID) {
+		o.writeErrorResponse(rw, err, http.StatusBadRequest)
+
+		return
+	}
+
+	o.writeErrorResponse(rw, err, http.StatusInternalServerError)
+}
+
+// errBodyTooLarge is returned by the readers of maxBytesReader once the request body exceeds their limit.
+var errBodyTooLarge = errors.New("request body too large")
+
+// maxBytesReader limits the request body like http.MaxBytesReader, whose error is wrapped in errBodyTooLarge: its
+// type is not exported before Go 1.19.
+func maxBytesReader(rw http.ResponseWriter, body io.ReadCloser, n int64) io.Reader {
+	return &limitedReader{Reader: http.MaxBytesReader(rw, body, n), remaining: n}
+}
+
+type limitedReader struct {
+	io.Reader
+	remaining int64
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.remaining -= int64(n)
+
+	// http.MaxBytesReader fails once the limit is reached only if there is more to read
+	if err != nil && !errors.Is(err, io.EOF) && r.remaining == 0 {
+		return n, fmt.Errorf("%w: %s", errBodyTooLarge, err)
+	}
+
+	return n, err
+}
+
+// writeReadBodyError responds with 413 if the body, named by what, exceeds its maximum size.
+func (o *Operation) writeReadBodyError(rw http.ResponseWriter, err error, what string, maxSize int64) {
+	if errors.Is(err, errBodyTooLarge) {
+		o.writeErrorResponse(rw, fmt.Errorf("%s exceeds the maximum size of %d bytes", what, maxSize),
+			http.StatusRequestEntityTooLarge)
+
+		return
+	}
+
+	o.writeErrorResponse(rw, fmt.Errorf("read body: %w", err), http.StatusBadRequest)
+}
+
+// saveDocOpts returns the options of a SaveDoc request. The If-Match header holds the expected sequence of the
+// document, optionally quoted as an entity tag.
+func saveDocOpts(req *http.Request) ([]vault.SaveDocOpt, error) {
+	var opts []vault.SaveDocOpt
+
+	if ifMatch := req.Header.Get("If-Match"); ifMatch != "" {
+		sequence, err := strconv.ParseUint(strings.Trim(ifMatch, `"`), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid If-Match header: %s", ifMatch)
+		}
+
+		opts = append(opts, vault.WithExpectedSequence(sequence))
+	}
+
+	if r := req.URL.Query().Get("controllerRecipient"); r != "" {
+		controllerRecipient, err := strconv.ParseBool(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid controllerRecipient: %s", r)
+		}
+
+		if controllerRecipient {
+			opts = append(opts, vault.WithControllerRecipient())
+		}
+	}
+
+	return opts, nil
+}
+
+// writeSaveDocError responds with the current sequence of the document if it was updated concurrently, and with 412
+// if the document expected by If-Match does not exist.
+func (o *Operation) writeSaveDocError(rw http.ResponseWriter, err error) {
+	var mismatch *vault.SequenceMismatchError
+
+	if errors.Is(err, vault.ErrInvalidIndexTag) || errors.Is(err, vault.ErrInvalidCredential) ||
+		errors.Is(err, vault.ErrInvalidController) {
+		o.writeErrorResponse(rw, err, http.StatusBadRequest)
+
+		return
+	}
+
+	if errors.Is(err, vault.ErrExpectedDocumentNotFound) {
+		o.writeErrorResponse(rw, err, http.StatusPreconditionFailed)
+
+		return
+	}
+
+	if !errors.As(err, &mismatch) {
+		o.writeErrorResponse(rw, err, writeErrorStatus(err))
+
+		return
+	}
+
+	logger.Errorf("%v", err)
+
+	o.WriteResponse(rw, SequenceConflict{
+		Message:  err.Error(),
+		Code:     model.ErrCodeSequenceMismatch,
+		Sequence: mismatch.Current,
+	}, http.StatusConflict)
+}
+
+// ListVaults swagger:route GET /vaults vault listVaultsReq
+//
+// Lists the vaults of the controller, eg. for a wallet restored from its seed to find its vaults again.
+// If invocations are verified, the request must be signed by the controller or carry the admin token. Otherwise,
+// it must carry the admin token.
+//
+// Responses:
+//    default: genericError
+//        200: listVaultsResp
+func (o *Operation) ListVaults(rw http.ResponseWriter, req *http.Request) {
+	var (
+		query = req.URL.Query()
+		limit int
+	)
+
+	controller := query.Get("controller")
+	if controller == "" {
+		o.writeErrorResponse(rw, errors.New("controller is required"), http.StatusBadRequest)
+
+		return
+	}
+
+	if l := query.Get("limit"); l != "" {
+		var err error
+
+		limit, err = strconv.Atoi(l)
+		if err != nil || limit < 1 {
+			o.writeErrorResponse(rw, fmt.Errorf("invalid limit: %s", l), http.StatusBadRequest)
+
+			return
+		}
+	}
+
+	result, err := o.vault.ListVaults(controller, limit, query.Get("next"))
+	if errors.Is(err, vault.ErrInvalidContinuationToken) {
+		o.writeErrorResponse(rw, err, http.StatusBadRequest)
+
+		return
+	}
+
+	if err != nil {
+		o.writeErrorResponse(rw, err, http.StatusInternalServerError)
+
+		return
+	}
+
+	var resp listVaultsResp
+	resp.Body = result
+
+	o.WriteResponse(rw, resp.Body, http.StatusOK)
+}
+
+// ListDocs swagger:route GET /vaults/{vaultID}/docs vault listDocsReq
+//
+// Lists the documents stored in the vault. Document contents are not returned.
+// With a tag query parameter, only the documents with the index tag are listed.
+//
+// Responses:
+//    default: genericError
+//        200: listDocsResp
+func (o *Operation) ListDocs(rw http.ResponseWriter, req *http.Request) {
+	var (
+		vaultID = mux.Vars(req)["vaultID"]
+		query   = req.URL.Query()
+		limit   int
+	)
+
+	if tag := query.Get("tag"); tag != "" {
+		o.findDocs(rw, vaultID, tag)
+
+		return
+	}
+
+	if l := query.Get("limit"); l != "" {
+		var err error
+
+		limit, err = strconv.Atoi(l)
+		if err != nil || limit < 1 {
+			o.writeErrorResponse(rw, fmt.Errorf("invalid limit: %s", l), http.StatusBadRequest)
+
+			return
+		}
+	}
+
+	result, err := o.vault.ListDocs(vaultID, limit, query.Get("next"))
+	if errors.Is(err, vault.ErrInvalidContinuationToken) {
+		o.writeErrorResponse(rw, err, http.StatusBadRequest)
+
+		return
+	}
+
+	if err != nil {
+		o.writeErrorResponse(rw, err, docErrorStatus(err))
+
+		return
+	}
+
+	var resp listDocsResp
+	resp.Body = result
+
+	o.WriteResponse(rw, resp.Body, http.StatusOK)
+}
+
+// findDocs lists the documents with the index tag, given as name:value.
+func (o *Operation) findDocs(rw http.ResponseWriter, vaultID, tag string) {
+	name, value, ok := strings.Cut(tag, ":")
+	if !ok {
+		o.writeErrorResponse(rw, fmt.Errorf("%w: expected name:value", vault.ErrInvalidIndexTag),
+			http.StatusBadRequest)
+
+		return
+	}
+
+	result, err := o.vault.FindDocs(vaultID, name, value)
+	if errors.Is(err, vault.ErrInvalidIndexTag) {
+		o.writeErrorResponse(rw, err, http.StatusBadRequest)
+
+		return
+	}
+
+	if err != nil {
+		o.writeErrorResponse(rw, err, docErrorStatus(err))
+
+		return
+	}
+
+	var resp listDocsResp
+	resp.Body = result
+
+	o.WriteResponse(rw, resp.Body, http.StatusOK)
+}
+
+// GetDoc swagger:route GET /vaults/{vaultID}/docs/{docID} vault getDocReq
+//
+// Returns the decrypted document`s content by given docID.
+// The content of binary documents is returned as saved, with its original content type, as an attachment the
+// browser does not sniff: it is never rendered in the origin of the vault server.
+//
+// Responses:
+//    default: genericError
+//        200: getDocResp
+func (o *Operation) GetDoc(rw http.ResponseWriter, req *http.Request) {
+	var (
+		vaultID = mux.Vars(req)["vaultID"]
+		docID   = mux.Vars(req)["docID"]
+	)
+
+	result, err := o.vault.GetDocContent(vaultID, docID)
+	if err != nil {
+		o.writeErrorResponse(rw, err, docErrorStatus(err))
+
+		return
+	}
+
+	if result.MediaType != jsonMediaType {
+		rw.Header().Set("Content-Type", result.MediaType)
+		rw.Header().Set("Content-Disposition", "attachment")
+		rw.Header().Set("X-Content-Type-Options", "nosniff")
+		rw.WriteHeader(http.StatusOK)
+
+		if _, err = rw.Write(result.Data); err != nil {
+			logger.Errorf("unable to send a response: %v", err)
+		}
+
+		return
+	}
+
+	var resp getDocResp
+	resp.Body = result.Data
+
+	o.WriteResponse(rw, resp.Body, http.StatusOK)
+}
+
+// DeleteDoc swagger:route DELETE /vaults/{vaultID}/docs/{docID} vault deleteDocReq
+//
+// Deletes the document and revokes the authorizations targeting it.
+//
+// Responses:
+//    default: genericError
+//        200: deleteDocResp
+func (o *Operation) DeleteDoc(rw http.ResponseWriter, req *http.Request) {
+	var (
+		vaultID = mux.Vars(req)["vaultID"]
+		docID   = mux.Vars(req)["docID"]
+	)
+
+	if err := o.vault.DeleteDoc(vaultID, docID); err != nil {
+		o.writeErrorResponse(rw, err, docErrorStatus(err))
+
+		return
+	}
+
+	rw.WriteHeader(http.StatusOK)
+}
+
+// PatchDoc swagger:route PATCH /vaults/{vaultID}/docs/{docID} vault patchDocReq
+//
+// Applies a JSON merge patch (RFC 7386) to the content of a JSON document: members of the patch replace the ones
+// of the document, null members remove them. A JSON patch (RFC 6902) is applied instead if the content type is
+// application/json-patch+json. The document keeps its index tags.
+//
+// Responses:
+//    default: genericError
+//        200: patchDocResp
+func (o *Operation) PatchDoc(rw http.ResponseWriter, req *http.Request) {
+	var (
+		vaultID = mux.Vars(req)["vaultID"]
+		docID   = mux.Vars(req)["docID"]
+		patchFn = o.vault.PatchDoc
+	)
+
+	if ct := req.Header.Get("Content-Type"); ct != "" {
+		mediaType, _, err := mime.ParseMediaType(ct)
+
+		switch {
+		case err == nil && mediaType == jsonPatchMediaType:
+			patchFn = o.vault.JSONPatchDoc
+		case err != nil || (mediaType != mergePatchMediaType && mediaType != jsonMediaType):
+			o.writeErrorResponse(rw, fmt.Errorf("unsupported content type %s: expected %s or %s",
+				ct, mergePatchMediaType, jsonPatchMediaType), http.StatusUnsupportedMediaType)
+
+			return
+		}
+	}
+
+	opts, err := saveDocOpts(req)
+	if err != nil {
+		o.writeErrorResponse(rw, err, http.StatusBadRequest)
+
+		return
+	}
+
+	patch, err := io.ReadAll(maxBytesReader(rw, req.Body, o.maxDocSize))
+	if err != nil {
+		o.writeReadBodyError(rw, err, "document", o.maxDocSize)
+
+		return
+	}
+
+	result, err := patchFn(vaultID, docID, patch, opts...)
+	if err != nil {
+		o.writePatchDocError(rw, err)
+
+		return
+	}
+
+	o.WriteResponse(rw, result, http.StatusOK)
+}
+
+// writePatchDocError maps invalid patches to 400, unknown documents to 404 and binary documents to 409.
+func (o *Operation) writePatchDocError(rw http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, vault.ErrInvalidPatch):
+		o.writeErrorResponse(rw, err, http.StatusBadRequest)
+	case docErrorStatus(err) == http.StatusNotFound:
+		o.writeErrorResponse(rw, err, http.StatusNotFound)
+	case errors.Is(err, vault.ErrNotJSONDocument):
+		o.writeErrorResponse(rw, err, http.StatusConflict)
+	default:
+		o.writeSaveDocError(rw, err)
+	}
+}
+
+// GetDocMetadata swagger:route GET /vaults/{vaultID}/docs/{docID}/metadata vault getDocMetadataReq
+//
+// Returns the document`s metadata by given docID. HEAD requests check whether the document exists: they are handled
+// as GET requests, net/http dropping the body, so that they get the same status and headers.
+//
+// Responses:
+//    default: genericError
+//        200: getDocMetadataResp
+func (o *Operation) GetDocMetadata(rw http.ResponseWriter, req *http.Request) {
+	var (
+		vaultID = mux.Vars(req)["vaultID"]
+		docID   = mux.Vars(req)["docID"]
+	)
+
+	result, err := o.vault.GetDocMetadata(vaultID, docID)
+	if err != nil {
+		o.writeErrorResponse(rw, err, docErrorStatus(err))
+
+		return
+	}
+
+	var resp getDocMetadataResp
+	resp.Body = result
+
+	o.WriteResponse(rw, resp.Body, http.StatusOK)
+}
+
+// GetDocMetadataByURI swagger:route GET /vaults/docs/metadata vault getDocMetadataByURIReq
+//
+// Returns the metadata of the document of any vault stored at the given EDV document URI.
+//
+// Responses:
+//    default: genericError
+//        200: getDocMetadataResp
+func (o *Operation) GetDocMetadataByURI(rw http.ResponseWriter, req *http.Request) {
+	uri := req.URL.Query().Get("uri")
+	if uri == "" {
+		o.writeErrorResponse(rw, errors.New("missing uri"), http.StatusBadRequest)
+
+		return
+	}
+
+	result, err := o.vault.GetDocMetadataByURI(uri)
+	if errors.Is(err, vault.ErrInvalidDocURI) {
+		o.writeErrorResponse(rw, err, http.StatusBadRequest)
+
+		return
+	}
+
+	if err != nil {
+		o.writeErrorResponse(rw, err, docErrorStatus(err))
+
+		return
+	}
+
+	var resp getDocMetadataResp
+	resp.Body = result
+
+	o.WriteResponse(rw, resp.Body, http.StatusOK)
+}
+
+// CreateAuthorizationChallenge swagger:route POST /vaults/{vaultID}/authorizations/challenges vault createChallengeReq
+//
+// Issues a challenge for the requesting party of an authorization of the vault to sign, proving that it controls
+// a key of its DID. It can be answered once, before it expires.
+//
+// Responses:
+//    default: genericError
+//        201: createChallengeResp
+func (o *Operation) CreateAuthorizationChallenge(rw http.ResponseWriter, req *http.Request) {
+	result, err := o.vault.CreateAuthorizationChallenge(mux.Vars(req)["vaultID"])
+	if err != nil {
+		o.writeErrorResponse(rw, err, docErrorStatus(err))
+
+		return
+	}
+
+	var resp createChallengeResp
+	resp.Body = result
+
+	o.WriteResponse(rw, resp.Body, http.StatusCreated)
+}
+
+// CreateAuthorization swagger:route POST /vaults/{vaultID}/authorizations vault createAuthorizationsReq
+//
+// Creates an authorization. Fails with 403 if the proof that the requesting party controls a key of its DID is
+// invalid, or missing while the server requires it.
+//
+// Responses:
+//    default: genericError
+//        201: createAuthorizationResp
+//        403: genericError
+func (o *Operation) CreateAuthorization(rw http.ResponseWriter, req *http.Request) {
+	var doc createAuthorizationsReq
+
+	if err := json.NewDecoder(req.Body).Decode(&doc.Request); err != nil {
+		o.writeErrorResponse(rw, err, http.StatusBadRequest)
+
+		return
+	}
+
+	var (
+		vaultID         = mux.Vars(req)["vaultID"]
+		scope           = doc.Request.Scope
+		requestingParty = doc.Request.RequestingParty
+	)
+
+	var opts []vault.CreateAuthorizationOpt
+
+	if doc.Request.Proof != nil {
+		opts = append(opts, vault.WithKeyProof(doc.Request.Proof))
+	}
+
+	result, err := o.vault.CreateAuthorization(vaultID, requestingParty, &scope, opts...)
+	if errors.Is(err, vault.ErrInvalidKeyProof) {
+		o.writeErrorResponse(rw, err, http.StatusForbidden)
+
+		return
+	}
+
+	if err != nil {
+		o.writeErrorResponse(rw, err, writeErrorStatus(err))
+
+		return
+	}
+
+	var resp createAuthorizationResp
+	resp.Body = result
+
+	o.WriteResponse(rw, resp.Body, http.StatusCreated)
+}
+
+// CreateWebhook swagger:route POST /vaults/{vaultID}/webhooks vault createWebhookReq
+//
+// Registers a URL to notify of the documents saved in the vault. Events are delivered in the background and never
+// contain the content of the documents. If a secret is given, events are signed with it.
+//
+// Responses:
+//    default: genericError
+//        201: createWebhookResp
+func (o *Operation) CreateWebhook(rw http.ResponseWriter, req *http.Request) {
+	var body CreateWebhookBody
+
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		o.writeErrorResponse(rw, err, http.StatusBadRequest)
+
+		return
+	}
+
+	result, err := o.vault.CreateWebhook(mux.Vars(req)["vaultID"], body.URL, body.Secret)
+	if err != nil {
+		o.writeErrorResponse(rw, err, createWebhookErrorStatus(err))
+
+		return
+	}
+
+	var resp createWebhookResp
+	resp.Body = result
+
+	o.WriteResponse(rw, resp.Body, http.StatusCreated)
+}
+
+// ListWebhooks swagger:route GET /vaults/{vaultID}/webhooks vault listWebhooksReq
+//
+// Lists the webhooks of the vault, oldest first. Their secrets are not returned.
+//
+// Responses:
+//    default: genericError
+//        200: listWebhooksResp
+//        404: genericError
+func (o *Operation) ListWebhooks(rw http.ResponseWriter, req *http.Request) {
+	result, err := o.vault.ListWebhooks(mux.Vars(req)["vaultID"])
+	if err != nil {
+		o.writeErrorResponse(rw, err, docErrorStatus(err))
+
+		return
+	}
+
+	var resp listWebhooksResp
+	resp.Body = result
+
+	o.WriteResponse(rw, resp.Body, http.StatusOK)
+}
+
+// DeleteWebhook swagger:route DELETE /vaults/{vaultID}/webhooks/{webhookID} vault deleteWebhookReq
+//
+// Unregisters a webhook. Events already queued for the webhook are still delivered.
+//
+// Responses:
+//    default: genericError
+//        200: deleteWebhookResp
+//        404: genericError
+func (o *Operation) DeleteWebhook(rw http.ResponseWriter, req *http.Request) {
+	var (
+		vaultID   = mux.Vars(req)["vaultID"]
+		webhookID = mux.Vars(req)["webhookID"]
+	)
+
+	err := o.vault.DeleteWebhook(vaultID, webhookID)
+	if err != nil {
+		o.writeErrorResponse(rw, err, docErrorStatus(err))
+
+		return
+	}
+
+	rw.WriteHeader(http.StatusOK)
+}
+
+// ListAuthorizations swagger:route GET /vaults/{vaultID}/authorizations vault listAuthorizationsReq
+//
+// Lists the authorizations created for the vault. Expired authorizations are left out unless includeExpired is set.
+// Authorization tokens are not returned.
+//
+// Responses:
+//    default: genericError
+//        200: listAuthorizationsResp
+func (o *Operation) ListAuthorizations(rw http.ResponseWriter, req *http.Request) {
+	var (
+		vaultID = mux.Vars(req)["vaultID"]
+		values  = req.URL.Query()
+		query   = &vault.AuthorizationQuery{
+			RequestingParty: values.Get("requestingParty"),
+			Next:            values.Get("next"),
+		}
+	)
+
+	if l := values.Get("limit"); l != "" {
+		var err error
+
+		query.Limit, err = strconv.Atoi(l)
+		if err != nil || query.Limit < 1 {
+			o.writeErrorResponse(rw, fmt.Errorf("invalid limit: %s", l), http.StatusBadRequest)
+
+			return
+		}
+	}
+
+	if e := values.Get("includeExpired"); e != "" {
+		var err error
+
+		query.IncludeExpired, err = strconv.ParseBool(e)
+		if err != nil {
+			o.writeErrorResponse(rw, fmt.Errorf("invalid includeExpired: %s", e), http.StatusBadRequest)
+
+			return
+		}
+	}
+
+	result, err := o.vault.ListAuthorizations(vaultID, query)
+	if errors.Is(err, vault.ErrInvalidContinuationToken) {
+		o.writeErrorResponse(rw, err, http.StatusBadRequest)
+
+		return
+	}
+
+	if err != nil {
+		o.writeErrorResponse(rw, err, docErrorStatus(err))
+
+		return
+	}
+
+	var resp listAuthorizationsResp
+	resp.Body = result
+
+	o.WriteResponse(rw, resp.Body, http.StatusOK)
+}
+
+// GetAuditTrail swagger:route GET /vaults/{vaultID}/audit vault getAuditTrailReq
+//
+// Lists the events recorded when the authorizations of the vault were created, retrieved, used and deleted, oldest
+// first. The events do not contain tokens nor document contents.
+//
+// Responses:
+//    default: genericError
+//        200: getAuditTrailResp
+func (o *Operation) GetAuditTrail(rw http.ResponseWriter, req *http.Request) {
+	var (
+		vaultID = mux.Vars(req)["vaultID"]
+		values  = req.URL.Query()
+		query   = &vault.AuditQuery{Next: values.Get("next")}
+	)
+
+	if l := values.Get("limit"); l != "" {
+		var err error
+
+		query.Limit, err = strconv.Atoi(l)
+		if err != nil || query.Limit < 1 {
+			o.writeErrorResponse(rw, fmt.Errorf("invalid limit: %s", l), http.StatusBadRequest)
+
+			return
+		}
+	}
+
+	for _, p := range []struct {
+		name string
+		t    *time.Time
+	}{{"since", &query.Since}, {"until", &query.Until}} {
+		v := values.Get(p.name)
+		if v == "" {
+			continue
+		}
+
+		var err error
+
+		*p.t, err = time.Parse(time.RFC3339, v)
+		if err != nil {
+			o.writeErrorResponse(rw, fmt.Errorf("invalid %s: %s", p.name, v), http.StatusBadRequest)
+
+			return
+		}
+	}
+
+	result, err := o.vault.GetAuditTrail(vaultID, query)
+	if errors.Is(err, vault.ErrInvalidContinuationToken) {
+		o.writeErrorResponse(rw, err, http.StatusBadRequest)
+
+		return
+	}
+
+	if err != nil {
+		o.writeErrorResponse(rw, err, docErrorStatus(err))
+
+		return
+	}
+
+	var resp getAuditTrailResp
+	resp.Body = result
+
+	o.WriteResponse(rw, resp.Body, http.StatusOK)
+}
+
+// GetAuthorization swagger:route GET /vaults/{vaultID}/authorizations/{authID} vault getAuthorizationReq
+//
+// Fetches an authorization.
+//
+// Responses:
+//    default: genericError
+//        200: getAuthorizationResp
+func (o *Operation) GetAuthorization(rw http.ResponseWriter, req *http.Request) {
+	var (
+		vaultID = mux.Vars(req)["vaultID"]
+		authID  = mux.Vars(req)["authID"]
+	)
+
+	result, err := o.vault.GetAuthorization(vaultID, authID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, storage.ErrDataNotFound) {
+			status = http.StatusNotFound
+		}
+
+		o.writeErrorResponse(rw, err, status)
+
+		return
+	}
+
+	var resp createAuthorizationResp
+	resp.Body = result
+
+	o.WriteResponse(rw, resp.Body, http.StatusOK)
+}
+
+// UseAuthorization swagger:route POST /vaults/{vaultID}/authorizations/{authID}/uses vault useAuthorizationReq
+//
+// Records a use of an authorization, by the party exercising it. Fails with 403 once its usage caveat allows no
+// more uses, or if it expired or was revoked.
+//
+// Responses:
+//    default: genericError
+//        200: getAuthorizationResp
+//        403: genericError
+//        404: genericError
+func (o *Operation) UseAuthorization(rw http.ResponseWriter, req *http.Request) {
+	var (
+		vaultID = mux.Vars(req)["vaultID"]
+		authID  = mux.Vars(req)["authID"]
+	)
+
+	result, err := o.vault.UseAuthorization(vaultID, authID)
+	if err != nil {
+		o.writeErrorResponse(rw, err, useAuthorizationErrorStatus(err))
+
+		return
+	}
+
+	var resp getAuthorizationResp
+	resp.Body = result
+
+	o.WriteResponse(rw, resp.Body, http.StatusOK)
+}
+
+// useAuthorizationErrorStatus maps authorizations that cannot be used anymore to 403 and unknown ones to 404.
+func useAuthorizationErrorStatus(err error) int {
+	if errors.Is(err, vault.ErrAuthorizationExhausted) || errors.Is(err, vault.ErrAuthorizationInactive) {
+		return http.StatusForbidden
+	}
+
+	return docErrorStatus(err)
+}
+
+// DeleteAuthorization swagger:route DELETE /vaults/{vaultID}/authorizations/{authID} vault deleteAuthorizationReq
+//
+// Deletes an authorization and revokes its zcaps.
+//
+// Responses:
+//    default: genericError
+//        200: deleteAuthorizationResp
+//        404: genericError
+func (o *Operation) DeleteAuthorization(rw http.ResponseWriter, req *http.Request) {
+	var (
+		vaultID = mux.Vars(req)["vaultID"]
+		authID  = mux.Vars(req)["authID"]
+	)
+
+	err := o.vault.DeleteAuthorization(vaultID, authID)
+	if err != nil {
+		o.writeErrorResponse(rw, err, docErrorStatus(err))
+
+		return
+	}
+
+	rw.WriteHeader(http.StatusOK)
+}
+
+// GetRevocation swagger:route GET /revocations/{zcapID} vault getRevocationReq
+//
+// Returns the revocation of a zcap issued by the vault server.
+//
+// Responses:
+//    default: genericError
+//        200: getRevocationResp
+//        404: genericError
+func (o *Operation) GetRevocation(rw http.ResponseWriter, req *http.Request) {
+	result, err := o.vault.GetRevocation(mux.Vars(req)["zcapID"])
+	if err != nil {
+		o.writeErrorResponse(rw, err, docErrorStatus(err))
+
+		return
+	}
+
+	var resp getRevocationResp
+	resp.Body = result
+
+	o.WriteResponse(rw, resp.Body, http.StatusOK)
+}
+
+// docErrorStatus maps unknown vaults and documents to 404.
+func docErrorStatus(err error) int {
+	if errors.Is(err, storage.ErrDataNotFound) ||
+		errors.Is(err, vault.ErrDocumentNotFound) || errors.Is(err, vault.ErrVaultNotFound) {
+		return http.StatusNotFound
+	}
+
+	return http.StatusInternalServerError
+}
+
+// isAdmin reports whether the request carries the admin token.
+func (o *Operation) isAdmin(req *http.Request) bool {
+	return o.adminToken != "" &&
+		subtle.ConstantTimeCompare([]byte(req.Header.Get("Authorization")), []byte("Bearer "+o.adminToken)) == 1
+}
+
+// createVaultErrorStatus maps invalid controllers, unknown EDV backends, unsupported key types and KMS URLs that
+// are not allowed to 400, requests not signed by the verification method to 401 and controllers that already have a
+// vault to 409.
+func createVaultErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, vault.ErrInvalidController), errors.Is(err, vault.ErrUnknownEDVBackend),
+		errors.Is(err, vault.ErrUnsupportedKeyType), errors.Is(err, vault.ErrKMSURLNotAllowed),
+		errors.Is(err, vault.ErrReferenceWithoutController):
+		return http.StatusBadRequest
+	case errors.Is(err, vault.ErrVaultExists):
+		return http.StatusConflict
+	case errors.Is(err, vault.ErrUnauthorizedInvocation):
+		return http.StatusUnauthorized
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// createWebhookErrorStatus maps invalid webhooks to 400, unknown vaults to 404 and vaults being deleted to 409.
+func createWebhookErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, vault.ErrInvalidWebhook):
+		return http.StatusBadRequest
+	case errors.Is(err, storage.ErrDataNotFound):
+		return http.StatusNotFound
+	default:
+		return writeErrorStatus(err)
+	}
+}
+
+// writeErrorStatus maps writes to a vault being deleted to 409.
+func writeErrorStatus(err error) int {
+	if errors.Is(err, vault.ErrVaultDeleting) {
+		return http.StatusConflict
+	}
+
+	return http.StatusInternalServerError
+}
+
+func (o *Operation) writeErrorResponse(rw http.ResponseWriter, err error, status int) {
+	logger.Errorf("%v", err)
+
+	resp := model.ErrorResponse{
+		Message: err.Error(),
+	}
+
+	// the vault server cannot act on the documents of vaults controlled by an existing DID
+	if errors.Is(err, vault.ErrExternallyControlled) && status >= http.StatusInternalServerError {
+		status = http.StatusConflict
+	}
+
+	// the refusals of upstream servers are not internal errors: retrying does not help
+	var refusal *vault.UpstreamAuthError
+	if errors.As(err, &refusal) && status >= http.StatusInternalServerError {
+		status = http.StatusFailedDependency
+		resp.Upstream = refusal.Upstream
+		resp.Hint = refusal.Hint()
+	}
+
+	resp.Code = errorCode(err, status)
+
+	o.WriteResponse(rw, resp, status)
+}
+
+// errorCode returns the code of errors more specific than the one of the status code.
+func errorCode(err error, status int) model.ErrorCode {
+	switch {
+	case errors.Is(err, vault.ErrVaultDeleting):
+		return model.ErrCodeVaultDeleting
+	case status == http.StatusFailedDependency && errors.Is(err, vault.ErrUpstreamUnauthorized):
+		return model.ErrCodeUpstreamUnauthorized
+	}
+
+	return model.StatusErrorCode(status)
 }
 
 // WriteResponse writes response.
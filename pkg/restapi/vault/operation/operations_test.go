@@ -8,23 +8,29 @@ package operation_test
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/hyperledger/aries-framework-go/spi/storage"
 	"github.com/stretchr/testify/require"
-	"github.com/trustbloc/edv/pkg/restapi/messages"
+	"github.com/trustbloc/edv/pkg/edvutils"
 
 	"github.com/trustbloc/ace/pkg/restapi/handler"
 	"github.com/trustbloc/ace/pkg/restapi/model"
+	"github.com/trustbloc/ace/pkg/restapi/mw/gzipmw"
 	"github.com/trustbloc/ace/pkg/restapi/vault"
 	vaultoperation "github.com/trustbloc/ace/pkg/restapi/vault/operation"
 )
@@ -71,305 +77,3103 @@ func TestCreateVault(t *testing.T) {
 		require.NotEmpty(t, resp.EDV.URI)
 		require.NotEmpty(t, resp.EDV.AuthToken)
 	})
+
+	t.Run("Create vault with controller", func(t *testing.T) {
+		v := newVaultMock()
+		v.createWithControlFn = func(controller, verificationMethod string,
+			req *http.Request) (*vault.CreatedVault, error) {
+			require.Equal(t, "did:example:123", controller)
+			require.Equal(t, "#key-1", verificationMethod)
+
+			// the body is left for the verification of the signature
+			body, err := io.ReadAll(req.Body)
+			require.NoError(t, err)
+			require.JSONEq(t, `{"controller":"did:example:123","verificationMethod":"#key-1"}`, string(body))
+
+			return &vault.CreatedVault{ID: controller}, nil
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.CreateVaultPath, http.MethodPost)
+
+		respBody, code := sendRequestToHandler(t, h,
+			strings.NewReader(`{"controller":"did:example:123","verificationMethod":"#key-1"}`), path)
+
+		require.Equal(t, http.StatusCreated, code)
+
+		var resp *vault.CreatedVault
+
+		require.NoError(t, json.NewDecoder(respBody).Decode(&resp))
+		require.Equal(t, "did:example:123", resp.ID)
+	})
+
+	t.Run("Controller errors", func(t *testing.T) {
+		for err, status := range map[error]int{
+			fmt.Errorf("%w: unknown verification method", vault.ErrInvalidController):        http.StatusBadRequest,
+			fmt.Errorf("%w: an HTTP signature is required", vault.ErrUnauthorizedInvocation): http.StatusUnauthorized,
+			vault.ErrVaultExists: http.StatusConflict,
+		} {
+			v := newVaultMock()
+			v.createWithControlFn = func(string, string, *http.Request) (*vault.CreatedVault, error) {
+				return nil, err
+			}
+
+			operation := vaultoperation.New(v)
+
+			h := handlerLookup(t, operation, vaultoperation.CreateVaultPath, http.MethodPost)
+
+			respBody, code := sendRequestToHandler(t, h, strings.NewReader(`{"controller":"did:example:123"}`), path)
+
+			require.Equal(t, status, code)
+			require.Contains(t, respBody.String(), err.Error())
+		}
+	})
+
+	t.Run("Body too large", func(t *testing.T) {
+		v := newVaultMock()
+		v.createVaultFn = func() (*vault.CreatedVault, error) {
+			return nil, errors.New("unexpected call")
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.CreateVaultPath, http.MethodPost)
+
+		respBody, code := sendRequestToHandler(t, h,
+			strings.NewReader(`{"referenceId":"`+strings.Repeat("a", 64<<10)+`"}`), path)
+
+		require.Equal(t, http.StatusRequestEntityTooLarge, code)
+		require.Contains(t, respBody.String(), "request body exceeds the maximum size of 65536 bytes")
+	})
+
+	t.Run("Bad request", func(t *testing.T) {
+		operation := vaultoperation.New(newVaultMock())
+
+		h := handlerLookup(t, operation, vaultoperation.CreateVaultPath, http.MethodPost)
+
+		_, code := sendRequestToHandler(t, h, strings.NewReader("{"), path)
+
+		require.Equal(t, http.StatusBadRequest, code)
+	})
+
+	t.Run("Pin EDV backend", func(t *testing.T) {
+		v := newVaultMock()
+
+		operation := vaultoperation.New(v, vaultoperation.WithAdminToken("admin"))
+
+		h := handlerLookup(t, operation, vaultoperation.CreateVaultPath, http.MethodPost)
+
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"edvBackend":"new"}`))
+		req.Header.Set("Authorization", "Bearer admin")
+
+		rr := serveRequest(h, req)
+
+		require.Equal(t, http.StatusCreated, rr.Code)
+		require.Len(t, v.createVaultOpts, 1)
+	})
+
+	t.Run("Pin EDV backend without the admin token", func(t *testing.T) {
+		for _, tc := range []struct {
+			name       string
+			adminToken string
+			header     string
+		}{
+			{name: "missing token", adminToken: "admin"},
+			{name: "wrong token", adminToken: "admin", header: "Bearer other"},
+			{name: "no admin token configured", header: "Bearer "},
+		} {
+			v := newVaultMock()
+			v.createVaultFn = func() (*vault.CreatedVault, error) {
+				require.FailNow(t, "vault must not be created")
+
+				return nil, nil
+			}
+
+			operation := vaultoperation.New(v, vaultoperation.WithAdminToken(tc.adminToken))
+
+			h := handlerLookup(t, operation, vaultoperation.CreateVaultPath, http.MethodPost)
+
+			req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"edvBackend":"new"}`))
+			req.Header.Set("Authorization", tc.header)
+
+			rr := serveRequest(h, req)
+
+			require.Equal(t, http.StatusUnauthorized, rr.Code, tc.name)
+			require.Contains(t, rr.Body.String(), "edvBackend requires the admin token", tc.name)
+		}
+	})
+
+	t.Run("Unknown EDV backend", func(t *testing.T) {
+		v := newVaultMock()
+		v.createVaultFn = func() (*vault.CreatedVault, error) {
+			return nil, fmt.Errorf("%w: new", vault.ErrUnknownEDVBackend)
+		}
+
+		operation := vaultoperation.New(v, vaultoperation.WithAdminToken("admin"))
+
+		h := handlerLookup(t, operation, vaultoperation.CreateVaultPath, http.MethodPost)
+
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"edvBackend":"new"}`))
+		req.Header.Set("Authorization", "Bearer admin")
+
+		rr := serveRequest(h, req)
+
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "unknown EDV backend")
+	})
+
+	t.Run("Key type", func(t *testing.T) {
+		v := newVaultMock()
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.CreateVaultPath, http.MethodPost)
+
+		rr := serveRequest(h, httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"keyType":"X25519ECDHKW"}`)))
+
+		require.Equal(t, http.StatusCreated, rr.Code)
+		require.Len(t, v.createVaultOpts, 1)
+	})
+
+	t.Run("Existing vault of the reference ID", func(t *testing.T) {
+		v := newVaultMock()
+		v.createVaultFn = func() (*vault.CreatedVault, error) {
+			return &vault.CreatedVault{ID: "vaultID1", Existing: true}, nil
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.CreateVaultPath, http.MethodPost)
+
+		rr := serveRequest(h, httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"referenceId":"ref"}`)))
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.JSONEq(t, `{"id":"vaultID1"}`, rr.Body.String())
+		require.Len(t, v.createVaultOpts, 1)
+	})
+
+	t.Run("Reference ID without an owner", func(t *testing.T) {
+		v := newVaultMock()
+		v.createVaultFn = func() (*vault.CreatedVault, error) {
+			return nil, vault.ErrReferenceWithoutController
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.CreateVaultPath, http.MethodPost)
+
+		rr := serveRequest(h, httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"referenceId":"ref"}`)))
+
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "reference ID requires")
+	})
+
+	t.Run("Unsupported key type", func(t *testing.T) {
+		v := newVaultMock()
+		v.createVaultFn = func() (*vault.CreatedVault, error) {
+			return nil, fmt.Errorf("%w: RSA", vault.ErrUnsupportedKeyType)
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.CreateVaultPath, http.MethodPost)
+
+		rr := serveRequest(h, httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"keyType":"RSA"}`)))
+
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "unsupported key type")
+	})
+
+	t.Run("KMS URL not allowed", func(t *testing.T) {
+		v := newVaultMock()
+		v.createVaultFn = func() (*vault.CreatedVault, error) {
+			return nil, fmt.Errorf("%w: https://kms.example.com", vault.ErrKMSURLNotAllowed)
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.CreateVaultPath, http.MethodPost)
+
+		rr := serveRequest(h, httptest.NewRequest(http.MethodPost, path,
+			strings.NewReader(`{"kmsURL":"https://kms.example.com"}`)))
+
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "KMS URL not allowed")
+		require.Len(t, v.createVaultOpts, 1)
+	})
+}
+
+func TestExportVault(t *testing.T) {
+	const path = "/vaults/vaultID1/export"
+
+	t.Run("Success", func(t *testing.T) {
+		operation := vaultoperation.New(newVaultMock())
+
+		h := handlerLookup(t, operation, vaultoperation.ExportVaultPath, http.MethodGet)
+
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, path, nil)
+		require.NoError(t, err)
+
+		rr := serveRequest(h, req)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, "application/x-ndjson", rr.Header().Get("Content-Type"))
+
+		var entry vault.ArchiveEntry
+
+		dec := json.NewDecoder(rr.Body)
+		require.NoError(t, dec.Decode(&entry))
+		require.Equal(t, "vaultID1", entry.Vault.ID)
+		require.False(t, dec.More())
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		v := newVaultMock()
+		v.exportVaultFn = func(string) (*vault.VaultExport, error) {
+			return nil, fmt.Errorf("get vault info: %w", storage.ErrDataNotFound)
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.ExportVaultPath, http.MethodGet)
+		res, code := sendRequestToHandler(t, h, nil, path)
+
+		require.Equal(t, http.StatusNotFound, code)
+
+		var errResp *model.ErrorResponse
+
+		require.NoError(t, json.NewDecoder(res).Decode(&errResp))
+		require.Equal(t, model.ErrCodeNotFound, errResp.Code)
+	})
+}
+
+func TestImportVault(t *testing.T) {
+	const path = "/vaults/import"
+
+	t.Run("Success", func(t *testing.T) {
+		var archive []byte
+
+		v := newVaultMock()
+		v.importVaultFn = func(r io.Reader) (*vault.VaultImport, error) {
+			var err error
+
+			archive, err = io.ReadAll(r)
+			require.NoError(t, err)
+
+			return &vault.VaultImport{
+				CreatedVault: &vault.CreatedVault{ID: "did:key:new"},
+				SourceID:     "did:key:old",
+				Imported:     1,
+				Failures:     []*vault.ImportFailure{{DocID: "doc2", Error: "missing JWE"}},
+			}, nil
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.ImportVaultPath, http.MethodPost)
+		res, code := sendRequestToHandler(t, h, strings.NewReader(`{"vault":{"id":"did:key:old"}}`), path)
+
+		require.Equal(t, http.StatusCreated, code)
+		require.Equal(t, `{"vault":{"id":"did:key:old"}}`, string(archive))
+
+		var result vault.VaultImport
+
+		require.NoError(t, json.NewDecoder(res).Decode(&result))
+		require.Equal(t, "did:key:new", result.ID)
+		require.Equal(t, 1, result.Imported)
+		require.Equal(t, "doc2", result.Failures[0].DocID)
+	})
+
+	t.Run("Invalid archive", func(t *testing.T) {
+		v := newVaultMock()
+		v.importVaultFn = func(io.Reader) (*vault.VaultImport, error) {
+			return nil, fmt.Errorf("%w: EOF", vault.ErrInvalidArchive)
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.ImportVaultPath, http.MethodPost)
+		_, code := sendRequestToHandler(t, h, strings.NewReader(""), path)
+
+		require.Equal(t, http.StatusBadRequest, code)
+	})
+
+	t.Run("Keystore of the exported vault not available", func(t *testing.T) {
+		v := newVaultMock()
+		v.importVaultFn = func(io.Reader) (*vault.VaultImport, error) {
+			return nil, fmt.Errorf("%w: unknown vault did:key:old", vault.ErrSourceKeystoreUnavailable)
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.ImportVaultPath, http.MethodPost)
+		_, code := sendRequestToHandler(t, h, strings.NewReader(`{"vault":{"id":"did:key:old"}}`), path)
+
+		require.Equal(t, http.StatusBadRequest, code)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		v := newVaultMock()
+		v.importVaultFn = func(io.Reader) (*vault.VaultImport, error) {
+			return nil, errors.New("create vault: test")
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.ImportVaultPath, http.MethodPost)
+		_, code := sendRequestToHandler(t, h, strings.NewReader(""), path)
+
+		require.Equal(t, http.StatusInternalServerError, code)
+	})
+}
+
+func TestRekeyVault(t *testing.T) {
+	const path = "/vaults/vaultID1/rekey"
+
+	t.Run("Success", func(t *testing.T) {
+		operation := vaultoperation.New(newVaultMock())
+
+		h := handlerLookup(t, operation, vaultoperation.RekeyVaultPath, http.MethodPost)
+		res, code := sendRequestToHandler(t, h, nil, path)
+
+		require.Equal(t, http.StatusOK, code)
+
+		var rekey *vault.VaultRekey
+
+		require.NoError(t, json.NewDecoder(res).Decode(&rekey))
+		require.Equal(t, "vaultID1", rekey.ID)
+		require.True(t, rekey.Complete)
+	})
+
+	t.Run("Incomplete", func(t *testing.T) {
+		v := newVaultMock()
+		v.rekeyVaultFn = func(vaultID string) (*vault.VaultRekey, error) {
+			return &vault.VaultRekey{ID: vaultID, Total: 2, Rekeyed: 1, Error: "re-encrypt document doc2: EDV error"}, nil
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.RekeyVaultPath, http.MethodPost)
+		res, code := sendRequestToHandler(t, h, nil, path)
+
+		require.Equal(t, http.StatusBadGateway, code)
+
+		var rekey *vault.VaultRekey
+
+		require.NoError(t, json.NewDecoder(res).Decode(&rekey))
+		require.False(t, rekey.Complete)
+		require.Contains(t, rekey.Error, "doc2")
+	})
+
+	t.Run("Vault being deleted", func(t *testing.T) {
+		v := newVaultMock()
+		v.rekeyVaultFn = func(string) (*vault.VaultRekey, error) {
+			return nil, vault.ErrVaultDeleting
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.RekeyVaultPath, http.MethodPost)
+		_, code := sendRequestToHandler(t, h, nil, path)
+
+		require.Equal(t, http.StatusConflict, code)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		v := newVaultMock()
+		v.rekeyVaultFn = func(string) (*vault.VaultRekey, error) {
+			return nil, fmt.Errorf("get vault info: %w", storage.ErrDataNotFound)
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.RekeyVaultPath, http.MethodPost)
+		_, code := sendRequestToHandler(t, h, nil, path)
+
+		require.Equal(t, http.StatusNotFound, code)
+	})
+}
+
+func TestRotateTokens(t *testing.T) {
+	const path = "/vaults/vaultID1/rotate-tokens"
+
+	t.Run("Success", func(t *testing.T) {
+		operation := vaultoperation.New(newVaultMock())
+
+		h := handlerLookup(t, operation, vaultoperation.RotateTokensPath, http.MethodPost)
+		res, code := sendRequestToHandler(t, h, nil, path)
+
+		require.Equal(t, http.StatusOK, code)
+
+		var rotated *vault.CreatedVault
+
+		require.NoError(t, json.NewDecoder(res).Decode(&rotated))
+		require.Equal(t, "vaultID1", rotated.ID)
+		require.Equal(t, "edv-token", rotated.EDV.AuthToken)
+		require.Equal(t, "kms-token", rotated.KMS.AuthToken)
+	})
+
+	t.Run("Vault being deleted", func(t *testing.T) {
+		v := newVaultMock()
+		v.rotateTokensFn = func(string) (*vault.CreatedVault, error) {
+			return nil, vault.ErrVaultDeleting
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.RotateTokensPath, http.MethodPost)
+		_, code := sendRequestToHandler(t, h, nil, path)
+
+		require.Equal(t, http.StatusConflict, code)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		v := newVaultMock()
+		v.rotateTokensFn = func(string) (*vault.CreatedVault, error) {
+			return nil, fmt.Errorf("get vault info: %w", storage.ErrDataNotFound)
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.RotateTokensPath, http.MethodPost)
+		_, code := sendRequestToHandler(t, h, nil, path)
+
+		require.Equal(t, http.StatusNotFound, code)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		v := newVaultMock()
+		v.rotateTokensFn = func(string) (*vault.CreatedVault, error) {
+			return nil, errors.New("save tokens: test")
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.RotateTokensPath, http.MethodPost)
+		_, code := sendRequestToHandler(t, h, nil, path)
+
+		require.Equal(t, http.StatusInternalServerError, code)
+	})
+}
+
+func TestRekeyDoc(t *testing.T) {
+	const path = "/vaults/vaultID1/docs/doc1/rekey"
+
+	t.Run("Success", func(t *testing.T) {
+		operation := vaultoperation.New(newVaultMock())
+
+		h := handlerLookup(t, operation, vaultoperation.RekeyDocPath, http.MethodPost)
+		res, code := sendRequestToHandler(t, h, nil, path)
+
+		require.Equal(t, http.StatusOK, code)
+
+		var meta *vault.DocumentMetadata
+
+		require.NoError(t, json.NewDecoder(res).Decode(&meta))
+		require.Equal(t, "doc1", meta.ID)
+		require.Equal(t, uint64(1), meta.Sequence)
+	})
+
+	for _, tc := range []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"Vault being deleted", vault.ErrVaultDeleting, http.StatusConflict},
+		{"Not found", fmt.Errorf("get meta doc info: %w", storage.ErrDataNotFound), http.StatusNotFound},
+		{"Re-encryption failure", errors.New("re-encrypt document: EDV error"), http.StatusInternalServerError},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			v := newVaultMock()
+			v.rekeyDocFn = func(string, string) (*vault.DocumentMetadata, error) {
+				return nil, tc.err
+			}
+
+			operation := vaultoperation.New(v)
+
+			h := handlerLookup(t, operation, vaultoperation.RekeyDocPath, http.MethodPost)
+			_, code := sendRequestToHandler(t, h, nil, path)
+
+			require.Equal(t, tc.status, code)
+		})
+	}
+}
+
+func TestGetRekeyStatus(t *testing.T) {
+	const path = "/vaults/vaultID1/rekey"
+
+	t.Run("Success", func(t *testing.T) {
+		operation := vaultoperation.New(newVaultMock())
+
+		h := handlerLookup(t, operation, vaultoperation.GetRekeyStatusPath, http.MethodGet)
+		res, code := sendRequestToHandler(t, h, nil, path)
+
+		require.Equal(t, http.StatusOK, code)
+
+		var rekey *vault.VaultRekey
+
+		require.NoError(t, json.NewDecoder(res).Decode(&rekey))
+		require.Equal(t, 2, rekey.Total)
+		require.Equal(t, 1, rekey.Rekeyed)
+	})
+
+	t.Run("Never rekeyed", func(t *testing.T) {
+		v := newVaultMock()
+		v.getRekeyStatusFn = func(string) (*vault.VaultRekey, error) {
+			return nil, fmt.Errorf("get rekey: %w", storage.ErrDataNotFound)
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.GetRekeyStatusPath, http.MethodGet)
+		_, code := sendRequestToHandler(t, h, nil, path)
+
+		require.Equal(t, http.StatusNotFound, code)
+	})
+}
+
+func TestSaveDoc(t *testing.T) {
+	t.Run("Error", func(t *testing.T) {
+		const path = "/vaults/vaultID1/docs"
+
+		v := newVaultMock()
+		v.saveDocFn = func(vaultID, id string, content interface{}) (*vault.DocumentMetadata, error) {
+			return nil, errors.New("test")
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.SaveDocPath, http.MethodPost)
+		res, code := sendRequestToHandler(t, h, strings.NewReader(`{}`), path)
+
+		require.Equal(t, http.StatusInternalServerError, code)
+
+		var errResp *model.ErrorResponse
+
+		require.NoError(t, json.NewDecoder(res).Decode(&errResp))
+	})
+	t.Run("Vault being deleted", func(t *testing.T) {
+		const path = "/vaults/vaultID1/docs"
+
+		v := newVaultMock()
+		v.saveDocFn = func(vaultID, id string, content interface{}) (*vault.DocumentMetadata, error) {
+			return nil, vault.ErrVaultDeleting
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.SaveDocPath, http.MethodPost)
+		res, code := sendRequestToHandler(t, h, strings.NewReader(`{}`), path)
+
+		require.Equal(t, http.StatusConflict, code)
+
+		var errResp *model.ErrorResponse
+
+		require.NoError(t, json.NewDecoder(res).Decode(&errResp))
+		require.Equal(t, model.ErrCodeVaultDeleting, errResp.Code)
+	})
+	t.Run("Upstream authorization refused", func(t *testing.T) {
+		v := newVaultMock()
+		v.saveDocFn = func(vaultID, id string, content interface{}) (*vault.DocumentMetadata, error) {
+			return nil, fmt.Errorf("encrypt: %w", &vault.UpstreamAuthError{
+				Upstream:   vault.UpstreamKMS,
+				StatusCode: http.StatusUnauthorized,
+			})
+		}
+
+		h := handlerLookup(t, vaultoperation.New(v), vaultoperation.SaveDocPath, http.MethodPost)
+		res, code := sendRequestToHandler(t, h, strings.NewReader(`{"id":"doc1","content":{}}`), "/vaults/vaultID1/docs")
+
+		require.Equal(t, http.StatusFailedDependency, code)
+
+		var errResp *model.ErrorResponse
+
+		require.NoError(t, json.NewDecoder(res).Decode(&errResp))
+		require.Equal(t, model.ErrCodeUpstreamUnauthorized, errResp.Code)
+		require.Equal(t, vault.UpstreamKMS, errResp.Upstream)
+		require.Equal(t, "vault keystore authorization expired or invalid; re-create authorization", errResp.Hint)
+	})
+	t.Run("Sequence mismatch", func(t *testing.T) {
+		v := newVaultMock()
+		v.saveDocFn = func(vaultID, id string, content interface{}) (*vault.DocumentMetadata, error) {
+			return nil, fmt.Errorf("save: %w", &vault.SequenceMismatchError{Expected: 2, Current: 3})
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.SaveDocPath, http.MethodPost)
+
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodPost,
+			"/vaults/vaultID1/docs", strings.NewReader(`{"id":"doc1","content":{}}`))
+		require.NoError(t, err)
+
+		req.Header.Set("If-Match", `"2"`)
+
+		rr := serveRequest(h, req)
+
+		require.Equal(t, http.StatusConflict, rr.Code)
+		require.Len(t, v.saveDocOpts, 1)
+
+		var conflict vaultoperation.SequenceConflict
+
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&conflict))
+		require.Equal(t, uint64(3), conflict.Sequence)
+		require.Equal(t, model.ErrCodeSequenceMismatch, conflict.Code)
+		require.Contains(t, conflict.Message, "sequence mismatch: expected 2, current 3")
+	})
+	t.Run("If-Match on a missing document", func(t *testing.T) {
+		v := newVaultMock()
+		v.saveDocFn = func(vaultID, id string, content interface{}) (*vault.DocumentMetadata, error) {
+			return nil, fmt.Errorf("%w: doc1", vault.ErrExpectedDocumentNotFound)
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.SaveDocPath, http.MethodPost)
+
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodPost,
+			"/vaults/vaultID1/docs", strings.NewReader(`{"id":"doc1","content":{}}`))
+		require.NoError(t, err)
+
+		req.Header.Set("If-Match", `"0"`)
+
+		rr := serveRequest(h, req)
+
+		require.Equal(t, http.StatusPreconditionFailed, rr.Code)
+		require.Contains(t, rr.Body.String(), "expected document not found: doc1")
+	})
+	t.Run("Without If-Match", func(t *testing.T) {
+		v := newVaultMock()
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.SaveDocPath, http.MethodPost)
+		_, code := sendRequestToHandler(t, h, strings.NewReader(`{}`), "/vaults/vaultID1/docs")
+
+		require.Equal(t, http.StatusCreated, code)
+		require.Empty(t, v.saveDocOpts)
+	})
+	t.Run("Index tags", func(t *testing.T) {
+		v := newVaultMock()
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.SaveDocPath, http.MethodPost)
+		_, code := sendRequestToHandler(t, h, strings.NewReader(`{"content":{},"indexTags":{"type":"license"}}`),
+			"/vaults/vaultID1/docs")
+
+		require.Equal(t, http.StatusCreated, code)
+		require.Len(t, v.saveDocOpts, 1)
+	})
+	t.Run("Invalid index tag", func(t *testing.T) {
+		v := newVaultMock()
+		v.saveDocFn = func(string, string, interface{}) (*vault.DocumentMetadata, error) {
+			return nil, fmt.Errorf("index tags: %w: name and value are required", vault.ErrInvalidIndexTag)
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.SaveDocPath, http.MethodPost)
+		_, code := sendRequestToHandler(t, h, strings.NewReader(`{"content":{},"indexTags":{"type":""}}`),
+			"/vaults/vaultID1/docs")
+
+		require.Equal(t, http.StatusBadRequest, code)
+	})
+	t.Run("Invalid credential", func(t *testing.T) {
+		v := newVaultMock()
+		v.saveDocFn = func(string, string, interface{}) (*vault.DocumentMetadata, error) {
+			return nil, fmt.Errorf("%w: no proof", vault.ErrInvalidCredential)
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.SaveDocPath, http.MethodPost)
+		_, code := sendRequestToHandler(t, h,
+			strings.NewReader(`{"content":{"type":["VerifiableCredential"]}}`), "/vaults/vaultID1/docs")
+
+		require.Equal(t, http.StatusBadRequest, code)
+	})
+	t.Run("Controller recipient", func(t *testing.T) {
+		v := newVaultMock()
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.SaveDocPath, http.MethodPost)
+		_, code := sendRequestToHandler(t, h, strings.NewReader(`{"content":{}}`),
+			"/vaults/vaultID1/docs?controllerRecipient=true")
+
+		require.Equal(t, http.StatusCreated, code)
+		require.Len(t, v.saveDocOpts, 1)
+
+		_, code = sendRequestToHandler(t, h, strings.NewReader(`{"content":{}}`),
+			"/vaults/vaultID1/docs?controllerRecipient=false")
+
+		require.Equal(t, http.StatusCreated, code)
+		require.Empty(t, v.saveDocOpts)
+	})
+	t.Run("Invalid controller recipient", func(t *testing.T) {
+		v := newVaultMock()
+		v.saveDocFn = func(string, string, interface{}) (*vault.DocumentMetadata, error) {
+			return nil, fmt.Errorf("%w: did:example:123 has no key agreement key", vault.ErrInvalidController)
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.SaveDocPath, http.MethodPost)
+		_, code := sendRequestToHandler(t, h, strings.NewReader(`{"content":{}}`),
+			"/vaults/vaultID1/docs?controllerRecipient=true")
+
+		require.Equal(t, http.StatusBadRequest, code)
+
+		_, code = sendRequestToHandler(t, h, strings.NewReader(`{"content":{}}`),
+			"/vaults/vaultID1/docs?controllerRecipient=maybe")
+
+		require.Equal(t, http.StatusBadRequest, code)
+	})
+	t.Run("Invalid If-Match", func(t *testing.T) {
+		operation := vaultoperation.New(newVaultMock())
+
+		h := handlerLookup(t, operation, vaultoperation.SaveDocPath, http.MethodPost)
+
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodPost,
+			"/vaults/vaultID1/docs", strings.NewReader(`{}`))
+		require.NoError(t, err)
+
+		req.Header.Set("If-Match", "*")
+
+		rr := serveRequest(h, req)
+
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid If-Match header")
+	})
+	t.Run("JSON error", func(t *testing.T) {
+		const path = "/vaults/vaultID1/docs"
+
+		operation := vaultoperation.New(newVaultMock())
+
+		h := handlerLookup(t, operation, vaultoperation.SaveDocPath, http.MethodPost)
+		res, code := sendRequestToHandler(t, h, strings.NewReader(`{`), path)
+
+		require.Equal(t, http.StatusBadRequest, code)
+
+		var errResp *model.ErrorResponse
+
+		require.NoError(t, json.NewDecoder(res).Decode(&errResp))
+		require.Contains(t, errResp.Message, "unexpected EOF")
+	})
+	t.Run("Success (binary)", func(t *testing.T) {
+		var (
+			gotID, gotMediaType string
+			gotContent          []byte
+		)
+
+		v := newVaultMock()
+		v.saveBinaryDocFn = func(_, id, mediaType string, content []byte) (*vault.DocumentMetadata, error) {
+			gotID, gotMediaType, gotContent = id, mediaType, content
+
+			return &vault.DocumentMetadata{ID: id}, nil
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.SaveDocPath, http.MethodPost)
+
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodPost,
+			"/vaults/vaultID1/docs?id=doc1", bytes.NewReader([]byte{0x25, 0x50, 0x44, 0x46, 0x00}))
+		require.NoError(t, err)
+
+		req.Header.Set("Content-Type", "application/pdf")
+
+		rr := serveRequest(h, req)
+
+		require.Equal(t, http.StatusCreated, rr.Code)
+		require.Equal(t, "doc1", gotID)
+		require.Equal(t, "application/pdf", gotMediaType)
+		require.Equal(t, []byte{0x25, 0x50, 0x44, 0x46, 0x00}, gotContent)
+	})
+	t.Run("Binary content too large", func(t *testing.T) {
+		operation := vaultoperation.New(newVaultMock())
+
+		h := handlerLookup(t, operation, vaultoperation.SaveDocPath, http.MethodPost)
+
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodPost,
+			"/vaults/vaultID1/docs", bytes.NewReader(make([]byte, 10<<20+1)))
+		require.NoError(t, err)
+
+		req.Header.Set("Content-Type", "application/octet-stream")
+
+		rr := serveRequest(h, req)
+
+		require.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+	})
+	t.Run("Max doc size", func(t *testing.T) {
+		const maxDocSize = 64
+
+		doc := `{"id":"doc1","content":{"message":"Hello World!"}}`
+		doc += strings.Repeat(" ", maxDocSize-len(doc))
+
+		for _, tc := range []struct {
+			name        string
+			contentType string
+			body        string
+		}{
+			{"JSON", "application/json", doc},
+			{"binary", "application/octet-stream", strings.Repeat("a", maxDocSize)},
+		} {
+			var saved int
+
+			v := newVaultMock()
+			v.saveDocFn = func(string, string, interface{}) (*vault.DocumentMetadata, error) {
+				saved++
+
+				return &vault.DocumentMetadata{}, nil
+			}
+			v.saveBinaryDocFn = func(string, string, string, []byte) (*vault.DocumentMetadata, error) {
+				saved++
+
+				return &vault.DocumentMetadata{}, nil
+			}
+
+			operation := vaultoperation.New(v, vaultoperation.WithMaxDocSize(maxDocSize))
+
+			h := handlerLookup(t, operation, vaultoperation.SaveDocPath, http.MethodPost)
+
+			send := func(body string) *httptest.ResponseRecorder {
+				req, err := http.NewRequestWithContext(context.Background(), http.MethodPost,
+					"/vaults/vaultID1/docs", strings.NewReader(body))
+				require.NoError(t, err)
+
+				req.Header.Set("Content-Type", tc.contentType)
+
+				return serveRequest(h, req)
+			}
+
+			rr := send(tc.body)
+			require.Equal(t, http.StatusCreated, rr.Code, tc.name)
+			require.Equal(t, 1, saved, tc.name)
+
+			rr = send(tc.body + " ")
+			require.Equal(t, http.StatusRequestEntityTooLarge, rr.Code, tc.name)
+			require.Contains(t, rr.Body.String(), "document exceeds the maximum size of 64 bytes", tc.name)
+			require.Equal(t, 1, saved, tc.name)
+		}
+	})
+	t.Run("Body read error", func(t *testing.T) {
+		operation := vaultoperation.New(newVaultMock(), vaultoperation.WithMaxDocSize(64))
+
+		h := handlerLookup(t, operation, vaultoperation.SaveDocPath, http.MethodPost)
+
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodPost,
+			"/vaults/vaultID1/docs", iotest.ErrReader(errors.New("connection reset")))
+		require.NoError(t, err)
+
+		rr := serveRequest(h, req)
+
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "read body: connection reset")
+	})
+	t.Run("Gzip body too large", func(t *testing.T) {
+		operation := vaultoperation.New(newVaultMock(), vaultoperation.WithMaxDocSize(64))
+
+		h := handlerLookup(t, operation, vaultoperation.SaveDocPath, http.MethodPost)
+
+		router := mux.NewRouter()
+		router.Use(gzipmw.New(gzipmw.DefaultMaxDecompressedSize))
+		router.HandleFunc(h.Path(), h.Handle()).Methods(h.Method())
+
+		body := &bytes.Buffer{}
+		zw := gzip.NewWriter(body)
+
+		_, err := zw.Write(make([]byte, 1<<20))
+		require.NoError(t, err)
+		require.NoError(t, zw.Close())
+
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, "/vaults/vaultID1/docs", body)
+		require.NoError(t, err)
+
+		req.Header.Set("Content-Type", "application/octet-stream")
+		req.Header.Set("Content-Encoding", "gzip")
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+		require.Contains(t, rr.Body.String(), "document exceeds the maximum size of 64 bytes")
+	})
+	t.Run("Invalid content type", func(t *testing.T) {
+		operation := vaultoperation.New(newVaultMock())
+
+		h := handlerLookup(t, operation, vaultoperation.SaveDocPath, http.MethodPost)
+
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodPost,
+			"/vaults/vaultID1/docs", strings.NewReader(`{}`))
+		require.NoError(t, err)
+
+		req.Header.Set("Content-Type", "application/json; =")
+
+		rr := serveRequest(h, req)
+
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid content type")
+	})
+	t.Run("Error (generate ID)", func(t *testing.T) {
+		const path = "/vaults/vaultID1/docs"
+
+		operation := vaultoperation.New(newVaultMock())
+		operation.GenerateID = func() (string, error) {
+			return "", errors.New("test error")
+		}
+
+		h := handlerLookup(t, operation, vaultoperation.SaveDocPath, http.MethodPost)
+		res, code := sendRequestToHandler(t, h, strings.NewReader(`{}`), path)
+
+		require.Equal(t, http.StatusInternalServerError, code)
+
+		var errResp *model.ErrorResponse
+
+		require.NoError(t, json.NewDecoder(res).Decode(&errResp))
+		require.Contains(t, errResp.Message, "test error")
+	})
+	t.Run("Success", func(t *testing.T) {
+		const path = "/vaults/vaultID1/docs"
+
+		operation := vaultoperation.New(newVaultMock())
+
+		h := handlerLookup(t, operation, vaultoperation.SaveDocPath, http.MethodPost)
+		res, code := sendRequestToHandler(t, h, strings.NewReader(`{}`), path)
+
+		require.Equal(t, http.StatusCreated, code)
+
+		var resp *vault.DocumentMetadata
+
+		require.NoError(t, json.NewDecoder(res).Decode(&resp))
+
+		require.NotEmpty(t, resp.ID)
+		require.NotEmpty(t, resp.URI)
+	})
+	t.Run("Doc ID strategies", func(t *testing.T) {
+		const (
+			edvID  = "Sr7yHjomhn1aeaFnxREfRN"
+			uuidID = "6c2b1e8f-3b1f-4d5e-9a6f-0a4c1b7e2d3f"
+		)
+
+		save := func(t *testing.T, strategy vaultoperation.DocIDStrategy, contentType, query, body string) (
+			*httptest.ResponseRecorder, string) {
+			t.Helper()
+
+			var savedID string
+
+			v := newVaultMock()
+			v.saveDocFn = func(_, id string, _ interface{}) (*vault.DocumentMetadata, error) {
+				savedID = id
+
+				return &vault.DocumentMetadata{ID: id}, nil
+			}
+			v.saveBinaryDocFn = func(_, id, _ string, _ []byte) (*vault.DocumentMetadata, error) {
+				savedID = id
+
+				return &vault.DocumentMetadata{ID: id}, nil
+			}
+
+			h := handlerLookup(t, vaultoperation.New(v, vaultoperation.WithDocIDStrategy(strategy)),
+				vaultoperation.SaveDocPath, http.MethodPost)
+
+			req, err := http.NewRequestWithContext(context.Background(), http.MethodPost,
+				"/vaults/vaultID1/docs"+query, strings.NewReader(body))
+			require.NoError(t, err)
+
+			req.Header.Set("Content-Type", contentType)
+
+			return serveRequest(h, req), savedID
+		}
+
+		t.Run("edv-compatible", func(t *testing.T) {
+			rr, id := save(t, vaultoperation.DocIDEDVCompatible, "application/json", "", `{}`)
+			require.Equal(t, http.StatusCreated, rr.Code)
+			require.NoError(t, edvutils.CheckIfBase58Encoded128BitValue(id))
+
+			rr, id = save(t, vaultoperation.DocIDEDVCompatible, "application/json", "", `{"id":"`+edvID+`"}`)
+			require.Equal(t, http.StatusCreated, rr.Code)
+			require.Equal(t, edvID, id)
+
+			for _, malformed := range []string{"doc1", uuidID} {
+				rr, id = save(t, vaultoperation.DocIDEDVCompatible, "application/json", "",
+					`{"id":"`+malformed+`"}`)
+				require.Equal(t, http.StatusBadRequest, rr.Code)
+				require.Contains(t, rr.Body.String(), "invalid document ID "+malformed)
+				require.Empty(t, id)
+			}
+		})
+		t.Run("uuid", func(t *testing.T) {
+			rr, id := save(t, vaultoperation.DocIDUUID, "application/json", "", `{}`)
+			require.Equal(t, http.StatusCreated, rr.Code)
+
+			generated, err := uuid.Parse(id)
+			require.NoError(t, err)
+			require.Equal(t, uuid.Version(4), generated.Version())
+
+			rr, id = save(t, vaultoperation.DocIDUUID, "application/pdf", "?id="+uuidID, "%PDF")
+			require.Equal(t, http.StatusCreated, rr.Code)
+			require.Equal(t, uuidID, id)
+
+			for _, malformed := range []string{
+				edvID,
+				"6c2b1e8f-3b1f-1d5e-9a6f-0a4c1b7e2d3f", // version 1
+				"{6c2b1e8f-3b1f-4d5e-9a6f-0a4c1b7e2d3f}",
+			} {
+				rr, id = save(t, vaultoperation.DocIDUUID, "application/json", "", `{"id":"`+malformed+`"}`)
+				require.Equal(t, http.StatusBadRequest, rr.Code)
+				require.Contains(t, rr.Body.String(), "invalid document ID "+malformed)
+				require.Empty(t, id)
+			}
+		})
+		t.Run("caller-supplied-required", func(t *testing.T) {
+			rr, id := save(t, vaultoperation.DocIDCallerSupplied, "application/json", "", `{"id":"doc1"}`)
+			require.Equal(t, http.StatusCreated, rr.Code)
+			require.Equal(t, "doc1", id)
+
+			rr, id = save(t, vaultoperation.DocIDCallerSupplied, "application/json", "", `{}`)
+			require.Equal(t, http.StatusBadRequest, rr.Code)
+			require.Contains(t, rr.Body.String(), "an ID is required")
+			require.Empty(t, id)
+
+			rr, id = save(t, vaultoperation.DocIDCallerSupplied, "application/pdf", "", "%PDF")
+			require.Equal(t, http.StatusBadRequest, rr.Code)
+			require.Empty(t, id)
+
+			for _, malformed := range []string{"docs/doc1", " "} {
+				rr, id = save(t, vaultoperation.DocIDCallerSupplied, "application/json", "",
+					`{"id":"`+malformed+`"}`)
+				require.Equal(t, http.StatusBadRequest, rr.Code)
+				require.Contains(t, rr.Body.String(), "invalid document ID")
+				require.Empty(t, id)
+			}
+		})
+		t.Run("unknown strategy", func(t *testing.T) {
+			_, err := vaultoperation.ParseDocIDStrategy("ulid")
+			require.Error(t, err)
+
+			strategy, err := vaultoperation.ParseDocIDStrategy("uuid")
+			require.NoError(t, err)
+			require.Equal(t, vaultoperation.DocIDUUID, strategy)
+		})
+	})
+}
+
+func TestGetDocMetadata(t *testing.T) {
+	const path = "/vaults/vaultID1/docs/docID1/metadata"
+
+	t.Run("Internal error", func(t *testing.T) {
+		v := newVaultMock()
+		v.getDocMetadataFn = func(_, _ string) (*vault.DocumentMetadata, error) {
+			return nil, errors.New("test")
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.GetDocMetadataPath, http.MethodGet)
+
+		respBody, code := sendRequestToHandler(t, h, nil, path)
+
+		require.Equal(t, http.StatusInternalServerError, code)
+
+		var errResp *model.ErrorResponse
+
+		require.NoError(t, json.NewDecoder(respBody).Decode(&errResp))
+		require.NotEmpty(t, errResp.Message)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		v := newVaultMock()
+		v.getDocMetadataFn = func(_, _ string) (*vault.DocumentMetadata, error) {
+			return nil, fmt.Errorf("read document: %w: no such document", vault.ErrDocumentNotFound)
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.GetDocMetadataPath, http.MethodGet)
+
+		respBody, code := sendRequestToHandler(t, h, nil, path)
+
+		require.Equal(t, http.StatusNotFound, code)
+
+		var errResp *model.ErrorResponse
+
+		require.NoError(t, json.NewDecoder(respBody).Decode(&errResp))
+		require.NotEmpty(t, errResp.Message)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		operation := vaultoperation.New(newVaultMock())
+
+		h := handlerLookup(t, operation, vaultoperation.GetDocMetadataPath, http.MethodGet)
+		res, code := sendRequestToHandler(t, h, strings.NewReader(`{}`), path)
+
+		require.Equal(t, http.StatusOK, code)
+
+		var resp *vault.DocumentMetadata
+
+		require.NoError(t, json.NewDecoder(res).Decode(&resp))
+
+		require.NotEmpty(t, resp.ID)
+		require.NotEmpty(t, resp.URI)
+	})
+
+	t.Run("HEAD", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			err    error
+			status int
+		}{
+			"Exists": {
+				status: http.StatusOK,
+			},
+			"Not found": {
+				err:    fmt.Errorf("read document: %w: no such document", vault.ErrDocumentNotFound),
+				status: http.StatusNotFound,
+			},
+			"Internal error": {
+				err:    errors.New("test"),
+				status: http.StatusInternalServerError,
+			},
+		} {
+			tc := tc
+
+			t.Run(name, func(t *testing.T) {
+				v := newVaultMock()
+				if tc.err != nil {
+					v.getDocMetadataFn = func(_, _ string) (*vault.DocumentMetadata, error) {
+						return nil, tc.err
+					}
+				}
+
+				operation := vaultoperation.New(v)
+
+				router := mux.NewRouter()
+				router.Use(gzipmw.New(gzipmw.DefaultMaxDecompressedSize))
+
+				for _, method := range []string{http.MethodGet, http.MethodHead} {
+					h := handlerLookup(t, operation, vaultoperation.GetDocMetadataPath, method)
+
+					router.HandleFunc(h.Path(), h.Handle()).Methods(h.Method())
+				}
+
+				srv := httptest.NewServer(router)
+				defer srv.Close()
+
+				get := doRequest(t, srv.Client(), http.MethodGet, srv.URL+path)
+				head := doRequest(t, srv.Client(), http.MethodHead, srv.URL+path)
+
+				require.Equal(t, tc.status, get.StatusCode)
+				require.Equal(t, tc.status, head.StatusCode)
+				require.Equal(t, "gzip", get.Header.Get("Content-Encoding"))
+				require.Equal(t, get.Header.Get("Content-Encoding"), head.Header.Get("Content-Encoding"))
+				require.Equal(t, get.Header.Values("Vary"), head.Header.Values("Vary"))
+				require.Equal(t, get.Header.Get("Content-Type"), head.Header.Get("Content-Type"))
+				require.Empty(t, head.Body)
+			})
+		}
+	})
+}
+
+func TestGetDocMetadataByURI(t *testing.T) {
+	const uri = "https://edv.example.com/encrypted-data-vaults/HwtZ1bUn4SzXoQRoX9br6m/documents/M3aS9xwj8ybCwHkEiCJJR1"
+
+	for name, tc := range map[string]struct {
+		query  string
+		err    error
+		status int
+	}{
+		"Missing URI": {
+			status: http.StatusBadRequest,
+		},
+		"Invalid URI": {
+			query:  "?uri=documents",
+			err:    fmt.Errorf("%w: documents", vault.ErrInvalidDocURI),
+			status: http.StatusBadRequest,
+		},
+		"Not found": {
+			query:  "?uri=" + url.QueryEscape(uri),
+			err:    fmt.Errorf("%w: %s", vault.ErrDocumentNotFound, uri),
+			status: http.StatusNotFound,
+		},
+		"Internal error": {
+			query:  "?uri=" + url.QueryEscape(uri),
+			err:    errors.New("test"),
+			status: http.StatusInternalServerError,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			v := newVaultMock()
+			v.getDocMetadataByURIFn = func(string) (*vault.DocumentMetadata, error) {
+				return nil, tc.err
+			}
+
+			h := handlerLookup(t, vaultoperation.New(v), vaultoperation.GetDocMetadataByURIPath, http.MethodGet)
+
+			respBody, code := sendRequestToHandler(t, h, nil, "/vaults/docs/metadata"+tc.query)
+
+			require.Equal(t, tc.status, code)
+
+			var errResp *model.ErrorResponse
+
+			require.NoError(t, json.NewDecoder(respBody).Decode(&errResp))
+			require.NotEmpty(t, errResp.Message)
+		})
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		v := newVaultMock()
+		v.getDocMetadataByURIFn = func(u string) (*vault.DocumentMetadata, error) {
+			require.Equal(t, uri, u)
+
+			return &vault.DocumentMetadata{ID: "docID1", URI: u}, nil
+		}
+
+		h := handlerLookup(t, vaultoperation.New(v), vaultoperation.GetDocMetadataByURIPath, http.MethodGet)
+
+		res, code := sendRequestToHandler(t, h, nil, "/vaults/docs/metadata?uri="+url.QueryEscape(uri))
+
+		require.Equal(t, http.StatusOK, code)
+
+		var resp *vault.DocumentMetadata
+
+		require.NoError(t, json.NewDecoder(res).Decode(&resp))
+		require.Equal(t, "docID1", resp.ID)
+		require.Equal(t, uri, resp.URI)
+	})
+}
+
+func TestPatchDoc(t *testing.T) {
+	const path = "/vaults/vaultID1/docs/docID1"
+
+	t.Run("Success", func(t *testing.T) {
+		for _, ct := range []string{"", "application/merge-patch+json", "application/json; charset=utf-8"} {
+			v := newVaultMock()
+			v.patchDocFn = func(vaultID, docID string, patch []byte,
+				opts []vault.SaveDocOpt) (*vault.DocumentMetadata, error) {
+				require.Equal(t, "vaultID1", vaultID)
+				require.Equal(t, "docID1", docID)
+				require.JSONEq(t, `{"name":"Bob","age":null}`, string(patch))
+				require.Len(t, opts, 1)
+
+				return &vault.DocumentMetadata{ID: docID, Sequence: 3}, nil
+			}
+
+			h := handlerLookup(t, vaultoperation.New(v), vaultoperation.PatchDocPath, http.MethodPatch)
+
+			req, err := http.NewRequestWithContext(context.Background(), http.MethodPatch, path,
+				strings.NewReader(`{"name":"Bob","age":null}`))
+			require.NoError(t, err)
+
+			req.Header.Set("If-Match", `"2"`)
+
+			if ct != "" {
+				req.Header.Set("Content-Type", ct)
+			}
+
+			rr := serveRequest(h, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var resp *vault.DocumentMetadata
+
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+			require.Equal(t, "docID1", resp.ID)
+			require.Equal(t, uint64(3), resp.Sequence)
+		}
+	})
+
+	t.Run("JSON patch", func(t *testing.T) {
+		v := newVaultMock()
+		v.patchDocFn = func(_, _ string, _ []byte, _ []vault.SaveDocOpt) (*vault.DocumentMetadata, error) {
+			return nil, errors.New("merge patch applied")
+		}
+		v.jsonPatchDocFn = func(vaultID, docID string, patch []byte,
+			opts []vault.SaveDocOpt) (*vault.DocumentMetadata, error) {
+			require.Equal(t, "vaultID1", vaultID)
+			require.Equal(t, "docID1", docID)
+			require.JSONEq(t, `[{"op":"remove","path":"/age"}]`, string(patch))
+			require.Len(t, opts, 1)
+
+			return &vault.DocumentMetadata{ID: docID, Sequence: 3}, nil
+		}
+
+		h := handlerLookup(t, vaultoperation.New(v), vaultoperation.PatchDocPath, http.MethodPatch)
+
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodPatch, path,
+			strings.NewReader(`[{"op":"remove","path":"/age"}]`))
+		require.NoError(t, err)
+
+		req.Header.Set("Content-Type", "application/json-patch+json")
+		req.Header.Set("If-Match", `"2"`)
+
+		rr := serveRequest(h, req)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var resp *vault.DocumentMetadata
+
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+		require.Equal(t, uint64(3), resp.Sequence)
+	})
+
+	t.Run("Unsupported content type", func(t *testing.T) {
+		h := handlerLookup(t, vaultoperation.New(newVaultMock()), vaultoperation.PatchDocPath, http.MethodPatch)
+
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodPatch, path,
+			strings.NewReader(`name=Bob`))
+		require.NoError(t, err)
+
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		rr := serveRequest(h, req)
+
+		require.Equal(t, http.StatusUnsupportedMediaType, rr.Code)
+	})
+
+	t.Run("Invalid If-Match header", func(t *testing.T) {
+		h := handlerLookup(t, vaultoperation.New(newVaultMock()), vaultoperation.PatchDocPath, http.MethodPatch)
+
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodPatch, path, strings.NewReader(`{}`))
+		require.NoError(t, err)
+
+		req.Header.Set("If-Match", "abc")
+
+		rr := serveRequest(h, req)
+
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			err    error
+			status int
+		}{
+			"Malformed patch": {
+				err:    fmt.Errorf("%w: must be a JSON object", vault.ErrInvalidPatch),
+				status: http.StatusBadRequest,
+			},
+			"Unknown document": {
+				err:    fmt.Errorf("get meta doc info: %w", storage.ErrDataNotFound),
+				status: http.StatusNotFound,
+			},
+			"Binary document": {
+				err:    fmt.Errorf("%w: docID1 is a binary document", vault.ErrNotJSONDocument),
+				status: http.StatusConflict,
+			},
+			"Sequence mismatch": {
+				err:    &vault.SequenceMismatchError{Expected: 2, Current: 3},
+				status: http.StatusConflict,
+			},
+			"Internal error": {
+				err:    errors.New("test"),
+				status: http.StatusInternalServerError,
+			},
+		} {
+			tc := tc
+
+			t.Run(name, func(t *testing.T) {
+				v := newVaultMock()
+				v.patchDocFn = func(_, _ string, _ []byte, _ []vault.SaveDocOpt) (*vault.DocumentMetadata, error) {
+					return nil, tc.err
+				}
+
+				h := handlerLookup(t, vaultoperation.New(v), vaultoperation.PatchDocPath, http.MethodPatch)
+
+				res, code := sendRequestToHandler(t, h, strings.NewReader(`{"name":"Bob"}`), path)
+
+				require.Equal(t, tc.status, code)
+
+				var errResp *model.ErrorResponse
+
+				require.NoError(t, json.NewDecoder(res).Decode(&errResp))
+				require.NotEmpty(t, errResp.Message)
+			})
+		}
+	})
+
+	t.Run("Document too large", func(t *testing.T) {
+		h := handlerLookup(t, vaultoperation.New(newVaultMock(), vaultoperation.WithMaxDocSize(8)),
+			vaultoperation.PatchDocPath, http.MethodPatch)
+
+		_, code := sendRequestToHandler(t, h, strings.NewReader(`{"name":"Robert"}`), path)
+
+		require.Equal(t, http.StatusRequestEntityTooLarge, code)
+	})
+}
+
+func TestListDocs(t *testing.T) {
+	const path = "/vaults/vaultID1/docs"
+
+	t.Run("Invalid limit", func(t *testing.T) {
+		operation := vaultoperation.New(newVaultMock())
+
+		h := handlerLookup(t, operation, vaultoperation.ListDocsPath, http.MethodGet)
+
+		for _, limit := range []string{"abc", "0", "-1"} {
+			respBody, code := sendRequestToHandler(t, h, nil, path+"?limit="+limit)
+
+			require.Equal(t, http.StatusBadRequest, code)
+
+			var errResp *model.ErrorResponse
+
+			require.NoError(t, json.NewDecoder(respBody).Decode(&errResp))
+			require.Contains(t, errResp.Message, "invalid limit")
+		}
+	})
+
+	t.Run("Invalid continuation token", func(t *testing.T) {
+		v := newVaultMock()
+		v.listDocsFn = func(_ string, _ int, _ string) (*vault.DocumentList, error) {
+			return nil, fmt.Errorf("%w: test", vault.ErrInvalidContinuationToken)
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.ListDocsPath, http.MethodGet)
+
+		_, code := sendRequestToHandler(t, h, nil, path+"?next=!")
+
+		require.Equal(t, http.StatusBadRequest, code)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		v := newVaultMock()
+		v.listDocsFn = func(_ string, _ int, _ string) (*vault.DocumentList, error) {
+			return nil, fmt.Errorf("get vault info: %w", storage.ErrDataNotFound)
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.ListDocsPath, http.MethodGet)
+
+		_, code := sendRequestToHandler(t, h, nil, path)
+
+		require.Equal(t, http.StatusNotFound, code)
+	})
+
+	t.Run("Internal error", func(t *testing.T) {
+		v := newVaultMock()
+		v.listDocsFn = func(_ string, _ int, _ string) (*vault.DocumentList, error) {
+			return nil, errors.New("test")
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.ListDocsPath, http.MethodGet)
+
+		_, code := sendRequestToHandler(t, h, nil, path)
+
+		require.Equal(t, http.StatusInternalServerError, code)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		var (
+			gotVaultID, gotNext string
+			gotLimit            int
+		)
+
+		v := newVaultMock()
+		v.listDocsFn = func(vaultID string, limit int, next string) (*vault.DocumentList, error) {
+			gotVaultID, gotLimit, gotNext = vaultID, limit, next
+
+			return &vault.DocumentList{
+				Documents: []*vault.DocumentListEntry{{ID: "docID1", URI: "uri1"}},
+				Next:      "token2",
+			}, nil
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.ListDocsPath, http.MethodGet)
+		res, code := sendRequestToHandler(t, h, nil, path+"?limit=10&next=token1")
+
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, "vaultID1", gotVaultID)
+		require.Equal(t, 10, gotLimit)
+		require.Equal(t, "token1", gotNext)
+
+		var resp *vault.DocumentList
+
+		require.NoError(t, json.NewDecoder(res).Decode(&resp))
+		require.Len(t, resp.Documents, 1)
+		require.Equal(t, "docID1", resp.Documents[0].ID)
+		require.Equal(t, "token2", resp.Next)
+	})
+
+	t.Run("By index tag", func(t *testing.T) {
+		v := newVaultMock()
+		v.listDocsFn = func(string, int, string) (*vault.DocumentList, error) {
+			require.FailNow(t, "documents must be looked up by tag")
+
+			return nil, nil
+		}
+		v.findDocsFn = func(vaultID, name, value string) (*vault.DocumentList, error) {
+			require.Equal(t, "vaultID1", vaultID)
+			require.Equal(t, "type", name)
+			require.Equal(t, "driver:license", value)
+
+			return &vault.DocumentList{Documents: []*vault.DocumentListEntry{{ID: "docID1"}}}, nil
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.ListDocsPath, http.MethodGet)
+		res, code := sendRequestToHandler(t, h, nil, path+"?tag="+url.QueryEscape("type:driver:license"))
+
+		require.Equal(t, http.StatusOK, code)
+
+		var resp *vault.DocumentList
+
+		require.NoError(t, json.NewDecoder(res).Decode(&resp))
+		require.Len(t, resp.Documents, 1)
+		require.Equal(t, "docID1", resp.Documents[0].ID)
+	})
+
+	t.Run("Invalid index tag", func(t *testing.T) {
+		v := newVaultMock()
+		v.findDocsFn = func(string, string, string) (*vault.DocumentList, error) {
+			return nil, fmt.Errorf("%w: name and value are required", vault.ErrInvalidIndexTag)
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.ListDocsPath, http.MethodGet)
+
+		for _, tag := range []string{"type", "type:"} {
+			_, code := sendRequestToHandler(t, h, nil, path+"?tag="+tag)
+
+			require.Equal(t, http.StatusBadRequest, code)
+		}
+	})
+}
+
+func TestGetDoc(t *testing.T) {
+	const path = "/vaults/vaultID1/docs/docID1"
+
+	t.Run("Internal error", func(t *testing.T) {
+		v := newVaultMock()
+		v.getDocContentFn = func(_, _ string) (*vault.DocumentContent, error) {
+			return nil, errors.New("test")
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.GetDocPath, http.MethodGet)
+
+		respBody, code := sendRequestToHandler(t, h, nil, path)
+
+		require.Equal(t, http.StatusInternalServerError, code)
+
+		var errResp *model.ErrorResponse
+
+		require.NoError(t, json.NewDecoder(respBody).Decode(&errResp))
+		require.NotEmpty(t, errResp.Message)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		v := newVaultMock()
+		v.getDocContentFn = func(_, _ string) (*vault.DocumentContent, error) {
+			return nil, fmt.Errorf("read document: %w: no such document", vault.ErrDocumentNotFound)
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.GetDocPath, http.MethodGet)
+
+		respBody, code := sendRequestToHandler(t, h, nil, path)
+
+		require.Equal(t, http.StatusNotFound, code)
+
+		var errResp *model.ErrorResponse
+
+		require.NoError(t, json.NewDecoder(respBody).Decode(&errResp))
+		require.NotEmpty(t, errResp.Message)
+	})
+
+	t.Run("Externally controlled vault", func(t *testing.T) {
+		v := newVaultMock()
+		v.getDocContentFn = func(_, _ string) (*vault.DocumentContent, error) {
+			return nil, fmt.Errorf("%w: did:example:123", vault.ErrExternallyControlled)
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.GetDocPath, http.MethodGet)
+
+		respBody, code := sendRequestToHandler(t, h, nil, path)
+
+		require.Equal(t, http.StatusConflict, code)
+
+		var errResp *model.ErrorResponse
+
+		require.NoError(t, json.NewDecoder(respBody).Decode(&errResp))
+		require.Contains(t, errResp.Message, "authorization tokens")
+	})
+
+	t.Run("Upstream authorization refused", func(t *testing.T) {
+		for _, refusal := range []*vault.UpstreamAuthError{
+			{Upstream: vault.UpstreamEDV, StatusCode: http.StatusUnauthorized, Message: "token expired"},
+			{Upstream: vault.UpstreamKMS, StatusCode: http.StatusForbidden, Message: "capability revoked"},
+		} {
+			refusal := refusal
+
+			v := newVaultMock()
+			v.getDocContentFn = func(_, _ string) (*vault.DocumentContent, error) {
+				return nil, fmt.Errorf("read document: %w", refusal)
+			}
+
+			h := handlerLookup(t, vaultoperation.New(v), vaultoperation.GetDocPath, http.MethodGet)
+
+			respBody, code := sendRequestToHandler(t, h, nil, path)
+
+			require.Equal(t, http.StatusFailedDependency, code)
+
+			var errResp *model.ErrorResponse
+
+			require.NoError(t, json.NewDecoder(respBody).Decode(&errResp))
+			require.Equal(t, model.ErrCodeUpstreamUnauthorized, errResp.Code)
+			require.Equal(t, refusal.Upstream, errResp.Upstream)
+			require.Equal(t, refusal.Hint(), errResp.Hint)
+			require.Contains(t, errResp.Message, refusal.Message)
+		}
+	})
+
+	t.Run("EDV errors are mapped by type, not wording", func(t *testing.T) {
+		for err, status := range map[error]int{
+			fmt.Errorf("read document: %w: vault gone", vault.ErrVaultNotFound):  http.StatusNotFound,
+			fmt.Errorf("read document: %w", vault.ErrDocumentNotFound):           http.StatusNotFound,
+			errors.New("read document: specified document does not exist."):      http.StatusInternalServerError,
+			errors.New("read document: the EDV server returned status code 404"): http.StatusInternalServerError,
+		} {
+			err := err
+
+			v := newVaultMock()
+			v.getDocContentFn = func(_, _ string) (*vault.DocumentContent, error) {
+				return nil, err
+			}
+
+			h := handlerLookup(t, vaultoperation.New(v), vaultoperation.GetDocPath, http.MethodGet)
+
+			_, code := sendRequestToHandler(t, h, nil, path)
+			require.Equal(t, status, code, err.Error())
+		}
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		operation := vaultoperation.New(newVaultMock())
+
+		h := handlerLookup(t, operation, vaultoperation.GetDocPath, http.MethodGet)
+		res, code := sendRequestToHandler(t, h, nil, path)
+
+		require.Equal(t, http.StatusOK, code)
+
+		var resp map[string]interface{}
+
+		require.NoError(t, json.NewDecoder(res).Decode(&resp))
+		require.Equal(t, "Hello World!", resp["message"])
+	})
+
+	t.Run("Success (binary)", func(t *testing.T) {
+		content := []byte{0x25, 0x50, 0x44, 0x46, 0x00, 0xff}
+
+		v := newVaultMock()
+		v.getDocContentFn = func(_, _ string) (*vault.DocumentContent, error) {
+			return &vault.DocumentContent{MediaType: "application/pdf", Data: content}, nil
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.GetDocPath, http.MethodGet)
+
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, path, http.NoBody)
+		require.NoError(t, err)
+
+		rr := serveRequest(h, req)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, "application/pdf", rr.Header().Get("Content-Type"))
+		require.Equal(t, "attachment", rr.Header().Get("Content-Disposition"))
+		require.Equal(t, "nosniff", rr.Header().Get("X-Content-Type-Options"))
+		require.Equal(t, content, rr.Body.Bytes())
+	})
+
+	t.Run("Success (HTML)", func(t *testing.T) {
+		v := newVaultMock()
+		v.getDocContentFn = func(_, _ string) (*vault.DocumentContent, error) {
+			return &vault.DocumentContent{MediaType: "text/html", Data: []byte("<script>alert(1)</script>")}, nil
+		}
+
+		h := handlerLookup(t, vaultoperation.New(v), vaultoperation.GetDocPath, http.MethodGet)
+
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, path, http.NoBody)
+		require.NoError(t, err)
+
+		rr := serveRequest(h, req)
+
+		// the browser downloads the content instead of rendering it
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, "text/html", rr.Header().Get("Content-Type"))
+		require.Equal(t, "attachment", rr.Header().Get("Content-Disposition"))
+		require.Equal(t, "nosniff", rr.Header().Get("X-Content-Type-Options"))
+	})
+}
+
+func TestDeleteDoc(t *testing.T) {
+	const path = "/vaults/vaultID1/docs/docID1"
+
+	t.Run("Internal error", func(t *testing.T) {
+		v := newVaultMock()
+		v.deleteDocFn = func(_, _ string) error {
+			return errors.New("test")
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.DeleteDocPath, http.MethodDelete)
+
+		respBody, code := sendRequestToHandler(t, h, nil, path)
+
+		require.Equal(t, http.StatusInternalServerError, code)
+
+		var errResp *model.ErrorResponse
+
+		require.NoError(t, json.NewDecoder(respBody).Decode(&errResp))
+		require.NotEmpty(t, errResp.Message)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		v := newVaultMock()
+		v.deleteDocFn = func(_, _ string) error {
+			return fmt.Errorf("get meta doc info: %w", storage.ErrDataNotFound)
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.DeleteDocPath, http.MethodDelete)
+
+		respBody, code := sendRequestToHandler(t, h, nil, path)
+
+		require.Equal(t, http.StatusNotFound, code)
+
+		var errResp *model.ErrorResponse
+
+		require.NoError(t, json.NewDecoder(respBody).Decode(&errResp))
+		require.NotEmpty(t, errResp.Message)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		var deleted string
+
+		v := newVaultMock()
+		v.deleteDocFn = func(vaultID, docID string) error {
+			deleted = vaultID + "/" + docID
+
+			return nil
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.DeleteDocPath, http.MethodDelete)
+
+		_, code := sendRequestToHandler(t, h, nil, path)
+
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, "vaultID1/docID1", deleted)
+	})
+}
+
+func TestOperation_GetAuthorization(t *testing.T) {
+	const path = "/vaults/vaultID/authorizations/authID"
+
+	t.Run("Internal error", func(t *testing.T) {
+		v := newVaultMock()
+		v.getAuthorizationFn = func(_, _ string) (*vault.CreatedAuthorization, error) {
+			return nil, errors.New("test")
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.GetAuthorizationPath, http.MethodGet)
+
+		respBody, code := sendRequestToHandler(t, h, nil, path)
+
+		require.Equal(t, http.StatusInternalServerError, code)
+
+		var errResp *model.ErrorResponse
+
+		require.NoError(t, json.NewDecoder(respBody).Decode(&errResp))
+		require.NotEmpty(t, errResp.Message)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		v := newVaultMock()
+		v.getAuthorizationFn = func(_, _ string) (*vault.CreatedAuthorization, error) {
+			return nil, storage.ErrDataNotFound
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.GetAuthorizationPath, http.MethodGet)
+
+		respBody, code := sendRequestToHandler(t, h, nil, path)
+
+		require.Equal(t, http.StatusNotFound, code)
+
+		var errResp *model.ErrorResponse
+
+		require.NoError(t, json.NewDecoder(respBody).Decode(&errResp))
+		require.NotEmpty(t, errResp.Message)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		operation := vaultoperation.New(newVaultMock())
+
+		h := handlerLookup(t, operation, vaultoperation.GetAuthorizationPath, http.MethodGet)
+		res, code := sendRequestToHandler(t, h, nil, path)
+
+		require.Equal(t, http.StatusOK, code)
+
+		var resp *vault.CreatedVault
+
+		require.NoError(t, json.NewDecoder(res).Decode(&resp))
+
+		require.NotEmpty(t, resp.ID)
+	})
+}
+
+func TestOperation_UseAuthorization(t *testing.T) {
+	const path = "/vaults/vaultID/authorizations/authID/uses"
+
+	t.Run("Success", func(t *testing.T) {
+		operation := vaultoperation.New(newVaultMock())
+
+		h := handlerLookup(t, operation, vaultoperation.UseAuthorizationPath, http.MethodPost)
+		res, code := sendRequestToHandler(t, h, nil, path)
+
+		require.Equal(t, http.StatusOK, code)
+		require.JSONEq(t, `{"id":"authID","scope":null,"requestingParty":"","authTokens":null,"uses":1}`,
+			res.String())
+	})
+
+	for _, tc := range []struct {
+		err    error
+		status int
+	}{
+		{err: fmt.Errorf("%w: used 5 of 5 times", vault.ErrAuthorizationExhausted), status: http.StatusForbidden},
+		{err: fmt.Errorf("%w: expired", vault.ErrAuthorizationInactive), status: http.StatusForbidden},
+		{err: storage.ErrDataNotFound, status: http.StatusNotFound},
+		{err: errors.New("test"), status: http.StatusInternalServerError},
+	} {
+		t.Run("Error: "+tc.err.Error(), func(t *testing.T) {
+			v := newVaultMock()
+			v.useAuthorizationFn = func(_, _ string) (*vault.CreatedAuthorization, error) {
+				return nil, tc.err
+			}
+
+			operation := vaultoperation.New(v)
+
+			h := handlerLookup(t, operation, vaultoperation.UseAuthorizationPath, http.MethodPost)
+
+			respBody, code := sendRequestToHandler(t, h, nil, path)
+
+			require.Equal(t, tc.status, code)
+			require.Contains(t, respBody.String(), tc.err.Error())
+		})
+	}
+}
+
+func TestCreateAuthorization(t *testing.T) {
+	const path = "/vaults/vaultID1/authorizations"
+
+	t.Run("JSON error", func(t *testing.T) {
+		operation := vaultoperation.New(newVaultMock())
+
+		h := handlerLookup(t, operation, vaultoperation.CreateAuthorizationPath, http.MethodPost)
+		res, code := sendRequestToHandler(t, h, strings.NewReader(`{`), path)
+
+		require.Equal(t, http.StatusBadRequest, code)
+
+		var errResp *model.ErrorResponse
+
+		require.NoError(t, json.NewDecoder(res).Decode(&errResp))
+		require.Contains(t, errResp.Message, "unexpected EOF")
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		v := newVaultMock()
+		v.createAuthorizationFn = func(vID, rp string, scope *vault.AuthorizationsScope,
+			opts []vault.CreateAuthorizationOpt) (*vault.CreatedAuthorization, error) {
+			return nil, errors.New("test error")
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.CreateAuthorizationPath, http.MethodPost)
+		res, code := sendRequestToHandler(t, h, strings.NewReader(`{}`), path)
+
+		require.Equal(t, http.StatusInternalServerError, code)
+
+		var errResp *model.ErrorResponse
+
+		require.NoError(t, json.NewDecoder(res).Decode(&errResp))
+		require.Contains(t, errResp.Message, "test error")
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		operation := vaultoperation.New(newVaultMock())
+
+		h := handlerLookup(t, operation, vaultoperation.CreateAuthorizationPath, http.MethodPost)
+		res, code := sendRequestToHandler(t, h, strings.NewReader(`{}`), path)
+
+		require.Equal(t, http.StatusCreated, code)
+
+		var resp *vault.CreatedAuthorization
+
+		require.NoError(t, json.NewDecoder(res).Decode(&resp))
+
+		require.NotEmpty(t, resp.ID)
+	})
+
+	t.Run("Forwards the proof of key control", func(t *testing.T) {
+		v := newVaultMock()
+
+		var forwarded int
+
+		v.createAuthorizationFn = func(vID, rp string, scope *vault.AuthorizationsScope,
+			opts []vault.CreateAuthorizationOpt) (*vault.CreatedAuthorization, error) {
+			forwarded = len(opts)
+
+			return &vault.CreatedAuthorization{ID: "authID"}, nil
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.CreateAuthorizationPath, http.MethodPost)
+		_, code := sendRequestToHandler(t, h, strings.NewReader(
+			`{"requestingParty":"did:example:rp","proof":{"challenge":"c","verificationMethod":"did:example:rp#key",`+
+				`"signature":"sig"}}`), path)
+
+		require.Equal(t, http.StatusCreated, code)
+		require.Equal(t, 1, forwarded)
+	})
+
+	t.Run("Invalid proof of key control", func(t *testing.T) {
+		v := newVaultMock()
+		v.createAuthorizationFn = func(vID, rp string, scope *vault.AuthorizationsScope,
+			opts []vault.CreateAuthorizationOpt) (*vault.CreatedAuthorization, error) {
+			return nil, fmt.Errorf("%w: challenge expired", vault.ErrInvalidKeyProof)
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.CreateAuthorizationPath, http.MethodPost)
+		res, code := sendRequestToHandler(t, h, strings.NewReader(`{}`), path)
+
+		require.Equal(t, http.StatusForbidden, code)
+
+		var errResp *model.ErrorResponse
+
+		require.NoError(t, json.NewDecoder(res).Decode(&errResp))
+		require.Equal(t, model.ErrCodeForbidden, errResp.Code)
+		require.Contains(t, errResp.Message, "challenge expired")
+	})
+}
+
+func TestCreateAuthorizationChallenge(t *testing.T) {
+	const path = "/vaults/vaultID1/authorizations/challenges"
+
+	t.Run("Success", func(t *testing.T) {
+		operation := vaultoperation.New(newVaultMock())
+
+		h := handlerLookup(t, operation, vaultoperation.CreateChallengePath, http.MethodPost)
+		res, code := sendRequestToHandler(t, h, nil, path)
+
+		require.Equal(t, http.StatusCreated, code)
+
+		var resp *vault.AuthorizationChallenge
+
+		require.NoError(t, json.NewDecoder(res).Decode(&resp))
+		require.Equal(t, "challenge", resp.Challenge)
+	})
+
+	t.Run("Vault not found", func(t *testing.T) {
+		v := newVaultMock()
+		v.createChallengeFn = func(vaultID string) (*vault.AuthorizationChallenge, error) {
+			return nil, fmt.Errorf("get vault info: %w", vault.ErrVaultNotFound)
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.CreateChallengePath, http.MethodPost)
+		_, code := sendRequestToHandler(t, h, nil, path)
+
+		require.Equal(t, http.StatusNotFound, code)
+	})
+}
+
+func TestCreateWebhook(t *testing.T) {
+	const path = "/vaults/vaultID1/webhooks"
+
+	t.Run("Success", func(t *testing.T) {
+		v := newVaultMock()
+
+		var secret string
+
+		v.createWebhookFn = func(vaultID, webhookURL, s string) (*vault.Webhook, error) {
+			require.Equal(t, "vaultID1", vaultID)
+
+			secret = s
+
+			return &vault.Webhook{ID: "hook1", URL: webhookURL}, nil
+		}
+
+		h := handlerLookup(t, vaultoperation.New(v), vaultoperation.CreateWebhookPath, http.MethodPost)
+		res, code := sendRequestToHandler(t, h,
+			strings.NewReader(`{"url":"https://indexer.example.com/events","secret":"s3cr3t"}`), path)
+
+		require.Equal(t, http.StatusCreated, code)
+		require.Equal(t, "s3cr3t", secret)
+
+		var resp map[string]interface{}
+
+		require.NoError(t, json.NewDecoder(res).Decode(&resp))
+		require.Equal(t, "hook1", resp["id"])
+		require.Equal(t, "https://indexer.example.com/events", resp["url"])
+		require.NotContains(t, resp, "secret")
+	})
+
+	t.Run("JSON error", func(t *testing.T) {
+		h := handlerLookup(t, vaultoperation.New(newVaultMock()), vaultoperation.CreateWebhookPath, http.MethodPost)
+		_, code := sendRequestToHandler(t, h, strings.NewReader(`{`), path)
+
+		require.Equal(t, http.StatusBadRequest, code)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		for err, status := range map[error]int{
+			fmt.Errorf("%w: url must be absolute", vault.ErrInvalidWebhook): http.StatusBadRequest,
+			fmt.Errorf("get vault info: %w", storage.ErrDataNotFound):       http.StatusNotFound,
+			vault.ErrVaultDeleting:   http.StatusConflict,
+			errors.New("test error"): http.StatusInternalServerError,
+		} {
+			v := newVaultMock()
+			v.createWebhookFn = func(string, string, string) (*vault.Webhook, error) {
+				return nil, err
+			}
+
+			h := handlerLookup(t, vaultoperation.New(v), vaultoperation.CreateWebhookPath, http.MethodPost)
+			res, code := sendRequestToHandler(t, h, strings.NewReader(`{"url":"ftp://example.com"}`), path)
+
+			require.Equal(t, status, code, err)
+
+			var errResp *model.ErrorResponse
+
+			require.NoError(t, json.NewDecoder(res).Decode(&errResp))
+			require.Equal(t, err.Error(), errResp.Message)
+		}
+	})
+}
+
+func TestListWebhooks(t *testing.T) {
+	const path = "/vaults/vaultID1/webhooks"
+
+	t.Run("Success", func(t *testing.T) {
+		v := newVaultMock()
+		v.listWebhooksFn = func(vaultID string) (*vault.WebhookList, error) {
+			require.Equal(t, "vaultID1", vaultID)
+
+			return &vault.WebhookList{Webhooks: []*vault.Webhook{
+				{ID: "hook1", URL: "https://indexer.example.com/events"},
+			}}, nil
+		}
+
+		h := handlerLookup(t, vaultoperation.New(v), vaultoperation.ListWebhooksPath, http.MethodGet)
+		res, code := sendRequestToHandler(t, h, nil, path)
+
+		require.Equal(t, http.StatusOK, code)
+
+		var resp *vault.WebhookList
+
+		require.NoError(t, json.NewDecoder(res).Decode(&resp))
+		require.Len(t, resp.Webhooks, 1)
+		require.Equal(t, "hook1", resp.Webhooks[0].ID)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		for err, status := range map[error]int{
+			fmt.Errorf("get vault info: %w", storage.ErrDataNotFound): http.StatusNotFound,
+			errors.New("test error"):                                  http.StatusInternalServerError,
+		} {
+			v := newVaultMock()
+			v.listWebhooksFn = func(string) (*vault.WebhookList, error) {
+				return nil, err
+			}
+
+			h := handlerLookup(t, vaultoperation.New(v), vaultoperation.ListWebhooksPath, http.MethodGet)
+			_, code := sendRequestToHandler(t, h, nil, path)
+
+			require.Equal(t, status, code, err)
+		}
+	})
+}
+
+func TestDeleteWebhook(t *testing.T) {
+	const path = "/vaults/vaultID1/webhooks/hook1"
+
+	t.Run("Success", func(t *testing.T) {
+		v := newVaultMock()
+		v.deleteWebhookFn = func(vaultID, id string) error {
+			require.Equal(t, "vaultID1", vaultID)
+			require.Equal(t, "hook1", id)
+
+			return nil
+		}
+
+		h := handlerLookup(t, vaultoperation.New(v), vaultoperation.DeleteWebhookPath, http.MethodDelete)
+		_, code := sendRequestToHandler(t, h, nil, path)
+
+		require.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		for err, status := range map[error]int{
+			fmt.Errorf("get webhook: %w", storage.ErrDataNotFound): http.StatusNotFound,
+			errors.New("test error"):                               http.StatusInternalServerError,
+		} {
+			v := newVaultMock()
+			v.deleteWebhookFn = func(string, string) error {
+				return err
+			}
+
+			h := handlerLookup(t, vaultoperation.New(v), vaultoperation.DeleteWebhookPath, http.MethodDelete)
+			_, code := sendRequestToHandler(t, h, nil, path)
+
+			require.Equal(t, status, code, err)
+		}
+	})
+}
+
+func TestGetAuthorization(t *testing.T) {
+	const path = "/vaults/vaultID1/authorizations/authID1"
+
+	operation := vaultoperation.New(newVaultMock())
+
+	h := handlerLookup(t, operation, vaultoperation.GetAuthorizationPath, http.MethodGet)
+	_, code := sendRequestToHandler(t, h, nil, path)
+
+	require.Equal(t, http.StatusOK, code)
+}
+
+func TestGetVault(t *testing.T) {
+	const path = "/vaults/vaultID1"
+
+	t.Run("Not found", func(t *testing.T) {
+		v := newVaultMock()
+		v.getVaultInfoFn = func(_ string) (*vault.VaultInfo, error) {
+			return nil, fmt.Errorf("get vault info: %w", storage.ErrDataNotFound)
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.GetVaultPath, http.MethodGet)
+
+		_, code := sendRequestToHandler(t, h, nil, path)
+
+		require.Equal(t, http.StatusNotFound, code)
+	})
+
+	t.Run("Internal error", func(t *testing.T) {
+		v := newVaultMock()
+		v.getVaultInfoFn = func(_ string) (*vault.VaultInfo, error) {
+			return nil, errors.New("test")
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.GetVaultPath, http.MethodGet)
+
+		_, code := sendRequestToHandler(t, h, nil, path)
+
+		require.Equal(t, http.StatusInternalServerError, code)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		var gotVaultID string
+
+		v := newVaultMock()
+		v.getVaultInfoFn = func(vaultID string) (*vault.VaultInfo, error) {
+			gotVaultID = vaultID
+
+			return &vault.VaultInfo{ID: vaultID, Controller: "did:example:123", DocCount: 2}, nil
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.GetVaultPath, http.MethodGet)
+		res, code := sendRequestToHandler(t, h, nil, path)
+
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, "vaultID1", gotVaultID)
+
+		var resp *vault.VaultInfo
+
+		require.NoError(t, json.NewDecoder(res).Decode(&resp))
+		require.Equal(t, "did:example:123", resp.Controller)
+		require.Equal(t, 2, resp.DocCount)
+		require.Nil(t, resp.Created)
+	})
 }
 
-func TestSaveDoc(t *testing.T) {
-	t.Run("Error", func(t *testing.T) {
-		const path = "/vaults/vaultID1/docs"
+func TestListVaults(t *testing.T) {
+	const path = "/vaults?controller=did:example:123"
+
+	t.Run("Missing controller", func(t *testing.T) {
+		operation := vaultoperation.New(newVaultMock())
+
+		h := handlerLookup(t, operation, vaultoperation.ListVaultsPath, http.MethodGet)
+
+		respBody, code := sendRequestToHandler(t, h, nil, "/vaults")
+
+		require.Equal(t, http.StatusBadRequest, code)
+
+		var errResp *model.ErrorResponse
 
+		require.NoError(t, json.NewDecoder(respBody).Decode(&errResp))
+		require.Contains(t, errResp.Message, "controller is required")
+	})
+
+	t.Run("Requires the admin token if invocations are not verified", func(t *testing.T) {
 		v := newVaultMock()
-		v.saveDocFn = func(vaultID, id string, content interface{}) (*vault.DocumentMetadata, error) {
+		v.listVaultsFn = func(_ string, _ int, _ string) (*vault.VaultList, error) {
+			return &vault.VaultList{}, nil
+		}
+
+		for _, operation := range []*vaultoperation.Operation{
+			vaultoperation.New(v),
+			vaultoperation.New(v, vaultoperation.WithAdminToken("admin")),
+		} {
+			h := handlerLookup(t, operation, vaultoperation.ListVaultsPath, http.MethodGet)
+
+			respBody, code := sendRequestToHandler(t, h, nil, path)
+			require.Equal(t, http.StatusUnauthorized, code)
+
+			var errResp *model.ErrorResponse
+
+			require.NoError(t, json.NewDecoder(respBody).Decode(&errResp))
+			require.Contains(t, errResp.Message, "the admin token is required")
+		}
+
+		operation := vaultoperation.New(v, vaultoperation.WithAdminToken("admin"))
+
+		h := handlerLookup(t, operation, vaultoperation.ListVaultsPath, http.MethodGet)
+
+		_, code := sendAdminRequestToHandler(t, h, path)
+		require.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("Invalid limit", func(t *testing.T) {
+		operation := vaultoperation.New(newVaultMock(), vaultoperation.WithAdminToken("admin"))
+
+		h := handlerLookup(t, operation, vaultoperation.ListVaultsPath, http.MethodGet)
+
+		for _, limit := range []string{"abc", "0", "-1"} {
+			_, code := sendAdminRequestToHandler(t, h, path+"&limit="+limit)
+
+			require.Equal(t, http.StatusBadRequest, code)
+		}
+	})
+
+	t.Run("Invalid continuation token", func(t *testing.T) {
+		v := newVaultMock()
+		v.listVaultsFn = func(_ string, _ int, _ string) (*vault.VaultList, error) {
+			return nil, fmt.Errorf("%w: test", vault.ErrInvalidContinuationToken)
+		}
+
+		operation := vaultoperation.New(v, vaultoperation.WithAdminToken("admin"))
+
+		h := handlerLookup(t, operation, vaultoperation.ListVaultsPath, http.MethodGet)
+
+		_, code := sendAdminRequestToHandler(t, h, path+"&next=!")
+
+		require.Equal(t, http.StatusBadRequest, code)
+	})
+
+	t.Run("Internal error", func(t *testing.T) {
+		v := newVaultMock()
+		v.listVaultsFn = func(_ string, _ int, _ string) (*vault.VaultList, error) {
 			return nil, errors.New("test")
 		}
 
-		operation := vaultoperation.New(v)
+		operation := vaultoperation.New(v, vaultoperation.WithAdminToken("admin"))
 
-		h := handlerLookup(t, operation, vaultoperation.SaveDocPath, http.MethodPost)
-		res, code := sendRequestToHandler(t, h, strings.NewReader(`{}`), path)
+		h := handlerLookup(t, operation, vaultoperation.ListVaultsPath, http.MethodGet)
+
+		_, code := sendAdminRequestToHandler(t, h, path)
 
 		require.Equal(t, http.StatusInternalServerError, code)
+	})
 
-		var errResp *model.ErrorResponse
+	t.Run("Success", func(t *testing.T) {
+		var (
+			gotController, gotNext string
+			gotLimit               int
+		)
 
-		require.NoError(t, json.NewDecoder(res).Decode(&errResp))
+		created := time.Now().UTC().Truncate(time.Second)
+
+		v := newVaultMock()
+		v.listVaultsFn = func(controller string, limit int, next string) (*vault.VaultList, error) {
+			gotController, gotLimit, gotNext = controller, limit, next
+
+			return &vault.VaultList{
+				Vaults: []*vault.VaultListEntry{{ID: "vaultID1", Created: &created, EDVURI: "https://edv/1"}},
+				Next:   "next2",
+			}, nil
+		}
+
+		operation := vaultoperation.New(v, vaultoperation.WithAdminToken("admin"))
+
+		h := handlerLookup(t, operation, vaultoperation.ListVaultsPath, http.MethodGet)
+		res, code := sendAdminRequestToHandler(t, h, path+"&limit=1&next=next1")
+
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, "did:example:123", gotController)
+		require.Equal(t, 1, gotLimit)
+		require.Equal(t, "next1", gotNext)
+
+		var resp *vault.VaultList
+
+		require.NoError(t, json.NewDecoder(res).Decode(&resp))
+		require.Equal(t, "next2", resp.Next)
+		require.Len(t, resp.Vaults, 1)
+		require.Equal(t, "vaultID1", resp.Vaults[0].ID)
+		require.Equal(t, "https://edv/1", resp.Vaults[0].EDVURI)
+		require.True(t, created.Equal(*resp.Vaults[0].Created))
 	})
-	t.Run("JSON error", func(t *testing.T) {
-		const path = "/vaults/vaultID1/docs"
+}
 
+func TestDeleteVault(t *testing.T) {
+	const path = "/vaults/vaultID1"
+
+	t.Run("Not confirmed", func(t *testing.T) {
 		operation := vaultoperation.New(newVaultMock())
 
-		h := handlerLookup(t, operation, vaultoperation.SaveDocPath, http.MethodPost)
-		res, code := sendRequestToHandler(t, h, strings.NewReader(`{`), path)
+		h := handlerLookup(t, operation, vaultoperation.DeleteVaultPath, http.MethodDelete)
 
-		require.Equal(t, http.StatusBadRequest, code)
+		for _, query := range []string{"", "?confirm=false", "?confirm=abc"} {
+			res, code := sendRequestToHandler(t, h, nil, path+query)
 
-		var errResp *model.ErrorResponse
+			require.Equal(t, http.StatusBadRequest, code)
 
-		require.NoError(t, json.NewDecoder(res).Decode(&errResp))
-		require.Contains(t, errResp.Message, "unexpected EOF")
+			var errResp *model.ErrorResponse
+
+			require.NoError(t, json.NewDecoder(res).Decode(&errResp))
+			require.Contains(t, errResp.Message, "confirm=true")
+		}
 	})
-	t.Run("Error (generate ID)", func(t *testing.T) {
-		const path = "/vaults/vaultID1/docs"
 
+	t.Run("Not found", func(t *testing.T) {
+		v := newVaultMock()
+		v.deleteVaultFn = func(_ string) (*vault.VaultDeletion, error) {
+			return nil, fmt.Errorf("get vault info: %w", storage.ErrDataNotFound)
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.DeleteVaultPath, http.MethodDelete)
+		_, code := sendRequestToHandler(t, h, nil, path+"?confirm=true")
+
+		require.Equal(t, http.StatusNotFound, code)
+	})
+
+	t.Run("Incomplete", func(t *testing.T) {
+		v := newVaultMock()
+		v.deleteVaultFn = func(vaultID string) (*vault.VaultDeletion, error) {
+			return &vault.VaultDeletion{
+				ID:  vaultID,
+				EDV: &vault.DeletionStatus{Deleted: true},
+				KMS: &vault.DeletionStatus{Error: "test"},
+			}, nil
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.DeleteVaultPath, http.MethodDelete)
+		res, code := sendRequestToHandler(t, h, nil, path+"?confirm=true")
+
+		require.Equal(t, http.StatusBadGateway, code)
+
+		var resp *vault.VaultDeletion
+
+		require.NoError(t, json.NewDecoder(res).Decode(&resp))
+		require.False(t, resp.Complete)
+		require.Equal(t, "test", resp.KMS.Error)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		var gotVaultID string
+
+		v := newVaultMock()
+		v.deleteVaultFn = func(vaultID string) (*vault.VaultDeletion, error) {
+			gotVaultID = vaultID
+
+			return &vault.VaultDeletion{
+				ID:       vaultID,
+				Complete: true,
+				EDV:      &vault.DeletionStatus{Deleted: true},
+				KMS:      &vault.DeletionStatus{Deleted: true},
+			}, nil
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.DeleteVaultPath, http.MethodDelete)
+		_, code := sendRequestToHandler(t, h, nil, path+"?confirm=true")
+
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, "vaultID1", gotVaultID)
+	})
+}
+
+func TestWriteResponse(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	(&vaultoperation.Operation{}).WriteResponse(rec, make(chan int), http.StatusInternalServerError)
+	reader := rec.Result().Body
+
+	res, err := io.ReadAll(reader)
+	require.NoError(t, reader.Close())
+	require.NoError(t, err)
+	require.Empty(t, res)
+}
+
+func TestListAuthorizations(t *testing.T) {
+	const path = "/vaults/vaultID1/authorizations"
+
+	t.Run("Invalid query", func(t *testing.T) {
 		operation := vaultoperation.New(newVaultMock())
-		operation.GenerateID = func() (string, error) {
-			return "", errors.New("test error")
+
+		h := handlerLookup(t, operation, vaultoperation.ListAuthorizationsPath, http.MethodGet)
+
+		for query, msg := range map[string]string{
+			"limit=abc":          "invalid limit",
+			"limit=0":            "invalid limit",
+			"includeExpired=abc": "invalid includeExpired",
+		} {
+			respBody, code := sendRequestToHandler(t, h, nil, path+"?"+query)
+
+			require.Equal(t, http.StatusBadRequest, code)
+
+			var errResp *model.ErrorResponse
+
+			require.NoError(t, json.NewDecoder(respBody).Decode(&errResp))
+			require.Contains(t, errResp.Message, msg)
 		}
+	})
 
-		h := handlerLookup(t, operation, vaultoperation.SaveDocPath, http.MethodPost)
-		res, code := sendRequestToHandler(t, h, strings.NewReader(`{}`), path)
+	t.Run("Invalid continuation token", func(t *testing.T) {
+		v := newVaultMock()
+		v.listAuthorizationsFn = func(string, *vault.AuthorizationQuery) (*vault.AuthorizationList, error) {
+			return nil, fmt.Errorf("%w: test", vault.ErrInvalidContinuationToken)
+		}
 
-		require.Equal(t, http.StatusInternalServerError, code)
+		operation := vaultoperation.New(v)
 
-		var errResp *model.ErrorResponse
+		h := handlerLookup(t, operation, vaultoperation.ListAuthorizationsPath, http.MethodGet)
 
-		require.NoError(t, json.NewDecoder(res).Decode(&errResp))
-		require.Contains(t, errResp.Message, "test error")
+		_, code := sendRequestToHandler(t, h, nil, path+"?next=!")
+
+		require.Equal(t, http.StatusBadRequest, code)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		v := newVaultMock()
+		v.listAuthorizationsFn = func(string, *vault.AuthorizationQuery) (*vault.AuthorizationList, error) {
+			return nil, fmt.Errorf("get vault info: %w", storage.ErrDataNotFound)
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.ListAuthorizationsPath, http.MethodGet)
+
+		_, code := sendRequestToHandler(t, h, nil, path)
+
+		require.Equal(t, http.StatusNotFound, code)
+	})
+
+	t.Run("Internal error", func(t *testing.T) {
+		v := newVaultMock()
+		v.listAuthorizationsFn = func(string, *vault.AuthorizationQuery) (*vault.AuthorizationList, error) {
+			return nil, errors.New("test")
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.ListAuthorizationsPath, http.MethodGet)
+
+		_, code := sendRequestToHandler(t, h, nil, path)
+
+		require.Equal(t, http.StatusInternalServerError, code)
 	})
+
 	t.Run("Success", func(t *testing.T) {
-		const path = "/vaults/vaultID1/docs"
+		var (
+			gotVaultID string
+			gotQuery   *vault.AuthorizationQuery
+		)
+
+		v := newVaultMock()
+		v.listAuthorizationsFn = func(vaultID string, query *vault.AuthorizationQuery) (*vault.AuthorizationList, error) {
+			gotVaultID, gotQuery = vaultID, query
+
+			return &vault.AuthorizationList{
+				Authorizations: []*vault.AuthorizationListEntry{{
+					ID:              "authID1",
+					RequestingParty: "did:example:rp",
+					Status:          vault.AuthorizationStatusActive,
+				}},
+				Next: "token2",
+			}, nil
+		}
+
+		operation := vaultoperation.New(v)
+
+		h := handlerLookup(t, operation, vaultoperation.ListAuthorizationsPath, http.MethodGet)
+		res, code := sendRequestToHandler(t, h, nil,
+			path+"?requestingParty=did:example:rp&includeExpired=true&limit=10&next=token1")
+
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, "vaultID1", gotVaultID)
+		require.Equal(t, &vault.AuthorizationQuery{
+			RequestingParty: "did:example:rp",
+			IncludeExpired:  true,
+			Limit:           10,
+			Next:            "token1",
+		}, gotQuery)
+
+		var resp *vault.AuthorizationList
 
+		require.NoError(t, json.NewDecoder(res).Decode(&resp))
+		require.Len(t, resp.Authorizations, 1)
+		require.Equal(t, "authID1", resp.Authorizations[0].ID)
+		require.Equal(t, "token2", resp.Next)
+	})
+}
+
+func TestGetAuditTrail(t *testing.T) {
+	const path = "/vaults/vaultID1/audit"
+
+	t.Run("Invalid query", func(t *testing.T) {
 		operation := vaultoperation.New(newVaultMock())
 
-		h := handlerLookup(t, operation, vaultoperation.SaveDocPath, http.MethodPost)
-		res, code := sendRequestToHandler(t, h, strings.NewReader(`{}`), path)
+		h := handlerLookup(t, operation, vaultoperation.GetAuditTrailPath, http.MethodGet)
 
-		require.Equal(t, http.StatusCreated, code)
+		for query, msg := range map[string]string{
+			"limit=abc":        "invalid limit",
+			"limit=0":          "invalid limit",
+			"since=yesterday":  "invalid since",
+			"until=2021-01-01": "invalid until",
+		} {
+			respBody, code := sendRequestToHandler(t, h, nil, path+"?"+query)
 
-		var resp *vault.DocumentMetadata
+			require.Equal(t, http.StatusBadRequest, code)
+
+			var errResp *model.ErrorResponse
+
+			require.NoError(t, json.NewDecoder(respBody).Decode(&errResp))
+			require.Contains(t, errResp.Message, msg)
+		}
+	})
+
+	t.Run("Invalid continuation token", func(t *testing.T) {
+		v := newVaultMock()
+		v.getAuditTrailFn = func(string, *vault.AuditQuery) (*vault.AuditTrail, error) {
+			return nil, fmt.Errorf("%w: test", vault.ErrInvalidContinuationToken)
+		}
+
+		h := handlerLookup(t, vaultoperation.New(v), vaultoperation.GetAuditTrailPath, http.MethodGet)
+
+		_, code := sendRequestToHandler(t, h, nil, path+"?next=!")
+
+		require.Equal(t, http.StatusBadRequest, code)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		v := newVaultMock()
+		v.getAuditTrailFn = func(string, *vault.AuditQuery) (*vault.AuditTrail, error) {
+			return nil, fmt.Errorf("get vault info: %w", storage.ErrDataNotFound)
+		}
+
+		h := handlerLookup(t, vaultoperation.New(v), vaultoperation.GetAuditTrailPath, http.MethodGet)
+
+		_, code := sendRequestToHandler(t, h, nil, path)
+
+		require.Equal(t, http.StatusNotFound, code)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		var (
+			gotVaultID string
+			gotQuery   *vault.AuditQuery
+		)
+
+		v := newVaultMock()
+		v.getAuditTrailFn = func(vaultID string, query *vault.AuditQuery) (*vault.AuditTrail, error) {
+			gotVaultID, gotQuery = vaultID, query
+
+			return &vault.AuditTrail{
+				Events: []*vault.AuditEvent{{
+					ID:              "event1",
+					Type:            vault.AuditAuthorizationCreated,
+					VaultID:         vaultID,
+					AuthorizationID: "authID1",
+					RequestingParty: "did:example:rp",
+				}},
+				Next: "token2",
+			}, nil
+		}
+
+		h := handlerLookup(t, vaultoperation.New(v), vaultoperation.GetAuditTrailPath, http.MethodGet)
+		res, code := sendRequestToHandler(t, h, nil,
+			path+"?since=2021-06-01T00:00:00Z&until=2021-07-01T12:00:00%2B02:00&limit=10&next=token1")
+
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, "vaultID1", gotVaultID)
+		require.True(t, time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC).Equal(gotQuery.Since))
+		require.True(t, time.Date(2021, 7, 1, 10, 0, 0, 0, time.UTC).Equal(gotQuery.Until))
+		require.Equal(t, 10, gotQuery.Limit)
+		require.Equal(t, "token1", gotQuery.Next)
+
+		var resp *vault.AuditTrail
 
 		require.NoError(t, json.NewDecoder(res).Decode(&resp))
+		require.Len(t, resp.Events, 1)
+		require.Equal(t, vault.AuditAuthorizationCreated, resp.Events[0].Type)
+		require.Equal(t, "token2", resp.Next)
+	})
+}
 
-		require.NotEmpty(t, resp.ID)
-		require.NotEmpty(t, resp.URI)
+func TestDeleteAuthorization(t *testing.T) {
+	const path = "/vaults/vaultID1/authorizations/authID1"
+
+	t.Run("Not found", func(t *testing.T) {
+		v := newVaultMock()
+		v.deleteAuthorizationFn = func(_, _ string) error {
+			return fmt.Errorf("get: %w", storage.ErrDataNotFound)
+		}
+
+		h := handlerLookup(t, vaultoperation.New(v), vaultoperation.DeleteAuthorizationPath, http.MethodDelete)
+		_, code := sendRequestToHandler(t, h, nil, path)
+
+		require.Equal(t, http.StatusNotFound, code)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		v := newVaultMock()
+		v.deleteAuthorizationFn = func(_, _ string) error {
+			return errors.New("test")
+		}
+
+		h := handlerLookup(t, vaultoperation.New(v), vaultoperation.DeleteAuthorizationPath, http.MethodDelete)
+		buf, code := sendRequestToHandler(t, h, nil, path)
+
+		require.Equal(t, http.StatusInternalServerError, code)
+		require.Contains(t, buf.String(), "test")
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		var gotVaultID, gotAuthID string
+
+		v := newVaultMock()
+		v.deleteAuthorizationFn = func(vaultID, id string) error {
+			gotVaultID, gotAuthID = vaultID, id
+
+			return nil
+		}
+
+		h := handlerLookup(t, vaultoperation.New(v), vaultoperation.DeleteAuthorizationPath, http.MethodDelete)
+		_, code := sendRequestToHandler(t, h, nil, path)
+
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, "vaultID1", gotVaultID)
+		require.Equal(t, "authID1", gotAuthID)
 	})
 }
 
-func TestGetDocMetadata(t *testing.T) {
-	const path = "/vaults/vaultID1/docs/docID1/metadata"
+func TestGetRevocation(t *testing.T) {
+	zcapID := "urn:uuid:" + uuid.New().String()
+	path := "/revocations/" + url.PathEscape(zcapID)
 
-	t.Run("Internal error", func(t *testing.T) {
+	t.Run("Not revoked", func(t *testing.T) {
 		v := newVaultMock()
-		v.getDocMetadataFn = func(_, _ string) (*vault.DocumentMetadata, error) {
-			return nil, errors.New("test")
+		v.getRevocationFn = func(_ string) (*vault.Revocation, error) {
+			return nil, fmt.Errorf("get: %w", storage.ErrDataNotFound)
 		}
 
-		operation := vaultoperation.New(v)
+		h := handlerLookup(t, vaultoperation.New(v), vaultoperation.GetRevocationPath, http.MethodGet)
+		_, code := sendRequestToHandler(t, h, nil, path)
+
+		require.Equal(t, http.StatusNotFound, code)
+	})
+
+	t.Run("Revoked", func(t *testing.T) {
+		h := handlerLookup(t, vaultoperation.New(newVaultMock()), vaultoperation.GetRevocationPath, http.MethodGet)
+		buf, code := sendRequestToHandler(t, h, nil, path)
 
-		h := handlerLookup(t, operation, vaultoperation.GetDocMetadataPath, http.MethodGet)
+		require.Equal(t, http.StatusOK, code)
 
-		respBody, code := sendRequestToHandler(t, h, nil, path)
+		var revocation vault.Revocation
 
-		require.Equal(t, http.StatusInternalServerError, code)
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &revocation))
+		require.Equal(t, zcapID, revocation.ZcapID)
+	})
+}
 
-		var errResp *model.ErrorResponse
+// sendRequestToHandler reads response from given http handle func.
+func TestInvocationVerifier(t *testing.T) {
+	protected := []struct {
+		path   string
+		method string
+		url    string
+	}{
+		{vaultoperation.DeleteVaultPath, http.MethodDelete, "/vaults/vaultID1"},
+		{vaultoperation.ExportVaultPath, http.MethodGet, "/vaults/vaultID1/export"},
+		{vaultoperation.RekeyVaultPath, http.MethodPost, "/vaults/vaultID1/rekey"},
+		{vaultoperation.RotateTokensPath, http.MethodPost, "/vaults/vaultID1/rotate-tokens"},
+		{vaultoperation.SaveDocPath, http.MethodPost, "/vaults/vaultID1/docs"},
+		{vaultoperation.DeleteDocPath, http.MethodDelete, "/vaults/vaultID1/docs/docID1"},
+		{vaultoperation.CreateAuthorizationPath, http.MethodPost, "/vaults/vaultID1/authorizations"},
+		{vaultoperation.DeleteAuthorizationPath, http.MethodDelete, "/vaults/vaultID1/authorizations/authID1"},
+		{vaultoperation.CreateWebhookPath, http.MethodPost, "/vaults/vaultID1/webhooks"},
+		{vaultoperation.GetAuditTrailPath, http.MethodGet, "/vaults/vaultID1/audit"},
+		{vaultoperation.GetVaultPath, http.MethodGet, "/vaults/vaultID1"},
+		{vaultoperation.GetRekeyStatusPath, http.MethodGet, "/vaults/vaultID1/rekey"},
+		{vaultoperation.ListDocsPath, http.MethodGet, "/vaults/vaultID1/docs"},
+		{vaultoperation.GetDocPath, http.MethodGet, "/vaults/vaultID1/docs/docID1"},
+		{vaultoperation.PatchDocPath, http.MethodPatch, "/vaults/vaultID1/docs/docID1"},
+		{vaultoperation.GetDocMetadataPath, http.MethodGet, "/vaults/vaultID1/docs/docID1/metadata"},
+		{vaultoperation.RekeyDocPath, http.MethodPost, "/vaults/vaultID1/docs/docID1/rekey"},
+		{vaultoperation.ListAuthorizationsPath, http.MethodGet, "/vaults/vaultID1/authorizations"},
+		{vaultoperation.GetAuthorizationPath, http.MethodGet, "/vaults/vaultID1/authorizations/authID1"},
+		{vaultoperation.UseAuthorizationPath, http.MethodPost, "/vaults/vaultID1/authorizations/authID1/uses"},
+	}
 
-		require.NoError(t, json.NewDecoder(respBody).Decode(&errResp))
-		require.NotEmpty(t, errResp.Message)
-	})
+	t.Run("Unauthorized", func(t *testing.T) {
+		var verified []string
 
-	t.Run("Not found", func(t *testing.T) {
-		v := newVaultMock()
-		v.getDocMetadataFn = func(_, _ string) (*vault.DocumentMetadata, error) {
-			return nil, errors.New(messages.ErrDocumentNotFound.Error() + ".")
-		}
+		operation := vaultoperation.New(newVaultMock(), vaultoperation.WithInvocationVerifier(
+			invocationVerifierFn(func(vaultID string, _ *http.Request) error {
+				verified = append(verified, vaultID)
 
-		operation := vaultoperation.New(v)
+				return fmt.Errorf("%w: invalid HTTP signature", vault.ErrUnauthorizedInvocation)
+			}),
+		))
 
-		h := handlerLookup(t, operation, vaultoperation.GetDocMetadataPath, http.MethodGet)
+		for _, endpoint := range protected {
+			h := handlerLookup(t, operation, endpoint.path, endpoint.method)
 
-		respBody, code := sendRequestToHandler(t, h, nil, path)
+			respBody, code := sendRequestToHandler(t, h, strings.NewReader(`{}`), endpoint.url)
 
-		require.Equal(t, http.StatusNotFound, code)
+			require.Equal(t, http.StatusUnauthorized, code, endpoint.path)
 
-		var errResp *model.ErrorResponse
+			var errResp *model.ErrorResponse
 
-		require.NoError(t, json.NewDecoder(respBody).Decode(&errResp))
-		require.NotEmpty(t, errResp.Message)
+			require.NoError(t, json.NewDecoder(respBody).Decode(&errResp))
+			require.Equal(t, model.ErrCodeUnauthorized, errResp.Code)
+			require.Contains(t, errResp.Message, "invalid HTTP signature")
+		}
+
+		require.Len(t, verified, len(protected))
+
+		for _, vaultID := range verified {
+			require.Equal(t, "vaultID1", vaultID)
+		}
 	})
 
-	t.Run("Success", func(t *testing.T) {
-		operation := vaultoperation.New(newVaultMock())
+	t.Run("Unknown vault", func(t *testing.T) {
+		operation := vaultoperation.New(newVaultMock(), vaultoperation.WithInvocationVerifier(
+			invocationVerifierFn(func(string, *http.Request) error {
+				return fmt.Errorf("get vault info: %w", storage.ErrDataNotFound)
+			}),
+		))
 
-		h := handlerLookup(t, operation, vaultoperation.GetDocMetadataPath, http.MethodGet)
-		res, code := sendRequestToHandler(t, h, strings.NewReader(`{}`), path)
+		h := handlerLookup(t, operation, vaultoperation.DeleteDocPath, http.MethodDelete)
 
-		require.Equal(t, http.StatusOK, code)
+		_, code := sendRequestToHandler(t, h, nil, "/vaults/vaultID1/docs/docID1")
+		require.Equal(t, http.StatusNotFound, code)
+	})
 
-		var resp *vault.DocumentMetadata
+	t.Run("Verifier error", func(t *testing.T) {
+		operation := vaultoperation.New(newVaultMock(), vaultoperation.WithInvocationVerifier(
+			invocationVerifierFn(func(string, *http.Request) error {
+				return errors.New("test")
+			}),
+		))
 
-		require.NoError(t, json.NewDecoder(res).Decode(&resp))
+		h := handlerLookup(t, operation, vaultoperation.DeleteDocPath, http.MethodDelete)
 
-		require.NotEmpty(t, resp.ID)
-		require.NotEmpty(t, resp.URI)
+		_, code := sendRequestToHandler(t, h, nil, "/vaults/vaultID1/docs/docID1")
+		require.Equal(t, http.StatusInternalServerError, code)
 	})
-}
 
-func TestOperation_GetAuthorization(t *testing.T) {
-	const path = "/vaults/vaultID/authorizations/authID"
+	t.Run("Authorized", func(t *testing.T) {
+		var deleted string
 
-	t.Run("Internal error", func(t *testing.T) {
 		v := newVaultMock()
-		v.getAuthorizationFn = func(_, _ string) (*vault.CreatedAuthorization, error) {
-			return nil, errors.New("test")
+		v.deleteDocFn = func(vaultID, docID string) error {
+			deleted = vaultID + "/" + docID
+
+			return nil
 		}
 
-		operation := vaultoperation.New(v)
+		operation := vaultoperation.New(v, vaultoperation.WithInvocationVerifier(
+			invocationVerifierFn(func(string, *http.Request) error {
+				return nil
+			}),
+		))
 
-		h := handlerLookup(t, operation, vaultoperation.GetAuthorizationPath, http.MethodGet)
+		h := handlerLookup(t, operation, vaultoperation.DeleteDocPath, http.MethodDelete)
 
-		respBody, code := sendRequestToHandler(t, h, nil, path)
+		_, code := sendRequestToHandler(t, h, nil, "/vaults/vaultID1/docs/docID1")
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, "vaultID1/docID1", deleted)
+	})
 
-		require.Equal(t, http.StatusInternalServerError, code)
+	t.Run("Challenges are issued to anyone", func(t *testing.T) {
+		operation := vaultoperation.New(newVaultMock(), vaultoperation.WithInvocationVerifier(
+			invocationVerifierFn(func(string, *http.Request) error {
+				return fmt.Errorf("%w: invalid HTTP signature", vault.ErrUnauthorizedInvocation)
+			}),
+		))
 
-		var errResp *model.ErrorResponse
+		h := handlerLookup(t, operation, vaultoperation.CreateChallengePath, http.MethodPost)
 
-		require.NoError(t, json.NewDecoder(respBody).Decode(&errResp))
-		require.NotEmpty(t, errResp.Message)
+		_, code := sendRequestToHandler(t, h, nil, "/vaults/vaultID1/authorizations/challenges")
+		require.Equal(t, http.StatusCreated, code)
 	})
 
-	t.Run("Not found", func(t *testing.T) {
+	t.Run("Vaults are created with the signature of their owner", func(t *testing.T) {
 		v := newVaultMock()
-		v.getAuthorizationFn = func(_, _ string) (*vault.CreatedAuthorization, error) {
-			return nil, storage.ErrDataNotFound
-		}
 
-		operation := vaultoperation.New(v)
+		operation := vaultoperation.New(v, vaultoperation.WithInvocationVerifier(
+			invocationVerifierFn(func(string, *http.Request) error {
+				return nil
+			}),
+		))
 
-		h := handlerLookup(t, operation, vaultoperation.GetAuthorizationPath, http.MethodGet)
+		h := handlerLookup(t, operation, vaultoperation.CreateVaultPath, http.MethodPost)
 
-		respBody, code := sendRequestToHandler(t, h, nil, path)
+		_, code := sendRequestToHandler(t, h, nil, "/vaults")
+		require.Equal(t, http.StatusUnauthorized, code)
+		require.Nil(t, v.createVaultOpts)
 
-		require.Equal(t, http.StatusNotFound, code)
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, "/vaults", nil)
+		require.NoError(t, err)
 
-		var errResp *model.ErrorResponse
+		req.Header.Set("Signature", `keyId="did:example:123#key-1",signature="c2ln"`)
 
-		require.NoError(t, json.NewDecoder(respBody).Decode(&errResp))
-		require.NotEmpty(t, errResp.Message)
+		rr := serveRequest(h, req)
+		require.Equal(t, http.StatusCreated, rr.Code)
+		require.Len(t, v.createVaultOpts, 1)
 	})
 
-	t.Run("Success", func(t *testing.T) {
-		operation := vaultoperation.New(newVaultMock())
-
-		h := handlerLookup(t, operation, vaultoperation.GetAuthorizationPath, http.MethodGet)
-		res, code := sendRequestToHandler(t, h, nil, path)
+	t.Run("Listing vaults is verified for the controller", func(t *testing.T) {
+		var verified []string
 
-		require.Equal(t, http.StatusOK, code)
+		operation := vaultoperation.New(newVaultMock(), vaultoperation.WithAdminToken("admin"),
+			vaultoperation.WithInvocationVerifier(
+				invocationVerifierFn(func(controller string, _ *http.Request) error {
+					verified = append(verified, controller)
 
-		var resp *vault.CreatedVault
+					return fmt.Errorf("%w: invalid HTTP signature", vault.ErrUnauthorizedInvocation)
+				}),
+			))
 
-		require.NoError(t, json.NewDecoder(res).Decode(&resp))
+		h := handlerLookup(t, operation, vaultoperation.ListVaultsPath, http.MethodGet)
 
-		require.NotEmpty(t, resp.ID)
-	})
-}
+		_, code := sendRequestToHandler(t, h, nil, "/vaults?controller=did:example:123")
+		require.Equal(t, http.StatusUnauthorized, code)
+		require.Equal(t, []string{"did:example:123"}, verified)
 
-func TestCreateAuthorization(t *testing.T) {
-	const path = "/vaults/vaultID1/authorizations"
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet,
+			"/vaults?controller=did:example:123", nil)
+		require.NoError(t, err)
 
-	t.Run("JSON error", func(t *testing.T) {
-		operation := vaultoperation.New(newVaultMock())
+		req.Header.Set("Authorization", "Bearer admin")
 
-		h := handlerLookup(t, operation, vaultoperation.CreateAuthorizationPath, http.MethodPost)
-		res, code := sendRequestToHandler(t, h, strings.NewReader(`{`), path)
+		rr := serveRequest(h, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Len(t, verified, 1)
 
+		_, code = sendRequestToHandler(t, h, nil, "/vaults")
 		require.Equal(t, http.StatusBadRequest, code)
-
-		var errResp *model.ErrorResponse
-
-		require.NoError(t, json.NewDecoder(res).Decode(&errResp))
-		require.Contains(t, errResp.Message, "unexpected EOF")
+		require.Len(t, verified, 1)
 	})
 
-	t.Run("Error", func(t *testing.T) {
+	t.Run("Reads are verified", func(t *testing.T) {
 		v := newVaultMock()
-		v.createAuthorizationFn = func(vID, rp string, scope *vault.AuthorizationsScope,
-		) (*vault.CreatedAuthorization, error) {
-			return nil, errors.New("test error")
+		v.getDocFn = func(_, _ string) ([]byte, error) {
+			return []byte(`{}`), nil
+		}
+		v.getAuthorizationFn = func(_, _ string) (*vault.CreatedAuthorization, error) {
+			return &vault.CreatedAuthorization{}, nil
 		}
 
-		operation := vaultoperation.New(v)
+		operation := vaultoperation.New(v, vaultoperation.WithInvocationVerifier(
+			invocationVerifierFn(func(string, *http.Request) error {
+				return vault.ErrUnauthorizedInvocation
+			}),
+		))
 
-		h := handlerLookup(t, operation, vaultoperation.CreateAuthorizationPath, http.MethodPost)
-		res, code := sendRequestToHandler(t, h, strings.NewReader(`{}`), path)
+		h := handlerLookup(t, operation, vaultoperation.GetDocPath, http.MethodGet)
 
-		require.Equal(t, http.StatusInternalServerError, code)
+		_, code := sendRequestToHandler(t, h, nil, "/vaults/vaultID1/docs/docID1")
+		require.Equal(t, http.StatusUnauthorized, code)
 
-		var errResp *model.ErrorResponse
+		h = handlerLookup(t, operation, vaultoperation.GetAuthorizationPath, http.MethodGet)
 
-		require.NoError(t, json.NewDecoder(res).Decode(&errResp))
-		require.Contains(t, errResp.Message, "test error")
+		_, code = sendRequestToHandler(t, h, nil, "/vaults/vaultID1/authorizations/authID1")
+		require.Equal(t, http.StatusUnauthorized, code)
+
+		h = handlerLookup(t, operation, vaultoperation.GetDocMetadataPath, http.MethodHead)
+
+		_, code = sendRequestToHandler(t, h, nil, "/vaults/vaultID1/docs/docID1/metadata")
+		require.Equal(t, http.StatusUnauthorized, code)
 	})
 
-	t.Run("Success", func(t *testing.T) {
-		operation := vaultoperation.New(newVaultMock())
+	t.Run("Document URIs are verified for their vault", func(t *testing.T) {
+		var verified []string
+
+		operation := vaultoperation.New(newVaultMock(), vaultoperation.WithInvocationVerifier(
+			invocationVerifierFn(func(uri string, _ *http.Request) error {
+				verified = append(verified, uri)
+
+				switch uri {
+				case "https://edv.example.com/invalid":
+					return vault.ErrInvalidDocURI
+				case "https://edv.example.com/unknown":
+					return vault.ErrDocumentNotFound
+				default:
+					return vault.ErrUnauthorizedInvocation
+				}
+			}),
+		))
+
+		h := handlerLookup(t, operation, vaultoperation.GetDocMetadataByURIPath, http.MethodGet)
+
+		for uri, status := range map[string]int{
+			"https://edv.example.com/doc":     http.StatusUnauthorized,
+			"https://edv.example.com/invalid": http.StatusBadRequest,
+			"https://edv.example.com/unknown": http.StatusNotFound,
+		} {
+			_, code := sendRequestToHandler(t, h, nil, "/vaults/docs/metadata?uri="+url.QueryEscape(uri))
+			require.Equal(t, status, code, uri)
+		}
 
-		h := handlerLookup(t, operation, vaultoperation.CreateAuthorizationPath, http.MethodPost)
-		res, code := sendRequestToHandler(t, h, strings.NewReader(`{}`), path)
+		require.Len(t, verified, 3)
 
-		require.Equal(t, http.StatusCreated, code)
+		_, code := sendRequestToHandler(t, h, nil, "/vaults/docs/metadata")
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Len(t, verified, 3)
+	})
 
-		var resp *vault.CreatedAuthorization
+	t.Run("Imports require the admin token", func(t *testing.T) {
+		v := newVaultMock()
+		v.importVaultFn = func(io.Reader) (*vault.VaultImport, error) {
+			return &vault.VaultImport{CreatedVault: &vault.CreatedVault{ID: "vaultID2"}}, nil
+		}
 
-		require.NoError(t, json.NewDecoder(res).Decode(&resp))
+		operation := vaultoperation.New(v, vaultoperation.WithAdminToken("admin"),
+			vaultoperation.WithInvocationVerifier(
+				invocationVerifierFn(func(string, *http.Request) error {
+					return nil
+				}),
+			))
 
-		require.NotEmpty(t, resp.ID)
+		h := handlerLookup(t, operation, vaultoperation.ImportVaultPath, http.MethodPost)
+
+		_, code := sendRequestToHandler(t, h, strings.NewReader(`{}`), "/vaults/import")
+		require.Equal(t, http.StatusUnauthorized, code)
+
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, "/vaults/import",
+			strings.NewReader(`{}`))
+		require.NoError(t, err)
+
+		req.Header.Set("Authorization", "Bearer admin")
+
+		rr := serveRequest(h, req)
+		require.Equal(t, http.StatusCreated, rr.Code)
 	})
 }
 
-func TestGetAuthorization(t *testing.T) {
-	const path = "/vaults/vaultID1/authorizations/authID1"
+type invocationVerifierFn func(vaultID string, req *http.Request) error
 
-	operation := vaultoperation.New(newVaultMock())
+func (fn invocationVerifierFn) VerifyInvocation(vaultID string, req *http.Request) error {
+	return fn(vaultID, req)
+}
 
-	h := handlerLookup(t, operation, vaultoperation.GetAuthorizationPath, http.MethodGet)
-	_, code := sendRequestToHandler(t, h, nil, path)
+func (fn invocationVerifierFn) VerifyDocURIInvocation(uri string, req *http.Request) error {
+	return fn(uri, req)
+}
 
-	require.Equal(t, http.StatusOK, code)
+func (fn invocationVerifierFn) VerifyAuthorizationInvocation(vaultID, _ string, req *http.Request) error {
+	return fn(vaultID, req)
 }
 
-func TestDeleteVault(t *testing.T) {
-	const path = "/vaults/vaultID1"
+func (fn invocationVerifierFn) VerifyControllerInvocation(controller string, req *http.Request) error {
+	return fn(controller, req)
+}
 
-	operation := vaultoperation.New(newVaultMock())
+func sendRequestToHandler(t *testing.T, h handler.Handler, reqBody io.Reader, path string) (*bytes.Buffer, int) {
+	t.Helper()
 
-	h := handlerLookup(t, operation, vaultoperation.DeleteVaultPath, http.MethodDelete)
-	_, code := sendRequestToHandler(t, h, nil, path)
+	// prepare request
+	req, err := http.NewRequestWithContext(context.Background(), h.Method(), path, reqBody)
+	require.NoError(t, err)
 
-	require.Equal(t, http.StatusOK, code)
+	rr := serveRequest(h, req)
+
+	return rr.Body, rr.Code
 }
 
-func TestWriteResponse(t *testing.T) {
-	rec := httptest.NewRecorder()
+type response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
 
-	(&vaultoperation.Operation{}).WriteResponse(rec, make(chan int), http.StatusInternalServerError)
-	reader := rec.Result().Body
+// doRequest sends the request as a client accepting gzip would.
+func doRequest(t *testing.T, client *http.Client, method, url string) *response {
+	t.Helper()
 
-	res, err := io.ReadAll(reader)
-	require.NoError(t, reader.Close())
+	req, err := http.NewRequestWithContext(context.Background(), method, url, nil)
 	require.NoError(t, err)
-	require.Empty(t, res)
-}
 
-func TestDeleteAuthorization(t *testing.T) {
-	const path = "/vaults/vaultID1/authorizations/authID1"
+	req.Header.Set("Accept-Encoding", "gzip")
 
-	operation := vaultoperation.New(newVaultMock())
+	resp, err := client.Do(req)
+	require.NoError(t, err)
 
-	h := handlerLookup(t, operation, vaultoperation.DeleteAuthorizationPath, http.MethodDelete)
-	_, code := sendRequestToHandler(t, h, nil, path)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
 
-	require.Equal(t, http.StatusOK, code)
+	return &response{StatusCode: resp.StatusCode, Header: resp.Header, Body: body}
 }
 
-// sendRequestToHandler reads response from given http handle func.
-func sendRequestToHandler(t *testing.T, h handler.Handler, reqBody io.Reader, path string) (*bytes.Buffer, int) {
+func sendAdminRequestToHandler(t *testing.T, h handler.Handler, path string) (*bytes.Buffer, int) {
 	t.Helper()
 
-	// prepare request
-	req, err := http.NewRequestWithContext(context.Background(), h.Method(), path, reqBody)
+	req, err := http.NewRequestWithContext(context.Background(), h.Method(), path, nil)
 	require.NoError(t, err)
 
+	req.Header.Set("Authorization", "Bearer admin")
+
+	rr := serveRequest(h, req)
+
+	return rr.Body, rr.Code
+}
+
+func serveRequest(h handler.Handler, req *http.Request) *httptest.ResponseRecorder {
 	// prepare router
 	router := mux.NewRouter()
 
@@ -381,7 +3185,7 @@ func sendRequestToHandler(t *testing.T, h handler.Handler, reqBody io.Reader, pa
 	// serve http on given response and request
 	router.ServeHTTP(rr, req)
 
-	return rr.Body, rr.Code
+	return rr
 }
 
 func handlerLookup(t *testing.T, op *vaultoperation.Operation, lookup, method string) handler.Handler { //nolint:ireturn
@@ -427,40 +3231,283 @@ func newVaultMock() *vaultMock {
 				URI: "localhost:7777/encrypted-data-vaults/HwtZ1bUn4SzXoQRoX9br6m/documents/M3aS9xwj8ybCwHkEiCJJR1",
 			}, nil
 		},
-		createAuthorizationFn: func(vID, rp string, scope *vault.AuthorizationsScope) (*vault.CreatedAuthorization, error) {
+		saveBinaryDocFn: func(vaultID, id, mediaType string, content []byte) (*vault.DocumentMetadata, error) {
+			return &vault.DocumentMetadata{ID: id}, nil
+		},
+		getDocFn: func(vaultID, id string) ([]byte, error) {
+			return []byte(`{"message":"Hello World!"}`), nil
+		},
+		getDocContentFn: func(vaultID, id string) (*vault.DocumentContent, error) {
+			return &vault.DocumentContent{MediaType: "application/json", Data: []byte(`{"message":"Hello World!"}`)}, nil
+		},
+		deleteDocFn: func(vaultID, id string) error {
+			return nil
+		},
+		listDocsFn: func(vaultID string, limit int, next string) (*vault.DocumentList, error) {
+			return &vault.DocumentList{Documents: []*vault.DocumentListEntry{}}, nil
+		},
+		listVaultsFn: func(controller string, limit int, next string) (*vault.VaultList, error) {
+			return &vault.VaultList{Vaults: []*vault.VaultListEntry{}}, nil
+		},
+		createAuthorizationFn: func(vID, rp string, scope *vault.AuthorizationsScope,
+			opts []vault.CreateAuthorizationOpt) (*vault.CreatedAuthorization, error) {
 			return &vault.CreatedAuthorization{ID: uuid.New().String()}, nil
 		},
+		createChallengeFn: func(vaultID string) (*vault.AuthorizationChallenge, error) {
+			return &vault.AuthorizationChallenge{Challenge: "challenge", ExpiresAt: time.Now().Add(time.Minute)}, nil
+		},
 		getAuthorizationFn: func(vaultID, id string) (*vault.CreatedAuthorization, error) {
 			return &vault.CreatedAuthorization{ID: uuid.New().String()}, nil
 		},
+		useAuthorizationFn: func(vaultID, id string) (*vault.CreatedAuthorization, error) {
+			return &vault.CreatedAuthorization{ID: id, Uses: 1}, nil
+		},
+		listAuthorizationsFn: func(vaultID string, query *vault.AuthorizationQuery) (*vault.AuthorizationList, error) {
+			return &vault.AuthorizationList{Authorizations: []*vault.AuthorizationListEntry{}}, nil
+		},
+		deleteAuthorizationFn: func(vaultID, id string) error {
+			return nil
+		},
+		getAuditTrailFn: func(vaultID string, query *vault.AuditQuery) (*vault.AuditTrail, error) {
+			return &vault.AuditTrail{Events: []*vault.AuditEvent{}}, nil
+		},
+		getRevocationFn: func(zcapID string) (*vault.Revocation, error) {
+			return &vault.Revocation{ZcapID: zcapID, RevokedAt: time.Now()}, nil
+		},
+		exportVaultFn: func(vaultID string) (*vault.VaultExport, error) {
+			return &vault.VaultExport{Vault: &vault.VaultInfo{ID: vaultID}}, nil
+		},
+		importVaultFn: func(archive io.Reader) (*vault.VaultImport, error) {
+			return &vault.VaultImport{
+				CreatedVault: &vault.CreatedVault{ID: "did:key:z6MkiCxgAoySWK"},
+				SourceID:     "did:key:z6MkpSourceVault",
+			}, nil
+		},
+		rekeyVaultFn: func(vaultID string) (*vault.VaultRekey, error) {
+			return &vault.VaultRekey{ID: vaultID, Complete: true, Total: 1, Rekeyed: 1}, nil
+		},
+		rekeyDocFn: func(vaultID, docID string) (*vault.DocumentMetadata, error) {
+			return &vault.DocumentMetadata{ID: docID, Sequence: 1}, nil
+		},
+		rotateTokensFn: func(vaultID string) (*vault.CreatedVault, error) {
+			return &vault.CreatedVault{ID: vaultID, Authorization: &vault.Authorization{
+				EDV: &vault.Location{URI: "https://edv.example.com/encrypted-data-vaults/1", AuthToken: "edv-token"},
+				KMS: &vault.Location{URI: "https://kms.example.com/kms/keystores/1", AuthToken: "kms-token"},
+			}}, nil
+		},
+		getRekeyStatusFn: func(vaultID string) (*vault.VaultRekey, error) {
+			return &vault.VaultRekey{ID: vaultID, Total: 2, Rekeyed: 1}, nil
+		},
+		createWebhookFn: func(vaultID, webhookURL, secret string) (*vault.Webhook, error) {
+			return &vault.Webhook{ID: uuid.New().String(), URL: webhookURL, Created: time.Now()}, nil
+		},
+		listWebhooksFn: func(vaultID string) (*vault.WebhookList, error) {
+			return &vault.WebhookList{Webhooks: []*vault.Webhook{}}, nil
+		},
+		deleteWebhookFn: func(vaultID, id string) error {
+			return nil
+		},
 	}
 }
 
 type vaultMock struct {
 	createVaultFn         func() (*vault.CreatedVault, error)
+	createWithControlFn   func(controller, verificationMethod string, req *http.Request) (*vault.CreatedVault, error)
+	getVaultInfoFn        func(vaultID string) (*vault.VaultInfo, error)
+	listVaultsFn          func(controller string, limit int, next string) (*vault.VaultList, error)
+	deleteVaultFn         func(vaultID string) (*vault.VaultDeletion, error)
 	saveDocFn             func(vaultID, id string, content interface{}) (*vault.DocumentMetadata, error)
 	getDocMetadataFn      func(vaultID, docID string) (*vault.DocumentMetadata, error)
-	createAuthorizationFn func(vID, rp string, scope *vault.AuthorizationsScope) (*vault.CreatedAuthorization, error)
+	getDocMetadataByURIFn func(uri string) (*vault.DocumentMetadata, error)
+	patchDocFn            func(vaultID, docID string, patch []byte,
+		opts []vault.SaveDocOpt) (*vault.DocumentMetadata, error)
+	jsonPatchDocFn func(vaultID, docID string, patch []byte,
+		opts []vault.SaveDocOpt) (*vault.DocumentMetadata, error)
+	saveBinaryDocFn       func(vaultID, id, mediaType string, content []byte) (*vault.DocumentMetadata, error)
+	getDocFn              func(vaultID, docID string) ([]byte, error)
+	getDocContentFn       func(vaultID, docID string) (*vault.DocumentContent, error)
+	deleteDocFn           func(vaultID, docID string) error
+	listDocsFn            func(vaultID string, limit int, next string) (*vault.DocumentList, error)
+	createAuthorizationFn func(vID, rp string, scope *vault.AuthorizationsScope,
+		opts []vault.CreateAuthorizationOpt) (*vault.CreatedAuthorization, error)
+	createChallengeFn     func(vaultID string) (*vault.AuthorizationChallenge, error)
 	getAuthorizationFn    func(vaultID, id string) (*vault.CreatedAuthorization, error)
+	useAuthorizationFn    func(vaultID, id string) (*vault.CreatedAuthorization, error)
+	listAuthorizationsFn  func(vaultID string, query *vault.AuthorizationQuery) (*vault.AuthorizationList, error)
+	deleteAuthorizationFn func(vaultID, id string) error
+	getAuditTrailFn       func(vaultID string, query *vault.AuditQuery) (*vault.AuditTrail, error)
+	getRevocationFn       func(zcapID string) (*vault.Revocation, error)
+	exportVaultFn         func(vaultID string) (*vault.VaultExport, error)
+	importVaultFn         func(archive io.Reader) (*vault.VaultImport, error)
+	rekeyVaultFn          func(vaultID string) (*vault.VaultRekey, error)
+	rotateTokensFn        func(vaultID string) (*vault.CreatedVault, error)
+	rekeyDocFn            func(vaultID, docID string) (*vault.DocumentMetadata, error)
+	getRekeyStatusFn      func(vaultID string) (*vault.VaultRekey, error)
+	findDocsFn            func(vaultID, name, value string) (*vault.DocumentList, error)
+	createWebhookFn       func(vaultID, webhookURL, secret string) (*vault.Webhook, error)
+	listWebhooksFn        func(vaultID string) (*vault.WebhookList, error)
+	deleteWebhookFn       func(vaultID, id string) error
+	saveDocOpts           []vault.SaveDocOpt
+	createVaultOpts       []vault.CreateVaultOpt
 }
 
-func (v *vaultMock) CreateVault() (*vault.CreatedVault, error) {
+func (v *vaultMock) CreateVault(opts ...vault.CreateVaultOpt) (*vault.CreatedVault, error) {
+	v.createVaultOpts = opts
+
 	return v.createVaultFn()
 }
 
-func (v *vaultMock) SaveDoc(vaultID, id string, content []byte) (*vault.DocumentMetadata, error) {
+func (v *vaultMock) CreateVaultWithController(controller, verificationMethod string, req *http.Request,
+	opts ...vault.CreateVaultOpt) (*vault.CreatedVault, error) {
+	v.createVaultOpts = opts
+
+	return v.createWithControlFn(controller, verificationMethod, req)
+}
+
+func (v *vaultMock) GetVaultInfo(vaultID string) (*vault.VaultInfo, error) {
+	return v.getVaultInfoFn(vaultID)
+}
+
+func (v *vaultMock) ListVaults(controller string, limit int, next string) (*vault.VaultList, error) {
+	return v.listVaultsFn(controller, limit, next)
+}
+
+func (v *vaultMock) DeleteVault(vaultID string) (*vault.VaultDeletion, error) {
+	return v.deleteVaultFn(vaultID)
+}
+
+func (v *vaultMock) SaveDoc(vaultID, id string, content []byte,
+	opts ...vault.SaveDocOpt,
+) (*vault.DocumentMetadata, error) {
+	v.saveDocOpts = opts
+
 	return v.saveDocFn(vaultID, id, content)
 }
 
+func (v *vaultMock) SaveBinaryDoc(vaultID, id, mediaType string, content []byte,
+	opts ...vault.SaveDocOpt,
+) (*vault.DocumentMetadata, error) {
+	v.saveDocOpts = opts
+
+	return v.saveBinaryDocFn(vaultID, id, mediaType, content)
+}
+
+func (v *vaultMock) SaveBinaryDocStream(vaultID, id, mediaType string, content io.Reader,
+	opts ...vault.SaveDocOpt,
+) (*vault.DocumentMetadata, error) {
+	src, err := io.ReadAll(content)
+	if err != nil {
+		return nil, err
+	}
+
+	return v.SaveBinaryDoc(vaultID, id, mediaType, src, opts...)
+}
+
 func (v *vaultMock) GetDocMetadata(vaultID, docID string) (*vault.DocumentMetadata, error) {
 	return v.getDocMetadataFn(vaultID, docID)
 }
 
+func (v *vaultMock) GetDocMetadataByURI(uri string) (*vault.DocumentMetadata, error) {
+	return v.getDocMetadataByURIFn(uri)
+}
+
+func (v *vaultMock) PatchDoc(vaultID, docID string, patch []byte,
+	opts ...vault.SaveDocOpt) (*vault.DocumentMetadata, error) {
+	return v.patchDocFn(vaultID, docID, patch, opts)
+}
+
+func (v *vaultMock) JSONPatchDoc(vaultID, docID string, patch []byte,
+	opts ...vault.SaveDocOpt) (*vault.DocumentMetadata, error) {
+	return v.jsonPatchDocFn(vaultID, docID, patch, opts)
+}
+
+func (v *vaultMock) GetDoc(vaultID, docID string) ([]byte, error) {
+	return v.getDocFn(vaultID, docID)
+}
+
+func (v *vaultMock) GetDocContent(vaultID, docID string) (*vault.DocumentContent, error) {
+	return v.getDocContentFn(vaultID, docID)
+}
+
+func (v *vaultMock) DeleteDoc(vaultID, docID string) error {
+	return v.deleteDocFn(vaultID, docID)
+}
+
+func (v *vaultMock) ListDocs(vaultID string, limit int, next string) (*vault.DocumentList, error) {
+	return v.listDocsFn(vaultID, limit, next)
+}
+
 func (v *vaultMock) CreateAuthorization(vID, rp string, scope *vault.AuthorizationsScope,
+	opts ...vault.CreateAuthorizationOpt,
 ) (*vault.CreatedAuthorization, error) {
-	return v.createAuthorizationFn(vID, rp, scope)
+	return v.createAuthorizationFn(vID, rp, scope, opts)
+}
+
+func (v *vaultMock) CreateAuthorizationChallenge(vaultID string) (*vault.AuthorizationChallenge, error) {
+	return v.createChallengeFn(vaultID)
 }
 
 func (v *vaultMock) GetAuthorization(vaultID, id string) (*vault.CreatedAuthorization, error) {
 	return v.getAuthorizationFn(vaultID, id)
 }
+
+func (v *vaultMock) UseAuthorization(vaultID, id string) (*vault.CreatedAuthorization, error) {
+	return v.useAuthorizationFn(vaultID, id)
+}
+
+func (v *vaultMock) ListAuthorizations(vaultID string, query *vault.AuthorizationQuery,
+) (*vault.AuthorizationList, error) {
+	return v.listAuthorizationsFn(vaultID, query)
+}
+
+func (v *vaultMock) DeleteAuthorization(vaultID, id string) error {
+	return v.deleteAuthorizationFn(vaultID, id)
+}
+
+func (v *vaultMock) GetAuditTrail(vaultID string, query *vault.AuditQuery) (*vault.AuditTrail, error) {
+	return v.getAuditTrailFn(vaultID, query)
+}
+
+func (v *vaultMock) GetRevocation(zcapID string) (*vault.Revocation, error) {
+	return v.getRevocationFn(zcapID)
+}
+
+func (v *vaultMock) ExportVault(vaultID string) (*vault.VaultExport, error) {
+	return v.exportVaultFn(vaultID)
+}
+
+func (v *vaultMock) ImportVault(archive io.Reader) (*vault.VaultImport, error) {
+	return v.importVaultFn(archive)
+}
+
+func (v *vaultMock) RekeyVault(vaultID string) (*vault.VaultRekey, error) {
+	return v.rekeyVaultFn(vaultID)
+}
+
+func (v *vaultMock) RotateTokens(vaultID string) (*vault.CreatedVault, error) {
+	return v.rotateTokensFn(vaultID)
+}
+
+func (v *vaultMock) RekeyDoc(vaultID, docID string) (*vault.DocumentMetadata, error) {
+	return v.rekeyDocFn(vaultID, docID)
+}
+
+func (v *vaultMock) GetRekeyStatus(vaultID string) (*vault.VaultRekey, error) {
+	return v.getRekeyStatusFn(vaultID)
+}
+
+func (v *vaultMock) FindDocs(vaultID, name, value string) (*vault.DocumentList, error) {
+	return v.findDocsFn(vaultID, name, value)
+}
+
+func (v *vaultMock) CreateWebhook(vaultID, webhookURL, secret string) (*vault.Webhook, error) {
+	return v.createWebhookFn(vaultID, webhookURL, secret)
+}
+
+func (v *vaultMock) ListWebhooks(vaultID string) (*vault.WebhookList, error) {
+	return v.listWebhooksFn(vaultID)
+}
+
+func (v *vaultMock) DeleteWebhook(vaultID, id string) error {
+	return v.deleteWebhookFn(vaultID, id)
+}
@@ -0,0 +1,138 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	edv "github.com/trustbloc/edv/pkg/client"
+	"github.com/trustbloc/edv/pkg/restapi/models"
+)
+
+// ErrInvalidPatch is returned when a merge patch is not a JSON object, or when a JSON patch is malformed or cannot be
+// applied to the document.
+var ErrInvalidPatch = errors.New("invalid patch")
+
+// ErrNotJSONDocument is returned when a JSON document is expected, eg. to apply a patch, and the document is binary.
+var ErrNotJSONDocument = errors.New("not a JSON document")
+
+// withIndexedAttributes stores the document with the given encrypted indexes unless index tags are given.
+func withIndexedAttributes(indexed []models.IndexedAttributeCollection) SaveDocOpt {
+	return func(opts *saveDocOpts) {
+		opts.indexed = indexed
+	}
+}
+
+// PatchDoc applies a JSON merge patch (RFC 7386) to the content of a JSON document and saves the result. The
+// document keeps its index tags, unless WithIndexTags is given, and stays encrypted to the controller of the vault
+// if it was. Fails with a SequenceMismatchError if the document is updated concurrently, or if its sequence is not
+// the one given with WithExpectedSequence.
+func (c *Client) PatchDoc(vaultID, docID string, patch []byte, opts ...SaveDocOpt) (*DocumentMetadata, error) {
+	var fields map[string]interface{}
+
+	if err := json.Unmarshal(patch, &fields); err != nil || fields == nil {
+		return nil, fmt.Errorf("%w: must be a JSON object", ErrInvalidPatch)
+	}
+
+	return c.patchDoc(vaultID, docID, func(original []byte) ([]byte, error) {
+		return jsonpatch.MergePatch(original, patch)
+	}, opts)
+}
+
+// JSONPatchDoc applies a JSON patch (RFC 6902) to the content of a JSON document and saves the result, like
+// PatchDoc. The operations are applied in order, and the document is left unchanged if any of them fails, eg. a
+// test operation.
+func (c *Client) JSONPatchDoc(vaultID, docID string, patch []byte, opts ...SaveDocOpt) (*DocumentMetadata, error) {
+	operations, err := jsonpatch.DecodePatch(patch)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPatch, err)
+	}
+
+	return c.patchDoc(vaultID, docID, operations.Apply, opts)
+}
+
+// patchDoc saves the document with the content returned by apply for its current content.
+func (c *Client) patchDoc(vaultID, docID string, apply func([]byte) ([]byte, error),
+	opts []SaveDocOpt) (*DocumentMetadata, error) {
+	info, err := c.getVaultInfo(vaultID)
+	if err != nil {
+		return nil, fmt.Errorf("get vault info: %w", err)
+	}
+
+	backend, err := c.edvBackend(info)
+	if err != nil {
+		return nil, err
+	}
+
+	dInfo, err := c.getMetaDocInfo(vaultID, docID)
+	if err != nil {
+		return nil, fmt.Errorf("get meta doc info: %w", err)
+	}
+
+	options := &saveDocOpts{}
+
+	for _, fn := range opts {
+		fn(options)
+	}
+
+	if options.expectedSequence != nil && *options.expectedSequence != dInfo.Sequence {
+		return nil, &SequenceMismatchError{Expected: *options.expectedSequence, Current: dInfo.Sequence}
+	}
+
+	encDoc, err := backend.client.ReadDocument(lastElm(info.Auth.EDV.URI, "/"), dInfo.EdvID, edv.WithRequestHeader(
+		c.edvSign(info.DidURL, info.Auth.EDV)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("read document: %w", err)
+	}
+
+	doc, err := decryptDocument(c.webKMS(info), c.webCrypto(info), encDoc.JWE)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt document: %w", err)
+	}
+
+	if _, binary := doc.Meta[mediaTypeField]; binary {
+		return nil, fmt.Errorf("%w: %s is a binary document", ErrNotJSONDocument, docID)
+	}
+
+	original, err := json.Marshal(doc.Content)
+	if err != nil {
+		return nil, fmt.Errorf("marshal content: %w", err)
+	}
+
+	patched, err := apply(original)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPatch, err)
+	}
+
+	content := make(map[string]interface{})
+
+	if err = json.Unmarshal(patched, &content); err != nil {
+		return nil, fmt.Errorf("%w: the patched content is not a JSON object", ErrInvalidPatch)
+	}
+
+	if c.verifyCredentials && isCredential(content) {
+		if err = c.verifyCredential(patched); err != nil {
+			return nil, err
+		}
+	}
+
+	// the document is saved over the version the patch was applied to only
+	opts = append(opts,
+		WithExpectedSequence(dInfo.Sequence),
+		withIndexedAttributes(encDoc.IndexedAttributeCollections),
+	)
+
+	if dInfo.ControllerKeyID != "" {
+		opts = append(opts, WithControllerRecipient())
+	}
+
+	return c.saveDoc(vaultID, docID, info, &models.StructuredDocument{Content: content}, opts)
+}
@@ -0,0 +1,250 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/ace/pkg/internal/testutil"
+	"github.com/trustbloc/ace/pkg/restapi/vault"
+)
+
+func TestClient_PatchDoc(t *testing.T) {
+	loader := testutil.DocumentLoader(t)
+
+	remoteKMS := httptest.NewServer(newKMSHandler(t))
+	t.Cleanup(remoteKMS.Close)
+
+	edv := httptest.NewServer(newEDVHandler(t))
+	t.Cleanup(edv.Close)
+
+	provider := mem.NewProvider()
+
+	client, err := vault.NewClient(remoteKMS.URL, edv.URL, newLocalKms(t, provider), provider, loader)
+	require.NoError(t, err)
+
+	created, err := client.CreateVault()
+	require.NoError(t, err)
+
+	saved, err := client.SaveDoc(created.ID, "doc", []byte(`{"name":"Alice","age":42,"address":{"city":"Paris"}}`),
+		vault.WithIndexTags(map[string]string{"type": "person"}))
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name     string
+		patch    string
+		expected string
+	}{
+		{
+			name:     "Adds a field",
+			patch:    `{"email":"alice@example.com"}`,
+			expected: `{"name":"Alice","age":42,"address":{"city":"Paris"},"email":"alice@example.com"}`,
+		},
+		{
+			name:     "Changes a field",
+			patch:    `{"address":{"city":"Lyon"}}`,
+			expected: `{"name":"Alice","age":42,"address":{"city":"Lyon"},"email":"alice@example.com"}`,
+		},
+		{
+			name:     "Removes a field",
+			patch:    `{"age":null}`,
+			expected: `{"name":"Alice","address":{"city":"Lyon"},"email":"alice@example.com"}`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			before, err := client.GetDocMetadata(created.ID, "doc")
+			require.NoError(t, err)
+
+			meta, err := client.PatchDoc(created.ID, "doc", []byte(tc.patch))
+			require.NoError(t, err)
+			require.Equal(t, before.Sequence+1, meta.Sequence)
+			require.Equal(t, saved.URI, meta.URI)
+
+			content, err := client.GetDoc(created.ID, "doc")
+			require.NoError(t, err)
+			require.JSONEq(t, tc.expected, string(content))
+		})
+	}
+
+	t.Run("Concurrent patches are not lost", func(t *testing.T) {
+		before, err := client.GetDocMetadata(created.ID, "doc")
+		require.NoError(t, err)
+
+		const patches = 10
+
+		var (
+			wg   sync.WaitGroup
+			errs = make([]error, patches)
+		)
+
+		for i := 0; i < patches; i++ {
+			wg.Add(1)
+
+			go func(i int) {
+				defer wg.Done()
+
+				_, errs[i] = client.PatchDoc(created.ID, "doc", []byte(fmt.Sprintf(`{"field%d":true}`, i)))
+			}(i)
+		}
+
+		wg.Wait()
+
+		content, err := client.GetDoc(created.ID, "doc")
+		require.NoError(t, err)
+
+		var fields map[string]interface{}
+
+		require.NoError(t, json.Unmarshal(content, &fields))
+
+		applied := 0
+
+		for i, err := range errs {
+			if err != nil {
+				// patches of a version another patch was saved over fail instead of overwriting it
+				require.True(t, errors.Is(err, vault.ErrSequenceMismatch))
+				require.NotContains(t, fields, fmt.Sprintf("field%d", i))
+
+				continue
+			}
+
+			applied++
+
+			require.Contains(t, fields, fmt.Sprintf("field%d", i))
+		}
+
+		require.NotZero(t, applied)
+
+		after, err := client.GetDocMetadata(created.ID, "doc")
+		require.NoError(t, err)
+		require.Equal(t, before.Sequence+uint64(applied), after.Sequence)
+	})
+
+	t.Run("The document keeps its index tags", func(t *testing.T) {
+		found, err := client.FindDocs(created.ID, "type", "person")
+		require.NoError(t, err)
+		require.Len(t, found.Documents, 1)
+		require.Equal(t, "doc", found.Documents[0].ID)
+	})
+
+	t.Run("Expected sequence", func(t *testing.T) {
+		meta, err := client.GetDocMetadata(created.ID, "doc")
+		require.NoError(t, err)
+
+		_, err = client.PatchDoc(created.ID, "doc", []byte(`{"age":43}`), vault.WithExpectedSequence(meta.Sequence-1))
+
+		var mismatch *vault.SequenceMismatchError
+
+		require.True(t, errors.As(err, &mismatch))
+		require.Equal(t, meta.Sequence, mismatch.Current)
+
+		patched, err := client.PatchDoc(created.ID, "doc", []byte(`{"age":43}`),
+			vault.WithExpectedSequence(meta.Sequence))
+		require.NoError(t, err)
+		require.Equal(t, meta.Sequence+1, patched.Sequence)
+	})
+
+	t.Run("Error if the patch is not a JSON object", func(t *testing.T) {
+		for _, patch := range []string{`{"name":`, `[{"op":"remove","path":"/age"}]`, `"Bob"`, `null`} {
+			_, err := client.PatchDoc(created.ID, "doc", []byte(patch))
+			require.True(t, errors.Is(err, vault.ErrInvalidPatch), patch)
+		}
+	})
+
+	t.Run("Error if the document does not exist", func(t *testing.T) {
+		_, err := client.PatchDoc(created.ID, "unknown", []byte(`{"name":"Bob"}`))
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+	})
+
+	t.Run("Error if the document is binary", func(t *testing.T) {
+		_, err := client.SaveBinaryDoc(created.ID, "pdf", "application/pdf", []byte("%PDF-1.4"))
+		require.NoError(t, err)
+
+		_, err = client.PatchDoc(created.ID, "pdf", []byte(`{"name":"Bob"}`))
+		require.True(t, errors.Is(err, vault.ErrNotJSONDocument))
+	})
+}
+
+func TestClient_JSONPatchDoc(t *testing.T) {
+	loader := testutil.DocumentLoader(t)
+
+	remoteKMS := httptest.NewServer(newKMSHandler(t))
+	t.Cleanup(remoteKMS.Close)
+
+	edv := httptest.NewServer(newEDVHandler(t))
+	t.Cleanup(edv.Close)
+
+	provider := mem.NewProvider()
+
+	client, err := vault.NewClient(remoteKMS.URL, edv.URL, newLocalKms(t, provider), provider, loader)
+	require.NoError(t, err)
+
+	created, err := client.CreateVault()
+	require.NoError(t, err)
+
+	_, err = client.SaveDoc(created.ID, "doc", []byte(`{"name":"Alice","tags":["a","b"],"address":{"city":"Paris"}}`),
+		vault.WithIndexTags(map[string]string{"type": "person"}))
+	require.NoError(t, err)
+
+	t.Run("Applies the operations in order", func(t *testing.T) {
+		before, err := client.GetDocMetadata(created.ID, "doc")
+		require.NoError(t, err)
+
+		meta, err := client.JSONPatchDoc(created.ID, "doc", []byte(`[
+			{"op":"test","path":"/name","value":"Alice"},
+			{"op":"add","path":"/tags/-","value":"c"},
+			{"op":"remove","path":"/tags/0"},
+			{"op":"move","from":"/address/city","path":"/city"},
+			{"op":"remove","path":"/address"}
+		]`), vault.WithExpectedSequence(before.Sequence))
+		require.NoError(t, err)
+		require.Equal(t, before.Sequence+1, meta.Sequence)
+
+		content, err := client.GetDoc(created.ID, "doc")
+		require.NoError(t, err)
+		require.JSONEq(t, `{"name":"Alice","tags":["b","c"],"city":"Paris"}`, string(content))
+
+		found, err := client.FindDocs(created.ID, "type", "person")
+		require.NoError(t, err)
+		require.Len(t, found.Documents, 1)
+	})
+
+	t.Run("The document is unchanged if an operation fails", func(t *testing.T) {
+		before, err := client.GetDocMetadata(created.ID, "doc")
+		require.NoError(t, err)
+
+		for _, patch := range []string{
+			`[{"op":"replace","path":"/name","value":"Bob"},{"op":"test","path":"/name","value":"Alice"}]`,
+			`[{"op":"remove","path":"/unknown"}]`,
+			`[{"op":"replace","path":"","value":["not","an","object"]}]`,
+			`{"op":"remove","path":"/name"}`,
+		} {
+			_, err = client.JSONPatchDoc(created.ID, "doc", []byte(patch))
+			require.True(t, errors.Is(err, vault.ErrInvalidPatch), patch)
+		}
+
+		after, err := client.GetDocMetadata(created.ID, "doc")
+		require.NoError(t, err)
+		require.Equal(t, before.Sequence, after.Sequence)
+	})
+
+	t.Run("Error if the document is binary", func(t *testing.T) {
+		_, err := client.SaveBinaryDoc(created.ID, "pdf", "application/pdf", []byte("%PDF-1.4"))
+		require.NoError(t, err)
+
+		_, err = client.JSONPatchDoc(created.ID, "pdf", []byte(`[{"op":"add","path":"/name","value":"Bob"}]`))
+		require.True(t, errors.Is(err, vault.ErrNotJSONDocument))
+	})
+}
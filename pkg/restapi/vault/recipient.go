@@ -0,0 +1,159 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	ariescrypto "github.com/hyperledger/aries-framework-go/pkg/crypto"
+	ariesdid "github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/util/jwkkid"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+)
+
+const (
+	x25519KeyAgreementKey2019 = "X25519KeyAgreementKey2019"
+	jsonWebKey2020            = "JsonWebKey2020"
+	x25519Curve               = "X25519"
+)
+
+// WithControllerRecipient also encrypts the document to the key agreement key of the controller of the vault, the
+// DID the vault is identified by: the controller can then decrypt the document with its own key, without going
+// through the WebKMS of the vault. The key must be an X25519 key if the keys of the vault are, see WithVaultKeyType,
+// and a NIST P-curve key otherwise. Fails with ErrInvalidController if the controller has no such key.
+func WithControllerRecipient() SaveDocOpt {
+	return func(opts *saveDocOpts) {
+		opts.controllerRecipient = true
+	}
+}
+
+// recipient is a key documents are encrypted to besides the key-wrapping key of the vault.
+type recipient struct {
+	// keyID is the DID URL of the key.
+	keyID string
+	key   *ariescrypto.PublicKey
+}
+
+// publicKeys returns the key of the recipient, none if r is nil.
+func (r *recipient) publicKeys() []*ariescrypto.PublicKey {
+	if r == nil {
+		return nil
+	}
+
+	return []*ariescrypto.PublicKey{r.key}
+}
+
+// id returns the DID URL of the key of the recipient, empty if r is nil.
+func (r *recipient) id() string {
+	if r == nil {
+		return ""
+	}
+
+	return r.keyID
+}
+
+// saveDocRecipient returns the recipient the document is encrypted to besides the key of the vault according to
+// the options, nil if there is none.
+func (c *Client) saveDocRecipient(vaultID string, info *vaultInfo, opts []SaveDocOpt) (*recipient, error) {
+	options := &saveDocOpts{}
+
+	for _, fn := range opts {
+		fn(options)
+	}
+
+	if !options.controllerRecipient {
+		return nil, nil
+	}
+
+	return c.controllerRecipient(vaultID, info)
+}
+
+// controllerRecipient returns the first key agreement key of the controller of the vault. Recipients are identified
+// by the KMS key ID of their key, as in the KMS of the controller.
+func (c *Client) controllerRecipient(vaultID string, info *vaultInfo) (*recipient, error) {
+	docResolution, err := c.registry.Resolve(vaultID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: resolve %s: %s", ErrInvalidController, vaultID, err)
+	}
+
+	doc := docResolution.DIDDocument
+
+	if len(doc.KeyAgreement) == 0 {
+		return nil, fmt.Errorf("%w: %s has no key agreement key", ErrInvalidController, vaultID)
+	}
+
+	vm := doc.KeyAgreement[0].VerificationMethod
+
+	keyID := vm.ID
+	if strings.HasPrefix(keyID, "#") {
+		keyID = doc.ID + keyID
+	}
+
+	key, keyType, err := keyAgreementKey(&vm)
+	if err != nil {
+		return nil, fmt.Errorf("%w: key agreement key %s: %s", ErrInvalidController, keyID, err)
+	}
+
+	// the keys a document is encrypted to are wrapped with the same algorithm
+	if (keyType == kms.X25519ECDHKWType) != (info.keyType() == kms.X25519ECDHKWType) {
+		return nil, fmt.Errorf("%w: key agreement key %s is a %s key, the keys of the vault are %s keys",
+			ErrInvalidController, keyID, keyType, info.keyType())
+	}
+
+	src, err := json.Marshal(key)
+	if err != nil {
+		return nil, fmt.Errorf("marshal key agreement key: %w", err)
+	}
+
+	key.KID, err = jwkkid.CreateKID(src, keyType)
+	if err != nil {
+		return nil, fmt.Errorf("%w: key agreement key %s: %s", ErrInvalidController, keyID, err)
+	}
+
+	return &recipient{keyID: keyID, key: key}, nil
+}
+
+// keyAgreementKey returns the public key of the verification method along with its KMS key type.
+func keyAgreementKey(vm *ariesdid.VerificationMethod) (*ariescrypto.PublicKey, kms.KeyType, error) {
+	switch vm.Type {
+	case x25519KeyAgreementKey2019:
+		return &ariescrypto.PublicKey{X: vm.Value, Curve: x25519Curve, Type: "OKP"}, kms.X25519ECDHKWType, nil
+	case jsonWebKey2020:
+		j := vm.JSONWebKey()
+		if j == nil {
+			return nil, "", fmt.Errorf("no JWK")
+		}
+
+		switch k := j.Key.(type) {
+		case *ecdsa.PublicKey:
+			keyType, ok := map[string]kms.KeyType{
+				elliptic.P256().Params().Name: kms.NISTP256ECDHKWType,
+				elliptic.P384().Params().Name: kms.NISTP384ECDHKWType,
+				elliptic.P521().Params().Name: kms.NISTP521ECDHKWType,
+			}[j.Crv]
+			if !ok {
+				return nil, "", fmt.Errorf("unsupported curve %s", j.Crv)
+			}
+
+			return &ariescrypto.PublicKey{X: k.X.Bytes(), Y: k.Y.Bytes(), Curve: j.Crv, Type: j.Kty}, keyType, nil
+		case []byte:
+			if j.Crv != x25519Curve {
+				return nil, "", fmt.Errorf("unsupported curve %s", j.Crv)
+			}
+
+			return &ariescrypto.PublicKey{X: k, Curve: j.Crv, Type: j.Kty}, kms.X25519ECDHKWType, nil
+		}
+
+		return nil, "", fmt.Errorf("unsupported JWK key %T", j.Key)
+	default:
+		return nil, "", fmt.Errorf("unsupported verification method type %s", vm.Type)
+	}
+}
@@ -0,0 +1,248 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	"github.com/hyperledger/aries-framework-go/pkg/crypto"
+	"github.com/hyperledger/aries-framework-go/pkg/crypto/tinkcrypto"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk/jwksupport"
+	vdrapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	mockvdr "github.com/hyperledger/aries-framework-go/pkg/mock/vdr"
+	vdrkey "github.com/hyperledger/aries-framework-go/pkg/vdr/key"
+	"github.com/piprate/json-gold/ld"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edv/pkg/restapi/models"
+
+	"github.com/trustbloc/ace/pkg/internal/testutil"
+	"github.com/trustbloc/ace/pkg/restapi/vault"
+)
+
+func TestClient_SaveDocWithControllerRecipient(t *testing.T) {
+	loader := testutil.DocumentLoader(t)
+
+	t.Run("Either the vault or the controller decrypts the document", func(t *testing.T) {
+		controllerKMS := newLocalKms(t, mem.NewProvider())
+		doc := newKeyAgreementDIDDoc(t, controllerKMS, kms.NISTP256ECDHKWType)
+
+		client := newRecipientVaultClient(t, loader, doc)
+
+		created, err := client.CreateVault()
+		require.NoError(t, err)
+		require.Equal(t, doc.ID, created.ID)
+
+		meta, err := client.SaveDoc(created.ID, "doc", []byte(`{"message":"Hello World!"}`),
+			vault.WithControllerRecipient())
+		require.NoError(t, err)
+		require.Equal(t, []string{meta.EncKeyURI, doc.ID + "#key-agreement"}, meta.Recipients)
+
+		jwe := readJWE(t, meta.URI)
+		require.Len(t, jwe.Recipients, 2)
+
+		// the vault decrypts with its WebKMS key
+		content, err := client.GetDoc(created.ID, "doc")
+		require.NoError(t, err)
+		require.JSONEq(t, `{"message":"Hello World!"}`, string(content))
+
+		// the controller decrypts with its own key
+		requireDecrypts(t, controllerKMS, jwe, `{"message":"Hello World!"}`)
+
+		stored, err := client.GetDocMetadata(created.ID, "doc")
+		require.NoError(t, err)
+		require.Equal(t, meta.Recipients, stored.Recipients)
+
+		// updates saved without the option are encrypted to the key of the vault only
+		meta, err = client.SaveDoc(created.ID, "doc", []byte(`{"message":"Bye!"}`))
+		require.NoError(t, err)
+		require.Equal(t, []string{meta.EncKeyURI}, meta.Recipients)
+		require.Len(t, readJWE(t, meta.URI).Recipients, 1)
+	})
+
+	t.Run("Re-encrypted documents stay encrypted to the controller", func(t *testing.T) {
+		controllerKMS := newLocalKms(t, mem.NewProvider())
+		doc := newKeyAgreementDIDDoc(t, controllerKMS, kms.X25519ECDHKWType)
+
+		client := newRecipientVaultClient(t, loader, doc)
+
+		created, err := client.CreateVault(vault.WithVaultKeyType(kms.X25519ECDHKWType))
+		require.NoError(t, err)
+
+		_, err = client.SaveDoc(created.ID, "doc", []byte(`{"message":"Hello World!"}`),
+			vault.WithControllerRecipient())
+		require.NoError(t, err)
+
+		rekey, err := client.RekeyVault(created.ID)
+		require.NoError(t, err)
+		require.True(t, rekey.Complete)
+
+		meta, err := client.GetDocMetadata(created.ID, "doc")
+		require.NoError(t, err)
+		require.Equal(t, []string{rekey.KeyURI, doc.ID + "#key-agreement"}, meta.Recipients)
+
+		requireDecrypts(t, controllerKMS, readJWE(t, meta.URI), `{"message":"Hello World!"}`)
+	})
+
+	t.Run("Error if the controller has no suitable key agreement key", func(t *testing.T) {
+		controllerKMS := newLocalKms(t, mem.NewProvider())
+
+		noKey := newKeyAgreementDIDDoc(t, controllerKMS, kms.NISTP256ECDHKWType)
+		noKey.KeyAgreement = nil
+
+		for name, tc := range map[string]struct {
+			doc  *did.Doc
+			opts []vault.CreateVaultOpt
+			err  string
+		}{
+			"No key agreement key": {
+				doc: noKey,
+				err: "has no key agreement key",
+			},
+			"Key of another family than the keys of the vault": {
+				doc:  newKeyAgreementDIDDoc(t, controllerKMS, kms.NISTP256ECDHKWType),
+				opts: []vault.CreateVaultOpt{vault.WithVaultKeyType(kms.X25519ECDHKWType)},
+				err:  "the keys of the vault are X25519ECDHKW keys",
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				client := newRecipientVaultClient(t, loader, tc.doc)
+
+				created, err := client.CreateVault(tc.opts...)
+				require.NoError(t, err)
+
+				_, err = client.SaveDoc(created.ID, "doc", []byte(`{}`), vault.WithControllerRecipient())
+				require.True(t, errors.Is(err, vault.ErrInvalidController))
+				require.Contains(t, err.Error(), tc.err)
+
+				_, err = client.GetDocMetadata(created.ID, "doc")
+				require.Error(t, err)
+			})
+		}
+	})
+}
+
+// newRecipientVaultClient returns a client creating vaults in fake EDV and WebKMS servers, whose DID is the one of
+// the document. The keys the vault signs with are did:key keys of the client.
+func newRecipientVaultClient(t *testing.T, loader ld.DocumentLoader, doc *did.Doc) *vault.Client {
+	t.Helper()
+
+	remoteKMS := httptest.NewServer(newKMSHandler(t))
+	t.Cleanup(remoteKMS.Close)
+
+	edv := httptest.NewServer(newEDVHandler(t))
+	t.Cleanup(edv.Close)
+
+	provider := mem.NewProvider()
+
+	client, err := vault.NewClient(remoteKMS.URL, edv.URL, newLocalKms(t, provider), provider, loader,
+		vault.WithRegistry(&mockvdr.MockVDRegistry{
+			CreateFunc: func(_ string, created *did.Doc, _ ...vdrapi.DIDMethodOption) (*did.DocResolution, error) {
+				doc.CapabilityDelegation = created.CapabilityDelegation
+
+				return &did.DocResolution{DIDDocument: doc}, nil
+			},
+			ResolveFunc: func(id string, opts ...vdrapi.DIDMethodOption) (*did.DocResolution, error) {
+				if id == doc.ID {
+					return &did.DocResolution{DIDDocument: doc}, nil
+				}
+
+				return vdrkey.New().Read(id, opts...)
+			},
+		}),
+	)
+	require.NoError(t, err)
+
+	return client
+}
+
+// newKeyAgreementDIDDoc returns a DID document whose key agreement key is a new key of the KMS.
+func newKeyAgreementDIDDoc(t *testing.T, k vault.KeyManager, keyType kms.KeyType) *did.Doc {
+	t.Helper()
+
+	doc := newDIDDoc()
+
+	_, src, err := k.CreateAndExportPubKeyBytes(keyType)
+	require.NoError(t, err)
+
+	var pub crypto.PublicKey
+
+	require.NoError(t, json.Unmarshal(src, &pub))
+
+	vm := &did.VerificationMethod{
+		ID:         doc.ID + "#key-agreement",
+		Type:       "X25519KeyAgreementKey2019",
+		Controller: doc.ID,
+		Value:      pub.X,
+	}
+
+	if keyType != kms.X25519ECDHKWType {
+		j, err := jwksupport.JWKFromKey(&ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(pub.X),
+			Y:     new(big.Int).SetBytes(pub.Y),
+		})
+		require.NoError(t, err)
+
+		vm, err = did.NewVerificationMethodFromJWK(doc.ID+"#key-agreement", "JsonWebKey2020", doc.ID, j)
+		require.NoError(t, err)
+	}
+
+	doc.KeyAgreement = []did.Verification{*did.NewReferencedVerification(vm, did.KeyAgreement)}
+
+	return doc
+}
+
+// readJWE reads the JWE of the document from the fake EDV server.
+func readJWE(t *testing.T, docURI string) *jose.JSONWebEncryption {
+	t.Helper()
+
+	resp, err := http.Get(docURI) //nolint:noctx
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, resp.Body.Close())
+	}()
+
+	var encDoc models.EncryptedDocument
+
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&encDoc))
+
+	jwe, err := jose.Deserialize(string(encDoc.JWE))
+	require.NoError(t, err)
+
+	return jwe
+}
+
+// requireDecrypts decrypts the JWE with the keys of the KMS only.
+func requireDecrypts(t *testing.T, k vault.KeyManager, jwe *jose.JSONWebEncryption, expected string) {
+	t.Helper()
+
+	cr, err := tinkcrypto.New()
+	require.NoError(t, err)
+
+	plaintext, err := jose.NewJWEDecrypt(nil, cr, k).Decrypt(jwe)
+	require.NoError(t, err)
+
+	var doc models.StructuredDocument
+
+	require.NoError(t, json.Unmarshal(plaintext, &doc))
+
+	content, err := json.Marshal(doc.Content)
+	require.NoError(t, err)
+	require.JSONEq(t, expected, string(content))
+}
@@ -0,0 +1,142 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+)
+
+const referenceFormat = "reference_%s_%s"
+
+// ErrReferenceWithoutController is returned when a vault is created with a reference ID but without an
+// authenticated controller or owner to scope the reference ID to.
+var ErrReferenceWithoutController = errors.New("a reference ID requires the signature of the controller or owner")
+
+// WithReferenceID makes the creation of the vault idempotent: if the controller already created a vault with the
+// reference ID, that vault is returned, with its current authorization, instead of a new one. Reference IDs are
+// scoped to the DID of the controller, or of the owner of vaults created with WithVaultOwner: vaults created without
+// either cannot have one.
+func WithReferenceID(referenceID string) CreateVaultOpt {
+	return func(opts *createVaultOpts) {
+		opts.referenceID = referenceID
+	}
+}
+
+// vaultReference records the vault a controller created with a reference ID.
+type vaultReference struct {
+	VaultID string `json:"vault_id"`
+}
+
+// vaultReferenceKey returns the key of the reference record of the vault to create, empty if it has no reference ID.
+// The controller is a DID or the DID URL of the verification method of an owner.
+func vaultReferenceKey(controller string, opts []CreateVaultOpt) (string, error) {
+	options := &createVaultOpts{}
+
+	for _, fn := range opts {
+		fn(options)
+	}
+
+	if options.referenceID == "" {
+		return "", nil
+	}
+
+	controller = strings.Split(controller, "#")[0]
+	if controller == "" {
+		return "", ErrReferenceWithoutController
+	}
+
+	return fmt.Sprintf(referenceFormat, controller, options.referenceID), nil
+}
+
+// referencedVault returns the vault recorded under the reference key, nil if there is none. The reference is
+// recorded once the vault is, so a reference to a missing vault is one left behind by a failed deletion.
+func (c *Client) referencedVault(key string) (*CreatedVault, error) {
+	if key == "" {
+		return nil, nil
+	}
+
+	src, err := c.store.Get(key)
+	if errors.Is(err, storage.ErrDataNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("get vault reference: %w", err)
+	}
+
+	ref := &vaultReference{}
+
+	err = json.Unmarshal(src, ref)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal vault reference: %w", err)
+	}
+
+	info, err := c.getVaultInfo(ref.VaultID)
+	if errors.Is(err, storage.ErrDataNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("get vault info: %w", err)
+	}
+
+	// the authorization returned by the creation of the vault, as rotated since
+	auth := info.Auth
+	if info.Owner != "" {
+		auth = info.IssuedAuth
+	}
+
+	return &CreatedVault{ID: ref.VaultID, Authorization: auth, Existing: true}, nil
+}
+
+// createReferencedVault creates the vault and records it under the reference key. The store has no conditional
+// writes, so concurrent retries are serialized by a lock on the key: they wait for the vault being created and
+// return it instead of creating others.
+func (c *Client) createReferencedVault(key string, create func() (*CreatedVault, error)) (*CreatedVault, error) {
+	if key == "" {
+		return create()
+	}
+
+	unlock := c.referenceMu.lock(key)
+	defer unlock()
+
+	existing, err := c.referencedVault(key)
+	if err != nil || existing != nil {
+		return existing, err
+	}
+
+	created, err := create()
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.saveVaultReference(key, created.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func (c *Client) saveVaultReference(key, vaultID string) error {
+	src, err := json.Marshal(&vaultReference{VaultID: vaultID})
+	if err != nil {
+		return fmt.Errorf("marshal vault reference: %w", err)
+	}
+
+	err = c.store.Put(key, src)
+	if err != nil {
+		return fmt.Errorf("save vault reference: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,178 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/pkg/mock/vdr"
+	"github.com/hyperledger/aries-framework-go/pkg/vdr/fingerprint"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/ace/pkg/internal/testutil"
+	"github.com/trustbloc/ace/pkg/restapi/vault"
+)
+
+func TestClient_ReferenceID(t *testing.T) {
+	loader := testutil.DocumentLoader(t)
+
+	t.Run("Returns the vault created with the reference ID", func(t *testing.T) {
+		client, _ := newKeyTypeVaultClient(t, loader)
+		owner := newVaultOwner(t)
+
+		created, err := client.CreateVault(vault.WithVaultOwner(owner()), vault.WithReferenceID("ref"))
+		require.NoError(t, err)
+		require.False(t, created.Existing)
+		require.NotNil(t, created.Authorization)
+
+		existing, err := client.CreateVault(vault.WithVaultOwner(owner()), vault.WithReferenceID("ref"))
+		require.NoError(t, err)
+		require.True(t, existing.Existing)
+		require.Equal(t, created.ID, existing.ID)
+		require.Equal(t, created.Authorization, existing.Authorization)
+
+		other, err := client.CreateVault(vault.WithVaultOwner(owner()), vault.WithReferenceID("other"))
+		require.NoError(t, err)
+		require.False(t, other.Existing)
+		require.NotEqual(t, created.ID, other.ID)
+
+		unreferenced, err := client.CreateVault(vault.WithVaultOwner(owner()))
+		require.NoError(t, err)
+		require.NotEqual(t, created.ID, unreferenced.ID)
+	})
+
+	t.Run("Returns the rotated authorization of the vault", func(t *testing.T) {
+		client, _ := newKeyTypeVaultClient(t, loader)
+		owner := newVaultOwner(t)
+
+		created, err := client.CreateVault(vault.WithVaultOwner(owner()), vault.WithReferenceID("ref"))
+		require.NoError(t, err)
+
+		rotated, err := client.RotateTokens(created.ID)
+		require.NoError(t, err)
+
+		existing, err := client.CreateVault(vault.WithVaultOwner(owner()), vault.WithReferenceID("ref"))
+		require.NoError(t, err)
+		require.True(t, existing.Existing)
+		require.Equal(t, rotated.Authorization, existing.Authorization)
+	})
+
+	t.Run("Concurrent retries create a single vault", func(t *testing.T) {
+		client, _ := newKeyTypeVaultClient(t, loader)
+		owner := newVaultOwner(t)
+
+		const retries = 5
+
+		var (
+			wg      sync.WaitGroup
+			results = make([]*vault.CreatedVault, retries)
+		)
+
+		for i := 0; i < retries; i++ {
+			wg.Add(1)
+
+			go func(i int, req *http.Request) {
+				defer wg.Done()
+
+				result, err := client.CreateVault(vault.WithVaultOwner(req), vault.WithReferenceID("ref"))
+				require.NoError(t, err)
+
+				results[i] = result
+			}(i, owner())
+		}
+
+		wg.Wait()
+
+		// the retries wait for the vault being created, so they all resolve it
+		for _, result := range results {
+			require.Equal(t, results[0].ID, result.ID)
+			require.NotNil(t, result.Authorization)
+
+			info, err := client.GetVaultInfo(result.ID)
+			require.NoError(t, err)
+			require.Equal(t, result.ID, info.ID)
+		}
+	})
+
+	t.Run("Reference IDs are scoped to the controller", func(t *testing.T) {
+		doc, priv := newSigningDIDDoc(t)
+		client, _ := newRoundTripVaultClient(t, loader,
+			vault.WithRegistry(&vdr.MockVDRegistry{ResolveValue: doc, CreateValue: newDIDDoc()}))
+
+		created, err := client.CreateVaultWithController(doc.ID, "#key-1", newSignedRequest(t, priv, doc.ID+"#key-1"),
+			vault.WithReferenceID("ref"))
+		require.NoError(t, err)
+		require.False(t, created.Existing)
+
+		existing, err := client.CreateVaultWithController(doc.ID, "#key-1", newSignedRequest(t, priv, doc.ID+"#key-1"),
+			vault.WithReferenceID("ref"))
+		require.NoError(t, err)
+		require.True(t, existing.Existing)
+		require.Equal(t, doc.ID, existing.ID)
+		require.Equal(t, created.Authorization, existing.Authorization)
+	})
+
+	t.Run("Reference IDs are scoped to the owner", func(t *testing.T) {
+		client, _ := newKeyTypeVaultClient(t, loader)
+
+		created, err := client.CreateVault(vault.WithVaultOwner(newVaultOwner(t)()), vault.WithReferenceID("ref"))
+		require.NoError(t, err)
+
+		other, err := client.CreateVault(vault.WithVaultOwner(newVaultOwner(t)()), vault.WithReferenceID("ref"))
+		require.NoError(t, err)
+		require.False(t, other.Existing)
+		require.NotEqual(t, created.ID, other.ID)
+	})
+
+	t.Run("Requires a controller or an owner", func(t *testing.T) {
+		client, _ := newKeyTypeVaultClient(t, loader)
+
+		_, err := client.CreateVault(vault.WithReferenceID("ref"))
+		require.ErrorIs(t, err, vault.ErrReferenceWithoutController)
+	})
+
+	t.Run("Deleting the vault releases the reference ID", func(t *testing.T) {
+		client, _ := newKeyTypeVaultClient(t, loader)
+		owner := newVaultOwner(t)
+
+		created, err := client.CreateVault(vault.WithVaultOwner(owner()), vault.WithReferenceID("ref"))
+		require.NoError(t, err)
+
+		deletion, err := client.DeleteVault(created.ID)
+		require.NoError(t, err)
+		require.True(t, deletion.Complete)
+
+		recreated, err := client.CreateVault(vault.WithVaultOwner(owner()), vault.WithReferenceID("ref"))
+		require.NoError(t, err)
+		require.False(t, recreated.Existing)
+		require.NotEqual(t, created.ID, recreated.ID)
+	})
+}
+
+// newVaultOwner returns a function returning vault creation requests signed by the same new did:key.
+func newVaultOwner(t *testing.T) func() *http.Request {
+	t.Helper()
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	_, keyID := fingerprint.CreateDIDKey(pub)
+
+	return func() *http.Request {
+		req, err := http.NewRequest(http.MethodPost, "https://vault.example.com/vaults", strings.NewReader(`{}`))
+		require.NoError(t, err)
+
+		signRequest(t, req, priv, keyID)
+
+		return req
+	}
+}
@@ -0,0 +1,324 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	edv "github.com/trustbloc/edv/pkg/client"
+	"github.com/trustbloc/edv/pkg/restapi/models"
+)
+
+const rekeyFormat = "rekey_%s"
+
+// VaultRekey reports the re-encryption of the documents of a vault under a new key.
+type VaultRekey struct {
+	ID string `json:"id"`
+	// KeyURI is the URI of the key the documents are re-encrypted to.
+	KeyURI  string    `json:"keyURI"`
+	Started time.Time `json:"started"`
+	Updated time.Time `json:"updated"`
+	// Complete is true once every document of the vault is encrypted to the key.
+	Complete bool `json:"complete"`
+	Total    int  `json:"total"`
+	Rekeyed  int  `json:"rekeyed"`
+	// Error reports the first document that could not be re-encrypted.
+	Error string `json:"error,omitempty"`
+}
+
+// RekeyVault creates a new key in the keystore of the vault and re-encrypts every document of the vault to it.
+// Progress is recorded per document: if a document cannot be re-encrypted, the VaultRekey reports it and the call
+// can be retried to resume the re-encryption with the same key. Once complete, a new call starts over with a new key.
+func (c *Client) RekeyVault(vaultID string) (*VaultRekey, error) {
+	info, err := c.getVaultInfo(vaultID)
+	if err != nil {
+		return nil, fmt.Errorf("get vault info: %w", err)
+	}
+
+	if info.Deleting {
+		return nil, ErrVaultDeleting
+	}
+
+	rekey, err := c.currentRekey(vaultID, info, true)
+	if err != nil {
+		return nil, err
+	}
+
+	docs, err := c.queryMetaDocInfos(vaultID)
+	if err != nil {
+		return nil, fmt.Errorf("query meta doc infos: %w", err)
+	}
+
+	sort.Slice(docs, func(i, j int) bool { return docs[i].DocID < docs[j].DocID })
+
+	rekey.Total = len(docs)
+	rekey.Rekeyed = 0
+	rekey.Error = ""
+
+	for _, d := range docs {
+		if d.KidURL != rekey.KeyURI {
+			err = c.rekeyDoc(vaultID, info, d, rekey.KeyURI)
+			if err != nil {
+				if rekey.Error == "" {
+					rekey.Error = fmt.Sprintf("re-encrypt document %s: %s", d.DocID, err)
+				}
+
+				continue
+			}
+		}
+
+		rekey.Rekeyed++
+
+		err = c.saveRekey(rekey)
+		if err != nil {
+			return nil, fmt.Errorf("save rekey: %w", err)
+		}
+	}
+
+	rekey.Complete = rekey.Error == ""
+
+	err = c.saveRekey(rekey)
+	if err != nil {
+		return nil, fmt.Errorf("save rekey: %w", err)
+	}
+
+	return rekey, nil
+}
+
+// RekeyDoc re-encrypts the document to the current key of the vault: the key of its last re-encryption. If the vault
+// was never re-encrypted, a re-encryption to a new key of its keystore is started, which RekeyVault resumes. It is
+// meant for the documents RekeyVault could not re-encrypt, which can then be fixed one at a time. Documents already
+// encrypted to the key are left as they are.
+func (c *Client) RekeyDoc(vaultID, docID string) (*DocumentMetadata, error) {
+	info, err := c.getVaultInfo(vaultID)
+	if err != nil {
+		return nil, fmt.Errorf("get vault info: %w", err)
+	}
+
+	if info.Deleting {
+		return nil, ErrVaultDeleting
+	}
+
+	backend, err := c.edvBackend(info)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := c.getMetaDocInfo(vaultID, docID)
+	if err != nil {
+		return nil, fmt.Errorf("get meta doc info: %w", err)
+	}
+
+	rekey, err := c.currentRekey(vaultID, info, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.KidURL != rekey.KeyURI {
+		err = c.rekeyDoc(vaultID, info, d, rekey.KeyURI)
+		if err != nil {
+			return nil, fmt.Errorf("re-encrypt document: %w", err)
+		}
+	}
+
+	return docMetadata(backend, lastElm(info.Auth.EDV.URI, "/"), docID, d), nil
+}
+
+// currentRekey returns the last re-encryption of the vault, starting one with a new key if there is none, or if the
+// last one is complete and restart is true. The key is recorded before it is used, so that the documents of the
+// vault are all re-encrypted to the same key.
+func (c *Client) currentRekey(vaultID string, info *vaultInfo, restart bool) (*VaultRekey, error) {
+	unlock := c.vaultMu.lock(fmt.Sprintf(rekeyFormat, vaultID))
+	defer unlock()
+
+	rekey, err := c.getRekey(vaultID)
+	if err != nil && !errors.Is(err, storage.ErrDataNotFound) {
+		return nil, fmt.Errorf("get rekey: %w", err)
+	}
+
+	if rekey == nil || (restart && rekey.Complete) {
+		return c.startRekey(vaultID, info)
+	}
+
+	return rekey, nil
+}
+
+// GetRekeyStatus returns the progress of the last re-encryption of the vault.
+func (c *Client) GetRekeyStatus(vaultID string) (*VaultRekey, error) {
+	_, err := c.getVaultInfo(vaultID)
+	if err != nil {
+		return nil, fmt.Errorf("get vault info: %w", err)
+	}
+
+	rekey, err := c.getRekey(vaultID)
+	if err != nil {
+		return nil, fmt.Errorf("get rekey: %w", err)
+	}
+
+	return rekey, nil
+}
+
+func (c *Client) startRekey(vaultID string, info *vaultInfo) (*VaultRekey, error) {
+	_, kidURL, err := c.webKMS(info).Create(info.keyType())
+	if err != nil {
+		return nil, fmt.Errorf("create key: %w", err)
+	}
+
+	kidURLStr, ok := kidURL.(string)
+	if !ok {
+		return nil, fmt.Errorf("kidURL is not a string")
+	}
+
+	now := time.Now().UTC()
+
+	rekey := &VaultRekey{ID: vaultID, KeyURI: c.buildKMSURL(info, kidURLStr), Started: now}
+
+	err = c.saveRekey(rekey)
+	if err != nil {
+		return nil, fmt.Errorf("save rekey: %w", err)
+	}
+
+	return rekey, nil
+}
+
+// rekeyDoc decrypts the document and writes it back to EDV encrypted to the key, with an incremented sequence.
+// The encrypted indexes of the document are kept. The chunks of chunked documents are replaced by copies encrypted
+// to the key. Documents encrypted to the controller stay encrypted to its current key agreement key.
+func (c *Client) rekeyDoc(vaultID string, info *vaultInfo, d *metaDocInfo, keyURI string) error { // nolint:funlen
+	defer c.docMetaCache.invalidate(vaultID, d.DocID)
+
+	backend, err := c.edvBackend(info)
+	if err != nil {
+		return err
+	}
+
+	// the document is re-read under its lock, it may have been saved since it was listed
+	unlock := c.docMu.lock(docLockKey(vaultID, d.DocID))
+	defer unlock()
+
+	current, err := c.getMetaDocInfo(vaultID, d.DocID)
+	if err != nil {
+		return fmt.Errorf("get meta doc info: %w", err)
+	}
+
+	*d = *current
+
+	if d.KidURL == keyURI {
+		return nil
+	}
+
+	var (
+		edvVaultID = lastElm(info.Auth.EDV.URI, "/")
+		wKMS       = c.webKMS(info)
+		wCrypto    = c.webCrypto(info)
+	)
+
+	encDoc, err := backend.client.ReadDocument(edvVaultID, d.EdvID, edv.WithRequestHeader(
+		c.edvSign(info.DidURL, info.Auth.EDV)),
+	)
+	if err != nil {
+		return fmt.Errorf("read document: %w", err)
+	}
+
+	doc, err := decryptDocument(wKMS, wCrypto, encDoc.JWE)
+	if err != nil {
+		return fmt.Errorf("decrypt document: %w", err)
+	}
+
+	var controller *recipient
+
+	if d.ControllerKeyID != "" {
+		controller, err = c.controllerRecipient(vaultID, info)
+		if err != nil {
+			return err
+		}
+	}
+
+	chunks, chunked, err := docChunks(doc)
+	if err != nil {
+		return fmt.Errorf("read document: %w", err)
+	}
+
+	var rekeyed []string
+
+	if chunked {
+		rekeyed, err = c.rekeyChunks(info, backend, chunks, keyURI, controller)
+		if err != nil {
+			return fmt.Errorf("rekey chunks: %w", err)
+		}
+
+		doc.Meta[chunksField] = rekeyed
+	}
+
+	encContent, err := encryptToKey(wKMS, wCrypto, keyURI, doc, controller.publicKeys()...)
+	if err != nil {
+		c.deleteStaleChunks(info, backend, rekeyed)
+
+		return fmt.Errorf("encrypt document: %w", err)
+	}
+
+	err = backend.client.UpdateDocument(edvVaultID, d.EdvID, &models.EncryptedDocument{
+		ID:                          d.EdvID,
+		Sequence:                    d.Sequence + 1,
+		IndexedAttributeCollections: encDoc.IndexedAttributeCollections,
+		JWE:                         []byte(encContent),
+	}, edv.WithRequestHeader(c.edvSign(info.DidURL, info.Auth.EDV)))
+	if err != nil {
+		c.deleteStaleChunks(info, backend, rekeyed)
+
+		return fmt.Errorf("update document: %w", err)
+	}
+
+	d.KidURL = keyURI
+	d.Sequence++
+	d.ControllerKeyID = controller.id()
+
+	if chunked {
+		d.Chunks = rekeyed
+	}
+
+	err = c.saveMetaDocInfo(vaultID, d.DocID, d)
+	if err != nil {
+		return err
+	}
+
+	c.deleteStaleChunks(info, backend, chunks)
+
+	return nil
+}
+
+func (c *Client) saveRekey(rekey *VaultRekey) error {
+	rekey.Updated = time.Now().UTC()
+
+	src, err := json.Marshal(rekey)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	return c.store.Put(fmt.Sprintf(rekeyFormat, rekey.ID), src)
+}
+
+func (c *Client) getRekey(vaultID string) (*VaultRekey, error) {
+	src, err := c.store.Get(fmt.Sprintf(rekeyFormat, vaultID))
+	if err != nil {
+		return nil, fmt.Errorf("store get: %w", err)
+	}
+
+	var rekey *VaultRekey
+
+	err = json.Unmarshal(src, &rekey)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal: %w", err)
+	}
+
+	return rekey, nil
+}
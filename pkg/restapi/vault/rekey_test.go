@@ -0,0 +1,283 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/ace/pkg/internal/testutil"
+	"github.com/trustbloc/ace/pkg/restapi/vault"
+)
+
+func TestClient_RekeyVault(t *testing.T) {
+	loader := testutil.DocumentLoader(t)
+
+	t.Run("Success", func(t *testing.T) {
+		client, vID := newRoundTripVaultClient(t, loader)
+
+		_, err := client.SaveDoc(vID, "doc1", []byte(`{"message":"Hello World!"}`))
+		require.NoError(t, err)
+
+		_, err = client.SaveBinaryDoc(vID, "doc2", "text/plain", []byte("Hello World!"))
+		require.NoError(t, err)
+
+		before, err := client.GetDocMetadata(vID, "doc1")
+		require.NoError(t, err)
+
+		rekey, err := client.RekeyVault(vID)
+		require.NoError(t, err)
+		require.True(t, rekey.Complete)
+		require.Equal(t, vID, rekey.ID)
+		require.NotEmpty(t, rekey.KeyURI)
+		require.Equal(t, 2, rekey.Total)
+		require.Equal(t, 2, rekey.Rekeyed)
+		require.Empty(t, rekey.Error)
+
+		after, err := client.GetDocMetadata(vID, "doc1")
+		require.NoError(t, err)
+		require.Equal(t, rekey.KeyURI, after.EncKeyURI)
+		require.NotEqual(t, before.EncKeyURI, after.EncKeyURI)
+		require.Equal(t, before.Sequence+1, after.Sequence)
+
+		doc, err := client.GetDoc(vID, "doc1")
+		require.NoError(t, err)
+		require.JSONEq(t, `{"message":"Hello World!"}`, string(doc))
+
+		content, err := client.GetDocContent(vID, "doc2")
+		require.NoError(t, err)
+		require.Equal(t, []byte("Hello World!"), content.Data)
+
+		status, err := client.GetRekeyStatus(vID)
+		require.NoError(t, err)
+		require.Equal(t, rekey.KeyURI, status.KeyURI)
+		require.True(t, status.Complete)
+
+		// once complete, documents are re-encrypted to a new key
+		again, err := client.RekeyVault(vID)
+		require.NoError(t, err)
+		require.True(t, again.Complete)
+		require.NotEqual(t, rekey.KeyURI, again.KeyURI)
+		require.Equal(t, 2, again.Rekeyed)
+	})
+
+	t.Run("Resumes after a failure", func(t *testing.T) {
+		var (
+			mu         sync.Mutex
+			refusedKey string
+		)
+
+		kmsHandler := newKMSHandler(t)
+
+		// the KMS refuses to unwrap the keys of the documents encrypted to the refused key
+		remoteKMS := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			refused := refusedKey != "" && strings.HasSuffix(r.URL.Path, "/keys/"+refusedKey+"/unwrap")
+			mu.Unlock()
+
+			if refused {
+				w.WriteHeader(http.StatusInternalServerError)
+
+				return
+			}
+
+			kmsHandler(w, r)
+		}))
+		t.Cleanup(remoteKMS.Close)
+
+		edv := httptest.NewServer(newEDVHandler(t))
+		t.Cleanup(edv.Close)
+
+		provider := mem.NewProvider()
+
+		client, err := vault.NewClient(remoteKMS.URL, edv.URL, newLocalKms(t, provider), provider, loader)
+		require.NoError(t, err)
+
+		created, err := client.CreateVault()
+		require.NoError(t, err)
+
+		vID := created.ID
+
+		for docID, message := range map[string]string{"doc1": "Hello World!", "doc2": "Hello again!", "doc3": "Bye!"} {
+			_, err = client.SaveDoc(vID, docID, []byte(`{"message":"`+message+`"}`))
+			require.NoError(t, err)
+		}
+
+		meta, err := client.GetDocMetadata(vID, "doc3")
+		require.NoError(t, err)
+
+		mu.Lock()
+		refusedKey = meta.EncKeyURI[strings.LastIndex(meta.EncKeyURI, "/")+1:]
+		mu.Unlock()
+
+		rekey, err := client.RekeyVault(vID)
+		require.NoError(t, err)
+		require.False(t, rekey.Complete)
+		require.Equal(t, 3, rekey.Total)
+		require.Equal(t, 2, rekey.Rekeyed)
+		require.Contains(t, rekey.Error, "re-encrypt document doc3")
+
+		status, err := client.GetRekeyStatus(vID)
+		require.NoError(t, err)
+		require.Equal(t, rekey, status)
+
+		rekeyed, err := client.GetDocMetadata(vID, "doc1")
+		require.NoError(t, err)
+		require.Equal(t, rekey.KeyURI, rekeyed.EncKeyURI)
+
+		mu.Lock()
+		refusedKey = ""
+		mu.Unlock()
+
+		resumed, err := client.RekeyVault(vID)
+		require.NoError(t, err)
+		require.True(t, resumed.Complete)
+		require.Equal(t, rekey.KeyURI, resumed.KeyURI)
+		require.Equal(t, 3, resumed.Rekeyed)
+
+		// documents re-encrypted before the failure are not re-encrypted again
+		meta, err = client.GetDocMetadata(vID, "doc1")
+		require.NoError(t, err)
+		require.Equal(t, rekeyed.Sequence, meta.Sequence)
+
+		doc, err := client.GetDoc(vID, "doc3")
+		require.NoError(t, err)
+		require.JSONEq(t, `{"message":"Bye!"}`, string(doc))
+	})
+
+	t.Run("Vault not found", func(t *testing.T) {
+		client, _ := newRoundTripVaultClient(t, loader)
+
+		_, err := client.RekeyVault("did:example:unknown")
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+	})
+}
+
+func TestClient_GetRekeyStatus(t *testing.T) {
+	client, vID := newRoundTripVaultClient(t, testutil.DocumentLoader(t))
+
+	_, err := client.GetRekeyStatus(vID)
+	require.True(t, errors.Is(err, storage.ErrDataNotFound))
+
+	_, err = client.GetRekeyStatus("did:example:unknown")
+	require.True(t, errors.Is(err, storage.ErrDataNotFound))
+}
+
+func TestClient_RekeyDoc(t *testing.T) {
+	loader := testutil.DocumentLoader(t)
+
+	t.Run("Re-encrypts to a new key if the vault was never re-encrypted", func(t *testing.T) {
+		client, vID := newRoundTripVaultClient(t, loader)
+
+		before, err := client.SaveDoc(vID, "doc1", []byte(`{"message":"Hello World!"}`))
+		require.NoError(t, err)
+
+		after, err := client.RekeyDoc(vID, "doc1")
+		require.NoError(t, err)
+		require.NotEqual(t, before.EncKeyURI, after.EncKeyURI)
+		require.Equal(t, before.Sequence+1, after.Sequence)
+		require.Equal(t, before.URI, after.URI)
+
+		meta, err := client.GetDocMetadata(vID, "doc1")
+		require.NoError(t, err)
+		require.Equal(t, after.EncKeyURI, meta.EncKeyURI)
+
+		doc, err := client.GetDoc(vID, "doc1")
+		require.NoError(t, err)
+		require.JSONEq(t, `{"message":"Hello World!"}`, string(doc))
+	})
+
+	t.Run("Records the new key of a vault that was never re-encrypted", func(t *testing.T) {
+		client, vID := newRoundTripVaultClient(t, loader)
+
+		for _, docID := range []string{"doc1", "doc2", "doc3"} {
+			_, err := client.SaveDoc(vID, docID, []byte(`{"message":"Hello World!"}`))
+			require.NoError(t, err)
+		}
+
+		first, err := client.RekeyDoc(vID, "doc1")
+		require.NoError(t, err)
+
+		second, err := client.RekeyDoc(vID, "doc2")
+		require.NoError(t, err)
+		require.Equal(t, first.EncKeyURI, second.EncKeyURI)
+
+		status, err := client.GetRekeyStatus(vID)
+		require.NoError(t, err)
+		require.Equal(t, first.EncKeyURI, status.KeyURI)
+		require.False(t, status.Complete)
+
+		// the vault re-encryption resumes with the key
+		rekey, err := client.RekeyVault(vID)
+		require.NoError(t, err)
+		require.True(t, rekey.Complete)
+		require.Equal(t, first.EncKeyURI, rekey.KeyURI)
+		require.Equal(t, 3, rekey.Rekeyed)
+
+		meta, err := client.GetDocMetadata(vID, "doc3")
+		require.NoError(t, err)
+		require.Equal(t, first.EncKeyURI, meta.EncKeyURI)
+	})
+
+	t.Run("Re-encrypts to the key of the last re-encryption of the vault", func(t *testing.T) {
+		client, vID := newRoundTripVaultClient(t, loader)
+
+		_, err := client.SaveDoc(vID, "doc1", []byte(`{"message":"Hello World!"}`))
+		require.NoError(t, err)
+
+		rekey, err := client.RekeyVault(vID)
+		require.NoError(t, err)
+
+		// saved after the re-encryption, to a key of its own
+		before, err := client.SaveBinaryDoc(vID, "doc2", "text/plain", []byte("Hello again!"))
+		require.NoError(t, err)
+		require.NotEqual(t, rekey.KeyURI, before.EncKeyURI)
+
+		after, err := client.RekeyDoc(vID, "doc2")
+		require.NoError(t, err)
+		require.Equal(t, rekey.KeyURI, after.EncKeyURI)
+		require.Equal(t, before.Sequence+1, after.Sequence)
+
+		content, err := client.GetDocContent(vID, "doc2")
+		require.NoError(t, err)
+		require.Equal(t, []byte("Hello again!"), content.Data)
+
+		// documents already encrypted to the key are left as they are
+		again, err := client.RekeyDoc(vID, "doc2")
+		require.NoError(t, err)
+		require.Equal(t, after.Sequence, again.Sequence)
+	})
+
+	t.Run("Document not found", func(t *testing.T) {
+		client, vID := newRoundTripVaultClient(t, loader)
+
+		_, err := client.RekeyDoc(vID, "doc1")
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+	})
+
+	t.Run("Vault being deleted", func(t *testing.T) {
+		client, vID, _ := newLegacyVaultClient(t, loader, &backendFailures{edv: true})
+
+		_, err := client.SaveDoc(vID, "doc1", []byte(`{"message":"Hello World!"}`))
+		require.NoError(t, err)
+
+		result, err := client.DeleteVault(vID)
+		require.NoError(t, err)
+		require.False(t, result.Complete)
+
+		_, err = client.RekeyDoc(vID, "doc1")
+		require.True(t, errors.Is(err, vault.ErrVaultDeleting))
+	})
+}
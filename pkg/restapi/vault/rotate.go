@@ -0,0 +1,112 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/trustbloc/edge-core/pkg/zcapld"
+)
+
+// RotateTokens issues new EDV and KMS tokens to the controller of the vault, or to its owner, see WithVaultOwner,
+// eg. when a device holding the previous ones is lost, and records the zcaps of the previous ones as revoked: the
+// tokens last returned by RotateTokens or CreateVault, or the tokens the vault was created with. The new tokens are
+// returned in the shape CreateVault returns them.
+//
+// The new tokens are delegated from the ones the vault was created with and signed with the key of the vault, which
+// must be held by the vault server. The new tokens and the revocations are saved at once, so the previous tokens
+// are not revoked unless the new ones are issued. Like the zcaps of deleted authorizations, the revoked zcaps are
+// honored by EDV and KMS until they expire: parties accepting them must check GetRevocation.
+func (c *Client) RotateTokens(vaultID string) (*CreatedVault, error) {
+	// the vault info is saved along with the revocations
+	info, unlock, err := c.lockVaultInfo(vaultID)
+	if err != nil {
+		return nil, fmt.Errorf("get vault info: %w", err)
+	}
+
+	defer unlock()
+
+	if info.Deleting {
+		return nil, ErrVaultDeleting
+	}
+
+	previous := info.IssuedAuth
+	if previous == nil {
+		previous = info.Auth
+	}
+
+	invoker := info.DidURL
+	if info.Owner != "" {
+		invoker = info.Owner
+	}
+
+	info.IssuedAuth, err = c.issueTokens(info, invoker)
+	if err != nil {
+		return nil, err
+	}
+
+	infoOp, err := vaultInfoOperation(vaultID, info)
+	if err != nil {
+		return nil, fmt.Errorf("save vault info: %w", err)
+	}
+
+	ops := []storage.Operation{infoOp}
+
+	for _, token := range []string{previous.EDV.AuthToken, previous.KMS.AuthToken} {
+		op, err := revocationOperation(token)
+		if err != nil {
+			return nil, fmt.Errorf("revoke: %w", err)
+		}
+
+		ops = append(ops, op)
+	}
+
+	err = c.store.Batch(ops)
+	if err != nil {
+		return nil, fmt.Errorf("save tokens: %w", err)
+	}
+
+	return &CreatedVault{
+		ID:            vaultID,
+		Authorization: info.IssuedAuth,
+	}, nil
+}
+
+// issueTokens returns the EDV and KMS tokens of the vault delegated to the invoker, allowing the actions of the
+// tokens the vault was created with.
+func (c *Client) issueTokens(info *vaultInfo, invoker string) (*Authorization, error) {
+	edvActions, err := allowedActions(info.Auth.EDV.AuthToken)
+	if err != nil {
+		return nil, fmt.Errorf("edv uncompressZCAP: %w", err)
+	}
+
+	kmsActions, err := allowedActions(info.Auth.KMS.AuthToken)
+	if err != nil {
+		return nil, fmt.Errorf("kms uncompressZCAP: %w", err)
+	}
+
+	tokens, err := c.delegate(info, invoker, edvActions, kmsActions, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Authorization{
+		EDV: &Location{URI: info.Auth.EDV.URI, AuthToken: tokens.EDV},
+		KMS: &Location{URI: info.Auth.KMS.URI, AuthToken: tokens.KMS},
+	}, nil
+}
+
+// allowedActions returns the actions allowed by the compressed zcap.
+func allowedActions(token string) ([]string, error) {
+	zcap, err := zcapld.DecompressZCAP(token)
+	if err != nil {
+		return nil, err
+	}
+
+	return zcap.AllowedAction, nil
+}
@@ -0,0 +1,213 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	mockstorage "github.com/hyperledger/aries-framework-go/pkg/mock/storage"
+	"github.com/hyperledger/aries-framework-go/pkg/vdr/fingerprint"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/zcapld"
+
+	"github.com/trustbloc/ace/pkg/internal/testutil"
+	"github.com/trustbloc/ace/pkg/restapi/vault"
+)
+
+func TestClient_RotateTokens(t *testing.T) {
+	loader := testutil.DocumentLoader(t)
+
+	zcapIDs := func(t *testing.T, auth *vault.Authorization) []string {
+		t.Helper()
+
+		var ids []string
+
+		for _, token := range []string{auth.EDV.AuthToken, auth.KMS.AuthToken} {
+			zcap, err := zcapld.DecompressZCAP(token)
+			require.NoError(t, err)
+
+			ids = append(ids, zcap.ID)
+		}
+
+		return ids
+	}
+
+	requireRevoked := func(t *testing.T, client *vault.Client, auth *vault.Authorization, revoked bool) {
+		t.Helper()
+
+		for _, id := range zcapIDs(t, auth) {
+			_, err := client.GetRevocation(id)
+			if revoked {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, storage.ErrDataNotFound)
+			}
+		}
+	}
+
+	t.Run("Rotates the tokens of the controller", func(t *testing.T) {
+		client, _ := newKeyTypeVaultClient(t, loader)
+
+		created, err := client.CreateVault()
+		require.NoError(t, err)
+
+		info, err := client.GetVaultInfo(created.ID)
+		require.NoError(t, err)
+
+		rotated, err := client.RotateTokens(created.ID)
+		require.NoError(t, err)
+		require.Equal(t, created.ID, rotated.ID)
+		require.Equal(t, created.EDV.URI, rotated.EDV.URI)
+		require.Equal(t, created.KMS.URI, rotated.KMS.URI)
+		require.NotEqual(t, created.EDV.AuthToken, rotated.EDV.AuthToken)
+		require.NotEqual(t, created.KMS.AuthToken, rotated.KMS.AuthToken)
+
+		for i, token := range []string{rotated.EDV.AuthToken, rotated.KMS.AuthToken} {
+			zcap, err := zcapld.DecompressZCAP(token)
+			require.NoError(t, err)
+			require.Contains(t, zcap.Invoker, info.Controller)
+			require.Contains(t, zcap.Parent, zcapIDs(t, created.Authorization)[i])
+		}
+
+		requireRevoked(t, client, created.Authorization, true)
+		requireRevoked(t, client, rotated.Authorization, false)
+
+		// the vault server keeps invoking EDV and KMS with the tokens the vault was created with
+		_, err = client.SaveDoc(created.ID, "M3aS9xwj8ybCwHkEiCJJR1", []byte(`{"rotated":true}`))
+		require.NoError(t, err)
+
+		again, err := client.RotateTokens(created.ID)
+		require.NoError(t, err)
+
+		requireRevoked(t, client, rotated.Authorization, true)
+		requireRevoked(t, client, again.Authorization, false)
+	})
+
+	t.Run("Issues the tokens to the owner of the vault", func(t *testing.T) {
+		client, _ := newKeyTypeVaultClient(t, loader)
+
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		_, ownerKeyID := fingerprint.CreateDIDKey(pub)
+
+		req, err := http.NewRequest(http.MethodPost, "https://vault.example.com/vaults", strings.NewReader(`{}`))
+		require.NoError(t, err)
+
+		signRequest(t, req, priv, ownerKeyID)
+
+		created, err := client.CreateVault(vault.WithVaultOwner(req))
+		require.NoError(t, err)
+
+		invoke := func(t *testing.T, token string) error {
+			t.Helper()
+
+			req, err := http.NewRequest(http.MethodGet, "https://vault.example.com/vaults/"+created.ID, nil)
+			require.NoError(t, err)
+
+			req.Header.Set("Capability-Invocation", `zcap capability="`+token+`",action="read"`)
+			signRequestWith(t, req, []string{"(request-target)", "(created)", "capability-invocation"}, priv,
+				ownerKeyID)
+
+			return client.VerifyInvocation(created.ID, req)
+		}
+
+		require.NoError(t, invoke(t, created.EDV.AuthToken))
+
+		rotated, err := client.RotateTokens(created.ID)
+		require.NoError(t, err)
+
+		for _, token := range []string{rotated.EDV.AuthToken, rotated.KMS.AuthToken} {
+			zcap, err := zcapld.DecompressZCAP(token)
+			require.NoError(t, err)
+			require.Equal(t, ownerKeyID, zcap.Invoker)
+		}
+
+		requireRevoked(t, client, created.Authorization, true)
+		require.NoError(t, invoke(t, rotated.EDV.AuthToken))
+		require.ErrorIs(t, invoke(t, created.EDV.AuthToken), vault.ErrUnauthorizedInvocation)
+	})
+
+	t.Run("Requires the signature of the owner", func(t *testing.T) {
+		client, _ := newKeyTypeVaultClient(t, loader)
+
+		req, err := http.NewRequest(http.MethodPost, "https://vault.example.com/vaults", strings.NewReader(`{}`))
+		require.NoError(t, err)
+
+		_, err = client.CreateVault(vault.WithVaultOwner(req))
+		require.ErrorIs(t, err, vault.ErrUnauthorizedInvocation)
+	})
+
+	t.Run("Keeps the tokens if the new ones cannot be saved", func(t *testing.T) {
+		data := map[string]mockstorage.DBEntry{}
+
+		store := &mockstorage.MockStoreProvider{
+			Store: &mockstorage.MockStore{Store: data, ErrBatch: errors.New("batch error")},
+		}
+
+		lKMS := newLocalKms(t, store)
+		client, err := vault.NewClient("", "", lKMS, store, loader)
+		require.NoError(t, err)
+
+		vID, dURL, kid := createVaultID(t, lKMS)
+
+		data["info_"+vID] = mockstorage.DBEntry{
+			Value: []byte(`{"did_url":"` + dURL + `", "kid":"` + kid + `","version":3,"auth":` + vaultAuth + `}`),
+		}
+
+		info := data["info_"+vID].Value
+
+		_, err = client.RotateTokens(vID)
+		require.EqualError(t, err, "save tokens: batch error")
+		require.Equal(t, info, data["info_"+vID].Value)
+
+		for key := range data {
+			require.NotContains(t, key, "revocation_")
+		}
+	})
+
+	t.Run("Vault being deleted", func(t *testing.T) {
+		client, err := vault.NewClient("", "", nil, &mockstorage.MockStoreProvider{
+			Store: &mockstorage.MockStore{Store: map[string]mockstorage.DBEntry{
+				"info_vid": {Value: []byte(`{"version":3,"deleting":true,"auth":` + vaultAuth + `}`)},
+			}},
+		}, loader)
+		require.NoError(t, err)
+
+		_, err = client.RotateTokens("vid")
+		require.ErrorIs(t, err, vault.ErrVaultDeleting)
+	})
+
+	t.Run("Unknown vault", func(t *testing.T) {
+		client, err := vault.NewClient("", "", nil, &mockstorage.MockStoreProvider{
+			Store: &mockstorage.MockStore{Store: map[string]mockstorage.DBEntry{}},
+		}, loader)
+		require.NoError(t, err)
+
+		_, err = client.RotateTokens("vid")
+		require.ErrorIs(t, err, storage.ErrDataNotFound)
+	})
+
+	t.Run("Malformed token", func(t *testing.T) {
+		client, err := vault.NewClient("", "", nil, &mockstorage.MockStoreProvider{
+			Store: &mockstorage.MockStore{Store: map[string]mockstorage.DBEntry{
+				"info_vid": {Value: []byte(`{"version":3,"auth":{"edv":{"authToken":"invalid"},"kms":{}}}`)},
+			}},
+		}, loader)
+		require.NoError(t, err)
+
+		_, err = client.RotateTokens("vid")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "edv uncompressZCAP")
+	})
+}
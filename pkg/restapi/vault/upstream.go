@@ -0,0 +1,115 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	ariescrypto "github.com/hyperledger/aries-framework-go/pkg/crypto"
+)
+
+// The upstream servers the vault server calls with the authorizations of the vaults.
+const (
+	UpstreamEDV = "edv"
+	UpstreamKMS = "kms"
+)
+
+// maxUpstreamMessage is the number of bytes of the body of a refusal kept in an UpstreamAuthError at most.
+const maxUpstreamMessage = 512
+
+// ErrUpstreamUnauthorized is returned when the EDV or KMS server refuses the authorization of a vault, eg. because
+// it expired or was revoked. Retrying does not help: the error wraps an UpstreamAuthError detailing the refusal.
+var ErrUpstreamUnauthorized = errors.New("upstream server refused the authorization of the vault")
+
+// UpstreamAuthError is the refusal of the authorization of a vault by the EDV or KMS server.
+type UpstreamAuthError struct {
+	// Upstream is the server that refused the authorization, UpstreamEDV or UpstreamKMS.
+	Upstream string
+	// StatusCode is the status code of the refusal, 401 or 403.
+	StatusCode int
+	// Message is the body of the refusal.
+	Message string
+}
+
+func (e *UpstreamAuthError) Error() string {
+	return fmt.Sprintf("%s: the %s server returned status code %d along with the following message: %s",
+		ErrUpstreamUnauthorized, e.Upstream, e.StatusCode, e.Message)
+}
+
+// Unwrap returns ErrUpstreamUnauthorized.
+func (e *UpstreamAuthError) Unwrap() error {
+	return ErrUpstreamUnauthorized
+}
+
+// Hint tells how to remediate the refusal.
+func (e *UpstreamAuthError) Hint() string {
+	what := "vault EDV authorization"
+	if e.Upstream == UpstreamKMS {
+		what = "vault keystore authorization"
+	}
+
+	if e.StatusCode == http.StatusForbidden {
+		return fmt.Sprintf("%s revoked or insufficient; re-create authorization", what)
+	}
+
+	return fmt.Sprintf("%s expired or invalid; re-create authorization", what)
+}
+
+// upstreamAuthHTTPClient turns the 401 and 403 responses of an upstream server into UpstreamAuthError errors,
+// which the EDV and WebKMS clients pass on, since the messages of their errors are not stable.
+type upstreamAuthHTTPClient struct {
+	base     HTTPClient
+	upstream string
+}
+
+func (c *upstreamAuthHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	resp, err := c.base.Do(req)
+	if err != nil || (resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusForbidden) {
+		return resp, err
+	}
+
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil {
+			logger.Errorf("failed to close response body: %s", errClose)
+		}
+	}()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response body: %w", err)
+	}
+
+	if len(body) > maxUpstreamMessage {
+		body = body[:maxUpstreamMessage]
+	}
+
+	return nil, &UpstreamAuthError{Upstream: c.upstream, StatusCode: resp.StatusCode, Message: string(body)}
+}
+
+// kmsHTTPClient returns the HTTP client of the calls to the KMS server.
+func (c *Client) kmsHTTPClient() HTTPClient {
+	return &upstreamAuthHTTPClient{base: c.httpClient, upstream: UpstreamKMS}
+}
+
+// unwrapErrCrypto records the refusals of the key unwrapping calls, since JWE decryption does not wrap their errors.
+type unwrapErrCrypto struct {
+	ariescrypto.Crypto
+	refusal *UpstreamAuthError
+}
+
+func (c *unwrapErrCrypto) UnwrapKey(recWK *ariescrypto.RecipientWrappedKey, kh interface{},
+	opts ...ariescrypto.WrapKeyOpts) ([]byte, error) {
+	key, err := c.Crypto.UnwrapKey(recWK, kh, opts...)
+	if err != nil {
+		errors.As(err, &c.refusal)
+	}
+
+	return key, err
+}
@@ -0,0 +1,124 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/ace/pkg/internal/testutil"
+	"github.com/trustbloc/ace/pkg/restapi/vault"
+)
+
+func TestClient_UpstreamAuthorizationRefused(t *testing.T) {
+	loader := testutil.DocumentLoader(t)
+
+	for name, tc := range map[string]struct {
+		upstream string
+		status   int
+		hint     string
+	}{
+		"EDV token expired": {
+			upstream: vault.UpstreamEDV,
+			status:   http.StatusUnauthorized,
+			hint:     "vault EDV authorization expired or invalid; re-create authorization",
+		},
+		"EDV capability revoked": {
+			upstream: vault.UpstreamEDV,
+			status:   http.StatusForbidden,
+			hint:     "vault EDV authorization revoked or insufficient; re-create authorization",
+		},
+		"KMS token expired": {
+			upstream: vault.UpstreamKMS,
+			status:   http.StatusUnauthorized,
+			hint:     "vault keystore authorization expired or invalid; re-create authorization",
+		},
+		"KMS capability revoked": {
+			upstream: vault.UpstreamKMS,
+			status:   http.StatusForbidden,
+			hint:     "vault keystore authorization revoked or insufficient; re-create authorization",
+		},
+	} {
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			var edvStatus, kmsStatus int32
+
+			remoteKMS := httptest.NewServer(newRefusingHandler(newKMSHandler(t), &kmsStatus))
+			t.Cleanup(remoteKMS.Close)
+
+			edv := httptest.NewServer(newRefusingHandler(newEDVHandler(t), &edvStatus))
+			t.Cleanup(edv.Close)
+
+			provider := mem.NewProvider()
+
+			client, err := vault.NewClient(remoteKMS.URL, edv.URL, newLocalKms(t, provider), provider, loader)
+			require.NoError(t, err)
+
+			created, err := client.CreateVault()
+			require.NoError(t, err)
+
+			_, err = client.SaveDoc(created.ID, "doc", []byte(`{"message":"Hello World!"}`))
+			require.NoError(t, err)
+
+			refused := &edvStatus
+			if tc.upstream == vault.UpstreamKMS {
+				refused = &kmsStatus
+			}
+
+			atomic.StoreInt32(refused, int32(tc.status))
+
+			requireRefusal := func(err error) {
+				t.Helper()
+
+				require.True(t, errors.Is(err, vault.ErrUpstreamUnauthorized), err)
+
+				var refusal *vault.UpstreamAuthError
+
+				require.True(t, errors.As(err, &refusal))
+				require.Equal(t, tc.upstream, refusal.Upstream)
+				require.Equal(t, tc.status, refusal.StatusCode)
+				require.Equal(t, "refused", refusal.Message)
+				require.Equal(t, tc.hint, refusal.Hint())
+			}
+
+			_, err = client.GetDoc(created.ID, "doc")
+			requireRefusal(err)
+
+			_, err = client.SaveDoc(created.ID, "doc", []byte(`{"message":"Bye!"}`))
+			requireRefusal(err)
+
+			// the vault works again once the upstream server accepts its authorization
+			atomic.StoreInt32(refused, 0)
+
+			content, err := client.GetDoc(created.ID, "doc")
+			require.NoError(t, err)
+			require.JSONEq(t, `{"message":"Hello World!"}`, string(content))
+		})
+	}
+}
+
+// newRefusingHandler returns a handler refusing the requests with the status, if set, and passing them on to the
+// handler otherwise.
+func newRefusingHandler(handler http.HandlerFunc, status *int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s := atomic.LoadInt32(status); s != 0 {
+			w.WriteHeader(int(s))
+			_, _ = w.Write([]byte("refused"))
+
+			return
+		}
+
+		handler(w, r)
+	}
+}
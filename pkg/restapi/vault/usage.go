@@ -0,0 +1,175 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+)
+
+// CaveatTypeUsage limits the number of times an authorization can be used to the Uses of the caveat. The EDV and
+// KMS do not know it: it is enforced by the vault server, which counts the uses recorded with UseAuthorization.
+const CaveatTypeUsage = "usage"
+
+// AuthorizationStatusExhausted is the status of the authorizations whose usage caveat allows no more uses.
+const AuthorizationStatusExhausted = "exhausted"
+
+// ErrAuthorizationExhausted is returned when an authorization is used once more than its usage caveat allows.
+var ErrAuthorizationExhausted = errors.New("authorization has no remaining uses")
+
+// ErrAuthorizationInactive is returned when an expired or revoked authorization is used.
+var ErrAuthorizationInactive = errors.New("authorization is not active")
+
+const authorizationUsageFormat = "authorization_usage_%s_%s"
+
+// authorizationUsage counts the uses of an authorization. It is stored apart from the authorization, which is
+// stored as it was created.
+type authorizationUsage struct {
+	Uses uint64 `json:"uses"`
+}
+
+// maxUses returns the uses allowed by the most restrictive usage caveat, or nil if there is none.
+func maxUses(caveats []Caveat) *uint64 {
+	var res *uint64
+
+	for _, caveat := range caveats {
+		if caveat.Type != CaveatTypeUsage {
+			continue
+		}
+
+		uses := caveat.Uses
+
+		if res == nil || uses < *res {
+			res = &uses
+		}
+	}
+
+	return res
+}
+
+// UseAuthorization records a use of the authorization, on behalf of the party exercising it, and returns the
+// authorization with its updated usage. It fails with ErrAuthorizationExhausted if its usage caveat allows no more
+// uses and with ErrAuthorizationInactive if it expired or was revoked.
+func (c *Client) UseAuthorization(vaultID, id string) (*CreatedAuthorization, error) {
+	a, err := c.getAuthorization(vaultID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	if status := a.status(now); status != AuthorizationStatusActive {
+		return nil, fmt.Errorf("%w: %s", ErrAuthorizationInactive, status)
+	}
+
+	// the count is read and written back under the lock so that concurrent uses are all counted
+	c.usageMu.Lock()
+	defer c.usageMu.Unlock()
+
+	usage, err := c.getAuthorizationUsage(vaultID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if limit := maxUses(scopeCaveats(a.Scope)); limit != nil && usage.Uses >= *limit {
+		return nil, fmt.Errorf("%w: used %d of %d times", ErrAuthorizationExhausted, usage.Uses, *limit)
+	}
+
+	usage.Uses++
+
+	src, err := json.Marshal(usage)
+	if err != nil {
+		return nil, fmt.Errorf("marshal: %w", err)
+	}
+
+	err = c.store.Put(fmt.Sprintf(authorizationUsageFormat, vaultID, id), src)
+	if err != nil {
+		return nil, fmt.Errorf("put usage: %w", err)
+	}
+
+	a.setValidity(usage, now)
+
+	err = c.recordAudit(AuditAuthorizationUsed, vaultID, a)
+	if err != nil {
+		return nil, fmt.Errorf("record audit event: %w", err)
+	}
+
+	return a, nil
+}
+
+// loadValidity sets the usage and remaining validity of the authorization.
+func (c *Client) loadValidity(vaultID string, a *CreatedAuthorization, now time.Time) error {
+	usage, err := c.getAuthorizationUsage(vaultID, a.ID)
+	if err != nil {
+		return err
+	}
+
+	a.setValidity(usage, now)
+
+	return nil
+}
+
+func (c *Client) getAuthorizationUsage(vaultID, id string) (*authorizationUsage, error) {
+	usage := &authorizationUsage{}
+
+	src, err := c.store.Get(fmt.Sprintf(authorizationUsageFormat, vaultID, id))
+	if errors.Is(err, storage.ErrDataNotFound) {
+		return usage, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("get usage: %w", err)
+	}
+
+	err = json.Unmarshal(src, usage)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal usage: %w", err)
+	}
+
+	return usage, nil
+}
+
+// setValidity sets the uses, remaining uses and seconds until expiry of the authorization, and its status.
+func (a *CreatedAuthorization) setValidity(usage *authorizationUsage, now time.Time) {
+	a.Uses = usage.Uses
+	a.RemainingUses = nil
+	a.SecondsUntilExpiry = nil
+
+	if limit := maxUses(scopeCaveats(a.Scope)); limit != nil {
+		var remaining uint64
+
+		if usage.Uses < *limit {
+			remaining = *limit - usage.Uses
+		}
+
+		a.RemainingUses = &remaining
+	}
+
+	if a.ExpiresAt != nil {
+		seconds := int64(a.ExpiresAt.Sub(now) / time.Second)
+
+		if seconds < 0 {
+			seconds = 0
+		}
+
+		a.SecondsUntilExpiry = &seconds
+	}
+
+	a.Status = a.status(now)
+}
+
+func scopeCaveats(scope *AuthorizationsScope) []Caveat {
+	if scope == nil {
+		return nil
+	}
+
+	return scope.Caveats
+}
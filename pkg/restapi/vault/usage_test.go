@@ -0,0 +1,159 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault_test
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/zcapld"
+
+	"github.com/trustbloc/ace/pkg/internal/testutil"
+	"github.com/trustbloc/ace/pkg/restapi/vault"
+)
+
+func TestClient_UseAuthorization(t *testing.T) {
+	loader := testutil.DocumentLoader(t)
+
+	newAuthorization := func(t *testing.T, caveats ...vault.Caveat) (*vault.Client, string, *vault.CreatedAuthorization) {
+		t.Helper()
+
+		client, _ := newKeyTypeVaultClient(t, loader)
+
+		created, err := client.CreateVault()
+		require.NoError(t, err)
+
+		auth, err := client.CreateAuthorization(created.ID, "did:example:rp", &vault.AuthorizationsScope{
+			Target:  "doc",
+			Actions: []string{"read"},
+			Caveats: caveats,
+		})
+		require.NoError(t, err)
+
+		return client, created.ID, auth
+	}
+
+	t.Run("Reports the remaining uses and validity", func(t *testing.T) {
+		client, vaultID, auth := newAuthorization(t,
+			vault.Caveat{Type: vault.CaveatTypeUsage, Uses: 2},
+			vault.Caveat{Type: zcapld.CaveatTypeExpiry, Duration: 3600},
+		)
+		require.Equal(t, uint64(2), *auth.RemainingUses)
+
+		got, err := client.GetAuthorization(vaultID, auth.ID)
+		require.NoError(t, err)
+		require.Zero(t, got.Uses)
+		require.Equal(t, uint64(2), *got.RemainingUses)
+		require.InDelta(t, 3600, *got.SecondsUntilExpiry, 5)
+		require.Equal(t, vault.AuthorizationStatusActive, got.Status)
+
+		used, err := client.UseAuthorization(vaultID, auth.ID)
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), used.Uses)
+		require.Equal(t, uint64(1), *used.RemainingUses)
+
+		used, err = client.UseAuthorization(vaultID, auth.ID)
+		require.NoError(t, err)
+		require.Zero(t, *used.RemainingUses)
+		require.Equal(t, vault.AuthorizationStatusExhausted, used.Status)
+
+		_, err = client.UseAuthorization(vaultID, auth.ID)
+		require.True(t, errors.Is(err, vault.ErrAuthorizationExhausted))
+
+		got, err = client.GetAuthorization(vaultID, auth.ID)
+		require.NoError(t, err)
+		require.Equal(t, uint64(2), got.Uses)
+		require.Equal(t, vault.AuthorizationStatusExhausted, got.Status)
+
+		list, err := client.ListAuthorizations(vaultID, nil)
+		require.NoError(t, err)
+		require.Len(t, list.Authorizations, 1)
+		require.Equal(t, vault.AuthorizationStatusExhausted, list.Authorizations[0].Status)
+	})
+
+	t.Run("Authorizations without caveats are not limited", func(t *testing.T) {
+		client, vaultID, auth := newAuthorization(t)
+		require.Nil(t, auth.RemainingUses)
+		require.Nil(t, auth.SecondsUntilExpiry)
+
+		for i := 0; i < 3; i++ {
+			_, err := client.UseAuthorization(vaultID, auth.ID)
+			require.NoError(t, err)
+		}
+
+		got, err := client.GetAuthorization(vaultID, auth.ID)
+		require.NoError(t, err)
+		require.Equal(t, uint64(3), got.Uses)
+		require.Nil(t, got.RemainingUses)
+		require.Equal(t, vault.AuthorizationStatusActive, got.Status)
+	})
+
+	t.Run("Concurrent uses are all counted", func(t *testing.T) {
+		client, vaultID, auth := newAuthorization(t, vault.Caveat{Type: vault.CaveatTypeUsage, Uses: 5})
+
+		var (
+			wg        sync.WaitGroup
+			succeeded int32
+		)
+
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				if _, err := client.UseAuthorization(vaultID, auth.ID); err == nil {
+					atomic.AddInt32(&succeeded, 1)
+				}
+			}()
+		}
+
+		wg.Wait()
+
+		require.Equal(t, int32(5), succeeded)
+
+		got, err := client.GetAuthorization(vaultID, auth.ID)
+		require.NoError(t, err)
+		require.Equal(t, uint64(5), got.Uses)
+	})
+
+	t.Run("Usage caveats are not included in the zcaps", func(t *testing.T) {
+		_, _, auth := newAuthorization(t,
+			vault.Caveat{Type: vault.CaveatTypeUsage, Uses: 2},
+			vault.Caveat{Type: zcapld.CaveatTypeExpiry, Duration: 3600},
+		)
+
+		for _, token := range []string{auth.Tokens.EDV, auth.Tokens.KMS} {
+			zcap, err := zcapld.DecompressZCAP(token)
+			require.NoError(t, err)
+			require.Equal(t, []zcapld.Caveat{{Type: zcapld.CaveatTypeExpiry, Duration: 3600}}, zcap.Caveats)
+		}
+	})
+
+	t.Run("Error if the authorization is not active", func(t *testing.T) {
+		client, vaultID, auth := newAuthorization(t, vault.Caveat{Type: zcapld.CaveatTypeExpiry, Duration: 0})
+
+		_, err := client.UseAuthorization(vaultID, auth.ID)
+		require.True(t, errors.Is(err, vault.ErrAuthorizationInactive))
+	})
+
+	t.Run("Error if the authorization does not exist", func(t *testing.T) {
+		client, vaultID, auth := newAuthorization(t, vault.Caveat{Type: vault.CaveatTypeUsage, Uses: 2})
+
+		_, err := client.UseAuthorization(vaultID, auth.ID)
+		require.NoError(t, err)
+
+		require.NoError(t, client.DeleteAuthorization(vaultID, auth.ID))
+
+		_, err = client.UseAuthorization(vaultID, auth.ID)
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+	})
+}
@@ -0,0 +1,592 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+)
+
+const (
+	webhookFormat    = "webhook_%s_%s"
+	deadLetterFormat = "webhook_dead_letter_%s_%s"
+
+	vaultWebhooksTag    = "vault_webhooks"
+	vaultDeadLettersTag = "vault_dead_letters"
+
+	// DocSavedEventType is the type of the events sent when a document is saved.
+	DocSavedEventType = "DocumentSaved"
+	// WebhookSignatureHeader carries the hex-encoded HMAC-SHA256 of the event, keyed with the secret of the webhook,
+	// as "sha256=<hex>". It is not set for webhooks registered without a secret.
+	WebhookSignatureHeader = "X-Vault-Signature"
+
+	defaultWebhookAttempts = 5
+	defaultWebhookBackoff  = time.Second
+	defaultWebhookWorkers  = 4
+
+	// webhookQueueSize is the number of deliveries waiting for a worker, above which events are dropped and recorded
+	// as dead letters.
+	webhookQueueSize = 1024
+
+	webhookTimeout     = time.Minute
+	webhookDialTimeout = 30 * time.Second
+)
+
+// ErrInvalidWebhook is returned when registering a webhook whose URL is not an absolute http(s) URL.
+var ErrInvalidWebhook = errors.New("invalid webhook")
+
+// ErrWebhookAddressNotAllowed is returned by the clients of NewWebhookHTTPClient when connecting to an address that
+// is not public, eg. a loopback or private address.
+var ErrWebhookAddressNotAllowed = errors.New("webhook address not allowed")
+
+var (
+	errWebhooksClosed   = errors.New("webhook deliveries stopped")
+	errWebhookQueueFull = errors.New("webhook delivery queue full")
+)
+
+// nonPublicNetworks are the networks webhooks cannot reach besides the loopback, private, link-local, multicast and
+// unspecified addresses.
+var nonPublicNetworks = []*net.IPNet{ // nolint:gochecknoglobals
+	mustParseCIDR("0.0.0.0/8"),
+	// shared address space of carrier-grade NATs
+	mustParseCIDR("100.64.0.0/10"),
+}
+
+// Webhook is a URL notified of the documents saved in a vault. Its secret is never returned.
+type Webhook struct {
+	ID      string    `json:"id"`
+	URL     string    `json:"url"`
+	Created time.Time `json:"created"`
+}
+
+// WebhookEvent is POSTed to the webhooks of a vault. It never contains the content of the document.
+type WebhookEvent struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	VaultID   string    `json:"vaultID"`
+	DocID     string    `json:"docID"`
+	Sequence  uint64    `json:"sequence"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// WebhookList lists the webhooks of a vault.
+type WebhookList struct {
+	Webhooks []*Webhook `json:"webhooks"`
+}
+
+type webhook struct {
+	Webhook
+	Secret string `json:"secret,omitempty"`
+}
+
+// webhookDelivery is the delivery of an event to a webhook.
+type webhookDelivery struct {
+	hook     *webhook
+	event    *WebhookEvent
+	body     []byte
+	attempts int
+	// backoff is the delay before the next retry.
+	backoff time.Duration
+}
+
+// webhookDispatcher delivers the events to the webhooks with a fixed number of workers, started on the first event.
+type webhookDispatcher struct {
+	ctx    context.Context // nolint:containedctx // canceled by Client.Close
+	cancel context.CancelFunc
+	start  sync.Once
+	queue  chan *webhookDelivery
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+	closed bool
+	// retries are the deliveries waiting for their next attempt.
+	retries map[*webhookDelivery]*time.Timer
+}
+
+func newWebhookDispatcher() *webhookDispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &webhookDispatcher{
+		ctx:     ctx,
+		cancel:  cancel,
+		queue:   make(chan *webhookDelivery, webhookQueueSize),
+		retries: make(map[*webhookDelivery]*time.Timer),
+	}
+}
+
+// deadLetter records an event that could not be delivered to a webhook.
+type deadLetter struct {
+	WebhookID string        `json:"webhookID"`
+	URL       string        `json:"url"`
+	Event     *WebhookEvent `json:"event"`
+	Attempts  int           `json:"attempts"`
+	Error     string        `json:"error"`
+	Failed    time.Time     `json:"failed"`
+}
+
+// WithWebhookRetries sets the number of attempts to deliver an event to a webhook and the delay before the first
+// retry, doubled on every retry. Defaults to 5 attempts, starting with a 1s delay.
+func WithWebhookRetries(attempts int, backoff time.Duration) Opt {
+	return func(vault *Client) {
+		vault.webhookAttempts = attempts
+		vault.webhookBackoff = backoff
+	}
+}
+
+// WithWebhookWorkers sets the number of events delivered to the webhooks at once. Defaults to 4.
+func WithWebhookWorkers(workers int) Opt {
+	return func(vault *Client) {
+		vault.webhookWorkers = workers
+	}
+}
+
+// WithWebhookHTTPClient sets the HTTP client events are delivered with, instead of a client of NewWebhookHTTPClient.
+// The client is then responsible for restricting the addresses webhooks can reach.
+func WithWebhookHTTPClient(client HTTPClient) Opt {
+	return func(vault *Client) {
+		vault.webhookClient = client
+	}
+}
+
+// NewWebhookHTTPClient returns an HTTP client only connecting to public addresses, so that webhooks cannot reach
+// the services of the network of the vault server. The addresses are checked once resolved, on every connection,
+// and redirects are not followed. Proxies are not used.
+func NewWebhookHTTPClient(tlsConfig *tls.Config) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: webhookDialTimeout,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return fmt.Errorf("%w: %s", ErrWebhookAddressNotAllowed, address)
+			}
+
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return fmt.Errorf("%w: %s", ErrWebhookAddressNotAllowed, address)
+			}
+
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: webhookTimeout,
+		Transport: &http.Transport{
+			DialContext:     dialer.DialContext,
+			TLSClientConfig: tlsConfig,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+
+	for _, n := range nonPublicNetworks {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, n, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+
+	return n
+}
+
+// CreateWebhook registers a URL to notify of the documents saved in the vault. If a secret is given, events are
+// signed with it, see WebhookSignatureHeader. By default, events are only delivered to public addresses, see
+// NewWebhookHTTPClient.
+func (c *Client) CreateWebhook(vaultID, webhookURL, secret string) (*Webhook, error) {
+	u, err := url.Parse(webhookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: url must be an absolute http or https URL: %s", ErrInvalidWebhook, webhookURL)
+	}
+
+	info, err := c.getVaultInfo(vaultID)
+	if err != nil {
+		return nil, fmt.Errorf("get vault info: %w", err)
+	}
+
+	if info.Deleting {
+		return nil, ErrVaultDeleting
+	}
+
+	hook := &webhook{
+		Webhook: Webhook{ID: uuid.New().String(), URL: webhookURL, Created: time.Now().UTC()},
+		Secret:  secret,
+	}
+
+	src, err := json.Marshal(hook)
+	if err != nil {
+		return nil, fmt.Errorf("marshal: %w", err)
+	}
+
+	err = c.store.Put(fmt.Sprintf(webhookFormat, vaultID, hook.ID), src,
+		storage.Tag{Name: vaultWebhooksTag, Value: vaultIndex(vaultID)},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("store put: %w", err)
+	}
+
+	return &hook.Webhook, nil
+}
+
+// ListWebhooks lists the webhooks of the vault, oldest first.
+func (c *Client) ListWebhooks(vaultID string) (*WebhookList, error) {
+	_, err := c.getVaultInfo(vaultID)
+	if err != nil {
+		return nil, fmt.Errorf("get vault info: %w", err)
+	}
+
+	hooks, err := c.queryWebhooks(vaultID)
+	if err != nil {
+		return nil, fmt.Errorf("query webhooks: %w", err)
+	}
+
+	sort.Slice(hooks, func(i, j int) bool {
+		if hooks[i].Created.Equal(hooks[j].Created) {
+			return hooks[i].ID < hooks[j].ID
+		}
+
+		return hooks[i].Created.Before(hooks[j].Created)
+	})
+
+	list := &WebhookList{Webhooks: []*Webhook{}}
+
+	for _, hook := range hooks {
+		list.Webhooks = append(list.Webhooks, &hook.Webhook)
+	}
+
+	return list, nil
+}
+
+// DeleteWebhook unregisters the webhook. Events already queued for the webhook are still delivered.
+func (c *Client) DeleteWebhook(vaultID, id string) error {
+	key := fmt.Sprintf(webhookFormat, vaultID, id)
+
+	_, err := c.store.Get(key)
+	if err != nil {
+		return fmt.Errorf("get webhook: %w", err)
+	}
+
+	err = c.store.Delete(key)
+	if err != nil {
+		return fmt.Errorf("delete webhook: %w", err)
+	}
+
+	return nil
+}
+
+// notifyDocSaved queues the delivery of the event of the saved document to the webhooks of the vault.
+func (c *Client) notifyDocSaved(vaultID string, meta *DocumentMetadata) {
+	event := &WebhookEvent{
+		ID:        uuid.New().String(),
+		Type:      DocSavedEventType,
+		VaultID:   vaultID,
+		DocID:     meta.ID,
+		Sequence:  meta.Sequence,
+		Timestamp: time.Now().UTC(),
+	}
+
+	hooks, err := c.queryWebhooks(vaultID)
+	if err != nil {
+		logger.Errorf("failed to query webhooks of vault %s: %s", vaultID, err)
+
+		return
+	}
+
+	if len(hooks) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		logger.Errorf("failed to marshal webhook event: %s", err)
+
+		return
+	}
+
+	for _, hook := range hooks {
+		c.enqueueDelivery(&webhookDelivery{hook: hook, event: event, body: body, backoff: c.webhookBackoff})
+	}
+}
+
+// enqueueDelivery queues the delivery for the next available worker. It is recorded as a dead letter if the queue
+// is full or the client is closed.
+func (c *Client) enqueueDelivery(d *webhookDelivery) {
+	w := c.webhooks
+
+	w.mu.Lock()
+
+	err := errWebhooksClosed
+
+	if !w.closed {
+		w.start.Do(func() {
+			for i := 0; i < c.webhookWorkers; i++ {
+				w.wg.Add(1)
+
+				go c.runWebhookWorker()
+			}
+		})
+
+		select {
+		case w.queue <- d:
+			err = nil
+		default:
+			err = errWebhookQueueFull
+		}
+	}
+
+	w.mu.Unlock()
+
+	if err != nil {
+		c.saveDeadLetter(d, err)
+	}
+}
+
+func (c *Client) runWebhookWorker() {
+	defer c.webhooks.wg.Done()
+
+	for {
+		select {
+		case <-c.webhooks.ctx.Done():
+			return
+		case d := <-c.webhooks.queue:
+			c.deliver(d)
+		}
+	}
+}
+
+// deliver POSTs the event to the webhook. Failed deliveries are retried with exponential backoff, and recorded as
+// dead letters once every attempt failed.
+func (c *Client) deliver(d *webhookDelivery) {
+	d.attempts++
+
+	err := c.post(c.webhooks.ctx, d.hook, d.body)
+	if err == nil {
+		return
+	}
+
+	if d.attempts >= c.webhookAttempts {
+		c.saveDeadLetter(d, err)
+
+		return
+	}
+
+	logger.Warnf("failed to deliver event %s to webhook %s (attempt %d): %s", d.event.ID, d.hook.ID, d.attempts, err)
+
+	c.retryDelivery(d, err)
+}
+
+// retryDelivery queues the delivery again after its backoff. Deliveries waiting for a retry hold no worker.
+func (c *Client) retryDelivery(d *webhookDelivery, cause error) {
+	w := c.webhooks
+
+	w.mu.Lock()
+
+	if w.closed {
+		w.mu.Unlock()
+
+		c.saveDeadLetter(d, cause)
+
+		return
+	}
+
+	// Close waits for the retries already firing
+	w.wg.Add(1)
+
+	w.retries[d] = time.AfterFunc(d.backoff, func() {
+		defer w.wg.Done()
+
+		w.mu.Lock()
+		_, pending := w.retries[d]
+		delete(w.retries, d)
+		w.mu.Unlock()
+
+		// deliveries no longer pending were recorded as dead letters by Close
+		if pending {
+			c.enqueueDelivery(d)
+		}
+	})
+
+	d.backoff *= 2
+
+	w.mu.Unlock()
+}
+
+// Close stops the delivery of the events to the webhooks. Deliveries in progress are aborted, and the deliveries
+// not completed, including the ones waiting for a retry, are recorded as dead letters.
+func (c *Client) Close() {
+	w := c.webhooks
+
+	w.mu.Lock()
+
+	if w.closed {
+		w.mu.Unlock()
+
+		return
+	}
+
+	w.closed = true
+
+	var pending []*webhookDelivery
+
+	for d, timer := range w.retries {
+		if timer.Stop() {
+			w.wg.Done()
+		}
+
+		pending = append(pending, d)
+	}
+
+	w.retries = nil
+
+	w.mu.Unlock()
+
+	// aborts the deliveries in progress, which are then recorded as dead letters
+	w.cancel()
+	w.wg.Wait()
+
+	for {
+		select {
+		case d := <-w.queue:
+			pending = append(pending, d)
+		default:
+			for _, d := range pending {
+				c.saveDeadLetter(d, errWebhooksClosed)
+			}
+
+			return
+		}
+	}
+}
+
+func (c *Client) post(ctx context.Context, hook *webhook, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if hook.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(hook.Secret))
+		mac.Write(body) // nolint:errcheck // never fails
+
+		req.Header.Set(WebhookSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := c.webhookClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("post: %w", err)
+	}
+
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil {
+			logger.Errorf("failed to close response body: %s", errClose)
+		}
+	}()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (c *Client) saveDeadLetter(d *webhookDelivery, cause error) {
+	logger.Errorf("giving up delivering event %s to webhook %s after %d attempts: %s",
+		d.event.ID, d.hook.ID, d.attempts, cause)
+
+	src, err := json.Marshal(&deadLetter{
+		WebhookID: d.hook.ID,
+		URL:       d.hook.URL,
+		Event:     d.event,
+		Attempts:  d.attempts,
+		Error:     cause.Error(),
+		Failed:    time.Now().UTC(),
+	})
+	if err != nil {
+		logger.Errorf("failed to marshal dead letter: %s", err)
+
+		return
+	}
+
+	err = c.store.Put(fmt.Sprintf(deadLetterFormat, d.event.VaultID, d.event.ID+"_"+d.hook.ID), src,
+		storage.Tag{Name: vaultDeadLettersTag, Value: vaultIndex(d.event.VaultID)},
+	)
+	if err != nil {
+		logger.Errorf("failed to save dead letter: %s", err)
+	}
+}
+
+func (c *Client) queryWebhooks(vID string) ([]*webhook, error) {
+	iter, err := c.store.Query(fmt.Sprintf("%s:%s", vaultWebhooksTag, vaultIndex(vID)))
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	defer func() {
+		if errClose := iter.Close(); errClose != nil {
+			logger.Errorf("failed to close iterator: %s", errClose)
+		}
+	}()
+
+	var hooks []*webhook
+
+	for {
+		ok, err := iter.Next()
+		if err != nil {
+			return nil, fmt.Errorf("iterator next: %w", err)
+		}
+
+		if !ok {
+			return hooks, nil
+		}
+
+		src, err := iter.Value()
+		if err != nil {
+			return nil, fmt.Errorf("iterator value: %w", err)
+		}
+
+		var hook *webhook
+
+		err = json.Unmarshal(src, &hook)
+		if err != nil {
+			return nil, fmt.Errorf("unmarshal: %w", err)
+		}
+
+		hooks = append(hooks, hook)
+	}
+}
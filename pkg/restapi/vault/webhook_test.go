@@ -0,0 +1,397 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vault_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/piprate/json-gold/ld"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/ace/pkg/internal/testutil"
+	"github.com/trustbloc/ace/pkg/restapi/vault"
+)
+
+type webhookRequest struct {
+	signature string
+	body      []byte
+}
+
+func TestClient_CreateWebhook(t *testing.T) {
+	loader := testutil.DocumentLoader(t)
+
+	newWebhookServer := func(t *testing.T, status func(attempt int32) int) (*httptest.Server, chan *webhookRequest) {
+		t.Helper()
+
+		var (
+			attempts int32
+			requests = make(chan *webhookRequest, 10)
+		)
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+
+			requests <- &webhookRequest{signature: r.Header.Get(vault.WebhookSignatureHeader), body: body}
+
+			w.WriteHeader(status(atomic.AddInt32(&attempts, 1)))
+		}))
+		t.Cleanup(srv.Close)
+
+		return srv, requests
+	}
+
+	receive := func(t *testing.T, requests chan *webhookRequest) *webhookRequest {
+		t.Helper()
+
+		select {
+		case r := <-requests:
+			return r
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "webhook not notified")
+		}
+
+		return nil
+	}
+
+	t.Run("Notifies signed events", func(t *testing.T) {
+		srv, requests := newWebhookServer(t, func(int32) int { return http.StatusNoContent })
+
+		client, vID := newWebhookVaultClient(t, loader)
+
+		hook, err := client.CreateWebhook(vID, srv.URL, "s3cr3t")
+		require.NoError(t, err)
+		require.NotEmpty(t, hook.ID)
+		require.Equal(t, srv.URL, hook.URL)
+		require.False(t, hook.Created.IsZero())
+
+		docMeta, err := client.SaveDoc(vID, "doc1", []byte(`{"message":"Hello World!"}`))
+		require.NoError(t, err)
+
+		r := receive(t, requests)
+
+		mac := hmac.New(sha256.New, []byte("s3cr3t"))
+		mac.Write(r.body)
+		require.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), r.signature)
+		require.NotContains(t, string(r.body), "Hello World!")
+
+		var event vault.WebhookEvent
+
+		require.NoError(t, json.Unmarshal(r.body, &event))
+		require.NotEmpty(t, event.ID)
+		require.Equal(t, vault.DocSavedEventType, event.Type)
+		require.Equal(t, vID, event.VaultID)
+		require.Equal(t, "doc1", event.DocID)
+		require.Equal(t, docMeta.Sequence, event.Sequence)
+		require.False(t, event.Timestamp.IsZero())
+
+		docMeta, err = client.SaveBinaryDoc(vID, "doc1", "text/plain", []byte("Hello again!"))
+		require.NoError(t, err)
+
+		require.NoError(t, json.Unmarshal(receive(t, requests).body, &event))
+		require.Equal(t, "doc1", event.DocID)
+		require.Equal(t, uint64(1), event.Sequence)
+	})
+
+	t.Run("Notifies every webhook of the vault", func(t *testing.T) {
+		srv, requests := newWebhookServer(t, func(int32) int { return http.StatusOK })
+
+		client, vID := newWebhookVaultClient(t, loader)
+
+		_, err := client.CreateWebhook(vID, srv.URL+"/a", "")
+		require.NoError(t, err)
+
+		_, err = client.CreateWebhook(vID, srv.URL+"/b", "")
+		require.NoError(t, err)
+
+		_, err = client.SaveDoc(vID, "doc1", []byte(`{"message":"Hello World!"}`))
+		require.NoError(t, err)
+
+		for i := 0; i < 2; i++ {
+			r := receive(t, requests)
+			require.Empty(t, r.signature)
+		}
+	})
+
+	t.Run("Retries failed deliveries", func(t *testing.T) {
+		srv, requests := newWebhookServer(t, func(attempt int32) int {
+			if attempt < 3 {
+				return http.StatusServiceUnavailable
+			}
+
+			return http.StatusOK
+		})
+
+		client, vID := newWebhookVaultClient(t, loader, vault.WithWebhookRetries(3, time.Millisecond))
+
+		_, err := client.CreateWebhook(vID, srv.URL, "")
+		require.NoError(t, err)
+
+		_, err = client.SaveDoc(vID, "doc1", []byte(`{"message":"Hello World!"}`))
+		require.NoError(t, err)
+
+		first := receive(t, requests)
+
+		for i := 0; i < 2; i++ {
+			require.Equal(t, first.body, receive(t, requests).body)
+		}
+	})
+
+	t.Run("Gives up after the last attempt", func(t *testing.T) {
+		srv, requests := newWebhookServer(t, func(int32) int { return http.StatusInternalServerError })
+
+		client, vID := newWebhookVaultClient(t, loader, vault.WithWebhookRetries(2, time.Millisecond))
+
+		_, err := client.CreateWebhook(vID, srv.URL, "")
+		require.NoError(t, err)
+
+		_, err = client.SaveDoc(vID, "doc1", []byte(`{"message":"Hello World!"}`))
+		require.NoError(t, err)
+
+		receive(t, requests)
+		receive(t, requests)
+
+		select {
+		case <-requests:
+			require.FailNow(t, "unexpected attempt")
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+
+	t.Run("Delivers with a bounded number of workers", func(t *testing.T) {
+		var inFlight, maxInFlight int32
+
+		srv, requests := newWebhookServer(t, func(int32) int {
+			n := atomic.AddInt32(&inFlight, 1)
+			defer atomic.AddInt32(&inFlight, -1)
+
+			for {
+				highest := atomic.LoadInt32(&maxInFlight)
+				if n <= highest || atomic.CompareAndSwapInt32(&maxInFlight, highest, n) {
+					break
+				}
+			}
+
+			time.Sleep(10 * time.Millisecond)
+
+			return http.StatusOK
+		})
+
+		client, vID := newWebhookVaultClient(t, loader, vault.WithWebhookWorkers(1))
+
+		for _, path := range []string{"/a", "/b", "/c"} {
+			_, err := client.CreateWebhook(vID, srv.URL+path, "")
+			require.NoError(t, err)
+		}
+
+		for _, docID := range []string{"doc1", "doc2"} {
+			_, err := client.SaveDoc(vID, docID, []byte(`{"message":"Hello World!"}`))
+			require.NoError(t, err)
+		}
+
+		for i := 0; i < 6; i++ {
+			receive(t, requests)
+		}
+
+		require.Equal(t, int32(1), atomic.LoadInt32(&maxInFlight))
+	})
+
+	t.Run("Stops retrying once closed", func(t *testing.T) {
+		srv, requests := newWebhookServer(t, func(int32) int { return http.StatusServiceUnavailable })
+
+		client, vID := newWebhookVaultClient(t, loader, vault.WithWebhookRetries(3, time.Hour))
+
+		_, err := client.CreateWebhook(vID, srv.URL, "")
+		require.NoError(t, err)
+
+		_, err = client.SaveDoc(vID, "doc1", []byte(`{"message":"Hello World!"}`))
+		require.NoError(t, err)
+
+		receive(t, requests)
+
+		// the retry is not waited for
+		closed := make(chan struct{})
+
+		go func() {
+			client.Close()
+			close(closed)
+		}()
+
+		select {
+		case <-closed:
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "close did not return")
+		}
+
+		_, err = client.SaveDoc(vID, "doc1", []byte(`{"message":"Hello again!"}`))
+		require.NoError(t, err)
+
+		select {
+		case <-requests:
+			require.FailNow(t, "unexpected delivery")
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+
+	t.Run("Does not deliver events to addresses that are not public by default", func(t *testing.T) {
+		srv, requests := newWebhookServer(t, func(int32) int { return http.StatusOK })
+
+		client, vID := newRoundTripVaultClient(t, loader, vault.WithWebhookRetries(1, time.Millisecond))
+		t.Cleanup(client.Close)
+
+		_, err := client.CreateWebhook(vID, srv.URL, "")
+		require.NoError(t, err)
+
+		_, err = client.SaveDoc(vID, "doc1", []byte(`{"message":"Hello World!"}`))
+		require.NoError(t, err)
+
+		select {
+		case <-requests:
+			require.FailNow(t, "unexpected delivery")
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+
+	t.Run("Invalid URL", func(t *testing.T) {
+		client, vID := newRoundTripVaultClient(t, loader)
+
+		for _, u := range []string{"", "/events", "ftp://example.com/events", "https://", "http://%zz"} {
+			_, err := client.CreateWebhook(vID, u, "")
+			require.True(t, errors.Is(err, vault.ErrInvalidWebhook), u)
+		}
+	})
+
+	t.Run("Vault not found", func(t *testing.T) {
+		client, _ := newRoundTripVaultClient(t, loader)
+
+		_, err := client.CreateWebhook("did:example:unknown", "https://example.com/events", "")
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+	})
+}
+
+func TestNewWebhookHTTPClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+
+	client := vault.NewWebhookHTTPClient(nil)
+
+	for _, u := range []string{
+		srv.URL,
+		"http://10.0.0.1:1/events",
+		"http://169.254.169.254/latest/meta-data",
+		"http://[::1]:1/events",
+		"http://100.64.0.1:1/events",
+		"http://0.0.0.0:1/events",
+	} {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, u, http.NoBody)
+		require.NoError(t, err)
+
+		resp, err := client.Do(req)
+		if err == nil {
+			require.NoError(t, resp.Body.Close())
+		}
+
+		require.True(t, errors.Is(err, vault.ErrWebhookAddressNotAllowed), u)
+	}
+}
+
+func TestClient_ListWebhooks(t *testing.T) {
+	loader := testutil.DocumentLoader(t)
+
+	t.Run("Success", func(t *testing.T) {
+		client, vID := newWebhookVaultClient(t, loader)
+
+		list, err := client.ListWebhooks(vID)
+		require.NoError(t, err)
+		require.Empty(t, list.Webhooks)
+
+		first, err := client.CreateWebhook(vID, "https://indexer.example.com/a", "s3cr3t")
+		require.NoError(t, err)
+
+		second, err := client.CreateWebhook(vID, "https://indexer.example.com/b", "")
+		require.NoError(t, err)
+
+		list, err = client.ListWebhooks(vID)
+		require.NoError(t, err)
+		require.Len(t, list.Webhooks, 2)
+		require.Equal(t, first.ID, list.Webhooks[0].ID)
+		require.Equal(t, second.ID, list.Webhooks[1].ID)
+
+		src, err := json.Marshal(list)
+		require.NoError(t, err)
+		require.NotContains(t, string(src), "s3cr3t")
+	})
+
+	t.Run("Vault not found", func(t *testing.T) {
+		client, _ := newWebhookVaultClient(t, loader)
+
+		_, err := client.ListWebhooks("did:example:unknown")
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+	})
+}
+
+func TestClient_DeleteWebhook(t *testing.T) {
+	loader := testutil.DocumentLoader(t)
+
+	t.Run("Success", func(t *testing.T) {
+		var notified int32
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&notified, 1)
+			w.WriteHeader(http.StatusOK)
+		}))
+		t.Cleanup(srv.Close)
+
+		client, vID := newWebhookVaultClient(t, loader)
+
+		hook, err := client.CreateWebhook(vID, srv.URL, "")
+		require.NoError(t, err)
+
+		require.NoError(t, client.DeleteWebhook(vID, hook.ID))
+
+		list, err := client.ListWebhooks(vID)
+		require.NoError(t, err)
+		require.Empty(t, list.Webhooks)
+
+		_, err = client.SaveDoc(vID, "doc1", []byte(`{"message":"Hello World!"}`))
+		require.NoError(t, err)
+
+		time.Sleep(100 * time.Millisecond)
+		require.Zero(t, atomic.LoadInt32(&notified))
+	})
+
+	t.Run("Webhook not found", func(t *testing.T) {
+		client, vID := newWebhookVaultClient(t, loader)
+
+		err := client.DeleteWebhook(vID, "unknown")
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+	})
+}
+
+// newWebhookVaultClient returns a client delivering events to webhooks on any address, eg. test servers.
+func newWebhookVaultClient(t *testing.T, loader ld.DocumentLoader, opts ...vault.Opt) (*vault.Client, string) {
+	t.Helper()
+
+	client, vID := newRoundTripVaultClient(t, loader, append(opts, vault.WithWebhookHTTPClient(http.DefaultClient))...)
+	t.Cleanup(client.Close)
+
+	return client, vID
+}
@@ -0,0 +1,26 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package version
+
+import (
+	"github.com/trustbloc/ace/pkg/restapi/handler"
+	"github.com/trustbloc/ace/pkg/restapi/version/operation"
+)
+
+// New returns new controller instance.
+func New() *Controller {
+	return &Controller{handlers: operation.New().GetRESTHandlers()}
+}
+
+// Controller contains handlers for controller.
+type Controller struct {
+	handlers []handler.Handler
+}
+
+// GetOperations returns all controller endpoints.
+func (c *Controller) GetOperations() []handler.Handler {
+	return c.handlers
+}
@@ -0,0 +1,20 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package version_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/ace/pkg/restapi/version"
+)
+
+func TestController_New(t *testing.T) {
+	controller := version.New()
+	require.NotNil(t, controller)
+	require.Equal(t, 1, len(controller.GetOperations()))
+}
@@ -0,0 +1,48 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/log"
+
+	"github.com/trustbloc/ace/pkg/buildinfo"
+	"github.com/trustbloc/ace/pkg/restapi/handler"
+)
+
+var logger = log.New("version")
+
+// API endpoints.
+const (
+	versionEndpoint = "/version"
+)
+
+// New returns a new version operation.
+func New() *Operation {
+	return &Operation{}
+}
+
+// Operation defines the handler reporting the build metadata of the service.
+type Operation struct{}
+
+// GetRESTHandlers get all controller API handler available for this service.
+func (o *Operation) GetRESTHandlers() []handler.Handler {
+	return []handler.Handler{
+		handler.NewHTTPHandler(versionEndpoint, http.MethodGet, o.versionHandler),
+	}
+}
+
+func (o *Operation) versionHandler(rw http.ResponseWriter, _ *http.Request) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusOK)
+
+	err := json.NewEncoder(rw).Encode(buildinfo.Get())
+	if err != nil {
+		logger.Errorf("version response failure, %s", err)
+	}
+}
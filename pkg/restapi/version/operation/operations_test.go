@@ -0,0 +1,37 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/ace/pkg/buildinfo"
+	"github.com/trustbloc/ace/pkg/restapi/version/operation"
+)
+
+func TestVersion(t *testing.T) {
+	handlers := operation.New().GetRESTHandlers()
+	require.Equal(t, 1, len(handlers))
+	require.Equal(t, "/version", handlers[0].Path())
+	require.Equal(t, http.MethodGet, handlers[0].Method())
+
+	b := httptest.NewRecorder()
+
+	handlers[0].Handle()(b, nil)
+
+	require.Equal(t, http.StatusOK, b.Code)
+	require.Equal(t, "application/json", b.Header().Get("Content-Type"))
+
+	var info *buildinfo.Info
+
+	require.NoError(t, json.NewDecoder(b.Body).Decode(&info))
+	require.Equal(t, buildinfo.Get(), info)
+}
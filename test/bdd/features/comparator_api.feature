@@ -14,6 +14,12 @@ Feature: Comparator_REST API
     Then  response status is "200 OK"
     And  response contains "status" with value "success"
 
+  @comparator_liveness
+  Scenario: Comparator liveness
+    When an HTTP GET is sent to "https://localhost:8065/livez"
+    Then  response status is "200 OK"
+    And  response contains "status" with value "success"
+
   @comparator_e2e
   Scenario: Comparator
     Then Check comparator config is created
@@ -10,6 +10,9 @@ Feature: Confidential Storage Hub
   Background: Components are ready
     # confidential storage hub
     When an HTTP GET is sent to "https://localhost:8095/healthcheck"
+    Then response status is "200 OK"
+     And response contains "status" with value "success"
+    When an HTTP GET is sent to "https://localhost:8095/livez"
     Then response status is "200 OK"
      And response contains "status" with value "success"
     # hub-kms
@@ -13,6 +13,12 @@ Feature: Gatekeeper API
     Then  response status is "200 OK"
      And  response contains "status" with value "success"
 
+  Scenario: Service liveness
+    Given Gatekeeper is running on "localhost" port "9014"
+    When  an HTTP GET is sent to "https://localhost:9014/livez"
+    Then  response status is "200 OK"
+     And  response contains "status" with value "success"
+
   Scenario: Create policy configuration for storing/releasing protected data
     When  an HTTP PUT with bearer token "gk_token" is sent to "https://localhost:9014/v1/policy/containment-policy"
           """
@@ -81,14 +87,16 @@ Feature: Gatekeeper API
       And release transaction created on DID by "Handler"
 
     When  an HTTP POST with "(request-target),date" headers signed by "Approver 1" is sent to "https://localhost:9014/v1/release/{ticket_id}/authorize"
-    Then  response status is "200 OK"
+    Then  response status is "202 Accepted"
+     And  ticket status becomes "COLLECTING" as seen by "Handler"
 
     When  an HTTP GET with "(request-target),date" headers signed by "Handler" is sent to "https://localhost:9014/v1/release/{ticket_id}/status"
     Then  response status is "200 OK"
      And  response contains "status" with value "COLLECTING"
 
     When  an HTTP POST with "(request-target),date" headers signed by "Approver 2" is sent to "https://localhost:9014/v1/release/{ticket_id}/authorize"
-    Then  response status is "200 OK"
+    Then  response status is "202 Accepted"
+     And  ticket status becomes "READY_TO_COLLECT" as seen by "Handler"
 
     When  an HTTP GET with "(request-target),date" headers signed by "Handler" is sent to "https://localhost:9014/v1/release/{ticket_id}/status"
     Then  response status is "200 OK"
@@ -129,10 +137,11 @@ Feature: Gatekeeper API
      And  response contains non-empty "ticket_id"
 
     When  an HTTP POST with "(request-target),date" headers signed by "Approver 1" is sent to "https://localhost:9014/v1/release/{ticket_id}/authorize"
-    Then  response status is "200 OK"
+    Then  response status is "202 Accepted"
 
     When  an HTTP POST with "(request-target),date" headers signed by "Approver 2" is sent to "https://localhost:9014/v1/release/{ticket_id}/authorize"
-    Then  response status is "200 OK"
+    Then  response status is "202 Accepted"
+     And  ticket status becomes "READY_TO_COLLECT" as seen by "Handler"
 
     When  an HTTP POST with "(request-target),date" headers signed by "Handler" is sent to "https://localhost:9014/v1/release/{ticket_id}/collect"
     Then  response status is "200 OK"
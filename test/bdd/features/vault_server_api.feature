@@ -14,6 +14,12 @@ Feature: Vault server API
     Then  response status is "200 OK"
     And  response contains "status" with value "success"
 
+  @vault_server_liveness
+  Scenario: Vault server liveness
+    When an HTTP GET is sent to "https://localhost:9099/livez"
+    Then  response status is "200 OK"
+    And  response contains "status" with value "success"
+
   @vault_server_create
   Scenario: Creates a vault
     When Create a new vault using the vault server "https://localhost:9099"
@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package comparator
 
 import (
+	"context"
 	"crypto/tls"
 	"errors"
 	"fmt"
@@ -98,7 +99,7 @@ func (e *Steps) RegisterSteps(s *godog.ScenarioContext) {
 }
 
 func (e *Steps) createVaultForComparator(endpoint string) error {
-	result, err := vaultclient.New(endpoint, vaultclient.WithHTTPClient(e.httpClient)).CreateVault()
+	result, err := vaultclient.New(endpoint, vaultclient.WithHTTPClient(e.httpClient)).CreateVault(context.Background())
 	if err != nil {
 		return err
 	}
@@ -119,7 +120,7 @@ func (e *Steps) createVaultForComparator(endpoint string) error {
 }
 
 func (e *Steps) saveDocumentForComparator(docID, data string) error {
-	res, err := vaultclient.New(e.vaultURL, vaultclient.WithHTTPClient(e.httpClient)).SaveDoc(e.vaultID, docID,
+	res, err := vaultclient.New(e.vaultURL, vaultclient.WithHTTPClient(e.httpClient)).SaveDoc(context.Background(), e.vaultID, docID,
 		map[string]interface{}{
 			"contents": data,
 		})
@@ -299,6 +300,7 @@ func (e *Steps) createVaultAuthorization(duration string) error {
 	}
 
 	result, err := vaultclient.New(vaultURL, vaultclient.WithHTTPClient(e.httpClient)).CreateAuthorization(
+		context.Background(),
 		e.vaultID,
 		e.cshAuthKey,
 		&vault.AuthorizationsScope{
@@ -12,7 +12,6 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
-	"path"
 	"path/filepath"
 	"strings"
 	"time"
@@ -24,7 +23,6 @@ import (
 	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
 	"github.com/hyperledger/aries-framework-go/pkg/crypto"
 	"github.com/hyperledger/aries-framework-go/pkg/crypto/tinkcrypto"
-	remotecrypto "github.com/hyperledger/aries-framework-go/pkg/crypto/webkms"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/jose"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/jsonld"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/suite"
@@ -49,7 +47,7 @@ import (
 	"github.com/trustbloc/ace/pkg/client/csh/client"
 	"github.com/trustbloc/ace/pkg/client/csh/client/operations"
 	"github.com/trustbloc/ace/pkg/client/csh/models"
-	zcapld2 "github.com/trustbloc/ace/pkg/restapi/csh/operation/zcapld"
+	"github.com/trustbloc/ace/pkg/client/zcap"
 	"github.com/trustbloc/ace/test/bdd/pkg/internal/ldutil"
 )
 
@@ -102,7 +100,7 @@ type user struct {
 	vdr              vdrapi.Registry
 }
 
-func (u *user) initKeystore(baseURL string, httpClient webkms.HTTPClient) error { //nolint:funlen
+func (u *user) initKeystore(baseURL string, httpClient webkms.HTTPClient) error {
 	var err error
 
 	u.localkms, err = localkms.New(
@@ -136,46 +134,16 @@ func (u *user) initKeystore(baseURL string, httpClient webkms.HTTPClient) error
 	u.keystoreURL = keystoreURL
 	u.keystoreRootZCAP = base64.URLEncoding.EncodeToString(zcaps)
 
-	httpSigner := zcapld2.NewHTTPSigner(
-		u.controller,
-		u.keystoreRootZCAP,
-		func(request *http.Request) (string, error) {
-			var action string
-
-			switch strings.ToLower(path.Base(request.URL.Path)) {
-			case "sign":
-				action = "sign"
-			case "wrap":
-				action = "wrap"
-			case "unwrap":
-				action = "unwrap"
-			case "export":
-				action = "exportKey"
-			default:
-				action = "createKey"
-			}
-
-			return action, nil
-		},
-		&zcapld.AriesDIDKeySecrets{},
-		&zcapld.AriesDIDKeySignatureHashAlgorithm{
-			KMS:      u.localkms,
-			Crypto:   u.localcrypto,
-			Resolver: u.vdr,
-		},
-	)
-
-	u.webkms = webkms.New(
-		u.keystoreURL,
-		httpClient,
-		webkms.WithHeaders(httpSigner),
-	)
+	kmsClient := zcap.NewSignedKMSClient(u.keystoreURL, httpClient, &zcap.Invoker{
+		Controller: u.controller,
+		ZCAP:       u.keystoreRootZCAP,
+		KMS:        u.localkms,
+		Crypto:     u.localcrypto,
+		Resolver:   u.vdr,
+	})
 
-	u.remotecrypto = remotecrypto.New(
-		u.keystoreURL,
-		httpClient,
-		webkms.WithHeaders(httpSigner),
-	)
+	u.webkms = kmsClient.KMS
+	u.remotecrypto = kmsClient.Crypto
 
 	return nil
 }
@@ -228,28 +196,16 @@ func (u *user) initConfidentialStorage(baseURL string, httpClient edv.HTTPClient
 		return fmt.Errorf("failed to compress edv zcap: %w", err)
 	}
 
-	u.edvClient = edv.New(
+	u.edvClient = zcap.NewSignedEDVClient(
 		baseURL,
+		&zcap.Invoker{
+			Controller: u.controller,
+			ZCAP:       u.edvRootZCAP,
+			KMS:        u.localkms,
+			Crypto:     u.localcrypto,
+			Resolver:   u.vdr,
+		},
 		edv.WithHTTPClient(httpClient),
-		edv.WithHeaders(zcapld2.NewHTTPSigner(
-			u.controller,
-			u.edvRootZCAP,
-			func(r *http.Request) (string, error) {
-				action := "write"
-
-				if r.Method == http.MethodGet {
-					action = "read"
-				}
-
-				return action, nil
-			},
-			&zcapld.AriesDIDKeySecrets{},
-			&zcapld.AriesDIDKeySignatureHashAlgorithm{
-				Crypto:   u.localcrypto,
-				KMS:      u.localkms,
-				Resolver: u.vdr,
-			},
-		)),
 	)
 
 	u.edvVaultID = pathLeaf(edvVaultURL)
@@ -14,6 +14,7 @@ import (
 	"fmt"
 	"net/http"
 	"text/template"
+	"time"
 
 	"github.com/cucumber/godog"
 
@@ -24,6 +25,10 @@ import (
 
 const (
 	authToken = "gk_token"
+
+	// ticket authorizations are processed in the background
+	ticketStatusRetries    = 10
+	ticketStatusRetryDelay = 500 * time.Millisecond
 )
 
 // DIDOwner defines parameters of a DID owner.
@@ -57,6 +62,7 @@ func (s *Steps) RegisterSteps(sc *godog.ScenarioContext) {
 	sc.Step(`^policy configuration with ID "([^"]*)"$`, s.createPolicy)
 	sc.Step(`^social media handle "([^"]*)" converted into DID by "([^"]*)"$`, s.convertIntoDID)
 	sc.Step(`^release transaction created on DID by "([^"]*)"$`, s.createTicket)
+	sc.Step(`^ticket status becomes "([^"]*)" as seen by "([^"]*)"$`, s.waitForTicketStatus)
 }
 
 func (s *Steps) createDIDOwner(ctx context.Context, name string) (context.Context, error) {
@@ -182,6 +188,38 @@ func (s *Steps) createTicket(ctx context.Context, didOwner string) (context.Cont
 	return context.WithValue(ctx, "ticket_id", s.ticketID), nil //nolint:revive,staticcheck
 }
 
+func (s *Steps) waitForTicketStatus(ctx context.Context, status, didOwner string) error {
+	owner, ok := s.didOwners[didOwner]
+	if !ok {
+		return fmt.Errorf("missing did owner %q", didOwner)
+	}
+
+	var resp ticketStatusResponse
+
+	for i := 0; i < ticketStatusRetries; i++ {
+		_, err := httputil.DoRequest(ctx, "https://localhost:9014/v1/release/"+s.ticketID+"/status",
+			httputil.WithHTTPClient(s.cs.HTTPClient),
+			httputil.WithMethod(http.MethodGet),
+			httputil.WithParsedResponse(&resp),
+			httputil.WithSigner(&common.RequestSigner{
+				Headers:     []string{"(request-target)", "date"},
+				PublicKeyID: owner.PublicKeyID,
+				PrivateKey:  owner.PrivateKey,
+			}))
+		if err != nil {
+			return fmt.Errorf("do request: %w", err)
+		}
+
+		if resp.Status == status {
+			return nil
+		}
+
+		time.Sleep(ticketStatusRetryDelay)
+	}
+
+	return fmt.Errorf("ticket status is %q, expected %q", resp.Status, status)
+}
+
 // GetDID is a helper function used in template to get DID by owner name.
 func (s *Steps) GetDID(didOwner string) string {
 	return s.didOwners[didOwner].DID
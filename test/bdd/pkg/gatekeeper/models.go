@@ -22,3 +22,7 @@ type releaseRequest struct {
 type releaseResponse struct {
 	TicketID string `json:"ticket_id"`
 }
+
+type ticketStatusResponse struct {
+	Status string `json:"status"`
+}
@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package vault
 
 import (
+	"context"
 	"crypto"
 	"crypto/ed25519"
 	"crypto/tls"
@@ -24,7 +25,6 @@ import (
 	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
 	ariescrypto "github.com/hyperledger/aries-framework-go/pkg/crypto"
 	"github.com/hyperledger/aries-framework-go/pkg/crypto/tinkcrypto"
-	webcrypto "github.com/hyperledger/aries-framework-go/pkg/crypto/webkms"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
 	ariesjose "github.com/hyperledger/aries-framework-go/pkg/doc/jose"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk/jwksupport"
@@ -33,16 +33,13 @@ import (
 	vdrapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr"
 	"github.com/hyperledger/aries-framework-go/pkg/kms"
 	"github.com/hyperledger/aries-framework-go/pkg/kms/localkms"
-	"github.com/hyperledger/aries-framework-go/pkg/kms/webkms"
 	"github.com/hyperledger/aries-framework-go/pkg/secretlock"
 	"github.com/hyperledger/aries-framework-go/pkg/secretlock/noop"
 	vdrpkg "github.com/hyperledger/aries-framework-go/pkg/vdr"
 	"github.com/hyperledger/aries-framework-go/pkg/vdr/fingerprint"
 	vdrkey "github.com/hyperledger/aries-framework-go/pkg/vdr/key"
 	"github.com/hyperledger/aries-framework-go/spi/storage"
-	"github.com/igor-pavlenko/httpsignatures-go"
 	"github.com/trustbloc/edge-core/pkg/zcapld"
-	edv "github.com/trustbloc/edv/pkg/client"
 
 	vaultclient "github.com/trustbloc/ace/pkg/client/vault"
 	vccrypto "github.com/trustbloc/ace/pkg/doc/vc/crypto"
@@ -57,6 +54,7 @@ type Steps struct {
 	httpClient     *http.Client
 	vaultID        string
 	vaultURL       string
+	capability     string
 	variableMapper map[string]string
 	authorizations map[string]*vault.CreatedAuthorization
 	kms            kms.KeyManager
@@ -129,17 +127,23 @@ func (e *Steps) checkAccessibility(docID, auth string) error {
 		return fmt.Errorf("failed to fetch doc: %w", err)
 	}
 
-	URIParts := strings.Split(docMeta.URI, "/")
+	cv := e.authorizedVault(docMeta.URI, authorization)
 
-	edvClient := edv.New("https://"+URIParts[2]+"/"+URIParts[3], edv.WithHTTPClient(e.httpClient))
+	edvClient, err := vaultclient.NewEDVClientFromVault(cv, e.kms, e.crypto, e.signerRegistry(), e.httpClient)
+	if err != nil {
+		return fmt.Errorf("failed to create edv client: %w", err)
+	}
 
-	eDoc, err := edvClient.ReadDocument(URIParts[4], URIParts[6], edv.WithRequestHeader(
-		e.edvSign(authorization.RequestingParty, authorization.Tokens.EDV)),
-	)
+	eDoc, err := edvClient.ReadDocument(edvClient.VaultID, path.Base(docMeta.URI))
 	if err != nil {
 		return fmt.Errorf("edvClient failed to read document: %w", err)
 	}
 
+	kmsClient, err := vaultclient.NewKMSClientFromVault(cv, e.kms, e.crypto, e.signerRegistry(), e.httpClient)
+	if err != nil {
+		return fmt.Errorf("failed to create kms client: %w", err)
+	}
+
 	store, err := mem.NewProvider().OpenStore("test")
 	if err != nil {
 		return fmt.Errorf("failed to open mem store: %w", err)
@@ -147,16 +151,8 @@ func (e *Steps) checkAccessibility(docID, auth string) error {
 
 	decrypter := ariesjose.NewJWEDecrypt(
 		[]resolver.KIDResolver{&resolver.StoreResolver{Store: store}},
-		webcrypto.New(
-			e.kmsURI,
-			e.httpClient,
-			webkms.WithHeaders(e.kmsSign(authorization.RequestingParty, authorization.Tokens.KMS)),
-		),
-		webkms.New(
-			e.kmsURI,
-			e.httpClient,
-			webkms.WithHeaders(e.kmsSign(authorization.RequestingParty, authorization.Tokens.KMS)),
-		),
+		kmsClient.Crypto,
+		kmsClient.KMS,
 	)
 
 	JWE, err := ariesjose.Deserialize(string(eDoc.JWE))
@@ -182,13 +178,13 @@ func (e *Steps) checkNotAvailable(docID, auth string) error {
 		return err
 	}
 
-	URIParts := strings.Split(docMeta.URI, "/")
-
-	edvClient := edv.New("https://"+URIParts[2]+"/"+URIParts[1], edv.WithHTTPClient(e.httpClient))
+	edvClient, err := vaultclient.NewEDVClientFromVault(e.authorizedVault(docMeta.URI, authorization),
+		e.kms, e.crypto, e.signerRegistry(), e.httpClient)
+	if err != nil {
+		return fmt.Errorf("failed to create edv client: %w", err)
+	}
 
-	_, err = edvClient.ReadDocument(URIParts[4], URIParts[6], edv.WithRequestHeader(
-		e.edvSign(authorization.RequestingParty, authorization.Tokens.EDV)),
-	)
+	_, err = edvClient.ReadDocument(edvClient.VaultID, path.Base(docMeta.URI))
 
 	if err == nil {
 		return errors.New("expected an error, but got <nil>")
@@ -201,6 +197,28 @@ func (e *Steps) checkNotAvailable(docID, auth string) error {
 	return err
 }
 
+// authorizedVault returns the vault holding the document at docURI, as if created with the tokens of authorization.
+func (e *Steps) authorizedVault(docURI string, authorization *vault.CreatedAuthorization) *vault.CreatedVault {
+	// https://<host>/encrypted-data-vaults/<vault id>/documents/<doc id>
+	edvURI := strings.Join(strings.Split(docURI, "/")[:5], "/")
+
+	return &vault.CreatedVault{
+		ID: e.vaultID,
+		Authorization: &vault.Authorization{
+			EDV: &vault.Location{URI: edvURI, AuthToken: authorization.Tokens.EDV},
+			KMS: &vault.Location{URI: e.kmsURI, AuthToken: authorization.Tokens.KMS},
+		},
+	}
+}
+
+// signerRegistry returns the registry resolving the DIDs of the parties the steps sign on behalf of.
+func (e *Steps) signerRegistry() vdrapi.Registry {
+	return vdrpkg.New(
+		vdrpkg.WithVDR(vdrkey.New()),
+		vdrpkg.WithVDR(e.orbVDR),
+	)
+}
+
 func (e *Steps) createAuthorization(method, duration, name string) error {
 	sec, err := strconv.Atoi(duration)
 	if err != nil {
@@ -223,6 +241,7 @@ func (e *Steps) createAuthorization(method, duration, name string) error {
 	}
 
 	result, err := vaultclient.New(e.vaultURL, vaultclient.WithHTTPClient(e.httpClient)).CreateAuthorization(
+		vaultclient.ContextWithCapability(context.Background(), e.capability),
 		e.vaultID,
 		requestingParty,
 		&vault.AuthorizationsScope{
@@ -245,7 +264,7 @@ func (e *Steps) createAuthorization(method, duration, name string) error {
 }
 
 func (e *Steps) createVault(endpoint string) error {
-	result, err := vaultclient.New(endpoint, vaultclient.WithHTTPClient(e.httpClient)).CreateVault()
+	result, err := vaultclient.New(endpoint, vaultclient.WithHTTPClient(e.httpClient)).CreateVault(context.Background())
 	if err != nil {
 		return err
 	}
@@ -257,6 +276,7 @@ func (e *Steps) createVault(endpoint string) error {
 	e.vaultID = result.ID
 	e.vaultURL = endpoint
 	e.kmsURI = result.KMS.URI
+	e.capability = result.EDV.AuthToken
 
 	_, err = vdrutil.ResolveDID(e.vdrRegistry, e.vaultID, 10) //nolint: gomnd
 	if err != nil {
@@ -267,7 +287,8 @@ func (e *Steps) createVault(endpoint string) error {
 }
 
 func (e *Steps) saveDoc(docID, data string) (*vault.DocumentMetadata, error) {
-	res, err := vaultclient.New(e.vaultURL, vaultclient.WithHTTPClient(e.httpClient)).SaveDoc(e.vaultID, docID,
+	res, err := vaultclient.New(e.vaultURL, vaultclient.WithHTTPClient(e.httpClient)).SaveDoc(
+		vaultclient.ContextWithCapability(context.Background(), e.capability), e.vaultID, docID,
 		map[string]interface{}{
 			"contents": data,
 		})
@@ -317,7 +338,7 @@ func (e *Steps) checkAuthorization(auth string) error {
 	}
 
 	result, err := vaultclient.New(e.vaultURL, vaultclient.WithHTTPClient(e.httpClient)).
-		GetAuthorization(e.vaultID, authorization.ID)
+		GetAuthorization(context.Background(), e.vaultID, authorization.ID)
 	if err != nil {
 		return err
 	}
@@ -329,28 +350,14 @@ func (e *Steps) checkAuthorization(auth string) error {
 	return nil
 }
 
-func (e *Steps) kmsSign(controller, authToken string) func(req *http.Request) (*http.Header, error) {
-	return func(req *http.Request) (*http.Header, error) {
-		var action string
-
-		switch strings.ToLower(path.Base(req.URL.Path)) {
-		case "unwrap":
-			action = "unwrap"
-		default:
-			action = "createKey"
-		}
-
-		return e.sign(req, controller, action, authToken)
-	}
-}
-
 func (e *Steps) getDoc(id string) (*vault.DocumentMetadata, error) {
 	docID, ok := e.variableMapper[id]
 	if !ok {
 		docID = id
 	}
 
-	result, err := vaultclient.New(e.vaultURL, vaultclient.WithHTTPClient(e.httpClient)).GetDocMetaData(e.vaultID, docID)
+	result, err := vaultclient.New(e.vaultURL, vaultclient.WithHTTPClient(e.httpClient)).
+		GetDocMetaData(context.Background(), e.vaultID, docID)
 	if err != nil {
 		return nil, err
 	}
@@ -362,41 +369,6 @@ func (e *Steps) getDoc(id string) (*vault.DocumentMetadata, error) {
 	return result, nil
 }
 
-func (e *Steps) edvSign(controller, authToken string) func(req *http.Request) (*http.Header, error) {
-	return func(req *http.Request) (*http.Header, error) {
-		action := "write"
-		if req.Method == http.MethodGet {
-			action = "read"
-		}
-
-		return e.sign(req, controller, action, authToken)
-	}
-}
-
-func (e *Steps) sign(req *http.Request, controller, action, zcap string) (*http.Header, error) {
-	req.Header.Set(
-		zcapld.CapabilityInvocationHTTPHeader,
-		fmt.Sprintf(`zcap capability=%q,action=%q`, zcap, action),
-	)
-
-	hs := httpsignatures.NewHTTPSignatures(&zcapld.AriesDIDKeySecrets{})
-	hs.SetSignatureHashAlgorithm(&zcapld.AriesDIDKeySignatureHashAlgorithm{
-		Crypto: e.crypto,
-		KMS:    e.kms,
-		Resolver: vdrpkg.New(
-			vdrpkg.WithVDR(vdrkey.New()),
-			vdrpkg.WithVDR(e.orbVDR),
-		),
-	})
-
-	err := hs.Sign(controller, req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to sign http request: %w", err)
-	}
-
-	return &req.Header, nil
-}
-
 func (e *Steps) createDIDKey() (string, error) {
 	sig, err := signature.NewCryptoSigner(e.crypto, e.kms, kms.ED25519)
 	if err != nil {